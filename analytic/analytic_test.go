@@ -0,0 +1,107 @@
+package analytic
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestMeanField(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		dim int
+		h   float64
+	}{
+		{dim: 1, h: 0},
+		{dim: 1, h: 1},
+		{dim: 1, h: 3},
+		{dim: 2, h: 2.5},
+		{dim: 2, h: -1},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			// Minimize the energy per site of the classical spins tilted by theta.
+			z := CriticalField(test.dim)
+			const steps = 1 << 16
+			minE, minTheta := math.Inf(1), 0.0
+			for j := range steps + 1 {
+				theta := math.Pi / 2 * float64(j) / steps
+				if test.h < 0 {
+					theta = -theta
+				}
+				e := -z/2*math.Cos(theta)*math.Cos(theta) - test.h*math.Sin(theta)
+				if e < minE {
+					minE, minTheta = e, theta
+				}
+			}
+
+			if e := MeanFieldEnergy(test.dim, test.h); math.Abs(e-minE) > 1e-8 {
+				t.Fatalf("%f %f", e, minE)
+			}
+			if m := MeanFieldMagnetization(test.dim, test.h); math.Abs(m-math.Cos(minTheta)) > 1e-3 {
+				t.Fatalf("%f %f", m, math.Cos(minTheta))
+			}
+		})
+	}
+}
+
+func TestSpinWave(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		dim  int
+		h    float64
+		k    []float64
+		want float64
+	}{
+		// Below the critical field, the dispersion is 2z sqrt(1 - (h/z)^2 gamma_k).
+		{dim: 1, h: 1, k: []float64{0}, want: 4 * math.Sqrt(1-0.25)},
+		{dim: 1, h: 1, k: []float64{math.Pi / 2}, want: 4},
+		{dim: 2, h: 2, k: []float64{math.Pi, 0}, want: 8},
+		// Above the critical field, it is 2 sqrt(h (h - z gamma_k)).
+		{dim: 1, h: 3, k: []float64{0}, want: 2 * math.Sqrt(3)},
+		{dim: 2, h: 5, k: []float64{math.Pi / 3, math.Pi / 3}, want: 2 * math.Sqrt(5*(5-2))},
+		// The gap closes at the mean-field critical field.
+		{dim: 1, h: 2, k: []float64{0}, want: 0},
+		{dim: 2, h: 4, k: []float64{0, 0}, want: 0},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if w := SpinWaveDispersion(test.dim, test.h, test.k); math.Abs(w-test.want) > 1e-12 {
+				t.Fatalf("%f %f", w, test.want)
+			}
+		})
+	}
+
+	// In a strong field, the dispersion approaches the exact one of the chain, 2 sqrt(1 + h^2 - 2h cos k).
+	const h = 100
+	for _, k := range []float64{0, 1, math.Pi} {
+		exact := 2 * math.Sqrt(1+h*h-2*h*math.Cos(k))
+		if w := SpinWaveDispersion(1, h, []float64{k}); math.Abs(w-exact) > 1e-4*exact {
+			t.Fatalf("%f %f %f", k, w, exact)
+		}
+	}
+}
+
+func TestSpinWaveMagnetization(t *testing.T) {
+	t.Parallel()
+	for _, dim := range []int{1, 2} {
+		if m := SpinWaveMagnetization(dim, 0); m != 1 {
+			t.Fatalf("%d %f", dim, m)
+		}
+		if m := SpinWaveMagnetization(dim, CriticalField(dim)+0.1); m != 0 {
+			t.Fatalf("%d %f", dim, m)
+		}
+		// Quantum fluctuations reduce the order parameter more as the field grows.
+		prev := 0.0
+		for _, r := range []float64{0.2, 0.5, 0.8, 0.95} {
+			h := r * CriticalField(dim)
+			mf, sw := MeanFieldMagnetization(dim, h), SpinWaveMagnetization(dim, h)
+			if sw <= 0 || sw >= mf || 1-sw/mf <= prev {
+				t.Fatalf("%d %f %f %f %f", dim, h, mf, sw, prev)
+			}
+			prev = 1 - sw/mf
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package analytic
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestExactIsing(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n int
+		h float64
+		// e and mx are the energy and transverse magnetization per site, or NaN if they are to be compared with the dense hamiltonian.
+		e  float64
+		mx float64
+	}{
+		{n: 1, h: 0.7, e: -0.7, mx: 1},
+		// The two spins are in the even sector of |up up> and |down down> and the symmetric state of |up down> and |down up>.
+		{n: 2, h: 0.6, e: -math.Sqrt(1+4*0.6*0.6) / 2, mx: 2 * 0.6 / math.Sqrt(1+4*0.6*0.6)},
+		{n: 2, h: 0, e: -0.5, mx: 0},
+		{n: 6, h: 0.5, e: math.NaN()},
+		{n: 8, h: 1, e: math.NaN()},
+		{n: 7, h: -2, e: math.NaN()},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			c, err := ExactIsing(test.n, test.h)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !math.IsNaN(test.e) {
+				if math.Abs(c.Energy-test.e) > 1e-12 || math.Abs(c.TransverseMagnetization-test.mx) > 1e-12 {
+					t.Fatalf("%+v %f %f", c, test.e, test.mx)
+				}
+				return
+			}
+
+			vals := tensor.Zeros(1)
+			bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+			if err := tensor.Eig(vals, nil, denseIsingChain(test.n, test.h), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if e0 := float64(real(vals.At(0))) / float64(test.n); math.Abs(c.Energy-e0) > 1e-5 {
+				t.Fatalf("%f %f", c.Energy, e0)
+			}
+			if gap := float64(real(vals.At(1)-vals.At(0))) / 2; test.h > 1 || test.h < -1 {
+				// Above the critical field, the first excitation is a single fermion.
+				if math.Abs(c.Gap-2*gap) > 1e-4 {
+					t.Fatalf("%f %f", c.Gap, 2*gap)
+				}
+			}
+
+			// The magnetization is the derivative of the energy.
+			const dh = 1e-4
+			cp, err := ExactIsing(test.n, test.h+dh)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			cm, err := ExactIsing(test.n, test.h-dh)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if mx := -(cp.Energy - cm.Energy) / (2 * dh); math.Abs(c.TransverseMagnetization-mx) > 1e-6 {
+				t.Fatalf("%f %f", c.TransverseMagnetization, mx)
+			}
+		})
+	}
+}
+
+func TestExactIsingInfinite(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		h  float64
+		e  float64
+		mx float64
+	}{
+		{h: 0, e: -1, mx: 0},
+		{h: 1, e: -4 / math.Pi, mx: 2 / math.Pi},
+		{h: 0.5, e: -1.06354440997337},
+		{h: 2, e: -2.12708881994674},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			c := ExactIsingInfinite(test.h)
+			if math.Abs(c.Energy-test.e) > 1e-8 {
+				t.Fatalf("%f %f", c.Energy, test.e)
+			}
+			if test.mx != 0 && math.Abs(c.TransverseMagnetization-test.mx) > 1e-8 {
+				t.Fatalf("%f %f", c.TransverseMagnetization, test.mx)
+			}
+
+			// A long open chain approaches the infinite chain, up to the boundary corrections of order 1/n.
+			open, err := ExactIsing(200, test.h)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if math.Abs(open.Energy-c.Energy) > 2e-2 || math.Abs(open.TransverseMagnetization-c.TransverseMagnetization) > 2e-2 {
+				t.Fatalf("%+v %+v", open, c)
+			}
+		})
+	}
+}
+
+func TestSpontaneousMagnetization(t *testing.T) {
+	t.Parallel()
+	if m := SpontaneousMagnetization(0); m != 1 {
+		t.Fatalf("%f", m)
+	}
+	if m := SpontaneousMagnetization(1.5); m != 0 {
+		t.Fatalf("%f", m)
+	}
+	if m, want := SpontaneousMagnetization(0.6), math.Pow(0.64, 0.125); math.Abs(m-want) > 1e-12 {
+		t.Fatalf("%f %f", m, want)
+	}
+}
+
+// denseIsingChain returns the dense hamiltonian of the open chain of ExactIsing.
+func denseIsingChain(n int, h float64) *tensor.Dense {
+	dim := 1 << n
+	spin := func(state, i int) float32 { return float32(1 - 2*((state>>i)&1)) }
+	a := tensor.Zeros(dim, dim)
+	for state := range dim {
+		var diag float32
+		for i := range n {
+			if i+1 < n {
+				diag -= spin(state, i) * spin(state, i+1)
+			}
+			a.SetAt([]int{state ^ (1 << i), state}, complex(float32(-h), 0))
+		}
+		a.SetAt([]int{state, state}, complex(diag, 0))
+	}
+	return a
+}
@@ -0,0 +1,175 @@
+package edsweep
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	solverPython    = "python"
+	solverStreaming = "streaming"
+)
+
+// SweepConfig describes the configurations of a sweep, which is read from a JSON or YAML file by the -config flag.
+// For example, the default sweep in YAML is
+//
+//	solver: python
+//	lattices:
+//	  - dimension: 1
+//	    sizes: [4, 9, 16, 25]
+//	    tc_guess: 1
+//	    h_logs: [-2, -1.5, -1, 1, 1.5, 2]
+//	    h_log_offsets: [0.05, 0.1, 0.2, 0.3, 0.4, 0.5]
+//	  - dimension: 2
+//	    sizes: [2, 3, 4, 5]
+//	    tc_guess: 2
+//	    h_logs: [-2, -1.5, -1, 1, 1.5, 2]
+//	    h_log_offsets: [0.05, 0.1, 0.2, 0.3, 0.4, 0.5]
+//
+// A lattice may also declare its observables, such as
+//
+//	observables: [energy, m, binder, entropy(2), entropy(4), zz(1), zz(4)]
+//
+// see observable.
+type SweepConfig struct {
+	// Solver is the eigensolver, python or streaming, which overrides the -streaming flag if not empty.
+	Solver   string          `json:"solver" yaml:"solver"`
+	Lattices []LatticeConfig `json:"lattices" yaml:"lattices"`
+}
+
+// LatticeConfig describes the lattices of a dimension, and their field grid.
+type LatticeConfig struct {
+	// Dimension is 1 for chains of Sizes spins, and 2 for square lattices of side Sizes.
+	Dimension int   `json:"dimension" yaml:"dimension"`
+	Sizes     []int `json:"sizes" yaml:"sizes"`
+
+	// TcGuess is a guess of the critical field, around which the field grid is refined.
+	TcGuess float64 `json:"tc_guess" yaml:"tc_guess"`
+	// HLogs are the base 10 logarithms of the fields.
+	HLogs []float64 `json:"h_logs" yaml:"h_logs"`
+	// HLogOffsets are added to and subtracted from log10(TcGuess) for more fields.
+	HLogOffsets []float64 `json:"h_log_offsets" yaml:"h_log_offsets"`
+
+	// Observables are the expressions of the observables of the ground states, see observable, which default to energy, m and binder.
+	// Adding one to a solved sweep computes it from the saved eigenvectors when solving again, without diagonalizing.
+	Observables []string `json:"observables" yaml:"observables"`
+}
+
+// numSpins returns the numbers of spins of the lattices of l.
+func (l LatticeConfig) numSpins() []int {
+	ns := make([]int, 0, len(l.Sizes))
+	for _, size := range l.Sizes {
+		n := size
+		if l.Dimension == 2 {
+			n = size * size
+		}
+		ns = append(ns, n)
+	}
+	return ns
+}
+
+// defaultSweepConfig returns the sweep over chains and square lattices of up to 25 spins.
+func defaultSweepConfig() SweepConfig {
+	hLogs := []float64{-2, -1.5, -1, 1, 1.5, 2}
+	offsets := []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.5}
+	return SweepConfig{Lattices: []LatticeConfig{
+		{Dimension: 1, Sizes: []int{4, 9, 16, 25}, TcGuess: 1, HLogs: hLogs, HLogOffsets: offsets},
+		{Dimension: 2, Sizes: []int{2, 3, 4, 5}, TcGuess: 2, HLogs: hLogs, HLogOffsets: offsets},
+	}}
+}
+
+// readSweepConfig reads the sweep config file fpath, which is YAML if its extension is .yaml or .yml, and JSON otherwise.
+// Unknown fields are rejected, so that typos are not silently ignored.
+func readSweepConfig(fpath string) (SweepConfig, error) {
+	b, err := os.ReadFile(fpath)
+	if err != nil {
+		return SweepConfig{}, errors.Wrap(err, "")
+	}
+	var cfg SweepConfig
+	switch strings.ToLower(filepath.Ext(fpath)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil {
+			return SweepConfig{}, errors.Wrap(err, fpath)
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return SweepConfig{}, errors.Wrap(err, fpath)
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return SweepConfig{}, errors.Wrap(err, fpath)
+	}
+	return cfg, nil
+}
+
+func (cfg SweepConfig) validate() error {
+	switch cfg.Solver {
+	case "", solverPython, solverStreaming:
+	default:
+		return errors.Errorf("unknown solver %q", cfg.Solver)
+	}
+	if len(cfg.Lattices) == 0 {
+		return errors.Errorf("no lattices")
+	}
+	for i, l := range cfg.Lattices {
+		if l.Dimension != 1 && l.Dimension != 2 {
+			return errors.Errorf("%d dimension %d", i, l.Dimension)
+		}
+		for _, size := range l.Sizes {
+			if size < 1 {
+				return errors.Errorf("%d size %d", i, size)
+			}
+		}
+		if len(l.HLogOffsets) > 0 && !(l.TcGuess > 0) {
+			return errors.Errorf("%d tc_guess %f", i, l.TcGuess)
+		}
+		if len(l.Sizes) == 0 || len(l.HLogs)+len(l.HLogOffsets) == 0 {
+			return errors.Errorf("%d %#v", i, l)
+		}
+		if _, err := parseObservables(l.Observables, l.numSpins()); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+	}
+	return nil
+}
+
+// configs returns the configurations of the sweep.
+func (cfg SweepConfig) configs() ([]Statistics, error) {
+	configs := make([]Statistics, 0)
+	for _, l := range cfg.Lattices {
+		obs, err := parseObservables(l.Observables, l.numSpins())
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		hLogs := append([]float64{}, l.HLogs...)
+		tcLog := math.Log10(l.TcGuess)
+		for _, hl := range l.HLogOffsets {
+			hLogs = append(hLogs, tcLog+hl)
+			hLogs = append(hLogs, tcLog-hl)
+		}
+
+		for _, size := range l.Sizes {
+			n := [2]int{size, 1}
+			if l.Dimension == 2 {
+				n = [2]int{size, size}
+			}
+			for _, hl := range hLogs {
+				h := complex(float32(math.Pow(10, hl)), 0)
+				configs = append(configs, Statistics{n: n, h: h, obs: obs})
+			}
+		}
+	}
+	return configs, nil
+}
@@ -0,0 +1,115 @@
+package edsweep
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSweepConfig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		fname   string
+		content string
+		// numConfigs is the number of configs, or -1 if the config is invalid.
+		numConfigs int
+	}{
+		{
+			fname: "sweep.yaml",
+			content: `
+solver: streaming
+lattices:
+  - dimension: 1
+    sizes: [4, 6]
+    tc_guess: 1
+    h_logs: [-1, 1]
+    h_log_offsets: [0.1]
+    observables: [energy, m, binder, entropy(2), zz(3), xy(0)]
+`,
+			numConfigs: 2 * (2 + 2),
+		},
+		{
+			fname:      "sweep.json",
+			content:    `{"lattices": [{"dimension": 2, "sizes": [2, 3], "h_logs": [0, 1, 2]}, {"dimension": 1, "sizes": [4], "h_logs": [0]}]}`,
+			numConfigs: 2*3 + 1,
+		},
+		{
+			fname:      "sweep.yml",
+			content:    "lattices:\n  - dimension: 2\n    sizes: [2]\n    tc_guess: 2\n    h_log_offsets: [0.1, 0.2]\n",
+			numConfigs: 4,
+		},
+		// A typo of a field.
+		{fname: "sweep.yaml", content: "lattices:\n  - dimension: 1\n    size: [4]\n    h_logs: [0]\n", numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [4], "h_log": [0]}]}`, numConfigs: -1},
+		// Invalid solvers and lattices.
+		{fname: "sweep.json", content: `{"solver": "lapack", "lattices": [{"dimension": 1, "sizes": [4], "h_logs": [0]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": []}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 3, "sizes": [2], "h_logs": [0]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [4, 0], "h_logs": [0]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [], "h_logs": [0]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [4]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [4], "h_log_offsets": [0.1]}]}`, numConfigs: -1},
+		// Invalid observables.
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [4], "h_logs": [0], "observables": ["magnetization"]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [4], "h_logs": [0], "observables": ["entropy(x)"]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [4], "h_logs": [0], "observables": ["entropy(2"]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [4], "h_logs": [0], "observables": ["qz(1)"]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [4], "h_logs": [0], "observables": ["m", "m"]}]}`, numConfigs: -1},
+		// The cut of the entropy and the distance of the correlator are out of the smallest lattice.
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 1, "sizes": [8, 4], "h_logs": [0], "observables": ["entropy(4)"]}]}`, numConfigs: -1},
+		{fname: "sweep.json", content: `{"lattices": [{"dimension": 2, "sizes": [2], "h_logs": [0], "observables": ["zz(4)"]}]}`, numConfigs: -1},
+		// Malformed files.
+		{fname: "sweep.json", content: `{"lattices": [`, numConfigs: -1},
+		{fname: "sweep.yaml", content: "lattices: [", numConfigs: -1},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			fpath := filepath.Join(dir, test.fname)
+			if err := os.WriteFile(fpath, []byte(test.content), 0644); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			cfg, err := readSweepConfig(fpath)
+			if test.numConfigs < 0 {
+				if err == nil {
+					t.Fatalf("expected error %#v", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			configs, err := cfg.configs()
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(configs) != test.numConfigs {
+				t.Fatalf("%d %d", len(configs), test.numConfigs)
+			}
+		})
+	}
+}
+
+func TestDefaultSweepConfig(t *testing.T) {
+	t.Parallel()
+	cfg := defaultSweepConfig()
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	configs, err := cfg.configs()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// Each lattice has 6 fields and 2*6 offsets around the critical field.
+	if want := 2 * 4 * (6 + 2*6); len(configs) != want {
+		t.Fatalf("%d %d", len(configs), want)
+	}
+	for _, c := range configs {
+		if len(c.obs) != len(defaultObservables) {
+			t.Fatalf("%#v", c.obs)
+		}
+	}
+}
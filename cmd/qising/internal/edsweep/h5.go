@@ -0,0 +1,123 @@
+package edsweep
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/qising/h5"
+	"github.com/pkg/errors"
+)
+
+// maxH5VectorSpins is the largest number of spins whose eigenvectors are written to the HDF5 file, which is built in memory.
+const maxH5VectorSpins = 20
+
+// writeH5 writes the results of a sweep to the HDF5 file fpath, in the group <n0>x<n1>/<h> of each configuration.
+// A group has the attributes n0, n1 and h, and the datasets eigenvalues, magnetization, m2 and binder_cumulant.
+// It also has the dataset eigenvectors, whose column j is the eigenvector of eigenvalues[j], for lattices of at most maxH5VectorSpins spins.
+// If betas is not empty, a group also has the datasets beta, thermal_energy, specific_heat and thermal_tail along the temperature axis, see Thermal.
+// The declared observables are the datasets of its subgroup observables, named by their expressions.
+// The root group has the attribute lambda.
+func writeH5(fpath string, stats []Statistics, lambda float64, betas []float64) error {
+	root := h5.NewGroup()
+	if err := root.SetAttr("lambda", lambda); err != nil {
+		return errors.Wrap(err, "")
+	}
+	for _, s := range stats {
+		g := root.Group(fmt.Sprintf("%dx%d", s.n[0], s.n[1])).Group(filepath.Base(s.dir))
+		if err := writeH5Group(g, s, betas); err != nil {
+			return errors.Wrap(err, s.dir)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := h5.WriteFile(fpath, root); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// namedValue is an attribute or dataset of an HDF5 group.
+type namedValue struct {
+	name string
+	v    any
+}
+
+func writeH5Group(g *h5.Group, s Statistics, betas []float64) error {
+	attrs := []namedValue{
+		{name: "n0", v: s.n[0]},
+		{name: "n1", v: s.n[1]},
+		{name: "h", v: float64(real(s.h))},
+	}
+	for _, a := range attrs {
+		if err := g.SetAttr(a.name, a.v); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+
+	vals := make([]complex128, 0, len(s.EigenValue))
+	for i := range s.EigenValue {
+		vals = append(vals, s.eigenValue(i))
+	}
+	datasets := []namedValue{
+		{name: "eigenvalues", v: vals},
+		{name: "magnetization", v: s.Magnetization},
+		{name: "m2", v: s.M2},
+		{name: "binder_cumulant", v: s.BinderCumulant},
+	}
+	if len(betas) > 0 {
+		var energy, heat, tail []float64
+		for _, th := range thermal(s, betas) {
+			energy, heat, tail = append(energy, th.e), append(heat, th.c), append(tail, th.tail)
+		}
+		datasets = append(datasets, []namedValue{
+			{name: "beta", v: betas},
+			{name: "thermal_energy", v: energy},
+			{name: "specific_heat", v: heat},
+			{name: "thermal_tail", v: tail},
+		}...)
+	}
+	for _, d := range datasets {
+		if err := g.SetDataset(d.name, d.v); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+
+	if len(s.observables) > 0 {
+		og := g.Group("observables")
+		for _, v := range s.observables {
+			if err := og.SetDataset(v.Name, complex(v.Real, v.Imag)); err != nil {
+				return errors.Wrap(err, v.Name)
+			}
+		}
+	}
+
+	if s.n[0]*s.n[1] > maxH5VectorSpins {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(s.dir, fnameEigen))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer f.Close()
+	vvs, err := mat.ReadEig(f)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if len(vvs) == 0 {
+		return errors.Errorf("no eigenpairs")
+	}
+	vecs := make([]complex128, 0, len(vvs)*len(vvs[0].Vec))
+	for i := range vvs[0].Vec {
+		for _, vv := range vvs {
+			vecs = append(vecs, vv.Vec[i])
+		}
+	}
+	if err := g.SetDataset("eigenvectors", vecs, len(vvs[0].Vec), len(vvs)); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
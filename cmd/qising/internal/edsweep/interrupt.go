@@ -0,0 +1,51 @@
+package edsweep
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// fnameInterrupted is the marker in the run directory listing the configs left unsolved by an interrupted run.
+// Since solved configs are skipped, running again resumes the sweep, and removes the marker when it finishes.
+const fnameInterrupted = "interrupted.txt"
+
+// errInterrupted is returned by solveAll when it is interrupted.
+var errInterrupted = errors.New("interrupted")
+
+// notifyInterrupt returns a channel that is closed on the first SIGINT or SIGTERM.
+// Later signals exit immediately as usual.
+func notifyInterrupt() <-chan struct{} {
+	interrupt := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		signal.Stop(sigs)
+		log.Printf("%v, finishing the configs in flight, signal again to exit immediately", sig)
+		close(interrupt)
+	}()
+	return interrupt
+}
+
+// writeInterrupted writes the marker listing the unsolved configs to runDir.
+func writeInterrupted(runDir string, configs []Statistics) error {
+	var b strings.Builder
+	for _, c := range configs {
+		dir := configDir(runDir, c)
+		if _, err := os.Stat(filepath.Join(dir, fnameDone)); err == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s\n", dir)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, fnameInterrupted), []byte(b.String()), 0644); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package edsweep
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fumin/qising/exactdiag"
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/pkg/errors"
+)
+
+const (
+	fnameObservables = "observables.json"
+	fnameObsTable    = "observables.csv"
+)
+
+const (
+	observableEnergy     = "energy"
+	observableM          = "m"
+	observableBinder     = "binder"
+	observableEntropy    = "entropy"
+	observableCorrelator = "correlator"
+)
+
+// defaultObservables are the observables of a lattice whose config declares none.
+var defaultObservables = []string{observableEnergy, observableM, observableBinder}
+
+// pauli are the single spin operators of correlators.
+var pauli = map[byte][][]complex64{'x': mat.PauliX, 'y': mat.PauliY, 'z': mat.PauliZ}
+
+// observable is a measurement of the ground state, which is declared in the sweep config by one of the expressions
+//   - energy, the ground state energy.
+//   - m and binder, the magnetization and the Binder cumulant, see exactdiag.Statistics.
+//   - entropy(l), the entanglement entropy between the first l sites and the rest.
+//   - ab(r), where a and b are each one of x, y and z, the correlator <A_0 B_r> of the Pauli matrices A and B on the sites 0 and r.
+//
+// Sites are indexed by y*n[1] + x as in exactdiag.
+type observable struct {
+	// name is the expression, which also names the value in the results.
+	name string
+	kind string
+	// ops are the Pauli matrices of a correlator.
+	ops [2][][]complex64
+	// arg is the cut of an entropy, or the distance of a correlator.
+	arg int
+}
+
+// parseObservable parses the expression s, see observable.
+func parseObservable(s string) (observable, error) {
+	o := observable{name: strings.TrimSpace(s)}
+	switch o.name {
+	case observableEnergy, observableM, observableBinder:
+		o.kind = o.name
+		return o, nil
+	}
+
+	fn, arg, ok := strings.Cut(o.name, "(")
+	if !ok || !strings.HasSuffix(arg, ")") {
+		return observable{}, errors.Errorf("%q", s)
+	}
+	var err error
+	o.arg, err = strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(arg, ")")))
+	if err != nil {
+		return observable{}, errors.Wrap(err, s)
+	}
+	switch {
+	case fn == observableEntropy:
+		o.kind = observableEntropy
+	case len(fn) == 2 && pauli[fn[0]] != nil && pauli[fn[1]] != nil:
+		o.kind = observableCorrelator
+		o.ops = [2][][]complex64{pauli[fn[0]], pauli[fn[1]]}
+	default:
+		return observable{}, errors.Errorf("unknown observable %q", s)
+	}
+	return o, nil
+}
+
+// parseObservables parses the expressions ss of a lattice, whose sizes are numSpins.
+func parseObservables(ss []string, numSpins []int) ([]observable, error) {
+	if len(ss) == 0 {
+		ss = defaultObservables
+	}
+	obs := make([]observable, 0, len(ss))
+	for i, s := range ss {
+		o, err := parseObservable(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		if slices.ContainsFunc(obs, func(p observable) bool { return p.name == o.name }) {
+			return nil, errors.Errorf("%d duplicate %q", i, o.name)
+		}
+		for _, ns := range numSpins {
+			if err := o.check(ns); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%d", ns))
+			}
+		}
+		obs = append(obs, o)
+	}
+	return obs, nil
+}
+
+// check returns an error if o is not defined on a lattice of numSpins spins.
+func (o observable) check(numSpins int) error {
+	switch o.kind {
+	case observableEntropy:
+		if o.arg < 1 || o.arg >= numSpins {
+			return errors.Errorf("%q %d", o.name, numSpins)
+		}
+	case observableCorrelator:
+		if o.arg < 0 || o.arg >= numSpins {
+			return errors.Errorf("%q %d", o.name, numSpins)
+		}
+	}
+	return nil
+}
+
+// needsVector returns whether o is computed from the ground state, instead of from exactdiag.Statistics.
+func (o observable) needsVector() bool {
+	return o.kind == observableEntropy || o.kind == observableCorrelator
+}
+
+// eval returns the value of o of the lattice n, whose statistics are stats and ground state is vec.
+func (o observable) eval(n [2]int, stats exactdiag.Statistics, vec []complex128) (complex128, error) {
+	switch o.kind {
+	case observableEnergy:
+		return complex(stats.EigenValue[0], stats.EigenValueImag[0]), nil
+	case observableM:
+		return complex(stats.Magnetization, 0), nil
+	case observableBinder:
+		return complex(stats.BinderCumulant, 0), nil
+	case observableEntropy:
+		sites := make([]int, 0, o.arg)
+		for i := range o.arg {
+			sites = append(sites, i)
+		}
+		s, err := exactdiag.EntanglementEntropy(n, vec, sites)
+		if err != nil {
+			return 0, errors.Wrap(err, "")
+		}
+		return complex(s, 0), nil
+	default:
+		c, err := exactdiag.Correlation(n, vec, o.ops[0], o.ops[1], 0, o.arg)
+		if err != nil {
+			return 0, errors.Wrap(err, "")
+		}
+		return c, nil
+	}
+}
+
+// observableValue is the value of an observable, which is written to the observables file of a config.
+type observableValue struct {
+	Name string
+	Real float64
+	Imag float64
+}
+
+// getObservables computes the observables obs of the solved config in dir, and writes them to the observables file.
+// The ground state is read from the eigenvector file only if an observable needs it.
+func getObservables(dir string, n [2]int, obs []observable) error {
+	sb, err := os.ReadFile(filepath.Join(dir, fnameStatistics))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	var stats exactdiag.Statistics
+	if err := json.Unmarshal(sb, &stats); err != nil {
+		return errors.Wrap(err, dir)
+	}
+	var vec []complex128
+	if slices.ContainsFunc(obs, observable.needsVector) {
+		vec, err = readGround(dir)
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+
+	values := make([]observableValue, 0, len(obs))
+	for _, o := range obs {
+		v, err := o.eval(n, stats, vec)
+		if err != nil {
+			return errors.Wrap(err, o.name)
+		}
+		values = append(values, observableValue{Name: o.name, Real: real(v), Imag: imag(v)})
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := os.WriteFile(filepath.Join(dir, fnameObservables), b, 0644); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// readGround reads the ground state from the eigenvector file in dir.
+func readGround(dir string) ([]complex128, error) {
+	f, err := os.Open(filepath.Join(dir, fnameEigen))
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	defer f.Close()
+	er, err := mat.NewEigReader(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	vec := make([]complex128, 0)
+	err = er.Column(0, func(_ int, v complex128) error {
+		vec = append(vec, v)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return vec, nil
+}
+
+// readObservables reads the observables file in dir, returning nothing if it does not exist, such as for results of previous versions.
+func readObservables(dir string) ([]observableValue, error) {
+	b, err := os.ReadFile(filepath.Join(dir, fnameObservables))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	var values []observableValue
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, errors.Wrap(err, dir)
+	}
+	return values, nil
+}
+
+// observablesChanged returns whether the observables file in dir lacks or differs from obs,
+// in which case the observables of an already solved config are computed again.
+func observablesChanged(dir string, obs []observable) (bool, error) {
+	values, err := readObservables(dir)
+	if err != nil {
+		return false, errors.Wrap(err, "")
+	}
+	if len(values) != len(obs) {
+		return true, nil
+	}
+	for i, v := range values {
+		if v.Name != obs[i].name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// writeObservables writes the observables of stats to the CSV file fpath, one row per observable of each config.
+func writeObservables(fpath string, stats []Statistics) error {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	w := csv.NewWriter(f)
+	if err1 := w.Write([]string{"n0", "n1", "h", "name", "real", "imag"}); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	for _, s := range stats {
+		for _, v := range s.observables {
+			row := []string{
+				strconv.Itoa(s.n[0]), strconv.Itoa(s.n[1]), fmt.Sprintf("%f", real(s.h)),
+				v.Name, strconv.FormatFloat(v.Real, 'g', -1, 64), strconv.FormatFloat(v.Imag, 'g', -1, 64),
+			}
+			if err1 := w.Write(row); err1 != nil && err == nil {
+				err = errors.Wrap(err1, "")
+			}
+		}
+	}
+
+	w.Flush()
+	if err1 := w.Error(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package edsweep
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+
+	"github.com/fumin/qising/plot"
+	"github.com/pkg/errors"
+)
+
+const (
+	// fnamePlotMagnetization and fnamePlotBinder are the plots in the run directory of each lattice dimension.
+	fnamePlotMagnetization = "magnetization_%dd.svg"
+	fnamePlotBinder        = "binder_%dd.svg"
+)
+
+// writePlots writes the plots of the magnetization and the Binder cumulant against the field of each lattice dimension to runDir,
+// with a curve for each lattice size.
+// The crossings of the Binder cumulants of consecutive sizes are marked, which estimate the critical field.
+func writePlots(runDir string, stats []Statistics) error {
+	byDim := make(map[int][]Statistics)
+	for _, s := range stats {
+		dim := 2
+		if s.n[0] == 1 || s.n[1] == 1 {
+			dim = 1
+		}
+		byDim[dim] = append(byDim[dim], s)
+	}
+
+	for dim, ss := range byDim {
+		// sizes are the curves ordered by the number of spins.
+		sizes := make([][]Statistics, 0)
+		for _, s := range ss {
+			i := slices.IndexFunc(sizes, func(c []Statistics) bool { return c[0].n == s.n })
+			if i < 0 {
+				sizes = append(sizes, nil)
+				i = len(sizes) - 1
+			}
+			sizes[i] = append(sizes[i], s)
+		}
+		slices.SortFunc(sizes, func(a, b []Statistics) int { return cmp.Compare(a[0].n[0]*a[0].n[1], b[0].n[0]*b[0].n[1]) })
+
+		mPlot := &plot.Plot{Title: fmt.Sprintf("Magnetization, %dD", dim), XLabel: "h", YLabel: "m", LogX: true}
+		bPlot := &plot.Plot{Title: fmt.Sprintf("Binder cumulant, %dD", dim), XLabel: "h", YLabel: "U", LogX: true}
+		for _, c := range sizes {
+			name := fmt.Sprintf("%dx%d", c[0].n[0], c[0].n[1])
+			m, b := plot.Series{Name: name}, plot.Series{Name: name}
+			for _, s := range c {
+				h := float64(real(s.h))
+				m.X, m.Y = append(m.X, h), append(m.Y, s.Magnetization)
+				b.X, b.Y = append(b.X, h), append(b.Y, s.BinderCumulant)
+			}
+			mPlot.Series = append(mPlot.Series, m)
+			bPlot.Series = append(bPlot.Series, b)
+		}
+		crossings := plot.Series{Name: "crossings", NoLine: true}
+		for i := 1; i < len(bPlot.Series); i++ {
+			xs, ys := binderCrossings(bPlot.Series[i-1], bPlot.Series[i])
+			crossings.X, crossings.Y = append(crossings.X, xs...), append(crossings.Y, ys...)
+		}
+		if len(crossings.X) > 0 {
+			bPlot.Series = append(bPlot.Series, crossings)
+		}
+
+		if err := plot.WriteFile(filepath.Join(runDir, fmt.Sprintf(fnamePlotMagnetization, dim)), mPlot); err != nil {
+			return errors.Wrap(err, "")
+		}
+		if err := plot.WriteFile(filepath.Join(runDir, fmt.Sprintf(fnamePlotBinder, dim)), bPlot); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return nil
+}
+
+// binderCrossings returns the points where the curves a and b cross between the fields they share, interpolating linearly in log h.
+func binderCrossings(a, b plot.Series) ([]float64, []float64) {
+	type point struct{ x, ya, yb float64 }
+	points := make([]point, 0)
+	for i, x := range a.X {
+		if j := slices.Index(b.X, x); j >= 0 {
+			points = append(points, point{x: x, ya: a.Y[i], yb: b.Y[j]})
+		}
+	}
+	slices.SortFunc(points, func(p, q point) int { return cmp.Compare(p.x, q.x) })
+
+	xs, ys := make([]float64, 0), make([]float64, 0)
+	for i := 1; i < len(points); i++ {
+		p, q := points[i-1], points[i]
+		dp, dq := p.ya-p.yb, q.ya-q.yb
+		if dp == 0 || dq == 0 || (dp > 0) == (dq > 0) {
+			continue
+		}
+		t := dp / (dp - dq)
+		xs = append(xs, math.Pow(10, math.Log10(p.x)+t*(math.Log10(q.x)-math.Log10(p.x))))
+		ys = append(ys, p.ya+t*(q.ya-p.ya))
+	}
+	return xs, ys
+}
@@ -0,0 +1,56 @@
+package edsweep
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// progressBarWidth is the number of characters of the bar of newProgress.
+const progressBarWidth = 20
+
+// newProgress returns a progress function of exactdiag.IsingOptions, which logs a bar, the percentage and the estimated time remaining of the task name,
+// at most once every interval and when the task completes.
+// Each report is a line of its own, so that the reports of concurrent workers do not garble each other.
+func newProgress(name string, interval time.Duration) func(done, total int) {
+	return newProgressWithClock(name, interval, time.Now, log.Printf)
+}
+
+// newProgressWithClock is like newProgress, but reads the time from now and logs with logf.
+func newProgressWithClock(name string, interval time.Duration, now func() time.Time, logf func(format string, v ...any)) func(done, total int) {
+	start := now()
+	var last time.Time
+	return func(done, total int) {
+		t := now()
+		if done < total && t.Sub(last) < interval {
+			return
+		}
+		last = t
+		logf("%s %s", name, progressLine(done, total, t.Sub(start)))
+	}
+}
+
+// progressLine returns the bar, the percentage and the estimated time remaining, after done of total in elapsed time.
+// An empty task, whose total is zero, is complete, and done is clamped to [0, total].
+func progressLine(done, total int, elapsed time.Duration) string {
+	if total <= 0 {
+		done, total = 0, 0
+	}
+	done = min(max(done, 0), total)
+	frac := 1.0
+	if total > 0 {
+		frac = float64(done) / float64(total)
+	}
+	filled := int(frac * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	if done == total {
+		return fmt.Sprintf("[%s] 100%% done in %v", bar, elapsed.Round(time.Second))
+	}
+	// The rows take about the same time, since each has the same number of entries.
+	eta := "unknown"
+	if done > 0 {
+		eta = time.Duration(float64(elapsed) * (1 - frac) / frac).Round(time.Second).String()
+	}
+	return fmt.Sprintf("[%s] %5.1f%% ETA %s", bar, 100*frac, eta)
+}
@@ -0,0 +1,75 @@
+package edsweep
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestProgressLine(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		done    int
+		total   int
+		elapsed time.Duration
+		line    string
+	}{
+		// Without progress, the time remaining is unknown.
+		{done: 0, total: 8, elapsed: 5 * time.Second, line: "[                    ]   0.0% ETA unknown"},
+		{done: 0, total: 8, elapsed: 0, line: "[                    ]   0.0% ETA unknown"},
+		// The remaining rows take as long as the done ones.
+		{done: 1, total: 4, elapsed: 10 * time.Second, line: "[=====               ]  25.0% ETA 30s"},
+		{done: 3, total: 4, elapsed: 90 * time.Second, line: "[===============     ]  75.0% ETA 30s"},
+		{done: 1, total: 3, elapsed: time.Minute, line: "[======              ]  33.3% ETA 2m0s"},
+		{done: 999, total: 1000, elapsed: 999 * time.Second, line: "[=================== ]  99.9% ETA 1s"},
+		{done: 4, total: 4, elapsed: 2500 * time.Millisecond, line: "[====================] 100% done in 3s"},
+		// An empty task is complete.
+		{done: 0, total: 0, elapsed: time.Second, line: "[====================] 100% done in 1s"},
+		// Progress out of range is clamped.
+		{done: 5, total: 4, elapsed: time.Second, line: "[====================] 100% done in 1s"},
+		{done: -1, total: 4, elapsed: time.Second, line: "[                    ]   0.0% ETA unknown"},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if line := progressLine(test.done, test.total, test.elapsed); line != test.line {
+				t.Fatalf("%q %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestProgressThrottle(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	var lines []string
+	logf := func(format string, v ...any) { lines = append(lines, fmt.Sprintf(format, v...)) }
+	progress := newProgressWithClock("4x4", 10*time.Second, func() time.Time { return now }, logf)
+
+	steps := []struct {
+		elapsed time.Duration
+		done    int
+		line    string
+	}{
+		// The first report is logged, and the next ones only after the interval.
+		{elapsed: 0, done: 0, line: "4x4 [                    ]   0.0% ETA unknown"},
+		{elapsed: 5 * time.Second, done: 1},
+		{elapsed: 9 * time.Second, done: 2},
+		{elapsed: 10 * time.Second, done: 2, line: "4x4 [==========          ]  50.0% ETA 10s"},
+		{elapsed: 15 * time.Second, done: 3},
+		// Completion is always logged.
+		{elapsed: 16 * time.Second, done: 4, line: "4x4 [====================] 100% done in 16s"},
+	}
+	var want []string
+	for _, s := range steps {
+		now = start.Add(s.elapsed)
+		progress(s.done, 4)
+		if s.line != "" {
+			want = append(want, s.line)
+		}
+		if len(lines) != len(want) || (len(want) > 0 && lines[len(lines)-1] != want[len(want)-1]) {
+			t.Fatalf("%v %#v %#v", s.elapsed, lines, want)
+		}
+	}
+}
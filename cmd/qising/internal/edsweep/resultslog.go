@@ -0,0 +1,104 @@
+package edsweep
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fumin/qising/exactdiag"
+	"github.com/pkg/errors"
+)
+
+// fnameLog is the results log in the run directory, see resultsLog.
+const fnameLog = "results.jsonl"
+
+// logRecord is a line of the results log.
+type logRecord struct {
+	N [2]int
+	H float32
+	// Dir is the directory of the config relative to the run directory.
+	Dir         string
+	Statistics  exactdiag.Statistics
+	Observables []observableValue
+	// Solve are the solver metadata, which are absent if unknown.
+	Solve *solveMeta `json:",omitempty"`
+}
+
+// resultsLog is the append-only log of the results of the configs in a run directory, one JSON record per line in the order they complete.
+// Unlike gather, which scans every config directory, reading the log is a single file, hence the results of a running sweep can be analyzed cheaply.
+// A config appears again when its results change, such as when observables are added, in which case the last record wins.
+// Configs solved before the log existed are not in it, but are found by gather.
+type resultsLog struct {
+	runDir string
+	// mu serializes the appends of the workers of solveAll.
+	mu sync.Mutex
+}
+
+func newResultsLog(runDir string) *resultsLog {
+	return &resultsLog{runDir: runDir}
+}
+
+// append appends the results of the solved config e to the log.
+// A record is written in a single write to a file opened for appending, so that a concurrent reader sees either all of it or a partial last line.
+func (l *resultsLog) append(e configEntry) error {
+	s, err := readConfig(e)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	rel, err := filepath.Rel(l.runDir, e.dir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	b, err := json.Marshal(logRecord{N: s.n, H: real(s.h), Dir: rel, Statistics: s.Statistics, Observables: s.observables, Solve: s.meta})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(filepath.Join(l.runDir, fnameLog), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if _, err1 := f.Write(b); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// readLog returns the results in the results log of runDir, in the order of gather.
+// A partial last line, which is being written or was cut by a crash, is ignored.
+func readLog(runDir string) ([]Statistics, error) {
+	b, err := os.ReadFile(filepath.Join(runDir, fnameLog))
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	lines := bytes.Split(b, []byte("\n"))
+	// The last element is empty if the log ends with a complete line, and partial otherwise.
+	lines = lines[:len(lines)-1]
+
+	index := make(map[string]int)
+	stats := make([]Statistics, 0, len(lines))
+	for i, line := range lines {
+		var r logRecord
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", i+1))
+		}
+		s := Statistics{n: r.N, h: complex(r.H, 0), dir: filepath.Join(runDir, r.Dir), observables: r.Observables, meta: r.Solve, Statistics: r.Statistics}
+		if j, ok := index[r.Dir]; ok {
+			stats[j] = s
+			continue
+		}
+		index[r.Dir] = len(stats)
+		stats = append(stats, s)
+	}
+	sortStatistics(stats)
+	return stats, nil
+}
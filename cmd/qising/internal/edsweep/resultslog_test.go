@@ -0,0 +1,131 @@
+package edsweep
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag"
+)
+
+func TestResultsLog(t *testing.T) {
+	t.Parallel()
+	runDir := t.TempDir()
+	configs := []Statistics{
+		{
+			n:           [2]int{4, 1},
+			h:           1.5,
+			Statistics:  exactdiag.Statistics{EigenValue: []float64{-6.1, -5.2}, EigenValueImag: []float64{0, 0.25}, Magnetization: 0.3, M2: 0.09, BinderCumulant: 0.6},
+			observables: []observableValue{{Name: "energy", Real: -6.1}, {Name: "zz(1)", Real: 0.4, Imag: -0.1}},
+			meta:        &solveMeta{Solver: solverStreaming, Iterations: 42, Seconds: 0.5, Variance: 1e-12},
+		},
+		// Results of previous versions have neither observables nor solver metadata.
+		{
+			n:          [2]int{2, 2},
+			h:          0.25,
+			Statistics: exactdiag.Statistics{EigenValue: []float64{-4}, EigenValueImag: []float64{0}, Magnetization: 0.9, M2: 0.81, BinderCumulant: 0.66},
+		},
+		{
+			n:          [2]int{4, 1},
+			h:          0.5,
+			Statistics: exactdiag.Statistics{EigenValue: []float64{-4.2}, EigenValueImag: []float64{0}},
+			meta:       &solveMeta{Solver: solverPython, Seconds: 2},
+		},
+	}
+	l := newResultsLog(runDir)
+	for i := range configs {
+		configs[i].dir = configDir(runDir, configs[i])
+		writeConfigResults(t, configs[i])
+		if err := l.append(configEntry{n: configs[i].n, h: configs[i].h, dir: configs[i].dir, done: true}); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	// The results are read in the order of gather.
+	want := []Statistics{configs[1], configs[2], configs[0]}
+	checkLog(t, runDir, want)
+
+	// Results that changed, such as by adding observables, are appended again, and the last record wins.
+	configs[1].observables = []observableValue{{Name: "m", Real: 0.9}}
+	writeConfigResults(t, configs[1])
+	if err := l.append(configEntry{n: configs[1].n, h: configs[1].h, dir: configs[1].dir, done: true}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	want = []Statistics{configs[1], configs[2], configs[0]}
+	checkLog(t, runDir, want)
+
+	// A crash in the middle of a write leaves a truncated last line, which is ignored.
+	fpath := filepath.Join(runDir, fnameLog)
+	b, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	record, err := json.Marshal(logRecord{N: [2]int{9, 1}, H: 3, Dir: "9x1/3.000000"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := os.WriteFile(fpath, append(b, record[:len(record)/2]...), 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	checkLog(t, runDir, want)
+
+	// A corrupt complete line is an error.
+	if err := os.WriteFile(fpath, append(append(b, record[:len(record)/2]...), '\n'), 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err := readLog(runDir); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestResultsLogMissing(t *testing.T) {
+	t.Parallel()
+	if _, err := readLog(t.TempDir()); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+// writeConfigResults writes the results of the solved config s to its directory, as solve does.
+func writeConfigResults(t *testing.T, s Statistics) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	b, err := json.Marshal(s.Statistics)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, fnameStatistics), b, 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if s.observables != nil {
+		b, err := json.Marshal(s.observables)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if err := os.WriteFile(filepath.Join(s.dir, fnameObservables), b, 0644); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	if s.meta != nil {
+		if err := writeSolveMeta(s.dir, *s.meta); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+}
+
+func checkLog(t *testing.T, runDir string, want []Statistics) {
+	stats, err := readLog(runDir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("%d %d", len(stats), len(want))
+	}
+	for i := range stats {
+		if !reflect.DeepEqual(stats[i], want[i]) {
+			t.Fatalf("%d %s %s", i, fmt.Sprintf("%#v", stats[i]), fmt.Sprintf("%#v", want[i]))
+		}
+	}
+}
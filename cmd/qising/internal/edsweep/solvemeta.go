@@ -0,0 +1,83 @@
+package edsweep
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/fumin/qising/exactdiag"
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// fnameSolve is the solver metadata of a config, see solveMeta.
+const fnameSolve = "solve.json"
+
+// solveMeta are the solver metadata of a config, which downstream tools use to filter unconverged results.
+type solveMeta struct {
+	// Solver is solverStreaming or solverPython.
+	Solver string
+	// Iterations is the number of applications of the hamiltonian by the streaming solver, and 0 if unknown, as for Python.
+	Iterations int
+	// Seconds is the wall time of the eigensolver.
+	Seconds float64
+	// Variance is |(H - E0)v|^2 / |v|^2 of the ground state v with energy E0, which is <H^2> - <H>^2 for a hermitian H, and vanishes for an exact eigenstate.
+	Variance float64
+}
+
+// groundVariance returns the variance of the ground state vv, see solveMeta.
+func groundVariance(n [2]int, h complex64, lambda float64, vv mat.ValVec) (float64, error) {
+	coupling := func(a, b [2]int) complex64 { return 1 }
+	field := func(a [2]int) complex64 { return h }
+	op, err := exactdiag.NewIsingOperator(n, coupling, field, exactdiag.NewIsingOptions().LongitudinalField(complex(0, float32(lambda))))
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	if op.Dim() != len(vv.Vec) {
+		return 0, errors.Errorf("%d %d", op.Dim(), len(vv.Vec))
+	}
+	v := tensor.Zeros(len(vv.Vec), 1)
+	for i, c := range vv.Vec {
+		v.SetAt([]int{i, 0}, complex64(c))
+	}
+	hv := op.Apply(tensor.Zeros(1), v)
+
+	var res, norm float64
+	for i, c := range vv.Vec {
+		r := complex128(hv.At(i, 0)) - vv.Val*c
+		res += real(r)*real(r) + imag(r)*imag(r)
+		norm += real(c)*real(c) + imag(c)*imag(c)
+	}
+	if norm == 0 {
+		return 0, errors.Errorf("zero vector")
+	}
+	return res / norm, nil
+}
+
+func writeSolveMeta(dir string, meta solveMeta) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := os.WriteFile(filepath.Join(dir, fnameSolve), b, 0644); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// readSolveMeta reads the solver metadata of the config in dir, which are nil for configs solved before they were recorded.
+func readSolveMeta(dir string) (*solveMeta, error) {
+	b, err := os.ReadFile(filepath.Join(dir, fnameSolve))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	meta := &solveMeta{}
+	if err := json.Unmarshal(b, meta); err != nil {
+		return nil, errors.Wrap(err, dir)
+	}
+	return meta, nil
+}
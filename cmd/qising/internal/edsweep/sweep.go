@@ -0,0 +1,718 @@
+package edsweep
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fumin/qising/analytic"
+	"github.com/fumin/qising/cmd/qising/internal/emit"
+	"github.com/fumin/qising/exactdiag"
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/qising/linalg"
+	"github.com/pkg/errors"
+)
+
+const (
+	fnameEigen      = "eig.csv"
+	fnameDone       = "done.txt"
+	fnameStatistics = "statistics.txt"
+)
+
+var (
+	// defaultRunDir is the default run directory.
+	defaultRunDir = filepath.Join("runs", "qising", "exactdiag")
+	// legacyRunDir is the default run directory before the exactdiag and mps sweeps were merged into the qising command.
+	legacyRunDir = filepath.Join("runs", "qising")
+)
+
+// Flags are the command line flags of the subcommands.
+type Flags struct {
+	RunDir     string
+	Workers    int
+	Lambda     float64
+	Streaming  bool
+	NPZ        bool
+	H5Path     string
+	ConfigPath string
+	Betas      string
+	// Progress is the interval of the progress log of building the hamiltonian, and 0 disables it.
+	Progress time.Duration
+	// Format is the format of the results printed to stdout, see emit.
+	Format string
+	// Log is whether gather reads the results log instead of scanning the config directories.
+	Log bool
+	// All is whether clean removes the whole run directory.
+	All bool
+}
+
+// Register defines the flags of the subcommand cmd in fs.
+func (f *Flags) Register(fs *flag.FlagSet, cmd string) {
+	fs.StringVar(&f.RunDir, "d", defaultRunDir, "run directory, whose default was "+legacyRunDir+" in previous versions, pass -d "+legacyRunDir+" to resume or gather the sweeps of those versions")
+	switch cmd {
+	case "solve":
+		fs.IntVar(&f.Workers, "workers", 1, "number of configurations solved concurrently")
+		fs.BoolVar(&f.Streaming, "streaming", false, "find the lowest eigenvalues with the Arnoldi iteration streaming the hamiltonian from disk, instead of with Python")
+		fs.BoolVar(&f.NPZ, "npz", false, "also write the eigenpairs to eig.npz, which is read in Python by numpy.load")
+		fs.DurationVar(&f.Progress, "progress", 10*time.Second, "interval of the progress log, with the estimated time remaining, of building the hamiltonian of each config, 0 disables it")
+		fs.StringVar(&f.ConfigPath, "config", "", "JSON or YAML file describing the sweep, see SweepConfig, which defaults to chains and square lattices of up to 25 spins")
+		f.registerReport(fs)
+	case "gather":
+		fs.BoolVar(&f.Log, "log", false, "read the results from the log "+fnameLog+", which solve appends to as configs complete, instead of scanning the config directories")
+		f.registerReport(fs)
+	case "clean":
+		fs.BoolVar(&f.All, "all", false, "remove the whole run directory, instead of only the unfinished configs")
+	}
+}
+
+func (f *Flags) registerReport(fs *flag.FlagSet) {
+	fs.Float64Var(&f.Lambda, "lambda", 0, "imaginary longitudinal field of the Yang-Lee Ising model, results are cached per run directory so use a separate one for each value")
+	fs.StringVar(&f.H5Path, "h5", "", "also write the results of all configurations to this HDF5 file, see writeH5")
+	fs.StringVar(&f.Betas, "betas", "", "comma separated inverse temperatures, at which thermal averages over the computed eigenvalues are written to thermal.csv in the run directory")
+	fs.StringVar(&f.Format, "format", emit.FormatCSV, "format of the results printed to stdout, csv or jsonl")
+}
+
+type Statistics struct {
+	n [2]int
+	h complex64
+	// dir is the directory of the results, which is set by gather.
+	dir string
+	// obs are the observables declared by the sweep config, and observables their values read by gather.
+	obs         []observable
+	observables []observableValue
+	// meta are the solver metadata read by gather, which are nil if unknown.
+	meta *solveMeta
+	exactdiag.Statistics
+}
+
+// eigenValue returns the i-th eigenvalue of s, whose parts are NaN if s has fewer eigenvalues.
+// Statistics written before the imaginary parts were recorded have none, which are zero since their hamiltonians were hermitian.
+func (s Statistics) eigenValue(i int) complex128 {
+	if i >= len(s.EigenValue) {
+		return complex(math.NaN(), math.NaN())
+	}
+	var im float64
+	if i < len(s.EigenValueImag) {
+		im = s.EigenValueImag[i]
+	}
+	return complex(s.EigenValue[i], im)
+}
+
+func getStatistics(dir string, n [2]int) error {
+	f, err := os.Open(filepath.Join(dir, fnameEigen))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer f.Close()
+	stats, err := exactdiag.ReadStatistics(n, f)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	b, err := json.Marshal(stats)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	mPath := filepath.Join(dir, fnameStatistics)
+	if err := os.WriteFile(mPath, b, 0644); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+func solveGround(f Flags, dir string, n [2]int, h complex64, interrupt <-chan struct{}) error {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	opt := exactdiag.NewIsingOptions().LongitudinalField(complex(0, float32(f.Lambda))).Interrupt(interrupt)
+	if f.Streaming {
+		// Without Python, the hamiltonian can be written in the binary format, which EigsDirStreaming memory maps for all applications.
+		opt = opt.WriteCOOOptions(mat.NewWriteCOOOptions().Format(mat.COOFormatBinary))
+	}
+	if f.Progress > 0 {
+		opt = opt.Progress(newProgress(fmt.Sprintf("%v %f hamiltonian", n, real(h)), f.Progress))
+	}
+	if err := exactdiag.TransverseFieldIsingExplicit(tmpDir, n, h, opt); err != nil {
+		return errors.Wrap(err, "")
+	}
+	var vv []mat.ValVec
+	meta := solveMeta{Solver: solverPython}
+	start := time.Now()
+	switch {
+	case f.Streaming:
+		// Three eigenvalues are reported.
+		var prof linalg.ArnoldiProfile
+		vv, err = mat.EigsDirStreaming(tmpDir, min(3, 1<<(n[0]*n[1])), linalg.NewArnoldiOptions().Profile(&prof).Interrupt(interrupt))
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		meta.Solver, meta.Iterations = solverStreaming, prof.Applications
+	default:
+		vv = mat.EigsDir(tmpDir)
+	}
+	meta.Seconds = time.Since(start).Seconds()
+	if meta.Variance, err = groundVariance(n, h, f.Lambda, vv[0]); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if err := writeEig(dir, vv); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if f.NPZ {
+		if err := mat.WriteNPZDir(dir, nil, vv); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	if err := writeSolveMeta(dir, meta); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// solve solves the config in dir if it is not solved yet, and returns whether its results changed.
+// Closing interrupt stops the Go solvers of the ground state, whose config is then solved again by the next run.
+func solve(f Flags, dir string, n [2]int, h complex64, obs []observable, interrupt <-chan struct{}) (bool, error) {
+	donePath := filepath.Join(dir, fnameDone)
+	if _, err := os.Stat(donePath); err == nil {
+		// Only the observables added to the sweep config since the config was solved are computed.
+		changed, err := observablesChanged(dir, obs)
+		if err != nil {
+			return false, errors.Wrap(err, "")
+		}
+		if changed {
+			if err := getObservables(dir, n, obs); err != nil {
+				return false, errors.Wrap(err, "")
+			}
+		}
+		return changed, nil
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return false, errors.Wrap(err, "")
+	}
+
+	if err := solveGround(f, dir, n, h, interrupt); err != nil {
+		return false, errors.Wrap(err, "")
+	}
+	if err := getStatistics(dir, n); err != nil {
+		return false, errors.Wrap(err, "")
+	}
+	if err := getObservables(dir, n, obs); err != nil {
+		return false, errors.Wrap(err, "")
+	}
+
+	if err := os.WriteFile(donePath, nil, 0644); err != nil {
+		return false, errors.Wrap(err, "")
+	}
+	return true, nil
+}
+
+// warnLegacy logs a warning if runDir is the default run directory, and the configs of a previous version are in legacyDir,
+// since they are not resumed nor gathered from the new default.
+func warnLegacy(runDir, legacyDir string) {
+	if runDir != defaultRunDir || !hasConfigs(legacyDir) {
+		return
+	}
+	log.Printf("%s has the configs of a previous version, whose default run directory was %s, pass -d %s to use them, or move them to %s", legacyDir, legacyDir, legacyDir, runDir)
+}
+
+// hasConfigs returns whether dir has the lattice directories of configDir.
+func hasConfigs(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		var n0, n1 int
+		if _, err := fmt.Sscanf(e.Name(), "%dx%d", &n0, &n1); err == nil && e.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// configDir returns the directory of the results of c.
+func configDir(runDir string, c Statistics) string {
+	nstr := fmt.Sprintf("%dx%d", c.n[0], c.n[1])
+	hstr := fmt.Sprintf("%f", real(c.h))
+	return filepath.Join(runDir, nstr, hstr)
+}
+
+// solveFunc solves a config, see solve.
+type solveFunc func(f Flags, dir string, n [2]int, h complex64, obs []observable, interrupt <-chan struct{}) (bool, error)
+
+// solveAll solves configs with a pool of workers, and appends the results of each completed config to the results log.
+// Errors are attributed to their configs, and the first error in the order of configs is returned.
+// When interrupt is closed, no more configs are started, and errInterrupted is returned after the ones in flight finish.
+func solveAll(f Flags, configs []Statistics, interrupt <-chan struct{}) error {
+	return solveAllWith(f, configs, interrupt, solve)
+}
+
+// solveAllWith is like solveAll, but solves each config with solveOne.
+func solveAllWith(f Flags, configs []Statistics, interrupt <-chan struct{}, solveOne solveFunc) error {
+	jobs := make(chan int)
+	errs := make([]error, len(configs))
+	rlog := newResultsLog(f.RunDir)
+	var wg sync.WaitGroup
+	for range max(f.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				c := configs[i]
+				dir := configDir(f.RunDir, c)
+				changed, err := solveOne(f, dir, c.n, c.h, c.obs, interrupt)
+				if err == nil && changed {
+					err = rlog.append(configEntry{n: c.n, h: c.h, dir: dir, done: true})
+				}
+				if err != nil {
+					errs[i] = errors.Wrap(err, fmt.Sprintf("%d %f", c.n, c.h))
+					log.Printf("%v %f failed", c.n, real(c.h))
+					continue
+				}
+				log.Printf("%v %f", c.n, real(c.h))
+			}
+		}()
+	}
+	var interrupted bool
+	for i := range configs {
+		select {
+		case jobs <- i:
+			continue
+		case <-interrupt:
+			interrupted = true
+		}
+		break
+	}
+	close(jobs)
+	wg.Wait()
+	// An interrupt after the last config was dispatched is seen only by the solves in flight.
+	select {
+	case <-interrupt:
+		interrupted = true
+	default:
+	}
+	for _, err := range errs {
+		if errors.Is(err, linalg.ErrInterrupted) {
+			interrupted = true
+		}
+	}
+
+	if interrupted {
+		// The solvers of the configs in flight may have been interrupted too, in which case they are solved again by the next run.
+		for _, err := range errs {
+			if err != nil {
+				log.Printf("%v", err)
+			}
+		}
+		return errInterrupted
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configEntry is the directory of the results of a config in a run directory.
+type configEntry struct {
+	n   [2]int
+	h   complex64
+	dir string
+	// done is whether the config is solved.
+	done bool
+}
+
+// scan returns the config directories in runDir, which are laid out as in configDir.
+func scan(runDir string) ([]configEntry, error) {
+	entries := make([]configEntry, 0)
+	nEntries, err := os.ReadDir(runDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	for _, nent := range nEntries {
+		// Skip files such as the thermal table and the interrupted marker.
+		if !nent.IsDir() {
+			continue
+		}
+		// Parse for lattice size.
+		nstr := strings.Split(nent.Name(), "x")
+		var n [2]int
+		for i, s := range nstr {
+			n[i], err = strconv.Atoi(s)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%#v", nent))
+			}
+		}
+
+		ndir := filepath.Join(runDir, nent.Name())
+		hEntries, err := os.ReadDir(ndir)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%#v", nent))
+		}
+		for _, hent := range hEntries {
+			hf, err := strconv.ParseFloat(hent.Name(), 64)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%#v %#v", nent, hent))
+			}
+			e := configEntry{n: n, h: complex(float32(hf), 0), dir: filepath.Join(ndir, hent.Name())}
+			if _, err := os.Stat(filepath.Join(e.dir, fnameDone)); err == nil {
+				e.done = true
+			}
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func gather(dir string) ([]Statistics, error) {
+	entries, err := scan(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	stats := make([]Statistics, 0, len(entries))
+	for _, e := range entries {
+		// Skip configs that are not solved yet, such as those of an interrupted run.
+		if !e.done {
+			continue
+		}
+		s, err := readConfig(e)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		stats = append(stats, s)
+	}
+	sortStatistics(stats)
+	return stats, nil
+}
+
+// readConfig reads the results of the solved config e.
+func readConfig(e configEntry) (Statistics, error) {
+	sb, err := os.ReadFile(filepath.Join(e.dir, fnameStatistics))
+	if err != nil {
+		return Statistics{}, errors.Wrap(err, e.dir)
+	}
+	s := Statistics{n: e.n, h: e.h, dir: e.dir}
+	if err := json.Unmarshal(sb, &s); err != nil {
+		return Statistics{}, errors.Wrap(err, e.dir)
+	}
+	s.observables, err = readObservables(e.dir)
+	if err != nil {
+		return Statistics{}, errors.Wrap(err, e.dir)
+	}
+	s.meta, err = readSolveMeta(e.dir)
+	if err != nil {
+		return Statistics{}, errors.Wrap(err, e.dir)
+	}
+	return s, nil
+}
+
+// sortStatistics sorts stats numerically, so that the output does not depend on the order results were written.
+func sortStatistics(stats []Statistics) {
+	slices.SortFunc(stats, func(a, b Statistics) int {
+		if c := cmp.Compare(a.n[0], b.n[0]); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.n[1], b.n[1]); c != 0 {
+			return c
+		}
+		return cmp.Compare(real(a.h), real(b.h))
+	})
+}
+
+func writeEig(dir string, vvs []mat.ValVec) error {
+	fpath := filepath.Join(dir, fnameEigen)
+	f, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	w := csv.NewWriter(f)
+
+	row := make([]string, len(vvs))
+	for j, vv := range vvs {
+		row[j] = strconv.FormatComplex(vv.Val, 'f', -1, 128)
+	}
+	if err1 := w.Write(row); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	for i := range len(vvs[0].Vec) {
+		for j, vv := range vvs {
+			row[j] = strconv.FormatComplex(vv.Vec[i], 'f', -1, 128)
+		}
+		if err1 := w.Write(row); err1 != nil && err == nil {
+			err = errors.Wrap(err1, "")
+			break
+		}
+	}
+
+	w.Flush()
+	if err1 := w.Error(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// Solve solves the configs of the sweep that are not solved yet, and reports the results of the run directory as Gather.
+func Solve(f Flags) error {
+	warnLegacy(f.RunDir, legacyRunDir)
+	betas, err := parseBetas(f.Betas)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := os.MkdirAll(f.RunDir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	sweep := defaultSweepConfig()
+	if f.ConfigPath != "" {
+		sweep, err = readSweepConfig(f.ConfigPath)
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	if sweep.Solver != "" {
+		f.Streaming = sweep.Solver == solverStreaming
+	}
+	configs, err := sweep.configs()
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	// Solve for the hamiltonian.
+	err = solveAll(f, configs, notifyInterrupt())
+	interrupted := errors.Is(err, errInterrupted)
+	if err != nil && !interrupted {
+		return errors.Wrap(err, "")
+	}
+	markerPath := filepath.Join(f.RunDir, fnameInterrupted)
+	switch {
+	case interrupted:
+		if err := writeInterrupted(f.RunDir, configs); err != nil {
+			return errors.Wrap(err, "")
+		}
+	default:
+		if err := os.Remove(markerPath); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "")
+		}
+	}
+
+	stats, err := gather(f.RunDir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := report(f, stats, betas); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if interrupted {
+		return errors.Errorf("interrupted after solving %d of %d configs, the unsolved ones are listed in %s", len(stats), len(configs), markerPath)
+	}
+	return nil
+}
+
+// Gather prints and plots the results of the solved configs in the run directory, and writes them to the HDF5 file and the thermal table if requested.
+// With f.Log, the results are read from the results log, which is cheap enough to run while a sweep is still solving, see resultsLog.
+func Gather(f Flags) error {
+	warnLegacy(f.RunDir, legacyRunDir)
+	betas, err := parseBetas(f.Betas)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	read := gather
+	if f.Log {
+		read = readLog
+	}
+	stats, err := read(f.RunDir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := report(f, stats, betas); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// Plot writes the plots of the solved configs in the run directory, see writePlots.
+func Plot(f Flags) error {
+	warnLegacy(f.RunDir, legacyRunDir)
+	stats, err := gather(f.RunDir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := writePlots(f.RunDir, stats); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// Stats recomputes the statistics and the declared observables of the solved configs in the run directory from their saved eigenvectors.
+func Stats(f Flags) error {
+	warnLegacy(f.RunDir, legacyRunDir)
+	entries, err := scan(f.RunDir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	rlog := newResultsLog(f.RunDir)
+	var count int
+	for _, e := range entries {
+		if !e.done {
+			continue
+		}
+		if err := getStatistics(e.dir, e.n); err != nil {
+			return errors.Wrap(err, e.dir)
+		}
+		// The observables are those declared when the config was solved, whose names are their expressions.
+		values, err := readObservables(e.dir)
+		if err != nil {
+			return errors.Wrap(err, e.dir)
+		}
+		obs := make([]observable, 0, len(values))
+		for _, v := range values {
+			o, err := parseObservable(v.Name)
+			if err != nil {
+				return errors.Wrap(err, e.dir)
+			}
+			obs = append(obs, o)
+		}
+		if len(obs) > 0 {
+			if err := getObservables(e.dir, e.n, obs); err != nil {
+				return errors.Wrap(err, e.dir)
+			}
+		}
+		if err := rlog.append(e); err != nil {
+			return errors.Wrap(err, e.dir)
+		}
+		count++
+	}
+	log.Printf("recomputed the statistics of %d configs in %s", count, f.RunDir)
+	return nil
+}
+
+// Clean removes the unfinished configs and the interrupted marker from the run directory, so that they are solved afresh by the next run.
+// With f.All, the whole run directory is removed.
+func Clean(f Flags) error {
+	warnLegacy(f.RunDir, legacyRunDir)
+	if f.All {
+		if err := os.RemoveAll(f.RunDir); err != nil {
+			return errors.Wrap(err, "")
+		}
+		return nil
+	}
+	entries, err := scan(f.RunDir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	var count int
+	for _, e := range entries {
+		if e.done {
+			continue
+		}
+		if err := os.RemoveAll(e.dir); err != nil {
+			return errors.Wrap(err, "")
+		}
+		count++
+	}
+	if err := os.Remove(filepath.Join(f.RunDir, fnameInterrupted)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "")
+	}
+	log.Printf("removed %d unfinished configs from %s", count, f.RunDir)
+	return nil
+}
+
+// report prints stats, writes their plots and observables, and writes them to the HDF5 file and the thermal table if requested.
+func report(f Flags, stats []Statistics, betas []float64) error {
+	if err := writeObservables(filepath.Join(f.RunDir, fnameObsTable), stats); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if f.H5Path != "" {
+		if err := writeH5(f.H5Path, stats, f.Lambda, betas); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	if len(betas) > 0 {
+		if err := writeThermal(filepath.Join(f.RunDir, fnameThermal), stats, betas); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	if err := writePlots(f.RunDir, stats); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if err := writeResults(os.Stdout, stats, f.Format); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// writeResults writes stats to w in format, see emit.
+// The mean-field and spin-wave predictions of the infinite lattice are overlaid for context, and the solver metadata follow.
+func writeResults(w io.Writer, stats []Statistics, format string) error {
+	ew, err := emit.NewWriter(w, format)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	for _, s := range stats {
+		dim := 2
+		if s.n[0] == 1 || s.n[1] == 1 {
+			dim = 1
+		}
+		h := float64(real(s.h))
+		e0, e1, e2 := s.eigenValue(0), s.eigenValue(1), s.eigenValue(2)
+		gap := real(e1) - real(e0)
+		var solver, iterations, variance, seconds any
+		if s.meta != nil {
+			solver, variance, seconds = s.meta.Solver, s.meta.Variance, s.meta.Seconds
+			if s.meta.Iterations > 0 {
+				iterations = s.meta.Iterations
+			}
+		}
+		record := []emit.Field{
+			{Name: "n0", Value: s.n[0]},
+			{Name: "n1", Value: s.n[1]},
+			{Name: "h", Value: h},
+			{Name: "e0", Value: real(e0)},
+			{Name: "e1", Value: real(e1)},
+			{Name: "e2", Value: real(e2)},
+			{Name: "e0i", Value: imag(e0)},
+			{Name: "e1i", Value: imag(e1)},
+			{Name: "e2i", Value: imag(e2)},
+			{Name: "m", Value: s.Magnetization},
+			{Name: "binder", Value: s.BinderCumulant},
+			{Name: "gap", Value: gap},
+			{Name: "m_mf", Value: analytic.MeanFieldMagnetization(dim, h)},
+			{Name: "m_sw", Value: analytic.SpinWaveMagnetization(dim, h)},
+			{Name: "gap_sw", Value: analytic.SpinWaveGap(dim, h)},
+			{Name: "solver", Value: solver},
+			{Name: "iterations", Value: iterations},
+			{Name: "variance", Value: variance},
+			{Name: "seconds", Value: seconds},
+			// Exact diagonalization truncates nothing.
+			{Name: "discarded", Value: 0.0},
+		}
+		if err := ew.Write(record); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	if err := ew.Flush(); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package edsweep
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag"
+	"github.com/fumin/qising/linalg"
+	"github.com/pkg/errors"
+)
+
+func TestWriteResults(t *testing.T) {
+	t.Parallel()
+	// The first config has the solver metadata of a streaming solve, and the second is a result of a previous version without them.
+	stats := []Statistics{
+		{
+			n:          [2]int{4, 1},
+			h:          1.5,
+			Statistics: exactdiag.Statistics{EigenValue: []float64{-6.5, -5.25, -4}, EigenValueImag: []float64{0, 0, 0.5}, Magnetization: 0.25, BinderCumulant: 0.5},
+			meta:       &solveMeta{Solver: solverStreaming, Iterations: 42, Seconds: 0.5, Variance: 1e-12},
+		},
+		{
+			n:          [2]int{2, 2},
+			h:          0.5,
+			Statistics: exactdiag.Statistics{EigenValue: []float64{-8, -7.5, -7}, EigenValueImag: []float64{0, 0, 0}, Magnetization: 0.75, BinderCumulant: 0.625},
+		},
+	}
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{
+			format: "csv",
+			golden: `n0,n1,h,e0,e1,e2,e0i,e1i,e2i,m,binder,gap,m_mf,m_sw,gap_sw,solver,iterations,variance,seconds,discarded
+4,1,1.5,-6.5,-5.25,-4,0,0,0.5,0.25,0.5,1.25,0.66143783,0.64455112,2.6457513,streaming,42,1e-12,0.5,0
+2,2,0.5,-8,-7.5,-7,0,0,0,0.75,0.625,0.5,0.99215674,0.99214917,7.9372539,,,,,0
+`,
+		},
+		{
+			format: "jsonl",
+			golden: `{"n0":4,"n1":1,"h":1.5,"e0":-6.5,"e1":-5.25,"e2":-4,"e0i":0,"e1i":0,"e2i":0.5,"m":0.25,"binder":0.5,"gap":1.25,"m_mf":0.66143783,"m_sw":0.64455112,"gap_sw":2.6457513,"solver":"streaming","iterations":42,"variance":1e-12,"seconds":0.5,"discarded":0}
+{"n0":2,"n1":2,"h":0.5,"e0":-8,"e1":-7.5,"e2":-7,"e0i":0,"e1i":0,"e2i":0,"m":0.75,"binder":0.625,"gap":0.5,"m_mf":0.99215674,"m_sw":0.99214917,"gap_sw":7.9372539,"solver":null,"iterations":null,"variance":null,"seconds":null,"discarded":0}
+`,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var b strings.Builder
+			if err := writeResults(&b, stats, test.format); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if b.String() != test.golden {
+				t.Fatalf("%s", b.String())
+			}
+		})
+	}
+}
+
+func TestGatherPrevious(t *testing.T) {
+	t.Parallel()
+	runDir := t.TempDir()
+	// Statistics written before the imaginary parts of the eigenvalues were recorded, and a lattice with fewer than three eigenvalues.
+	previous := []struct {
+		dir        string
+		statistics string
+	}{
+		{dir: "2x1/1.000000", statistics: `{"EigenValue":[-2.5,-1.5,-0.5],"Magnetization":0.5,"M2":0.25,"BinderCumulant":0.5}`},
+		{dir: "1x1/1.000000", statistics: `{"EigenValue":[-1,1],"Magnetization":0,"M2":0,"BinderCumulant":0}`},
+	}
+	for _, p := range previous {
+		dir := filepath.Join(runDir, p.dir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fnameStatistics), []byte(p.statistics), 0644); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fnameDone), nil, 0644); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+
+	stats, err := gather(runDir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	var b strings.Builder
+	if err := writeResults(&b, stats, "csv"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	golden := `n0,n1,h,e0,e1,e2,e0i,e1i,e2i,m,binder,gap,m_mf,m_sw,gap_sw,solver,iterations,variance,seconds,discarded
+1,1,1,-1,1,,0,0,,0,0,2,0.8660254,0.8624867,3.4641016,,,,,0
+2,1,1,-2.5,-1.5,-0.5,0,0,0,0.5,0.5,1,0.8660254,0.8624867,3.4641016,,,,,0
+`
+	if b.String() != golden {
+		t.Fatalf("%s", b.String())
+	}
+}
+
+func TestHasConfigs(t *testing.T) {
+	t.Parallel()
+	// The layout of previous versions has the lattice directories at the top, and the current one has the directories of the methods.
+	legacy, current := t.TempDir(), t.TempDir()
+	for _, dir := range []string{filepath.Join(legacy, "4x1", "1.000000"), filepath.Join(current, "exactdiag", "4x1", "1.000000"), filepath.Join(current, "mps")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	if !hasConfigs(legacy) {
+		t.Fatalf("expected configs")
+	}
+	if hasConfigs(current) || hasConfigs(filepath.Join(current, "missing")) {
+		t.Fatalf("unexpected configs")
+	}
+}
+
+func TestSolveAll(t *testing.T) {
+	t.Parallel()
+	configs := make([]Statistics, 0)
+	// The configs are in the order of gather.
+	for _, n := range [][2]int{{2, 1}, {2, 2}, {3, 1}} {
+		for _, h := range []complex64{0.5, 1, 1.5, 2} {
+			configs = append(configs, Statistics{n: n, h: h})
+		}
+	}
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d", workers), func(t *testing.T) {
+			t.Parallel()
+			runDir := t.TempDir()
+			f := Flags{RunDir: runDir, Workers: workers}
+			if err := solveAllWith(f, configs, make(chan struct{}), fakeSolve(nil)); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// The results do not depend on the number of workers, nor on the order the configs complete.
+			stats, err := gather(runDir)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(stats) != len(configs) {
+				t.Fatalf("%d %d", len(stats), len(configs))
+			}
+			for i, s := range stats {
+				c := configs[i]
+				if s.n != c.n || s.h != c.h || !reflect.DeepEqual(s.Statistics, fakeStatistics(c.n, c.h)) {
+					t.Fatalf("%d %#v %#v", i, s, c)
+				}
+			}
+			logged, err := readLog(runDir)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !reflect.DeepEqual(logged, stats) {
+				t.Fatalf("%#v %#v", logged, stats)
+			}
+		})
+	}
+}
+
+func TestSolveAllError(t *testing.T) {
+	t.Parallel()
+	configs := make([]Statistics, 0)
+	for _, h := range []complex64{0.5, 1, 1.5, 2, 2.5, 3} {
+		configs = append(configs, Statistics{n: [2]int{2, 1}, h: h})
+	}
+	errFake := errors.New("fake")
+	failed := map[complex64]bool{1.5: true, 2.5: true}
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d", workers), func(t *testing.T) {
+			t.Parallel()
+			runDir := t.TempDir()
+			f := Flags{RunDir: runDir, Workers: workers}
+			err := solveAllWith(f, configs, make(chan struct{}), fakeSolve(func(n [2]int, h complex64) error {
+				if failed[h] {
+					return errors.Wrap(errFake, fmt.Sprintf("%f", real(h)))
+				}
+				return nil
+			}))
+			// The first error in the order of configs is returned with its config.
+			if !errors.Is(err, errFake) {
+				t.Fatalf("%+v", err)
+			}
+			if want := fmt.Sprintf("%d %f", configs[2].n, configs[2].h); !strings.HasPrefix(err.Error(), want) {
+				t.Fatalf("%q %q", err.Error(), want)
+			}
+
+			// The other configs are solved.
+			stats, err := gather(runDir)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(stats) != len(configs)-len(failed) {
+				t.Fatalf("%d", len(stats))
+			}
+		})
+	}
+}
+
+func TestSolveAllInterruptInFlight(t *testing.T) {
+	t.Parallel()
+	configs := []Statistics{{n: [2]int{2, 1}, h: 1}}
+	runDir := t.TempDir()
+	f := Flags{RunDir: runDir, Workers: 4}
+	// The interrupt arrives after the only config was dispatched, and stops its solver.
+	interrupt := make(chan struct{})
+	err := solveAllWith(f, configs, interrupt, fakeSolve(func(n [2]int, h complex64) error {
+		close(interrupt)
+		return errors.Wrap(linalg.ErrInterrupted, "")
+	}))
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("%+v", err)
+	}
+}
+
+// fakeSolve returns a solve that writes the results of fakeStatistics without diagonalizing, or fails with the error of fail if not nil.
+func fakeSolve(fail func(n [2]int, h complex64) error) solveFunc {
+	return func(f Flags, dir string, n [2]int, h complex64, obs []observable, interrupt <-chan struct{}) (bool, error) {
+		if fail != nil {
+			if err := fail(n, h); err != nil {
+				return false, err
+			}
+		}
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return false, errors.Wrap(err, "")
+		}
+		b, err := json.Marshal(fakeStatistics(n, h))
+		if err != nil {
+			return false, errors.Wrap(err, "")
+		}
+		if err := os.WriteFile(filepath.Join(dir, fnameStatistics), b, 0644); err != nil {
+			return false, errors.Wrap(err, "")
+		}
+		if err := os.WriteFile(filepath.Join(dir, fnameDone), nil, 0644); err != nil {
+			return false, errors.Wrap(err, "")
+		}
+		return true, nil
+	}
+}
+
+// fakeStatistics returns statistics that identify the config of n and h.
+func fakeStatistics(n [2]int, h complex64) exactdiag.Statistics {
+	e0 := -float64(n[0]*n[1]) * float64(real(h))
+	return exactdiag.Statistics{EigenValue: []float64{e0, e0 + 1, e0 + 2}, EigenValueImag: []float64{0, 0, 0}, Magnetization: float64(real(h))}
+}
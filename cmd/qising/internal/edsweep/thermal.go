@@ -0,0 +1,95 @@
+package edsweep
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const fnameThermal = "thermal.csv"
+
+// parseBetas parses the comma separated inverse temperatures s.
+func parseBetas(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	betas := make([]float64, 0)
+	for _, f := range strings.Split(s, ",") {
+		beta, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		if !(beta > 0) {
+			return nil, errors.Errorf("%f", beta)
+		}
+		betas = append(betas, beta)
+	}
+	return betas, nil
+}
+
+// Thermal are the canonical averages at the inverse temperature beta over the computed eigenvalues.
+type Thermal struct {
+	beta float64
+	// e is the energy per site, and c the specific heat per site beta^2 (<E^2> - <E>^2) / N.
+	e, c float64
+	// tail is the Boltzmann weight of the highest computed eigenvalue.
+	// Since the spectrum is truncated, the averages are valid only when tail is negligible.
+	tail float64
+}
+
+// thermal returns the canonical averages over the real parts of the eigenvalues of s at each of betas.
+func thermal(s Statistics, betas []float64) []Thermal {
+	numSpins := float64(s.n[0] * s.n[1])
+	e0 := s.EigenValue[0]
+	ts := make([]Thermal, 0, len(betas))
+	for _, beta := range betas {
+		// Weights are relative to the ground state to avoid overflow.
+		var z, e, e2, last float64
+		for _, ek := range s.EigenValue {
+			last = math.Exp(-beta * (ek - e0))
+			z += last
+			e += last * ek
+			e2 += last * ek * ek
+		}
+		e, e2 = e/z, e2/z
+		ts = append(ts, Thermal{beta: beta, e: e / numSpins, c: beta * beta * (e2 - e*e) / numSpins, tail: last / z})
+	}
+	return ts
+}
+
+// writeThermal writes the (h, T) phase diagram of stats at betas to the CSV file fpath.
+func writeThermal(fpath string, stats []Statistics, betas []float64) error {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	w := csv.NewWriter(f)
+	if err1 := w.Write([]string{"n0", "n1", "h", "beta", "t", "e", "c", "tail"}); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	for _, s := range stats {
+		for _, th := range thermal(s, betas) {
+			row := []string{
+				strconv.Itoa(s.n[0]), strconv.Itoa(s.n[1]), fmt.Sprintf("%f", real(s.h)),
+				fmt.Sprintf("%f", th.beta), fmt.Sprintf("%f", 1/th.beta), fmt.Sprintf("%f", th.e), fmt.Sprintf("%f", th.c), fmt.Sprintf("%g", th.tail),
+			}
+			if err1 := w.Write(row); err1 != nil && err == nil {
+				err = errors.Wrap(err1, "")
+			}
+		}
+	}
+
+	w.Flush()
+	if err1 := w.Error(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
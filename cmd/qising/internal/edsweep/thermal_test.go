@@ -0,0 +1,107 @@
+package edsweep
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag"
+)
+
+func TestParseBetas(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		s     string
+		betas []float64
+		err   bool
+	}{
+		// Without betas, no thermal table is written.
+		{s: "", betas: nil},
+		{s: "0.5", betas: []float64{0.5}},
+		{s: "0.5, 1,2", betas: []float64{0.5, 1, 2}},
+		{s: "0.5,,2", err: true},
+		{s: " ", err: true},
+		{s: "-1", err: true},
+		{s: "0.5,0", err: true},
+		{s: "hot", err: true},
+		{s: "NaN", err: true},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			betas, err := parseBetas(test.s)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error %#v", betas)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !reflect.DeepEqual(betas, test.betas) {
+				t.Fatalf("%#v %#v", betas, test.betas)
+			}
+		})
+	}
+}
+
+func TestWriteThermal(t *testing.T) {
+	t.Parallel()
+	// The spectrum of the 2x1 lattice -Z0 Z1 - h (X0 + X1) is -r, -1, 1, r, where r = sqrt(1 + 4h^2).
+	hs := []float64{0.5, 1.5}
+	betas := []float64{0.25, 2}
+	stats := make([]Statistics, 0, len(hs))
+	for _, h := range hs {
+		r := math.Sqrt(1 + 4*h*h)
+		stats = append(stats, Statistics{n: [2]int{2, 1}, h: complex(float32(h), 0), Statistics: exactdiag.Statistics{EigenValue: []float64{-r, -1, 1, r}}})
+	}
+	fpath := filepath.Join(t.TempDir(), fnameThermal)
+	if err := writeThermal(fpath, stats, betas); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	f, err := os.Open(fpath)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if header := []string{"n0", "n1", "h", "beta", "t", "e", "c", "tail"}; !reflect.DeepEqual(records[0], header) {
+		t.Fatalf("%#v", records[0])
+	}
+	if len(records) != 1+len(hs)*len(betas) {
+		t.Fatalf("%d", len(records))
+	}
+	for i, h := range hs {
+		for j, beta := range betas {
+			// The closed form of the partition function Z = 2 cosh(beta r) + 2 cosh(beta), and its derivatives.
+			r := math.Sqrt(1 + 4*h*h)
+			z := 2*math.Cosh(beta*r) + 2*math.Cosh(beta)
+			e := -(2*r*math.Sinh(beta*r) + 2*math.Sinh(beta)) / z
+			e2 := (2*r*r*math.Cosh(beta*r) + 2*math.Cosh(beta)) / z
+			tail := math.Exp(-beta*r) / z
+			want := []float64{2, 1, h, beta, 1 / beta, e / 2, beta * beta * (e2 - e*e) / 2, tail}
+
+			record := records[1+i*len(betas)+j]
+			for k, w := range want {
+				got, err := strconv.ParseFloat(record[k], 64)
+				if err != nil {
+					t.Fatalf("%d %d %+v", i, j, err)
+				}
+				// The table is written in six decimals, or six significant digits.
+				if math.Abs(got-w) > 1e-5*max(1, math.Abs(w)) {
+					t.Fatalf("%d %d %d %f %f", i, j, k, got, w)
+				}
+			}
+		}
+	}
+}
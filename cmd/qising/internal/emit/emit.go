@@ -0,0 +1,142 @@
+// Package emit writes the results of the subcommands as CSV or JSON lines, so that downstream tools can filter them, such as by convergence.
+package emit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FormatCSV is a header followed by a row for each record.
+	FormatCSV = "csv"
+	// FormatJSONL is a JSON object for each record on its own line, whose keys are the names of the fields.
+	FormatJSONL = "jsonl"
+)
+
+// A Field is a named value of a record, which is an int, a bool, a string, a float32, a float64, or nil if it is not available.
+// Floats that are not finite are not available either, and are written as empty CSV cells or JSON nulls.
+type Field struct {
+	Name  string
+	Value any
+}
+
+// A Writer writes records in a format.
+// Every record of a Writer should have the same fields in the same order, which are the header of CSV.
+type Writer struct {
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+	header bool
+}
+
+// NewWriter returns a Writer of format to w.
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	switch format {
+	case FormatCSV, FormatJSONL:
+	default:
+		return nil, errors.Errorf("unknown format %q", format)
+	}
+	return &Writer{format: format, w: w, csv: csv.NewWriter(w)}, nil
+}
+
+// Write writes a record.
+func (w *Writer) Write(fields []Field) error {
+	if w.format == FormatJSONL {
+		if _, err := io.WriteString(w.w, jsonLine(fields)); err != nil {
+			return errors.Wrap(err, "")
+		}
+		return nil
+	}
+
+	if !w.header {
+		names := make([]string, 0, len(fields))
+		for _, f := range fields {
+			names = append(names, f.Name)
+		}
+		if err := w.csv.Write(names); err != nil {
+			return errors.Wrap(err, "")
+		}
+		w.header = true
+	}
+	row := make([]string, 0, len(fields))
+	for _, f := range fields {
+		row = append(row, cell(f.Value))
+	}
+	if err := w.csv.Write(row); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// jsonLine returns the JSON object of fields, in their order, terminated by a newline.
+func jsonLine(fields []Field) string {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(format(f.Name))
+		b.WriteString(":")
+		b.WriteString(format(f.Value))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// cell returns the CSV cell of v, which is empty if v is not available.
+func cell(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if c := format(v); c != "null" {
+		return c
+	}
+	return ""
+}
+
+// format returns the JSON of v, with float64s in eight significant digits, and float32s in the fewest digits that parse back to them.
+func format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "null"
+		}
+		return strconv.FormatFloat(v, 'g', 8, 64)
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return "null"
+		}
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("%q %+v", v, err))
+		}
+		return string(b)
+	default:
+		panic(fmt.Sprintf("%#v", v))
+	}
+}
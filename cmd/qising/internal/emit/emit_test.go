@@ -0,0 +1,100 @@
+package emit
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+	// The records have the solver metadata of a converged streaming solve, and of a result of a previous version whose metadata are unknown.
+	records := [][]Field{
+		{
+			{Name: "n0", Value: 4},
+			{Name: "h", Value: 1.5},
+			{Name: "e0", Value: -6.123456789},
+			{Name: "m", Value: float32(0.1)},
+			{Name: "twosite", Value: true},
+			{Name: "solver", Value: "streaming"},
+			{Name: "iterations", Value: 42},
+			{Name: "variance", Value: 1.25e-13},
+			{Name: "seconds", Value: 0.5},
+			{Name: "discarded", Value: 0.0},
+		},
+		{
+			{Name: "n0", Value: 9},
+			{Name: "h", Value: 0.25},
+			{Name: "e0", Value: math.Inf(-1)},
+			{Name: "m", Value: float32(math.NaN())},
+			{Name: "twosite", Value: false},
+			{Name: "solver", Value: nil},
+			{Name: "iterations", Value: nil},
+			{Name: "variance", Value: math.NaN()},
+			{Name: "seconds", Value: nil},
+			{Name: "discarded", Value: 0.0},
+		},
+		// Strings are quoted as needed.
+		{
+			{Name: "n0", Value: -1},
+			{Name: "h", Value: 1e20},
+			{Name: "e0", Value: 0.1},
+			{Name: "m", Value: float32(1e-8)},
+			{Name: "twosite", Value: false},
+			{Name: "solver", Value: `a "b", c`},
+			{Name: "iterations", Value: 0},
+			{Name: "variance", Value: -2.5e-7},
+			{Name: "seconds", Value: 3.0},
+			{Name: "discarded", Value: 1e-9},
+		},
+	}
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{
+			format: FormatCSV,
+			golden: `n0,h,e0,m,twosite,solver,iterations,variance,seconds,discarded
+4,1.5,-6.1234568,0.1,true,streaming,42,1.25e-13,0.5,0
+9,0.25,,,false,,,,,0
+-1,1e+20,0.1,1e-08,false,"a ""b"", c",0,-2.5e-07,3,1e-09
+`,
+		},
+		{
+			format: FormatJSONL,
+			golden: `{"n0":4,"h":1.5,"e0":-6.1234568,"m":0.1,"twosite":true,"solver":"streaming","iterations":42,"variance":1.25e-13,"seconds":0.5,"discarded":0}
+{"n0":9,"h":0.25,"e0":null,"m":null,"twosite":false,"solver":null,"iterations":null,"variance":null,"seconds":null,"discarded":0}
+{"n0":-1,"h":1e+20,"e0":0.1,"m":1e-08,"twosite":false,"solver":"a \"b\", c","iterations":0,"variance":-2.5e-07,"seconds":3,"discarded":1e-09}
+`,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var b strings.Builder
+			w, err := NewWriter(&b, test.format)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for _, r := range records {
+				if err := w.Write(r); err != nil {
+					t.Fatalf("%+v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if b.String() != test.golden {
+				t.Fatalf("%s", b.String())
+			}
+		})
+	}
+}
+
+func TestNewWriterError(t *testing.T) {
+	t.Parallel()
+	if _, err := NewWriter(&strings.Builder{}, "tsv"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
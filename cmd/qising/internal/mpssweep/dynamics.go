@@ -0,0 +1,139 @@
+package mpssweep
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/fumin/qising/mps"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// Quench are the parameters of the quench of the dynamics subcommand.
+type Quench struct {
+	L int
+	// H0 is the field of the initial ground state, and H1 the field of the evolution.
+	H0, H1  float64
+	Dt      float64
+	Steps   int
+	BondDim int
+}
+
+func (q *Quench) register(fs *flag.FlagSet) {
+	fs.IntVar(&q.L, "l", 32, "length of the chain")
+	fs.Float64Var(&q.H0, "h0", 0.5, "transverse field of the initial ground state")
+	fs.Float64Var(&q.H1, "h1", 2, "transverse field after the quench")
+	fs.Float64Var(&q.Dt, "dt", 0.05, "time step of TEBD")
+	fs.IntVar(&q.Steps, "steps", 100, "number of time steps")
+	fs.IntVar(&q.BondDim, "b", 64, "maximum bond dimension of the ground state search and the evolution")
+}
+
+// dynamicsPath is the file in the run directory of the time series of the quench q.
+func dynamicsPath(runDir string, q Quench) string {
+	return filepath.Join(runDir, fmt.Sprintf("dynamics_%d_%f_%f_%d.csv", q.L, q.H0, q.H1, q.BondDim))
+}
+
+// Dynamics prepares the ground state at the field H0 of the flags, quenches the field to H1, and evolves the state with TEBD.
+// The time series of the magnetization, the Loschmidt echo and the entanglement entropy are written to a CSV file in the run directory.
+// Since the ground state of the ordered phase of a finite chain is the symmetric superposition, whose magnetization m vanishes, m2 = <M^2>/l^2 is also written.
+// The Loschmidt echo L(t) = |<psi(0)|psi(t)>|^2 is written along with its rate -ln L(t) / l, whose kinks in time are the dynamical quantum phase transitions.
+// The weight discarded by the truncations of TEBD so far is written as well, which bounds the error of the state, and tells when the bond dimension becomes inadequate.
+// See M. Heyl, A. Polkovnikov and S. Kehrein, Dynamical Quantum Phase Transitions in the Transverse-Field Ising Model, Phys. Rev. Lett. 110, 135704 (2013).
+func Dynamics(f Flags) error {
+	q := f.Quench
+	if q.L < 2 || q.Steps < 0 || !(q.Dt > 0) || q.BondDim < 1 {
+		return errors.Errorf("%#v", q)
+	}
+	if err := os.MkdirAll(f.RunDir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "")
+	}
+	bufs := make([]*tensor.Dense, 0)
+	for _ = range 10 {
+		bufs = append(bufs, tensorPool.Get(1))
+	}
+	defer tensorPool.Release(bufs...)
+
+	// Search for the ground state before the quench.
+	n := [2]int{q.L, 1}
+	h0 := mps.Ising(n, complex(float32(q.H0), 0))
+	fs := make([]*tensor.Dense, 0, len(h0))
+	for _ = range h0 {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	psi0 := mps.RandMPS(h0, q.BondDim)
+	opt := mps.NewSearchGroundStateOptions().Tol(1e-6).MaxBondDim(q.BondDim).Pool(tensorPool)
+	if err := mps.SearchGroundState(fs, h0, psi0, [10]*tensor.Dense(bufs), opt); err != nil {
+		return errors.Wrap(err, "")
+	}
+	norm := mps.InnerProduct(psi0, psi0, [2]*tensor.Dense(bufs[:2]))
+	psi0[0].Mul(complex(float32(1/math.Sqrt(float64(real(norm)))), 0))
+
+	// Evolve a copy of the ground state, since TEBD evolves in place.
+	psi := make([]*tensor.Dense, 0, len(psi0))
+	for _, m := range psi0 {
+		psi = append(psi, tensor.Zeros(m.Shape()...).Set([]int{0, 0, 0}, m))
+	}
+	hs := mps.IsingBonds(q.L, complex(float32(q.H1), 0))
+	report := mps.NewTruncationReport(q.L)
+	tebdOpt := mps.NewTEBDOptions().MaxBondDim(q.BondDim).TruncationReport(report)
+
+	fpath := dynamicsPath(f.RunDir, q)
+	file, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	w := csv.NewWriter(file)
+	if err1 := w.Write([]string{"t", "m", "m2", "loschmidt", "rate", "entropy", "discarded"}); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	for step := 0; step <= q.Steps && err == nil; step++ {
+		if step > 0 {
+			if err1 := mps.TEBD(psi, hs, float32(q.Dt), 1, [10]*tensor.Dense(bufs), tebdOpt); err1 != nil {
+				err = errors.Wrap(err1, fmt.Sprintf("%d", step))
+				break
+			}
+		}
+		row, err1 := observeQuench(psi0, psi, float64(step)*q.Dt, [5]*tensor.Dense(bufs[:5]))
+		if err1 != nil {
+			err = errors.Wrap(err1, fmt.Sprintf("%d", step))
+			break
+		}
+		row = append(row, strconv.FormatFloat(report.Total(), 'g', 8, 64))
+		if err1 := w.Write(row); err1 != nil {
+			err = errors.Wrap(err1, "")
+		}
+	}
+
+	w.Flush()
+	if err1 := w.Error(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := file.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("wrote %d steps to %s", q.Steps, fpath)
+	return nil
+}
+
+// observeQuench returns the CSV row at time t of the normalized state psi, which started from psi0.
+func observeQuench(psi0, psi []*tensor.Dense, t float64, bufs [5]*tensor.Dense) ([]string, error) {
+	stats := mps.Statistics(psi, [2]*tensor.Dense(bufs[:2]))
+	overlap := mps.InnerProduct(psi0, psi, [2]*tensor.Dense(bufs[:2]))
+	echo := float64(real(overlap)*real(overlap) + imag(overlap)*imag(overlap))
+	entropy, err := mps.EntanglementEntropy(psi, len(psi)/2, bufs)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'g', 8, 64) }
+	rate := -math.Log(echo) / float64(len(psi))
+	return []string{format(t), format(stats.M), format(stats.M2), format(echo), format(rate), format(float64(entropy))}, nil
+}
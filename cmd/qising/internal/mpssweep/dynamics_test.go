@@ -0,0 +1,72 @@
+package mpssweep
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag"
+	"github.com/fumin/qising/exactdiag/mat"
+)
+
+func TestDynamics(t *testing.T) {
+	t.Parallel()
+	// The initial state is in the paramagnetic phase, whose ground state is unique.
+	q := Quench{L: 6, H0: 1.5, H1: 0.5, Dt: 0.05, Steps: 4, BondDim: 8}
+	f := Flags{RunDir: t.TempDir(), Quench: q}
+	if err := Dynamics(f); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	file, err := os.Open(dynamicsPath(f.RunDir, q))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if header := []string{"t", "m", "m2", "loschmidt", "rate", "entropy", "discarded"}; !reflect.DeepEqual(records[0], header) {
+		t.Fatalf("%#v", records[0])
+	}
+	if len(records) != 1+q.Steps+1 {
+		t.Fatalf("%d", len(records))
+	}
+
+	// The row at t=0 is the initial ground state, whose magnetization is that of exact diagonalization.
+	row := make([]float64, 0, len(records[1]))
+	for _, cell := range records[1] {
+		v, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		row = append(row, v)
+	}
+	n := [2]int{q.L, 1}
+	hamiltonian, buf := mat.M([][]complex64{{0}}), mat.M([][]complex64{{0}})
+	exactdiag.TransverseFieldIsing(hamiltonian, buf, n, complex(float32(q.H0), 0))
+	want, err := exactdiag.GetStatistics(n, hamiltonian.COO().Eigen()[:1])
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if row[0] != 0 {
+		t.Fatalf("%f", row[0])
+	}
+	// The magnetization vanishes by the spin flip symmetry.
+	if math.Abs(row[1]) > 1e-3 {
+		t.Fatalf("%f", row[1])
+	}
+	if math.Abs(row[2]-want.M2) > 1e-3 {
+		t.Fatalf("%f %f", row[2], want.M2)
+	}
+	if math.Abs(row[3]-1) > 1e-4 || math.Abs(row[4]) > 1e-4 {
+		t.Fatalf("%f %f", row[3], row[4])
+	}
+	if row[6] != 0 {
+		t.Fatalf("%g", row[6])
+	}
+}
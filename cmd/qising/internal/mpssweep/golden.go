@@ -0,0 +1,172 @@
+package mpssweep
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fumin/qising/cmd/qising/internal/emit"
+	"github.com/pkg/errors"
+)
+
+const goldenSeed = 1
+
+var (
+	//go:embed golden.csv
+	goldenCSV string
+)
+
+// newGoldenConfigs returns a small fixed suite of seeded configs, which runs in seconds.
+func newGoldenConfigs() []Config {
+	configs := make([]Config, 0)
+	for _, h := range []float32{0.5, 1.5, 3} {
+		configs = append(configs, Config{l: 8, h: complex(h, 0), bondDim: 2, tol: 1e-4, seed: goldenSeed})
+		configs = append(configs, Config{l: 8, h: complex(h, 0), bondDim: 4, tol: 1e-5, seed: goldenSeed})
+		configs = append(configs, Config{l: 8, h: complex(h, 0), bondDim: 8, tol: 1e-6, seed: goldenSeed, twoSite: true})
+	}
+	return configs
+}
+
+// goldenTolerance is the absolute tolerance of each emitted number.
+// The tolerances are a few orders of magnitude above float32 roundoff,
+// so that reordering floating point operations passes while accuracy regressions do not.
+var goldenTolerance = struct {
+	e0     float64
+	m      float64
+	binder float64
+}{e0: 1e-4, m: 1e-4, binder: 1e-3}
+
+func golden(updatePath string) error {
+	configs := newGoldenConfigs()
+	statistics := make([]Statistics, 0, len(configs))
+	for _, cfg := range configs {
+		stat, err := solve(cfg)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%#v", cfg))
+		}
+		statistics = append(statistics, stat)
+	}
+
+	if updatePath != "" {
+		var b strings.Builder
+		if err := writeStatistics(&b, statistics, emit.FormatCSV); err != nil {
+			return errors.Wrap(err, "")
+		}
+		if err := os.WriteFile(updatePath, []byte(b.String()), 0644); err != nil {
+			return errors.Wrap(err, "")
+		}
+		return nil
+	}
+
+	want, err := readGolden(goldenCSV)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if len(want) != len(statistics) {
+		return errors.Errorf("%d %d", len(want), len(statistics))
+	}
+	var failed int
+	for i, s := range statistics {
+		w := want[i]
+		if w.cfg.l != s.cfg.l || w.cfg.h != s.cfg.h || w.cfg.bondDim != s.cfg.bondDim || w.cfg.twoSite != s.cfg.twoSite {
+			return errors.Errorf("%d %#v %#v", i, w.cfg, s.cfg)
+		}
+		if diff := math.Abs(float64(s.e0 - w.e0)); diff > goldenTolerance.e0 {
+			log.Printf("FAIL %#v e0 %f want %f diff %g", s.cfg, s.e0, w.e0, diff)
+			failed++
+		}
+		if diff := math.Abs(float64(s.m - w.m)); diff > goldenTolerance.m {
+			log.Printf("FAIL %#v m %f want %f diff %g", s.cfg, s.m, w.m, diff)
+			failed++
+		}
+		if diff := math.Abs(float64(s.binder - w.binder)); diff > goldenTolerance.binder {
+			log.Printf("FAIL %#v binder %f want %f diff %g", s.cfg, s.binder, w.binder, diff)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d mismatches", failed)
+	}
+	log.Printf("golden: %d configs ok", len(statistics))
+	return nil
+}
+
+// readGolden reads the CSV written by writeStatistics, whose solver metadata are unknown if it predates them.
+func readGolden(s string) ([]Statistics, error) {
+	records, err := csv.NewReader(strings.NewReader(s)).ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if len(records) == 0 {
+		return nil, errors.Errorf("empty")
+	}
+
+	stats := make([]Statistics, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if len(record) != 7 && len(record) != 11 {
+			return nil, errors.Errorf("%d %#v", i, record)
+		}
+		s := Statistics{variance: math.NaN(), seconds: math.NaN(), discarded: math.NaN()}
+		var h, e0, m, binder float64
+		var err error
+		if s.cfg.l, err = strconv.Atoi(record[0]); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		if h, err = strconv.ParseFloat(record[1], 32); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		if s.cfg.bondDim, err = strconv.Atoi(record[2]); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		if s.cfg.twoSite, err = strconv.ParseBool(record[3]); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		if e0, err = parseCell(record[4], 32); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		if m, err = parseCell(record[5], 32); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		if binder, err = parseCell(record[6], 32); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		s.cfg.h = complex(float32(h), 0)
+		s.e0, s.m, s.binder = float32(e0), float32(m), float32(binder)
+
+		if len(record) == 11 {
+			if record[7] != "" {
+				if s.sweeps, err = strconv.Atoi(record[7]); err != nil {
+					return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+				}
+			}
+			if s.variance, err = parseCell(record[8], 64); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+			}
+			if s.seconds, err = parseCell(record[9], 64); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+			}
+			if s.discarded, err = parseCell(record[10], 64); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+			}
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// parseCell parses the float of a CSV cell, which is NaN if the cell is empty.
+func parseCell(cell string, bitSize int) (float64, error) {
+	if cell == "" {
+		return math.NaN(), nil
+	}
+	v, err := strconv.ParseFloat(cell, bitSize)
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	return v, nil
+}
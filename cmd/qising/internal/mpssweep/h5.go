@@ -0,0 +1,83 @@
+package mpssweep
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fumin/qising/h5"
+	"github.com/pkg/errors"
+)
+
+// writeH5 writes the results of a sweep to the HDF5 file fpath, in the group <l>_<h>_<b> of each configuration.
+// A group has the attributes l, h, bond_dim, tol, seed and two_site, and the datasets e0, m and binder_cumulant.
+// If the ground state is available, its subgroup state holds the site tensors of the ground state, named by their zero padded sites, with the axes left, up and right.
+func writeH5(fpath string, stats []Statistics) error {
+	root := h5.NewGroup()
+	for _, s := range stats {
+		cfg := s.cfg
+		g := root.Group(fmt.Sprintf("%d_%f_%d", cfg.l, real(cfg.h), cfg.bondDim))
+		if err := writeH5Group(g, s); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%#v", cfg))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := h5.WriteFile(fpath, root); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+func writeH5Group(g *h5.Group, s Statistics) error {
+	var twoSite int
+	if s.cfg.twoSite {
+		twoSite = 1
+	}
+	attrs := []struct {
+		name string
+		v    any
+	}{
+		{name: "l", v: s.cfg.l},
+		{name: "h", v: real(s.cfg.h)},
+		{name: "bond_dim", v: s.cfg.bondDim},
+		{name: "tol", v: s.cfg.tol},
+		{name: "seed", v: int64(s.cfg.seed)},
+		{name: "two_site", v: twoSite},
+	}
+	for _, a := range attrs {
+		if err := g.SetAttr(a.name, a.v); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	datasets := []struct {
+		name string
+		v    any
+	}{
+		{name: "e0", v: s.e0},
+		{name: "m", v: s.m},
+		{name: "binder_cumulant", v: s.binder},
+	}
+	for _, d := range datasets {
+		if err := g.SetDataset(d.name, d.v); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+
+	if s.state == nil {
+		return nil
+	}
+	state := g.Group("state")
+	for i, m := range s.state {
+		data := make([]complex64, 0, m.Shape()[0]*m.Shape()[1]*m.Shape()[2])
+		for _, v := range m.All() {
+			data = append(data, v)
+		}
+		if err := state.SetDataset(fmt.Sprintf("%03d", i), data, m.Shape()...); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package mpssweep
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyInterrupt returns a channel that is closed on the first SIGINT or SIGTERM.
+// Later signals exit immediately as usual.
+func notifyInterrupt() <-chan struct{} {
+	interrupt := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		signal.Stop(sigs)
+		log.Printf("%v, stopping after the current iteration, signal again to exit immediately", sig)
+		close(interrupt)
+	}()
+	return interrupt
+}
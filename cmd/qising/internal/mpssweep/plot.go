@@ -0,0 +1,47 @@
+package mpssweep
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/fumin/qising/plot"
+	"github.com/pkg/errors"
+)
+
+const (
+	// fnamePlotMagnetization and fnamePlotBinder are the plots in the run directory.
+	fnamePlotMagnetization = "magnetization.svg"
+	fnamePlotBinder        = "binder.svg"
+)
+
+// writePlots writes the plots of the magnetization and the Binder cumulant against the field to runDir,
+// with a curve for each chain length, bond dimension and search.
+func writePlots(runDir string, statistics []Statistics) error {
+	mPlot := &plot.Plot{Title: "Magnetization", XLabel: "h", YLabel: "m", LogX: true}
+	bPlot := &plot.Plot{Title: "Binder cumulant", XLabel: "h", YLabel: "U", LogX: true}
+	for _, s := range statistics {
+		name := fmt.Sprintf("l=%d b=%d", s.cfg.l, s.cfg.bondDim)
+		if s.cfg.twoSite {
+			name += " 2-site"
+		}
+		i := slices.IndexFunc(mPlot.Series, func(c plot.Series) bool { return c.Name == name })
+		if i < 0 {
+			mPlot.Series = append(mPlot.Series, plot.Series{Name: name})
+			bPlot.Series = append(bPlot.Series, plot.Series{Name: name})
+			i = len(mPlot.Series) - 1
+		}
+		h := float64(real(s.cfg.h))
+		m, b := &mPlot.Series[i], &bPlot.Series[i]
+		m.X, m.Y = append(m.X, h), append(m.Y, float64(s.m))
+		b.X, b.Y = append(b.X, h), append(b.Y, float64(s.binder))
+	}
+
+	if err := plot.WriteFile(filepath.Join(runDir, fnamePlotMagnetization), mPlot); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := plot.WriteFile(filepath.Join(runDir, fnamePlotBinder), bPlot); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package mpssweep
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/fumin/qising/cmd/qising/internal/emit"
+	"github.com/fumin/qising/mps"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// fnameResults is the file in the run directory holding the results of the completed configs, in the format of writeStatistics.
+// The configs in it are skipped by later runs, so that an interrupted run resumes where it stopped, until clean -all removes it.
+const fnameResults = "results.csv"
+
+// resultKey identifies the results of cfg in the results file.
+func resultKey(cfg Config) string {
+	return fmt.Sprintf("%d_%f_%d_%t", cfg.l, real(cfg.h), cfg.bondDim, cfg.twoSite)
+}
+
+// readResults reads the results file fpath by the keys of the results, returning nothing if it does not exist.
+func readResults(fpath string) (map[string]Statistics, error) {
+	stats, err := readResultsFile(fpath)
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	solved := make(map[string]Statistics, len(stats))
+	for _, s := range stats {
+		solved[resultKey(s.cfg)] = s
+	}
+	log.Printf("skipping %d completed configs in %s", len(solved), fpath)
+	return solved, nil
+}
+
+// readResultsFile reads the results in the file fpath in the order they are written.
+func readResultsFile(fpath string) ([]Statistics, error) {
+	b, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	stats, err := readGolden(string(b))
+	if err != nil {
+		return nil, errors.Wrap(err, fpath)
+	}
+	return stats, nil
+}
+
+// writeResults writes stats to the results file fpath, through a temporary file so that an interruption never leaves it partially written.
+func writeResults(fpath string, stats []Statistics) error {
+	var b strings.Builder
+	if err := writeStatistics(&b, stats, emit.FormatCSV); err != nil {
+		return errors.Wrap(err, "")
+	}
+	tmp := fpath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := os.Rename(tmp, fpath); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// loadState loads the ground state saved by saveState to fpath.
+func loadState(fpath string) ([]*tensor.Dense, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	defer f.Close()
+	state, err := mps.Load(f)
+	if err != nil {
+		return nil, errors.Wrap(err, fpath)
+	}
+	return state, nil
+}
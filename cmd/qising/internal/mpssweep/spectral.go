@@ -0,0 +1,135 @@
+package mpssweep
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/fumin/qising/mps"
+	"github.com/fumin/qising/plot"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// Spectrum are the parameters of the spectral subcommand.
+type Spectrum struct {
+	L       int
+	H       float64
+	BondDim int
+	Moments int
+	// OmegaMax and DOmega are the range and step of the excitation energies.
+	OmegaMax, DOmega float64
+}
+
+func (s *Spectrum) register(fs *flag.FlagSet) {
+	fs.IntVar(&s.L, "l", 32, "length of the chain")
+	fs.Float64Var(&s.H, "h", 2, "transverse field")
+	fs.IntVar(&s.BondDim, "b", 64, "maximum bond dimension of the ground state search and the Chebyshev vectors")
+	fs.IntVar(&s.Moments, "moments", 128, "number of Chebyshev moments, which determines the energy resolution")
+	fs.Float64Var(&s.OmegaMax, "omega-max", 8, "largest excitation energy")
+	fs.Float64Var(&s.DOmega, "domega", 0.02, "step of the excitation energies")
+}
+
+// spectralPath is the file in the run directory of the structure factor of s, with the given extension.
+func spectralPath(runDir string, s Spectrum, ext string) string {
+	return filepath.Join(runDir, fmt.Sprintf("spectral_%d_%f_%d_%d.%s", s.L, s.H, s.BondDim, s.Moments, ext))
+}
+
+// Spectral computes the dynamic structure factor S(k, omega) of the Z spins of the ground state of the transverse field Ising chain, see mps.DynamicStructureFactor.
+// The structure factor is written to a CSV file in the run directory with a row for each k and omega, which plots as a heatmap,
+// and its peak for each k is plotted against the quasiparticle dispersion 2*sqrt(1 + h^2 - 2*h*cos(k)) of the paramagnetic phase.
+func Spectral(f Flags) error {
+	s := f.Spectrum
+	if s.L < 2 || s.Moments < 2 || !(s.OmegaMax > 0) || !(s.DOmega > 0) || s.BondDim < 1 {
+		return errors.Errorf("%#v", s)
+	}
+	if err := os.MkdirAll(f.RunDir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "")
+	}
+	bufs := make([]*tensor.Dense, 0)
+	for _ = range 10 {
+		bufs = append(bufs, tensorPool.Get(1))
+	}
+	defer tensorPool.Release(bufs...)
+
+	n := [2]int{s.L, 1}
+	ws := mps.Ising(n, complex(float32(s.H), 0))
+	fs := make([]*tensor.Dense, 0, len(ws))
+	for _ = range ws {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	ground := mps.RandMPS(ws, s.BondDim)
+	opt := mps.NewSearchGroundStateOptions().Tol(1e-6).MaxBondDim(s.BondDim).Pool(tensorPool)
+	if err := mps.SearchGroundState(fs, ws, ground, [10]*tensor.Dense(bufs), opt); err != nil {
+		return errors.Wrap(err, "")
+	}
+	norm := mps.InnerProduct(ground, ground, [2]*tensor.Dense(bufs[:2]))
+	ground[0].Mul(complex(float32(1/math.Sqrt(float64(real(norm)))), 0))
+	e0, err := mps.Moment(ws, ground, 1, [10]*tensor.Dense(bufs))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	omegas := make([]float64, 0)
+	for i := 0; float64(i)*s.DOmega <= s.OmegaMax; i++ {
+		omegas = append(omegas, float64(i)*s.DOmega)
+	}
+	pauliZ := [][]complex64{{1, 0}, {0, -1}}
+	spectralOpt := mps.NewSpectralOptions().NumMoments(s.Moments).MaxBondDim(s.BondDim)
+	sf, err := mps.DynamicStructureFactor(ws, ground, float64(real(e0)), pauliZ, omegas, [10]*tensor.Dense(bufs), spectralOpt)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if err := writeStructureFactor(spectralPath(f.RunDir, s, "csv"), sf); err != nil {
+		return errors.Wrap(err, "")
+	}
+	p := &plot.Plot{Title: fmt.Sprintf("Dispersion h=%g", s.H), XLabel: "k", YLabel: "omega"}
+	peaks := plot.Series{Name: fmt.Sprintf("peak of S l=%d b=%d", s.L, s.BondDim), X: sf.Ks, Y: sf.Dispersion(), NoLine: true}
+	exact := plot.Series{Name: "quasiparticle"}
+	for _, k := range sf.Ks {
+		exact.X = append(exact.X, k)
+		exact.Y = append(exact.Y, 2*math.Sqrt(1+s.H*s.H-2*s.H*math.Cos(k)))
+	}
+	p.Series = []plot.Series{peaks, exact}
+	fpath := spectralPath(f.RunDir, s, "svg")
+	if err := plot.WriteFile(fpath, p); err != nil {
+		return errors.Wrap(err, "")
+	}
+	log.Printf("wrote %d momenta to %s", len(sf.Ks), fpath)
+	return nil
+}
+
+// writeStructureFactor writes the structure factor to fpath in CSV, with a row for each momentum and excitation energy.
+func writeStructureFactor(fpath string, sf mps.StructureFactor) error {
+	file, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	w := csv.NewWriter(file)
+	if err1 := w.Write([]string{"k", "omega", "s"}); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'g', 8, 64) }
+	for i, k := range sf.Ks {
+		for j, omega := range sf.Omegas {
+			if err1 := w.Write([]string{format(k), format(omega), format(sf.S[i][j])}); err1 != nil && err == nil {
+				err = errors.Wrap(err1, "")
+			}
+		}
+	}
+
+	w.Flush()
+	if err1 := w.Error(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := file.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
@@ -0,0 +1,569 @@
+package mpssweep
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fumin/qising/cmd/qising/internal/emit"
+	"github.com/fumin/qising/mps"
+	"github.com/fumin/qising/pool"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// tensorPool is shared across configs, so that buffers are reused from one search to the next.
+var tensorPool = pool.New()
+
+// Flags are the command line flags of the subcommands.
+type Flags struct {
+	RunDir          string
+	Golden          bool
+	GoldenUpdate    string
+	SaveStates      bool
+	CheckpointEvery int
+	Profile         bool
+	IDMRG           bool
+	Continuation    bool
+	Noise           float64
+	H5Path          string
+	// Format is the format of the results printed to stdout, see emit.
+	Format string
+	// All is whether clean removes the whole run directory.
+	All bool
+	// Quench are the parameters of the dynamics subcommand.
+	Quench Quench
+	// Spectrum are the parameters of the spectral subcommand.
+	Spectrum Spectrum
+}
+
+// Register defines the flags of the subcommand cmd in fs.
+func (f *Flags) Register(fs *flag.FlagSet, cmd string) {
+	fs.StringVar(&f.RunDir, "d", filepath.Join("runs", "qising", "mps"), "run directory, whose default was runs/qising in previous versions, pass -d runs/qising to resume the sweeps of those versions")
+	switch cmd {
+	case "solve":
+		fs.BoolVar(&f.Golden, "golden", false, "run a small seeded suite and compare against the stored golden.csv")
+		fs.StringVar(&f.GoldenUpdate, "golden-update", "", "in golden mode, write results to this path instead of comparing")
+		fs.BoolVar(&f.SaveStates, "save-states", false, "save the ground states to the run directory, from which stats recomputes the observables")
+		fs.IntVar(&f.CheckpointEvery, "checkpoint-every", 0, "save a checkpoint to the run directory every this many sweeps, and resume from it, 0 saves only when interrupted")
+		fs.BoolVar(&f.Profile, "profile", false, "log the time spent in each phase of the search")
+		fs.BoolVar(&f.IDMRG, "idmrg", false, "compute bulk quantities of the infinite chain with the infinite DMRG, in which case l is reported as 0")
+		fs.BoolVar(&f.Continuation, "continuation", false, "start each search from the ground state of the previous field of the same length and bond dimension, instead of a random state; resuming a run continues from the fields solved by earlier runs only with -save-states")
+		fs.Float64Var(&f.Noise, "noise", 0, "mixing factor of the density matrix perturbation of the first sweep, which escapes the symmetric superposition of the ordered phase, see mps.SearchGroundStateOptions.Noise")
+		fs.StringVar(&f.H5Path, "h5", "", "also write the results and ground states of all configurations to this HDF5 file, see writeH5")
+		fs.StringVar(&f.Format, "format", emit.FormatCSV, "format of the results printed to stdout, csv or jsonl")
+	case "gather":
+		fs.StringVar(&f.H5Path, "h5", "", "also write the results and saved ground states of all configurations to this HDF5 file, see writeH5")
+		fs.StringVar(&f.Format, "format", emit.FormatCSV, "format of the results printed to stdout, csv or jsonl")
+	case "stats":
+		fs.StringVar(&f.Format, "format", emit.FormatCSV, "format of the results printed to stdout, csv or jsonl")
+	case "clean":
+		fs.BoolVar(&f.All, "all", false, "remove the whole run directory, instead of only the checkpoints")
+	case "dynamics":
+		f.Quench.register(fs)
+	case "spectral":
+		f.Spectrum.register(fs)
+	}
+}
+
+type Config struct {
+	l       int
+	h       complex64
+	bondDim int
+	tol     float32
+
+	// seed, when non-zero, seeds the random initial state.
+	seed    uint64
+	twoSite bool
+	// initial, when non-nil, is copied to the initial state instead of a random one.
+	initial []*tensor.Dense
+	// noise is the mixing factor of the density matrix perturbation of the first sweep, which decays by noiseDecay in every later sweep.
+	noise float32
+
+	// checkpointDir, when non-empty, is where the search saves and resumes checkpoints, every checkpointEvery sweeps.
+	checkpointDir   string
+	checkpointEvery int
+	// profile is whether to log the time spent in each phase of the search.
+	profile bool
+	// statePath, when non-empty, is where the ground state is saved.
+	statePath string
+	// interrupt, when closed, stops the search at the end of the current iteration.
+	interrupt <-chan struct{}
+}
+
+func newConfigs() []Config {
+	hLogs := []float64{0.05, 0.1, 0.2, 0.3, 0.4, 0.5, 1, 1.5, 2}
+	// Add negative logs, so that hLogs becomes {-2, -1, tcLog, 1, 2...}.
+	hLogsLen := len(hLogs)
+	for i := range hLogsLen {
+		hLogs = append(hLogs, -hLogs[i])
+	}
+	// tcGuess is a guess of the critical temperature.
+	const tcGuess float64 = 1
+	tcLog := math.Log10(tcGuess)
+	for i := range hLogs {
+		hLogs[i] += tcLog
+	}
+	slices.Sort(hLogs)
+
+	configs := make([]Config, 0)
+	for _, l := range []int{25} {
+		for _, hl := range hLogs {
+			for _, bondDim := range []int{2, 4, 8} {
+				h := math.Pow(10, hl)
+				cfg := Config{l: l, h: complex(float32(h), 0)}
+				cfg.bondDim = bondDim
+
+				switch bondDim {
+				case 2:
+					cfg.tol = 1e-4
+				case 4:
+					cfg.tol = 1e-5
+				default:
+					cfg.tol = 1e-6
+				}
+
+				configs = append(configs, cfg)
+			}
+		}
+	}
+	return configs
+}
+
+type Statistics struct {
+	cfg    Config
+	e0     float32
+	m      float32
+	binder float32
+
+	// Solver metadata, which are unknown for results of previous runs that did not record them, in which case sweeps is 0 and the others are NaN.
+	// sweeps is the number of iterations of the search.
+	sweeps int
+	// variance is <H^2> - <H>^2, which vanishes for an eigenstate, up to the float32 cancellation of the order of 1e-7*e0^2 which may leave it negative.
+	variance float64
+	// seconds is the wall time of the search.
+	seconds float64
+	// discarded is the total discarded weight of the truncations of the search.
+	discarded float64
+
+	// state is the ground state, which is nil for the infinite DMRG and for results of previous runs whose states were not saved.
+	state []*tensor.Dense
+}
+
+// noiseDecay is the factor by which the noise decays in every sweep, so that it is off after about ten sweeps.
+const noiseDecay = 0.3
+
+func solve(cfg Config) (Statistics, error) {
+	n := [2]int{cfg.l, 1}
+	h := mps.Ising(n, cfg.h)
+
+	// Buffers.
+	fs := make([]*tensor.Dense, 0, len(h))
+	for _ = range h {
+		fs = append(fs, tensorPool.Get(1))
+	}
+	bufs := make([]*tensor.Dense, 0)
+	for _ = range 10 {
+		bufs = append(bufs, tensorPool.Get(1))
+	}
+	defer tensorPool.Release(append(fs, bufs...)...)
+
+	// Search for ground state.
+	var r *rand.Rand
+	if cfg.seed != 0 {
+		r = rand.New(rand.NewPCG(cfg.seed, cfg.seed))
+	}
+	search := mps.SearchGroundState
+	initD := cfg.bondDim
+	if cfg.twoSite {
+		search = mps.SearchGroundState2Site
+		initD = 1
+	}
+	state := mps.RandMPSWithRand(r, h, initD)
+	if cfg.initial != nil {
+		state = copyState(cfg.initial)
+	}
+	opt := mps.NewSearchGroundStateOptions().Tol(cfg.tol).MaxBondDim(cfg.bondDim).Pool(tensorPool).Interrupt(cfg.interrupt).Noise(cfg.noise, noiseDecay).Rand(r)
+	if cfg.checkpointDir != "" {
+		opt = opt.Checkpoint(cfg.checkpointDir, cfg.checkpointEvery)
+	}
+	var prof mps.Profile
+	report := mps.NewTruncationReport(len(h))
+	opt = opt.Profile(&prof).TruncationReport(report)
+	start := time.Now()
+	if err := search(fs, h, state, [10]*tensor.Dense(bufs), opt); err != nil {
+		return Statistics{}, errors.Wrap(err, "")
+	}
+	seconds := time.Since(start).Seconds()
+	if cfg.profile {
+		log.Printf("l %d h %f b %d: %d sweeps, %v, %#v", cfg.l, real(cfg.h), cfg.bondDim, len(prof.Sweeps), prof.Total(), tensorPool.Stats())
+	}
+
+	if cfg.statePath != "" {
+		if err := saveState(cfg.statePath, state); err != nil {
+			return Statistics{}, errors.Wrap(err, "")
+		}
+	}
+
+	stat := measure(cfg, fs, h, state, [2]*tensor.Dense(bufs))
+	stat.sweeps, stat.seconds, stat.discarded = len(prof.Sweeps), seconds, report.Total()
+	return stat, nil
+}
+
+// measure returns the statistics of the ground state of the hamiltonian h, using fs as buffers of the L expressions.
+// The solver metadata other than the variance are unknown.
+func measure(cfg Config, fs, h, state []*tensor.Dense, bufs [2]*tensor.Dense) Statistics {
+	psiIP := mps.InnerProduct(state, state, bufs)
+	e0 := mps.LExpressions(fs, h, state, bufs) / psiIP
+	h2 := mps.H2(h, state, bufs) / psiIP
+	variance := float64(real(h2)) - float64(real(e0))*float64(real(e0))
+	// Calculate magnetization per spin and the Binder cumulant.
+	mStats := mps.Statistics(state, bufs)
+	m := math.Sqrt(mStats.M2)
+
+	stat := Statistics{cfg: cfg, e0: real(e0), m: float32(m), binder: float32(mStats.BinderCumulant), state: state}
+	stat.variance, stat.seconds, stat.discarded = variance, math.NaN(), math.NaN()
+	return stat
+}
+
+// solveIDMRG computes the energy density and magnetization of the infinite chain, ignoring cfg.l.
+func solveIDMRG(cfg Config) (Statistics, error) {
+	bufs := make([]*tensor.Dense, 0)
+	for _ = range 10 {
+		bufs = append(bufs, tensorPool.Get(1))
+	}
+	defer tensorPool.Release(bufs...)
+
+	opt := mps.NewIDMRGOptions().Tol(cfg.tol).MaxBondDim(cfg.bondDim)
+	start := time.Now()
+	res, err := mps.IDMRG(mps.Ising([2]int{3, 1}, cfg.h), [10]*tensor.Dense(bufs), opt)
+	if err != nil {
+		return Statistics{}, errors.Wrap(err, "")
+	}
+	seconds := time.Since(start).Seconds()
+
+	// Calculate the magnetization from the long range correlation, since the state may be a superposition of both ferromagnetic states.
+	z := tensor.T2([][]complex64{{1, 0}, {0, -1}})
+	zz := res.Correlation(z, z, len(res.Left)/2)
+	m := math.Sqrt(cmplx.Abs(complex128(zz)))
+
+	// The Binder cumulant is defined only for finite chains.
+	cfg.l = 0
+	stat := Statistics{cfg: cfg, e0: real(res.EnergyDensity), m: float32(m), binder: float32(math.NaN())}
+	stat.variance, stat.seconds, stat.discarded = math.NaN(), seconds, math.NaN()
+	return stat, nil
+}
+
+// copyState returns a copy of state, so that a search starting from it leaves state intact.
+func copyState(state []*tensor.Dense) []*tensor.Dense {
+	c := make([]*tensor.Dense, len(state))
+	for i, m := range state {
+		c[i] = tensor.Zeros(m.Shape()...).Set([]int{0, 0, 0}, m)
+	}
+	return c
+}
+
+// chainKey identifies the configs that differ only in the field, which are the neighbors of the continuation in the field.
+type chainKey struct {
+	l       int
+	bondDim int
+	twoSite bool
+}
+
+func saveState(fpath string, state []*tensor.Dense) error {
+	if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
+		return errors.Wrap(err, "")
+	}
+	f, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err1 := mps.Save(f, state); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// writeStatistics writes statistics to w in format, see emit, with the solver metadata following the observables.
+func writeStatistics(w io.Writer, statistics []Statistics, format string) error {
+	ew, err := emit.NewWriter(w, format)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	for _, s := range statistics {
+		var sweeps any
+		if s.sweeps > 0 {
+			sweeps = s.sweeps
+		}
+		record := []emit.Field{
+			{Name: "l", Value: s.cfg.l},
+			{Name: "h", Value: real(s.cfg.h)},
+			{Name: "b", Value: s.cfg.bondDim},
+			{Name: "twosite", Value: s.cfg.twoSite},
+			{Name: "e0", Value: s.e0},
+			{Name: "m", Value: s.m},
+			{Name: "binder", Value: s.binder},
+			{Name: "sweeps", Value: sweeps},
+			{Name: "variance", Value: s.variance},
+			{Name: "seconds", Value: s.seconds},
+			{Name: "discarded", Value: s.discarded},
+		}
+		if err := ew.Write(record); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	if err := ew.Flush(); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// configName names the checkpoint and the saved ground state of cfg.
+// It is the key of the results of cfg, so that the configs that differ only in twoSite do not share them.
+func configName(cfg Config) string {
+	return resultKey(cfg)
+}
+
+func statePath(runDir string, cfg Config) string {
+	return filepath.Join(runDir, "state", configName(cfg)+".mps")
+}
+
+// Solve solves the configs of the sweep that are not in the results file yet, and prints and plots the results.
+func Solve(f Flags) error {
+	if f.Golden {
+		return golden(f.GoldenUpdate)
+	}
+	if err := os.MkdirAll(f.RunDir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	// Skip the configs solved by previous runs, including interrupted ones.
+	solved, err := readResults(filepath.Join(f.RunDir, fnameResults))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	interrupt := notifyInterrupt()
+
+	configs := newConfigs()
+	solveFn := solve
+	if f.IDMRG {
+		solveFn = solveIDMRG
+	}
+	statistics, interrupted, err := solveConfigs(f, configs, solved, interrupt, solveFn)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if err := writeStatistics(os.Stdout, statistics, f.Format); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := writePlots(f.RunDir, statistics); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if f.H5Path != "" {
+		if err := writeH5(f.H5Path, statistics); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	log.Printf("pool %#v", tensorPool.Stats())
+
+	if interrupted {
+		return errors.Errorf("interrupted after %d of %d configs, completed results are in %s", len(statistics), len(configs), filepath.Join(f.RunDir, fnameResults))
+	}
+	return nil
+}
+
+// solveConfigs solves the configs that are not in solved with solveFn, flushing the results to the run directory after each one.
+// It returns early with interrupted set when interrupt is closed.
+func solveConfigs(f Flags, configs []Config, solved map[string]Statistics, interrupt <-chan struct{}, solveFn func(Config) (Statistics, error)) (statistics []Statistics, interrupted bool, err error) {
+	resultsPath := filepath.Join(f.RunDir, fnameResults)
+	statistics = make([]Statistics, 0, len(configs))
+	// previous are the ground states of the previous field for the continuation.
+	// The configs are in increasing order of the field, so that the continuation starts from the ordered phase,
+	// and follows one of its symmetry broken states into the critical region, instead of restarting from random states in every sector.
+	// Within a run, previous holds the states in memory, so the continuation needs no -save-states.
+	// The states of configs solved by previous runs, however, are only known if they were saved.
+	previous := make(map[chainKey][]*tensor.Dense)
+	for _, cfg := range configs {
+		// The infinite DMRG reports l as 0.
+		keyCfg := cfg
+		if f.IDMRG {
+			keyCfg.l = 0
+		}
+		key := chainKey{l: cfg.l, bondDim: cfg.bondDim, twoSite: cfg.twoSite}
+		if stat, ok := solved[resultKey(keyCfg)]; ok {
+			// The results file lacks the tolerance and seed.
+			stat.cfg = keyCfg
+			statistics = append(statistics, stat)
+			if f.Continuation {
+				// Without a saved state, the next field starts from a random state, rather than from the state of a farther field.
+				var state []*tensor.Dense
+				if f.SaveStates {
+					state, err = loadState(statePath(f.RunDir, cfg))
+					if err != nil && !os.IsNotExist(errors.Cause(err)) {
+						return nil, false, errors.Wrap(err, "")
+					}
+				}
+				previous[key] = state
+			}
+			continue
+		}
+		select {
+		case <-interrupt:
+			interrupted = true
+		default:
+		}
+		if interrupted {
+			break
+		}
+
+		cfgName := configName(cfg)
+		if !f.IDMRG {
+			// Checkpoints are always enabled, so that an interrupted search resumes where it stopped.
+			cfg.checkpointDir = filepath.Join(f.RunDir, "checkpoint", cfgName)
+			cfg.checkpointEvery = f.CheckpointEvery
+			cfg.profile = f.Profile
+			if f.SaveStates {
+				cfg.statePath = statePath(f.RunDir, cfg)
+			}
+			cfg.interrupt = interrupt
+			cfg.noise = float32(f.Noise)
+			if f.Continuation {
+				cfg.initial = previous[key]
+			}
+		}
+		stat, err := solveFn(cfg)
+		if errors.Is(err, mps.ErrInterrupted) {
+			log.Printf("interrupted %s, resume by running again", cfgName)
+			interrupted = true
+			break
+		}
+		if err != nil {
+			return nil, false, errors.Wrap(err, fmt.Sprintf("%#v", cfg))
+		}
+		// The checkpoint of a completed search is stale, and would otherwise be resumed by the next run.
+		if cfg.checkpointDir != "" {
+			if err := os.RemoveAll(cfg.checkpointDir); err != nil {
+				return nil, false, errors.Wrap(err, "")
+			}
+		}
+		statistics = append(statistics, stat)
+		previous[key] = stat.state
+		log.Printf("%#v %f %f %f", stat.cfg, stat.e0, stat.m, stat.binder)
+
+		// Flush the completed results, so that they survive an interruption.
+		if err := writeResults(resultsPath, statistics); err != nil {
+			return nil, false, errors.Wrap(err, "")
+		}
+	}
+	return statistics, interrupted, nil
+}
+
+// Gather prints and plots the results in the run directory, and writes them together with the saved ground states to the HDF5 file if requested.
+func Gather(f Flags) error {
+	statistics, err := gather(f.RunDir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := writeStatistics(os.Stdout, statistics, f.Format); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := writePlots(f.RunDir, statistics); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if f.H5Path != "" {
+		if err := writeH5(f.H5Path, statistics); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return nil
+}
+
+// gather returns the results in runDir, with the ground states that were saved.
+func gather(runDir string) ([]Statistics, error) {
+	statistics, err := readResultsFile(filepath.Join(runDir, fnameResults))
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	for i, s := range statistics {
+		state, err := loadState(statePath(runDir, s.cfg))
+		if os.IsNotExist(errors.Cause(err)) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		statistics[i].state = state
+	}
+	return statistics, nil
+}
+
+// Plot writes the plots of the results in the run directory, see writePlots.
+func Plot(f Flags) error {
+	statistics, err := readResultsFile(filepath.Join(f.RunDir, fnameResults))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := writePlots(f.RunDir, statistics); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// Stats recomputes the observables of the results in the run directory from their saved ground states.
+func Stats(f Flags) error {
+	resultsPath := filepath.Join(f.RunDir, fnameResults)
+	statistics, err := gather(f.RunDir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	var count int
+	for i, s := range statistics {
+		if s.state == nil {
+			continue
+		}
+		h := mps.Ising([2]int{s.cfg.l, 1}, s.cfg.h)
+		fs := make([]*tensor.Dense, 0, len(h))
+		for range h {
+			fs = append(fs, tensor.Zeros(1))
+		}
+		bufs := [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+		// The recomputed variance replaces the recorded one, but the rest of the solver metadata are kept.
+		stat := measure(s.cfg, fs, h, s.state, bufs)
+		stat.sweeps, stat.seconds, stat.discarded = s.sweeps, s.seconds, s.discarded
+		statistics[i] = stat
+		count++
+	}
+	if err := writeResults(resultsPath, statistics); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := writeStatistics(os.Stdout, statistics, f.Format); err != nil {
+		return errors.Wrap(err, "")
+	}
+	log.Printf("recomputed the statistics of %d of %d results from their saved ground states", count, len(statistics))
+	return nil
+}
+
+// Clean removes the checkpoints of interrupted searches from the run directory, so that they are solved afresh by the next run.
+// With f.All, the whole run directory is removed, including the results.
+func Clean(f Flags) error {
+	dir := filepath.Join(f.RunDir, "checkpoint")
+	if f.All {
+		dir = f.RunDir
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
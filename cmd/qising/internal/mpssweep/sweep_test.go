@@ -0,0 +1,154 @@
+package mpssweep
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestWriteStatistics(t *testing.T) {
+	t.Parallel()
+	// The first result has the solver metadata of a search, and the second is a result of a previous run without them.
+	stats := []Statistics{
+		{cfg: Config{l: 16, h: 1, bondDim: 8, twoSite: true}, e0: -20.25, m: 0.5, binder: 0.625, sweeps: 7, variance: 2.5e-6, seconds: 1.5, discarded: 1e-9},
+		{cfg: Config{l: 8, h: 0.5, bondDim: 4}, e0: -8.5, m: 0.75, binder: 0.5, variance: math.NaN(), seconds: math.NaN(), discarded: math.NaN()},
+	}
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{
+			format: "csv",
+			golden: `l,h,b,twosite,e0,m,binder,sweeps,variance,seconds,discarded
+16,1,8,true,-20.25,0.5,0.625,7,2.5e-06,1.5,1e-09
+8,0.5,4,false,-8.5,0.75,0.5,,,,
+`,
+		},
+		{
+			format: "jsonl",
+			golden: `{"l":16,"h":1,"b":8,"twosite":true,"e0":-20.25,"m":0.5,"binder":0.625,"sweeps":7,"variance":2.5e-06,"seconds":1.5,"discarded":1e-09}
+{"l":8,"h":0.5,"b":4,"twosite":false,"e0":-8.5,"m":0.75,"binder":0.5,"sweeps":null,"variance":null,"seconds":null,"discarded":null}
+`,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var b strings.Builder
+			if err := writeStatistics(&b, stats, test.format); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if b.String() != test.golden {
+				t.Fatalf("%s", b.String())
+			}
+		})
+	}
+}
+
+func TestSolveConfigsContinuation(t *testing.T) {
+	t.Parallel()
+	// The configs are two fields of one chain, and a field of another chain.
+	configs := []Config{
+		{l: 8, h: 0.5, bondDim: 4, tol: 1e-5, seed: 1},
+		{l: 8, h: 1, bondDim: 4, tol: 1e-5, seed: 1},
+		{l: 8, h: 1, bondDim: 8, tol: 1e-5, seed: 1},
+	}
+	tests := []struct {
+		saveStates bool
+		// resume is whether the first config is solved by a previous run.
+		resume bool
+		// continued is whether the second config starts from the state of the first.
+		continued bool
+	}{
+		{saveStates: false, resume: false, continued: true},
+		{saveStates: true, resume: false, continued: true},
+		{saveStates: true, resume: true, continued: true},
+		// The state of the previous run is unknown, so the search starts from a random state.
+		{saveStates: false, resume: true, continued: false},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			f := Flags{RunDir: t.TempDir(), SaveStates: test.saveStates, Continuation: true}
+			if test.resume {
+				if _, _, err := solveConfigs(f, configs[:1], nil, nil, solve); err != nil {
+					t.Fatalf("%+v", err)
+				}
+			}
+			solved, err := readResults(filepath.Join(f.RunDir, fnameResults))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			initials := make([][]*tensor.Dense, 0)
+			solveFn := func(cfg Config) (Statistics, error) {
+				initials = append(initials, cfg.initial)
+				return solve(cfg)
+			}
+			statistics, interrupted, err := solveConfigs(f, configs, solved, nil, solveFn)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if interrupted || len(statistics) != len(configs) {
+				t.Fatalf("%t %d", interrupted, len(statistics))
+			}
+			// The first config of each chain starts from a random state.
+			if !test.resume && initials[0] != nil {
+				t.Fatalf("%v", initials[0])
+			}
+			if last := initials[len(initials)-1]; last != nil {
+				t.Fatalf("%v", last)
+			}
+
+			second := initials[len(initials)-2]
+			if !test.continued {
+				if second != nil {
+					t.Fatalf("%v", second)
+				}
+				return
+			}
+			first := statistics[0].state
+			if test.resume {
+				first, err = loadState(statePath(f.RunDir, configs[0]))
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+			}
+			if len(second) != len(first) {
+				t.Fatalf("%d %d", len(second), len(first))
+			}
+			for j := range second {
+				if err := second[j].Equal(first[j], 0); err != nil {
+					t.Fatalf("%d %+v", j, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGolden(t *testing.T) {
+	t.Parallel()
+	if err := golden(""); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func TestConfigName(t *testing.T) {
+	t.Parallel()
+	// The golden configs include single-site and two-site searches, whose checkpoints and states are distinct.
+	names := make(map[string]Config)
+	for _, cfg := range newGoldenConfigs() {
+		for _, twoSite := range []bool{false, true} {
+			cfg.twoSite = twoSite
+			name := configName(cfg)
+			if other, ok := names[name]; ok {
+				t.Fatalf("%s %#v %#v", name, cfg, other)
+			}
+			names[name] = cfg
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Command qising runs and post-processes the parameter sweeps of the exact diagonalization and MPS solvers.
+//
+// Usage:
+//
+//	qising <subcommand> <exactdiag|mps> [flags]
+//
+// The subcommands are:
+//   - solve solves the configs of the sweep that are not in the run directory yet, and prints the results.
+//   - gather prints the results in the run directory, and writes them to HDF5 and other formats, without solving.
+//   - plot writes SVG plots of the magnetization and the Binder cumulant against the field to the run directory, which solve and gather also do.
+//   - stats recomputes the observables in the run directory from the saved eigenvectors or ground states.
+//   - clean removes the partial results of interrupted runs, or with -all the whole run directory.
+//   - dynamics, for mps only, quenches the transverse field of a ground state and writes the time series of its observables to the run directory.
+//   - spectral, for mps only, writes the dynamic structure factor of the ground state and the plot of its dispersion to the run directory.
+//
+// The results printed by solve, gather, and stats of mps are CSV, or JSON lines with -format jsonl, followed by the solver metadata of each record:
+// the number of iterations, the energy variance <H^2> - <H>^2, the wall time and the discarded weight, which are empty or null if unknown.
+//
+// Since results are cached in the run directory, a sweep is re-run partially by cleaning or removing only the results of some configs.
+//
+// The default run directories are runs/qising/exactdiag and runs/qising/mps, whereas the exactdiag and mps run commands of previous versions shared runs/qising.
+// Pass -d runs/qising to resume or gather the sweeps of those versions.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fumin/qising/cmd/qising/internal/edsweep"
+	"github.com/fumin/qising/cmd/qising/internal/mpssweep"
+	"github.com/pkg/errors"
+)
+
+const usage = "usage: qising <solve|gather|plot|stats|clean|dynamics|spectral> <exactdiag|mps> [flags]"
+
+// subcommand is a subcommand of a method, which parses its flags from args and runs.
+type subcommand func(name string, args []string) error
+
+func exactdiagCommand(run func(edsweep.Flags) error) subcommand {
+	return func(name string, args []string) error {
+		var f edsweep.Flags
+		fs := flag.NewFlagSet(name, flag.ExitOnError)
+		f.Register(fs, name)
+		fs.Parse(args)
+		return run(f)
+	}
+}
+
+func mpsCommand(run func(mpssweep.Flags) error) subcommand {
+	return func(name string, args []string) error {
+		var f mpssweep.Flags
+		fs := flag.NewFlagSet(name, flag.ExitOnError)
+		f.Register(fs, name)
+		fs.Parse(args)
+		return run(f)
+	}
+}
+
+var subcommands = map[string]map[string]subcommand{
+	"solve": {
+		"exactdiag": exactdiagCommand(edsweep.Solve),
+		"mps":       mpsCommand(mpssweep.Solve),
+	},
+	"gather": {
+		"exactdiag": exactdiagCommand(edsweep.Gather),
+		"mps":       mpsCommand(mpssweep.Gather),
+	},
+	"plot": {
+		"exactdiag": exactdiagCommand(edsweep.Plot),
+		"mps":       mpsCommand(mpssweep.Plot),
+	},
+	"stats": {
+		"exactdiag": exactdiagCommand(edsweep.Stats),
+		"mps":       mpsCommand(mpssweep.Stats),
+	},
+	"clean": {
+		"exactdiag": exactdiagCommand(edsweep.Clean),
+		"mps":       mpsCommand(mpssweep.Clean),
+	},
+	"dynamics": {
+		"mps": mpsCommand(mpssweep.Dynamics),
+	},
+	"spectral": {
+		"mps": mpsCommand(mpssweep.Spectral),
+	},
+}
+
+func mainWithErr(args []string) error {
+	if len(args) < 2 {
+		return errors.New(usage)
+	}
+	methods, ok := subcommands[args[0]]
+	if !ok {
+		return errors.Errorf("unknown subcommand %q, %s", args[0], usage)
+	}
+	run, ok := methods[args[1]]
+	if !ok {
+		return errors.Errorf("unknown method %q, %s", args[1], usage)
+	}
+	if err := run(args[0], args[2:]); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%s %s", args[0], args[1]))
+	}
+	return nil
+}
+
+func main() {
+	log.SetFlags(log.Lmicroseconds | log.Llongfile | log.LstdFlags)
+
+	if err := mainWithErr(os.Args[1:]); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
@@ -0,0 +1,50 @@
+// Command selftest validates the solvers of this build against the reference data embedded in the qising package.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/fumin/qising"
+	"github.com/pkg/errors"
+)
+
+var (
+	update = flag.String("update", "", "recompute the reference data in double precision, and write it to this directory instead of testing")
+)
+
+func mainWithErr() error {
+	if *update != "" {
+		if err := qising.WriteReference(*update); err != nil {
+			return errors.Wrap(err, "")
+		}
+		return nil
+	}
+
+	results, err := qising.SelfTest()
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	var failed int
+	for _, r := range results {
+		fmt.Println(r)
+		if !r.OK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	log.Printf("selftest: %d checks ok", len(results))
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	log.SetFlags(log.Lmicroseconds | log.Llongfile | log.LstdFlags)
+
+	if err := mainWithErr(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
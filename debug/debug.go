@@ -0,0 +1,49 @@
+// Package debug switches on the checks of invariants throughout this module,
+// such as the orthonormality of Krylov bases, the canonical form of MPS, and the Hermiticity of effective hamiltonians.
+// The checks are enabled by building with the qisingdebug tag, for example go test -tags qisingdebug ./...
+// Otherwise Enabled is a false constant, so that checks guarded by it are removed by the compiler and cost nothing.
+// A failed check panics, since it is a bug rather than an error that callers can handle.
+package debug
+
+import (
+	"fmt"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// Tol is the tolerance of the checks, relative to the magnitude of the checked quantities.
+// It is loose compared with float32 roundoff, so that only genuine violations are reported.
+const Tol = 1e-3
+
+// Assert panics if err is not nil.
+func Assert(err error) {
+	if err != nil {
+		panic(fmt.Sprintf("debug: %+v", err))
+	}
+}
+
+// Orthonormal returns an error if the columns of q are not orthonormal, that is if q.H @ q is not the identity.
+func Orthonormal(q *tensor.Dense) error {
+	qq := tensor.MatMul(tensor.Zeros(1), q.H(), q)
+	if err := qq.Equal(tensor.Zeros(1).Eye(q.Shape()[1], 0), Tol); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%#v", q.Shape()))
+	}
+	return nil
+}
+
+// Hermitian returns an error if the square matrix a is not Hermitian.
+func Hermitian(a *tensor.Dense) error {
+	if err := a.Equal(a.H(), Tol*max(1, a.InfNorm())); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%#v", a.Shape()))
+	}
+	return nil
+}
+
+// Close returns an error if a and b are not equal relative to their magnitudes.
+func Close(a, b *tensor.Dense) error {
+	if err := a.Equal(b, Tol*max(1, a.FrobeniusNorm(), b.FrobeniusNorm())); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%#v %#v", a.Shape(), b.Shape()))
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package debug
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestChecks(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		check func() error
+		ok    bool
+	}
+	tests := []testcase{
+		{check: func() error { return Orthonormal(tensor.T2([][]complex64{{1, 0}, {0, 1i}, {0, 0}})) }, ok: true},
+		{check: func() error { return Orthonormal(tensor.T2([][]complex64{{1, 1}, {0, 1}})) }, ok: false},
+		{check: func() error { return Hermitian(tensor.T2([][]complex64{{1, 2 - 1i}, {2 + 1i, -3}})) }, ok: true},
+		{check: func() error { return Hermitian(tensor.T2([][]complex64{{1, 2i}, {2i, -3}})) }, ok: false},
+		{check: func() error { return Close(tensor.T1([]complex64{1000, 1}), tensor.T1([]complex64{1000.5, 1})) }, ok: true},
+		{check: func() error { return Close(tensor.T1([]complex64{1, 1}), tensor.T1([]complex64{1, 1.1})) }, ok: false},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if err := test.check(); (err == nil) != test.ok {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !qisingdebug
+
+package debug
+
+// Enabled reports whether the checks of invariants are enabled.
+const Enabled = false
@@ -0,0 +1,123 @@
+// Package basis indexes the basis states of a lattice of spins 1/2 in the eigenbasis of Z, which is the basis of the matrices in exactdiag.
+//
+// Site s = y*n[1] + x of a lattice of shape n is the bit N-1-s of the index of a basis state, where N is the number of spins,
+// so that the first site is the most significant bit, as in the Kronecker product of the single spin spaces of the sites in row major order.
+// A bit 0 is the +1 eigenstate of Z, and a bit 1 the -1 eigenstate.
+// A basis state is also represented by its spins []byte, in which state[s] is the bit of site s.
+package basis
+
+import (
+	"fmt"
+	"iter"
+	"math/bits"
+)
+
+// Mask returns the bit of site in the index of a basis state of numSpins spins.
+func Mask(numSpins, site int) int {
+	if site < 0 || site >= numSpins {
+		panic(fmt.Sprintf("%d %d", site, numSpins))
+	}
+	return 1 << (numSpins - 1 - site)
+}
+
+// SiteMask returns the bit of the site {y, x} of a lattice of shape n.
+func SiteMask(n [2]int, site [2]int) int {
+	return Mask(n[0]*n[1], site[0]*n[1]+site[1])
+}
+
+// SublatticeMask returns the bits of the sites of a lattice of shape n for which in is true.
+func SublatticeMask(n [2]int, in func(site [2]int) bool) int {
+	var mask int
+	for y := range n[0] {
+		for x := range n[1] {
+			if in([2]int{y, x}) {
+				mask |= SiteMask(n, [2]int{y, x})
+			}
+		}
+	}
+	return mask
+}
+
+// Checkerboard returns the masks of the two sublattices of a lattice of shape n, whose sites have even and odd y+x respectively.
+// Neighboring sites of an open lattice belong to different sublattices, as do those of a periodic lattice of even lengths.
+func Checkerboard(n [2]int) (even, odd int) {
+	even = SublatticeMask(n, func(site [2]int) bool { return (site[0]+site[1])%2 == 0 })
+	odd = SublatticeMask(n, func(site [2]int) bool { return (site[0]+site[1])%2 == 1 })
+	return even, odd
+}
+
+// Decode sets the spins state of the basis state of index i, where the number of spins is len(state).
+func Decode(state []byte, i int) {
+	for s := range state {
+		state[s] = byte((i >> (len(state) - 1 - s)) & 1)
+	}
+}
+
+// Encode returns the index of the basis state of spins state.
+func Encode(state []byte) int {
+	var i int
+	for _, b := range state {
+		i = i<<1 | int(b&1)
+	}
+	return i
+}
+
+// States iterates over the indices and spins of all basis states of numSpins spins in ascending order.
+// The spins are reused between iterations, and must not be retained.
+func States(numSpins int) iter.Seq2[int, []byte] {
+	return func(yield func(int, []byte) bool) {
+		state := make([]byte, numSpins)
+		for i := range 1 << numSpins {
+			Decode(state, i)
+			if !yield(i, state) {
+				return
+			}
+		}
+	}
+}
+
+// Down returns the number of spins of the basis state i in the -1 eigenstate of Z.
+// The number on a sublattice is Down(i & mask).
+func Down(i int) int {
+	return bits.OnesCount(uint(i))
+}
+
+// Magnetization returns the eigenvalue of the sum of the Z spins of the basis state i of numSpins spins.
+func Magnetization(numSpins, i int) int {
+	return numSpins - 2*Down(i)
+}
+
+// Parity returns the eigenvalue +1 or -1 of the product of the Z spins of the basis state i.
+func Parity(i int) int {
+	return 1 - 2*(Down(i)&1)
+}
+
+// Flip returns the basis state of i with all of its numSpins spins flipped, which is the image of i under the spin flip prod_a X_a.
+// The spin flip sector of parity p is spanned by |i> + p|Flip(i)>.
+func Flip(numSpins, i int) int {
+	return i ^ (1<<numSpins - 1)
+}
+
+// MajorityUp sets upState to the Z spins +1 or -1 of state, flipped if necessary so that at least half of the spins are +1.
+// It removes the spin flip symmetry from the magnetization of a basis state.
+func MajorityUp(upState []int8, state []byte) {
+	var ups int
+	for _, b := range state {
+		if b == 1 {
+			ups++
+		}
+	}
+	// Bit 0 is the +1 spin, unless the spins are flipped.
+	up := byte(0)
+	if ups >= len(state)-ups {
+		up = 1
+	}
+	for s, b := range state {
+		switch b {
+		case up:
+			upState[s] = 1
+		default:
+			upState[s] = -1
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package basis
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestStates(t *testing.T) {
+	t.Parallel()
+	want := [][]byte{{0, 0, 0}, {0, 0, 1}, {0, 1, 0}, {0, 1, 1}, {1, 0, 0}, {1, 0, 1}, {1, 1, 0}, {1, 1, 1}}
+	var count int
+	for i, state := range States(3) {
+		if i != count || !slices.Equal(state, want[i]) {
+			t.Fatalf("%d %d %v %v", i, count, state, want[i])
+		}
+		if j := Encode(state); j != i {
+			t.Fatalf("%d %d", j, i)
+		}
+		decoded := make([]byte, 3)
+		Decode(decoded, i)
+		if !slices.Equal(decoded, state) {
+			t.Fatalf("%v %v", decoded, state)
+		}
+		count++
+	}
+	if count != 8 {
+		t.Fatalf("%d", count)
+	}
+
+	// Iteration stops early.
+	count = 0
+	for i := range States(4) {
+		if i == 2 {
+			break
+		}
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("%d", count)
+	}
+}
+
+func TestMask(t *testing.T) {
+	t.Parallel()
+	n := [2]int{2, 3}
+	// Site {1, 0} is the fourth of six sites, which is bit 2.
+	if m := SiteMask(n, [2]int{1, 0}); m != 0b000100 {
+		t.Fatalf("%b", m)
+	}
+	state := make([]byte, 6)
+	Decode(state, SiteMask(n, [2]int{1, 0}))
+	if !slices.Equal(state, []byte{0, 0, 0, 1, 0, 0}) {
+		t.Fatalf("%v", state)
+	}
+	even, odd := Checkerboard(n)
+	if even != 0b101010 || odd != 0b010101 {
+		t.Fatalf("%b %b", even, odd)
+	}
+	if m := SublatticeMask(n, func(site [2]int) bool { return site[0] == 0 }); m != 0b111000 {
+		t.Fatalf("%b", m)
+	}
+}
+
+func TestClassification(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		numSpins      int
+		i             int
+		magnetization int
+		parity        int
+		flip          int
+		majorityUp    []int8
+	}{
+		{numSpins: 4, i: 0b0000, magnetization: 4, parity: 1, flip: 0b1111, majorityUp: []int8{1, 1, 1, 1}},
+		{numSpins: 4, i: 0b0100, magnetization: 2, parity: -1, flip: 0b1011, majorityUp: []int8{1, -1, 1, 1}},
+		{numSpins: 4, i: 0b1101, magnetization: -2, parity: -1, flip: 0b0010, majorityUp: []int8{1, 1, -1, 1}},
+		{numSpins: 3, i: 0b111, magnetization: -3, parity: -1, flip: 0b000, majorityUp: []int8{1, 1, 1}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if m := Magnetization(test.numSpins, test.i); m != test.magnetization {
+				t.Fatalf("%d %d", m, test.magnetization)
+			}
+			if p := Parity(test.i); p != test.parity {
+				t.Fatalf("%d %d", p, test.parity)
+			}
+			if f := Flip(test.numSpins, test.i); f != test.flip {
+				t.Fatalf("%b %b", f, test.flip)
+			}
+			state, upState := make([]byte, test.numSpins), make([]int8, test.numSpins)
+			Decode(state, test.i)
+			MajorityUp(upState, state)
+			if !slices.Equal(upState, test.majorityUp) {
+				t.Fatalf("%v %v", upState, test.majorityUp)
+			}
+		})
+	}
+}
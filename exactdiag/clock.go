@@ -0,0 +1,170 @@
+package exactdiag
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/pkg/errors"
+)
+
+// ClockOptions are options for building the Potts and clock hamiltonians.
+type ClockOptions struct {
+	periodic [2]bool
+	coo      mat.WriteCOOOptions
+	progress func(done, total int)
+}
+
+// NewClockOptions returns the default options, which has open boundary conditions.
+func NewClockOptions() ClockOptions {
+	opt := ClockOptions{}
+	opt.coo = mat.NewWriteCOOOptions()
+	return opt
+}
+
+// Periodic sets whether the boundary conditions are periodic in each direction of the lattice, as in IsingOptions.Periodic.
+func (opt ClockOptions) Periodic(p [2]bool) ClockOptions {
+	opt.periodic = p
+	return opt
+}
+
+// WriteCOOOptions sets the file format of the hamiltonian.
+func (opt ClockOptions) WriteCOOOptions(o mat.WriteCOOOptions) ClockOptions {
+	opt.coo = o
+	return opt
+}
+
+// Progress sets the function called with the number of rows written and the total, as in IsingOptions.Progress.
+func (opt ClockOptions) Progress(fn func(done, total int)) ClockOptions {
+	opt.progress = fn
+	return opt
+}
+
+// PottsExplicit writes to dir the hamiltonian of the q-state quantum Potts model -J sum_<a, b> sum_{k=1}^{q-1} Omega_a^k Omega_b^{q-k} - f sum_a sum_{k=1}^{q-1} Gamma_a^k
+// on a lattice of shape n, where Omega = diag(1, w, ..., w^{q-1}) with w = exp(2*pi*i/q), and Gamma is the cyclic shift |s> -> |s+1 mod q>.
+// The coupling is -J (q delta_{s_a s_b} - 1) in the basis of the states s of the sites, and q = 2 is TransverseFieldIsingExplicit with h = f.
+// The basis state of index i has the state of site y*n[1] + x in the digit N-1-(y*n[1]+x) of i in base q, where N is the number of sites,
+// which for q = 2 is the basis of the basis package.
+// The MPO of the same hamiltonian is mps.Potts.
+// See J. Solyom, Duality of the block transformation and decimation for quantum spins, Phys. Rev. B 24, 230 (1981).
+func PottsExplicit(dir string, n [2]int, q int, j, f complex64, options ...ClockOptions) error {
+	if q < 2 {
+		return errors.Errorf("%d", q)
+	}
+	coupling := make([]complex64, q)
+	field := make([]complex64, q)
+	for d := range q {
+		coupling[d] = j
+		field[d] = -f
+	}
+	coupling[0] = -j * complex(float32(q-1), 0)
+	field[0] = 0
+	if err := clockExplicit(dir, n, q, coupling, field, options...); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// ClockExplicit writes to dir the hamiltonian of the q-state quantum clock model -J/2 sum_<a, b> (Omega_a Omega_b^dagger + h.c.) - f/2 sum_a (Gamma_a + Gamma_a^dagger),
+// where the operators and the basis are those of PottsExplicit.
+// The coupling is -J cos(2*pi*(s_a - s_b)/q), and the factors of 1/2 make q = 2 the transverse field Ising model with h = f,
+// while q = 3 is the Potts model with J/2 and f/2, and large q approaches the XY model.
+// The MPO of the same hamiltonian is mps.Clock.
+// See G. Ortiz, E. Cobanera and Z. Nussinov, Dualities and the phase diagram of the p-clock model, Nucl. Phys. B 854, 780 (2012).
+func ClockExplicit(dir string, n [2]int, q int, j, f complex64, options ...ClockOptions) error {
+	if q < 2 {
+		return errors.Errorf("%d", q)
+	}
+	coupling := make([]complex64, q)
+	field := make([]complex64, q)
+	for d := range q {
+		coupling[d] = -j * complex(float32(math.Cos(2*math.Pi*float64(d)/float64(q))), 0)
+	}
+	field[1] -= f / 2
+	field[q-1] -= f / 2
+	if err := clockExplicit(dir, n, q, coupling, field, options...); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// clockExplicit writes to dir the Z_q symmetric hamiltonian sum_<a, b> coupling[s_a - s_b mod q] + sum_a sum_k field[k] Gamma_a^k,
+// in the basis of PottsExplicit.
+func clockExplicit(dir string, n [2]int, q int, coupling, field []complex64, options ...ClockOptions) error {
+	opt := NewClockOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	numSites := n[0] * n[1]
+	dim := 1
+	for range numSites {
+		if dim > math.MaxInt32/q {
+			return errors.Errorf("%d %d", q, numSites)
+		}
+		dim *= q
+	}
+	// place[s] is the value of the digit of site s in the index of a basis state.
+	place := make([]int, numSites)
+	for s := range numSites {
+		place[s] = 1
+		for range numSites - 1 - s {
+			place[s] *= q
+		}
+	}
+	w, err := mat.NewCOOWriter(dir, dim, dim, opt.coo)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	bonds := make([][2]int, 0, 2)
+	state := make([]int, numSites)
+	vrcs := make([]vRowCol, 0)
+Loop:
+	for i := range dim {
+		for s := range state {
+			state[s] = (i / place[s]) % q
+		}
+		vrcs = vrcs[:0]
+
+		var diag complex64
+		for y := range n[0] {
+			for x := range n[1] {
+				s := y*n[1] + x
+				for _, b := range neighbors(bonds, n, y, x, opt.periodic) {
+					diag += coupling[(state[s]-state[b[0]*n[1]+b[1]]+q)%q]
+				}
+			}
+		}
+		if diag != 0 {
+			vrcs = append(vrcs, vRowCol{v: diag, row: i, col: i})
+		}
+
+		// Gamma^k maps the state of a site from s-k to s, hence row i has the entry of field[k] in the column of that site shifted back by k.
+		for s, p := range place {
+			for k := 1; k < q; k++ {
+				if field[k] == 0 {
+					continue
+				}
+				from := (state[s] - k + q) % q
+				vrcs = append(vrcs, vRowCol{v: field[k], row: i, col: i + (from-state[s])*p})
+			}
+		}
+
+		slices.SortFunc(vrcs, rowMajor)
+		for _, v := range vrcs {
+			if err1 := w.Write(v.row, v.col, v.v); err1 != nil && err == nil {
+				err = errors.Wrap(err1, fmt.Sprintf("%d", i))
+				break Loop
+			}
+		}
+		if done := i + 1; opt.progress != nil && (done%progressInterval == 0 || done == dim) {
+			opt.progress(done, dim)
+		}
+	}
+
+	if err1 := w.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package exactdiag
+
+import (
+	"fmt"
+	"math/cmplx"
+	"os"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag/mat"
+)
+
+func TestClockExplicit(t *testing.T) {
+	t.Parallel()
+	type build func(dir string) error
+	tests := []struct {
+		got  build
+		want build
+	}{
+		// Both models of two states are the transverse field Ising model.
+		{
+			got:  func(dir string) error { return PottsExplicit(dir, [2]int{3, 2}, 2, 1, 0.7) },
+			want: func(dir string) error { return TransverseFieldIsingExplicit(dir, [2]int{3, 2}, 0.7) },
+		},
+		{
+			got: func(dir string) error {
+				return ClockExplicit(dir, [2]int{5, 1}, 2, 1, 0.7, NewClockOptions().Periodic([2]bool{true, false}))
+			},
+			want: func(dir string) error {
+				return TransverseFieldIsingExplicit(dir, [2]int{5, 1}, 0.7, NewIsingOptions().Periodic([2]bool{true, false}))
+			},
+		},
+		// The three state clock model is the Potts model with half the coupling and field.
+		{
+			got:  func(dir string) error { return ClockExplicit(dir, [2]int{2, 2}, 3, 1.5, 0.8) },
+			want: func(dir string) error { return PottsExplicit(dir, [2]int{2, 2}, 3, 0.75, 0.4) },
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			got, err := readBuild(test.got)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			want, err := readBuild(test.want)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !got.Equal(want) {
+				t.Fatalf("\n%s, expected \n\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestClockExplicitHermitian(t *testing.T) {
+	t.Parallel()
+	for _, q := range []int{3, 4, 5} {
+		m, err := readBuild(func(dir string) error {
+			return ClockExplicit(dir, [2]int{3, 1}, q, 1, 0.6, NewClockOptions().Periodic([2]bool{true, false}))
+		})
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if m.Rows() != q*q*q {
+			t.Fatalf("%d %d", q, m.Rows())
+		}
+		dense := m.Dense()
+		for i := range dense {
+			for j := range dense[i] {
+				if c := dense[j][i]; cmplx.Abs(complex128(dense[i][j]-complex(real(c), -imag(c)))) > 1e-6 {
+					t.Fatalf("%d %d %d %v %v", q, i, j, dense[i][j], c)
+				}
+			}
+		}
+	}
+
+	if err := PottsExplicit(t.TempDir(), [2]int{2, 1}, 1, 1, 1); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+// readBuild returns the matrix written by build to a temporary directory.
+func readBuild(build func(dir string) error) (*mat.COO, error) {
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := build(dir); err != nil {
+		return nil, err
+	}
+	return mat.ReadCOO(dir)
+}
@@ -0,0 +1,124 @@
+package exactdiag
+
+import (
+	"math"
+	"slices"
+
+	"github.com/fumin/qising/exactdiag/basis"
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/pkg/errors"
+)
+
+// EntanglementEntropyOptions are options for computing the entanglement entropy.
+type EntanglementEntropyOptions struct {
+	renyi float64
+}
+
+// NewEntanglementEntropyOptions returns the default entanglement entropy options, which computes the von Neumann entropy.
+func NewEntanglementEntropyOptions() EntanglementEntropyOptions {
+	opt := EntanglementEntropyOptions{}
+	opt.renyi = 1
+	return opt
+}
+
+// Renyi sets the order n of the Renyi entropy log(sum_i p_i^n) / (1-n).
+// An order of 1 corresponds to the von Neumann entropy, and the order must be positive and finite.
+func (opt EntanglementEntropyOptions) Renyi(n float64) EntanglementEntropyOptions {
+	opt.renyi = n
+	return opt
+}
+
+// ReducedDensityMatrix returns the reduced density matrix rho_A = Tr_B |vec><vec| / <vec|vec> of the sites A in subsystem,
+// where B are the other sites, and sites are indexed by y*n[1] + x.
+// The rows and columns of rho_A are indexed by the spins of subsystem, in which the first site is the most significant bit as in the basis package.
+// The partial trace pairs the amplitudes of the basis states that agree on the bits of B.
+func ReducedDensityMatrix(n [2]int, vec []complex128, subsystem []int) ([][]complex128, error) {
+	norm, err := checkVector(n, vec)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	numSpins := n[0] * n[1]
+	for i, s := range subsystem {
+		if s < 0 || s >= numSpins || slices.Contains(subsystem[:i], s) {
+			return nil, errors.Errorf("%v %d", subsystem, numSpins)
+		}
+	}
+	env := make([]int, 0, numSpins-len(subsystem))
+	for s := range numSpins {
+		if !slices.Contains(subsystem, s) {
+			env = append(env, s)
+		}
+	}
+
+	// psi[a][b] is the amplitude of the spins a of subsystem and b of the environment.
+	psi := make([][]complex128, 1<<len(subsystem))
+	for a := range psi {
+		psi[a] = make([]complex128, 1<<len(env))
+	}
+	for i, c := range vec {
+		psi[gather(numSpins, i, subsystem)][gather(numSpins, i, env)] = c
+	}
+
+	rho := make([][]complex128, len(psi))
+	for a := range rho {
+		rho[a] = make([]complex128, len(psi))
+		for a1 := range rho[a] {
+			var v complex128
+			for b, c := range psi[a] {
+				c1 := psi[a1][b]
+				v += c * complex(real(c1), -imag(c1))
+			}
+			rho[a][a1] = v / complex(norm, 0)
+		}
+	}
+	return rho, nil
+}
+
+// EntanglementEntropy returns the entanglement entropy between the sites in subsystem and the other sites of vec, see ReducedDensityMatrix.
+// For a chain, the subsystem of the first l sites corresponds to the bond l of mps.EntanglementEntropy.
+func EntanglementEntropy(n [2]int, vec []complex128, subsystem []int, options ...EntanglementEntropyOptions) (float64, error) {
+	opt := NewEntanglementEntropyOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if !(opt.renyi > 0) || math.IsInf(opt.renyi, 1) {
+		return 0, errors.Errorf("%f", opt.renyi)
+	}
+	rho, err := ReducedDensityMatrix(n, vec, subsystem)
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	ps, err := mat.HermitianEigenvalues(rho)
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+
+	if opt.renyi == 1 {
+		var s float64
+		for _, p := range ps {
+			if p > 0 {
+				s -= p * math.Log(p)
+			}
+		}
+		return s, nil
+	}
+	var sum float64
+	for _, p := range ps {
+		if p > 0 {
+			sum += math.Pow(p, opt.renyi)
+		}
+	}
+	return math.Log(sum) / (1 - opt.renyi), nil
+}
+
+// gather returns the bits of sites in the basis state i of numSpins spins, packed in the order of sites with the first site as the most significant bit.
+func gather(numSpins, i int, sites []int) int {
+	var g int
+	for _, s := range sites {
+		g <<= 1
+		if i&basis.Mask(numSpins, s) != 0 {
+			g |= 1
+		}
+	}
+	return g
+}
@@ -0,0 +1,145 @@
+package exactdiag
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/qising/mps"
+	"github.com/fumin/tensor"
+)
+
+func TestEntanglementEntropy(t *testing.T) {
+	t.Parallel()
+	ghz := make([]complex128, 8)
+	ghz[0], ghz[7] = 1, 1
+	// A singlet between sites 0 and 2, and site 1 up.
+	singlet := make([]complex128, 8)
+	singlet[0b001], singlet[0b100] = 1, -1
+	tests := []struct {
+		n         [2]int
+		vec       []complex128
+		subsystem []int
+		entropy   float64
+	}{
+		{n: [2]int{3, 1}, vec: []complex128{0, 0, 0, 0, 0, 1i, 0, 0}, subsystem: []int{0}, entropy: 0},
+		{n: [2]int{3, 1}, vec: ghz, subsystem: []int{1}, entropy: math.Log(2)},
+		{n: [2]int{3, 1}, vec: ghz, subsystem: []int{2, 0}, entropy: math.Log(2)},
+		{n: [2]int{1, 3}, vec: singlet, subsystem: []int{0, 1}, entropy: math.Log(2)},
+		{n: [2]int{1, 3}, vec: singlet, subsystem: []int{1}, entropy: 0},
+		{n: [2]int{1, 3}, vec: singlet, subsystem: []int{}, entropy: 0},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			s, err := EntanglementEntropy(test.n, test.vec, test.subsystem)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if math.Abs(s-test.entropy) > 1e-9 {
+				t.Fatalf("%f %f", s, test.entropy)
+			}
+		})
+	}
+}
+
+func TestReducedDensityMatrix(t *testing.T) {
+	t.Parallel()
+	n := [2]int{2, 2}
+	vec := randVec(rand.New(rand.NewPCG(0, 0)), 16)
+	rho, err := ReducedDensityMatrix(n, vec, []int{3, 1})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// <Z_3 X_1> from the reduced density matrix equals that from the full state, where site 3 is the first bit of rho.
+	var zx complex128
+	for a := range 4 {
+		for a1 := range 4 {
+			op := complex128(mat.PauliZ[a>>1][a1>>1] * mat.PauliX[a&1][a1&1])
+			zx += rho[a1][a] * op
+		}
+	}
+	want, err := Correlation(n, vec, mat.PauliZ, mat.PauliX, 3, 1)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if cmplx.Abs(zx-want) > 1e-9 {
+		t.Fatalf("%v %v", zx, want)
+	}
+
+	// The entropies of complementary subsystems of a pure state are equal, and the Renyi entropy of order 2 is -log Tr rho^2.
+	var purity float64
+	for a := range rho {
+		for a1 := range rho {
+			purity += real(rho[a][a1] * rho[a1][a])
+		}
+	}
+	opt := NewEntanglementEntropyOptions().Renyi(2)
+	s, err := EntanglementEntropy(n, vec, []int{3, 1}, opt)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	sc, err := EntanglementEntropy(n, vec, []int{0, 2}, opt)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if math.Abs(s-sc) > 1e-9 || math.Abs(s+math.Log(purity)) > 1e-9 {
+		t.Fatalf("%f %f %f", s, sc, -math.Log(purity))
+	}
+
+	for _, renyi := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if _, err := EntanglementEntropy(n, vec, []int{3, 1}, NewEntanglementEntropyOptions().Renyi(renyi)); err == nil {
+			t.Fatalf("expected error %f", renyi)
+		}
+	}
+	if _, err := ReducedDensityMatrix(n, vec, []int{1, 1}); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := ReducedDensityMatrix(n, vec, []int{4}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestEntanglementEntropyMPS(t *testing.T) {
+	t.Parallel()
+	n, h := [2]int{6, 1}, complex64(1)
+	m, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+	TransverseFieldIsing(m, buf, n, h)
+	ground := m.COO().Eigen()[0].Vec
+
+	ws := mps.Ising(n, h)
+	fs := make([]*tensor.Dense, 0, len(ws))
+	for range ws {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	var bufs [10]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	ms := mps.RandMPS(ws, 8)
+	if err := mps.SearchGroundState(fs, ws, ms, bufs, mps.NewSearchGroundStateOptions().MaxBondDim(8)); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	for bond := 1; bond < n[0]; bond++ {
+		subsystem := make([]int, bond)
+		for i := range subsystem {
+			subsystem[i] = i
+		}
+		s, err := EntanglementEntropy(n, ground, subsystem)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		want, err := mps.EntanglementEntropy(ms, bond, [5]*tensor.Dense(bufs[:5]))
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if math.Abs(s-float64(want)) > 1e-3 {
+			t.Fatalf("%d %f %f", bond, s, want)
+		}
+	}
+}
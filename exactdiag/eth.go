@@ -0,0 +1,106 @@
+package exactdiag
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/pkg/errors"
+)
+
+// EigenstateMagnetization is the magnetization of an eigenstate, see EigenstateMagnetizations.
+type EigenstateMagnetization struct {
+	// EnergyDensity is E/N, where E is the real part of the eigenvalue and N the number of spins.
+	EnergyDensity float64
+	// Magnetization is <|M|>/N, where M is the sum of the Z spins, as in Statistics.
+	Magnetization float64
+	// M2 is <M^2>/N^2.
+	M2 float64
+}
+
+// EigenstateMagnetizations returns the magnetizations of each eigenstate in vvs of the lattice of shape n, such as the full spectrum returned by mat.COO.Eigen.
+func EigenstateMagnetizations(n [2]int, vvs []mat.ValVec) ([]EigenstateMagnetization, error) {
+	numSpins := n[0] * n[1]
+	nf := float64(numSpins)
+	ems := make([]EigenstateMagnetization, 0, len(vvs))
+	for i, vv := range vvs {
+		if len(vv.Vec) != 1<<numSpins {
+			return nil, errors.Errorf("%d %d %d", i, len(vv.Vec), 1<<numSpins)
+		}
+		mag := newMagnetization(numSpins)
+		for j, amplitude := range vv.Vec {
+			mag.add(j, amplitude)
+		}
+		if mag.totalProb == 0 {
+			return nil, errors.Errorf("zero vector %d", i)
+		}
+		em := EigenstateMagnetization{EnergyDensity: real(vv.Val) / nf}
+		em.Magnetization = mag.m / mag.totalProb / nf
+		em.M2 = mag.m2 / mag.totalProb / (nf * nf)
+		ems = append(ems, em)
+	}
+	return ems, nil
+}
+
+// MagnetizationBin are the microcanonical averages of the eigenstates whose energy densities are in [EnergyLo, EnergyHi), see MagnetizationHistogram.
+type MagnetizationBin struct {
+	EnergyLo, EnergyHi float64
+	// Count is the number of eigenstates in the bin, and the averages are zero if it is zero.
+	Count int
+	// Magnetization and M2 are the averages over the eigenstates in the bin of their <|M|>/N and <M^2>/N^2.
+	Magnetization, M2 float64
+	// MagnetizationStd and M2Std are the standard deviations over the eigenstates in the bin,
+	// which decay exponentially with the number of spins if the eigenstate thermalization hypothesis holds, and not if the model is integrable.
+	MagnetizationStd, M2Std float64
+}
+
+// MagnetizationHistogram bins the eigenstates vvs of the lattice of shape n into numBins bins of equal width of the energy density,
+// spanning from the lowest to the highest one, whose last bin includes the highest energy density.
+// Comparing the averages of a bin to the thermal averages at the same energy density, and the fluctuations between neighboring eigenstates across system sizes,
+// tests the eigenstate thermalization hypothesis, where integrability is broken by terms such as IsingOptions.LongitudinalField.
+// Like ThermalStatistics, the eigenstates are those of the full space, including the degenerate eigenstates of both parity sectors.
+// See L. D'Alessio, Y. Kafri, A. Polkovnikov, and M. Rigol, From quantum chaos and eigenstate thermalization to statistical mechanics and thermodynamics, Adv. Phys. 65, 239 (2016).
+func MagnetizationHistogram(n [2]int, vvs []mat.ValVec, numBins int) ([]MagnetizationBin, error) {
+	if numBins < 1 || len(vvs) == 0 {
+		return nil, errors.Errorf("%d %d", numBins, len(vvs))
+	}
+	ems, err := EigenstateMagnetizations(n, vvs)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	lo := slices.MinFunc(ems, func(a, b EigenstateMagnetization) int { return cmp.Compare(a.EnergyDensity, b.EnergyDensity) }).EnergyDensity
+	hi := slices.MaxFunc(ems, func(a, b EigenstateMagnetization) int { return cmp.Compare(a.EnergyDensity, b.EnergyDensity) }).EnergyDensity
+	width := (hi - lo) / float64(numBins)
+
+	bins := make([]MagnetizationBin, numBins)
+	for i := range bins {
+		bins[i].EnergyLo = lo + float64(i)*width
+		bins[i].EnergyHi = lo + float64(i+1)*width
+	}
+	// The sums of squares of the values, which become the standard deviations.
+	m2s, m4s := make([]float64, numBins), make([]float64, numBins)
+	for _, em := range ems {
+		i := numBins - 1
+		if width > 0 {
+			i = min(int((em.EnergyDensity-lo)/width), numBins-1)
+		}
+		bins[i].Count++
+		bins[i].Magnetization += em.Magnetization
+		bins[i].M2 += em.M2
+		m2s[i] += em.Magnetization * em.Magnetization
+		m4s[i] += em.M2 * em.M2
+	}
+	for i := range bins {
+		b := &bins[i]
+		if b.Count == 0 {
+			continue
+		}
+		c := float64(b.Count)
+		b.Magnetization /= c
+		b.M2 /= c
+		b.MagnetizationStd = math.Sqrt(max(0, m2s[i]/c-b.Magnetization*b.Magnetization))
+		b.M2Std = math.Sqrt(max(0, m4s[i]/c-b.M2*b.M2))
+	}
+	return bins, nil
+}
@@ -0,0 +1,94 @@
+package exactdiag
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag/mat"
+)
+
+func TestMagnetizationHistogram(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n       [2]int
+		h       complex64
+		g       complex64
+		numBins int
+	}{
+		{n: [2]int{6, 1}, h: 0, numBins: 1},
+		{n: [2]int{6, 1}, h: 1, numBins: 5},
+		{n: [2]int{7, 1}, h: 1.05, g: 0.5, numBins: 8},
+		{n: [2]int{2, 3}, h: 0.7, g: 0.2, numBins: 20},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			m, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+			TransverseFieldIsing(m, buf, test.n, test.h, NewIsingOptions().LongitudinalField(test.g))
+			vvs := m.COO().Eigen()
+			bins, err := MagnetizationHistogram(test.n, vvs, test.numBins)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(bins) != test.numBins {
+				t.Fatalf("%d %d", len(bins), test.numBins)
+			}
+
+			// The bins cover the spectrum, and the average over all eigenstates is the infinite temperature average.
+			numSpins := float64(test.n[0] * test.n[1])
+			var count int
+			var magnetization, m2 float64
+			for j, b := range bins {
+				count += b.Count
+				magnetization += float64(b.Count) * b.Magnetization
+				m2 += float64(b.Count) * b.M2
+				if b.MagnetizationStd < 0 || b.M2Std < 0 || (b.Count == 0 && (b.Magnetization != 0 || b.M2 != 0)) {
+					t.Fatalf("%d %+v", j, b)
+				}
+			}
+			if count != len(vvs) {
+				t.Fatalf("%d %d", count, len(vvs))
+			}
+			lo, hi := real(vvs[0].Val), real(vvs[0].Val)
+			for _, vv := range vvs {
+				lo, hi = min(lo, real(vv.Val)), max(hi, real(vv.Val))
+			}
+			if math.Abs(bins[0].EnergyLo-lo/numSpins) > 1e-9 || math.Abs(bins[len(bins)-1].EnergyHi-hi/numSpins) > 1e-9 {
+				t.Fatalf("%+v %+v %f %f", bins[0], bins[len(bins)-1], lo, hi)
+			}
+			th, err := ThermalStatistics(test.n, 0, vvs)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			magnetization, m2 = magnetization/float64(count), m2/float64(count)
+			if math.Abs(magnetization-th.Magnetization) > 1e-6 || math.Abs(m2-th.M2) > 1e-6 {
+				t.Fatalf("%f %f %+v", magnetization, m2, th)
+			}
+		})
+	}
+}
+
+func TestMagnetizationHistogramClassical(t *testing.T) {
+	t.Parallel()
+	// Without the transverse field, the energy density of a basis state of the open chain is -(N-1-2w)/N, where w is the number of domain walls,
+	// which falls into a bin of its own, and the lowest bin holds the two ferromagnetic states, whose magnetizations are 1.
+	n := [2]int{5, 1}
+	m, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+	TransverseFieldIsing(m, buf, n, 0)
+	bins, err := MagnetizationHistogram(n, m.COO().Eigen(), 5)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if b := bins[0]; b.Count != 2 || math.Abs(b.Magnetization-1) > 1e-6 || math.Abs(b.M2-1) > 1e-6 || b.MagnetizationStd > 1e-6 {
+		t.Fatalf("%+v", b)
+	}
+	// The highest bin holds the two antiferromagnetic states, whose |M| is 1.
+	if b := bins[4]; b.Count != 2 || math.Abs(b.Magnetization-0.2) > 1e-6 || math.Abs(b.M2-0.04) > 1e-6 {
+		t.Fatalf("%+v", b)
+	}
+
+	if _, err := MagnetizationHistogram(n, m.COO().Eigen(), 0); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -2,139 +2,272 @@ package exactdiag
 
 import (
 	"cmp"
-	"encoding/csv"
 	"fmt"
+	"io"
 	"math"
-	"math/cmplx"
-	"os"
-	"path/filepath"
 	"slices"
-	"strconv"
 
+	"github.com/fumin/qising/exactdiag/basis"
 	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/qising/linalg"
 	"github.com/pkg/errors"
 )
 
 var (
 	identity = mat.COOIdentity(2)
+	pauliX   = mat.M(mat.PauliX)
+	pauliZ   = mat.M(mat.PauliZ)
 )
 
-func TransverseFieldIsing(hamiltonian, buf mat.Matrix, n [2]int, h complex64) {
+// IsingOptions are options for building the transverse field Ising hamiltonian.
+type IsingOptions struct {
+	periodic     [2]bool
+	longitudinal complex64
+	parity       int
+	coupling     complex64
+	spinHalf     bool
+	fieldAlongZ  bool
+	coo          mat.WriteCOOOptions
+	progress     func(done, total int)
+	interrupt    <-chan struct{}
+}
+
+// NewIsingOptions returns the default options, which has open boundary conditions.
+// The default conventions are the hamiltonian -J sum_<a, b> Z_a Z_b - h sum_a X_a in terms of Pauli matrices, with the ferromagnetic J = 1.
+func NewIsingOptions() IsingOptions {
+	opt := IsingOptions{}
+	opt.coupling = 1
+	opt.coo = mat.NewWriteCOOOptions()
+	return opt
+}
+
+// Periodic sets whether the boundary conditions are periodic in each direction of the lattice.
+// Wrap-around bonds are added only in directions of length greater than 2, since otherwise they coincide with existing bonds.
+func (opt IsingOptions) Periodic(p [2]bool) IsingOptions {
+	opt.periodic = p
+	return opt
+}
+
+// LongitudinalField sets the field g of the additional term -g * sum_i Z_i.
+// An imaginary g gives the non-Hermitian Yang-Lee model.
+func (opt IsingOptions) LongitudinalField(g complex64) IsingOptions {
+	opt.longitudinal = g
+	return opt
+}
+
+// ParitySector restricts the hamiltonian to the eigenspace of the global spin flip P = prod_i X_i with eigenvalue parity, which is 1 or -1,
+// halving the dimension of the hamiltonian.
+// The i-th basis state of the sector is (|0 i> + parity*|1 ~i>)/sqrt(2), where the first spin of the lattice is fixed to the +1 eigenstate of Z,
+// and ~i flips the other spins.
+// Since the longitudinal field does not commute with P, the two options are exclusive.
+// The default parity 0 builds the hamiltonian on the full space.
+func (opt IsingOptions) ParitySector(parity int) IsingOptions {
+	opt.parity = parity
+	return opt
+}
+
+// Coupling sets the overall coupling J, which multiplies the coupling of every bond.
+// A negative J is antiferromagnetic.
+func (opt IsingOptions) Coupling(j complex64) IsingOptions {
+	opt.coupling = j
+	return opt
+}
+
+// SpinHalf sets whether the hamiltonian is in terms of the spin operators S = sigma/2 instead of the Pauli matrices sigma,
+// which divides the couplings by 4 and the transverse and longitudinal fields by 2.
+// For example, the critical point h = 1 of the chain with Pauli matrices becomes h = 1/2 with spin operators.
+// Observables such as the magnetization of Statistics remain in terms of the Pauli matrices.
+func (opt IsingOptions) SpinHalf(s bool) IsingOptions {
+	opt.spinHalf = s
+	return opt
+}
+
+// FieldAlongZ sets whether the transverse field is along Z and the couplings along X, -J sum_<a, b> X_a X_b - h sum_a Z_a - g sum_a X_a,
+// which is the convention of the Jordan-Wigner solution of the chain.
+// The spectrum is unchanged, but the magnetization of Statistics remains along Z, which becomes the transverse axis.
+// It is supported only by TransverseFieldIsingDisordered without the parity sector option.
+func (opt IsingOptions) FieldAlongZ(z bool) IsingOptions {
+	opt.fieldAlongZ = z
+	return opt
+}
+
+// WriteCOOOptions sets the file format of the hamiltonian written by TransverseFieldIsingExplicit.
+func (opt IsingOptions) WriteCOOOptions(o mat.WriteCOOOptions) IsingOptions {
+	opt.coo = o
+	return opt
+}
+
+// Progress sets the function called by TransverseFieldIsingExplicit with the number of rows written and the total,
+// every progressInterval rows and when all rows are written, which is cheap enough to be throttled by the caller.
+func (opt IsingOptions) Progress(fn func(done, total int)) IsingOptions {
+	opt.progress = fn
+	return opt
+}
+
+// Interrupt sets a channel, such as the Done channel of a context.Context, whose closing stops TransverseFieldIsingExplicit within progressInterval rows.
+// The hamiltonian in the directory is then incomplete, and the returned error has the cause linalg.ErrInterrupted.
+func (opt IsingOptions) Interrupt(c <-chan struct{}) IsingOptions {
+	opt.interrupt = c
+	return opt
+}
+
+// progressInterval is the number of rows between the calls of the progress function of IsingOptions, and between the checks of the interrupt channel.
+const progressInterval = 1 << 16
+
+// checkParity checks that the parity sector option is consistent.
+func (opt IsingOptions) checkParity() error {
+	switch opt.parity {
+	case 0:
+		return nil
+	case 1, -1:
+		if opt.longitudinal != 0 || opt.fieldAlongZ {
+			return errors.Errorf("%d %v %t", opt.parity, opt.longitudinal, opt.fieldAlongZ)
+		}
+		return nil
+	default:
+		return errors.Errorf("%d", opt.parity)
+	}
+}
+
+// scales returns the factors of the couplings and the fields of the spin convention.
+func (opt IsingOptions) scales() (complex64, complex64) {
+	if opt.spinHalf {
+		return opt.coupling / 4, 0.5
+	}
+	return opt.coupling, 1
+}
+
+// YangLeeIsing builds the Ising model in a transverse field h and an imaginary longitudinal field i*lambda, whose hamiltonian is non-Hermitian.
+// See M. E. Fisher, Yang-Lee Edge Singularity and phi^3 Field Theory, Phys. Rev. Lett. 40, 1610 (1978).
+func YangLeeIsing(hamiltonian, buf mat.Matrix, n [2]int, h complex64, lambda float32, options ...IsingOptions) {
+	opt := NewIsingOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	TransverseFieldIsing(hamiltonian, buf, n, h, opt.LongitudinalField(complex(0, lambda)))
+}
+
+func TransverseFieldIsing(hamiltonian, buf mat.Matrix, n [2]int, h complex64, options ...IsingOptions) {
+	coupling := func(a, b [2]int) complex64 { return 1 }
+	field := func(a [2]int) complex64 { return h }
+	TransverseFieldIsingDisordered(hamiltonian, buf, n, coupling, field, options...)
+}
+
+// TransverseFieldIsingDisordered builds the random transverse field Ising hamiltonian -sum_<a, b> J_ab Z_a Z_b - sum_a h_a X_a on a lattice of shape n,
+// where the coupling J_ab of each bond is given by coupling(a, b), and the field h_a of each site by field(a).
+// The couplings are multiplied by the overall coupling of options, and the operators and factors follow the conventions of options, see IsingOptions.
+// coupling is called once per bond, and field once per site, hence both may draw random numbers, or look up the couplings in a slice.
+// See D. S. Fisher, Critical behavior of random transverse-field Ising spin chains, Phys. Rev. B 51, 6411 (1995).
+func TransverseFieldIsingDisordered(hamiltonian, buf mat.Matrix, n [2]int, coupling func(a, b [2]int) complex64, field func(a [2]int) complex64, options ...IsingOptions) {
+	opt := NewIsingOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if err := opt.checkParity(); err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
 	numSpins := n[0] * n[1]
-	hamiltonian.Zeros(1<<numSpins, 1<<numSpins)
+	dim := 1 << numSpins
+	addTerm := AddTerm
+	if opt.parity != 0 {
+		dim /= 2
+		addTerm = func(hamiltonian, buf mat.Matrix, n [2]int, c complex64, ops map[[2]int]*mat.COO) {
+			addParityTerm(hamiltonian, buf, n, opt.parity, c, ops)
+		}
+	}
+	hamiltonian.Zeros(dim, dim)
+	j, hs := opt.scales()
+	couplingOp, fieldOp := pauliZ, pauliX
+	if opt.fieldAlongZ {
+		couplingOp, fieldOp = pauliX, pauliZ
+	}
 
+	bonds := make([][2]int, 0, 2)
 	for y := 0; y < n[0]; y++ {
 		for x := 0; x < n[1]; x++ {
-			up := y - 1
-			if up >= 0 {
-				coupling(hamiltonian, n, [2]int{up, x}, [2]int{y, x}, buf)
+			for _, b := range neighbors(bonds, n, y, x, opt.periodic) {
+				addTerm(hamiltonian, buf, n, -j*coupling(b, [2]int{y, x}), map[[2]int]*mat.COO{b: couplingOp, {y, x}: couplingOp})
 			}
 
-			left := x - 1
-			if left >= 0 {
-				coupling(hamiltonian, n, [2]int{y, left}, [2]int{y, x}, buf)
+			addTerm(hamiltonian, buf, n, -hs*field([2]int{y, x}), map[[2]int]*mat.COO{{y, x}: fieldOp})
+			if opt.longitudinal != 0 {
+				AddOneSiteTerm(hamiltonian, buf, n, -hs*opt.longitudinal, couplingOp, [2]int{y, x})
 			}
-
-			magnetic(hamiltonian, n, [2]int{y, x}, h, buf)
 		}
 	}
 }
 
-func TransverseFieldIsingExplicit(dir string, n [2]int, h complex64) error {
-	numSpins := n[0] * n[1]
-	shapePath := filepath.Join(dir, mat.FnameShape)
-	if err := os.WriteFile(shapePath, []byte(fmt.Sprintf("%d,%d", 1<<numSpins, 1<<numSpins)), 0644); err != nil {
+func TransverseFieldIsingExplicit(dir string, n [2]int, h complex64, options ...IsingOptions) error {
+	opt := NewIsingOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if err := opt.checkParity(); err != nil {
 		return errors.Wrap(err, "")
 	}
-
-	cooPath := filepath.Join(dir, mat.FnameCOO)
-	f, err := os.Create(cooPath)
+	if opt.fieldAlongZ {
+		return errors.Errorf("field along Z")
+	}
+	_, hs := opt.scales()
+	numSpins := n[0] * n[1]
+	dim := 1 << numSpins
+	if opt.parity != 0 {
+		dim /= 2
+	}
+	w, err := mat.NewCOOWriter(dir, dim, dim, opt.coo)
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
-	w := csv.NewWriter(f)
 
 	// bonds is a reusable buffer for recording coupling bonds.
 	bonds := make([][2]int, 0, 2)
 	// flipped is a reusable buffer for the flipped state.
 	flipped := make([]byte, numSpins)
-	// prev is the previously written value for compression.
-	prev := vRowCol{v: complex64(cmplx.NaN()), row: -1, col: -1}
 	vrcs := make([]vRowCol, 0)
 Loop:
-	for i, state := range bits(numSpins) {
+	for i, state := range basis.States(numSpins) {
+		// The basis states of a parity sector are those whose first spin is 0, which come first.
+		if i >= dim {
+			break
+		}
 		vrcs = vrcs[:0]
-		vrcs = couplingExplicit(vrcs, n, i, state, bonds)
-		vrcs = magneticExplicit(vrcs, n, h, i, state, flipped)
+		vrcs = couplingExplicit(vrcs, n, opt, i, state, bonds)
+		vrcs = magneticExplicit(vrcs, n, hs*h, opt.parity, i, state, flipped)
 
 		slices.SortFunc(vrcs, rowMajor)
 		for _, v := range vrcs {
-			var vStr string
-			if v.v != prev.v {
-				vStr = mat.FormatNumpy(v.v)
-			}
-			var rowStr string
-			if v.row != prev.row {
-				rowStr = strconv.Itoa(v.row)
-			}
-			colStr := strconv.Itoa(v.col)
-
-			if err1 := w.Write([]string{vStr, rowStr, colStr}); err1 != nil && err == nil {
+			if err1 := w.Write(v.row, v.col, v.v); err1 != nil && err == nil {
 				err = errors.Wrap(err1, "")
 				break Loop
 			}
-			prev = v
 		}
 
-		if i%1e6 == 0 {
-			// log.Printf("%d/%d %.2f", i, int(1)<<numSpins, float64(i)/float64(int(1)<<numSpins))
+		if done := i + 1; opt.progress != nil && (done%progressInterval == 0 || done == dim) {
+			opt.progress(done, dim)
+		}
+		if (i+1)%progressInterval == 0 {
+			select {
+			case <-opt.interrupt:
+				err = errors.WithStack(linalg.ErrInterrupted)
+				break Loop
+			default:
+			}
 		}
 	}
 
-	w.Flush()
-	if err1 := w.Error(); err1 != nil && err == nil {
-		err = errors.Wrap(err1, "")
-	}
-	if err1 := f.Close(); err1 != nil && err == nil {
+	if err1 := w.Close(); err1 != nil && err == nil {
 		err = errors.Wrap(err1, "")
 	}
 	return err
 }
 
-func pickSpinUp(upState []int8, state []byte) {
-	ups := 0
-	for _, b := range state {
-		if b == 1 {
-			ups++
-		}
-	}
-
-	downs := len(state) - ups
-	switch {
-	case ups < downs:
-		for i, b := range state {
-			switch b {
-			case 0:
-				upState[i] = 1
-			default:
-				upState[i] = -1
-			}
-		}
-	default:
-		for i, b := range state {
-			switch b {
-			case 0:
-				upState[i] = -1
-			default:
-				upState[i] = 1
-			}
-		}
-	}
-}
-
 type Statistics struct {
-	EigenValue     []float64
+	EigenValue []float64
+	// EigenValueImag are the imaginary parts of the eigenvalues, which are non-zero for non-Hermitian hamiltonians.
+	EigenValueImag []float64
 	Magnetization  float64
+	// M2 is <M^2>/N^2, where M is the sum of the Z spins and N the number of spins.
+	M2             float64
 	BinderCumulant float64
 }
 
@@ -142,99 +275,222 @@ func GetStatistics(n [2]int, vvs []mat.ValVec) (Statistics, error) {
 	var stats Statistics
 	for _, vv := range vvs {
 		stats.EigenValue = append(stats.EigenValue, real(vv.Val))
+		stats.EigenValueImag = append(stats.EigenValueImag, imag(vv.Val))
 	}
 	ground := vvs[0]
 	numSpins := n[0] * n[1]
 	if len(ground.Vec) != 1<<numSpins {
 		return Statistics{}, errors.Errorf("%d %d", len(ground.Vec), 1<<numSpins)
 	}
-	// spinUpBasis is the basis where the majority of spins are up.
-	spinUpBasis := make([]int8, numSpins)
-	var totalProb float64
-	var m2 float64
-	for i, fullBasis := range bits(numSpins) {
-		pickSpinUp(spinUpBasis, fullBasis)
-		amplitude := ground.Vec[i]
-		probability := real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude)
-
-		var basisM float64
-		for _, spin := range spinUpBasis {
-			basisM += float64(spin)
+	m := newMagnetization(numSpins)
+	for i, amplitude := range ground.Vec {
+		m.add(i, amplitude)
+	}
+	if err := m.statistics(&stats); err != nil {
+		return Statistics{}, errors.Wrap(err, "")
+	}
+	return stats, nil
+}
+
+// ReadStatistics is like GetStatistics, but streams the ground state from the eig.csv file r, using memory independent of its size.
+func ReadStatistics(n [2]int, r io.Reader) (Statistics, error) {
+	er, err := mat.NewEigReader(r)
+	if err != nil {
+		return Statistics{}, errors.Wrap(err, "")
+	}
+	var stats Statistics
+	for _, v := range er.Eigenvalues() {
+		stats.EigenValue = append(stats.EigenValue, real(v))
+		stats.EigenValueImag = append(stats.EigenValueImag, imag(v))
+	}
+	numSpins := n[0] * n[1]
+	m := newMagnetization(numSpins)
+	err = er.Column(0, func(i int, amplitude complex128) error {
+		if i >= 1<<numSpins {
+			return errors.Errorf("%d %d", i, 1<<numSpins)
 		}
+		m.add(i, amplitude)
+		return nil
+	})
+	if err != nil {
+		return Statistics{}, errors.Wrap(err, "")
+	}
+	if m.count != 1<<numSpins {
+		return Statistics{}, errors.Errorf("%d %d", m.count, 1<<numSpins)
+	}
+	if err := m.statistics(&stats); err != nil {
+		return Statistics{}, errors.Wrap(err, "")
+	}
+	return stats, nil
+}
+
+// magnetization accumulates the moments of the magnetization of a state, one basis state at a time.
+type magnetization struct {
+	// state and spinUpBasis are reusable buffers for the basis state, and its spins where the majority is up.
+	state       []byte
+	spinUpBasis []int8
+
+	count     int
+	totalProb float64
+	m         float64
+	m2        float64
+	m4        float64
+}
 
-		totalProb += probability
-		stats.Magnetization += probability * basisM
-		stats.BinderCumulant += probability * math.Pow(basisM, 4)
-		m2 += probability * math.Pow(basisM, 2)
+func newMagnetization(numSpins int) *magnetization {
+	return &magnetization{state: make([]byte, numSpins), spinUpBasis: make([]int8, numSpins)}
+}
+
+// add adds the basis state i with the given amplitude.
+func (m *magnetization) add(i int, amplitude complex128) {
+	basis.Decode(m.state, i)
+	basis.MajorityUp(m.spinUpBasis, m.state)
+	probability := real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude)
+
+	var basisM float64
+	for _, spin := range m.spinUpBasis {
+		basisM += float64(spin)
 	}
-	if math.Abs(totalProb-1) > 1e-3 {
-		return Statistics{}, errors.Errorf("%f", totalProb)
+
+	m.count++
+	m.totalProb += probability
+	m.m += probability * basisM
+	m.m2 += probability * math.Pow(basisM, 2)
+	m.m4 += probability * math.Pow(basisM, 4)
+}
+
+// statistics stores the magnetization and Binder cumulant in stats.
+func (m *magnetization) statistics(stats *Statistics) error {
+	if math.Abs(m.totalProb-1) > 1e-3 {
+		return errors.Errorf("%f", m.totalProb)
 	}
+	stats.Magnetization = m.m / float64(len(m.state))
+	stats.M2 = m.m2 / float64(len(m.state)*len(m.state))
+	stats.BinderCumulant = 1 - m.m4/(m.m2*m.m2)/3
+	return nil
+}
 
-	stats.Magnetization /= float64(numSpins)
-	stats.BinderCumulant /= (m2 * m2)
-	stats.BinderCumulant = 1 - stats.BinderCumulant/3
-	return stats, nil
+// AddOneSiteTerm adds the term c * op_i to hamiltonian, where op acts on site i of the lattice of shape n.
+// buf is a reusable buffer for building the term.
+func AddOneSiteTerm(hamiltonian, buf mat.Matrix, n [2]int, c complex64, op *mat.COO, i [2]int) {
+	AddTerm(hamiltonian, buf, n, c, map[[2]int]*mat.COO{i: op})
 }
 
-func coupling(hamiltonian mat.Matrix, n [2]int, i [2]int, j [2]int, system mat.Matrix) {
-	system.Scalar(1)
+// AddTwoSiteTerm adds the term c * opA_i opB_j to hamiltonian, where i and j are distinct sites of the lattice of shape n.
+// buf is a reusable buffer for building the term.
+func AddTwoSiteTerm(hamiltonian, buf mat.Matrix, n [2]int, c complex64, opA, opB *mat.COO, i, j [2]int) {
+	if i == j {
+		panic(fmt.Sprintf("%#v %#v", i, j))
+	}
+	AddTerm(hamiltonian, buf, n, c, map[[2]int]*mat.COO{i: opA, j: opB})
+}
+
+// AddTerm adds the term c * (tensor product of ops) to hamiltonian.
+// ops maps sites of the lattice of shape n to single spin operators, and sites not in ops are acted on by the identity.
+// buf is a reusable buffer for building the term.
+func AddTerm(hamiltonian, buf mat.Matrix, n [2]int, c complex64, ops map[[2]int]*mat.COO) {
+	buf.Scalar(1)
 	for y := 0; y < n[0]; y++ {
 		for x := 0; x < n[1]; x++ {
-			yx := [2]int{y, x}
-
+			op, ok := ops[[2]int{y, x}]
 			switch {
-			case yx == i || yx == j:
-				system.Kron(mat.M(mat.PauliZ))
+			case ok:
+				buf.Kron(op)
 			default:
-				system.Kron(identity)
+				buf.Kron(identity)
 			}
 		}
 	}
 
-	hamiltonian.Add(-1, system)
+	hamiltonian.Add(c, buf)
 }
 
-func magnetic(hamiltonian mat.Matrix, n [2]int, i [2]int, h complex64, system mat.Matrix) {
-	system.Scalar(1)
+// addParityTerm is like AddTerm, but adds the term restricted to the parity sector of the spin flip P = prod_i X_i, see IsingOptions.ParitySector.
+// ops must commute with P, and the operator on the first site {0, 0}, whose spin is fixed in the sector basis, must be either Z or X.
+// On the sector, Z_0 acts as the identity when paired with another Z, and X_0 acts as parity * prod_{i>0} X_i,
+// hence the term is the tensor product of the operators on the other sites.
+func addParityTerm(hamiltonian, buf mat.Matrix, n [2]int, parity int, c complex64, ops map[[2]int]*mat.COO) {
+	first := [2]int{0, 0}
+	op, ok := ops[first]
+	flipAll := false
+	switch {
+	case !ok:
+	case op.Equal(pauliZ):
+	case op.Equal(pauliX):
+		flipAll = true
+		c *= complex(float32(parity), 0)
+	default:
+		panic(fmt.Sprintf("%s", op))
+	}
+
+	buf.Scalar(1)
 	for y := 0; y < n[0]; y++ {
 		for x := 0; x < n[1]; x++ {
-			yx := [2]int{y, x}
+			if y == 0 && x == 0 {
+				continue
+			}
+			op, ok := ops[[2]int{y, x}]
 			switch {
-			case yx == i:
-				system.Kron(mat.M(mat.PauliX))
+			case flipAll && ok:
+				// The product of X with another operator is not needed by the Ising model.
+				panic(fmt.Sprintf("%v %s", [2]int{y, x}, op))
+			case flipAll:
+				buf.Kron(pauliX)
+			case ok:
+				buf.Kron(op)
 			default:
-				system.Kron(identity)
+				buf.Kron(identity)
 			}
 		}
 	}
 
-	hamiltonian.Add(-h, system)
+	hamiltonian.Add(c, buf)
 }
 
-func couplingExplicit(vrcs []vRowCol, n [2]int, i int, state []byte, bonds [][2]int) []vRowCol {
+// neighbors returns the up and left neighbors of site {y, x}, reusing the bonds buffer.
+func neighbors(bonds [][2]int, n [2]int, y, x int, periodic [2]bool) [][2]int {
+	bonds = bonds[:0]
+	up := y - 1
+	if up < 0 && periodic[0] && n[0] > 2 {
+		up = n[0] - 1
+	}
+	if up >= 0 {
+		bonds = append(bonds, [2]int{up, x})
+	}
+	left := x - 1
+	if left < 0 && periodic[1] && n[1] > 2 {
+		left = n[1] - 1
+	}
+	if left >= 0 {
+		bonds = append(bonds, [2]int{y, left})
+	}
+	return bonds
+}
+
+// couplingExplicit appends the diagonal coupling and longitudinal field terms of row i, where state is the i-th basis state.
+func couplingExplicit(vrcs []vRowCol, n [2]int, opt IsingOptions, i int, state []byte, bonds [][2]int) []vRowCol {
+	j, hs := opt.scales()
+	g := hs * opt.longitudinal
 	var diag complex64
 	for y := range n[0] {
 		for x := range n[1] {
 			spin := state[y*n[1]+x]
 
-			bonds = bonds[:0]
-			up := y - 1
-			if up >= 0 {
-				bonds = append(bonds, [2]int{up, x})
-			}
-			left := x - 1
-			if left >= 0 {
-				bonds = append(bonds, [2]int{y, left})
+			// Spin 0 is the +1 eigenstate of Z.
+			switch spin {
+			case 0:
+				diag -= g
+			default:
+				diag += g
 			}
 
-			for _, b := range bonds {
+			for _, b := range neighbors(bonds, n, y, x, opt.periodic) {
 				spinOther := state[b[0]*n[1]+b[1]]
 				switch {
 				case spinOther == spin:
-					diag -= 1
+					diag -= j
 				default:
-					diag += 1
+					diag += j
 				}
 			}
 		}
@@ -245,7 +501,9 @@ func couplingExplicit(vrcs []vRowCol, n [2]int, i int, state []byte, bonds [][2]
 	return vrcs
 }
 
-func magneticExplicit(vrcs []vRowCol, n [2]int, h complex64, i int, state []byte, flipped []byte) []vRowCol {
+// magneticExplicit appends the transverse field terms of row i, where state is the i-th basis state.
+// If parity is non-zero, a flipped state whose first spin is 1 is the partner of the basis state with all spins flipped, with which it differs by the factor parity.
+func magneticExplicit(vrcs []vRowCol, n [2]int, h complex64, parity int, i int, state []byte, flipped []byte) []vRowCol {
 	for y := range n[0] {
 		for x := range n[1] {
 			copy(flipped, state)
@@ -257,47 +515,18 @@ func magneticExplicit(vrcs []vRowCol, n [2]int, h complex64, i int, state []byte
 				flipped[idx] = 1
 			}
 
-			col := bitIndex(flipped)
-			vrcs = append(vrcs, vRowCol{v: -h, row: i, col: col})
-		}
-	}
-	return vrcs
-}
-
-func indexBit(state []byte, n, i int) {
-	stateStr := strconv.FormatInt(int64(i), 2)
-
-	state = state[:0]
-	// Pad zeros in front.
-	for j := 0; j < n-len(stateStr); j++ {
-		state = append(state, 0)
-	}
-	for _, bit := range []byte(stateStr) {
-		state = append(state, bit-'0')
-	}
-}
-
-func bits(n int) func(yield func(int, []byte) bool) {
-	state := make([]byte, n)
-	return func(yield func(int, []byte) bool) {
-		numStates := 1 << n
-		for i := range numStates {
-			indexBit(state, n, i)
-			if !yield(i, state) {
-				return
+			v := -h
+			if parity != 0 && flipped[0] == 1 {
+				for j := range flipped {
+					flipped[j] = 1 - flipped[j]
+				}
+				v *= complex(float32(parity), 0)
 			}
+			col := basis.Encode(flipped)
+			vrcs = append(vrcs, vRowCol{v: v, row: i, col: col})
 		}
 	}
-}
-
-func bitIndex(state []byte) int {
-	idx := 0
-	for i := len(state) - 1; i >= 0; i-- {
-		if state[i] == 1 {
-			idx += 1 << (len(state) - 1 - i)
-		}
-	}
-	return idx
+	return vrcs
 }
 
 type vRowCol struct {
@@ -7,9 +7,15 @@ import (
 	"math"
 	"math/cmplx"
 	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/qising/linalg"
+	"github.com/pkg/errors"
 )
 
 func TestTransverseFieldIsing(t *testing.T) {
@@ -122,10 +128,392 @@ func TestTransverseFieldIsing(t *testing.T) {
 	}
 }
 
+func TestTransverseFieldIsingPeriodic(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n        [2]int
+		periodic [2]bool
+		// diag is the diagonal of the hamiltonian, which counts the satisfied minus unsatisfied bonds.
+		diag []complex64
+	}{
+		{
+			n:        [2]int{3, 1},
+			periodic: [2]bool{true, false},
+			diag:     []complex64{-3, 1, 1, 1, 1, 1, 1, -3},
+		},
+		{
+			n:        [2]int{1, 4},
+			periodic: [2]bool{false, true},
+			diag:     []complex64{-4, 0, 0, 0, 0, 4, 0, 0, 0, 0, 4, 0, 0, 0, 0, -4},
+		},
+		{
+			// Wrap-around bonds are not added for a direction of length 2.
+			n:        [2]int{2, 1},
+			periodic: [2]bool{true, true},
+			diag:     []complex64{-1, 1, 1, -1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v %v", test.n, test.periodic), func(t *testing.T) {
+			t.Parallel()
+			m := mat.M([][]complex64{{0}})
+			buf := mat.M([][]complex64{{0}})
+			TransverseFieldIsing(m, buf, test.n, 1, NewIsingOptions().Periodic(test.periodic))
+
+			dense := m.Dense()
+			for i, d := range test.diag {
+				if dense[i][i] != d {
+					t.Fatalf("%d %v %v", i, dense[i][i], d)
+				}
+			}
+		})
+	}
+}
+
+func TestTransverseFieldIsingDisordered(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n        [2]int
+		periodic [2]bool
+		// js are the couplings of the bonds in the order they are added, and hs the fields of the sites in row major order.
+		js          []complex64
+		hs          []complex64
+		hamiltonian *mat.COO
+	}{
+		{
+			n:  [2]int{2, 1},
+			js: []complex64{0.5},
+			hs: []complex64{1, 0.25},
+			hamiltonian: mat.M([][]complex64{
+				{-0.5, -0.25, -1, 0},
+				{-0.25, 0.5, 0, -1},
+				{-1, 0, 0.5, -0.25},
+				{0, -1, -0.25, -0.5},
+			}),
+		},
+		{
+			// Uniform couplings reduce to the transverse field Ising model.
+			n:        [2]int{3, 3},
+			periodic: [2]bool{true, true},
+			js:       []complex64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+			hs:       []complex64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5, 0.5},
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var numBonds int
+			coupling := func(a, b [2]int) complex64 {
+				j := test.js[numBonds]
+				numBonds++
+				return j
+			}
+			field := func(a [2]int) complex64 { return test.hs[a[0]*test.n[1]+a[1]] }
+			opt := NewIsingOptions().Periodic(test.periodic)
+			m := mat.M([][]complex64{{0}})
+			buf := mat.M([][]complex64{{0}})
+			TransverseFieldIsingDisordered(m, buf, test.n, coupling, field, opt)
+			if numBonds != len(test.js) {
+				t.Fatalf("%d %d", numBonds, len(test.js))
+			}
+
+			want := test.hamiltonian
+			if want == nil {
+				w := mat.M([][]complex64{{0}})
+				TransverseFieldIsing(w, buf, test.n, test.hs[0], opt)
+				want = w.COO()
+			}
+			if !m.COO().Equal(want) {
+				t.Fatalf("%s, expected %s", m.COO(), want)
+			}
+		})
+	}
+}
+
+func TestIsingConventions(t *testing.T) {
+	t.Parallel()
+	// The couplings and fields are deterministic functions of the sites, so that every hamiltonian sees the same disorder.
+	coupling := func(a, b [2]int) complex64 { return complex(0.5+float32((3*a[0]+5*a[1]+7*b[0]+11*b[1])%7)/7, 0) }
+	field := func(a [2]int) complex64 { return complex(0.25+float32((5*a[0]+3*a[1])%5)/5, 0) }
+	scaled := func(j, h complex64) (func(a, b [2]int) complex64, func(a [2]int) complex64) {
+		return func(a, b [2]int) complex64 { return j * coupling(a, b) }, func(a [2]int) complex64 { return h * field(a) }
+	}
+	tests := []struct {
+		n   [2]int
+		opt IsingOptions
+		// j and h scale the couplings and fields of the equivalent hamiltonian in the default conventions.
+		j, h complex64
+		g    complex64
+		// spectrum is whether only the spectra are equal, instead of the matrices.
+		spectrum bool
+	}{
+		{n: [2]int{3, 2}, opt: NewIsingOptions().Coupling(2), j: 2, h: 1},
+		{n: [2]int{3, 2}, opt: NewIsingOptions().SpinHalf(true).LongitudinalField(0.5), j: 0.25, h: 0.5, g: 0.25},
+		{n: [2]int{2, 3}, opt: NewIsingOptions().SpinHalf(true).Coupling(-1).Periodic([2]bool{false, true}), j: -0.25, h: 0.5},
+		// The antiferromagnet of a bipartite lattice is mapped to the ferromagnet by flipping the spins of a sublattice.
+		{n: [2]int{3, 2}, opt: NewIsingOptions().Coupling(-1), j: 1, h: 1, spectrum: true},
+		// Rotating the spins about Y exchanges X and Z.
+		{n: [2]int{3, 2}, opt: NewIsingOptions().FieldAlongZ(true).LongitudinalField(0.3), j: 1, h: 1, g: 0.3, spectrum: true},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			buf := mat.M([][]complex64{{0}})
+			m := mat.M([][]complex64{{0}})
+			TransverseFieldIsingDisordered(m, buf, test.n, coupling, field, test.opt)
+			want := mat.M([][]complex64{{0}})
+			wantCoupling, wantField := scaled(test.j, test.h)
+			TransverseFieldIsingDisordered(want, buf, test.n, wantCoupling, wantField, NewIsingOptions().Periodic(test.opt.periodic).LongitudinalField(test.g))
+
+			if !test.spectrum {
+				if !m.COO().Equal(want.COO()) {
+					t.Fatalf("%s, expected %s", m.COO(), want.COO())
+				}
+			} else {
+				got, wantVVs := m.COO().Eigen(), want.COO().Eigen()
+				for k := range wantVVs {
+					if cmplx.Abs(got[k].Val-wantVVs[k].Val) > 1e-4 {
+						t.Fatalf("%d %v %v", k, got[k].Val, wantVVs[k].Val)
+					}
+				}
+			}
+			if test.spectrum {
+				return
+			}
+
+			// The matrix-free operator follows the same conventions.
+			op, err := NewIsingOperator(test.n, coupling, field, test.opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			wantOp, err := NewIsingOperator(test.n, wantCoupling, wantField, NewIsingOptions().Periodic(test.opt.periodic).LongitudinalField(test.g))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !slices.Equal(op.diag, wantOp.diag) || !slices.Equal(op.fields, wantOp.fields) {
+				t.Fatalf("%v %v %v %v", op.diag, wantOp.diag, op.fields, wantOp.fields)
+			}
+		})
+	}
+}
+
+func TestIsingConventionsExplicit(t *testing.T) {
+	t.Parallel()
+	n, h := [2]int{3, 2}, complex64(0.75)
+	opt := NewIsingOptions().Periodic([2]bool{false, true}).SpinHalf(true).Coupling(-1).LongitudinalField(0.5)
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := TransverseFieldIsingExplicit(dir, n, h, opt); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	got, err := mat.ReadCOO(dir)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	m := mat.M([][]complex64{{0}})
+	buf := mat.M([][]complex64{{0}})
+	TransverseFieldIsing(m, buf, n, h, opt)
+	if !got.Equal(m.COO()) {
+		t.Fatalf("\n%s, expected \n\n%s", got, m.COO())
+	}
+
+	if err := TransverseFieldIsingExplicit(dir, n, h, opt.FieldAlongZ(true)); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestYangLeeIsing(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		h      complex64
+		lambda float32
+		// complexSpectrum is whether the spectrum has eigenvalues with non-zero imaginary parts.
+		complexSpectrum bool
+	}{
+		{h: 2, lambda: 0.05, complexSpectrum: false},
+		{h: 0.5, lambda: 2, complexSpectrum: true},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v_%f", test.h, test.lambda), func(t *testing.T) {
+			t.Parallel()
+			m := mat.M([][]complex64{{0}})
+			buf := mat.M([][]complex64{{0}})
+			YangLeeIsing(m, buf, [2]int{4, 1}, test.h, test.lambda)
+
+			vvs := m.Eigen()
+			var hasImag bool
+			for i, vv := range vvs {
+				if i > 0 && real(vv.Val) < real(vvs[i-1].Val) {
+					t.Fatalf("not sorted %v", vvs)
+				}
+				if math.Abs(imag(vv.Val)) > 1e-4 {
+					hasImag = true
+				}
+
+				// PT symmetry implies the spectrum is closed under complex conjugation.
+				var found bool
+				for _, other := range vvs {
+					if cmplx.Abs(other.Val-cmplx.Conj(vv.Val)) < 1e-4 {
+						found = true
+					}
+				}
+				if !found {
+					t.Fatalf("%d %v", i, vv.Val)
+				}
+			}
+			if hasImag != test.complexSpectrum {
+				t.Fatalf("%v", vvs)
+			}
+		})
+	}
+}
+
+func TestAddTerm(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n    [2]int
+		add  func(hamiltonian, buf mat.Matrix, n [2]int)
+		want *mat.COO
+	}{
+		{
+			n: [2]int{2, 1},
+			add: func(hamiltonian, buf mat.Matrix, n [2]int) {
+				AddTwoSiteTerm(hamiltonian, buf, n, 2, mat.M(mat.PauliY), mat.M(mat.PauliY), [2]int{0, 0}, [2]int{1, 0})
+			},
+			want: mat.M([][]complex64{
+				{0, 0, 0, -2},
+				{0, 0, 2, 0},
+				{0, 2, 0, 0},
+				{-2, 0, 0, 0},
+			}),
+		},
+		{
+			n: [2]int{1, 2},
+			add: func(hamiltonian, buf mat.Matrix, n [2]int) {
+				AddOneSiteTerm(hamiltonian, buf, n, 1i, mat.M(mat.PauliX), [2]int{0, 1})
+				AddOneSiteTerm(hamiltonian, buf, n, 3, mat.M(mat.PauliZ), [2]int{0, 0})
+			},
+			want: mat.M([][]complex64{
+				{3, 1i, 0, 0},
+				{1i, 3, 0, 0},
+				{0, 0, -3, 1i},
+				{0, 0, 1i, -3},
+			}),
+		},
+		{
+			n: [2]int{3, 1},
+			add: func(hamiltonian, buf mat.Matrix, n [2]int) {
+				zs := map[[2]int]*mat.COO{{0, 0}: mat.M(mat.PauliZ), {1, 0}: mat.M(mat.PauliZ), {2, 0}: mat.M(mat.PauliZ)}
+				AddTerm(hamiltonian, buf, n, -1, zs)
+			},
+			want: mat.M([][]complex64{
+				{-1, 0, 0, 0, 0, 0, 0, 0},
+				{0, 1, 0, 0, 0, 0, 0, 0},
+				{0, 0, 1, 0, 0, 0, 0, 0},
+				{0, 0, 0, -1, 0, 0, 0, 0},
+				{0, 0, 0, 0, 1, 0, 0, 0},
+				{0, 0, 0, 0, 0, -1, 0, 0},
+				{0, 0, 0, 0, 0, 0, -1, 0},
+				{0, 0, 0, 0, 0, 0, 0, 1},
+			}),
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			numSpins := test.n[0] * test.n[1]
+			hamiltonian := mat.COOZeros(1<<numSpins, 1<<numSpins)
+			buf := mat.M([][]complex64{{0}})
+			test.add(hamiltonian, buf, test.n)
+			if !hamiltonian.Equal(test.want) {
+				t.Fatalf("%s, expected %s", hamiltonian, test.want)
+			}
+		})
+	}
+}
+
+func TestParitySector(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n        [2]int
+		h        complex64
+		periodic [2]bool
+	}{
+		{n: [2]int{1, 1}, h: 1},
+		{n: [2]int{4, 1}, h: 0.5},
+		{n: [2]int{2, 2}, h: 2},
+		{n: [2]int{3, 2}, h: 1, periodic: [2]bool{true, false}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			buf := mat.M([][]complex64{{0}})
+			opt := NewIsingOptions().Periodic(test.periodic)
+			full := mat.M([][]complex64{{0}})
+			TransverseFieldIsing(full, buf, test.n, test.h, opt)
+			want := make([]float64, 0)
+			for _, vv := range full.Eigen() {
+				want = append(want, real(vv.Val))
+			}
+
+			// The spectrum is the union of the spectra of both sectors, and the ground state is even.
+			got := make([]float64, 0)
+			for _, parity := range []int{1, -1} {
+				sector := mat.M([][]complex64{{0}})
+				TransverseFieldIsing(sector, buf, test.n, test.h, opt.ParitySector(parity))
+				if sector.Rows() != full.Rows()/2 {
+					t.Fatalf("%d %d", sector.Rows(), full.Rows())
+				}
+				vvs := sector.Eigen()
+				if parity == 1 && math.Abs(real(vvs[0].Val)-want[0]) > 1e-6 {
+					t.Fatalf("%v %f", vvs[0].Val, want[0])
+				}
+				for _, vv := range vvs {
+					got = append(got, real(vv.Val))
+				}
+			}
+			slices.Sort(got)
+			for j := range want {
+				if math.Abs(got[j]-want[j]) > 1e-6 {
+					t.Fatalf("%d %v %v", j, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParitySectorError(t *testing.T) {
+	t.Parallel()
+	tests := []IsingOptions{
+		NewIsingOptions().ParitySector(2),
+		NewIsingOptions().ParitySector(1).LongitudinalField(0.5),
+		NewIsingOptions().ParitySector(-1).FieldAlongZ(true),
+	}
+	for i, opt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			dir, err := os.MkdirTemp("", "")
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			defer os.RemoveAll(dir)
+			if err := TransverseFieldIsingExplicit(dir, [2]int{3, 1}, 1, opt); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
 func TestTransverseFieldIsingExplicit(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
-		n [2]int
+		n            [2]int
+		periodic     [2]bool
+		longitudinal complex64
+		parity       int
 	}{
 		{
 			n: [2]int{8, 1},
@@ -133,9 +521,44 @@ func TestTransverseFieldIsingExplicit(t *testing.T) {
 		{
 			n: [2]int{2, 2},
 		},
+		{
+			n:        [2]int{8, 1},
+			periodic: [2]bool{true, false},
+		},
+		{
+			n:        [2]int{3, 3},
+			periodic: [2]bool{true, true},
+		},
+		{
+			n:        [2]int{3, 2},
+			periodic: [2]bool{false, true},
+		},
+		{
+			n:            [2]int{2, 3},
+			periodic:     [2]bool{false, true},
+			longitudinal: 0.3i,
+		},
+		{
+			n:            [2]int{3, 2},
+			longitudinal: 0.5,
+		},
+		{
+			n:      [2]int{8, 1},
+			parity: 1,
+		},
+		{
+			n:        [2]int{3, 3},
+			periodic: [2]bool{true, true},
+			parity:   -1,
+		},
+		{
+			n:        [2]int{2, 3},
+			periodic: [2]bool{false, true},
+			parity:   1,
+		},
 	}
 	for _, test := range tests {
-		t.Run(fmt.Sprintf("%v", test.n), func(t *testing.T) {
+		t.Run(fmt.Sprintf("%v %v %d", test.n, test.periodic, test.parity), func(t *testing.T) {
 			t.Parallel()
 			dir, err := os.MkdirTemp("", "")
 			if err != nil {
@@ -145,21 +568,78 @@ func TestTransverseFieldIsingExplicit(t *testing.T) {
 
 			m := mat.M([][]complex64{{0}})
 			buf := mat.M([][]complex64{{0}})
-			TransverseFieldIsing(m, buf, test.n, 1)
+			opt := NewIsingOptions().Periodic(test.periodic).LongitudinalField(test.longitudinal).ParitySector(test.parity)
+			TransverseFieldIsing(m, buf, test.n, 1, opt)
+
+			for _, cooOpt := range []mat.WriteCOOOptions{
+				mat.NewWriteCOOOptions(),
+				mat.NewWriteCOOOptions().Format(mat.COOFormatVarint).Compress(true),
+			} {
+				if err := TransverseFieldIsingExplicit(dir, test.n, 1, opt.WriteCOOOptions(cooOpt)); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				mExplicit, err := mat.ReadCOO(dir)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				if !mExplicit.Equal(m) {
+					t.Fatalf("\n%s, expected \n\n%s", mExplicit, m)
+				}
+			}
+		})
+	}
+}
 
-			TransverseFieldIsingExplicit(dir, test.n, 1)
-			mExplicit, err := mat.ReadCOO(dir)
+func TestIsingProgress(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n    [2]int
+		want [][2]int
+	}{
+		{n: [2]int{3, 2}, want: [][2]int{{64, 64}}},
+		{n: [2]int{17, 1}, want: [][2]int{{progressInterval, 1 << 17}, {1 << 17, 1 << 17}}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			dir, err := os.MkdirTemp("", "")
 			if err != nil {
 				t.Fatalf("%+v", err)
 			}
-
-			if !mExplicit.Equal(m) {
-				t.Fatalf("\n%s, expected \n\n%s", mExplicit, m)
+			defer os.RemoveAll(dir)
+			calls := make([][2]int, 0)
+			opt := NewIsingOptions().Progress(func(done, total int) { calls = append(calls, [2]int{done, total}) })
+			if err := TransverseFieldIsingExplicit(dir, test.n, 1, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !slices.Equal(calls, test.want) {
+				t.Fatalf("%v %v", calls, test.want)
 			}
 		})
 	}
 }
 
+func TestIsingInterrupt(t *testing.T) {
+	t.Parallel()
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	interrupt := make(chan struct{})
+	close(interrupt)
+	calls := make([][2]int, 0)
+	opt := NewIsingOptions().Progress(func(done, total int) { calls = append(calls, [2]int{done, total}) }).Interrupt(interrupt)
+	if err := TransverseFieldIsingExplicit(dir, [2]int{17, 1}, 1, opt); !errors.Is(err, linalg.ErrInterrupted) {
+		t.Fatalf("%+v", err)
+	}
+	// The build stops at the first check of the interrupt.
+	if want := [][2]int{{progressInterval, 1 << 17}}; !slices.Equal(calls, want) {
+		t.Fatalf("%v %v", calls, want)
+	}
+}
+
 func TestEigen(t *testing.T) {
 	t.Parallel()
 	dir, err := os.MkdirTemp("", t.Name())
@@ -210,6 +690,56 @@ func TestEigen(t *testing.T) {
 	}
 }
 
+func TestReadStatistics(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n [2]int
+		h complex64
+	}{
+		{n: [2]int{4, 1}, h: 0.5},
+		{n: [2]int{2, 3}, h: 3},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			h, buf := mat.M([][]complex64{{0}}), mat.M([][]complex64{{0}})
+			TransverseFieldIsing(h, buf, test.n, test.h)
+			vvs := h.COO().Eigen()
+			want, err := GetStatistics(test.n, vvs)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Write the eigenvectors in the eig.csv format, one component of each eigenvector per row.
+			var b strings.Builder
+			row := make([]string, len(vvs))
+			for j, vv := range vvs {
+				row[j] = strconv.FormatComplex(vv.Val, 'g', -1, 128)
+			}
+			b.WriteString(strings.Join(row, ",") + "\n")
+			for k := range vvs[0].Vec {
+				for j, vv := range vvs {
+					row[j] = strconv.FormatComplex(vv.Vec[k], 'g', -1, 128)
+				}
+				b.WriteString(strings.Join(row, ",") + "\n")
+			}
+
+			got, err := ReadStatistics(test.n, strings.NewReader(b.String()))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%#v %#v", got, want)
+			}
+
+			// The ground state must match the lattice.
+			if _, err := ReadStatistics([2]int{test.n[0] + 1, test.n[1]}, strings.NewReader(b.String())); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
 func TestEigs(t *testing.T) {
 	t.Parallel()
 	type vectorSlice struct {
@@ -0,0 +1,159 @@
+package mat
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FnameCOOBinary = "coo.bin"
+
+	// binaryRecordSize is the size of a record in the binary COO format of COOFormatBinary.
+	binaryRecordSize = 24
+)
+
+// WriteCOOBinary writes the matrix in the binary COO format to dir.
+// It is a shorthand of WriteCOO with the format COOFormatBinary.
+func (m *COO) WriteCOOBinary(dir string) error {
+	if err := m.WriteCOO(dir, NewWriteCOOOptions().Format(COOFormatBinary)); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// ConvertCOOBinary converts the COO matrix in dir to the binary COO format.
+// Unlike rewriting the matrix with NewCOOWriter, the source file is kept, so that for example the Python eigensolver of EigsDir can still read the CSV format.
+// The conversion is streamed, so that matrices larger than memory can be converted.
+func ConvertCOOBinary(dir string) error {
+	format, err := detectCOOFormat(dir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if format == COOFormatBinary {
+		return nil
+	}
+	r, err := NewCOOReader(dir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer r.Close()
+
+	f, err := os.Create(filepath.Join(dir, FnameCOOBinary))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	w := bufio.NewWriter(f)
+	var buf [binaryRecordSize]byte
+	for {
+		v, err1 := r.Read()
+		if err1 == io.EOF {
+			break
+		}
+		if err1 != nil {
+			err = errors.Wrap(err1, "")
+			break
+		}
+
+		putRecord(buf[:], v)
+		if _, err1 := w.Write(buf[:]); err1 != nil {
+			err = errors.Wrap(err1, "")
+			break
+		}
+	}
+
+	if err1 := w.Flush(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// MmapCOO is a read-only matrix in the binary COO format, which is memory mapped from disk.
+type MmapCOO struct {
+	rows int
+	cols int
+	data []byte
+}
+
+// OpenMmapCOO memory maps the binary COO matrix in dir.
+// The rows and columns of all records are checked against the shape, so that MulVec does not index out of range.
+func OpenMmapCOO(dir string) (*MmapCOO, error) {
+	m := &MmapCOO{}
+	var err error
+	m.rows, m.cols, err = readShape(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	m.data, err = mmap(filepath.Join(dir, FnameCOOBinary))
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if len(m.data)%binaryRecordSize != 0 {
+		munmap(m.data)
+		return nil, errors.Errorf("%d", len(m.data))
+	}
+	for off := 0; off < len(m.data); off += binaryRecordSize {
+		v := getRecord(m.data[off : off+binaryRecordSize])
+		if v.row < 0 || v.row >= m.rows || v.col < 0 || v.col >= m.cols {
+			munmap(m.data)
+			return nil, errors.Errorf("%d %d %d %d %d", off/binaryRecordSize, v.row, v.col, m.rows, m.cols)
+		}
+	}
+	return m, nil
+}
+
+// Close unmaps the matrix.
+func (m *MmapCOO) Close() error {
+	if err := munmap(m.data); err != nil {
+		return errors.Wrap(err, "")
+	}
+	m.data = nil
+	return nil
+}
+
+func (m *MmapCOO) Rows() int { return m.rows }
+func (m *MmapCOO) Cols() int { return m.cols }
+
+// NumNonZero returns the number of stored entries.
+func (m *MmapCOO) NumNonZero() int {
+	return len(m.data) / binaryRecordSize
+}
+
+// MulVec computes dst = m @ x.
+func (m *MmapCOO) MulVec(dst, x []complex64) {
+	if len(dst) != m.rows || len(x) != m.cols {
+		panic(fmt.Sprintf("%d %d %d %d", len(dst), len(x), m.rows, m.cols))
+	}
+	clear(dst)
+	for off := 0; off < len(m.data); off += binaryRecordSize {
+		v := getRecord(m.data[off : off+binaryRecordSize])
+		dst[v.row] += v.v * x[v.col]
+	}
+}
+
+func putRecord(b []byte, v vRowCol) {
+	binary.LittleEndian.PutUint64(b[0:], uint64(v.row))
+	binary.LittleEndian.PutUint64(b[8:], uint64(v.col))
+	binary.LittleEndian.PutUint32(b[16:], math.Float32bits(real(v.v)))
+	binary.LittleEndian.PutUint32(b[20:], math.Float32bits(imag(v.v)))
+}
+
+func getRecord(b []byte) vRowCol {
+	var v vRowCol
+	v.row = int(binary.LittleEndian.Uint64(b[0:]))
+	v.col = int(binary.LittleEndian.Uint64(b[8:]))
+	re := math.Float32frombits(binary.LittleEndian.Uint32(b[16:]))
+	im := math.Float32frombits(binary.LittleEndian.Uint32(b[20:]))
+	v.v = complex(re, im)
+	return v
+}
@@ -0,0 +1,118 @@
+package mat
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestMmapCOO(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a *COO
+		x []complex64
+	}{
+		{
+			a: M([][]complex64{
+				{1, 0, 2i},
+				{0, -3, 0},
+			}),
+			x: []complex64{1, 2, 3},
+		},
+		{
+			a: M([][]complex64{
+				{8, -9, -6, 5},
+				{1, -3, 0, 7},
+				{2, 8, -8i, -3},
+				{1, 2, -5, -1 + 1i},
+			}),
+			x: []complex64{1, 1i, -1, 2},
+		},
+		{
+			a: COOZeros(3, 2),
+			x: []complex64{1, 1},
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			want := make([]complex64, test.a.Rows())
+			test.a.MulVec(want, test.x)
+
+			for _, write := range []func(dir string) error{
+				test.a.WriteCOOBinary,
+				func(dir string) error {
+					if err := test.a.WriteCOO(dir); err != nil {
+						return err
+					}
+					return ConvertCOOBinary(dir)
+				},
+			} {
+				dir, err := os.MkdirTemp("", "")
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				defer os.RemoveAll(dir)
+				if err := write(dir); err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				m, err := OpenMmapCOO(dir)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if m.Rows() != test.a.Rows() || m.Cols() != test.a.Cols() {
+					t.Fatalf("%d %d", m.Rows(), m.Cols())
+				}
+				if m.NumNonZero() != len(test.a.Data) {
+					t.Fatalf("%d %d", m.NumNonZero(), len(test.a.Data))
+				}
+				y := make([]complex64, m.Rows())
+				m.MulVec(y, test.x)
+				for j := range y {
+					if y[j] != want[j] {
+						t.Fatalf("%v, expected %v", y, want)
+					}
+				}
+				if err := m.Close(); err != nil {
+					t.Fatalf("%+v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestMmapCOOError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		row int
+		col int
+	}{
+		{row: 2, col: 0},
+		{row: 0, col: 3},
+		{row: -1, col: 0},
+		{row: 0, col: -1},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			dir, err := os.MkdirTemp("", "")
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			// The entry out of the shape is appended to Data directly, bypassing Append.
+			a := COOZeros(2, 3)
+			a.Append(1, 2, 1)
+			a.Data = append(a.Data, vRowCol{v: 2, row: test.row, col: test.col})
+			if err := a.WriteCOOBinary(dir); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if m, err := OpenMmapCOO(dir); err == nil {
+				m.Close()
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
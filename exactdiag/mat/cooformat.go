@@ -0,0 +1,300 @@
+package mat
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/cmplx"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FnameCOOVarint = "coo.vbin"
+
+	// varintMagic starts the varint COO format, after decompression.
+	varintMagic = "QCOO\x01"
+)
+
+// gzipMagic are the first bytes of a gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// COOFormat is a file format of COO matrices.
+type COOFormat int
+
+const (
+	// COOFormatCSV is the coo.csv format of rows {value, row, col}, in which an empty value or row repeats that of the previous row.
+	// It is the only format read by the Python eigensolver of EigsDir.
+	COOFormatCSV COOFormat = iota
+	// COOFormatVarint is the compact binary coo.vbin format.
+	// After a magic header, each entry is the signed varint of the row minus the previous row,
+	// the signed varint of the column minus the previous column, and the little endian float32 real and imaginary parts.
+	// Unlike the fixed size records of COOFormatBinary, it cannot be memory mapped.
+	COOFormatVarint
+	// COOFormatBinary is the coo.bin format of fixed size records, which OpenMmapCOO memory maps.
+	// Each record is the little endian encoding of {row int64, col int64, real float32, imag float32}.
+	COOFormatBinary
+)
+
+// WriteCOOOptions are options for writing COO matrices.
+type WriteCOOOptions struct {
+	format   COOFormat
+	compress bool
+}
+
+// NewWriteCOOOptions returns the default options, which write the uncompressed CSV format.
+func NewWriteCOOOptions() WriteCOOOptions {
+	opt := WriteCOOOptions{}
+	opt.format = COOFormatCSV
+	return opt
+}
+
+// Format sets the file format.
+func (opt WriteCOOOptions) Format(f COOFormat) WriteCOOOptions {
+	opt.format = f
+	return opt
+}
+
+// Compress sets whether the file is gzip compressed, which only the varint format supports.
+func (opt WriteCOOOptions) Compress(c bool) WriteCOOOptions {
+	opt.compress = c
+	return opt
+}
+
+func (opt WriteCOOOptions) fname() (string, error) {
+	switch opt.format {
+	case COOFormatCSV:
+		if opt.compress {
+			return "", errors.Errorf("compressed csv")
+		}
+		return FnameCOO, nil
+	case COOFormatVarint:
+		return FnameCOOVarint, nil
+	case COOFormatBinary:
+		if opt.compress {
+			return "", errors.Errorf("compressed binary")
+		}
+		return FnameCOOBinary, nil
+	default:
+		return "", errors.Errorf("%d", opt.format)
+	}
+}
+
+// COOWriter writes the entries of a COO matrix to a directory one at a time,
+// so that matrices larger than memory can be written.
+type COOWriter struct {
+	opt WriteCOOOptions
+	f   *os.File
+	bw  *bufio.Writer
+	gz  *gzip.Writer
+	csv *csv.Writer
+
+	// prev is the previously written entry for compression.
+	prev vRowCol
+	buf  [max(2*binary.MaxVarintLen64+8, binaryRecordSize)]byte
+}
+
+// NewCOOWriter writes the shape of a rows by cols matrix to dir, and returns a writer of its entries.
+// Matrix files of the other formats in dir are removed, so that readers do not pick up stale entries.
+func NewCOOWriter(dir string, rows, cols int, options ...WriteCOOOptions) (*COOWriter, error) {
+	opt := NewWriteCOOOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	fname, err := opt.fname()
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	for _, other := range []string{FnameCOO, FnameCOOVarint, FnameCOOBinary} {
+		if other == fname {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, other)); err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "")
+		}
+	}
+
+	shapePath := filepath.Join(dir, FnameShape)
+	if err := os.WriteFile(shapePath, []byte(fmt.Sprintf("%d,%d", rows, cols)), 0644); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	w := &COOWriter{opt: opt, prev: vRowCol{v: complex64(cmplx.NaN()), row: -1, col: -1}}
+	w.f, err = os.Create(filepath.Join(dir, fname))
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	w.bw = bufio.NewWriter(w.f)
+	switch opt.format {
+	case COOFormatCSV:
+		w.csv = csv.NewWriter(w.bw)
+	case COOFormatVarint:
+		var out io.Writer = w.bw
+		if opt.compress {
+			w.gz = gzip.NewWriter(w.bw)
+			out = w.gz
+		}
+		if _, err := io.WriteString(out, varintMagic); err != nil {
+			w.f.Close()
+			return nil, errors.Wrap(err, "")
+		}
+		w.prev.row, w.prev.col = 0, 0
+	}
+	return w, nil
+}
+
+// Write writes the entry v at row i and column j.
+func (w *COOWriter) Write(i, j int, v complex64) error {
+	switch w.opt.format {
+	case COOFormatCSV:
+		var vStr string
+		if v != w.prev.v {
+			vStr = FormatNumpy(v)
+		}
+		var rowStr string
+		if i != w.prev.row {
+			rowStr = strconv.Itoa(i)
+		}
+		if err := w.csv.Write([]string{vStr, rowStr, strconv.Itoa(j)}); err != nil {
+			return errors.Wrap(err, "")
+		}
+	case COOFormatBinary:
+		b := w.buf[:binaryRecordSize]
+		putRecord(b, vRowCol{v: v, row: i, col: j})
+		if _, err := w.bw.Write(b); err != nil {
+			return errors.Wrap(err, "")
+		}
+	default:
+		b := binary.AppendVarint(w.buf[:0], int64(i-w.prev.row))
+		b = binary.AppendVarint(b, int64(j-w.prev.col))
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(real(v)))
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(imag(v)))
+		var out io.Writer = w.bw
+		if w.gz != nil {
+			out = w.gz
+		}
+		if _, err := out.Write(b); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	w.prev = vRowCol{v: v, row: i, col: j}
+	return nil
+}
+
+// Close flushes the entries and closes the file.
+func (w *COOWriter) Close() error {
+	var err error
+	if w.csv != nil {
+		w.csv.Flush()
+		if err1 := w.csv.Error(); err1 != nil && err == nil {
+			err = errors.Wrap(err1, "")
+		}
+	}
+	if w.gz != nil {
+		if err1 := w.gz.Close(); err1 != nil && err == nil {
+			err = errors.Wrap(err1, "")
+		}
+	}
+	if err1 := w.bw.Flush(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := w.f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// detectCOOFormat returns the format of the COO matrix in dir, which is the first of the CSV, varint and binary files that exists.
+func detectCOOFormat(dir string) (COOFormat, error) {
+	for _, f := range []COOFormat{COOFormatCSV, COOFormatVarint, COOFormatBinary} {
+		fname, err := NewWriteCOOOptions().Format(f).fname()
+		if err != nil {
+			return -1, errors.Wrap(err, "")
+		}
+		_, err = os.Stat(filepath.Join(dir, fname))
+		if err == nil {
+			return f, nil
+		}
+		if !os.IsNotExist(err) {
+			return -1, errors.Wrap(err, "")
+		}
+	}
+	return -1, errors.Errorf("no matrix in %s", dir)
+}
+
+// newVarintReader returns the reader of the entries of the varint format in r, which is decompressed if it is gzip compressed.
+func newVarintReader(r io.Reader) (*bufio.Reader, *gzip.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, nil, errors.Wrap(err, "")
+	}
+	var gz *gzip.Reader
+	if bytes.Equal(head, gzipMagic) {
+		gz, err = gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "")
+		}
+		br = bufio.NewReader(gz)
+	}
+
+	magic := make([]byte, len(varintMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, nil, errors.Wrap(err, "")
+	}
+	if string(magic) != varintMagic {
+		return nil, nil, errors.Errorf("%q", magic)
+	}
+	return br, gz, nil
+}
+
+// readVarint reads the entry after prev in the varint format.
+func readVarint(r *bufio.Reader, prev vRowCol) (vRowCol, error) {
+	dRow, err := binary.ReadVarint(r)
+	if err == io.EOF {
+		return vRowCol{}, io.EOF
+	}
+	if err != nil {
+		return vRowCol{}, errors.Wrap(err, "")
+	}
+	dCol, err := binary.ReadVarint(r)
+	if err != nil {
+		return vRowCol{}, errors.Wrap(unexpectedEOF(err), "")
+	}
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return vRowCol{}, errors.Wrap(unexpectedEOF(err), "")
+	}
+	re := math.Float32frombits(binary.LittleEndian.Uint32(b[0:]))
+	im := math.Float32frombits(binary.LittleEndian.Uint32(b[4:]))
+	return vRowCol{v: complex(re, im), row: prev.row + int(dRow), col: prev.col + int(dCol)}, nil
+}
+
+// readBinary reads an entry in the binary format.
+func readBinary(r *bufio.Reader) (vRowCol, error) {
+	var b [binaryRecordSize]byte
+	n, err := io.ReadFull(r, b[:])
+	if err == io.EOF {
+		return vRowCol{}, io.EOF
+	}
+	if err != nil {
+		return vRowCol{}, errors.Wrap(err, fmt.Sprintf("%d", n))
+	}
+	return getRecord(b[:]), nil
+}
+
+// unexpectedEOF reports an EOF in the middle of an entry as io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
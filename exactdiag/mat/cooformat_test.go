@@ -0,0 +1,140 @@
+package mat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteCOO(t *testing.T) {
+	t.Parallel()
+	// unsorted has entries out of row major order, which the varint format encodes with negative deltas.
+	unsorted := COOZeros(3, 3)
+	unsorted.Append(2, 1, 1.5)
+	unsorted.Append(0, 2, -2i)
+	unsorted.Append(1, 0, 1.5)
+	unsorted.Append(1, 0, 3)
+	tests := []struct {
+		a *COO
+	}{
+		{a: M([][]complex64{
+			{1, 0, 2i},
+			{0, -3, 0},
+		})},
+		{a: M([][]complex64{
+			{8, -9, -6, 5},
+			{1, -3, 0, 7},
+			{2, 8, -8i, -3},
+			{1, 2, -5, -1 + 1i},
+		})},
+		{a: COOZeros(3, 2)},
+		{a: unsorted},
+	}
+	options := []WriteCOOOptions{
+		NewWriteCOOOptions(),
+		NewWriteCOOOptions().Format(COOFormatVarint),
+		NewWriteCOOOptions().Format(COOFormatVarint).Compress(true),
+		NewWriteCOOOptions().Format(COOFormatBinary),
+	}
+	for i, test := range tests {
+		for j, opt := range options {
+			t.Run(fmt.Sprintf("%d %d", i, j), func(t *testing.T) {
+				t.Parallel()
+				dir, err := os.MkdirTemp("", "")
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				defer os.RemoveAll(dir)
+
+				// Write in another format first, to check that stale files are removed.
+				if err := test.a.WriteCOO(dir, options[(j+1)%len(options)]); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if err := test.a.WriteCOO(dir, opt); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				var fnames []string
+				for _, fname := range []string{FnameCOO, FnameCOOVarint, FnameCOOBinary} {
+					if _, err := os.Stat(filepath.Join(dir, fname)); err == nil {
+						fnames = append(fnames, fname)
+					}
+				}
+				if len(fnames) != 1 {
+					t.Fatalf("%#v", fnames)
+				}
+				m, err := ReadCOO(dir)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if m.Rows() != test.a.Rows() || m.Cols() != test.a.Cols() || len(m.Data) != len(test.a.Data) {
+					t.Fatalf("%d %d %d", m.Rows(), m.Cols(), len(m.Data))
+				}
+				for k, v := range m.Data {
+					if v != test.a.Data[k] {
+						t.Fatalf("%d %v %v", k, v, test.a.Data[k])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestWriteCOOError(t *testing.T) {
+	t.Parallel()
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := M([][]complex64{{1, 2}, {3, 4}})
+	if err := a.WriteCOO(dir, NewWriteCOOOptions().Compress(true)); err == nil {
+		t.Fatalf("expected error")
+	}
+	if err := a.WriteCOO(dir, NewWriteCOOOptions().Format(COOFormatBinary).Compress(true)); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := NewCOOReader(dir); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// A truncated entry is an error rather than the end of the matrix.
+	if err := a.WriteCOO(dir, NewWriteCOOOptions().Format(COOFormatVarint)); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	fpath := filepath.Join(dir, FnameCOOVarint)
+	b, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := os.WriteFile(fpath, b[:len(b)-3], 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err := ReadCOO(dir); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if err := os.WriteFile(fpath, []byte("garbage"), 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err := NewCOOReader(dir); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// A truncated record of the binary format is also an error.
+	if err := a.WriteCOO(dir, NewWriteCOOOptions().Format(COOFormatBinary)); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	fpath = filepath.Join(dir, FnameCOOBinary)
+	b, err = os.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := os.WriteFile(fpath, b[:len(b)-3], 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err := ReadCOO(dir); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,67 @@
+package mat
+
+import (
+	"fmt"
+)
+
+// CSR is a sparse matrix in the Compressed Sparse Row format.
+// Unlike COO, which is convenient for construction, CSR is efficient for repeated matrix-vector products.
+type CSR struct {
+	rows int
+	cols int
+
+	// indptr[i]:indptr[i+1] is the range of row i in indices and data.
+	indptr  []int
+	indices []int
+	data    []complex64
+}
+
+// CSR converts m to the CSR format.
+func (m *COO) CSR() *CSR {
+	c := &CSR{rows: m.rows, cols: m.cols, indptr: make([]int, m.rows+1), indices: make([]int, len(m.Data)), data: make([]complex64, len(m.Data))}
+
+	// Count the number of entries in each row, so that Data need not be sorted.
+	for _, v := range m.Data {
+		c.indptr[v.row+1]++
+	}
+	for i := range m.rows {
+		c.indptr[i+1] += c.indptr[i]
+	}
+
+	next := make([]int, m.rows)
+	copy(next, c.indptr)
+	for _, v := range m.Data {
+		k := next[v.row]
+		c.indices[k] = v.col
+		c.data[k] = v.v
+		next[v.row]++
+	}
+	return c
+}
+
+func (m *CSR) Rows() int { return m.rows }
+func (m *CSR) Cols() int { return m.cols }
+
+// NumNonZero returns the number of stored entries.
+func (m *CSR) NumNonZero() int { return len(m.data) }
+
+// Row returns the column indices and values of the entries in row i.
+// The returned slices are views into m, and must not be modified.
+func (m *CSR) Row(i int) ([]int, []complex64) {
+	start, end := m.indptr[i], m.indptr[i+1]
+	return m.indices[start:end], m.data[start:end]
+}
+
+// MulVec computes dst = m @ src.
+func (m *CSR) MulVec(dst, src []complex64) {
+	if len(dst) != m.rows || len(src) != m.cols {
+		panic(fmt.Sprintf("%d %d %d %d", len(dst), len(src), m.rows, m.cols))
+	}
+	for i := range m.rows {
+		var v complex64
+		for k := m.indptr[i]; k < m.indptr[i+1]; k++ {
+			v += m.data[k] * src[m.indices[k]]
+		}
+		dst[i] = v
+	}
+}
@@ -0,0 +1,68 @@
+package mat
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestCSR(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		a *COO
+	}
+	tests := []testcase{
+		{a: M([][]complex64{
+			{1, 0, 2i},
+			{0, 0, 0},
+			{0, -3, 1 + 1i},
+		})},
+		{a: COOZeros(2, 3)},
+	}
+
+	// Unsorted entries with empty rows.
+	a := COOZeros(50, 40)
+	for i := range 200 {
+		a.Data = append(a.Data, vRowCol{v: complex(float32(i), -1), row: rand.IntN(25) * 2, col: rand.IntN(40)})
+	}
+	slices.SortFunc(a.Data, func(x, y vRowCol) int { return -rowMajor(x, y) })
+	tests = append(tests, testcase{a: a})
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			csr := test.a.CSR()
+			if csr.Rows() != test.a.Rows() || csr.Cols() != test.a.Cols() || csr.NumNonZero() != len(test.a.Data) {
+				t.Fatalf("%d %d %d", csr.Rows(), csr.Cols(), csr.NumNonZero())
+			}
+
+			x := make([]complex64, test.a.Cols())
+			for j := range x {
+				x[j] = complex(float32(j), 1)
+			}
+			want := make([]complex64, test.a.Rows())
+			test.a.MulVec(want, x)
+			y := make([]complex64, csr.Rows())
+			for j := range y {
+				y[j] = 999
+			}
+			csr.MulVec(y, x)
+			for j := range y {
+				if y[j] != want[j] {
+					t.Fatalf("%d %v %v", j, y[j], want[j])
+				}
+			}
+
+			// Check rows.
+			for r := range csr.Rows() {
+				cols, vals := csr.Row(r)
+				for k, c := range cols {
+					if vals[k] == 0 || test.a.At(r, c) == 0 {
+						t.Fatalf("%d %d %v", r, c, vals[k])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package mat
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// DirMatVec computes y = m @ x, where m is the COO matrix in dir as written by WriteCOO or exactdiag.TransverseFieldIsingExplicit.
+// If dir has the binary format of COOFormatBinary, m is memory mapped with OpenMmapCOO,
+// and otherwise the entries of m are streamed from disk, so that only x and y are held in memory.
+func DirMatVec(dir string, x, y []complex64) error {
+	rows, cols, err := readShape(dir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if len(y) != rows || len(x) != cols {
+		return errors.Errorf("%d %d %d %d", len(y), len(x), rows, cols)
+	}
+
+	m, err := openMmapCOOIfExists(dir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if m != nil {
+		m.MulVec(y, x)
+		if err := m.Close(); err != nil {
+			return errors.Wrap(err, "")
+		}
+		return nil
+	}
+
+	r, err := NewCOOReader(dir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer r.Close()
+	clear(y)
+	for {
+		v, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		if v.row < 0 || v.row >= rows || v.col < 0 || v.col >= cols {
+			return errors.Errorf("%d %d %d %d", v.row, v.col, rows, cols)
+		}
+		y[v.row] += v.v * x[v.col]
+	}
+	return nil
+}
+
+// openMmapCOOIfExists returns the memory mapped matrix in dir if dir has the binary format, and nil otherwise.
+func openMmapCOOIfExists(dir string) (*MmapCOO, error) {
+	_, err := os.Stat(filepath.Join(dir, FnameCOOBinary))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	m, err := OpenMmapCOO(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return m, nil
+}
+
+// dirOperator is the linear operator of the COO matrix in dir, which is streamed from disk on every application,
+// unless mmap is the matrix memory mapped for the lifetime of the operator.
+type dirOperator struct {
+	dir  string
+	mmap *MmapCOO
+	dim  int
+	// x and y are reusable buffers for the input and output of DirMatVec.
+	x []complex64
+	y []complex64
+	// err is the first error of DirMatVec, since LinearOperator.Apply cannot return errors.
+	err error
+}
+
+func (op *dirOperator) Dim() int { return op.dim }
+
+func (op *dirOperator) Apply(dst, src *tensor.Dense) *tensor.Dense {
+	for i := range op.x {
+		op.x[i] = src.At(i, 0)
+	}
+	if op.mmap != nil {
+		op.mmap.MulVec(op.y, op.x)
+	} else if err := DirMatVec(op.dir, op.x, op.y); err != nil && op.err == nil {
+		op.err = errors.Wrap(err, "")
+	}
+	dst.Reset(op.dim, 1)
+	for i, v := range op.y {
+		dst.SetAt([]int{i, 0}, v)
+	}
+	return dst
+}
+
+// EigsDirStreaming is like EigsDir, but finds the k eigenvalues with the smallest real part with the Arnoldi iteration of linalg,
+// in which the matrix is applied with DirMatVec.
+// A matrix in the binary format of COOFormatBinary is memory mapped once for all applications.
+// It thus needs neither Python nor memory for the matrix, only for the Krylov space of options.
+func EigsDirStreaming(dir string, k int, options ...linalg.ArnoldiOptions) ([]ValVec, error) {
+	rows, cols, err := readShape(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if rows != cols {
+		return nil, errors.Errorf("%d %d", rows, cols)
+	}
+	op := &dirOperator{dir: dir, dim: rows, x: make([]complex64, rows), y: make([]complex64, rows)}
+	op.mmap, err = openMmapCOOIfExists(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if op.mmap != nil {
+		defer op.mmap.Close()
+	}
+
+	eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+	var bufs [7]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	err = linalg.ArnoldiOperator(eigvals, eigvecs, op, k, bufs, options...)
+	if op.err != nil {
+		return nil, errors.Wrap(op.err, "")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("%d %d", rows, k))
+	}
+
+	vvs := make([]ValVec, 0, k)
+	for j := range k {
+		vec := make([]complex128, rows)
+		for i := range vec {
+			vec[i] = complex128(eigvecs.At(i, j))
+		}
+		vvs = append(vvs, ValVec{Val: complex128(eigvals.At(j)), Vec: vec})
+	}
+	return vvs, nil
+}
@@ -0,0 +1,141 @@
+package mat
+
+import (
+	"fmt"
+	"math/cmplx"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestDirMatVec(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a *COO
+		x []complex64
+	}{
+		{
+			a: M([][]complex64{
+				{1, 0, 2i},
+				{0, -3, 0},
+			}),
+			x: []complex64{1, 2, 3},
+		},
+		{
+			a: M([][]complex64{
+				{8, -9, -6, 5},
+				{1, -3, 0, 7},
+				{2, 8, -8i, -3},
+				{1, 2, -5, -1 + 1i},
+			}),
+			x: []complex64{1, 1i, -1, 2},
+		},
+		{
+			a: COOZeros(3, 2),
+			x: []complex64{1, 1},
+		},
+	}
+	// The binary format is memory mapped rather than streamed.
+	options := []WriteCOOOptions{NewWriteCOOOptions(), NewWriteCOOOptions().Format(COOFormatBinary)}
+	for i, test := range tests {
+		for j, opt := range options {
+			t.Run(fmt.Sprintf("%d %d", i, j), func(t *testing.T) {
+				t.Parallel()
+				dir, err := os.MkdirTemp("", "")
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				defer os.RemoveAll(dir)
+				if err := test.a.WriteCOO(dir, opt); err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				want := make([]complex64, test.a.Rows())
+				test.a.MulVec(want, test.x)
+				y := make([]complex64, test.a.Rows())
+				y[0] = 1e9
+				if err := DirMatVec(dir, test.x, y); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if !slices.Equal(y, want) {
+					t.Fatalf("%v, expected %v", y, want)
+				}
+
+				if err := DirMatVec(dir, test.x, make([]complex64, test.a.Rows()+1)); err == nil {
+					t.Fatalf("expected error")
+				}
+			})
+		}
+	}
+}
+
+func TestEigsDirStreaming(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a *COO
+		k int
+	}{
+		{
+			a: M([][]complex64{
+				{2, -1, 0, 0, 0},
+				{-1, 2, -1, 0, 0},
+				{0, -1, 2, -1, 0},
+				{0, 0, -1, 2, -1},
+				{0, 0, 0, -1, 2},
+			}),
+			k: 2,
+		},
+		{
+			a: M([][]complex64{
+				{1, 1i, 0, 0},
+				{-1i, -2, 0.5, 0},
+				{0, 0.5, 3, 2},
+				{0, 0, 2, 0},
+			}),
+			k: 3,
+		},
+	}
+	// The binary format is memory mapped rather than streamed.
+	options := []WriteCOOOptions{NewWriteCOOOptions(), NewWriteCOOOptions().Format(COOFormatBinary)}
+	for i, test := range tests {
+		for j, opt := range options {
+			t.Run(fmt.Sprintf("%d %d", i, j), func(t *testing.T) {
+				t.Parallel()
+				dir, err := os.MkdirTemp("", "")
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				defer os.RemoveAll(dir)
+				if err := test.a.WriteCOO(dir, opt); err != nil {
+					t.Fatalf("%+v", err)
+				}
+
+				vvs, err := EigsDirStreaming(dir, test.k)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				want := test.a.Eigen()
+				if len(vvs) != test.k {
+					t.Fatalf("%d", len(vvs))
+				}
+				x := make([]complex64, test.a.Rows())
+				ax := make([]complex64, test.a.Rows())
+				for j, vv := range vvs {
+					if cmplx.Abs(vv.Val-want[j].Val) > 1e-4 {
+						t.Fatalf("%d %v %v", j, vv.Val, want[j].Val)
+					}
+					// Check the residual of the eigenvector.
+					for l, v := range vv.Vec {
+						x[l] = complex64(v)
+					}
+					test.a.MulVec(ax, x)
+					for l := range ax {
+						if cmplx.Abs(complex128(ax[l])-vv.Val*vv.Vec[l]) > 1e-4 {
+							t.Fatalf("%d %d %v %v", j, l, ax, vv)
+						}
+					}
+				}
+			})
+		}
+	}
+}
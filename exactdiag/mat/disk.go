@@ -3,13 +3,10 @@ package mat
 import (
 	"context"
 	"database/sql"
-	"encoding/csv"
 	"fmt"
 	"io"
 	"math/cmplx"
 	"os"
-	"path/filepath"
-	"strconv"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -18,6 +15,7 @@ import (
 
 const (
 	tableMatrix = "m"
+	tableShape  = "shape"
 )
 
 type DiskMatrix struct {
@@ -26,6 +24,10 @@ type DiskMatrix struct {
 	cols int
 
 	db *sql.DB
+
+	// readers is the number of concurrent reader connections of a read-only matrix.
+	// It is zero for writable matrices.
+	readers int
 }
 
 func DiskM(dbPath string, dense [][]complex64) *DiskMatrix {
@@ -57,11 +59,101 @@ func diskM(dbPath string, dense [][]complex64) (*DiskMatrix, error) {
 	return m, nil
 }
 
+// OpenDiskM opens a snapshot created by Snapshot in read-only mode.
+// readers is the number of concurrent connections used to stream the matrix, for example in MulVec.
+func OpenDiskM(dbPath string, readers int) *DiskMatrix {
+	m, err := openDiskM(dbPath, readers)
+	if err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
+	return m
+}
+
+func openDiskM(dbPath string, readers int) (*DiskMatrix, error) {
+	readers = max(readers, 1)
+	m := &DiskMatrix{Path: dbPath, readers: readers}
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	var err error
+	// Since a snapshot is never modified, it is safe to skip locking with immutable.
+	m.db, err = sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro&immutable=1", dbPath))
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	m.db.SetMaxOpenConns(readers)
+	m.db.SetMaxIdleConns(readers)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	sqlStr := fmt.Sprintf(`SELECT rows, cols FROM %s`, tableShape)
+	if err := m.db.QueryRowContext(ctx, sqlStr).Scan(&m.rows, &m.cols); err != nil {
+		m.db.Close()
+		return nil, errors.Wrap(err, "")
+	}
+	return m, nil
+}
+
+// Snapshot writes a consistent copy of the matrix to dbPath, which must not exist, and can be opened by OpenDiskM.
+// The matrix may itself be a snapshot.
+func (m *DiskMatrix) Snapshot(dbPath string) {
+	if err := m.snapshot(dbPath); err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
+}
+
+// snapshot writes the snapshot to dbPath, which is removed if the snapshot fails, unless it existed before.
+func (m *DiskMatrix) snapshot(dbPath string) error {
+	if _, err := os.Stat(dbPath); err == nil {
+		return errors.Errorf("%s exists", dbPath)
+	}
+	if err := m.writeSnapshot(dbPath); err != nil {
+		os.Remove(dbPath)
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+func (m *DiskMatrix) writeSnapshot(dbPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 48*time.Hour)
+	defer cancel()
+	if _, err := m.db.ExecContext(ctx, `VACUUM INTO ?`, dbPath); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s", dbPath))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	// The shape table is already copied if the matrix is itself a snapshot.
+	sqlStr := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (rows INTEGER, cols INTEGER) STRICT`, tableShape)
+	if _, err1 := db.ExecContext(ctx, sqlStr); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	sqlStr = fmt.Sprintf(`DELETE FROM %s`, tableShape)
+	if _, err1 := db.ExecContext(ctx, sqlStr); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	sqlStr = fmt.Sprintf(`INSERT INTO %s (rows, cols) VALUES (?, ?)`, tableShape)
+	if _, err1 := db.ExecContext(ctx, sqlStr, m.rows, m.cols); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := db.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// Close closes the matrix.
+// The database file is removed unless the matrix is a read-only snapshot.
 func (m *DiskMatrix) Close() error {
 	var err error
 	if err1 := m.db.Close(); err1 != nil && err == nil {
 		err = err1
 	}
+	if m.readers > 0 {
+		return err
+	}
 	if err1 := os.Remove(m.Path); err1 != nil && err == nil {
 		err = err1
 	}
@@ -160,6 +252,70 @@ func (m *DiskMatrix) at(i, j int) (complex64, error) {
 	}
 }
 
+// MulVec computes dst = m @ x, streaming the matrix from disk.
+func (m *DiskMatrix) MulVec(dst, x []complex64) {
+	if err := m.mulVec(dst, x); err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
+}
+
+func (m *DiskMatrix) mulVec(dst, x []complex64) error {
+	if len(dst) != m.rows || len(x) != m.cols {
+		return errors.Errorf("%d %d %d %d", len(dst), len(x), m.rows, m.cols)
+	}
+	clear(dst)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 48*time.Hour)
+	defer cancel()
+	if m.readers <= 1 {
+		return m.mulVecRows(ctx, dst, x, 0, m.rows)
+	}
+
+	// Split the rows among readers, each of which writes to a disjoint part of dst.
+	chunk := (m.rows + m.readers - 1) / m.readers
+	errs := make(chan error, m.readers)
+	var numJobs int
+	for start := 0; start < m.rows; start += chunk {
+		numJobs++
+		go func(start, end int) {
+			errs <- m.mulVecRows(ctx, dst, x, start, end)
+		}(start, min(start+chunk, m.rows))
+	}
+	var err error
+	for range numJobs {
+		if err1 := <-errs; err1 != nil && err == nil {
+			err = errors.Wrap(err1, "")
+			cancel()
+		}
+	}
+	return err
+}
+
+// mulVecRows computes dst[start:end] = m[start:end] @ x.
+// The entries of a row are added in the order of their columns, which the primary key provides for free,
+// such that the result is reproducible regardless of the number of readers and the query plan.
+func (m *DiskMatrix) mulVecRows(ctx context.Context, dst, x []complex64, start, end int) error {
+	sqlStr := fmt.Sprintf(`SELECT i, j, re, im FROM %s WHERE i >= ? AND i < ? ORDER BY i, j`, tableMatrix)
+	rows, err := m.db.QueryContext(ctx, sqlStr, start, end)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i, j int
+		var re, im float32
+		if err := rows.Scan(&i, &j, &re, &im); err != nil {
+			return errors.Wrap(err, "")
+		}
+		dst[i] += complex(re, im) * x[j]
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
 func (a *DiskMatrix) Add(c complex64, b Matrix) {
 	if err := a.add(c, b); err != nil {
 		panic(fmt.Sprintf("%+v", err))
@@ -267,15 +423,11 @@ func (m *DiskMatrix) numNonZero() (int, error) {
 	return n, nil
 }
 
-func (m *DiskMatrix) WriteCOO(dir string) error {
+// WriteCOO writes the matrix to dir in the format of options, which is CSV by default.
+func (m *DiskMatrix) WriteCOO(dir string, options ...WriteCOOOptions) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 48*time.Hour)
 	defer cancel()
 
-	shapePath := filepath.Join(dir, FnameShape)
-	if err := os.WriteFile(shapePath, []byte(fmt.Sprintf("%d,%d", m.rows, m.cols)), 0644); err != nil {
-		return errors.Wrap(err, "")
-	}
-
 	sqlStr := fmt.Sprintf(`SELECT i, j, re, im FROM %s ORDER BY i, j`, tableMatrix)
 	rows, err := m.db.QueryContext(ctx, sqlStr)
 	if err != nil {
@@ -283,12 +435,10 @@ func (m *DiskMatrix) WriteCOO(dir string) error {
 	}
 	defer rows.Close()
 
-	cooPath := filepath.Join(dir, FnameCOO)
-	cooF, err := os.Create(cooPath)
+	w, err := NewCOOWriter(dir, m.rows, m.cols, options...)
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
-	w := csv.NewWriter(cooF)
 
 	for rows.Next() {
 		var i, j int
@@ -297,9 +447,8 @@ func (m *DiskMatrix) WriteCOO(dir string) error {
 			err = errors.Wrap(err1, "")
 			break
 		}
-		v := complex(re, im)
 
-		if err1 := w.Write([]string{FormatNumpy(v), strconv.Itoa(i), strconv.Itoa(j)}); err1 != nil && err == nil {
+		if err1 := w.Write(i, j, complex(re, im)); err1 != nil && err == nil {
 			err = errors.Wrap(err1, "")
 			break
 		}
@@ -308,15 +457,10 @@ func (m *DiskMatrix) WriteCOO(dir string) error {
 		err = errors.Wrap(err1, "")
 	}
 
-	w.Flush()
-	if err1 := w.Error(); err1 != nil && err == nil {
-		err = errors.Wrap(err1, "")
-	}
-	if err1 := cooF.Close(); err1 != nil && err == nil {
+	if err1 := w.Close(); err1 != nil && err == nil {
 		err = errors.Wrap(err1, "")
 	}
 	return err
-
 }
 
 func setItemMust(ctx context.Context, db *sql.DB, i, j int, v complex64) {
@@ -115,3 +115,154 @@ func TestDiskKron(t *testing.T) {
 		})
 	}
 }
+
+func TestAtMulVec(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a [][]complex64
+		x []complex64
+		y []complex64
+	}{
+		{
+			a: [][]complex64{
+				{1, 0, 2i},
+				{0, -3, 0},
+			},
+			x: []complex64{1, 2, 3},
+			y: []complex64{1 + 6i, -6},
+		},
+		{
+			a: [][]complex64{
+				{0, 0},
+				{0, 0},
+			},
+			x: []complex64{1, 1i},
+			y: []complex64{0, 0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v", test.a), func(t *testing.T) {
+			t.Parallel()
+			dir, err := os.MkdirTemp("", "")
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			for _, m := range []Matrix{M(test.a), DiskM(filepath.Join(dir, "a.db"), test.a)} {
+				for i, row := range test.a {
+					for j, v := range row {
+						if m.At(i, j) != v {
+							t.Fatalf("%T %d %d %v, expected %v", m, i, j, m.At(i, j), v)
+						}
+					}
+				}
+
+				y := make([]complex64, m.Rows())
+				m.MulVec(y, test.x)
+				for i := range y {
+					if y[i] != test.y[i] {
+						t.Fatalf("%T %v, expected %v", m, y, test.y)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDiskSnapshot(t *testing.T) {
+	t.Parallel()
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dense := make([][]complex64, 37)
+	for i := range dense {
+		dense[i] = make([]complex64, 37)
+		for j := range dense[i] {
+			if (i+2*j)%5 == 0 {
+				dense[i][j] = complex(float32(i), float32(-j))
+			}
+		}
+	}
+	a := DiskM(filepath.Join(dir, "a.db"), dense)
+	defer a.Close()
+	snapshotPath := filepath.Join(dir, "snapshot.db")
+	a.Snapshot(snapshotPath)
+
+	// Modifications after the snapshot are not visible.
+	a.Zeros(1, 1)
+
+	s := OpenDiskM(snapshotPath, 4)
+	if s.Rows() != len(dense) || s.Cols() != len(dense[0]) {
+		t.Fatalf("%d %d", s.Rows(), s.Cols())
+	}
+	if !s.COO().Equal(M(dense)) {
+		t.Fatalf("%s, expected %s", s.COO(), M(dense))
+	}
+
+	x := make([]complex64, s.Cols())
+	for i := range x {
+		x[i] = complex(1, float32(i))
+	}
+	want := make([]complex64, s.Rows())
+	M(dense).MulVec(want, x)
+
+	// Concurrent readers.
+	errs := make(chan error, 8)
+	for range cap(errs) {
+		go func() {
+			y := make([]complex64, s.Rows())
+			s.MulVec(y, x)
+			for i := range y {
+				if y[i] != want[i] {
+					errs <- fmt.Errorf("%d %v %v", i, y[i], want[i])
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	for range cap(errs) {
+		if err := <-errs; err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+
+	// A snapshot of a snapshot, which already has the shape.
+	snapshot2Path := filepath.Join(dir, "snapshot2.db")
+	s.Snapshot(snapshot2Path)
+	s2 := OpenDiskM(snapshot2Path, 1)
+	defer s2.Close()
+	if s2.Rows() != len(dense) || s2.Cols() != len(dense[0]) {
+		t.Fatalf("%d %d", s2.Rows(), s2.Cols())
+	}
+	if !s2.COO().Equal(M(dense)) {
+		t.Fatalf("%s, expected %s", s2.COO(), M(dense))
+	}
+
+	// A failed snapshot leaves no file behind, and an existing file is untouched.
+	if err := s.snapshot(snapshot2Path); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := os.Stat(snapshot2Path); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	failedPath := filepath.Join(dir, "missing", "snapshot.db")
+	if err := s.snapshot(failedPath); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := os.Stat(failedPath); !os.IsNotExist(err) {
+		t.Fatalf("%+v", err)
+	}
+
+	// Closing a snapshot does not remove it.
+	if err := s.Close(); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err := os.Stat(snapshotPath); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
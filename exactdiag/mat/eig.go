@@ -0,0 +1,110 @@
+package mat
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EigReader streams an eig.csv file, whose first row are the eigenvalues, and whose i+1-th row are the i-th components of the eigenvectors.
+// Only a single row is held in memory, so that statistics of eigenvectors too large to fit in memory can be computed.
+// Both numpy's and Go's formatting of complex numbers are accepted.
+type EigReader struct {
+	r    *csv.Reader
+	vals []complex128
+	row  []complex128
+	// rowI is the index of the last read row of eigenvector components.
+	rowI int
+}
+
+// NewEigReader returns a reader of the eig.csv file r, after reading its eigenvalues.
+func NewEigReader(r io.Reader) (*EigReader, error) {
+	er := &EigReader{r: csv.NewReader(r), rowI: -1}
+	er.r.ReuseRecord = true
+	rec, err := er.r.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	er.vals = make([]complex128, len(rec))
+	if err := parseEigRecord(er.vals, rec); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	er.row = make([]complex128, len(rec))
+	return er, nil
+}
+
+// Eigenvalues returns the eigenvalues, which are also the number of eigenvectors.
+func (er *EigReader) Eigenvalues() []complex128 {
+	return er.vals
+}
+
+// Rows calls fn with each remaining row, where row[j] is the i-th component of the j-th eigenvector.
+// row is reused between calls, and reading stops at the first error returned by fn.
+func (er *EigReader) Rows(fn func(i int, row []complex128) error) error {
+	for {
+		rec, err := er.r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		er.rowI++
+		if len(rec) != len(er.row) {
+			return errors.Errorf("%d %d %d", er.rowI, len(rec), len(er.row))
+		}
+		if err := parseEigRecord(er.row, rec); err != nil {
+			return errors.Wrap(err, "")
+		}
+		if err := fn(er.rowI, er.row); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+}
+
+// Column calls fn with each remaining component of the j-th eigenvector.
+func (er *EigReader) Column(j int, fn func(i int, v complex128) error) error {
+	if j < 0 || j >= len(er.vals) {
+		return errors.Errorf("%d %d", j, len(er.vals))
+	}
+	return er.Rows(func(i int, row []complex128) error { return fn(i, row[j]) })
+}
+
+// ReadEig reads all eigenvalues and eigenvectors of the eig.csv file r.
+func ReadEig(r io.Reader) ([]ValVec, error) {
+	er, err := NewEigReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	vvs := make([]ValVec, len(er.Eigenvalues()))
+	for j, v := range er.Eigenvalues() {
+		vvs[j].Val = v
+	}
+	err = er.Rows(func(i int, row []complex128) error {
+		for j, v := range row {
+			vvs[j].Vec = append(vvs[j].Vec, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return vvs, nil
+}
+
+// parseEigRecord parses rec into dst, accepting the imaginary unit j of numpy.
+func parseEigRecord(dst []complex128, rec []string) error {
+	for j, vStr := range rec {
+		s := strings.TrimSpace(vStr)
+		s = strings.ReplaceAll(s, "j", "i")
+		v, err := strconv.ParseComplex(s, 128)
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		dst[j] = v
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package mat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEigReader(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		csv  string
+		vals []complex128
+		vecs [][]complex128
+	}{
+		{
+			csv:  "(-1+0i),(2+0.5i)\n(0.5+0i),(1+0i)\n(0-0.5i),(0+2i)\n",
+			vals: []complex128{-1, 2 + 0.5i},
+			vecs: [][]complex128{{0.5, -0.5i}, {1, 2i}},
+		},
+		{
+			// numpy formatting.
+			csv:  "-1, (2+0.5j)\n 0.5, 1\n-0.5j, 2j\n",
+			vals: []complex128{-1, 2 + 0.5i},
+			vecs: [][]complex128{{0.5, -0.5i}, {1, 2i}},
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			vvs, err := ReadEig(strings.NewReader(test.csv))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(vvs) != len(test.vals) {
+				t.Fatalf("%#v", vvs)
+			}
+			for j, vv := range vvs {
+				if vv.Val != test.vals[j] || !slices.Equal(vv.Vec, test.vecs[j]) {
+					t.Fatalf("%d %#v", j, vv)
+				}
+			}
+
+			// Stream a single eigenvector.
+			er, err := NewEigReader(strings.NewReader(test.csv))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			vec := make([]complex128, 0)
+			err = er.Column(1, func(i int, v complex128) error {
+				if i != len(vec) {
+					t.Fatalf("%d %d", i, len(vec))
+				}
+				vec = append(vec, v)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !slices.Equal(vec, test.vecs[1]) {
+				t.Fatalf("%#v", vec)
+			}
+		})
+	}
+}
+
+func TestEigReaderError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		csv    string
+		column int
+	}{
+		{csv: "1,2\n1,2,3\n", column: 0},
+		{csv: "1,2\n1,x\n", column: 0},
+		{csv: "1,2\n1,2\n", column: 2},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			er, err := NewEigReader(strings.NewReader(test.csv))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := er.Column(test.column, func(int, complex128) error { return nil }); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
@@ -81,8 +81,14 @@ def main():
 
     # Compute eigenvalue.
     k = 3
-    # vals, vecs = scipy.sparse.linalg.eigs(m, which="SR", k=k)
-    vals, vecs = scipy.sparse.linalg.eigsh(m, which="SA", k=k)
+    m = m.tocsr()
+    if dtype == np.complex64 and abs(m - m.conj().transpose()).max() > 0:
+        # Non-Hermitian matrices require the general solver, whose eigenvalues are not sorted.
+        vals, vecs = scipy.sparse.linalg.eigs(m, which="SR", k=k)
+        order = np.argsort(vals.real)
+        vals, vecs = vals[order], vecs[:, order]
+    else:
+        vals, vecs = scipy.sparse.linalg.eigsh(m, which="SA", k=k)
 
     # Write eigenvalue.
     vecs = np.insert(vecs, 0, vals, axis=0)
@@ -0,0 +1,176 @@
+package mat
+
+import (
+	"fmt"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/mat"
+)
+
+// The functions in this file convert between the matrices of this package, the tensors of github.com/fumin/tensor, and the matrices of gonum.
+// Since gonum works in double precision, values are widened to complex128 or float64 on the way in, and narrowed to complex64 on the way out.
+// Gonum has no sparse matrix types, so COO is exposed to gonum as a mat.CMatrix instead, or densified.
+
+// ToCDense returns the gonum complex matrix of a two dimensional tensor.
+func ToCDense(a *tensor.Dense) *mat.CDense {
+	rows, cols := tensorDims(a)
+	b := mat.NewCDense(rows, cols, nil)
+	for i := range rows {
+		for j := range cols {
+			b.Set(i, j, complex128(a.At(i, j)))
+		}
+	}
+	return b
+}
+
+// ToDense returns the gonum real matrix of a two dimensional tensor, which must have no imaginary parts.
+func ToDense(a *tensor.Dense) (*mat.Dense, error) {
+	rows, cols := tensorDims(a)
+	b := mat.NewDense(rows, cols, nil)
+	for i := range rows {
+		for j := range cols {
+			v := a.At(i, j)
+			if imag(v) != 0 {
+				return nil, errors.Errorf("%d %d %v", i, j, v)
+			}
+			b.Set(i, j, float64(real(v)))
+		}
+	}
+	return b, nil
+}
+
+// FromCMatrix copies the gonum complex matrix a to dst, and returns dst.
+func FromCMatrix(dst *tensor.Dense, a mat.CMatrix) *tensor.Dense {
+	rows, cols := a.Dims()
+	dst.Reset(rows, cols)
+	for i := range rows {
+		for j := range cols {
+			dst.SetAt([]int{i, j}, complex64(a.At(i, j)))
+		}
+	}
+	return dst
+}
+
+// FromMatrix copies the gonum real matrix a to dst, and returns dst.
+func FromMatrix(dst *tensor.Dense, a mat.Matrix) *tensor.Dense {
+	rows, cols := a.Dims()
+	dst.Reset(rows, cols)
+	for i := range rows {
+		for j := range cols {
+			dst.SetAt([]int{i, j}, complex(float32(a.At(i, j)), 0))
+		}
+	}
+	return dst
+}
+
+func tensorDims(a *tensor.Dense) (int, int) {
+	s := a.Shape()
+	if len(s) != 2 {
+		panic(fmt.Sprintf("%#v", s))
+	}
+	return s[0], s[1]
+}
+
+// cooCMatrix is a read-only view of a COO as a gonum complex matrix, with the elements indexed for constant time access.
+type cooCMatrix struct {
+	rows, cols int
+	m          map[[2]int]complex128
+}
+
+// CMatrix returns a read-only view of m as a gonum complex matrix, which is not affected by subsequent modifications of m.
+// Its memory is proportional to the number of non-zero elements of m, so it can be passed to gonum functions that only read elements.
+func (m *COO) CMatrix() mat.CMatrix {
+	c := cooCMatrix{rows: m.rows, cols: m.cols, m: make(map[[2]int]complex128, len(m.Data))}
+	for _, v := range m.Data {
+		c.m[[2]int{v.row, v.col}] += complex128(v.v)
+	}
+	return c
+}
+
+func (c cooCMatrix) Dims() (int, int) { return c.rows, c.cols }
+
+func (c cooCMatrix) At(i, j int) complex128 {
+	if i < 0 || i >= c.rows || j < 0 || j >= c.cols {
+		panic(mat.ErrIndexOutOfRange)
+	}
+	return c.m[[2]int{i, j}]
+}
+
+func (c cooCMatrix) H() mat.CMatrix { return mat.ConjTranspose{CMatrix: c} }
+func (c cooCMatrix) T() mat.CMatrix { return mat.CTranspose{CMatrix: c} }
+
+// CDense returns m as a dense gonum complex matrix.
+func (m *COO) CDense() *mat.CDense {
+	b := mat.NewCDense(m.rows, m.cols, nil)
+	for _, v := range m.Data {
+		b.Set(v.row, v.col, b.At(v.row, v.col)+complex128(v.v))
+	}
+	return b
+}
+
+// COOFromCMatrix returns the COO of the non-zero elements of the gonum complex matrix a.
+func COOFromCMatrix(a mat.CMatrix) *COO {
+	rows, cols := a.Dims()
+	m := COOZeros(rows, cols)
+	for i := range rows {
+		for j := range cols {
+			if v := complex64(a.At(i, j)); v != 0 {
+				m.Data = append(m.Data, vRowCol{v: v, row: i, col: j})
+			}
+		}
+	}
+	return m
+}
+
+// COOFromMatrix returns the COO of the non-zero elements of the gonum real matrix a.
+// Gonum matrices that implement mat.NonZeroDoer, such as the banded ones, are traversed over their non-zero elements only.
+func COOFromMatrix(a mat.Matrix) *COO {
+	rows, cols := a.Dims()
+	m := COOZeros(rows, cols)
+	add := func(i, j int, v float64) {
+		if v != 0 {
+			m.Data = append(m.Data, vRowCol{v: complex(float32(v), 0), row: i, col: j})
+		}
+	}
+	if nz, ok := a.(mat.NonZeroDoer); ok {
+		nz.DoNonZero(add)
+		return m
+	}
+	for i := range rows {
+		for j := range cols {
+			add(i, j, a.At(i, j))
+		}
+	}
+	return m
+}
+
+// HermitianEigenvalues returns the eigenvalues of the Hermitian matrix a in ascending order, computed in double precision by gonum.
+// Since gonum has no complex eigensolver, a = x + iy is embedded in the real symmetric matrix [[x, -y], [y, x]],
+// whose spectrum is that of a with every eigenvalue doubled.
+func HermitianEigenvalues(a [][]complex128) ([]float64, error) {
+	n := len(a)
+	s := mat.NewSymDense(2*n, nil)
+	for i, row := range a {
+		if len(row) != n {
+			return nil, errors.Errorf("%d %d %d", i, len(row), n)
+		}
+		for j := i; j < n; j++ {
+			v := row[j]
+			s.SetSym(i, j, real(v))
+			s.SetSym(n+i, n+j, real(v))
+			s.SetSym(i, n+j, -imag(v))
+			s.SetSym(j, n+i, imag(v))
+		}
+	}
+	var eig mat.EigenSym
+	if ok := eig.Factorize(s, false); !ok {
+		return nil, errors.Errorf("factorize failed")
+	}
+	doubled := eig.Values(nil)
+	vals := make([]float64, n)
+	for i := range vals {
+		vals[i] = doubled[2*i]
+	}
+	return vals, nil
+}
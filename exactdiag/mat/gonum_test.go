@@ -0,0 +1,119 @@
+package mat
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/fumin/tensor"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestGonumTensor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a    *tensor.Dense
+		real bool
+	}{
+		{a: tensor.T2([][]complex64{{1, 2, 3}, {4, 5, 6}}), real: true},
+		{a: tensor.T2([][]complex64{{1 + 2i, 0}, {-3i, 4}}), real: false},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			c := ToCDense(test.a)
+			if err := FromCMatrix(tensor.Zeros(1), c).Equal(test.a, 0); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			// Gonum operations see the same matrix.
+			if err := FromCMatrix(tensor.Zeros(1), c.H()).Equal(test.a.H(), 0); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			d, err := ToDense(test.a)
+			if !test.real {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := FromMatrix(tensor.Zeros(1), d.T()).Equal(test.a.Transpose(1, 0), 0); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestGonumCOO(t *testing.T) {
+	t.Parallel()
+	m := M([][]complex64{
+		{0, 1 + 1i, 0},
+		{2, 0, 0},
+		{0, 0, -3i},
+	})
+	// Duplicate entries are summed.
+	m.Data = append(m.Data, vRowCol{v: 5, row: 1, col: 0})
+	want := mat.NewCDense(3, 3, []complex128{
+		0, 1 + 1i, 0,
+		7, 0, 0,
+		0, 0, -3i,
+	})
+
+	if !mat.CEqual(m.CMatrix(), want) {
+		t.Fatalf("%v", m.CMatrix())
+	}
+	if !mat.CEqual(m.CMatrix().H(), want.H()) {
+		t.Fatalf("%v", m.CMatrix().H())
+	}
+	if !mat.CEqual(m.CDense(), want) {
+		t.Fatalf("%v", m.CDense())
+	}
+	if back := COOFromCMatrix(want); !back.Equal(M([][]complex64{{0, 1 + 1i, 0}, {7, 0, 0}, {0, 0, -3i}})) {
+		t.Fatalf("%s", back)
+	}
+
+	// Banded gonum matrices are traversed over their non-zero elements.
+	band := mat.NewBandDense(3, 3, 1, 0, []float64{
+		0, 1,
+		2, 3,
+		4, 5,
+	})
+	if back := COOFromMatrix(band); !back.Equal(M([][]complex64{{1, 0, 0}, {2, 3, 0}, {0, 4, 5}})) {
+		t.Fatalf("%s", back)
+	}
+	if back := COOFromMatrix(mat.NewDense(2, 2, []float64{0, 1, 2, 0})); !back.Equal(M([][]complex64{{0, 1}, {2, 0}})) {
+		t.Fatalf("%s", back)
+	}
+}
+
+func TestHermitianEigenvalues(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a    [][]complex128
+		vals []float64
+	}{
+		{a: [][]complex128{{2}}, vals: []float64{2}},
+		// PauliY has the eigenvalues -1 and 1.
+		{a: [][]complex128{{0, -1i}, {1i, 0}}, vals: []float64{-1, 1}},
+		{a: [][]complex128{{1, 1 - 1i, 0}, {1 + 1i, 1, 0}, {0, 0, 3}}, vals: []float64{1 - math.Sqrt2, 1 + math.Sqrt2, 3}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			vals, err := HermitianEigenvalues(test.a)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !slices.EqualFunc(vals, test.vals, func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }) {
+				t.Fatalf("%v %v", vals, test.vals)
+			}
+		})
+	}
+	if _, err := HermitianEigenvalues([][]complex128{{1, 2}, {3}}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -6,36 +6,83 @@ import (
 	"log"
 	"math"
 	"math/cmplx"
-	"math/rand"
+	"math/rand/v2"
 	"slices"
 	"time"
 
 	"github.com/fumin/qising/exactdiag/mat/util"
+	"github.com/fumin/qising/linalg"
+	"github.com/pkg/errors"
 )
 
+// GradientDescentOptions are options for GradientDescentWithOptions.
+type GradientDescentOptions struct {
+	rand      *rand.Rand
+	interrupt <-chan struct{}
+}
+
+// NewGradientDescentOptions returns the default options.
+func NewGradientDescentOptions() GradientDescentOptions {
+	return GradientDescentOptions{}
+}
+
+// Rand sets the random source of the initial vector and the shuffles of the batches.
+// If r is nil, the global random source is used.
+func (opt GradientDescentOptions) Rand(r *rand.Rand) GradientDescentOptions {
+	opt.rand = r
+	return opt
+}
+
+// Interrupt sets a channel, such as the Done channel of a context.Context, whose closing stops the descent before its next epoch,
+// and returns an error whose cause is linalg.ErrInterrupted.
+func (opt GradientDescentOptions) Interrupt(c <-chan struct{}) GradientDescentOptions {
+	opt.interrupt = c
+	return opt
+}
+
 func GradientDescent(m *COO) (float32, []complex64) {
+	return GradientDescentWithRand(nil, m)
+}
+
+// GradientDescentWithRand is like GradientDescent, but draws the initial vector and the shuffles of the batches from r.
+// If r is nil, the global random source is used.
+func GradientDescentWithRand(r *rand.Rand, m *COO) (float32, []complex64) {
+	// Without an interrupt channel, the descent does not fail.
+	lambda, vec, _ := GradientDescentWithOptions(m, NewGradientDescentOptions().Rand(r))
+	return lambda, vec
+}
+
+// GradientDescentWithOptions is like GradientDescent with the options opt, and returns an error if it is interrupted.
+func GradientDescentWithOptions(m *COO, opt GradientDescentOptions) (float32, []complex64, error) {
 	floor := gerschgorin(m)
-	return gradientDescent(m, floor)
+	return gradientDescent(m, m.CSR(), floor, opt)
 }
 
-func gradientDescent(m *COO, floor float32) (float32, []complex64) {
+// rowReader reads the entries of a row of a matrix, such as CSR.
+type rowReader interface {
+	Row(i int) ([]int, []complex64)
+}
+
+// gradientDescent descends on the matrix m, whose rows are read from rows.
+func gradientDescent(m *COO, rows rowReader, floor float32, opt GradientDescentOptions) (float32, []complex64, error) {
+	r := opt.rand
+	randFloat := rand.Float64
+	if r != nil {
+		randFloat = r.Float64
+	}
 	var lambda float64 = float64(floor)
 	vecRe := make([]float64, m.cols)
 	vecIm := make([]float64, m.cols)
 	for i := range vecRe {
-		vecRe[i] = rand.Float64()
-		vecIm[i] = rand.Float64()
+		vecRe[i] = randFloat()
+		vecIm[i] = randFloat()
 	}
 	var lambdaGrad float64
 	vecReGrad := make([]float64, len(vecRe))
 	vecImGrad := make([]float64, len(vecIm))
 
-	byRow := make(map[int][]vRowCol)
-	for _, v := range m.Data {
-		byRow[v.row] = append(byRow[v.row], v)
-	}
 	batchSize := 256
-	data := newDataloader(m.cols, batchSize)
+	data := newDataloader(r, m.cols, batchSize)
 
 	var lossSEWeight float64 = 0 * float64(len(m.Data))
 	lossFn := func() (float64, float64, float64) {
@@ -53,8 +100,9 @@ func gradientDescent(m *COO, floor float32) (float32, []complex64) {
 		for _, i := range iBatch {
 			reVi, imVi := vecRe[i], vecIm[i]
 			var reAvLv, imAvLv float64
-			for _, aijV := range byRow[i] {
-				j, aij := aijV.col, aijV.v
+			cols, vals := rows.Row(i)
+			for k, j := range cols {
+				aij := vals[k]
 				reVj, imVj := vecRe[j], vecIm[j]
 
 				reAvLv += float64(real(aij))*reVj - float64(imag(aij))*imVj
@@ -67,8 +115,8 @@ func gradientDescent(m *COO, floor float32) (float32, []complex64) {
 				lossDiag += reAvLv
 				lambdaGrad += -reVi
 				vecReGrad[i] += -lambda
-				for _, aijV := range byRow[i] {
-					j, aij := aijV.col, aijV.v
+				for k, j := range cols {
+					aij := vals[k]
 					vecReGrad[j] += float64(real(aij))
 					vecImGrad[j] += -float64(imag(aij))
 				}
@@ -76,8 +124,8 @@ func gradientDescent(m *COO, floor float32) (float32, []complex64) {
 				lossDiag += -reAvLv
 				lambdaGrad += reVi
 				vecReGrad[i] += lambda
-				for _, aijV := range byRow[i] {
-					j, aij := aijV.col, aijV.v
+				for k, j := range cols {
+					aij := vals[k]
 					vecReGrad[j] += -float64(real(aij))
 					vecImGrad[j] += float64(imag(aij))
 				}
@@ -86,8 +134,8 @@ func gradientDescent(m *COO, floor float32) (float32, []complex64) {
 				lossDiag += imAvLv
 				lambdaGrad += -imVi
 				vecImGrad[i] += -lambda
-				for _, aijV := range byRow[i] {
-					j, aij := aijV.col, aijV.v
+				for k, j := range cols {
+					aij := vals[k]
 					vecReGrad[j] += float64(imag(aij))
 					vecImGrad[j] += float64(real(aij))
 				}
@@ -95,8 +143,8 @@ func gradientDescent(m *COO, floor float32) (float32, []complex64) {
 				lossDiag += -imAvLv
 				lambdaGrad += imVi
 				vecImGrad[i] += lambda
-				for _, aijV := range byRow[i] {
-					j, aij := aijV.col, aijV.v
+				for k, j := range cols {
+					aij := vals[k]
 					vecReGrad[j] += -float64(imag(aij))
 					vecImGrad[j] += -float64(real(aij))
 				}
@@ -115,6 +163,11 @@ func gradientDescent(m *COO, floor float32) (float32, []complex64) {
 	epochIters := (m.rows / len(data.batch)) + 1
 	learningRate := newLearningRateAdjuster()
 	for epoch := 0; epoch < math.MaxInt; epoch++ {
+		select {
+		case <-opt.interrupt:
+			return 0, nil, errors.WithStack(linalg.ErrInterrupted)
+		default:
+		}
 		var diagDiff float64
 		for i := 0; i < epochIters; i++ {
 			loss, lossDiag, lossSE := lossFn()
@@ -168,7 +221,7 @@ func gradientDescent(m *COO, floor float32) (float32, []complex64) {
 	for i := range vec {
 		vec[i] /= complex(norm, 0)
 	}
-	return float32(lambda), vec
+	return float32(lambda), vec, nil
 }
 
 type learningRateAdjuster struct {
@@ -219,10 +272,13 @@ type dataloader struct {
 	ptr     int
 
 	batch []int
+	// rand, if not nil, is the random source of the shuffles.
+	rand *rand.Rand
 }
 
-func newDataloader(n, batchSize int) *dataloader {
+func newDataloader(r *rand.Rand, n, batchSize int) *dataloader {
 	dl := &dataloader{
+		rand:    r,
 		indices: make([]int, n),
 		batch:   make([]int, batchSize),
 	}
@@ -249,7 +305,11 @@ func (dl *dataloader) get() []int {
 }
 
 func (dl *dataloader) shuffle() {
-	rand.Shuffle(len(dl.indices), func(i, j int) {
+	shuffle := rand.Shuffle
+	if dl.rand != nil {
+		shuffle = dl.rand.Shuffle
+	}
+	shuffle(len(dl.indices), func(i, j int) {
 		dl.indices[i], dl.indices[j] = dl.indices[j], dl.indices[i]
 	})
 }
@@ -0,0 +1,111 @@
+package mat
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/pkg/errors"
+)
+
+func TestGradientDescentRand(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a *COO
+	}{
+		{a: M([][]complex64{
+			{2, 1, 0},
+			{1, 2, 1},
+			{0, 1, 2},
+		})},
+		{a: M([][]complex64{
+			{-1, 0.5},
+			{0.5, 1},
+		})},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			lambda0, vec0 := GradientDescentWithRand(rand.New(rand.NewPCG(1, 1)), test.a)
+			lambda1, vec1 := GradientDescentWithRand(rand.New(rand.NewPCG(1, 1)), test.a)
+			if lambda0 != lambda1 || !slices.Equal(vec0, vec1) {
+				t.Fatalf("%f %v %f %v", lambda0, vec0, lambda1, vec1)
+			}
+		})
+	}
+}
+
+func TestGradientDescentCSR(t *testing.T) {
+	t.Parallel()
+	unsorted := COOZeros(3, 3)
+	unsorted.Append(2, 2, 2)
+	unsorted.Append(0, 2, 0.5i)
+	unsorted.Append(1, 1, 3)
+	unsorted.Append(0, 0, 1)
+	unsorted.Append(2, 0, -0.5i)
+	tests := []struct {
+		a *COO
+	}{
+		{a: M([][]complex64{
+			{2, 1, 0},
+			{1, 2, 1},
+			{0, 1, 2},
+		})},
+		{a: M([][]complex64{
+			{-1, 0.5},
+			{0.5, 1},
+		})},
+		{a: unsorted},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			floor := gerschgorin(test.a)
+			opt := NewGradientDescentOptions()
+			lambda, vec, err := gradientDescent(test.a, test.a.CSR(), floor, opt.Rand(rand.New(rand.NewPCG(1, 1))))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			wantLambda, want, err := gradientDescent(test.a, newCOORows(test.a), floor, opt.Rand(rand.New(rand.NewPCG(1, 1))))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if lambda != wantLambda || !slices.Equal(vec, want) {
+				t.Fatalf("%f %v %f %v", lambda, vec, wantLambda, want)
+			}
+		})
+	}
+}
+
+// cooRows reads the rows of a COO matrix by grouping its entries, as GradientDescent did before the CSR format.
+type cooRows struct {
+	cols map[int][]int
+	vals map[int][]complex64
+}
+
+func newCOORows(m *COO) cooRows {
+	r := cooRows{cols: make(map[int][]int), vals: make(map[int][]complex64)}
+	for _, v := range m.Data {
+		r.cols[v.row] = append(r.cols[v.row], v.col)
+		r.vals[v.row] = append(r.vals[v.row], v.v)
+	}
+	return r
+}
+
+func (r cooRows) Row(i int) ([]int, []complex64) { return r.cols[i], r.vals[i] }
+
+func TestGradientDescentInterrupt(t *testing.T) {
+	t.Parallel()
+	m := M([][]complex64{
+		{2, 1, 0},
+		{1, 2, 1},
+		{0, 1, 2},
+	}).COO()
+	interrupt := make(chan struct{})
+	close(interrupt)
+	if _, _, err := GradientDescentWithOptions(m, NewGradientDescentOptions().Interrupt(interrupt)); !errors.Is(err, linalg.ErrInterrupted) {
+		t.Fatalf("%+v", err)
+	}
+}
@@ -1,11 +1,15 @@
 package mat
 
 import (
+	"bufio"
 	"cmp"
+	"compress/gzip"
 	_ "embed"
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math"
+	"math/cmplx"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,6 +17,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/fumin/tensor"
 	"github.com/pkg/errors"
 	"gonum.org/v1/gonum/mat"
 )
@@ -42,12 +47,14 @@ type Matrix interface {
 	Scalar(complex64)
 	Rows() int
 	Cols() int
+	At(int, int) complex64
 
 	Add(complex64, Matrix)
+	MulVec([]complex64, []complex64)
 	Kron(*COO)
 	COO() *COO
 
-	WriteCOO(string) error
+	WriteCOO(string, ...WriteCOOOptions) error
 }
 
 type vRowCol struct {
@@ -106,6 +113,56 @@ func (m *COO) Scalar(v complex64) {
 	m.Data = append(m.Data, vRowCol{v: v, row: 0, col: 0})
 }
 
+// Append appends the entry v at row i and column j.
+// Entries should be appended in row major order without duplicates, which is the order kept by the other methods of COO.
+func (m *COO) Append(i, j int, v complex64) {
+	if i < 0 || i >= m.rows || j < 0 || j >= m.cols {
+		panic(fmt.Sprintf("%d %d %d %d", i, j, m.rows, m.cols))
+	}
+	m.Data = append(m.Data, vRowCol{v: v, row: i, col: j})
+}
+
+func (m *COO) At(i, j int) complex64 {
+	var v complex64
+	for _, d := range m.Data {
+		if d.row == i && d.col == j {
+			v += d.v
+		}
+	}
+	return v
+}
+
+// MulVec computes dst = m @ x.
+func (m *COO) MulVec(dst, x []complex64) {
+	if len(dst) != m.rows || len(x) != m.cols {
+		panic(fmt.Sprintf("%d %d %d %d", len(dst), len(x), m.rows, m.cols))
+	}
+	clear(dst)
+	for _, d := range m.Data {
+		dst[d.row] += d.v * x[d.col]
+	}
+}
+
+// NormBound returns the upper bound sqrt(|m|_1 |m|_inf) of the spectral norm of m, where |m|_1 and |m|_inf are the largest absolute column and row sums.
+// Unlike the randomized linalg.SpectralRadius, it is a strict bound, and is cheap to compute from the entries.
+// See Section 2.3.3 Some Matrix Norm Properties, G. H. Golub and C. F. Van Loan, Matrix Computations 4th Edition.
+func (m *COO) NormBound() float64 {
+	rowSums, colSums := make([]float64, m.rows), make([]float64, m.cols)
+	for _, d := range m.Data {
+		a := math.Hypot(float64(real(d.v)), float64(imag(d.v)))
+		rowSums[d.row] += a
+		colSums[d.col] += a
+	}
+	var rowMax, colMax float64
+	for _, s := range rowSums {
+		rowMax = max(rowMax, s)
+	}
+	for _, s := range colSums {
+		colMax = max(colMax, s)
+	}
+	return math.Sqrt(rowMax * colMax)
+}
+
 func (a *COO) Equal(b *COO) bool {
 	if a.rows != b.rows {
 		return false
@@ -253,64 +310,104 @@ func (m *COO) Dense() [][]complex64 {
 	return dense
 }
 
-func (m *COO) WriteCOO(dir string) error {
-	shapePath := filepath.Join(dir, FnameShape)
-	if err := os.WriteFile(shapePath, []byte(fmt.Sprintf("%d,%d", m.rows, m.cols)), 0644); err != nil {
-		return errors.Wrap(err, "")
-	}
-
-	cooPath := filepath.Join(dir, FnameCOO)
-	cooF, err := os.Create(cooPath)
+// WriteCOO writes the matrix to dir in the format of options, which is CSV by default.
+func (m *COO) WriteCOO(dir string, options ...WriteCOOOptions) error {
+	w, err := NewCOOWriter(dir, m.rows, m.cols, options...)
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
-
-	w := csv.NewWriter(cooF)
 	for _, v := range m.Data {
-		if err1 := w.Write([]string{FormatNumpy(v.v), strconv.Itoa(v.row), strconv.Itoa(v.col)}); err1 != nil && err == nil {
+		if err1 := w.Write(v.row, v.col, v.v); err1 != nil && err == nil {
 			err = errors.Wrap(err1, "")
 			break
 		}
 	}
-	w.Flush()
-	if err1 := w.Error(); err1 != nil && err == nil {
-		err = errors.Wrap(err1, "")
-	}
-
-	if err1 := cooF.Close(); err1 != nil && err == nil {
+	if err1 := w.Close(); err1 != nil && err == nil {
 		err = errors.Wrap(err1, "")
 	}
 	return err
 }
 
+// COOReader reads the entries of the COO matrix in a directory one at a time.
 type COOReader struct {
-	f *os.File
-	r *csv.Reader
-	i int
+	f  *os.File
+	r  *csv.Reader
+	vr *bufio.Reader
+	gz *gzip.Reader
+	br *bufio.Reader
+	i  int
 
 	prev vRowCol
 }
 
+// NewCOOReader returns a reader of the COO matrix in dir, whose format is detected from the files in dir.
 func NewCOOReader(dir string) (*COOReader, error) {
 	r := &COOReader{i: -1}
-
-	cooPath := filepath.Join(dir, "coo.csv")
-	var err error
-	r.f, err = os.Open(cooPath)
+	format, err := detectCOOFormat(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	fname, err := NewWriteCOOOptions().Format(format).fname()
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	r.f, err = os.Open(filepath.Join(dir, fname))
 	if err != nil {
 		return nil, errors.Wrap(err, "")
 	}
 
-	r.r = csv.NewReader(r.f)
+	switch format {
+	case COOFormatVarint:
+		r.vr, r.gz, err = newVarintReader(r.f)
+		if err != nil {
+			r.f.Close()
+			return nil, errors.Wrap(err, "")
+		}
+	case COOFormatBinary:
+		r.br = bufio.NewReader(r.f)
+	default:
+		r.r = csv.NewReader(r.f)
+	}
 	return r, nil
 }
 
 func (r *COOReader) Close() error {
-	return r.f.Close()
+	var err error
+	if r.gz != nil {
+		if err1 := r.gz.Close(); err1 != nil && err == nil {
+			err = errors.Wrap(err1, "")
+		}
+	}
+	if err1 := r.f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
 }
 
 func (r *COOReader) Read() (vRowCol, error) {
 	r.i++
+	if r.vr != nil {
+		vrc, err := readVarint(r.vr, r.prev)
+		if err == io.EOF {
+			return vRowCol{}, io.EOF
+		}
+		if err != nil {
+			return vRowCol{}, errors.Wrap(err, fmt.Sprintf("%d", r.i))
+		}
+		r.prev = vrc
+		return vrc, nil
+	}
+	if r.br != nil {
+		vrc, err := readBinary(r.br)
+		if err == io.EOF {
+			return vRowCol{}, io.EOF
+		}
+		if err != nil {
+			return vRowCol{}, errors.Wrap(err, fmt.Sprintf("%d", r.i))
+		}
+		return vrc, nil
+	}
+
 	record, err := r.r.Read()
 	if err == io.EOF {
 		return vRowCol{}, io.EOF
@@ -447,13 +544,48 @@ type ValVec struct {
 	Vec []complex128
 }
 
+// FixPhase multiplies each eigenvector of vvs by a phase, such that its largest component is real and positive, and returns vvs.
+// Among components of nearly equal magnitudes, the first is chosen, so that stored eigenvectors are comparable across runs, see linalg.FixPhase.
+func FixPhase(vvs []ValVec) []ValVec {
+	for _, vv := range vvs {
+		var largest float64
+		for _, x := range vv.Vec {
+			largest = max(largest, cmplx.Abs(x))
+		}
+		if largest == 0 {
+			continue
+		}
+		for i, x := range vv.Vec {
+			if cmplx.Abs(x) < (1-1e-6)*largest {
+				continue
+			}
+			phase := cmplx.Conj(x) / complex(cmplx.Abs(x), 0)
+			for j := range vv.Vec {
+				vv.Vec[j] *= phase
+			}
+			vv.Vec[i] = complex(cmplx.Abs(x), 0)
+			break
+		}
+	}
+	return vvs
+}
+
+// Eigen returns the eigenvalues and right eigenvectors of m, sorted by the real parts of the eigenvalues.
+// Real matrices are solved in double precision by gonum, whereas complex matrices, which may be non-Hermitian, are solved by tensor.Eig.
 func (m *COO) Eigen() []ValVec {
-	gnm := mat.NewDense(m.rows, m.cols, nil)
-	gnm.Zero()
 	for _, v := range m.Data {
 		if imag(v.v) != 0 {
-			panic("not real")
+			vvs, err := m.eigenComplex()
+			if err != nil {
+				panic(fmt.Sprintf("%+v", err))
+			}
+			return vvs
 		}
+	}
+
+	gnm := mat.NewDense(m.rows, m.cols, nil)
+	gnm.Zero()
+	for _, v := range m.Data {
 		gnm.Set(v.row, v.col, float64(real(v.v)))
 	}
 
@@ -480,6 +612,29 @@ func (m *COO) Eigen() []ValVec {
 	return vvs
 }
 
+func (m *COO) eigenComplex() ([]ValVec, error) {
+	a := tensor.Zeros(m.rows, m.cols)
+	for _, v := range m.Data {
+		a.SetAt([]int{v.row, v.col}, a.At(v.row, v.col)+v.v)
+	}
+
+	eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+	bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+	if err := tensor.Eig(eigvals, eigvecs, a, bufs); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	vvs := make([]ValVec, 0, m.rows)
+	for i := range m.rows {
+		vec := make([]complex128, 0, m.rows)
+		for j := range m.rows {
+			vec = append(vec, complex128(eigvecs.At(j, i)))
+		}
+		vvs = append(vvs, ValVec{Val: complex128(eigvals.At(i)), Vec: vec})
+	}
+	return vvs, nil
+}
+
 func Eigs(m Matrix) []ValVec {
 	vv, err := eigs(m)
 	if err != nil {
@@ -541,53 +696,10 @@ func eigsDir(mDir string) ([]ValVec, error) {
 		return nil, errors.Wrap(err, "")
 	}
 	defer f.Close()
-	r := csv.NewReader(f)
-	rowI := -1
-
-	rec, err := r.Read()
-	if err != nil {
-		return nil, errors.Wrap(err, "")
-	}
-	buf := make([]complex128, len(rec))
-	parseRec := func(rec []string) ([]complex128, error) {
-		for j, vStr := range rec {
-			s := strings.TrimSpace(vStr)
-			s = strings.ReplaceAll(s, "j", "i")
-			buf[j], err = strconv.ParseComplex(s, 128)
-			if err != nil {
-				return nil, errors.Errorf("%d %d %#v", rowI, j, rec)
-			}
-		}
-		return buf, nil
-	}
-	vs, err := parseRec(rec)
+	vvs, err := ReadEig(f)
 	if err != nil {
 		return nil, errors.Wrap(err, "")
 	}
-	vvs := make([]ValVec, len(rec))
-	for j, v := range vs {
-		vvs[j].Val = v
-	}
-
-	for {
-		rec, err := r.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return nil, errors.Wrap(err, "")
-		}
-		rowI++
-
-		vs, err := parseRec(rec)
-		if err != nil {
-			return nil, errors.Wrap(err, "")
-		}
-		for j, v := range vs {
-			vvs[j].Vec = append(vvs[j].Vec, v)
-		}
-	}
-
 	return vvs, nil
 }
 
@@ -2,6 +2,8 @@ package mat
 
 import (
 	"fmt"
+	"math"
+	"math/cmplx"
 	"testing"
 )
 
@@ -191,3 +193,120 @@ func TestKron(t *testing.T) {
 		})
 	}
 }
+
+func TestEigenComplex(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a    *COO
+		vals []complex128
+	}{
+		{
+			a:    M(PauliY),
+			vals: []complex128{-1, 1},
+		},
+		{
+			// Non-Hermitian.
+			a: M([][]complex64{
+				{1, 1i, 0},
+				{0, 2, 3},
+				{0, 0, 3 - 1i},
+			}),
+			vals: []complex128{1, 2, 3 - 1i},
+		},
+		{
+			// Non-Hermitian with loss.
+			a: M([][]complex64{
+				{-0.5i, 1},
+				{1, 0},
+			}),
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			vvs := test.a.Eigen()
+			if len(vvs) != test.a.Rows() {
+				t.Fatalf("%d %d", len(vvs), test.a.Rows())
+			}
+			for j, vv := range vvs {
+				if test.vals != nil && cmplx.Abs(vv.Val-test.vals[j]) > 1e-5 {
+					t.Fatalf("%d %v %v", j, vv.Val, test.vals[j])
+				}
+
+				// Check a @ v = lambda * v.
+				x := make([]complex64, len(vv.Vec))
+				for k, v := range vv.Vec {
+					x[k] = complex64(v)
+				}
+				y := make([]complex64, len(x))
+				test.a.MulVec(y, x)
+				for k := range y {
+					if diff := cmplx.Abs(complex128(y[k]) - vv.Val*vv.Vec[k]); diff > 1e-5 {
+						t.Fatalf("%d %d %f", j, k, diff)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFixPhase(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		vec  []complex128
+		want []complex128
+	}{
+		{vec: []complex128{0.6i, -0.8}, want: []complex128{-0.6i, 0.8}},
+		{vec: []complex128{-0.5, 0.5i, 0.1}, want: []complex128{0.5, -0.5i, -0.1}},
+		{vec: []complex128{0, 0}, want: []complex128{0, 0}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			vvs := FixPhase([]ValVec{{Vec: test.vec}})
+			for j := range test.want {
+				if cmplx.Abs(vvs[0].Vec[j]-test.want[j]) > 1e-12 {
+					t.Fatalf("%v %v", vvs[0].Vec, test.want)
+				}
+			}
+		})
+	}
+
+	// Eigenvectors differing by a phase agree after fixing their phases, even if components tie in magnitude.
+	vvs := FixPhase(M(PauliY).Eigen())
+	for _, vv := range vvs {
+		rotated := make([]complex128, len(vv.Vec))
+		for j, x := range vv.Vec {
+			rotated[j] = x * cmplx.Exp(0.7i)
+		}
+		FixPhase([]ValVec{{Vec: rotated}})
+		for j := range rotated {
+			if cmplx.Abs(rotated[j]-vv.Vec[j]) > 1e-12 {
+				t.Fatalf("%v %v", rotated, vv.Vec)
+			}
+		}
+	}
+}
+
+func TestNormBound(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		m     *COO
+		bound float64
+	}{
+		{m: M([][]complex64{{1, 0}, {0, -3}}), bound: 3},
+		{m: M(PauliX), bound: 1},
+		// The spectral norm is sqrt(2).
+		{m: M([][]complex64{{1, 1}, {0, 0}}), bound: math.Sqrt(2)},
+		{m: M([][]complex64{{1, 1i}, {1, -1}}), bound: 2},
+		{m: COOZeros(3, 3), bound: 0},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if b := test.m.NormBound(); math.Abs(b-test.bound) > 1e-9 {
+				t.Fatalf("%f %f", b, test.bound)
+			}
+		})
+	}
+}
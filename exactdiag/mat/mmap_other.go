@@ -0,0 +1,22 @@
+//go:build !(linux || darwin || freebsd)
+
+package mat
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// mmap falls back to reading the whole file on platforms without mmap support.
+func mmap(fpath string) ([]byte, error) {
+	b, err := os.ReadFile(fpath)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return b, nil
+}
+
+func munmap(b []byte) error {
+	return nil
+}
@@ -0,0 +1,41 @@
+//go:build linux || darwin || freebsd
+
+package mat
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+func mmap(fpath string) ([]byte, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if fi.Size() == 0 {
+		return []byte{}, nil
+	}
+
+	b, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return b, nil
+}
+
+func munmap(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if err := syscall.Munmap(b); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
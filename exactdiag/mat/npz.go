@@ -0,0 +1,172 @@
+package mat
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	FnameHamiltonianNPZ = "hamiltonian.npz"
+	FnameEigNPZ         = "eig.npz"
+
+	// npyMagic starts a .npy file of format version 1.0.
+	npyMagic = "\x93NUMPY\x01\x00"
+	// npyAlign is the alignment of the data of a .npy file, which numpy requires of the header length.
+	npyAlign = 64
+)
+
+// WriteNPZ writes m to w in the .npz format of scipy.sparse.save_npz, which is read in Python by scipy.sparse.load_npz.
+// The archive holds the CSR arrays data, indices and indptr, together with format and shape.
+// The data type is complex64, and the indices are int32 unless they overflow it.
+func (m *CSR) WriteNPZ(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := writeNPY(zw, "format", "|S3", nil, []byte("csr"))
+	if err1 := writeNPY(zw, "shape", "<i8", []int{2}, []int64{int64(m.rows), int64(m.cols)}); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := writeNPY(zw, "data", "<c8", []int{len(m.data)}, m.data); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	indexDescr := "<i4"
+	var indices, indptr any = toInt32s(m.indices), toInt32s(m.indptr)
+	if len(m.data) > math.MaxInt32 || m.cols > math.MaxInt32 {
+		indexDescr, indices, indptr = "<i8", toInt64s(m.indices), toInt64s(m.indptr)
+	}
+	if err1 := writeNPY(zw, "indices", indexDescr, []int{len(m.indices)}, indices); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := writeNPY(zw, "indptr", indexDescr, []int{len(m.indptr)}, indptr); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := zw.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// WriteEigNPZ writes the eigenpairs vvs to w in the .npz format of numpy.savez, which is read in Python by numpy.load.
+// The archive holds the complex128 arrays eigenvalues, of shape (k,), and eigenvectors, of shape (n, k),
+// whose column eigenvectors[:, j] is the eigenvector of eigenvalues[j] as in numpy.linalg.eig.
+func WriteEigNPZ(w io.Writer, vvs []ValVec) error {
+	if len(vvs) == 0 {
+		return errors.Errorf("no eigenpairs")
+	}
+	n := len(vvs[0].Vec)
+	vals := make([]complex128, 0, len(vvs))
+	for j, vv := range vvs {
+		if len(vv.Vec) != n {
+			return errors.Errorf("%d %d %d", j, len(vv.Vec), n)
+		}
+		vals = append(vals, vv.Val)
+	}
+	vecs := make([]complex128, 0, n*len(vvs))
+	for i := range n {
+		for _, vv := range vvs {
+			vecs = append(vecs, vv.Vec[i])
+		}
+	}
+
+	zw := zip.NewWriter(w)
+	err := writeNPY(zw, "eigenvalues", "<c16", []int{len(vals)}, vals)
+	if err1 := writeNPY(zw, "eigenvectors", "<c16", []int{n, len(vvs)}, vecs); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := zw.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// WriteNPZDir writes m to FnameHamiltonianNPZ and vvs to FnameEigNPZ in dir, skipping m if it is nil, and vvs if it is empty.
+func WriteNPZDir(dir string, m *CSR, vvs []ValVec) error {
+	if m != nil {
+		if err := writeNPZFile(filepath.Join(dir, FnameHamiltonianNPZ), m.WriteNPZ); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	if len(vvs) > 0 {
+		write := func(w io.Writer) error { return WriteEigNPZ(w, vvs) }
+		if err := writeNPZFile(filepath.Join(dir, FnameEigNPZ), write); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return nil
+}
+
+func writeNPZFile(fpath string, write func(io.Writer) error) error {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	bw := bufio.NewWriter(f)
+	err = write(bw)
+	if err1 := bw.Flush(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// writeNPY writes the array name.npy of the given numpy dtype descr and shape to zw.
+// data is the flattened array in C order, which is encoded with encoding/binary in little endian.
+// See https://numpy.org/doc/stable/reference/generated/numpy.lib.format.html
+func writeNPY(zw *zip.Writer, name, descr string, shape []int, data any) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: zip.Deflate})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	dims := make([]string, 0, len(shape))
+	for _, d := range shape {
+		dims = append(dims, fmt.Sprintf("%d", d))
+	}
+	shapeStr := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		shapeStr += ","
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, shapeStr)
+	// Pad the header with spaces and a newline, so that the data is aligned.
+	prefix := len(npyMagic) + 2
+	header += strings.Repeat(" ", (npyAlign-(prefix+len(header)+1)%npyAlign)%npyAlign) + "\n"
+
+	if _, err := io.WriteString(w, npyMagic); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint16(len(header))); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+func toInt32s(xs []int) []int32 {
+	ys := make([]int32, len(xs))
+	for i, x := range xs {
+		ys[i] = int32(x)
+	}
+	return ys
+}
+
+func toInt64s(xs []int) []int64 {
+	ys := make([]int64, len(xs))
+	for i, x := range xs {
+		ys[i] = int64(x)
+	}
+	return ys
+}
@@ -0,0 +1,147 @@
+package mat
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestCSRWriteNPZ(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		a       *COO
+		indptr  []int32
+		indices []int32
+		data    []complex64
+	}{
+		{
+			a: M([][]complex64{
+				{1, 0, 2i},
+				{0, -3, 0},
+			}),
+			indptr:  []int32{0, 2, 3},
+			indices: []int32{0, 2, 1},
+			data:    []complex64{1, 2i, -3},
+		},
+		{
+			a:       COOZeros(3, 2),
+			indptr:  []int32{0, 0, 0, 0},
+			indices: []int32{},
+			data:    []complex64{},
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var b bytes.Buffer
+			if err := test.a.CSR().WriteNPZ(&b); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			arrays := readNPZ(t, b.Bytes())
+
+			checkNPY(t, arrays, "format", "|S3", "()", []byte("csr"))
+			checkNPY(t, arrays, "shape", "<i8", "(2,)", []int64{int64(test.a.Rows()), int64(test.a.Cols())})
+			checkNPY(t, arrays, "data", "<c8", fmt.Sprintf("(%d,)", len(test.data)), test.data)
+			checkNPY(t, arrays, "indices", "<i4", fmt.Sprintf("(%d,)", len(test.indices)), test.indices)
+			checkNPY(t, arrays, "indptr", "<i4", fmt.Sprintf("(%d,)", len(test.indptr)), test.indptr)
+		})
+	}
+}
+
+func TestWriteNPZDir(t *testing.T) {
+	t.Parallel()
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	vvs := []ValVec{
+		{Val: -1, Vec: []complex128{1, 2, 3}},
+		{Val: 2 + 1i, Vec: []complex128{4i, 5, 6}},
+	}
+	if err := WriteNPZDir(dir, M([][]complex64{{1, 2}, {3, 4}}).CSR(), vvs); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, FnameHamiltonianNPZ)); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, FnameEigNPZ))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	arrays := readNPZ(t, b)
+	checkNPY(t, arrays, "eigenvalues", "<c16", "(2,)", []complex128{-1, 2 + 1i})
+	// Eigenvectors are columns.
+	checkNPY(t, arrays, "eigenvectors", "<c16", "(3, 2)", []complex128{1, 4i, 2, 5, 3, 6})
+
+	if err := WriteEigNPZ(io.Discard, []ValVec{{Vec: []complex128{1}}, {Vec: []complex128{1, 2}}}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+type npyArray struct {
+	descr string
+	shape string
+	data  []byte
+}
+
+var npyHeaderRe = regexp.MustCompile(`^\{'descr': '([^']*)', 'fortran_order': False, 'shape': (\([^)]*\)), \} *\n$`)
+
+func readNPZ(t *testing.T, b []byte) map[string]npyArray {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	arrays := make(map[string]npyArray)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		npy, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		r.Close()
+
+		if !bytes.HasPrefix(npy, []byte(npyMagic)) {
+			t.Fatalf("%s %q", f.Name, npy)
+		}
+		headerLen := int(binary.LittleEndian.Uint16(npy[len(npyMagic):]))
+		start := len(npyMagic) + 2 + headerLen
+		if start%npyAlign != 0 {
+			t.Fatalf("%s %d", f.Name, start)
+		}
+		match := npyHeaderRe.FindStringSubmatch(string(npy[len(npyMagic)+2 : start]))
+		if match == nil {
+			t.Fatalf("%s %q", f.Name, npy[:start])
+		}
+		arrays[f.Name] = npyArray{descr: match[1], shape: match[2], data: npy[start:]}
+	}
+	return arrays
+}
+
+func checkNPY(t *testing.T, arrays map[string]npyArray, name, descr, shape string, data any) {
+	a, ok := arrays[name+".npy"]
+	if !ok {
+		t.Fatalf("%s %v", name, arrays)
+	}
+	if a.descr != descr || a.shape != shape {
+		t.Fatalf("%s %s %s", name, a.descr, a.shape)
+	}
+	var want bytes.Buffer
+	if err := binary.Write(&want, binary.LittleEndian, data); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !slices.Equal(a.data, want.Bytes()) {
+		t.Fatalf("%s %v %v", name, a.data, want.Bytes())
+	}
+}
@@ -0,0 +1,180 @@
+package exactdiag
+
+import (
+	"cmp"
+	"math"
+	"math/cmplx"
+	"slices"
+
+	"github.com/fumin/qising/exactdiag/basis"
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/pkg/errors"
+)
+
+// MomentumSector is a sector of a periodic chain of N spins, in which the translation T by one site has the eigenvalue exp(i*2*pi*K/N).
+// T moves the spin on site i to site i+1.
+type MomentumSector struct {
+	K int
+	// Representatives are the basis states of the sector, each of which is the smallest basis state in its orbit under translations,
+	// and Periods are the sizes of the orbits.
+	Representatives []int
+	Periods         []int
+}
+
+// Momentum returns the crystal momentum 2*pi*K/N of the sector.
+func (s MomentumSector) Momentum(numSpins int) float64 {
+	return 2 * math.Pi * float64(s.K) / float64(numSpins)
+}
+
+// MomentumSectors returns the momentum sectors K = 0, ..., N-1 of a periodic chain of N spins, whose dimensions sum to 2^N.
+// An orbit of period R contributes a basis state to the sectors in which K*R is a multiple of N.
+func MomentumSectors(numSpins int) []MomentumSector {
+	sectors := make([]MomentumSector, numSpins)
+	for k := range sectors {
+		sectors[k].K = k
+	}
+	for s := range 1 << numSpins {
+		rep, _ := representative(s, numSpins)
+		if rep != s {
+			continue
+		}
+		period := orbitPeriod(s, numSpins)
+		for k := range sectors {
+			if k*period%numSpins == 0 {
+				sectors[k].Representatives = append(sectors[k].Representatives, s)
+				sectors[k].Periods = append(sectors[k].Periods, period)
+			}
+		}
+	}
+	return sectors
+}
+
+// TransverseFieldIsingMomentum returns the transverse field Ising hamiltonian of a periodic chain of shape n restricted to sector.
+// The basis state of the representative a of period R is |a(k)> = sum_{r<N} exp(-ikr) T^r |a> / sqrt(N^2/R),
+// and the matrix element of a term mapping a to T^{-l}b, where b is a representative, is multiplied by exp(-ikl) sqrt(R_a/R_b).
+// The chain must be periodic in its direction, and the parity sector and field along Z options are not supported.
+// See Section 4.1.3 Momentum states, A. W. Sandvik, Computational Studies of Quantum Spin Systems, AIP Conf. Proc. 1297, 135 (2010).
+func TransverseFieldIsingMomentum(n [2]int, h complex64, sector MomentumSector, options ...IsingOptions) (*mat.COO, error) {
+	opt := NewIsingOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	numSpins := n[0] * n[1]
+	axis := 0
+	if n[0] == 1 {
+		axis = 1
+	}
+	if n[1-axis] != 1 || (numSpins > 2 && !opt.periodic[axis]) || opt.parity != 0 || opt.fieldAlongZ {
+		return nil, errors.Errorf("%v %v %d %t", n, opt.periodic, opt.parity, opt.fieldAlongZ)
+	}
+	_, hs := opt.scales()
+	if len(sector.Representatives) != len(sector.Periods) {
+		return nil, errors.Errorf("%d %d", len(sector.Representatives), len(sector.Periods))
+	}
+	index := make(map[int]int, len(sector.Representatives))
+	for i, a := range sector.Representatives {
+		index[a] = i
+	}
+	k := sector.Momentum(numSpins)
+
+	dim := len(sector.Representatives)
+	elems := make(map[[2]int]complex64)
+	state := make([]byte, numSpins)
+	bonds := make([][2]int, 0, 2)
+	vrcs := make([]vRowCol, 0, 1)
+	for i, a := range sector.Representatives {
+		basis.Decode(state, a)
+		vrcs = couplingExplicit(vrcs[:0], n, opt, i, state, bonds)
+		for _, v := range vrcs {
+			elems[[2]int{i, i}] += v.v
+		}
+
+		for site := range numSpins {
+			flipped := a ^ basis.Mask(numSpins, site)
+			b, l := representative(flipped, numSpins)
+			j, ok := index[b]
+			if !ok {
+				continue
+			}
+			phase := cmplx.Exp(complex(0, -k*float64(l))) * complex(math.Sqrt(float64(sector.Periods[i])/float64(sector.Periods[j])), 0)
+			elems[[2]int{j, i}] += -hs * h * complex64(phase)
+		}
+	}
+
+	keys := make([][2]int, 0, len(elems))
+	for yx, v := range elems {
+		if v != 0 {
+			keys = append(keys, yx)
+		}
+	}
+	slices.SortFunc(keys, func(a, b [2]int) int {
+		if c := cmp.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a[1], b[1])
+	})
+	m := mat.COOZeros(dim, dim)
+	for _, yx := range keys {
+		m.Append(yx[0], yx[1], elems[yx])
+	}
+	return m, nil
+}
+
+// MomentumValVec is an eigenpair of a momentum sector.
+type MomentumValVec struct {
+	// K labels the momentum sector, see MomentumSector.
+	K int
+	// Vec is in the basis of the sector.
+	mat.ValVec
+}
+
+// MomentumEigen returns the full spectrum of the transverse field Ising hamiltonian of a periodic chain of shape n,
+// by diagonalizing each momentum sector, and labelling the eigenpairs with their sectors.
+// The eigenpairs are sorted by the real parts of the eigenvalues.
+func MomentumEigen(n [2]int, h complex64, options ...IsingOptions) ([]MomentumValVec, error) {
+	vvs := make([]MomentumValVec, 0)
+	for _, sector := range MomentumSectors(n[0] * n[1]) {
+		if len(sector.Representatives) == 0 {
+			continue
+		}
+		m, err := TransverseFieldIsingMomentum(n, h, sector, options...)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		for _, vv := range m.Eigen() {
+			vvs = append(vvs, MomentumValVec{K: sector.K, ValVec: vv})
+		}
+	}
+	slices.SortStableFunc(vvs, func(a, b MomentumValVec) int { return cmp.Compare(real(a.Val), real(b.Val)) })
+	return vvs, nil
+}
+
+// translate returns T s, where the spin of site i of the chain of numSpins spins is bit numSpins-1-i of s.
+func translate(s, numSpins int) int {
+	return s>>1 | (s&1)<<(numSpins-1)
+}
+
+// representative returns the smallest state rep in the orbit of s, and the number of translations l such that T^l s = rep.
+func representative(s, numSpins int) (int, int) {
+	rep, l := s, 0
+	t := s
+	for r := 1; r < numSpins; r++ {
+		t = translate(t, numSpins)
+		if t < rep {
+			rep, l = t, r
+		}
+	}
+	return rep, l
+}
+
+// orbitPeriod returns the smallest R > 0 such that T^R s = s.
+func orbitPeriod(s, numSpins int) int {
+	t := s
+	for r := 1; r <= numSpins; r++ {
+		t = translate(t, numSpins)
+		if t == s {
+			return r
+		}
+	}
+	return numSpins
+}
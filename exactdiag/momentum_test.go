@@ -0,0 +1,118 @@
+package exactdiag
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag/mat"
+)
+
+func TestMomentumSectors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		numSpins int
+		dims     []int
+	}{
+		{numSpins: 1, dims: []int{2}},
+		{numSpins: 2, dims: []int{3, 1}},
+		{numSpins: 4, dims: []int{6, 3, 4, 3}},
+		{numSpins: 6, dims: []int{14, 9, 11, 10, 11, 9}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			sectors := MomentumSectors(test.numSpins)
+			if len(sectors) != len(test.dims) {
+				t.Fatalf("%d", len(sectors))
+			}
+			for k, s := range sectors {
+				if s.K != k || len(s.Representatives) != test.dims[k] || len(s.Periods) != test.dims[k] {
+					t.Fatalf("%d %#v", k, s)
+				}
+			}
+		})
+	}
+}
+
+func TestMomentumEigen(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n            [2]int
+		h            complex64
+		longitudinal complex64
+	}{
+		{n: [2]int{6, 1}, h: 0.7},
+		{n: [2]int{1, 5}, h: 1.5},
+		{n: [2]int{7, 1}, h: 1, longitudinal: 0.3},
+		{n: [2]int{2, 1}, h: 0.5},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			opt := NewIsingOptions().Periodic([2]bool{true, true}).LongitudinalField(test.longitudinal)
+			full, buf := mat.M([][]complex64{{0}}), mat.M([][]complex64{{0}})
+			TransverseFieldIsing(full, buf, test.n, test.h, opt)
+			want := full.Eigen()
+
+			// Each sector is Hermitian.
+			for _, sector := range MomentumSectors(test.n[0] * test.n[1]) {
+				m, err := TransverseFieldIsingMomentum(test.n, test.h, sector, opt)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				dense := m.Dense()
+				for a := range dense {
+					for b := range dense[a] {
+						if cmplx.Abs(complex128(dense[a][b]-conj(dense[b][a]))) > 1e-6 {
+							t.Fatalf("%d %d %d %v", sector.K, a, b, dense)
+						}
+					}
+				}
+			}
+
+			got, err := MomentumEigen(test.n, test.h, opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%d %d", len(got), len(want))
+			}
+			for j := range want {
+				if cmplx.Abs(got[j].Val-want[j].Val) > 1e-4 {
+					t.Fatalf("%d %v %v", j, got[j].Val, want[j].Val)
+				}
+			}
+			// The ground state is translation invariant.
+			if got[0].K != 0 && math.Abs(real(got[1].Val-got[0].Val)) > 1e-4 {
+				t.Fatalf("%#v", got[0])
+			}
+		})
+	}
+}
+
+func TestTransverseFieldIsingMomentumError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n   [2]int
+		opt IsingOptions
+	}{
+		{n: [2]int{4, 1}, opt: NewIsingOptions()},
+		{n: [2]int{2, 2}, opt: NewIsingOptions().Periodic([2]bool{true, true})},
+		{n: [2]int{4, 1}, opt: NewIsingOptions().Periodic([2]bool{true, false}).ParitySector(1)},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			sector := MomentumSectors(test.n[0] * test.n[1])[0]
+			if _, err := TransverseFieldIsingMomentum(test.n, 1, sector, test.opt); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func conj(c complex64) complex64 {
+	return complex(real(c), -imag(c))
+}
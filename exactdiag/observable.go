@@ -0,0 +1,192 @@
+package exactdiag
+
+import (
+	"github.com/fumin/qising/exactdiag/basis"
+	"github.com/pkg/errors"
+)
+
+// ExpectationValue returns <vec|op_site|vec> / <vec|vec>, where op is a single spin operator such as mat.PauliX,
+// acting on the site of index site = y*n[1] + x of the lattice of shape n, in the basis of TransverseFieldIsingDisordered.
+// It pairs the amplitudes of the basis states that differ only in the spin of the site, without building the operator on the whole lattice.
+func ExpectationValue(n [2]int, vec []complex128, op [][]complex64, site int) (complex128, error) {
+	norm, err := checkExpectation(n, vec, op)
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	numSpins := n[0] * n[1]
+	if site < 0 || site >= numSpins {
+		return 0, errors.Errorf("%d %d", site, numSpins)
+	}
+	return siteExpectation(numSpins, vec, op, site) / complex(norm, 0), nil
+}
+
+// LocalExpectationValues returns the expectation values of op on each site, such as the local magnetization profile of mat.PauliZ, see ExpectationValue.
+func LocalExpectationValues(n [2]int, vec []complex128, op [][]complex64) ([]complex128, error) {
+	norm, err := checkExpectation(n, vec, op)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	numSpins := n[0] * n[1]
+	values := make([]complex128, numSpins)
+	for site := range numSpins {
+		values[site] = siteExpectation(numSpins, vec, op, site) / complex(norm, 0)
+	}
+	return values, nil
+}
+
+// TotalExpectationValue returns the expectation value of the sum of op over all sites, such as the total magnetization along X of mat.PauliX.
+func TotalExpectationValue(n [2]int, vec []complex128, op [][]complex64) (complex128, error) {
+	values, err := LocalExpectationValues(n, vec, op)
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	var total complex128
+	for _, v := range values {
+		total += v
+	}
+	return total, nil
+}
+
+// checkExpectation checks the shapes of vec and op, and returns the norm square of vec.
+func checkExpectation(n [2]int, vec []complex128, op [][]complex64) (float64, error) {
+	if len(op) != 2 || len(op[0]) != 2 || len(op[1]) != 2 {
+		return 0, errors.Errorf("%v", op)
+	}
+	norm, err := checkVector(n, vec)
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	return norm, nil
+}
+
+// checkVector checks that vec is a non-zero vector of the lattice of shape n, and returns its norm square.
+func checkVector(n [2]int, vec []complex128) (float64, error) {
+	numSpins := n[0] * n[1]
+	if len(vec) != 1<<numSpins {
+		return 0, errors.Errorf("%d %d", len(vec), 1<<numSpins)
+	}
+	var norm float64
+	for _, c := range vec {
+		norm += real(c)*real(c) + imag(c)*imag(c)
+	}
+	if norm == 0 {
+		return 0, errors.Errorf("zero vector")
+	}
+	return norm, nil
+}
+
+// siteExpectation returns the unnormalized <vec|op_site|vec>.
+func siteExpectation(numSpins int, vec []complex128, op [][]complex64, site int) complex128 {
+	mask := basis.Mask(numSpins, site)
+	var v complex128
+	for i, c := range vec {
+		if c == 0 {
+			continue
+		}
+		// <i| op_site = sum_b op[s][b] <i with the spin of site set to b|, where s is the spin of site in i.
+		s := 0
+		if i&mask != 0 {
+			s = 1
+		}
+		conj := complex(real(c), -imag(c))
+		v += conj * complex128(op[s][s]) * c
+		v += conj * complex128(op[s][1-s]) * vec[i^mask]
+	}
+	return v
+}
+
+// Correlation returns <vec|opA_siteI opB_siteJ|vec> / <vec|vec>, where opA and opB are single spin operators acting on the sites of indices siteI and siteJ, see ExpectationValue.
+// If siteI equals siteJ, the product opA @ opB acts on the site.
+// Like ExpectationValue, it pairs the amplitudes of the basis states that differ only in the spins of the two sites.
+func Correlation(n [2]int, vec []complex128, opA, opB [][]complex64, siteI, siteJ int) (complex128, error) {
+	norm, err := checkExpectation(n, vec, opA)
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	if _, err := checkExpectation(n, vec, opB); err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	numSpins := n[0] * n[1]
+	if siteI < 0 || siteI >= numSpins || siteJ < 0 || siteJ >= numSpins {
+		return 0, errors.Errorf("%d %d %d", siteI, siteJ, numSpins)
+	}
+	if siteI == siteJ {
+		var ab [2][2]complex64
+		for i := range 2 {
+			for j := range 2 {
+				ab[i][j] = opA[i][0]*opB[0][j] + opA[i][1]*opB[1][j]
+			}
+		}
+		return siteExpectation(numSpins, vec, [][]complex64{ab[0][:], ab[1][:]}, siteI) / complex(norm, 0), nil
+	}
+
+	maskI, maskJ := basis.Mask(numSpins, siteI), basis.Mask(numSpins, siteJ)
+	var v complex128
+	for i, c := range vec {
+		if c == 0 {
+			continue
+		}
+		si, sj := 0, 0
+		if i&maskI != 0 {
+			si = 1
+		}
+		if i&maskJ != 0 {
+			sj = 1
+		}
+		// Sum over the spins a and b of the two sites in the ket.
+		var ket complex128
+		for a := range 2 {
+			for b := range 2 {
+				k := i
+				if a != si {
+					k ^= maskI
+				}
+				if b != sj {
+					k ^= maskJ
+				}
+				ket += complex128(opA[si][a]*opB[sj][b]) * vec[k]
+			}
+		}
+		v += complex(real(c), -imag(c)) * ket
+	}
+	return v / complex(norm, 0), nil
+}
+
+// ZZCorrelationMatrix returns the connected correlations <Z_i Z_j> - <Z_i><Z_j> of all pairs of sites i and j of vec, indexed by i = y*n[1] + x.
+// Since Z is diagonal in the basis, each basis state contributes its probability times the product of the signs of its spins,
+// hence the matrix is accumulated in a single pass over vec.
+func ZZCorrelationMatrix(n [2]int, vec []complex128) ([][]float64, error) {
+	norm, err := checkVector(n, vec)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	numSpins := n[0] * n[1]
+	z := make([]float64, numSpins)
+	zz := make([][]float64, numSpins)
+	for i := range zz {
+		zz[i] = make([]float64, numSpins)
+	}
+	spins := make([]float64, numSpins)
+	for k, c := range vec {
+		p := (real(c)*real(c) + imag(c)*imag(c)) / norm
+		if p == 0 {
+			continue
+		}
+		for s := range spins {
+			spins[s] = float64(basis.Parity(k & basis.Mask(numSpins, s)))
+		}
+		for i, si := range spins {
+			z[i] += p * si
+			for j := i; j < numSpins; j++ {
+				zz[i][j] += p * si * spins[j]
+			}
+		}
+	}
+	for i := range numSpins {
+		for j := i; j < numSpins; j++ {
+			zz[i][j] -= z[i] * z[j]
+			zz[j][i] = zz[i][j]
+		}
+	}
+	return zz, nil
+}
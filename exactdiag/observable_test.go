@@ -0,0 +1,177 @@
+package exactdiag
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag/mat"
+)
+
+func TestExpectationValue(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n  [2]int
+		op [][]complex64
+	}{
+		{n: [2]int{1, 1}, op: mat.PauliX},
+		{n: [2]int{3, 1}, op: mat.PauliY},
+		{n: [2]int{2, 2}, op: [][]complex64{{0.5, 1 - 2i}, {-0.25i, -1}}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			numSpins := test.n[0] * test.n[1]
+			vec := randVec(rand.New(rand.NewPCG(uint64(i), uint64(i))), 1<<numSpins)
+			var norm complex128
+			for _, c := range vec {
+				norm += cmplx.Conj(c) * c
+			}
+
+			// Compare with the operator on the whole lattice.
+			local, err := LocalExpectationValues(test.n, vec, test.op)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			var total complex128
+			for site := range numSpins {
+				m, buf := mat.COOZeros(1<<numSpins, 1<<numSpins), mat.COOZeros(1, 1)
+				AddOneSiteTerm(m, buf, test.n, 1, mat.M(test.op), [2]int{site / test.n[1], site % test.n[1]})
+				var want complex128
+				for j, row := range m.Dense() {
+					for k, v := range row {
+						want += cmplx.Conj(vec[j]) * complex128(v) * vec[k]
+					}
+				}
+				want /= norm
+				total += want
+
+				v, err := ExpectationValue(test.n, vec, test.op, site)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if cmplx.Abs(v-want) > 1e-9 || cmplx.Abs(local[site]-want) > 1e-9 {
+					t.Fatalf("%d %v %v %v", site, v, local[site], want)
+				}
+			}
+			v, err := TotalExpectationValue(test.n, vec, test.op)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if cmplx.Abs(v-total) > 1e-9 {
+				t.Fatalf("%v %v", v, total)
+			}
+		})
+	}
+}
+
+func TestExpectationValueError(t *testing.T) {
+	t.Parallel()
+	n := [2]int{2, 1}
+	vec := []complex128{1, 0, 0, 0}
+	if _, err := ExpectationValue(n, vec, mat.PauliX, 2); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := ExpectationValue(n, vec[:3], mat.PauliX, 0); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := ExpectationValue(n, vec, [][]complex64{{1}}, 0); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := TotalExpectationValue(n, make([]complex128, 4), mat.PauliX); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n        [2]int
+		opA, opB [][]complex64
+	}{
+		{n: [2]int{2, 1}, opA: mat.PauliX, opB: mat.PauliZ},
+		{n: [2]int{2, 2}, opA: mat.PauliY, opB: [][]complex64{{0.5, 1 - 2i}, {-0.25i, -1}}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			numSpins := test.n[0] * test.n[1]
+			vec := randVec(rand.New(rand.NewPCG(uint64(i), uint64(i))), 1<<numSpins)
+			var norm complex128
+			for _, c := range vec {
+				norm += cmplx.Conj(c) * c
+			}
+
+			// Compare with the operators on the whole lattice.
+			site := func(s int) [2]int { return [2]int{s / test.n[1], s % test.n[1]} }
+			for si := range numSpins {
+				for sj := range numSpins {
+					m, buf := mat.COOZeros(1<<numSpins, 1<<numSpins), mat.COOZeros(1, 1)
+					if si == sj {
+						ab := mat.COOZeros(2, 2)
+						for r := range 2 {
+							for c := range 2 {
+								ab.Append(r, c, test.opA[r][0]*test.opB[0][c]+test.opA[r][1]*test.opB[1][c])
+							}
+						}
+						AddOneSiteTerm(m, buf, test.n, 1, ab, site(si))
+					} else {
+						AddTwoSiteTerm(m, buf, test.n, 1, mat.M(test.opA), mat.M(test.opB), site(si), site(sj))
+					}
+					var want complex128
+					for j, row := range m.Dense() {
+						for k, v := range row {
+							want += cmplx.Conj(vec[j]) * complex128(v) * vec[k]
+						}
+					}
+					want /= norm
+
+					v, err := Correlation(test.n, vec, test.opA, test.opB, si, sj)
+					if err != nil {
+						t.Fatalf("%+v", err)
+					}
+					if cmplx.Abs(v-want) > 1e-9 {
+						t.Fatalf("%d %d %v %v", si, sj, v, want)
+					}
+				}
+			}
+
+			zz, err := ZZCorrelationMatrix(test.n, vec)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			z, err := LocalExpectationValues(test.n, vec, mat.PauliZ)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for si := range numSpins {
+				for sj := range numSpins {
+					c, err := Correlation(test.n, vec, mat.PauliZ, mat.PauliZ, si, sj)
+					if err != nil {
+						t.Fatalf("%+v", err)
+					}
+					if want := real(c - z[si]*z[sj]); math.Abs(zz[si][sj]-want) > 1e-9 {
+						t.Fatalf("%d %d %f %f", si, sj, zz[si][sj], want)
+					}
+				}
+			}
+		})
+	}
+
+	if _, err := Correlation([2]int{2, 1}, []complex128{1, 0, 0, 0}, mat.PauliZ, mat.PauliZ, 0, 2); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := ZZCorrelationMatrix([2]int{2, 1}, []complex128{1, 0}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func randVec(r *rand.Rand, n int) []complex128 {
+	vec := make([]complex128, n)
+	for j := range vec {
+		vec[j] = complex(r.Float64()*2-1, r.Float64()*2-1)
+	}
+	return vec
+}
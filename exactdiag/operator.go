@@ -0,0 +1,132 @@
+package exactdiag
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fumin/qising/exactdiag/basis"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// IsingOperator is the matrix-free linear operator of the hamiltonian of TransverseFieldIsingDisordered.
+// Its products with vectors are computed from the couplings and fields without storing the matrix,
+// hence it uses memory linear in the dimension instead of the sparse matrix with numSpins+1 entries per row.
+type IsingOperator struct {
+	// diag is the diagonal of the hamiltonian, which consists of the coupling and longitudinal field terms.
+	diag []complex64
+	// fields are the transverse fields of the sites, and masks the bits of the sites in the index of a basis state.
+	fields []complex64
+	masks  []int
+}
+
+// NewIsingOperator returns the matrix-free operator of the hamiltonian of TransverseFieldIsingDisordered.
+// coupling and field are called in the same order as by TransverseFieldIsingDisordered.
+// The parity sector and field along Z options are not supported.
+func NewIsingOperator(n [2]int, coupling func(a, b [2]int) complex64, field func(a [2]int) complex64, options ...IsingOptions) (*IsingOperator, error) {
+	opt := NewIsingOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.parity != 0 {
+		return nil, errors.Errorf("parity sector %d", opt.parity)
+	}
+	if opt.fieldAlongZ {
+		return nil, errors.Errorf("field along Z")
+	}
+	j, hs := opt.scales()
+	g := hs * opt.longitudinal
+	numSpins := n[0] * n[1]
+	op := &IsingOperator{diag: make([]complex64, 1<<numSpins)}
+
+	mask := func(a [2]int) int { return basis.SiteMask(n, a) }
+	type bond struct {
+		masks [2]int
+		j     complex64
+	}
+	bonds := make([]bond, 0)
+	buf := make([][2]int, 0, 2)
+	for y := range n[0] {
+		for x := range n[1] {
+			for _, b := range neighbors(buf, n, y, x, opt.periodic) {
+				bonds = append(bonds, bond{masks: [2]int{mask(b), mask([2]int{y, x})}, j: j * coupling(b, [2]int{y, x})})
+			}
+			op.fields = append(op.fields, hs*field([2]int{y, x}))
+			op.masks = append(op.masks, mask([2]int{y, x}))
+		}
+	}
+
+	for i := range op.diag {
+		var d complex64
+		for _, b := range bonds {
+			switch {
+			case (i&b.masks[0] == 0) == (i&b.masks[1] == 0):
+				d -= b.j
+			default:
+				d += b.j
+			}
+		}
+		for _, m := range op.masks {
+			switch {
+			case i&m == 0:
+				d -= g
+			default:
+				d += g
+			}
+		}
+		op.diag[i] = d
+	}
+	return op, nil
+}
+
+// Dim returns the dimension of the hamiltonian.
+func (op *IsingOperator) Dim() int { return len(op.diag) }
+
+// Apply stores the product of the hamiltonian and src in dst.
+func (op *IsingOperator) Apply(dst, src *tensor.Dense) *tensor.Dense {
+	if s := src.Shape(); len(s) != 2 || s[0] != op.Dim() || s[1] != 1 {
+		panic(fmt.Sprintf("%v %d", s, op.Dim()))
+	}
+	dst.Reset(op.Dim(), 1)
+	for i, d := range op.diag {
+		v := d * src.At(i, 0)
+		// The transverse field flips the spin of each site.
+		for s, m := range op.masks {
+			v -= op.fields[s] * src.At(i^m, 0)
+		}
+		dst.SetAt([]int{i, 0}, v)
+	}
+	return dst
+}
+
+// MagnetizationOperator is the diagonal linear operator (M/N)^power, where M is the sum of the Z spins and N the number of spins.
+// It is the observable of the thermal averages of the magnetization moments, see linalg.ThermalExpectation.
+type MagnetizationOperator struct {
+	diag []complex64
+}
+
+// NewMagnetizationOperator returns the operator (M/N)^power on the lattice of shape n, in the basis of TransverseFieldIsingDisordered.
+func NewMagnetizationOperator(n [2]int, power int) *MagnetizationOperator {
+	numSpins := n[0] * n[1]
+	op := &MagnetizationOperator{diag: make([]complex64, 1<<numSpins)}
+	for i := range op.diag {
+		m := float64(basis.Magnetization(numSpins, i)) / float64(numSpins)
+		op.diag[i] = complex(float32(math.Pow(m, float64(power))), 0)
+	}
+	return op
+}
+
+// Dim returns the dimension of the operator.
+func (op *MagnetizationOperator) Dim() int { return len(op.diag) }
+
+// Apply stores the product of the operator and src in dst.
+func (op *MagnetizationOperator) Apply(dst, src *tensor.Dense) *tensor.Dense {
+	if s := src.Shape(); len(s) != 2 || s[0] != op.Dim() || s[1] != 1 {
+		panic(fmt.Sprintf("%v %d", s, op.Dim()))
+	}
+	dst.Reset(op.Dim(), 1)
+	for i, d := range op.diag {
+		dst.SetAt([]int{i, 0}, d*src.At(i, 0))
+	}
+	return dst
+}
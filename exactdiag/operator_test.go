@@ -0,0 +1,125 @@
+package exactdiag
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+)
+
+func TestIsingOperator(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n            [2]int
+		periodic     [2]bool
+		longitudinal complex64
+	}{
+		{n: [2]int{2, 1}},
+		{n: [2]int{4, 1}, periodic: [2]bool{true, false}, longitudinal: 0.3},
+		{n: [2]int{3, 2}, periodic: [2]bool{true, true}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			coupling := func(a, b [2]int) complex64 { return complex(1+0.25*float32(a[0])-0.5*float32(b[1]), 0) }
+			field := func(a [2]int) complex64 { return complex(0.5+0.125*float32(a[0]*3+a[1]), 0) }
+			opt := NewIsingOptions().Periodic(test.periodic).LongitudinalField(test.longitudinal)
+			m, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+			TransverseFieldIsingDisordered(m, buf, test.n, coupling, field, opt)
+			want := m.COO()
+
+			op, err := NewIsingOperator(test.n, coupling, field, opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if op.Dim() != want.Rows() {
+				t.Fatalf("%d %d", op.Dim(), want.Rows())
+			}
+			src, dst := tensor.Zeros(op.Dim(), 1), tensor.Zeros(1)
+			for j := range op.Dim() {
+				src.Reset(op.Dim(), 1)
+				src.SetAt([]int{j, 0}, 1)
+				op.Apply(dst, src)
+				for i := range op.Dim() {
+					if v, w := dst.At(i, 0), want.At(i, j); cmplx.Abs(complex128(v-w)) > 1e-5 {
+						t.Fatalf("%d %d %v %v", i, j, v, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIsingOperatorError(t *testing.T) {
+	t.Parallel()
+	coupling := func(a, b [2]int) complex64 { return 1 }
+	field := func(a [2]int) complex64 { return 1 }
+	if _, err := NewIsingOperator([2]int{2, 1}, coupling, field, NewIsingOptions().ParitySector(1)); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestThermalExpectation(t *testing.T) {
+	t.Parallel()
+	n := [2]int{8, 1}
+	h := complex64(1)
+	coupling := func(a, b [2]int) complex64 { return 1 }
+	field := func(a [2]int) complex64 { return h }
+	op, err := NewIsingOperator(n, coupling, field)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	m2 := NewMagnetizationOperator(n, 2)
+	m, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+	TransverseFieldIsing(m, buf, n, h)
+	vvs := m.COO().Eigen()
+
+	betas := []float64{0.1, 1, 4}
+	var bufs [7]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	opt := linalg.NewThermalOptions().NumSamples(16).Rand(rand.New(rand.NewPCG(1, 1)))
+	energy, err := linalg.ThermalExpectation(op, op, betas, bufs, opt)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	magnetization, err := linalg.ThermalExpectation(op, m2, betas, bufs, opt)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	ths, err := linalg.ThermalTraces(op, betas, bufs, opt)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// Compare with the averages over the full spectrum.
+	for b, beta := range betas {
+		var z, e, e2, mm float64
+		for _, vv := range vvs {
+			w := math.Exp(-beta * real(vv.Val-vvs[0].Val))
+			z += w
+			e += w * real(vv.Val)
+			e2 += w * real(vv.Val) * real(vv.Val)
+			for i, c := range vv.Vec {
+				mm += w * float64(real(m2.diag[i])) * real(c*cmplx.Conj(c))
+			}
+		}
+		e, e2, mm = e/z, e2/z, mm/z
+		logZ, c := math.Log(z)-beta*real(vvs[0].Val), beta*beta*(e2-e*e)
+		if th := ths[b]; math.Abs(th.LogZ-logZ) > 0.05*max(1, math.Abs(logZ)) || math.Abs(th.Energy-e) > 0.05*max(math.Abs(e), float64(n[0]*n[1])) || math.Abs(th.SpecificHeat-c) > 0.1*max(1, c) {
+			t.Fatalf("%f %+v %f %f %f", beta, th, logZ, e, c)
+		}
+		if d := cmplx.Abs(energy[b].Value - complex(e, 0)); d > 4*energy[b].Err+1e-3*math.Abs(e) {
+			t.Fatalf("%f %v %f %f", beta, energy[b].Value, e, energy[b].Err)
+		}
+		if d := cmplx.Abs(magnetization[b].Value - complex(mm, 0)); d > 4*magnetization[b].Err+1e-3 {
+			t.Fatalf("%f %v %f %f", beta, magnetization[b].Value, mm, magnetization[b].Err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package exactdiag
+
+import (
+	"math"
+
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/pkg/errors"
+)
+
+// Thermal are the thermal averages of a lattice at an inverse temperature, see ThermalStatistics.
+type Thermal struct {
+	Beta float64
+	// LogZ is the logarithm of the partition function Z = sum_i exp(-beta E_i), which is kept in log space since Z overflows at low temperatures.
+	LogZ float64
+	// Energy is <H>, and SpecificHeat is beta^2 (<H^2> - <H>^2).
+	Energy, SpecificHeat float64
+	// Magnetization is <|M|>/N, where M is the sum of the Z spins and N the number of spins, as in Statistics.
+	Magnetization float64
+	// M2 is <M^2>/N^2, and Susceptibility is beta N (<M^2>/N^2 - <|M|>^2/N^2).
+	M2, Susceptibility float64
+	BinderCumulant     float64
+}
+
+// ThermalStatistics returns the thermal averages at the inverse temperature beta of the lattice of shape n,
+// from the full spectrum vvs of the hamiltonian, such as that returned by mat.COO.Eigen.
+// Each eigenstate i is weighted by exp(-beta E_i) / Z, where E_i is the real part of its eigenvalue.
+// Since the spin flip symmetry makes <M> vanish on a finite lattice, the magnetization is that of |M| as in GetStatistics,
+// and the susceptibility is the corresponding finite size estimator.
+// The cost is that of the full spectrum, which limits the lattice to a few tens of spins.
+// See Section 3.2 Finite-size scaling, K. Binder and D. W. Heermann, Monte Carlo Simulation in Statistical Physics 5th Edition.
+func ThermalStatistics(n [2]int, beta float64, vvs []mat.ValVec) (Thermal, error) {
+	numSpins := n[0] * n[1]
+	dim := 1 << numSpins
+	if len(vvs) != dim {
+		return Thermal{}, errors.Errorf("%d %d", len(vvs), dim)
+	}
+	e0 := math.Inf(1)
+	for _, vv := range vvs {
+		if len(vv.Vec) != dim {
+			return Thermal{}, errors.Errorf("%d %d", len(vv.Vec), dim)
+		}
+		e0 = min(e0, real(vv.Val))
+	}
+
+	// The weights are shifted by the lowest energy e0 to avoid overflows.
+	var z, e, e2, m, m2, m4 float64
+	for _, vv := range vvs {
+		ev := real(vv.Val)
+		w := math.Exp(-beta * (ev - e0))
+		z += w
+		e += w * ev
+		e2 += w * ev * ev
+
+		mag := newMagnetization(numSpins)
+		for i, amplitude := range vv.Vec {
+			mag.add(i, amplitude)
+		}
+		if mag.totalProb == 0 {
+			return Thermal{}, errors.Errorf("zero vector")
+		}
+		m += w * mag.m / mag.totalProb
+		m2 += w * mag.m2 / mag.totalProb
+		m4 += w * mag.m4 / mag.totalProb
+	}
+	e, e2, m, m2, m4 = e/z, e2/z, m/z, m2/z, m4/z
+
+	nf := float64(numSpins)
+	th := Thermal{Beta: beta, LogZ: math.Log(z) - beta*e0, Energy: e}
+	th.SpecificHeat = beta * beta * (e2 - e*e)
+	th.Magnetization = m / nf
+	th.M2 = m2 / (nf * nf)
+	th.Susceptibility = beta * nf * (th.M2 - th.Magnetization*th.Magnetization)
+	th.BinderCumulant = 1 - m4/(m2*m2)/3
+	return th, nil
+}
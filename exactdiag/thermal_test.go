@@ -0,0 +1,114 @@
+package exactdiag
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag/basis"
+	"github.com/fumin/qising/exactdiag/mat"
+)
+
+func TestThermalStatistics(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n    [2]int
+		h    complex64
+		beta float64
+	}{
+		{n: [2]int{6, 1}, h: 0, beta: 0},
+		{n: [2]int{6, 1}, h: 0, beta: 0.7},
+		{n: [2]int{3, 2}, h: 0, beta: 2},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			m, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+			TransverseFieldIsing(m, buf, test.n, test.h)
+			th, err := ThermalStatistics(test.n, test.beta, m.COO().Eigen())
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Without the transverse field, the hamiltonian is diagonal, and the averages are the classical sums over the basis states.
+			numSpins := test.n[0] * test.n[1]
+			upState := make([]int8, numSpins)
+			var z, e, e2, absM, m2, m4 float64
+			for i, state := range basis.States(numSpins) {
+				ev := float64(real(m.COO().At(i, i)))
+				w := math.Exp(-test.beta * ev)
+				basis.MajorityUp(upState, state)
+				var mag float64
+				for _, s := range upState {
+					mag += float64(s)
+				}
+				z += w
+				e += w * ev
+				e2 += w * ev * ev
+				absM += w * mag
+				m2 += w * mag * mag
+				m4 += w * math.Pow(mag, 4)
+			}
+			e, e2, absM, m2, m4 = e/z, e2/z, absM/z, m2/z, m4/z
+			nf := float64(numSpins)
+			want := Thermal{Beta: test.beta, LogZ: math.Log(z), Energy: e, SpecificHeat: test.beta * test.beta * (e2 - e*e)}
+			want.Magnetization, want.M2 = absM/nf, m2/(nf*nf)
+			want.Susceptibility = test.beta * nf * (want.M2 - want.Magnetization*want.Magnetization)
+			want.BinderCumulant = 1 - m4/(m2*m2)/3
+
+			got := []float64{th.LogZ, th.Energy, th.SpecificHeat, th.Magnetization, th.M2, th.Susceptibility, th.BinderCumulant}
+			wants := []float64{want.LogZ, want.Energy, want.SpecificHeat, want.Magnetization, want.M2, want.Susceptibility, want.BinderCumulant}
+			for j := range got {
+				if math.Abs(got[j]-wants[j]) > 1e-6*max(1, math.Abs(wants[j])) {
+					t.Fatalf("%d %+v %+v", j, th, want)
+				}
+			}
+		})
+	}
+}
+
+func TestThermalStatisticsQuantum(t *testing.T) {
+	t.Parallel()
+	n, h := [2]int{6, 1}, complex64(2)
+	m, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+	TransverseFieldIsing(m, buf, n, h)
+	vvs := m.COO().Eigen()
+
+	// The energy and the specific heat are the derivatives -d ln Z/d beta and -beta^2 dE/d beta.
+	beta, db := 0.8, 1e-4
+	th, err := ThermalStatistics(n, beta, vvs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	lo, err := ThermalStatistics(n, beta-db, vvs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	hi, err := ThermalStatistics(n, beta+db, vvs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if e := -(hi.LogZ - lo.LogZ) / (2 * db); math.Abs(th.Energy-e) > 1e-4*math.Abs(e) {
+		t.Fatalf("%f %f", th.Energy, e)
+	}
+	if c := -beta * beta * (hi.Energy - lo.Energy) / (2 * db); math.Abs(th.SpecificHeat-c) > 1e-4*c {
+		t.Fatalf("%f %f", th.SpecificHeat, c)
+	}
+
+	// At low temperatures, the averages are those of the ground state.
+	cold, err := ThermalStatistics(n, 50, vvs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	ground, err := GetStatistics(n, vvs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if math.Abs(cold.Energy-ground.EigenValue[0]) > 1e-6 || math.Abs(cold.Magnetization-ground.Magnetization) > 1e-6 || math.Abs(cold.M2-ground.M2) > 1e-6 || cold.SpecificHeat > 1e-6 {
+		t.Fatalf("%+v %+v", cold, ground)
+	}
+
+	if _, err := ThermalStatistics(n, beta, vvs[:1]); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,24 @@
+package qising_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/fumin/qising"
+)
+
+func Example() {
+	// An Ising chain of 8 spins at the critical transverse field.
+	model := qising.ModelSpec{N: [2]int{8, 1}, H: 1}
+	for _, method := range []qising.Method{qising.ExactDiag, qising.MPS} {
+		obs, err := qising.Solve(model, method, qising.NewSolveOptions().Seed(1))
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		fmt.Printf("%s: ground energy %.3f\n", method, real(obs.Energies[0]))
+	}
+
+	// Output:
+	// exactdiag: ground energy -9.838
+	// mps: ground energy -9.838
+}
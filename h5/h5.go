@@ -0,0 +1,470 @@
+// Package h5 writes HDF5 files, which are self-describing and read in Python by h5py.
+// Only what is needed to store results is implemented: a tree of groups holding contiguous datasets and attributes of numbers and strings.
+// Files are written with the version 0 superblock, version 1 object headers and symbol table groups, the earliest and most widely readable layout.
+//
+// References:
+//   - HDF5 File Format Specification Version 2.0, https://docs.hdfgroup.org/hdf5/develop/_f_m_t2.html
+package h5
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	signature = "\x89HDF\r\n\x1a\n"
+	// undefined is the undefined address.
+	undefined = ^uint64(0)
+	// freeNull is the offset of the end of the free list of a local heap.
+	freeNull = 1
+
+	superblockSize = 96
+	entrySize      = 40
+	// internalK is half the maximum number of children of a B-tree node, and the libhdf5 default.
+	internalK = 16
+	// minLeafK is the libhdf5 default of half the maximum number of entries of a symbol table node.
+	minLeafK = 4
+
+	msgDataspace   = 0x01
+	msgDatatype    = 0x03
+	msgFillValue   = 0x05
+	msgLayout      = 0x08
+	msgAttribute   = 0x0C
+	msgSymbolTable = 0x11
+)
+
+// A Group is a named collection of datasets, attributes and subgroups.
+type Group struct {
+	attrs    []attribute
+	children map[string]any
+}
+
+// NewGroup returns an empty group, which becomes the root group of a file when written by WriteTo.
+func NewGroup() *Group {
+	return &Group{children: make(map[string]any)}
+}
+
+type attribute struct {
+	name string
+	value
+}
+
+type dataset struct {
+	value
+}
+
+// Group returns the subgroup name, creating it if it does not exist.
+// It panics if name is a dataset.
+func (g *Group) Group(name string) *Group {
+	switch c := g.children[name].(type) {
+	case *Group:
+		return c
+	case nil:
+		sub := NewGroup()
+		g.children[name] = sub
+		return sub
+	default:
+		panic(fmt.Sprintf("%s %T", name, c))
+	}
+}
+
+// SetAttr sets the attribute name to the scalar v, which is an int, int64, float32, float64, complex64, complex128 or string.
+func (g *Group) SetAttr(name string, v any) error {
+	val, err := newValue(v, nil)
+	if err != nil {
+		return errors.Wrap(err, name)
+	}
+	if val.shape != nil {
+		return errors.Errorf("%s %T", name, v)
+	}
+	g.attrs = slices.DeleteFunc(g.attrs, func(a attribute) bool { return a.name == name })
+	g.attrs = append(g.attrs, attribute{name: name, value: val})
+	return nil
+}
+
+// SetDataset sets the dataset name to v, which is a scalar or a slice of int, int64, float32, float64, complex64 or complex128.
+// A slice is stored in row major order with the given shape, which defaults to its length.
+// Complex numbers are stored as compounds of the fields r and i, which h5py reads as numpy complex numbers.
+func (g *Group) SetDataset(name string, v any, shape ...int) error {
+	if _, ok := g.children[name].(*Group); ok {
+		return errors.Errorf("%s is a group", name)
+	}
+	val, err := newValue(v, shape)
+	if err != nil {
+		return errors.Wrap(err, name)
+	}
+	g.children[name] = &dataset{value: val}
+	return nil
+}
+
+// WriteTo writes g as the root group of an HDF5 file to w.
+func (g *Group) WriteTo(w io.Writer) (int64, error) {
+	e := &encoder{b: make([]byte, superblockSize), leafK: max(minLeafK, (g.maxChildren()+1)/2)}
+	root := e.group(g)
+
+	b := e.b[:0]
+	b = append(b, signature...)
+	// Versions of the superblock, free space storage, root group symbol table entry, reserved, and shared header message format.
+	b = append(b, 0, 0, 0, 0, 0)
+	// Size of offsets and lengths, and reserved.
+	b = append(b, 8, 8, 0)
+	b = binary.LittleEndian.AppendUint16(b, uint16(e.leafK))
+	b = binary.LittleEndian.AppendUint16(b, internalK)
+	// File consistency flags.
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	// Base address, free space info address, end of file address and driver information block address.
+	b = binary.LittleEndian.AppendUint64(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, undefined)
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(e.b)))
+	b = binary.LittleEndian.AppendUint64(b, undefined)
+	b = root.append(b)
+	if len(b) != superblockSize {
+		panic(fmt.Sprintf("%d", len(b)))
+	}
+
+	n, err := w.Write(e.b)
+	if err != nil {
+		return int64(n), errors.Wrap(err, "")
+	}
+	return int64(n), nil
+}
+
+// WriteFile writes g as the root group of the HDF5 file fpath.
+func WriteFile(fpath string, g *Group) error {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	bw := bufio.NewWriter(f)
+	_, err = g.WriteTo(bw)
+	if err1 := bw.Flush(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// maxChildren returns the maximum number of children of g and its subgroups, which determines the size of symbol table nodes.
+func (g *Group) maxChildren() int {
+	n := len(g.children)
+	for _, c := range g.children {
+		if sub, ok := c.(*Group); ok {
+			n = max(n, sub.maxChildren())
+		}
+	}
+	return n
+}
+
+// A value is an encoded datatype, the dimensions of its dataspace, nil for scalars, and its little endian data.
+type value struct {
+	datatype []byte
+	shape    []int
+	data     []byte
+}
+
+func newValue(v any, shape []int) (value, error) {
+	var val value
+	length := -1
+	switch x := v.(type) {
+	case int:
+		val.datatype, val.data = int64Type(), binary.LittleEndian.AppendUint64(nil, uint64(x))
+	case int64:
+		val.datatype, val.data = int64Type(), binary.LittleEndian.AppendUint64(nil, uint64(x))
+	case float32, float64, complex64, complex128:
+		val.datatype = floatType(x)
+		val.data, _ = binary.Append(nil, binary.LittleEndian, x)
+	case string:
+		// The string is null terminated, so that the empty string has a positive size.
+		val.datatype, val.data = stringType(len(x)+1), append([]byte(x), 0)
+	case []int:
+		length, val.datatype = len(x), int64Type()
+		for _, xi := range x {
+			val.data = binary.LittleEndian.AppendUint64(val.data, uint64(xi))
+		}
+	case []int64:
+		length, val.datatype = len(x), int64Type()
+		val.data, _ = binary.Append(nil, binary.LittleEndian, x)
+	case []float32:
+		length, val.datatype = len(x), floatType(float32(0))
+		val.data, _ = binary.Append(nil, binary.LittleEndian, x)
+	case []float64:
+		length, val.datatype = len(x), floatType(float64(0))
+		val.data, _ = binary.Append(nil, binary.LittleEndian, x)
+	case []complex64:
+		length, val.datatype = len(x), floatType(complex64(0))
+		val.data, _ = binary.Append(nil, binary.LittleEndian, x)
+	case []complex128:
+		length, val.datatype = len(x), floatType(complex128(0))
+		val.data, _ = binary.Append(nil, binary.LittleEndian, x)
+	default:
+		return value{}, errors.Errorf("%T", v)
+	}
+
+	switch {
+	case length < 0 && len(shape) > 0:
+		return value{}, errors.Errorf("%T %v", v, shape)
+	case length >= 0 && len(shape) == 0:
+		val.shape = []int{length}
+	case length >= 0:
+		size := 1
+		for _, d := range shape {
+			size *= d
+		}
+		if size != length {
+			return value{}, errors.Errorf("%v %d", shape, length)
+		}
+		val.shape = slices.Clone(shape)
+	}
+	return val, nil
+}
+
+// int64Type returns the little endian signed 64 bit fixed point datatype.
+func int64Type() []byte {
+	b := []byte{0x10, 0x08, 0, 0}
+	b = binary.LittleEndian.AppendUint32(b, 8)
+	// Bit offset and precision.
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, 64)
+	return b
+}
+
+// floatType returns the IEEE 754 little endian datatype of x, and for complex numbers a compound of the real part r and the imaginary part i.
+func floatType(x any) []byte {
+	var size, expLoc, expSize, mantSize, bias int
+	switch x.(type) {
+	case float32:
+		size, expLoc, expSize, mantSize, bias = 4, 23, 8, 23, 127
+	case float64:
+		size, expLoc, expSize, mantSize, bias = 8, 52, 11, 52, 1023
+	case complex64:
+		return complexType(floatType(float32(0)), 4)
+	case complex128:
+		return complexType(floatType(float64(0)), 8)
+	default:
+		panic(fmt.Sprintf("%T", x))
+	}
+	// The mantissa is normalized with an implied most significant bit, and the sign bit is the most significant.
+	b := []byte{0x11, 0x20, byte(8*size - 1), 0}
+	b = binary.LittleEndian.AppendUint32(b, uint32(size))
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	b = binary.LittleEndian.AppendUint16(b, uint16(8*size))
+	b = append(b, byte(expLoc), byte(expSize), 0, byte(mantSize))
+	b = binary.LittleEndian.AppendUint32(b, uint32(bias))
+	return b
+}
+
+// complexType returns the version 1 compound datatype of the members r and i, whose datatype is part of the given size.
+func complexType(part []byte, size int) []byte {
+	b := []byte{0x16, 2, 0, 0}
+	b = binary.LittleEndian.AppendUint32(b, uint32(2*size))
+	for i, name := range []string{"r", "i"} {
+		b = append(b, pad8([]byte(name+"\x00"))...)
+		b = binary.LittleEndian.AppendUint32(b, uint32(i*size))
+		// Dimensionality, reserved, dimension permutation, reserved, and the four dimension sizes.
+		b = append(b, make([]byte, 1+3+4+4+4*4)...)
+		b = append(b, part...)
+	}
+	return b
+}
+
+// stringType returns the null terminated UTF-8 string datatype of the given size.
+func stringType(size int) []byte {
+	b := []byte{0x13, 0x10, 0, 0}
+	return binary.LittleEndian.AppendUint32(b, uint32(size))
+}
+
+// dataspace returns the version 1 dataspace message of shape, which is scalar if shape is nil.
+func dataspace(shape []int) []byte {
+	b := []byte{1, byte(len(shape)), 0, 0, 0, 0, 0, 0}
+	for _, d := range shape {
+		b = binary.LittleEndian.AppendUint64(b, uint64(d))
+	}
+	return b
+}
+
+func (a attribute) message() []byte {
+	name := []byte(a.name + "\x00")
+	space := dataspace(a.shape)
+	b := []byte{1, 0}
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(name)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(a.datatype)))
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(space)))
+	b = append(b, pad8(name)...)
+	b = append(b, pad8(a.datatype)...)
+	b = append(b, pad8(space)...)
+	return append(b, a.data...)
+}
+
+type message struct {
+	typ  uint16
+	data []byte
+}
+
+func attributeMessages(attrs []attribute) []message {
+	msgs := make([]message, 0, len(attrs))
+	for _, a := range attrs {
+		msgs = append(msgs, message{typ: msgAttribute, data: a.message()})
+	}
+	return msgs
+}
+
+// An entry is a symbol table entry.
+// Groups cache the addresses of their B-tree and local heap in the scratch pad.
+type entry struct {
+	name       string
+	nameOffset uint64
+	header     uint64
+	group      bool
+	btree      uint64
+	heap       uint64
+}
+
+func (en entry) append(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint64(b, en.nameOffset)
+	b = binary.LittleEndian.AppendUint64(b, en.header)
+	if !en.group {
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		b = binary.LittleEndian.AppendUint32(b, 0)
+		return append(b, make([]byte, 16)...)
+	}
+	b = binary.LittleEndian.AppendUint32(b, 1)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint64(b, en.btree)
+	return binary.LittleEndian.AppendUint64(b, en.heap)
+}
+
+// An encoder appends the objects of a file to b, each after its children, so that addresses are known when an object is written.
+type encoder struct {
+	b     []byte
+	leafK int
+}
+
+// put appends the 8 byte aligned b to the file, and returns its address.
+func (e *encoder) put(b []byte) uint64 {
+	e.b = pad8(e.b)
+	addr := uint64(len(e.b))
+	e.b = append(e.b, b...)
+	return addr
+}
+
+// objectHeader writes the version 1 object header of msgs, and returns its address.
+func (e *encoder) objectHeader(msgs []message) uint64 {
+	var body []byte
+	for _, m := range msgs {
+		data := pad8(m.data)
+		body = binary.LittleEndian.AppendUint16(body, m.typ)
+		body = binary.LittleEndian.AppendUint16(body, uint16(len(data)))
+		// Flags and reserved.
+		body = append(body, 0, 0, 0, 0)
+		body = append(body, data...)
+	}
+	b := []byte{1, 0}
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(msgs)))
+	// Reference count, header size and padding.
+	b = binary.LittleEndian.AppendUint32(b, 1)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(body)))
+	b = append(b, 0, 0, 0, 0)
+	return e.put(append(b, body...))
+}
+
+func (e *encoder) dataset(d *dataset) entry {
+	addr := undefined
+	if len(d.data) > 0 {
+		addr = e.put(d.data)
+	}
+	// Contiguous layout.
+	layout := []byte{3, 1}
+	layout = binary.LittleEndian.AppendUint64(layout, addr)
+	layout = binary.LittleEndian.AppendUint64(layout, uint64(len(d.data)))
+	msgs := []message{
+		{typ: msgDataspace, data: dataspace(d.shape)},
+		{typ: msgDatatype, data: d.datatype},
+		// Version 2 fill value, allocated early, never written, and undefined.
+		{typ: msgFillValue, data: []byte{2, 1, 1, 0}},
+		{typ: msgLayout, data: layout},
+	}
+	return entry{header: e.objectHeader(msgs)}
+}
+
+func (e *encoder) group(g *Group) entry {
+	names := make([]string, 0, len(g.children))
+	for name := range g.children {
+		names = append(names, name)
+	}
+	// Symbol table nodes are sorted by name.
+	slices.Sort(names)
+	entries := make([]entry, 0, len(names))
+	for _, name := range names {
+		var en entry
+		switch c := g.children[name].(type) {
+		case *Group:
+			en = e.group(c)
+		case *dataset:
+			en = e.dataset(c)
+		}
+		en.name = name
+		entries = append(entries, en)
+	}
+
+	// The local heap holds the names, after the empty string at offset 0.
+	heapData := make([]byte, 8)
+	for i, en := range entries {
+		entries[i].nameOffset = uint64(len(heapData))
+		heapData = append(heapData, pad8([]byte(en.name+"\x00"))...)
+	}
+	heap := []byte("HEAP\x00\x00\x00\x00")
+	heap = binary.LittleEndian.AppendUint64(heap, uint64(len(heapData)))
+	heap = binary.LittleEndian.AppendUint64(heap, freeNull)
+	// The data segment follows the 32 byte header.
+	e.b = pad8(e.b)
+	heapAddr := uint64(len(e.b))
+	heap = binary.LittleEndian.AppendUint64(heap, heapAddr+32)
+	e.put(append(heap, heapData...))
+
+	// A B-tree of a single leaf pointing to a symbol table node, or of no children for an empty group.
+	btree := []byte("TREE")
+	// Group node type and level.
+	btree = append(btree, 0, 0)
+	if len(entries) == 0 {
+		btree = binary.LittleEndian.AppendUint16(btree, 0)
+	} else {
+		btree = binary.LittleEndian.AppendUint16(btree, 1)
+	}
+	btree = binary.LittleEndian.AppendUint64(btree, undefined)
+	btree = binary.LittleEndian.AppendUint64(btree, undefined)
+	if len(entries) > 0 {
+		snod := []byte("SNOD\x01\x00")
+		snod = binary.LittleEndian.AppendUint16(snod, uint16(len(entries)))
+		for _, en := range entries {
+			snod = en.append(snod)
+		}
+		snod = append(snod, make([]byte, (2*e.leafK-len(entries))*entrySize)...)
+		snodAddr := e.put(snod)
+
+		btree = binary.LittleEndian.AppendUint64(btree, 0)
+		btree = binary.LittleEndian.AppendUint64(btree, snodAddr)
+		btree = binary.LittleEndian.AppendUint64(btree, entries[len(entries)-1].nameOffset)
+	}
+	// Nodes are read at their full size.
+	btreeSize := 24 + 2*internalK*8 + (2*internalK+1)*8
+	btree = append(btree, make([]byte, btreeSize-len(btree))...)
+	btreeAddr := e.put(btree)
+
+	symbolTable := binary.LittleEndian.AppendUint64(nil, btreeAddr)
+	symbolTable = binary.LittleEndian.AppendUint64(symbolTable, heapAddr)
+	msgs := append([]message{{typ: msgSymbolTable, data: symbolTable}}, attributeMessages(g.attrs)...)
+	return entry{header: e.objectHeader(msgs), group: true, btree: btreeAddr, heap: heapAddr}
+}
+
+// pad8 pads b with zeros to a multiple of 8 bytes.
+func pad8(b []byte) []byte {
+	return append(b, make([]byte, (8-len(b)%8)%8)...)
+}
@@ -0,0 +1,371 @@
+package h5
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	t.Parallel()
+	g := NewGroup()
+	if err := g.SetAttr("title", "ising"); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := g.SetDataset("x", []float64{1, -2.5, 3}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	sub := g.Group("4x4").Group("0.5")
+	for _, kv := range []struct {
+		name string
+		v    any
+	}{
+		{name: "n", v: 16},
+		{name: "h", v: float32(0.5)},
+		{name: "lambda", v: complex128(1i)},
+		{name: "empty", v: ""},
+	} {
+		if err := sub.SetAttr(kv.name, kv.v); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+	if err := sub.SetDataset("vecs", []complex64{1, 2i, 3, 4, 5, -6i}, 3, 2); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := sub.SetDataset("m", 0.25); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := sub.SetDataset("none", []int{}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	g.Group("empty")
+	// Enough children to need a symbol table node larger than the default.
+	many := g.Group("many")
+	for i := range 11 {
+		if err := many.SetDataset(fmt.Sprintf("%02d", i), int64(i)); err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+
+	var b bytes.Buffer
+	n, err := g.WriteTo(&b)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if n != int64(b.Len()) {
+		t.Fatalf("%d %d", n, b.Len())
+	}
+	objs := readFile(t, b.Bytes())
+
+	paths := make([]string, 0, len(objs))
+	for p := range objs {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	wantPaths := []string{"/", "/4x4", "/4x4/0.5", "/4x4/0.5/m", "/4x4/0.5/none", "/4x4/0.5/vecs", "/empty", "/many", "/x"}
+	for i := range 11 {
+		wantPaths = append(wantPaths, fmt.Sprintf("/many/%02d", i))
+	}
+	slices.Sort(wantPaths)
+	if !slices.Equal(paths, wantPaths) {
+		t.Fatalf("%v", paths)
+	}
+
+	checkValue(t, objs["/"].attrs["title"], "ising", nil)
+	checkValue(t, objs["/x"].value, []float64{1, -2.5, 3}, []int{3})
+	checkValue(t, objs["/4x4/0.5"].attrs["n"], int64(16), nil)
+	checkValue(t, objs["/4x4/0.5"].attrs["h"], float32(0.5), nil)
+	checkValue(t, objs["/4x4/0.5"].attrs["lambda"], complex128(1i), nil)
+	checkValue(t, objs["/4x4/0.5"].attrs["empty"], "", nil)
+	checkValue(t, objs["/4x4/0.5/vecs"].value, []complex64{1, 2i, 3, 4, 5, -6i}, []int{3, 2})
+	checkValue(t, objs["/4x4/0.5/m"].value, 0.25, nil)
+	checkValue(t, objs["/4x4/0.5/none"].value, []int64{}, []int{0})
+	checkValue(t, objs["/many/07"].value, int64(7), nil)
+}
+
+func TestSetDatasetError(t *testing.T) {
+	t.Parallel()
+	g := NewGroup()
+	g.Group("a")
+	tests := []struct {
+		name  string
+		v     any
+		shape []int
+	}{
+		{name: "a", v: 1},
+		{name: "b", v: []float64{1, 2, 3}, shape: []int{2, 2}},
+		{name: "c", v: 1.0, shape: []int{1}},
+		{name: "d", v: "s", shape: []int{1}},
+		{name: "e", v: []bool{true}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if err := g.SetDataset(test.name, test.v, test.shape...); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+	if err := g.SetAttr("x", []float64{1}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := NewGroup()
+	if err := g.SetDataset("e", []float64{-1, 0, 1}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	fpath := filepath.Join(dir, "a.h5")
+	if err := WriteFile(fpath, g); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	b, err := os.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	checkValue(t, readFile(t, b)["/e"].value, []float64{-1, 0, 1}, []int{3})
+}
+
+type object struct {
+	value value
+	attrs map[string]value
+}
+
+// readFile reads the objects of an HDF5 file by their paths, following the specification independently of the writer.
+func readFile(t *testing.T, b []byte) map[string]object {
+	if !bytes.HasPrefix(b, []byte(signature)) {
+		t.Fatalf("%q", b[:min(len(b), 8)])
+	}
+	if b[13] != 8 || b[14] != 8 {
+		t.Fatalf("%v", b[:16])
+	}
+	leafK := int(binary.LittleEndian.Uint16(b[16:]))
+	btreeK := int(binary.LittleEndian.Uint16(b[18:]))
+	if eof := binary.LittleEndian.Uint64(b[40:]); eof != uint64(len(b)) {
+		t.Fatalf("%d %d", eof, len(b))
+	}
+	r := &reader{t: t, b: b, leafK: leafK, btreeK: btreeK, objs: make(map[string]object)}
+	r.object("/", binary.LittleEndian.Uint64(b[56+8:]))
+	return r.objs
+}
+
+type reader struct {
+	t             *testing.T
+	b             []byte
+	leafK, btreeK int
+	objs          map[string]object
+}
+
+func (r *reader) u16(addr uint64) int    { return int(binary.LittleEndian.Uint16(r.b[addr:])) }
+func (r *reader) u32(addr uint64) int    { return int(binary.LittleEndian.Uint32(r.b[addr:])) }
+func (r *reader) u64(addr uint64) uint64 { return binary.LittleEndian.Uint64(r.b[addr:]) }
+
+func (r *reader) object(path string, addr uint64) {
+	t := r.t
+	if r.b[addr] != 1 {
+		t.Fatalf("%s %d", path, r.b[addr])
+	}
+	nmsgs, size := r.u16(addr+2), r.u32(addr+8)
+	obj := object{attrs: make(map[string]value)}
+	var layoutAddr, layoutSize uint64
+	var btree, heap uint64
+	p, end := addr+16, addr+16+uint64(size)
+	for range nmsgs {
+		typ, msize := r.u16(p), r.u16(p+2)
+		if msize%8 != 0 {
+			t.Fatalf("%s %d %d", path, typ, msize)
+		}
+		data := r.b[p+8 : p+8+uint64(msize)]
+		switch typ {
+		case msgDataspace:
+			obj.value.shape = readDataspace(t, data)
+		case msgDatatype:
+			obj.value.datatype = data
+		case msgLayout:
+			if data[0] != 3 || data[1] != 1 {
+				t.Fatalf("%s %v", path, data)
+			}
+			layoutAddr, layoutSize = binary.LittleEndian.Uint64(data[2:]), binary.LittleEndian.Uint64(data[10:])
+		case msgAttribute:
+			nameSize, typeSize, spaceSize := int(binary.LittleEndian.Uint16(data[2:])), int(binary.LittleEndian.Uint16(data[4:])), int(binary.LittleEndian.Uint16(data[6:]))
+			q := 8
+			name := string(data[q : q+nameSize-1])
+			q += (nameSize + 7) / 8 * 8
+			a := value{datatype: data[q : q+typeSize]}
+			q += (typeSize + 7) / 8 * 8
+			a.shape = readDataspace(t, data[q:q+spaceSize])
+			q += (spaceSize + 7) / 8 * 8
+			// The data is followed by the padding of the message.
+			count := 1
+			for _, d := range a.shape {
+				count *= d
+			}
+			a.data = data[q : q+count*int(binary.LittleEndian.Uint32(a.datatype[4:]))]
+			obj.attrs[name] = a
+		case msgSymbolTable:
+			btree, heap = binary.LittleEndian.Uint64(data), binary.LittleEndian.Uint64(data[8:])
+		}
+		p += 8 + uint64(msize)
+	}
+	if p != end {
+		t.Fatalf("%s %d %d", path, p, end)
+	}
+	if obj.value.datatype != nil {
+		if layoutAddr != undefined {
+			obj.value.data = r.b[layoutAddr : layoutAddr+layoutSize]
+		}
+		r.objs[path] = obj
+		return
+	}
+	r.objs[path] = obj
+	r.group(path, btree, heap)
+}
+
+func (r *reader) group(path string, btree, heap uint64) {
+	t := r.t
+	if string(r.b[heap:heap+4]) != "HEAP" || string(r.b[btree:btree+4]) != "TREE" {
+		t.Fatalf("%s %q %q", path, r.b[heap:heap+4], r.b[btree:btree+4])
+	}
+	heapSize, heapData := r.u64(heap+8), r.u64(heap+24)
+	if free := r.u64(heap + 16); free != freeNull {
+		t.Fatalf("%s %d", path, free)
+	}
+	name := func(offset uint64) string {
+		if offset >= heapSize {
+			t.Fatalf("%s %d %d", path, offset, heapSize)
+		}
+		s := r.b[heapData+offset:]
+		return string(s[:bytes.IndexByte(s, 0)])
+	}
+
+	if r.b[btree+5] != 0 {
+		t.Fatalf("%s level %d", path, r.b[btree+5])
+	}
+	entriesUsed := r.u16(btree + 6)
+	if entriesUsed > 2*r.btreeK {
+		t.Fatalf("%s %d", path, entriesUsed)
+	}
+	if end := btree + uint64(24+2*r.btreeK*8+(2*r.btreeK+1)*8); end > uint64(len(r.b)) {
+		t.Fatalf("%s %d", path, end)
+	}
+	for i := range entriesUsed {
+		keyAddr := btree + 24 + uint64(16*i)
+		snod := r.u64(keyAddr + 8)
+		if string(r.b[snod:snod+4]) != "SNOD" {
+			t.Fatalf("%s %q", path, r.b[snod:snod+4])
+		}
+		if end := snod + 8 + uint64(2*r.leafK*entrySize); end > uint64(len(r.b)) {
+			t.Fatalf("%s %d", path, end)
+		}
+		nsyms := r.u16(snod + 6)
+		prev := ""
+		for j := range nsyms {
+			en := snod + 8 + uint64(j*entrySize)
+			child := name(r.u64(en))
+			if j > 0 && child <= prev {
+				t.Fatalf("%s %q %q", path, prev, child)
+			}
+			prev = child
+			r.object(filepath.Join(path, child), r.u64(en+8))
+		}
+		// The key after a child is its largest name.
+		if last := name(r.u64(keyAddr + 16)); last != prev {
+			t.Fatalf("%s %q %q", path, last, prev)
+		}
+	}
+}
+
+func readDataspace(t *testing.T, b []byte) []int {
+	if b[0] != 1 || b[2] != 0 {
+		t.Fatalf("%v", b)
+	}
+	if b[1] == 0 {
+		return nil
+	}
+	shape := make([]int, 0, b[1])
+	for i := range int(b[1]) {
+		shape = append(shape, int(binary.LittleEndian.Uint64(b[8+8*i:])))
+	}
+	return shape
+}
+
+// checkValue checks the datatype, shape and data of val against the scalar or slice want.
+func checkValue(t *testing.T, val value, want any, shape []int) {
+	t.Helper()
+	if !slices.Equal(val.shape, shape) {
+		t.Fatalf("%v %v", val.shape, shape)
+	}
+	var datatype []byte
+	switch w := want.(type) {
+	case string:
+		if val.datatype[0] != 0x13 || int(binary.LittleEndian.Uint32(val.datatype[4:])) != len(w)+1 {
+			t.Fatalf("%v", val.datatype)
+		}
+		if string(val.data) != w+"\x00" {
+			t.Fatalf("%q %q", val.data, w)
+		}
+		return
+	case int64, []int64:
+		datatype = []byte{0x10, 0x08, 0, 0, 8, 0, 0, 0, 0, 0, 64, 0}
+	case float32:
+		datatype = float32Type
+	case float64, []float64:
+		datatype = float64Type
+	case complex64, []complex64:
+		datatype = compoundType(float32Type, 4)
+	case complex128, []complex128:
+		datatype = compoundType(float64Type, 8)
+	default:
+		t.Fatalf("%T", want)
+	}
+	// Message data is padded to 8 bytes.
+	if !slices.Equal(val.datatype[:len(datatype)], datatype) || slices.ContainsFunc(val.datatype[len(datatype):], func(x byte) bool { return x != 0 }) {
+		t.Fatalf("%v %v", val.datatype, datatype)
+	}
+	var data bytes.Buffer
+	if err := binary.Write(&data, binary.LittleEndian, want); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !slices.Equal(val.data, data.Bytes()) {
+		t.Fatalf("%v %v", val.data, data.Bytes())
+	}
+}
+
+var (
+	float32Type = []byte{0x11, 0x20, 31, 0, 4, 0, 0, 0, 0, 0, 32, 0, 23, 8, 0, 23, 127, 0, 0, 0}
+	float64Type = []byte{0x11, 0x20, 63, 0, 8, 0, 0, 0, 0, 0, 64, 0, 52, 11, 0, 52, 0xff, 0x03, 0, 0}
+)
+
+func compoundType(part []byte, size int) []byte {
+	b := []byte{0x16, 2, 0, 0, byte(2 * size), 0, 0, 0}
+	for i, name := range []string{"r", "i"} {
+		b = append(b, name[0], 0, 0, 0, 0, 0, 0, 0, byte(i*size), 0, 0, 0)
+		b = append(b, make([]byte, 28)...)
+		b = append(b, part...)
+	}
+	return b
+}
+
+func TestFloatType(t *testing.T) {
+	t.Parallel()
+	// The exponent bias and field sizes describe the IEEE 754 formats of math.
+	if math.Float64bits(1) != uint64(1023)<<52 || math.Float32bits(1) != uint32(127)<<23 {
+		t.Fatalf("%x %x", math.Float64bits(1), math.Float32bits(1))
+	}
+	if !slices.Equal(floatType(float32(0)), float32Type) || !slices.Equal(floatType(float64(0)), float64Type) {
+		t.Fatalf("%v %v", floatType(float32(0)), floatType(float64(0)))
+	}
+}
@@ -0,0 +1,527 @@
+// Package linalg implements eigenvalue solvers, matrix functions and planned contractions on top of the dense tensors of github.com/fumin/tensor.
+//
+// The solvers are functions instead of objects, and keep their state only in the buffers, operators and profiles passed by the caller,
+// hence they are re-entrant, and concurrent calls are safe as long as they share none of these.
+// Buffers shared between goroutines, which are overwritten by every call, silently corrupt the results,
+// so goroutines take their buffers from a pool such as github.com/fumin/qising/pool instead.
+// The types that carry scratch space, ContractionPlan and ArnoldiProfile, are not safe for concurrent use either.
+package linalg
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/fumin/qising/debug"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Machine precision of float32.
+	epsilon = 0x1p-23
+)
+
+// ErrInterrupted is the cause of the error returned by a solver that is stopped by the closing of its interrupt channel, such as that of ArnoldiOptions.Interrupt.
+var ErrInterrupted = errors.New("interrupted")
+
+// ArnoldiOptions are options for the Arnoldi iteration.
+type ArnoldiOptions struct {
+	krylovSpaceDim int
+	maxIterations  int
+	tol            float32
+	shiftInvert    bool
+	shift          complex64
+	profile        *ArnoldiProfile
+	fixPhase       bool
+	initial        *tensor.Dense
+	rand           *rand.Rand
+	interrupt      <-chan struct{}
+
+	// lanczos indicates that the operator is Hermitian, and the Krylov basis is built with the Lanczos recurrence.
+	lanczos bool
+}
+
+// ArnoldiProfile is a breakdown of the time spent in the Arnoldi iteration.
+// It is not safe for concurrent use, hence concurrent iterations record their own profiles, which are summed with Add afterwards.
+type ArnoldiProfile struct {
+	// Apply is the time spent applying the operator, and Applications the number of applications.
+	Apply        time.Duration
+	Applications int
+	// Orthogonalize is the time spent orthogonalizing the Krylov basis.
+	Orthogonalize time.Duration
+	// Restart is the time spent computing the Ritz pairs and restarting the Krylov-Schur decomposition.
+	Restart time.Duration
+}
+
+// Add adds the times in q to p.
+func (p *ArnoldiProfile) Add(q ArnoldiProfile) {
+	p.Apply += q.Apply
+	p.Applications += q.Applications
+	p.Orthogonalize += q.Orthogonalize
+	p.Restart += q.Restart
+}
+
+// clock returns the current time, or the zero time if p is nil in which case profiling is disabled.
+func (p *ArnoldiProfile) clock() time.Time {
+	if p == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// NewArnoldiOptions returns the default Arnoldi options.
+func NewArnoldiOptions() ArnoldiOptions {
+	opt := ArnoldiOptions{}
+	opt.maxIterations = 64
+	opt.tol = 2 * epsilon
+	return opt
+}
+
+// KrylovSpaceDim sets the dimension of the Krylov subspace, which defaults to max(2*k+1, 20).
+func (opt ArnoldiOptions) KrylovSpaceDim(n int) ArnoldiOptions {
+	opt.krylovSpaceDim = n
+	return opt
+}
+
+// MaxIterations sets the maximum number of restarts.
+func (opt ArnoldiOptions) MaxIterations(n int) ArnoldiOptions {
+	opt.maxIterations = n
+	return opt
+}
+
+// Tol sets the tolerance of the convergence criterion |r|*|y[n-1]| < tol*max(1, |theta|),
+// where theta is a Ritz value, y its eigenvector in the Krylov space, and r the residual of the Arnoldi relation.
+// The default is twice the machine precision, and looser tolerances trade accuracy for fewer iterations.
+// See Section 4.6 Stopping Criterion, ARPACK Users' Guide, Lehoucq et al.
+func (opt ArnoldiOptions) Tol(tol float32) ArnoldiOptions {
+	opt.tol = tol
+	return opt
+}
+
+// Shift turns on the shift-invert mode, in which the k eigenvalues closest to sigma are found.
+// In this mode, a-sigma*I is factorized once, and the Arnoldi iteration is performed on (a-sigma*I)^-1 with linear solves.
+// The eigenvalues mu of (a-sigma*I)^-1 are related to the eigenvalues lambda of a by lambda = sigma + 1/mu,
+// and thus interior eigenvalues of a near sigma become the well separated extremal eigenvalues of (a-sigma*I)^-1.
+// See Section 3.2 Shift-Invert Spectral Transformation Mode, ARPACK Users' Guide, Lehoucq et al.
+func (opt ArnoldiOptions) Shift(sigma complex64) ArnoldiOptions {
+	opt.shiftInvert = true
+	opt.shift = sigma
+	return opt
+}
+
+// Profile accumulates the time spent in the phases of the iteration to p.
+func (opt ArnoldiOptions) Profile(p *ArnoldiProfile) ArnoldiOptions {
+	opt.profile = p
+	return opt
+}
+
+// FixPhase sets whether to fix the phases of the eigenvectors, whose largest components are then real and positive, see FixPhase.
+func (opt ArnoldiOptions) FixPhase(fix bool) ArnoldiOptions {
+	opt.fixPhase = fix
+	return opt
+}
+
+// InitialVector sets the starting vector of the iteration, which is a column vector of shape {dimension, 1}, and defaults to a random vector.
+// A good guess of the wanted eigenvectors, such as the ground state of the previous sweep of DMRG, saves most of the iterations,
+// and an exact eigenvector spans an invariant subspace which the iteration escapes with random vectors.
+// The iteration reads v only at its start, hence v may be modified afterwards, and may be the buffer of the eigenvectors.
+func (opt ArnoldiOptions) InitialVector(v *tensor.Dense) ArnoldiOptions {
+	opt.initial = v
+	return opt
+}
+
+// Rand sets the source of the random vectors, which start the iteration without an initial vector and continue it past invariant subspaces,
+// and are otherwise drawn from the global source.
+func (opt ArnoldiOptions) Rand(r *rand.Rand) ArnoldiOptions {
+	opt.rand = r
+	return opt
+}
+
+// Interrupt sets a channel, such as the Done channel of a context.Context, whose closing stops the iteration at its next restart,
+// and returns an error whose cause is ErrInterrupted.
+func (opt ArnoldiOptions) Interrupt(c <-chan struct{}) ArnoldiOptions {
+	opt.interrupt = c
+	return opt
+}
+
+// interrupted returns ErrInterrupted if the Interrupt channel is closed.
+func (opt ArnoldiOptions) interrupted() error {
+	select {
+	case <-opt.interrupt:
+		return errors.WithStack(ErrInterrupted)
+	default:
+		return nil
+	}
+}
+
+// startVector sets x to the normalized initial vector of opt, or a random vector drawn from r if opt has none or it is zero.
+func (opt ArnoldiOptions) startVector(r *rand.Rand, x *tensor.Dense) error {
+	randUniform(r, x)
+	if v := opt.initial; v != nil {
+		if s := v.Shape(); len(s) != 2 || s[0] != x.Shape()[0] || s[1] != 1 {
+			return errors.Errorf("%#v %#v", s, x.Shape())
+		}
+		if v.FrobeniusNorm() > 0 {
+			x.Set([]int{0, 0}, v)
+		}
+	}
+	x.Mul(complex(1/x.FrobeniusNorm(), 0))
+	return nil
+}
+
+// Arnoldi finds the k eigenvalues with the smallest real part, and their eigenvectors.
+// In the shift-invert mode, the k eigenvalues closest to the shift are found instead, and are sorted by their distance to the shift.
+// The Krylov space is restarted with the Krylov-Schur method, which keeps the wanted Ritz vectors and purges the unwanted ones.
+// Converged Ritz vectors are locked, and are not modified by subsequent restarts.
+// For more details, see G. W. Stewart, A Krylov-Schur Algorithm for Large Eigenproblems, SIAM J. Matrix Anal. Appl. 23, 601 (2001),
+// and Section 4.6 Stopping Criterion, ARPACK Users' Guide, Lehoucq et al.
+func Arnoldi(eigvals, eigvecs, a *tensor.Dense, k int, bufs [7]*tensor.Dense, options ...ArnoldiOptions) error {
+	opt := NewArnoldiOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+
+	var op LinearOperator = MatrixOperator(a)
+	order := func(x, y complex64) int { return cmp.Compare(real(x), real(y)) }
+	if opt.shiftInvert {
+		lu, err := factorizeLU(a, opt.shift)
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		op = lu
+		order = func(x, y complex64) int { return cmp.Compare(abs(y), abs(x)) }
+	}
+
+	if err := arnoldi(eigvals, eigvecs, op, order, k, bufs, opt); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if opt.shiftInvert {
+		for i := range k {
+			eigvals.SetAt([]int{i}, opt.shift+1/eigvals.At(i))
+		}
+	}
+	return nil
+}
+
+// ArnoldiOperator is like Arnoldi, but works on a matrix-free linear operator.
+// Since the shift-invert mode requires factorizing a matrix, it is not supported.
+func ArnoldiOperator(eigvals, eigvecs *tensor.Dense, op LinearOperator, k int, bufs [7]*tensor.Dense, options ...ArnoldiOptions) error {
+	opt := NewArnoldiOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.shiftInvert {
+		return errors.Errorf("shift-invert needs a matrix")
+	}
+
+	order := func(x, y complex64) int { return cmp.Compare(real(x), real(y)) }
+	if err := arnoldi(eigvals, eigvecs, op, order, k, bufs, opt); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// arnoldi finds the first k eigenvalues of op in the order given by order.
+func arnoldi(eigvals, eigvecs *tensor.Dense, op LinearOperator, order func(complex64, complex64) int, k int, bufs [7]*tensor.Dense, opt ArnoldiOptions) error {
+	m := op.Dim()
+	n := opt.krylovSpaceDim
+	if n == 0 {
+		n = max(2*k+1, 20)
+	}
+	n = min(n, m)
+	if k < 1 || k > m || (n <= k && n < m) {
+		return errors.Errorf("%d %d %d", k, n, m)
+	}
+
+	// v and h hold the Krylov-Schur decomposition a@v[:, :n] = v[:, :n]@h[:n, :n] + v[:, n]@h[n:, :n].
+	v := bufs[0].Reset(m, n+1)
+	h := bufs[1].Reset(n+1, n)
+	// The Ritz pairs are stored in eigvals and eigvecs during the iteration.
+	vals, vecs := eigvals, eigvecs
+
+	// Start with the initial vector of options.
+	rng := opt.rand
+	if err := opt.startVector(rng, v.Slice([][2]int{{0, m}, {0, 1}})); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	// v[:, :locked] are the locked Schur vectors, and v[:, p] is the starting vector of the next expansion.
+	var locked, p int
+	var converged bool
+	prof := opt.profile
+	expandFn := expand
+	if opt.lanczos {
+		expandFn = expandLanczos
+	}
+	for range opt.maxIterations {
+		if err := opt.interrupted(); err != nil {
+			return errors.Wrap(err, "")
+		}
+		if err := expandFn(op, v, h, p, n, prof, rng, [3]*tensor.Dense(bufs[2:5])); err != nil {
+			return errors.Wrap(err, "")
+		}
+		if debug.Enabled {
+			debug.Assert(checkKrylovSchur(op, v, h, n))
+		}
+
+		// Compute the Ritz pairs of the active part.
+		t0 := prof.clock()
+		na, want := n-locked, k-locked
+		ha := bufs[2].Reset(na, na).Set([]int{0, 0}, h.Slice([][2]int{{locked, n}, {locked, n}}))
+		if err := tensor.Eig(vals, vecs, ha, [3]*tensor.Dense(bufs[3:6])); err != nil {
+			return errors.Wrap(err, "")
+		}
+		if opt.lanczos {
+			realEigvals(vals)
+		}
+		sortEigen(vals, vecs, order, bufs[2])
+
+		// Check convergence with the residual estimate |h[n, n-1]| * |y[na-1]|.
+		beta := abs(h.At(n, n-1))
+		var leading, numConverged int
+		for j := range want {
+			if beta*abs(vecs.At(na-1, j)) >= opt.tol*max(1, abs(vals.At(j))) {
+				continue
+			}
+			numConverged++
+			if leading == j {
+				leading++
+			}
+		}
+		if leading == want {
+			restart(v, h, vecs, locked, n, want, want, [4]*tensor.Dense(bufs[2:6]))
+			locked += want
+			converged = true
+			if prof != nil {
+				prof.Restart += time.Since(t0)
+			}
+			break
+		}
+
+		// Keep at least half of the active space, and more if many Ritz pairs converged, to prevent stagnation.
+		// For more details, see Section 5.1.2 XYaup2, ARPACK Users' Guide, Lehoucq et al.
+		keep := min(max(want+numConverged, (na+want)/2), na-1)
+		restart(v, h, vecs, locked, n, keep, leading, [4]*tensor.Dense(bufs[2:6]))
+		p = locked + keep
+		locked += leading
+		if debug.Enabled {
+			debug.Assert(checkKrylovSchur(op, v, h, p))
+		}
+		if prof != nil {
+			prof.Restart += time.Since(t0)
+		}
+	}
+	if !converged {
+		return errors.Errorf("not converged %d %d", locked, k)
+	}
+
+	// Since v[:, :k] spans an invariant subspace, the eigenpairs of a are those of h[:k, :k].
+	t0 := prof.clock()
+	hk := bufs[2].Reset(k, k).Set([]int{0, 0}, h.Slice([][2]int{{0, k}, {0, k}}))
+	y := bufs[6]
+	if err := tensor.Eig(eigvals, y, hk, [3]*tensor.Dense(bufs[3:6])); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if opt.lanczos {
+		realEigvals(eigvals)
+	}
+	sortEigen(eigvals, y, order, bufs[2])
+	tensor.MatMul(eigvecs, v.Slice([][2]int{{0, m}, {0, k}}), y)
+	if opt.fixPhase {
+		FixPhase(eigvecs)
+	}
+	if prof != nil {
+		prof.Restart += time.Since(t0)
+	}
+	if debug.Enabled {
+		debug.Assert(checkEigenpairs(op, eigvals, eigvecs, opt.tol))
+	}
+	return nil
+}
+
+// checkKrylovSchur checks that the Krylov-Schur decomposition op@v[:, :n] = v[:, :n+1]@h[:n+1, :n] holds, and that v[:, :n+1] is orthonormal.
+func checkKrylovSchur(op LinearOperator, v, h *tensor.Dense, n int) error {
+	m := v.Shape()[0]
+	// When the whole space is spanned, the residual vector is zero.
+	vn1 := v.Slice([][2]int{{0, m}, {0, min(n+1, m)}})
+	if err := debug.Orthonormal(vn1); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%d", n))
+	}
+
+	av := tensor.Zeros(m, n)
+	for j := range n {
+		av.Set([]int{0, j}, op.Apply(tensor.Zeros(1), v.Slice([][2]int{{0, m}, {j, j + 1}})))
+	}
+	vh := tensor.MatMul(tensor.Zeros(1), v.Slice([][2]int{{0, m}, {0, n + 1}}), h.Slice([][2]int{{0, n + 1}, {0, n}}))
+	if err := debug.Close(av, vh); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%d", n))
+	}
+	return nil
+}
+
+// checkEigenpairs checks that the residual op@x - lambda*x is within the convergence tolerance tol,
+// for the eigenvalues lambda in eigvals and eigenvectors x in eigvecs.
+func checkEigenpairs(op LinearOperator, eigvals, eigvecs *tensor.Dense, tol float32) error {
+	m := eigvecs.Shape()[0]
+	for j := range eigvals.Shape()[0] {
+		lambda := eigvals.At(j)
+		x := eigvecs.Slice([][2]int{{0, m}, {j, j + 1}})
+		r := op.Apply(tensor.Zeros(1), x).Add(-lambda, x)
+		if rNorm := r.FrobeniusNorm(); rNorm > max(tol, debug.Tol)*max(1, abs(lambda)) {
+			return errors.Errorf("%d %v %f", j, lambda, rNorm)
+		}
+	}
+	return nil
+}
+
+// expand extends the Krylov-Schur decomposition from p to n vectors with the Arnoldi process, drawing the random vectors of invariant subspaces from r.
+func expand(op LinearOperator, v, h *tensor.Dense, p, n int, prof *ArnoldiProfile, r *rand.Rand, bufs [3]*tensor.Dense) error {
+	m := v.Shape()[0]
+	for i := p; i < n; i++ {
+		t0 := prof.clock()
+		f := op.Apply(bufs[0], v.Slice([][2]int{{0, m}, {i, i + 1}}))
+		t1 := prof.clock()
+		q := v.Slice([][2]int{{0, m}, {0, i + 1}})
+		hi := h.Slice([][2]int{{0, i + 1}, {i, i + 1}})
+		fNorm, err := orthogonalize(f, hi, q, [2]*tensor.Dense(bufs[1:]))
+		if prof != nil {
+			prof.Apply += t1.Sub(t0)
+			prof.Applications++
+			prof.Orthogonalize += time.Since(t1)
+		}
+
+		vi := v.Slice([][2]int{{0, m}, {i + 1, i + 2}})
+		if err == nil && fNorm >= epsilon {
+			h.SetAt([]int{i + 1, i}, complex(fNorm, 0))
+			vi.Set([]int{0, 0}, f).Mul(complex(1/fNorm, 0))
+			continue
+		}
+
+		// An invariant subspace is found, continue with a random orthogonal vector.
+		// Section 5.1.3 XYaitr, ARPACK Users' Guide, Lehoucq et al.
+		h.SetAt([]int{i + 1, i}, 0)
+		if i+1 >= m {
+			vi.Mul(0)
+			continue
+		}
+		if err := randOrthogonal(r, vi, q, [2]*tensor.Dense(bufs[1:])); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return nil
+}
+
+// restart shrinks the active part of the Krylov-Schur decomposition to the first keep Ritz vectors in vecs.
+// The first lock of the kept vectors are then locked, by decoupling them from the residual.
+func restart(v, h, vecs *tensor.Dense, locked, n, keep, lock int, bufs [4]*tensor.Dense) {
+	m := v.Shape()[0]
+	na := n - locked
+
+	// Orthonormalize the kept Ritz vectors.
+	// Since the Ritz vectors are sorted, q[:, :j] spans an invariant subspace of h for every j.
+	y := bufs[0].Reset(na, keep).Set([]int{0, 0}, vecs.Slice([][2]int{{0, na}, {0, keep}}))
+	q := bufs[1]
+	QR(q, y, [2]*tensor.Dense(bufs[2:]))
+
+	// Transform the Krylov basis.
+	va := v.Slice([][2]int{{0, m}, {locked, n}})
+	vq := tensor.MatMul(bufs[2], va, q)
+	v.Set([]int{0, locked}, vq)
+	v.Set([]int{0, locked + keep}, v.Slice([][2]int{{0, m}, {n, n + 1}}))
+
+	// Transform the Rayleigh quotient, h = q.H @ h @ q.
+	hq := tensor.MatMul(bufs[2], h.Slice([][2]int{{0, n + 1}, {locked, n}}), q)
+	qhq := tensor.MatMul(bufs[3], q.H(), hq.Slice([][2]int{{locked, n}, {0, keep}}))
+	hq.Set([]int{locked, 0}, qhq)
+	hq.Set([]int{locked + keep, 0}, hq.Slice([][2]int{{n, n + 1}, {0, keep}}))
+	h.Slice([][2]int{{0, n + 1}, {locked, n}}).Mul(0)
+	h.Set([]int{0, locked}, hq.Slice([][2]int{{0, locked + keep + 1}, {0, keep}}))
+
+	// Lock converged vectors.
+	if lock == 0 {
+		return
+	}
+	h.Slice([][2]int{{locked + lock, locked + keep + 1}, {locked, locked + lock}}).Mul(0)
+}
+
+// orthogonalize orthogonalizes vector f against the orthonormal vectors in q, such that f_{out} = f_{in} - q @ h.
+// Re-orthogonalization used here is explained in
+// Remark 11.1, Chapter 11, Lecture notes of Numerical Methods for Solving Large Scale Eigenvalue Problems, Peter Arbenz.
+func orthogonalize(f, h, q *tensor.Dense, bufs [2]*tensor.Dense) (float32, error) {
+	// Angle of sin(pi/4) is explained in Section 5.1.3 XYaitr, ARPACK Users' Guide, Lehoucq et al.
+	sinPi4 := float32(math.Sin(math.Pi / 4))
+	if h != nil {
+		h.Mul(0)
+	}
+
+	for range 3 {
+		f0 := f.FrobeniusNorm()
+
+		c := tensor.MatMul(bufs[0], q.H(), f)
+		f.Add(-1, tensor.MatMul(bufs[1], q, c))
+		if h != nil {
+			h.Add(1, c)
+		}
+
+		fn := f.FrobeniusNorm()
+		if fn > sinPi4*f0 {
+			return fn, nil
+		}
+	}
+	return -1, errors.Errorf("orthogonalization failed")
+}
+
+type valVec struct {
+	val *tensor.Dense
+	vec *tensor.Dense
+	fn  func(complex64, complex64) int
+	buf *tensor.Dense
+}
+
+func (vv valVec) Len() int { return vv.val.Shape()[0] }
+func (vv valVec) Swap(i, j int) {
+	tmp := vv.val.At(i)
+	vv.val.SetAt([]int{i}, vv.val.At(j))
+	vv.val.SetAt([]int{j}, tmp)
+
+	m := vv.vec.Shape()[0]
+	vv.buf.Reset(m, 1).Set([]int{0, 0}, vv.vec.Slice([][2]int{{0, m}, {i, i + 1}}))
+	vv.vec.Set([]int{0, i}, vv.vec.Slice([][2]int{{0, m}, {j, j + 1}}))
+	vv.vec.Set([]int{0, j}, vv.buf)
+}
+func (vv valVec) Less(i, j int) bool {
+	return vv.fn(vv.val.At(i), vv.val.At(j)) < 0
+}
+
+// sortEigen sorts eigenvalues and their eigenvectors according to fn.
+func sortEigen(val, vec *tensor.Dense, fn func(complex64, complex64) int, buf *tensor.Dense) {
+	sort.Stable(valVec{val: val, vec: vec, fn: fn, buf: buf})
+}
+
+// randOrthogonal sets x to a random unit vector drawn from r, which is orthogonal to the orthonormal columns of q.
+func randOrthogonal(r *rand.Rand, x, q *tensor.Dense, bufs [2]*tensor.Dense) error {
+	for range 3 {
+		randUniform(r, x)
+		xNorm, err := orthogonalize(x, nil, q, bufs)
+		if err == nil {
+			x.Mul(complex(1/xNorm, 0))
+			return nil
+		}
+	}
+	return errors.Errorf("fail to orthogonalize")
+}
+
+func randVec(x *tensor.Dense) *tensor.Dense {
+	for i := range x.Shape()[0] {
+		x.SetAt([]int{i, 0}, complex(rand.Float32()*2-1, rand.Float32()*2-1))
+	}
+	return x
+}
+
+func abs(c complex64) float32 {
+	return float32(math.Hypot(float64(real(c)), float64(imag(c))))
+}
@@ -0,0 +1,364 @@
+package linalg
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+func TestArnoldi(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		a   *tensor.Dense
+		k   int
+		opt ArnoldiOptions
+		tol float32
+	}
+	tests := []testcase{
+		{
+			a:   tensor.T2([][]complex64{{-2, 0, 0, 0}, {0, -3, -4, 0}, {0, -4, -9, 0}, {0, 0, 0, 5}}),
+			k:   2,
+			opt: NewArnoldiOptions().KrylovSpaceDim(3),
+			tol: 1e-4,
+		},
+		{
+			a:   hermitian(rand.New(rand.NewPCG(0, 0)), 64),
+			k:   6,
+			opt: NewArnoldiOptions().KrylovSpaceDim(14),
+			tol: 1e-4,
+		},
+		{
+			a:   randMatrix(rand.New(rand.NewPCG(1, 1)), 48),
+			k:   4,
+			opt: NewArnoldiOptions().KrylovSpaceDim(16),
+			tol: 1e-4,
+		},
+		{
+			a:   hermitian(rand.New(rand.NewPCG(0, 0)), 64),
+			k:   2,
+			opt: NewArnoldiOptions().Tol(1e-4),
+			tol: 1e-2,
+		},
+		{
+			a:   randMatrix(rand.New(rand.NewPCG(2, 2)), 8),
+			k:   8,
+			opt: NewArnoldiOptions(),
+			tol: 1e-4,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [7]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+			if err := Arnoldi(eigvals, eigvecs, test.a, test.k, bufs, test.opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Compare with the dense eigenvalue solver.
+			m := test.a.Shape()[0]
+			lambda := tensor.Zeros(1)
+			if err := tensor.Eig(lambda, nil, tensor.Zeros(m, m).Set([]int{0, 0}, test.a), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := eigvals.Equal(lambda.Slice([][2]int{{0, test.k}}), test.tol); err != nil {
+				t.Fatalf("%+v %v %v", err, eigvals.ToSlice1(), lambda.ToSlice1())
+			}
+
+			for j := range test.k {
+				x := eigvecs.Slice([][2]int{{0, m}, {j, j + 1}})
+				ax := tensor.MatMul(tensor.Zeros(1), test.a, x)
+				ax.Add(-eigvals.At(j), x)
+				if r := ax.FrobeniusNorm(); r > test.tol*max(1, abs(eigvals.At(j))) {
+					t.Fatalf("%d %v %f", j, eigvals.At(j), r)
+				}
+			}
+		})
+	}
+}
+
+func TestArnoldiLocking(t *testing.T) {
+	t.Parallel()
+	// Eigenvalues are 0, 1, 2, ..., which are well separated and converge one after another.
+	m, k := 40, 5
+	q := tensor.Zeros(1)
+	tensor.QR(q, randMatrix(rand.New(rand.NewPCG(3, 3)), m), [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)})
+	d := tensor.Zeros(m, m)
+	for i := range m {
+		d.SetAt([]int{i, i}, complex(float32(i), 0))
+	}
+	a := tensor.MatMul(tensor.Zeros(1), q, tensor.MatMul(tensor.Zeros(1), d, q.H()))
+
+	var bufs [7]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+	opt := NewArnoldiOptions().KrylovSpaceDim(2 * k)
+	if err := Arnoldi(eigvals, eigvecs, a, k, bufs, opt); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for j := range k {
+		if d := abs(eigvals.At(j) - complex(float32(j), 0)); d > 1e-4 {
+			t.Fatalf("%d %v", j, eigvals.ToSlice1())
+		}
+	}
+}
+
+func TestArnoldiShift(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		a     *tensor.Dense
+		k     int
+		sigma complex64
+		tol   float32
+	}
+	tests := []testcase{
+		{
+			a:     hermitian(rand.New(rand.NewPCG(4, 4)), 64),
+			k:     3,
+			sigma: 0.3,
+			tol:   1e-3,
+		},
+		{
+			a:     randMatrix(rand.New(rand.NewPCG(5, 5)), 48),
+			k:     4,
+			sigma: complex(0.5, -0.5),
+			tol:   1e-3,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [7]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+			opt := NewArnoldiOptions().Shift(test.sigma)
+			if err := Arnoldi(eigvals, eigvecs, test.a, test.k, bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Compare with the eigenvalues closest to sigma.
+			m := test.a.Shape()[0]
+			lambda := tensor.Zeros(1)
+			if err := tensor.Eig(lambda, nil, tensor.Zeros(m, m).Set([]int{0, 0}, test.a), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			closest := lambda.ToSlice1()
+			slices.SortFunc(closest, func(x, y complex64) int { return cmp.Compare(abs(x-test.sigma), abs(y-test.sigma)) })
+			if err := eigvals.Equal(tensor.T1(closest[:test.k]), test.tol); err != nil {
+				t.Fatalf("%+v %v %v", err, eigvals.ToSlice1(), closest[:test.k])
+			}
+
+			for j := range test.k {
+				x := eigvecs.Slice([][2]int{{0, m}, {j, j + 1}})
+				ax := tensor.MatMul(tensor.Zeros(1), test.a, x)
+				ax.Add(-eigvals.At(j), x)
+				if r := ax.FrobeniusNorm(); r > test.tol*max(1, abs(eigvals.At(j))) {
+					t.Fatalf("%d %v %f", j, eigvals.At(j), r)
+				}
+			}
+		})
+	}
+}
+
+// laplacian is the matrix-free one dimensional discrete Laplacian with Dirichlet boundaries.
+type laplacian int
+
+func (op laplacian) Dim() int { return int(op) }
+
+func (op laplacian) Apply(dst, src *tensor.Dense) *tensor.Dense {
+	n := int(op)
+	dst.Reset(n, 1)
+	for i := range n {
+		v := 2 * src.At(i, 0)
+		if i > 0 {
+			v -= src.At(i-1, 0)
+		}
+		if i < n-1 {
+			v -= src.At(i+1, 0)
+		}
+		dst.SetAt([]int{i, 0}, v)
+	}
+	return dst
+}
+
+func TestArnoldiOperator(t *testing.T) {
+	t.Parallel()
+	n, k := 200, 3
+	var bufs [7]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+	// The smallest eigenvalues are clustered, so use a larger Krylov space.
+	opt := NewArnoldiOptions().KrylovSpaceDim(40).MaxIterations(512).Tol(1e-5)
+	if err := ArnoldiOperator(eigvals, eigvecs, laplacian(n), k, bufs, opt); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for j := range k {
+		want := 2 - 2*math.Cos(float64(j+1)*math.Pi/float64(n+1))
+		if d := math.Abs(float64(real(eigvals.At(j))) - want); d > 1e-5 {
+			t.Fatalf("%d %v %f", j, eigvals.ToSlice1(), want)
+		}
+	}
+
+	if err := ArnoldiOperator(eigvals, eigvecs, laplacian(n), k, bufs, NewArnoldiOptions().Shift(0)); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestArnoldiProfile(t *testing.T) {
+	t.Parallel()
+	var bufs [7]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+	var prof ArnoldiProfile
+	opt := NewArnoldiOptions().KrylovSpaceDim(20).Profile(&prof)
+	if err := ArnoldiOperator(eigvals, eigvecs, laplacian(20), 1, bufs, opt); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// The Krylov space is the whole space, so the iteration ends after a single expansion.
+	if prof.Applications != 20 {
+		t.Fatalf("%#v", prof)
+	}
+	if prof.Apply <= 0 || prof.Orthogonalize <= 0 || prof.Restart <= 0 {
+		t.Fatalf("%#v", prof)
+	}
+}
+
+func TestInitialVector(t *testing.T) {
+	t.Parallel()
+	solvers := []func(eigvals, eigvecs *tensor.Dense, op LinearOperator, k int, bufs [7]*tensor.Dense, options ...ArnoldiOptions) error{
+		ArnoldiOperator, LanczosOperator, DavidsonOperator, SelectiveLanczosOperator,
+	}
+	op := laplacian(200)
+	for i, solve := range solvers {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [7]*tensor.Dense
+			for j := range len(bufs) {
+				bufs[j] = tensor.Zeros(1)
+			}
+			opt := NewArnoldiOptions().KrylovSpaceDim(20).MaxIterations(1024).Tol(1e-5)
+			eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+			var cold ArnoldiProfile
+			if err := solve(eigvals, eigvecs, op, 1, bufs, opt.Profile(&cold)); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			want := eigvals.At(0)
+
+			// Starting from a perturbation of the ground state takes fewer applications than from a random vector.
+			r := rand.New(rand.NewPCG(uint64(i), uint64(i)))
+			x0 := tensor.Zeros(200, 1).Set([]int{0, 0}, eigvecs).Add(1e-3, randTensor(r, 200, 1))
+			var warm ArnoldiProfile
+			if err := solve(eigvals, eigvecs, op, 1, bufs, opt.Profile(&warm).InitialVector(x0)); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if d := abs(eigvals.At(0) - want); d > 1e-4 {
+				t.Fatalf("%v %v", eigvals.At(0), want)
+			}
+			if warm.Applications >= cold.Applications {
+				t.Fatalf("%d %d", warm.Applications, cold.Applications)
+			}
+
+			if err := solve(eigvals, eigvecs, op, 1, bufs, opt.InitialVector(tensor.Zeros(199, 1))); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	t.Parallel()
+	solvers := []func(eigvals, eigvecs *tensor.Dense, op LinearOperator, k int, bufs [7]*tensor.Dense, options ...ArnoldiOptions) error{
+		ArnoldiOperator, LanczosOperator, DavidsonOperator, SelectiveLanczosOperator,
+	}
+	// The ground state is degenerate, hence the found one depends on the random starting vector.
+	const n = 50
+	a := tensor.Zeros(n, n)
+	for i := 2; i < n; i++ {
+		a.SetAt([]int{i, i}, complex(float32(i-1), 0))
+	}
+	op := MatrixOperator(a)
+	for i, solve := range solvers {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [7]*tensor.Dense
+			for j := range len(bufs) {
+				bufs[j] = tensor.Zeros(1)
+			}
+			ground := func(seed uint64) *tensor.Dense {
+				eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+				r := rand.New(rand.NewPCG(seed, seed))
+				if err := solve(eigvals, eigvecs, op, 1, bufs, NewArnoldiOptions().KrylovSpaceDim(20).Tol(1e-5).Rand(r)); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				return eigvecs.Slice([][2]int{{0, n}, {0, 1}})
+			}
+
+			x, y := ground(1), ground(1)
+			if err := x.Equal(y, 0); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			// The ratios of the components in the ground space differ between seeds.
+			z := ground(2)
+			if d := abs(x.At(0, 0)/x.At(1, 0) - z.At(0, 0)/z.At(1, 0)); d < 1e-3 {
+				t.Fatalf("%v %v", x.ToSlice2()[:2], z.ToSlice2()[:2])
+			}
+		})
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	t.Parallel()
+	solvers := []func(eigvals, eigvecs *tensor.Dense, op LinearOperator, k int, bufs [7]*tensor.Dense, options ...ArnoldiOptions) error{
+		ArnoldiOperator, LanczosOperator, DavidsonOperator, SelectiveLanczosOperator,
+	}
+	interrupt := make(chan struct{})
+	close(interrupt)
+	for i, solve := range solvers {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [7]*tensor.Dense
+			for j := range len(bufs) {
+				bufs[j] = tensor.Zeros(1)
+			}
+			eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+			err := solve(eigvals, eigvecs, laplacian(200), 1, bufs, NewArnoldiOptions().Interrupt(interrupt))
+			if !errors.Is(err, ErrInterrupted) {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func randMatrix(r *rand.Rand, m int) *tensor.Dense {
+	a := tensor.Zeros(m, m)
+	for i := range m {
+		for j := range m {
+			a.SetAt([]int{i, j}, complex(r.Float32()*2-1, r.Float32()*2-1))
+		}
+	}
+	return a
+}
+
+func hermitian(r *rand.Rand, m int) *tensor.Dense {
+	a := randMatrix(r, m)
+	return tensor.Zeros(m, m).Add(1, a).Add(1, a.H())
+}
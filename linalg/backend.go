@@ -0,0 +1,28 @@
+package linalg
+
+import (
+	"github.com/fumin/tensor"
+)
+
+// The dense kernels MatMul, QR and SVD, which dominate the sweeps of DMRG and TEBD, are performed by a backend chosen at build time.
+// The default backend is pure Go, in which MatMul is the blocked multiplication of this package, and QR and SVD are those of github.com/fumin/tensor.
+// Building with the openblas tag and cgo routes the kernels through the cblas and LAPACKE interfaces of OpenBLAS instead,
+// which are tuned for the processor and multithreaded:
+//
+//	go build -tags openblas ./...
+//
+// The results of the backends agree up to rounding errors, and up to the phases of singular vectors. Backend is the name of the backend of the build.
+
+// QR performs the QR decomposition of matrix a as tensor.QR, with the backend of the build.
+// It returns the upper triangular matrix R, whose diagonal is real and non-negative, and q is set to Q, which has orthonormal columns.
+// Matrix a is modified upon return.
+func QR(q, a *tensor.Dense, bufs [2]*tensor.Dense) *tensor.Dense {
+	return backendQR(q, a, bufs)
+}
+
+// SVD performs the singular value decomposition a = u @ s @ v.H as tensor.SVD, with the backend of the build.
+// It returns the diagonal matrix s of the min(m, n) singular values in decreasing order, and u and v are set to the singular vectors.
+// Matrix a is modified upon return.
+func SVD(u, v, a *tensor.Dense, bufs [3]*tensor.Dense) (*tensor.Dense, error) {
+	return backendSVD(u, v, a, bufs)
+}
@@ -0,0 +1,27 @@
+//go:build !openblas || !cgo
+
+package linalg
+
+import (
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// Backend is the name of the backend of the dense kernels.
+const Backend = "go"
+
+func backendMatMul(c, a, b *tensor.Dense, opt MatMulOptions) *tensor.Dense {
+	return matmulBlocked(c, a, b, opt)
+}
+
+func backendQR(q, a *tensor.Dense, bufs [2]*tensor.Dense) *tensor.Dense {
+	return tensor.QR(q, a, bufs)
+}
+
+func backendSVD(u, v, a *tensor.Dense, bufs [3]*tensor.Dense) (*tensor.Dense, error) {
+	s, err := tensor.SVD(u, v, a, bufs)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return s, nil
+}
@@ -0,0 +1,120 @@
+//go:build openblas && cgo
+
+package linalg
+
+/*
+#cgo LDFLAGS: -lopenblas
+#include <cblas.h>
+#include <lapacke.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// Backend is the name of the backend of the dense kernels.
+const Backend = "openblas"
+
+func backendMatMul(c, a, b *tensor.Dense, _ MatMulOptions) *tensor.Dense {
+	m, k, n := a.Shape()[0], a.Shape()[1], b.Shape()[1]
+	as, bs := rowMajor(a), rowMajor(b)
+	cs := make([]complex64, m*n)
+	if m > 0 && n > 0 && k > 0 {
+		alpha, beta := complex64(1), complex64(0)
+		C.cblas_cgemm(C.CblasRowMajor, C.CblasNoTrans, C.CblasNoTrans, C.blasint(m), C.blasint(n), C.blasint(k),
+			unsafe.Pointer(&alpha), unsafe.Pointer(&as[0]), C.blasint(k), unsafe.Pointer(&bs[0]), C.blasint(n),
+			unsafe.Pointer(&beta), unsafe.Pointer(&cs[0]), C.blasint(n))
+	}
+	return fromRowMajor(c, m, n, cs)
+}
+
+// backendQR computes R with cgeqrf and Q with cungqr, and makes the diagonal of R non-negative as tensor.QR.
+func backendQR(q, a *tensor.Dense, _ [2]*tensor.Dense) *tensor.Dense {
+	m, n := a.Shape()[0], a.Shape()[1]
+	k := min(m, n)
+	as := rowMajor(a)
+	r := tensor.Zeros(k, n)
+	if k == 0 {
+		q.Reset(m, k)
+		return r
+	}
+	tau := make([]complex64, k)
+	if info := C.LAPACKE_cgeqrf(C.LAPACK_ROW_MAJOR, C.lapack_int(m), C.lapack_int(n), lapackPtr(as), C.lapack_int(n), lapackPtr(tau)); info != 0 {
+		panic(fmt.Sprintf("%d %d %d", info, m, n))
+	}
+	// R is the upper triangle of the first k rows.
+	r.Set(nil, fromRowMajor(tensor.Zeros(1), m, n, as).Slice([][2]int{{0, k}, {0, n}}))
+	for i := 1; i < k; i++ {
+		r.Slice([][2]int{{i, i + 1}, {0, i}}).Mul(0)
+	}
+
+	// The Householder reflectors are below the diagonal of the first k columns.
+	qs := make([]complex64, m*k)
+	for i := range m {
+		copy(qs[i*k:(i+1)*k], as[i*n:i*n+k])
+	}
+	if info := C.LAPACKE_cungqr(C.LAPACK_ROW_MAJOR, C.lapack_int(m), C.lapack_int(k), C.lapack_int(k), lapackPtr(qs), C.lapack_int(k), lapackPtr(tau)); info != 0 {
+		panic(fmt.Sprintf("%d %d %d", info, m, n))
+	}
+	fromRowMajor(q, m, k, qs)
+
+	for i := range k {
+		d := r.At(i, i)
+		if d == 0 {
+			continue
+		}
+		phase := d / complex(abs(d), 0)
+		r.Slice([][2]int{{i, i + 1}, {0, n}}).Mul(conj(phase))
+		q.Slice([][2]int{{0, m}, {i, i + 1}}).Mul(phase)
+	}
+	return r
+}
+
+// backendSVD computes the thin SVD with the divide and conquer cgesdd.
+func backendSVD(u, v, a *tensor.Dense, _ [3]*tensor.Dense) (*tensor.Dense, error) {
+	m, n := a.Shape()[0], a.Shape()[1]
+	k := min(m, n)
+	as := rowMajor(a)
+	sv := make([]float32, k)
+	us, vhs := make([]complex64, m*k), make([]complex64, k*n)
+	if k > 0 {
+		info := C.LAPACKE_cgesdd(C.LAPACK_ROW_MAJOR, C.char('S'), C.lapack_int(m), C.lapack_int(n), lapackPtr(as), C.lapack_int(n),
+			(*C.float)(unsafe.Pointer(&sv[0])), lapackPtr(us), C.lapack_int(k), lapackPtr(vhs), C.lapack_int(n))
+		if info != 0 {
+			return nil, errors.Errorf("%d %d %d", info, m, n)
+		}
+	}
+	fromRowMajor(u, m, k, us)
+	v.Reset(n, k).Set(nil, fromRowMajor(tensor.Zeros(1), k, n, vhs).H())
+	s := tensor.Zeros(k, k)
+	for i := range k {
+		s.SetAt([]int{i, i}, complex(sv[i], 0))
+	}
+	return s, nil
+}
+
+// rowMajor returns the elements of the matrix a in row major order.
+func rowMajor(a *tensor.Dense) []complex64 {
+	m, n := a.Shape()[0], a.Shape()[1]
+	x := make([]complex64, 0, m*n)
+	for _, v := range a.All() {
+		x = append(x, v)
+	}
+	return x
+}
+
+// fromRowMajor sets c to the m by n matrix whose elements in row major order are x, and returns c.
+// The elements are copied in bulk through a flat view of c, whose reset keeps the backing data of c.
+func fromRowMajor(c *tensor.Dense, m, n int, x []complex64) *tensor.Dense {
+	c.Reset(m, n).Reshape(m * n).T1(x)
+	return c
+}
+
+func lapackPtr(x []complex64) *C.lapack_complex_float {
+	return (*C.lapack_complex_float)(unsafe.Pointer(&x[0]))
+}
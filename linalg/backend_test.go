@@ -0,0 +1,86 @@
+package linalg
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+// The tests below check the contracts of the kernels, which hold for every backend, see Backend.
+
+func TestQR(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		m, n int
+	}{
+		{m: 5, n: 3},
+		{m: 4, n: 4},
+		{m: 3, n: 6},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%s %d", Backend, i), func(t *testing.T) {
+			t.Parallel()
+			a := randTensor(rand.New(rand.NewPCG(uint64(i), uint64(i))), test.m, test.n)
+			want := resetCopy(a)
+			q := tensor.Zeros(1)
+			r := QR(q, a, [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)})
+
+			k := min(test.m, test.n)
+			if err := tensor.MatMul(tensor.Zeros(1), q.H(), q).Equal(tensor.Zeros(1).Eye(k, 0), 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.MatMul(tensor.Zeros(1), q, r).Equal(want, 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for j := range r.Shape()[0] {
+				if d := r.At(j, j); real(d) < 0 || abs(complex(0, imag(d))) > 1e-6*real(d) {
+					t.Fatalf("%d %v", j, d)
+				}
+				for l := range j {
+					if r.At(j, l) != 0 {
+						t.Fatalf("%d %d %v", j, l, r.At(j, l))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSVD(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		m, n int
+	}{
+		{m: 6, n: 3},
+		{m: 4, n: 4},
+		{m: 2, n: 7},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%s %d", Backend, i), func(t *testing.T) {
+			t.Parallel()
+			a := randTensor(rand.New(rand.NewPCG(uint64(i), uint64(i))), test.m, test.n)
+			want := resetCopy(a)
+			u, v := tensor.Zeros(1), tensor.Zeros(1)
+			s, err := SVD(u, v, a, [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)})
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			k := min(test.m, test.n)
+			if s.Shape()[0] != k || s.Shape()[1] != k {
+				t.Fatalf("%#v", s.Shape())
+			}
+			for j := 1; j < k; j++ {
+				if real(s.At(j, j)) > real(s.At(j-1, j-1)) {
+					t.Fatalf("%d %v %v", j, s.At(j, j), s.At(j-1, j-1))
+				}
+			}
+			usv := tensor.MatMul(tensor.Zeros(1), tensor.MatMul(tensor.Zeros(1), u, s), v.H())
+			if err := usv.Equal(want, 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
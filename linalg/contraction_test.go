@@ -0,0 +1,182 @@
+package linalg
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestContractionPlan(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		aShape  []int
+		bShape  []int
+		axes    [][2]int
+		perm    []int
+		reshape []int
+	}
+	tests := []testcase{
+		{
+			aShape: []int{3, 4},
+			bShape: []int{4, 5},
+			axes:   [][2]int{{1, 0}},
+		},
+		{
+			// The L expression contracted with a site as in the effective hamiltonian.
+			aShape: []int{4, 3, 4},
+			bShape: []int{4, 2, 5},
+			axes:   [][2]int{{2, 0}},
+		},
+		{
+			aShape:  []int{3, 2, 4, 6},
+			bShape:  []int{6, 3, 2, 5},
+			axes:    [][2]int{{0, 1}, {3, 0}},
+			perm:    []int{3, 0, 2, 1},
+			reshape: []int{5 * 2 * 2 * 4, 1},
+		},
+		{
+			// Outer product.
+			aShape: []int{2, 3},
+			bShape: []int{4},
+			perm:   []int{2, 1, 0},
+		},
+		{
+			// Full contraction.
+			aShape: []int{2, 3},
+			bShape: []int{3, 2},
+			axes:   [][2]int{{0, 1}, {1, 0}},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			r := rand.New(rand.NewPCG(uint64(i), uint64(i)))
+			a, b := randTensor(r, test.aShape...), randTensor(r, test.bShape...)
+
+			want := tensor.Product(tensor.Zeros(1), a, b, test.axes)
+			if test.perm != nil {
+				want = want.Transpose(test.perm...)
+			}
+			want = resetCopy(want)
+			if test.reshape != nil {
+				want = want.Reshape(test.reshape...)
+			}
+
+			p := NewContractionPlan(test.aShape, test.bShape, test.axes, test.perm...)
+			if test.reshape != nil {
+				p = p.Reshape(test.reshape...)
+			}
+			c := p.Product(tensor.Zeros(1), a, b)
+			if err := c.Equal(want, 1e-6); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestContractionPlanAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	// AllocsPerRun cannot be called in parallel tests.
+	r := rand.New(rand.NewPCG(0, 0))
+	a, b := randTensor(r, 4, 3, 4), randTensor(r, 4, 2, 5)
+	p := NewContractionPlan(a.Shape(), b.Shape(), [][2]int{{2, 0}}, 1, 0, 2, 3).Reshape(4*3*2*5, 1)
+	c := tensor.Zeros(1)
+
+	// Once c has enough capacity, Product does not allocate.
+	if allocs := testing.AllocsPerRun(8, func() { p.Product(c, a, b) }); allocs != 0 {
+		t.Fatalf("%f", allocs)
+	}
+}
+
+func TestContractionPlanClone(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(1, 1))
+	a, b := randTensor(r, 4, 3, 4), randTensor(r, 4, 2, 5)
+	p := NewContractionPlan(a.Shape(), b.Shape(), [][2]int{{2, 0}}, 1, 0, 2, 3).Reshape(4*3*2*5, 1)
+	want := p.Product(tensor.Zeros(1), a, b)
+
+	// Clones contract concurrently without sharing scratch space.
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q, c := p.Clone(), tensor.Zeros(1)
+			for range 16 {
+				if err := q.Product(c, a, b).Equal(want, 0); err != nil {
+					errs[i] = err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+	}
+
+	// A plan in use by another goroutine panics.
+	p.busy.Store(true)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	p.Product(tensor.Zeros(1), a, b)
+}
+
+func TestContractionPlanWorkers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		workers int
+	}{
+		{workers: 1},
+		{workers: 3},
+		{workers: 0},
+		{workers: 1000},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			// The contraction is large enough for Product to start goroutines.
+			r := rand.New(rand.NewPCG(uint64(i), uint64(i)))
+			a, b := randTensor(r, 16, 3, 16), randTensor(r, 16, 2, 16)
+			serial := NewContractionPlan(a.Shape(), b.Shape(), [][2]int{{2, 0}}, 1, 0, 2, 3).Reshape(16*3*2*16, 1)
+			want := serial.Product(tensor.Zeros(1), a, b)
+
+			// Reshaping after setting the workers, and cloning, keep the workers consistent with the plan.
+			p := NewContractionPlan(a.Shape(), b.Shape(), [][2]int{{2, 0}}, 1, 0, 2, 3).Workers(test.workers).Reshape(16*3*2*16, 1)
+			for _, q := range []*ContractionPlan{p, p.Clone()} {
+				if err := q.Product(tensor.Zeros(1), a, b).Equal(want, 0); err != nil {
+					t.Fatalf("%+v", err)
+				}
+			}
+		})
+	}
+}
+
+func randTensor(r *rand.Rand, shape ...int) *tensor.Dense {
+	a := tensor.Zeros(shape...)
+	for ijk := range a.All() {
+		a.SetAt(ijk, complex(r.Float32()*2-1, r.Float32()*2-1))
+	}
+	return a
+}
+
+// resetCopy returns a contiguous copy of a, which can be reshaped.
+func resetCopy(a *tensor.Dense) *tensor.Dense {
+	c := tensor.Zeros(a.Shape()...)
+	for ijk, v := range a.All() {
+		c.SetAt(ijk, v)
+	}
+	return c
+}
@@ -0,0 +1,211 @@
+package linalg
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"time"
+
+	"github.com/fumin/qising/debug"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// DiagonalOperator is a linear operator whose diagonal is cheap to compute, which the Davidson method uses as a preconditioner.
+type DiagonalOperator interface {
+	LinearOperator
+	// Diagonal stores the diagonal of the operator in dst of shape {Dim(), 1}.
+	Diagonal(dst *tensor.Dense) *tensor.Dense
+}
+
+// DavidsonOperator is like LanczosOperator, but finds the k smallest eigenvalues of the Hermitian operator op with the Davidson method.
+// Instead of a Krylov space, the search space is expanded with the correction t = (theta - D)^-1 r of the lowest unconverged Ritz pair (theta, x),
+// where r = op@x - theta*x is the residual and D is the diagonal of op.
+// If op is not a DiagonalOperator, the correction is the residual itself, and the method reduces to a Lanczos iteration that is restarted differently.
+// When the search space reaches the Krylov space dimension in options, it is restarted with its lowest Ritz vectors.
+// A Ritz pair is converged when |r| < tol*max(1, |theta|), or when |r| is at the level of the rounding errors,
+// which unlike in the residual estimate of the Arnoldi iteration are present since r is computed explicitly.
+// See E. R. Davidson, The Iterative Calculation of a Few of the Lowest Eigenvalues and Corresponding Eigenvectors of Large Real-Symmetric Matrices,
+// J. Comput. Phys. 17, 87 (1975).
+func DavidsonOperator(eigvals, eigvecs *tensor.Dense, op LinearOperator, k int, bufs [7]*tensor.Dense, options ...ArnoldiOptions) error {
+	opt := NewArnoldiOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.shiftInvert {
+		return errors.Errorf("shift-invert needs a matrix")
+	}
+	m := op.Dim()
+	n := opt.krylovSpaceDim
+	if n == 0 {
+		n = max(2*k+1, 20)
+	}
+	n = min(n, m)
+	if k < 1 || k > m || (n <= k && n < m) {
+		return errors.Errorf("%d %d %d", k, n, m)
+	}
+
+	// v[:, :j] is the orthonormal basis of the search space, w[:, :j] = op@v[:, :j], and g[:j, :j] = v[:, :j].H@w[:, :j].
+	// v[:, n] holds the next correction, and w[:, n] the diagonal of op.
+	v := bufs[0].Reset(m, n+1)
+	w := bufs[1].Reset(m, n+1)
+	g := bufs[2].Reset(n, n)
+	t := v.Slice([][2]int{{0, m}, {n, n + 1}})
+	diag := w.Slice([][2]int{{0, m}, {n, n + 1}})
+	order := func(x, y complex64) int { return cmp.Compare(real(x), real(y)) }
+
+	// Start with the initial vector of options if given, else the unit vector of the smallest diagonal element if the diagonal is known, and a random vector otherwise.
+	dop, precondition := op.(DiagonalOperator)
+	if precondition {
+		diag.Set([]int{0, 0}, dop.Diagonal(bufs[3]))
+	}
+	rng := opt.rand
+	switch {
+	case opt.initial != nil:
+		if err := opt.startVector(rng, t); err != nil {
+			return errors.Wrap(err, "")
+		}
+	case precondition:
+		var smallest int
+		for i := range m {
+			if real(diag.At(i, 0)) < real(diag.At(smallest, 0)) {
+				smallest = i
+			}
+		}
+		t.Mul(0).SetAt([]int{smallest, 0}, 1)
+	default:
+		randUniform(rng, t)
+	}
+	prof := opt.profile
+	var j int
+	for range opt.maxIterations {
+		if err := opt.interrupted(); err != nil {
+			return errors.Wrap(err, "")
+		}
+		for j < n {
+			if err := davidsonExpand(op, v, w, g, j, prof, rng, [3]*tensor.Dense(bufs[3:6])); err != nil {
+				return errors.Wrap(err, "")
+			}
+			j++
+
+			// Compute the Ritz pairs, and the correction of the lowest unconverged one.
+			t0 := prof.clock()
+			ga := bufs[3].Reset(j, j).Set([]int{0, 0}, g.Slice([][2]int{{0, j}, {0, j}}))
+			if err := tensor.Eig(eigvals, eigvecs, ga, [3]*tensor.Dense(bufs[4:7])); err != nil {
+				return errors.Wrap(err, "")
+			}
+			realEigvals(eigvals)
+			sortEigen(eigvals, eigvecs, order, bufs[3])
+			// The rounding errors of r are proportional to the norm of op, which is estimated by the extremal Ritz values.
+			floor := davidsonRoundoff * max(1, abs(eigvals.At(0)), abs(eigvals.At(j-1)))
+			unconverged := -1
+			for i := range min(j, k) {
+				theta := eigvals.At(i)
+				y := eigvecs.Slice([][2]int{{0, j}, {i, i + 1}})
+				x := tensor.MatMul(bufs[3], v.Slice([][2]int{{0, m}, {0, j}}), y)
+				r := tensor.MatMul(bufs[4], w.Slice([][2]int{{0, m}, {0, j}}), y).Add(-theta, x)
+				rn := r.FrobeniusNorm()
+				if rn < opt.tol*max(1, abs(theta)) || rn < floor {
+					continue
+				}
+				unconverged = i
+				t.Set([]int{0, 0}, r)
+				if precondition {
+					for l := range m {
+						d := theta - diag.At(l, 0)
+						if abs(d) < davidsonMinDenominator {
+							d = davidsonMinDenominator
+						}
+						t.SetAt([]int{l, 0}, t.At(l, 0)/d)
+					}
+				}
+				break
+			}
+			if prof != nil {
+				prof.Restart += time.Since(t0)
+			}
+			if unconverged == -1 && j >= k {
+				davidsonEigvecs(eigvals, eigvecs, v, k, j, [2]*tensor.Dense(bufs[3:5]))
+				if opt.fixPhase {
+					FixPhase(eigvecs)
+				}
+				if debug.Enabled {
+					debug.Assert(checkEigenpairs(op, eigvals, eigvecs, opt.tol))
+				}
+				return nil
+			}
+			if unconverged == -1 {
+				randUniform(rng, t)
+			}
+		}
+
+		// Restart with the lowest Ritz vectors.
+		t0 := prof.clock()
+		keep := max(k, n/2)
+		y := eigvecs.Slice([][2]int{{0, j}, {0, keep}})
+		v.Set([]int{0, 0}, tensor.MatMul(bufs[3], v.Slice([][2]int{{0, m}, {0, j}}), y))
+		w.Set([]int{0, 0}, tensor.MatMul(bufs[3], w.Slice([][2]int{{0, m}, {0, j}}), y))
+		g.Mul(0)
+		for i := range keep {
+			g.SetAt([]int{i, i}, eigvals.At(i))
+		}
+		j = keep
+		if prof != nil {
+			prof.Restart += time.Since(t0)
+		}
+	}
+	return errors.Errorf("not converged %d", k)
+}
+
+// davidsonRoundoff is the relative rounding error of explicitly computed residuals,
+// which includes the drift of w from op@v accumulated over restarts.
+const davidsonRoundoff = 64 * epsilon
+
+// davidsonMinDenominator bounds the preconditioned correction (theta - D)^-1 r, where theta is close to a diagonal element of D.
+const davidsonMinDenominator = 1e-3
+
+// davidsonExpand orthonormalizes the correction v[:, n] against v[:, :j], and adds it to the search space as v[:, j].
+func davidsonExpand(op LinearOperator, v, w, g *tensor.Dense, j int, prof *ArnoldiProfile, r *rand.Rand, bufs [3]*tensor.Dense) error {
+	m, n := v.Shape()[0], g.Shape()[0]
+	t0 := prof.clock()
+	vj := v.Slice([][2]int{{0, m}, {j, j + 1}})
+	vj.Set([]int{0, 0}, v.Slice([][2]int{{0, m}, {n, n + 1}}))
+	q := v.Slice([][2]int{{0, m}, {0, j}})
+	tNorm, err := vj.FrobeniusNorm(), error(nil)
+	if j > 0 {
+		tNorm, err = orthogonalize(vj, nil, q, [2]*tensor.Dense(bufs[1:]))
+	}
+	if err == nil && tNorm >= epsilon {
+		vj.Mul(complex(1/tNorm, 0))
+	} else if err := randOrthogonal(r, vj, q, [2]*tensor.Dense(bufs[1:])); err != nil {
+		return errors.Wrap(err, "")
+	}
+	t1 := prof.clock()
+
+	wj := w.Slice([][2]int{{0, m}, {j, j + 1}})
+	wj.Set([]int{0, 0}, op.Apply(bufs[0], vj))
+	t2 := prof.clock()
+
+	// g is Hermitian, so only its new column is computed.
+	gj := tensor.MatMul(bufs[1], v.Slice([][2]int{{0, m}, {0, j + 1}}).H(), wj)
+	for i := range j {
+		g.SetAt([]int{i, j}, gj.At(i, 0))
+		g.SetAt([]int{j, i}, conj(gj.At(i, 0)))
+	}
+	g.SetAt([]int{j, j}, complex(real(gj.At(j, 0)), 0))
+	if prof != nil {
+		prof.Orthogonalize += t1.Sub(t0)
+		prof.Apply += t2.Sub(t1)
+		prof.Applications++
+	}
+	return nil
+}
+
+// davidsonEigvecs stores the first k Ritz pairs of the search space v[:, :j] in eigvals and eigvecs,
+// which on input hold the eigenpairs of the projected matrix.
+func davidsonEigvecs(eigvals, eigvecs, v *tensor.Dense, k, j int, bufs [2]*tensor.Dense) {
+	m := v.Shape()[0]
+	vals := bufs[0].Reset(k).Set([]int{0}, eigvals.Slice([][2]int{{0, k}}))
+	eigvals.Reset(k).Set([]int{0}, vals)
+	y := bufs[1].Reset(j, k).Set([]int{0, 0}, eigvecs.Slice([][2]int{{0, j}, {0, k}}))
+	tensor.MatMul(eigvecs, v.Slice([][2]int{{0, m}, {0, j}}), y)
+}
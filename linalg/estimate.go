@@ -0,0 +1,131 @@
+package linalg
+
+import (
+	"math"
+	"math/rand/v2"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// EstimateOptions are options for the randomized estimators SpectralRadius and Trace.
+type EstimateOptions struct {
+	iterations int
+	numSamples int
+	rand       *rand.Rand
+}
+
+// NewEstimateOptions returns the default options.
+func NewEstimateOptions() EstimateOptions {
+	opt := EstimateOptions{}
+	opt.iterations = 20
+	opt.numSamples = 16
+	return opt
+}
+
+// Iterations sets the number of iterations of the power method in SpectralRadius.
+func (opt EstimateOptions) Iterations(n int) EstimateOptions {
+	opt.iterations = n
+	return opt
+}
+
+// NumSamples sets the number of random vectors of Trace.
+func (opt EstimateOptions) NumSamples(n int) EstimateOptions {
+	opt.numSamples = n
+	return opt
+}
+
+// Rand sets the source of the random vectors, which are otherwise drawn from the global source.
+func (opt EstimateOptions) Rand(r *rand.Rand) EstimateOptions {
+	opt.rand = r
+	return opt
+}
+
+// SpectralRadius estimates the largest absolute value of the eigenvalues of op with the power method, which is the operator norm for Hermitian operators.
+// The estimate |op v_k| of the normalized iterate v_k is a lower bound of the operator norm, which converges at the rate of the ratio of the two largest absolute eigenvalues.
+// A few iterations suffice for rescaling the spectrum, such as into [-1, 1] for Chebyshev expansions, for which the estimate is enlarged by a safety margin.
+// See Section 7.3.1 The Power Method, G. H. Golub and C. F. Van Loan, Matrix Computations 4th Edition.
+func SpectralRadius(op LinearOperator, bufs [2]*tensor.Dense, options ...EstimateOptions) (float64, error) {
+	opt := NewEstimateOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.iterations < 1 {
+		return 0, errors.Errorf("%d", opt.iterations)
+	}
+	m := op.Dim()
+	v, w := bufs[0].Reset(m, 1), bufs[1]
+	randUniform(opt.rand, v)
+	v.Mul(complex(1/v.FrobeniusNorm(), 0))
+
+	var radius float64
+	for range opt.iterations {
+		op.Apply(w, v)
+		radius = float64(w.FrobeniusNorm())
+		if radius == 0 {
+			return 0, nil
+		}
+		v.Set([]int{0, 0}, w).Mul(complex(float32(1/radius), 0))
+	}
+	return radius, nil
+}
+
+// Trace estimates the trace of op with the Hutchinson estimator, which averages <z|op|z> over random vectors z of independent entries +1 or -1.
+// It returns the estimate and its standard error, which is NaN for a single vector.
+// Only applications of op are needed, such as for Tr f(H) with the Krylov approximation of f(H) z.
+// See M. F. Hutchinson, A Stochastic Estimator of the Trace of the Influence Matrix for Laplacian Smoothing Splines, Commun. Stat. Simul. Comput. 19, 433 (1990).
+func Trace(op LinearOperator, bufs [2]*tensor.Dense, options ...EstimateOptions) (complex128, float64, error) {
+	opt := NewEstimateOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.numSamples < 1 {
+		return 0, 0, errors.Errorf("%d", opt.numSamples)
+	}
+	randInt := rand.Int64
+	if opt.rand != nil {
+		randInt = opt.rand.Int64
+	}
+	m := op.Dim()
+	z, w := bufs[0].Reset(m, 1), bufs[1]
+
+	samples := make([]complex128, 0, opt.numSamples)
+	for range opt.numSamples {
+		for i := range m {
+			z.SetAt([]int{i, 0}, complex(float32(1-2*(randInt()&1)), 0))
+		}
+		op.Apply(w, z)
+		var s complex128
+		for i := range m {
+			s += complex128(w.At(i, 0)) * complex128(z.At(i, 0))
+		}
+		samples = append(samples, s)
+	}
+
+	var mean complex128
+	for _, s := range samples {
+		mean += s
+	}
+	mean /= complex(float64(len(samples)), 0)
+	stderr := math.NaN()
+	if k := len(samples); k > 1 {
+		var variance float64
+		for _, s := range samples {
+			d := s - mean
+			variance += real(d)*real(d) + imag(d)*imag(d)
+		}
+		stderr = math.Sqrt(variance / float64(k-1) / float64(k))
+	}
+	return mean, stderr, nil
+}
+
+// randUniform fills x with entries uniform in the unit square of the complex plane, drawn from r or the global source if r is nil.
+func randUniform(r *rand.Rand, x *tensor.Dense) *tensor.Dense {
+	if r == nil {
+		return randVec(x)
+	}
+	for i := range x.Shape()[0] {
+		x.SetAt([]int{i, 0}, complex(r.Float32()*2-1, r.Float32()*2-1))
+	}
+	return x
+}
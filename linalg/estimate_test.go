@@ -0,0 +1,85 @@
+package linalg
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestSpectralRadius(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		a   *tensor.Dense
+		opt EstimateOptions
+		tol float64
+	}
+	tests := []testcase{
+		{a: tensor.T2([][]complex64{{1, 0, 0}, {0, -3, 0}, {0, 0, 0.5}}), opt: NewEstimateOptions().Rand(rand.New(rand.NewPCG(1, 1))), tol: 1e-4},
+		{a: hermitian(rand.New(rand.NewPCG(2, 2)), 32), opt: NewEstimateOptions().Iterations(200).Rand(rand.New(rand.NewPCG(3, 3))), tol: 5e-2},
+		{a: tensor.Zeros(4, 4), opt: NewEstimateOptions().Rand(rand.New(rand.NewPCG(4, 4))), tol: 0},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			radius, err := SpectralRadius(MatrixOperator(test.a), [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}, test.opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			m := test.a.Shape()[0]
+			lambda := tensor.Zeros(1)
+			if err := tensor.Eig(lambda, nil, tensor.Zeros(m, m).Set([]int{0, 0}, test.a), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			var want float64
+			for j := range m {
+				want = max(want, float64(abs(lambda.At(j))))
+			}
+			// The estimate is a lower bound.
+			if radius > want*(1+1e-5) || radius < want*(1-test.tol) {
+				t.Fatalf("%f %f", radius, want)
+			}
+		})
+	}
+}
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		a   *tensor.Dense
+		opt EstimateOptions
+	}
+	tests := []testcase{
+		// The estimator is exact for diagonal matrices.
+		{a: tensor.T2([][]complex64{{1, 0, 0}, {0, -3i, 0}, {0, 0, 0.5}}), opt: NewEstimateOptions().Rand(rand.New(rand.NewPCG(1, 1)))},
+		{a: hermitian(rand.New(rand.NewPCG(2, 2)), 32), opt: NewEstimateOptions().NumSamples(64).Rand(rand.New(rand.NewPCG(3, 3)))},
+		{a: randMatrix(rand.New(rand.NewPCG(4, 4)), 16), opt: NewEstimateOptions().NumSamples(64).Rand(rand.New(rand.NewPCG(5, 5)))},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			trace, stderr, err := Trace(MatrixOperator(test.a), [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}, test.opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			var want complex128
+			for j := range test.a.Shape()[0] {
+				want += complex128(test.a.At(j, j))
+			}
+			if cmplx.Abs(trace-want) > 4*stderr+1e-5*max(1, cmplx.Abs(want)) {
+				t.Fatalf("%v %v %f", trace, want, stderr)
+			}
+		})
+	}
+
+	if _, stderr, err := Trace(MatrixOperator(tensor.T2([][]complex64{{1}})), [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}, NewEstimateOptions().NumSamples(1)); err != nil || !math.IsNaN(stderr) {
+		t.Fatalf("%+v %f", err, stderr)
+	}
+	if _, _, err := Trace(MatrixOperator(tensor.T2([][]complex64{{1}})), [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}, NewEstimateOptions().NumSamples(0)); err == nil {
+		t.Fatalf("expected error")
+	}
+}
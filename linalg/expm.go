@@ -0,0 +1,42 @@
+package linalg
+
+import (
+	"math"
+
+	"github.com/fumin/tensor"
+)
+
+const (
+	// expmTaylorOrder is the order of the Taylor series, which is accurate to machine precision for matrices of norm at most 1/2.
+	expmTaylorOrder = 10
+)
+
+// Expm computes the matrix exponential of a square matrix a, and stores the result in dst.
+// It scales a by a power of two such that its norm is at most 1/2, sums the Taylor series, and squares the result back.
+// See Method 3 Scaling and squaring, Nineteen Dubious Ways to Compute the Exponential of a Matrix, Twenty-Five Years Later, Cleve Moler and Charles Van Loan.
+func Expm(dst, a *tensor.Dense, bufs [3]*tensor.Dense) *tensor.Dense {
+	m := a.Shape()[0]
+
+	// Scale a such that its norm is at most 1/2.
+	var s int
+	for norm := a.InfNorm(); norm > 0.5; norm /= 2 {
+		s++
+	}
+	x := bufs[0].Reset(m, m).Set([]int{0, 0}, a).Mul(complex(float32(math.Ldexp(1, -s)), 0))
+
+	// Sum the Taylor series, in which the k-th term is term_{k-1} @ x / k.
+	dst.Eye(m, 0)
+	term, next := bufs[1].Eye(m, 0), bufs[2]
+	for k := 1; k <= expmTaylorOrder; k++ {
+		tensor.MatMul(next, term, x).Mul(complex(1/float32(k), 0))
+		term, next = next, term
+		dst.Add(1, term)
+	}
+
+	// Undo the scaling by repeated squaring.
+	for range s {
+		tensor.MatMul(next, dst, dst)
+		dst.Set([]int{0, 0}, next)
+	}
+	return dst
+}
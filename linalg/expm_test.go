@@ -0,0 +1,66 @@
+package linalg
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestExpm(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		a   *tensor.Dense
+		tol float32
+	}
+	tests := []testcase{
+		{
+			a:   tensor.T2([][]complex64{{0, 0}, {0, 0}}),
+			tol: 1e-6,
+		},
+		{
+			a:   tensor.T2([][]complex64{{1, 0}, {0, -2}}),
+			tol: 1e-5,
+		},
+		{
+			// exp(-i*theta*X) = cos(theta) - i*sin(theta)*X.
+			a:   tensor.T2([][]complex64{{0, -3i}, {-3i, 0}}),
+			tol: 1e-5,
+		},
+		{
+			a:   hermitian(rand.New(rand.NewPCG(6, 6)), 16).Mul(-1i),
+			tol: 1e-4,
+		},
+		{
+			a:   randMatrix(rand.New(rand.NewPCG(7, 7)), 8),
+			tol: 1e-4,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+			e := Expm(tensor.Zeros(1), test.a, bufs)
+
+			// Compare with the eigen decomposition a = v @ diag(lambda) @ v^-1, in which exp(a) @ v = v @ diag(exp(lambda)).
+			m := test.a.Shape()[0]
+			lambda, v := tensor.Zeros(1), tensor.Zeros(1)
+			if err := tensor.Eig(lambda, v, tensor.Zeros(m, m).Set([]int{0, 0}, test.a), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			ev := tensor.MatMul(tensor.Zeros(1), e, v)
+			for j := range m {
+				x := v.Slice([][2]int{{0, m}, {j, j + 1}})
+				l := lambda.At(j)
+				expl := complex(float32(math.Exp(float64(real(l)))), 0) * complex(float32(math.Cos(float64(imag(l)))), float32(math.Sin(float64(imag(l)))))
+				want := tensor.Zeros(m, 1).Add(expl, x)
+				if err := ev.Slice([][2]int{{0, m}, {j, j + 1}}).Equal(want, test.tol*max(1, abs(expl))); err != nil {
+					t.Fatalf("%d %+v", j, err)
+				}
+			}
+		})
+	}
+}
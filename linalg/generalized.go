@@ -0,0 +1,166 @@
+package linalg
+
+import (
+	"math"
+	"slices"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// Cholesky returns the lower triangular matrix l of the factorization b = l @ l.H of the Hermitian positive definite matrix b, whose diagonal is real and positive.
+// It returns an error if b is not positive definite to working precision, such as the overlap matrix of linearly dependent states.
+// See Algorithm 4.2.2, Section 4.2.5 The Cholesky Factorization, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan.
+func Cholesky(l, b *tensor.Dense) (*tensor.Dense, error) {
+	ls, err := cholesky(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	m := len(ls)
+	l.Reset(m, m)
+	for i := range m {
+		for j := range i + 1 {
+			l.SetAt([]int{i, j}, ls[i][j])
+		}
+	}
+	return l, nil
+}
+
+// cholesky returns the rows of the Cholesky factor of b, see Cholesky.
+func cholesky(b *tensor.Dense) ([][]complex64, error) {
+	if s := b.Shape(); len(s) != 2 || s[0] != s[1] {
+		return nil, errors.Errorf("%#v", s)
+	}
+	m := b.Shape()[0]
+	bNorm := b.FrobeniusNorm()
+	l := b.ToSlice2()
+	// lc[j] is the conjugate of the row l[j][:j], so that the inner products are the kernel dotu.
+	lc := make([][]complex64, m)
+	for i := range m {
+		for j := range i {
+			l[i][j] = (l[i][j] - dotu(l[i][:j], lc[j])) / l[j][j]
+		}
+		lc[i] = make([]complex64, i)
+		for j := range i {
+			lc[i][j] = conj(l[i][j])
+		}
+		d := real(l[i][i] - dotu(l[i][:i], lc[i]))
+		if d <= epsilon*bNorm {
+			return nil, errors.Errorf("not positive definite %d %f", i, d)
+		}
+		l[i][i] = complex(float32(math.Sqrt(float64(d))), 0)
+		clear(l[i][i+1:])
+	}
+	return l, nil
+}
+
+// GeneralizedEig solves the generalized eigenvalue problem a @ x = lambda * b @ x, where b is Hermitian positive definite,
+// such as the overlap matrix of a non-orthogonal basis of variational states.
+// With the Cholesky factorization b = l @ l.H, the problem reduces to the ordinary eigenvalue problem of c = l^-1 @ a @ l^-H, whose eigenvectors y give x = l^-H @ y.
+// The eigenvalues are sorted by their real parts in ascending order, and are real if a is Hermitian, in which case c is Hermitian too.
+// The eigenvectors are normalized such that x.H @ b @ x = 1.
+// The reduction is stable only if b is well conditioned, since the rounding errors of c grow with the condition number of b.
+// See Section 8.7.2 The Symmetric-Definite Problem, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan.
+func GeneralizedEig(eigvals, eigvecs, a, b *tensor.Dense, bufs [4]*tensor.Dense) error {
+	as, bs := a.Shape(), b.Shape()
+	if len(as) != 2 || as[0] != as[1] || !slices.Equal(as, bs) {
+		return errors.Errorf("%#v %#v", as, bs)
+	}
+	m := as[0]
+	l, err := cholesky(b)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	// w = l^-1 @ a, and c = (l^-1 @ w.H).H = l^-1 @ a @ l^-H.
+	w := a.ToSlice2()
+	forwardSubstitute(l, w)
+	wh := make([][]complex64, m)
+	for i := range m {
+		wh[i] = make([]complex64, m)
+		for j := range m {
+			wh[i][j] = conj(w[j][i])
+		}
+	}
+	forwardSubstitute(l, wh)
+	hermitian := isHermitian(a)
+	c := bufs[0].Reset(m, m)
+	for i := range m {
+		for j := range m {
+			v := conj(wh[j][i])
+			if hermitian {
+				// Average with the conjugate transpose to remove the rounding errors that break the symmetry.
+				v = (v + wh[i][j]) / 2
+			}
+			c.SetAt([]int{i, j}, v)
+		}
+	}
+
+	if err := tensor.Eig(eigvals, eigvecs, c, [3]*tensor.Dense(bufs[1:])); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if hermitian {
+		realEigvals(eigvals)
+	}
+
+	// x = l^-H @ y, where y is normalized such that x.H @ b @ x = y.H @ y = 1.
+	ys := eigvecs.ToSlice2()
+	for j := range m {
+		var n2 float64
+		for i := range m {
+			n2 += float64(real(ys[i][j])*real(ys[i][j]) + imag(ys[i][j])*imag(ys[i][j]))
+		}
+		s := complex(float32(1/math.Sqrt(n2)), 0)
+		for i := range m {
+			ys[i][j] *= s
+		}
+	}
+	backSubstituteH(l, ys)
+	for i := range m {
+		for j := range m {
+			eigvecs.SetAt([]int{i, j}, ys[i][j])
+		}
+	}
+	return nil
+}
+
+// forwardSubstitute solves l @ y = x for the lower triangular l, overwriting the rows of x with y.
+func forwardSubstitute(l, x [][]complex64) {
+	for i := range l {
+		for k := range i {
+			axpy(-l[i][k], x[k], x[i])
+		}
+		d := 1 / l[i][i]
+		for j := range x[i] {
+			x[i][j] *= d
+		}
+	}
+}
+
+// backSubstituteH solves l.H @ y = x for the lower triangular l, overwriting the rows of x with y.
+func backSubstituteH(l, x [][]complex64) {
+	for i := len(l) - 1; i >= 0; i-- {
+		// Row i of l.H is the conjugate of column i of l.
+		for k := i + 1; k < len(l); k++ {
+			axpy(-conj(l[k][i]), x[k], x[i])
+		}
+		d := 1 / conj(l[i][i])
+		for j := range x[i] {
+			x[i][j] *= d
+		}
+	}
+}
+
+// isHermitian returns whether a equals its conjugate transpose up to rounding errors.
+func isHermitian(a *tensor.Dense) bool {
+	m := a.Shape()[0]
+	tol := epsilon * max(1, a.FrobeniusNorm())
+	for i := range m {
+		for j := range i + 1 {
+			if abs(a.At(i, j)-conj(a.At(j, i))) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package linalg
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestGeneralizedEig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		m         int
+		hermitian bool
+		// identity is whether b is the identity, which reduces to Eig.
+		identity bool
+	}{
+		{m: 8, hermitian: true},
+		{m: 20, hermitian: true},
+		{m: 12, hermitian: false},
+		{m: 10, hermitian: true, identity: true},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			r := rand.New(rand.NewPCG(uint64(i), uint64(i)))
+			a := randMatrix(r, test.m)
+			if test.hermitian {
+				a = hermitian(r, test.m)
+			}
+			// b = c.H @ c + I is Hermitian positive definite.
+			b := tensor.Zeros(1).Eye(test.m, 0)
+			if !test.identity {
+				c := randMatrix(r, test.m)
+				b = tensor.MatMul(tensor.Zeros(1), c.H(), c).Add(1, b)
+			}
+			var bufs [4]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+			eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+			if err := GeneralizedEig(eigvals, eigvecs, a, b, bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// a @ x = b @ x @ diag(eigvals).
+			ax := tensor.MatMul(tensor.Zeros(1), a, eigvecs)
+			bx := tensor.MatMul(tensor.Zeros(1), b, eigvecs)
+			for j := range test.m {
+				if j > 0 && real(eigvals.At(j-1)) > real(eigvals.At(j)) {
+					t.Fatalf("%d %v %v", j, eigvals.At(j-1), eigvals.At(j))
+				}
+				if test.hermitian && imag(eigvals.At(j)) != 0 {
+					t.Fatalf("%d %v", j, eigvals.At(j))
+				}
+				col := [][2]int{{0, test.m}, {j, j + 1}}
+				lbx := resetCopy(bx.Slice(col)).Mul(eigvals.At(j))
+				if err := ax.Slice(col).Equal(lbx, 1e-3*max(1, abs(eigvals.At(j)))); err != nil {
+					t.Fatalf("%d %+v", j, err)
+				}
+			}
+			// The eigenvectors of a Hermitian a are b-orthonormal.
+			if test.hermitian {
+				xbx := tensor.MatMul(tensor.Zeros(1), eigvecs.H(), bx)
+				if err := xbx.Equal(tensor.Zeros(1).Eye(test.m, 0), 1e-3); err != nil {
+					t.Fatalf("%+v", err)
+				}
+			}
+			if test.identity {
+				want := tensor.Zeros(1)
+				if err := tensor.Eig(want, nil, resetCopy(a), [3]*tensor.Dense(bufs[1:])); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				realEigvals(want)
+				if err := eigvals.Equal(want, 1e-4); err != nil {
+					t.Fatalf("%+v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestGeneralizedEigNotPositiveDefinite(t *testing.T) {
+	t.Parallel()
+	a := tensor.T2([][]complex64{{1, 0}, {0, 2}})
+	var bufs [4]*tensor.Dense
+	for j := range bufs {
+		bufs[j] = tensor.Zeros(1)
+	}
+	// The overlap matrix of two identical states is singular.
+	b := tensor.T2([][]complex64{{1, 1}, {1, 1}})
+	if err := GeneralizedEig(tensor.Zeros(1), tensor.Zeros(1), a, b, bufs); err == nil {
+		t.Fatalf("expected error")
+	}
+	b = tensor.T2([][]complex64{{1, 0}, {0, -1}})
+	if err := GeneralizedEig(tensor.Zeros(1), tensor.Zeros(1), a, b, bufs); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestCholesky(t *testing.T) {
+	t.Parallel()
+	m := 9
+	c := randMatrix(rand.New(rand.NewPCG(3, 3)), m)
+	b := tensor.MatMul(tensor.Zeros(1), c.H(), c).Add(1, tensor.Zeros(1).Eye(m, 0))
+	l, err := Cholesky(tensor.Zeros(1), b)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for i := range m {
+		if imag(l.At(i, i)) != 0 || real(l.At(i, i)) <= 0 {
+			t.Fatalf("%d %v", i, l.At(i, i))
+		}
+		for j := i + 1; j < m; j++ {
+			if l.At(i, j) != 0 {
+				t.Fatalf("%d %d %v", i, j, l.At(i, j))
+			}
+		}
+	}
+	if err := tensor.MatMul(tensor.Zeros(1), l, l.H()).Equal(b, 1e-4); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package linalg
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/fumin/tensor"
+)
+
+// These are the building blocks of the shifted QR iteration of an upper Hessenberg matrix, which tensor.Eig runs internally,
+// for eigensolvers that need control over the iteration, such as the QZ iteration of the generalized eigenvalue problems whose b is not positive definite,
+// or the symmetric QR iteration of the projections of the Krylov solvers.
+// A QR iteration repeats ChaseBulgeHessenberg with WilkinsonShift on the trailing unreduced block of h, and Deflate splits h into smaller blocks as they converge.
+// See Section 7.5 The Practical QR Algorithm, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan.
+
+// WilkinsonShift returns the eigenvalue of the trailing 2×2 block of the upper Hessenberg matrix h that is closer to h[m-1, m-1],
+// with which the QR iteration converges quadratically, and cubically if h is Hermitian.
+// Unlike the Rayleigh quotient shift h[m-1, m-1], it does not stall on the real matrices whose trailing block has complex eigenvalues.
+// See Section 8.3.5 The Symmetric QR Algorithm, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan.
+func WilkinsonShift(h *tensor.Dense) complex64 {
+	m := h.Shape()[0]
+	if m == 1 {
+		return h.At(0, 0)
+	}
+	a, b := complex128(h.At(m-2, m-2)), complex128(h.At(m-2, m-1))
+	c, d := complex128(h.At(m-1, m-2)), complex128(h.At(m-1, m-1))
+	// The eigenvalues are d + delta ± sqrt(delta^2 + bc), where delta = (a-d)/2, which avoids the cancellation of (a+d)/2 when a ≈ -d.
+	delta := (a - d) / 2
+	r := cmplx.Sqrt(delta*delta + b*c)
+	lambda0, lambda1 := d+delta-r, d+delta+r
+	if cmplx.Abs(lambda0-d) <= cmplx.Abs(lambda1-d) {
+		return complex64(lambda0)
+	}
+	return complex64(lambda1)
+}
+
+// ChaseBulgeHessenberg applies a step of the QR iteration h - shift = z @ r, h = r @ z + shift = z.H @ h @ z, to the upper Hessenberg matrix h,
+// without forming h - shift, which loses the small eigenvalues to cancellation if shift is large.
+// The first Givens rotation is that of the QR factorization of h - shift, whose similarity transformation creates a bulge below the subdiagonal,
+// which the subsequent rotations chase down and off the matrix, such that h stays upper Hessenberg,
+// and by the implicit Q theorem is the same as that of the explicit QR step.
+// If q is not nil, it is multiplied on the right by z, which accumulates the Schur vectors.
+// Each step costs O(m^2) for an m×m matrix, instead of the O(m^3) of a QR factorization.
+// See Algorithm 7.5.1, Section 7.5.2 The Hessenberg QR Step, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan.
+func ChaseBulgeHessenberg(h, q *tensor.Dense, shift complex64) {
+	m := h.Shape()[0]
+	if m < 2 {
+		return
+	}
+	for k := range m - 1 {
+		var g givens
+		if k == 0 {
+			g = newGivens(h.At(0, 0)-shift, h.At(1, 0))
+		} else {
+			g = newGivens(h.At(k, k-1), h.At(k+1, k-1))
+		}
+
+		// h = g @ h @ g.H, whose rows k and k+1 are nonzero from column k-1 on, and the columns k and k+1 down to row k+2, which is the bulge.
+		g.applyLeft(h, k, max(k-1, 0))
+		if k > 0 {
+			h.SetAt([]int{k + 1, k - 1}, 0)
+		}
+		g.applyRight(h, k, min(k+3, m))
+		if q != nil {
+			g.applyRight(q, k, q.Shape()[0])
+		}
+	}
+}
+
+// Deflate sets to zero the subdiagonal entries of the upper Hessenberg matrix h that are negligible,
+// which splits h into unreduced blocks whose eigenvalues are those of h, and can be iterated independently.
+// An entry h[i, i-1] is negligible if it is below the machine precision relative to its neighboring diagonal entries, or below the safe minimum,
+// as in the LAPACK routine CLAHQR.
+// See Section 7.5.1 Deflation, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan.
+func Deflate(h *tensor.Dense) {
+	const (
+		ulp = 2 * epsilon
+		// Safe minimum such that 1/safmin does not overflow.
+		safmin = 0x1p-126
+	)
+	m := h.Shape()[0]
+	smlnum := safmin * (float32(m) / ulp)
+	for i := 1; i < m; i++ {
+		sd := abs(h.At(i, i-1))
+		if sd < smlnum || sd < ulp*(abs(h.At(i, i))+abs(h.At(i-1, i-1))) {
+			h.SetAt([]int{i, i - 1}, 0)
+		}
+	}
+}
+
+// givens is the rotation [[c, s], [-conj(s), c]] of the rows or columns k and k+1, where c is real.
+type givens struct {
+	c float64
+	s complex128
+}
+
+// newGivens returns the rotation that sets g to zero in the vector (f, g).
+// See Section 5.1.13 Complex Givens Rotations, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan.
+func newGivens(f, g complex64) givens {
+	ff, gg := complex128(f), complex128(g)
+	fAbs, gAbs := cmplx.Abs(ff), cmplx.Abs(gg)
+	switch {
+	case gAbs == 0:
+		return givens{c: 1}
+	case fAbs == 0:
+		return givens{c: 0, s: cmplx.Conj(gg) / complex(gAbs, 0)}
+	}
+	r := math.Hypot(fAbs, gAbs)
+	return givens{c: fAbs / r, s: ff / complex(fAbs, 0) * cmplx.Conj(gg) / complex(r, 0)}
+}
+
+// applyLeft multiplies the rows k and k+1 of a on the left by the rotation, in the columns from j0 on.
+func (g givens) applyLeft(a *tensor.Dense, k, j0 int) {
+	c := complex(g.c, 0)
+	for j := j0; j < a.Shape()[1]; j++ {
+		x, y := complex128(a.At(k, j)), complex128(a.At(k+1, j))
+		a.SetAt([]int{k, j}, complex64(c*x+g.s*y))
+		a.SetAt([]int{k + 1, j}, complex64(-cmplx.Conj(g.s)*x+c*y))
+	}
+}
+
+// applyRight multiplies the columns k and k+1 of a on the right by the conjugate transpose of the rotation, in the rows before i1.
+func (g givens) applyRight(a *tensor.Dense, k, i1 int) {
+	c := complex(g.c, 0)
+	for i := range i1 {
+		x, y := complex128(a.At(i, k)), complex128(a.At(i, k+1))
+		a.SetAt([]int{i, k}, complex64(c*x+cmplx.Conj(g.s)*y))
+		a.SetAt([]int{i, k + 1}, complex64(-g.s*x+c*y))
+	}
+}
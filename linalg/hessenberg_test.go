@@ -0,0 +1,209 @@
+package linalg
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+func TestWilkinsonShift(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		h    [][]complex64
+		want complex64
+	}{
+		// The eigenvalues are (5±sqrt(33))/2.
+		{h: [][]complex64{{1, 2}, {3, 4}}, want: 5.3722813},
+		// The eigenvalues of the trailing block are 2±sqrt(5).
+		{h: [][]complex64{{5, 1, 0}, {1, 3, 2}, {0, 2, 1}}, want: -0.23606798},
+		// A real matrix with complex eigenvalues ±i, which are equally close to h[1, 1].
+		{h: [][]complex64{{0, 1}, {-1, 0}}, want: -1i},
+		// A nilpotent block, whose eigenvalues are both zero.
+		{h: [][]complex64{{7, 3, 1}, {2, 1i, 1}, {0, 1, -1i}}, want: 0},
+		{h: [][]complex64{{2 + 1i}}, want: 2 + 1i},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if got := WilkinsonShift(tensor.T2(test.h)); abs(got-test.want) > 1e-6 {
+				t.Fatalf("%v %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestChaseBulgeHessenberg(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		m     int
+		shift complex64
+	}{
+		{m: 2, shift: 0.3},
+		{m: 6, shift: 0},
+		{m: 9, shift: 1 - 0.5i},
+		{m: 16, shift: 20},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			h := randHessenberg(rand.New(rand.NewPCG(uint64(i), uint64(i))), test.m)
+			h0 := resetCopy(h)
+			q := tensor.Zeros(1).Eye(test.m, 0)
+			ChaseBulgeHessenberg(h, q, test.shift)
+
+			for r := range test.m {
+				for c := range r - 1 {
+					if h.At(r, c) != 0 {
+						t.Fatalf("%d %d %v", r, c, h.At(r, c))
+					}
+				}
+			}
+			eye := tensor.Zeros(1).Eye(test.m, 0)
+			if err := tensor.MatMul(tensor.Zeros(1), q.H(), q).Equal(eye, 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			qhq := tensor.MatMul(tensor.Zeros(1), q.H(), tensor.MatMul(tensor.Zeros(1), h0, q))
+			if err := qhq.Equal(h, 1e-4*max(1, abs(test.shift))); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// The explicit QR step h0 - shift = z @ r, r @ z + shift, which equals h up to a diagonal unitary similarity, since the QR factorization is unique up to the phases of r.
+			a := resetCopy(h0).Add(-test.shift, eye)
+			z := tensor.Zeros(1)
+			rr := resetCopy(QR(z, a, [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}))
+			explicit := tensor.MatMul(tensor.Zeros(1), rr, z).Add(test.shift, eye)
+			for r := range test.m {
+				for c := range test.m {
+					if d := abs(h.At(r, c)) - abs(explicit.At(r, c)); math.Abs(float64(d)) > 1e-3*float64(max(1, abs(test.shift))) {
+						t.Fatalf("%d %d %v %v", r, c, h.At(r, c), explicit.At(r, c))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDeflate(t *testing.T) {
+	t.Parallel()
+	h := tensor.T2([][]complex64{
+		{1, 2, 3, 4},
+		{1e-9, 4, 5, 6},
+		{0, 1e-3, 6, 7},
+		{0, 0, 1e-33, 0},
+	})
+	Deflate(h)
+	want := tensor.T2([][]complex64{
+		{1, 2, 3, 4},
+		{0, 4, 5, 6},
+		{0, 1e-3, 6, 7},
+		{0, 0, 0, 0},
+	})
+	if err := h.Equal(want, 0); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// A subdiagonal entry below the safe minimum is negligible even if its neighboring diagonal entries are zero.
+	h = tensor.T2([][]complex64{{0, 1}, {1e-38, 0}})
+	Deflate(h)
+	if h.At(1, 0) != 0 {
+		t.Fatalf("%v", h.At(1, 0))
+	}
+}
+
+// TestQRIteration checks the QR iteration built from WilkinsonShift, ChaseBulgeHessenberg and Deflate against the exact eigenvalues of matrices with closed form spectra,
+// which a backward stable solver such as LAPACK's CHSEQR reproduces up to rounding errors.
+func TestQRIteration(t *testing.T) {
+	t.Parallel()
+	toeplitz := tensor.Zeros(8, 8)
+	toeplitzEigvals := make([]complex64, 8)
+	for i := range 8 {
+		toeplitz.SetAt([]int{i, i}, 2)
+		if i > 0 {
+			toeplitz.SetAt([]int{i, i - 1}, -1)
+			toeplitz.SetAt([]int{i - 1, i}, -1)
+		}
+		toeplitzEigvals[i] = complex(float32(2-2*math.Cos(float64(i+1)*math.Pi/9)), 0)
+	}
+	tests := []struct {
+		h    *tensor.Dense
+		want []complex64
+	}{
+		// The tridiagonal Toeplitz matrix, whose eigenvalues are 2 - 2cos(kπ/9).
+		{h: toeplitz, want: toeplitzEigvals},
+		// The companion matrix of (x-1)(x-2)(x-3)(x-4).
+		{h: tensor.T2([][]complex64{{10, -35, 50, -24}, {1, 0, 0, 0}, {0, 1, 0, 0}, {0, 0, 1, 0}}), want: []complex64{1, 2, 3, 4}},
+		// The companion matrix of (x-2)(x^2+1), a real non-normal matrix with complex eigenvalues.
+		{h: tensor.T2([][]complex64{{2, -1, 2}, {1, 0, 0}, {0, 1, 0}}), want: []complex64{-1i, 1i, 2}},
+		// The companion matrix of (x-i)(x+1)(x-2i), whose coefficients are complex.
+		{h: tensor.T2([][]complex64{{-1 + 3i, 2 + 3i, 2}, {1, 0, 0}, {0, 1, 0}}), want: []complex64{-1, 1i, 2i}},
+		// An upper triangular matrix is already deflated.
+		{h: tensor.T2([][]complex64{{3, 1, 1}, {0, -1i, 1}, {0, 0, 1}}), want: []complex64{-1i, 1, 3}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			got, err := hessenbergEigvals(test.h)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			slices.SortFunc(got, compareComplex)
+			want := slices.SortedFunc(slices.Values(test.want), compareComplex)
+			for j := range want {
+				if abs(got[j]-want[j]) > 1e-4*max(1, abs(want[j])) {
+					t.Fatalf("%d %v %v", j, got, want)
+				}
+			}
+		})
+	}
+}
+
+// hessenbergEigvals returns the eigenvalues of the upper Hessenberg matrix h by the QR iteration on its trailing unreduced block.
+// Since only the eigenvalues are needed, the blocks above the unreduced one are not updated.
+func hessenbergEigvals(h *tensor.Dense) ([]complex64, error) {
+	m := h.Shape()[0]
+	var iterations int
+	for end := m; end > 0; {
+		Deflate(h)
+		if end == 1 || h.At(end-1, end-2) == 0 {
+			end--
+			continue
+		}
+		start := end - 1
+		for start > 0 && h.At(start, start-1) != 0 {
+			start--
+		}
+		if iterations++; iterations > 30*m {
+			return nil, errors.Errorf("not converged %d %d", start, end)
+		}
+		block := h.Slice([][2]int{{start, end}, {start, end}})
+		ChaseBulgeHessenberg(block, nil, WilkinsonShift(block))
+	}
+	eigvals := make([]complex64, m)
+	for i := range m {
+		eigvals[i] = h.At(i, i)
+	}
+	return eigvals, nil
+}
+
+func compareComplex(x, y complex64) int {
+	if c := cmp.Compare(real(x), real(y)); c != 0 {
+		return c
+	}
+	return cmp.Compare(imag(x), imag(y))
+}
+
+func randHessenberg(r *rand.Rand, m int) *tensor.Dense {
+	h := randMatrix(r, m)
+	for i := range m {
+		for j := range i - 1 {
+			h.SetAt([]int{i, j}, 0)
+		}
+	}
+	return h
+}
@@ -0,0 +1,47 @@
+package linalg
+
+// The kernels below are the level 1 BLAS routines on complex64 slices, which are the inner loops of the LU factorization and its triangular solves.
+// Their loops are unrolled by four with two independent accumulators, which breaks the dependency chain of the additions,
+// and index the rows directly instead of through the digits of tensor.Dense.At.
+// See Section 1.5 Vectorization and Locality, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan.
+
+// dotu returns sum_i x[i] * y[i], where len(y) >= len(x).
+func dotu(x, y []complex64) complex64 {
+	y = y[:len(x)]
+	var re0, im0, re1, im1 float32
+	i := 0
+	for ; i+4 <= len(x); i += 4 {
+		x0, y0, x1, y1 := x[i], y[i], x[i+1], y[i+1]
+		re0 += real(x0)*real(y0) - imag(x0)*imag(y0)
+		im0 += real(x0)*imag(y0) + imag(x0)*real(y0)
+		re1 += real(x1)*real(y1) - imag(x1)*imag(y1)
+		im1 += real(x1)*imag(y1) + imag(x1)*real(y1)
+		x2, y2, x3, y3 := x[i+2], y[i+2], x[i+3], y[i+3]
+		re0 += real(x2)*real(y2) - imag(x2)*imag(y2)
+		im0 += real(x2)*imag(y2) + imag(x2)*real(y2)
+		re1 += real(x3)*real(y3) - imag(x3)*imag(y3)
+		im1 += real(x3)*imag(y3) + imag(x3)*real(y3)
+	}
+	for ; i < len(x); i++ {
+		re0 += real(x[i])*real(y[i]) - imag(x[i])*imag(y[i])
+		im0 += real(x[i])*imag(y[i]) + imag(x[i])*real(y[i])
+	}
+	return complex(re0+re1, im0+im1)
+}
+
+// axpy sets y[i] += a * x[i], where len(y) >= len(x).
+func axpy(a complex64, x, y []complex64) {
+	y = y[:len(x)]
+	ar, ai := real(a), imag(a)
+	i := 0
+	for ; i+4 <= len(x); i += 4 {
+		x0, x1, x2, x3 := x[i], x[i+1], x[i+2], x[i+3]
+		y[i] += complex(ar*real(x0)-ai*imag(x0), ar*imag(x0)+ai*real(x0))
+		y[i+1] += complex(ar*real(x1)-ai*imag(x1), ar*imag(x1)+ai*real(x1))
+		y[i+2] += complex(ar*real(x2)-ai*imag(x2), ar*imag(x2)+ai*real(x2))
+		y[i+3] += complex(ar*real(x3)-ai*imag(x3), ar*imag(x3)+ai*real(x3))
+	}
+	for ; i < len(x); i++ {
+		y[i] += complex(ar*real(x[i])-ai*imag(x[i]), ar*imag(x[i])+ai*real(x[i]))
+	}
+}
@@ -0,0 +1,45 @@
+package linalg
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestKernels(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(7, 7))
+	randSlice := func(n int) []complex64 {
+		x := make([]complex64, n)
+		for i := range x {
+			x[i] = complex(r.Float32()*2-1, r.Float32()*2-1)
+		}
+		return x
+	}
+	// The lengths cover the unrolled loops and their remainders.
+	for n := range 11 {
+		t.Run(fmt.Sprintf("%d", n), func(t *testing.T) {
+			x, y, a := randSlice(n), randSlice(n+1), complex64(complex(0.3, -0.7))
+
+			var want complex64
+			for i := range x {
+				want += x[i] * y[i]
+			}
+			if got := dotu(x, y); abs(got-want) > 1e-5 {
+				t.Fatalf("%v %v", got, want)
+			}
+
+			wantY := make([]complex64, len(y))
+			copy(wantY, y)
+			for i := range x {
+				wantY[i] += a * x[i]
+			}
+			axpy(a, x, y)
+			for i := range y {
+				if abs(y[i]-wantY[i]) > 1e-6 {
+					t.Fatalf("%d %v %v", i, y, wantY)
+				}
+			}
+		})
+	}
+}
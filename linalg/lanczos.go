@@ -0,0 +1,95 @@
+package linalg
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"time"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// LanczosOperator is like ArnoldiOperator, but assumes that op is Hermitian, and finds the k smallest eigenvalues, which are real.
+// The Krylov basis is built with the three-term recurrence of the Lanczos iteration,
+// in which the projected matrix is Hermitian and tridiagonal, except for the couplings to the Ritz vectors kept in restarts.
+// The Krylov basis is fully reorthogonalized, against the loss of orthogonality in floating point arithmetic.
+// For a Hermitian operator, restarting the Krylov-Schur decomposition is equivalent to the thick restart of the Lanczos iteration.
+// See K. Wu and H. Simon, Thick-Restart Lanczos Method for Large Symmetric Eigenvalue Problems, SIAM J. Matrix Anal. Appl. 22, 602 (2000).
+func LanczosOperator(eigvals, eigvecs *tensor.Dense, op LinearOperator, k int, bufs [7]*tensor.Dense, options ...ArnoldiOptions) error {
+	opt := NewArnoldiOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.shiftInvert {
+		return errors.Errorf("shift-invert needs a matrix")
+	}
+	opt.lanczos = true
+
+	order := func(x, y complex64) int { return cmp.Compare(real(x), real(y)) }
+	if err := arnoldi(eigvals, eigvecs, op, order, k, bufs, opt); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// expandLanczos is like expand, but computes the projected matrix h with the Lanczos recurrence.
+// Since h is Hermitian, column i of h above the diagonal is the conjugate of row i, which is already known,
+// and only the diagonal element and the norm of the residual are computed.
+func expandLanczos(op LinearOperator, v, h *tensor.Dense, p, n int, prof *ArnoldiProfile, r *rand.Rand, bufs [3]*tensor.Dense) error {
+	m := v.Shape()[0]
+	for i := p; i < n; i++ {
+		t0 := prof.clock()
+		vi := v.Slice([][2]int{{0, m}, {i, i + 1}})
+		f := op.Apply(bufs[0], vi)
+		t1 := prof.clock()
+
+		// f -= v[:, :i] @ h[:i, i], where h[:i, i] is the conjugate of h[i, :i].
+		for j := range i {
+			h.SetAt([]int{j, i}, conj(h.At(i, j)))
+		}
+		if i > 0 {
+			f.Add(-1, tensor.MatMul(bufs[1], v.Slice([][2]int{{0, m}, {0, i}}), h.Slice([][2]int{{0, i}, {i, i + 1}})))
+		}
+		alpha := real(tensor.MatMul(bufs[1], vi.H(), f).At(0, 0))
+		h.SetAt([]int{i, i}, complex(alpha, 0))
+		f.Add(complex(-alpha, 0), vi)
+
+		// The coefficients of the reorthogonalization are rounding errors, and are discarded to keep h Hermitian.
+		q := v.Slice([][2]int{{0, m}, {0, i + 1}})
+		fNorm, err := orthogonalize(f, nil, q, [2]*tensor.Dense(bufs[1:]))
+		if prof != nil {
+			prof.Apply += t1.Sub(t0)
+			prof.Applications++
+			prof.Orthogonalize += time.Since(t1)
+		}
+
+		vi1 := v.Slice([][2]int{{0, m}, {i + 1, i + 2}})
+		if err == nil && fNorm >= epsilon {
+			h.SetAt([]int{i + 1, i}, complex(fNorm, 0))
+			vi1.Set([]int{0, 0}, f).Mul(complex(1/fNorm, 0))
+			continue
+		}
+
+		// An invariant subspace is found, continue with a random orthogonal vector.
+		h.SetAt([]int{i + 1, i}, 0)
+		if i+1 >= m {
+			vi1.Mul(0)
+			continue
+		}
+		if err := randOrthogonal(r, vi1, q, [2]*tensor.Dense(bufs[1:])); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return nil
+}
+
+// realEigvals discards the imaginary parts of the eigenvalues of a Hermitian matrix, which are rounding errors.
+func realEigvals(eigvals *tensor.Dense) {
+	for i := range eigvals.Shape()[0] {
+		eigvals.SetAt([]int{i}, complex(real(eigvals.At(i)), 0))
+	}
+}
+
+func conj(c complex64) complex64 {
+	return complex(real(c), -imag(c))
+}
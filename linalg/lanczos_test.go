@@ -0,0 +1,119 @@
+package linalg
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestLanczos(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		op  LinearOperator
+		k   int
+		opt ArnoldiOptions
+	}
+	tests := []testcase{
+		{op: MatrixOperator(hermitian(rand.New(rand.NewPCG(0, 0)), 64)), k: 6, opt: NewArnoldiOptions().KrylovSpaceDim(14).MaxIterations(256)},
+		{op: MatrixOperator(hermitian(rand.New(rand.NewPCG(1, 1)), 8)), k: 8, opt: NewArnoldiOptions()},
+		{op: MatrixOperator(tensor.T2([][]complex64{{-2, 0, 0, 0}, {0, -3, -4, 0}, {0, -4, -9, 0}, {0, 0, 0, 5}})), k: 2, opt: NewArnoldiOptions().KrylovSpaceDim(3)},
+		{op: laplacian(200), k: 3, opt: NewArnoldiOptions().KrylovSpaceDim(40).MaxIterations(512).Tol(1e-5)},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			solvers := []func(eigvals, eigvecs *tensor.Dense, op LinearOperator, k int, bufs [7]*tensor.Dense, options ...ArnoldiOptions) error{
+				LanczosOperator, DavidsonOperator, SelectiveLanczosOperator,
+			}
+			for j, solve := range solvers {
+				var bufs [7]*tensor.Dense
+				for i := range len(bufs) {
+					bufs[i] = tensor.Zeros(1)
+				}
+				eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+				if err := solve(eigvals, eigvecs, test.op, test.k, bufs, test.opt); err != nil {
+					t.Fatalf("%d %+v", j, err)
+				}
+				want := tensor.Zeros(1)
+				if err := ArnoldiOperator(want, tensor.Zeros(1), test.op, test.k, bufs, test.opt); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if err := eigvals.Equal(want, 1e-4); err != nil {
+					t.Fatalf("%d %+v %v %v", j, err, eigvals.ToSlice1(), want.ToSlice1())
+				}
+
+				m := test.op.Dim()
+				for l := range test.k {
+					if imag(eigvals.At(l)) != 0 {
+						t.Fatalf("%d %d %v", j, l, eigvals.At(l))
+					}
+					x := eigvecs.Slice([][2]int{{0, m}, {l, l + 1}})
+					r := test.op.Apply(tensor.Zeros(1), x).Add(-eigvals.At(l), x)
+					if rn := r.FrobeniusNorm(); rn > 1e-3*max(1, abs(eigvals.At(l))) {
+						t.Fatalf("%d %d %v %f", j, l, eigvals.At(l), rn)
+					}
+				}
+			}
+		})
+	}
+}
+
+// diagonalLaplacian is the laplacian shifted by a diagonal, which is known to the Davidson preconditioner.
+type diagonalLaplacian struct {
+	laplacian
+	d []complex64
+}
+
+func (op diagonalLaplacian) Apply(dst, src *tensor.Dense) *tensor.Dense {
+	op.laplacian.Apply(dst, src)
+	for i, d := range op.d {
+		dst.SetAt([]int{i, 0}, dst.At(i, 0)+d*src.At(i, 0))
+	}
+	return dst
+}
+
+func (op diagonalLaplacian) Diagonal(dst *tensor.Dense) *tensor.Dense {
+	dst.Reset(len(op.d), 1)
+	for i, d := range op.d {
+		dst.SetAt([]int{i, 0}, 2+d)
+	}
+	return dst
+}
+
+func TestDavidsonPreconditioner(t *testing.T) {
+	t.Parallel()
+	n, k := 200, 2
+	op := diagonalLaplacian{laplacian: laplacian(n), d: make([]complex64, n)}
+	for i := range n {
+		op.d[i] = complex(float32(i), 0)
+	}
+	opt := NewArnoldiOptions().KrylovSpaceDim(20).MaxIterations(512).Tol(1e-5)
+	applications := make([]int, 2)
+	for j, o := range []LinearOperator{op, struct{ LinearOperator }{op}} {
+		var bufs [7]*tensor.Dense
+		for i := range len(bufs) {
+			bufs[i] = tensor.Zeros(1)
+		}
+		var prof ArnoldiProfile
+		eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+		if err := DavidsonOperator(eigvals, eigvecs, o, k, bufs, opt.Profile(&prof)); err != nil {
+			t.Fatalf("%d %+v", j, err)
+		}
+		want := tensor.Zeros(1)
+		if err := LanczosOperator(want, tensor.Zeros(1), op, k, bufs, opt); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if err := eigvals.Equal(want, 1e-4); err != nil {
+			t.Fatalf("%d %+v %v %v", j, err, eigvals.ToSlice1(), want.ToSlice1())
+		}
+		applications[j] = prof.Applications
+	}
+
+	// The diagonal dominates the operator, so the preconditioner accelerates convergence.
+	if applications[0] >= applications[1] {
+		t.Fatalf("%v", applications)
+	}
+}
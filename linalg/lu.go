@@ -0,0 +1,86 @@
+package linalg
+
+import (
+	"fmt"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// luFactors is the LU factorization p@(a-shift*I) = l@u, where l is unit lower triangular and u is upper triangular.
+// l and u are stored together in the rows of lu, and the permutation p is stored in perm.
+// The rows are contiguous slices, so that the inner loops run on the kernels of kernel.go.
+type luFactors struct {
+	lu   [][]complex64
+	perm []int
+}
+
+// factorizeLU factorizes a-shift*I with Gaussian elimination with partial pivoting.
+// See Algorithm 3.4.1, Section 3.4.4 Gaussian Elimination with Partial Pivoting, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan.
+func factorizeLU(a *tensor.Dense, shift complex64) (luFactors, error) {
+	m := a.Shape()[0]
+	aNorm := a.InfNorm()
+	lu := a.ToSlice2()
+	for i := range m {
+		lu[i][i] -= shift
+	}
+
+	f := luFactors{lu: lu, perm: make([]int, m)}
+	for i := range m {
+		f.perm[i] = i
+	}
+	for k := range m {
+		// Find the pivot.
+		pivot := k
+		for i := k + 1; i < m; i++ {
+			if abs(lu[i][k]) > abs(lu[pivot][k]) {
+				pivot = i
+			}
+		}
+		if abs(lu[pivot][k]) <= epsilon*aNorm {
+			return luFactors{}, errors.Errorf("singular %d %v", k, shift)
+		}
+		if pivot != k {
+			f.perm[k], f.perm[pivot] = f.perm[pivot], f.perm[k]
+			lu[k], lu[pivot] = lu[pivot], lu[k]
+		}
+
+		// Eliminate the entries below the pivot.
+		akk := lu[k][k]
+		for i := k + 1; i < m; i++ {
+			lik := lu[i][k] / akk
+			lu[i][k] = lik
+			axpy(-lik, lu[k][k+1:], lu[i][k+1:])
+		}
+	}
+	return f, nil
+}
+
+// Dim returns the dimension of a.
+func (f luFactors) Dim() int {
+	return len(f.lu)
+}
+
+// Apply solves (a-shift*I)@x = b for a vector b of shape {m, 1}, which is the application of the operator (a-shift*I)^-1.
+func (f luFactors) Apply(x, b *tensor.Dense) *tensor.Dense {
+	m := len(f.lu)
+	if s := b.Shape(); len(s) != 2 || s[0] != m || s[1] != 1 {
+		panic(fmt.Sprintf("%#v %d", s, m))
+	}
+
+	// Forward substitution l@y = p@b.
+	xs := make([]complex64, m)
+	for i := range m {
+		xs[i] = b.At(f.perm[i], 0) - dotu(f.lu[i][:i], xs)
+	}
+	// Backward substitution u@x = y.
+	for i := m - 1; i >= 0; i-- {
+		xs[i] = (xs[i] - dotu(f.lu[i][i+1:], xs[i+1:])) / f.lu[i][i]
+	}
+
+	x.Reset(m, 1)
+	for i, v := range xs {
+		x.SetAt([]int{i, 0}, v)
+	}
+	return x
+}
@@ -0,0 +1,31 @@
+package linalg
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestFactorizeLU(t *testing.T) {
+	t.Parallel()
+	m := 16
+	a := randMatrix(rand.New(rand.NewPCG(6, 6)), m)
+	var shift complex64 = complex(0.2, 0.1)
+	lu, err := factorizeLU(tensor.Zeros(m, m).Set([]int{0, 0}, a), shift)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	b := randVec(tensor.Zeros(m, 1))
+	x := lu.Apply(tensor.Zeros(1), b)
+	ax := tensor.MatMul(tensor.Zeros(1), a, x).Add(-shift, x)
+	if err := ax.Equal(b, 1e-4); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// Shifting by an eigenvalue is singular.
+	if _, err := factorizeLU(tensor.T2([][]complex64{{1, 0}, {0, 2}}), 2); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,53 @@
+package linalg
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestMatMul(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		m, k, n   int
+		workers   int
+		blockSize int
+		// transpose and conj are applied to the views of a and b before multiplying.
+		transpose bool
+		conj      bool
+	}{
+		{m: 1, k: 1, n: 1, workers: 1, blockSize: 64},
+		{m: 7, k: 5, n: 3, workers: 2, blockSize: 2},
+		{m: 130, k: 70, n: 65, workers: 0, blockSize: 64},
+		{m: 33, k: 17, n: 40, workers: 4, blockSize: 8, transpose: true},
+		{m: 20, k: 9, n: 11, workers: 3, blockSize: 4, conj: true},
+		{m: 0, k: 4, n: 3, workers: 2, blockSize: 4},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			r := rand.New(rand.NewPCG(uint64(i), uint64(i)))
+			a, b := randTensor(r, test.m, test.k), randTensor(r, test.k, test.n)
+			if test.transpose {
+				a, b = randTensor(r, test.k, test.m).Transpose(1, 0), randTensor(r, test.n, test.k).Transpose(1, 0)
+			}
+			if test.conj {
+				a, b = a.Conj(), b.Conj()
+			}
+			want := tensor.MatMul(tensor.Zeros(1), a, b)
+
+			opt := NewMatMulOptions().Workers(test.workers).BlockSize(test.blockSize)
+			got := MatMul(tensor.Zeros(1), a, b, opt)
+			if err := got.Equal(want, 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// The result does not depend on the number of workers.
+			if err := MatMul(tensor.Zeros(1), a, b, opt.Workers(1)).Equal(got, 0); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
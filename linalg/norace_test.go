@@ -0,0 +1,6 @@
+//go:build !race
+
+package linalg
+
+// raceEnabled is whether the race detector is enabled, which adds allocations that testing.AllocsPerRun counts.
+const raceEnabled = false
@@ -0,0 +1,31 @@
+package linalg
+
+import (
+	"github.com/fumin/tensor"
+)
+
+// LinearOperator is a square linear operator, which need not be materialized as a matrix.
+type LinearOperator interface {
+	// Dim returns the dimension of the vector space the operator acts on.
+	Dim() int
+	// Apply applies the operator to src of shape {Dim(), 1}, and stores the result in dst.
+	// src may be a non-contiguous view, and must not be modified.
+	Apply(dst, src *tensor.Dense) *tensor.Dense
+}
+
+type matrixOperator struct {
+	a *tensor.Dense
+}
+
+// MatrixOperator returns the linear operator of a square matrix a.
+func MatrixOperator(a *tensor.Dense) LinearOperator {
+	return matrixOperator{a: a}
+}
+
+func (op matrixOperator) Dim() int {
+	return op.a.Shape()[0]
+}
+
+func (op matrixOperator) Apply(dst, src *tensor.Dense) *tensor.Dense {
+	return tensor.MatMul(dst, op.a, src)
+}
@@ -0,0 +1,38 @@
+package linalg
+
+import (
+	"github.com/fumin/tensor"
+)
+
+// phaseTol is the relative tolerance within which a component is as large as the largest one,
+// so that the choice among components of equal magnitudes, such as those related by a symmetry, is not decided by rounding errors.
+const phaseTol = 1e-3
+
+// FixPhase multiplies each column of vecs by a phase, such that its largest component is real and positive, and returns vecs.
+// Among components of nearly equal magnitudes, the first is chosen.
+// Since eigenvectors are determined only up to a phase, which differs between runs with random starting vectors,
+// fixing it makes the eigenvectors of different runs, such as those of tensor.Eig or the Arnoldi iteration, comparable.
+func FixPhase(vecs *tensor.Dense) *tensor.Dense {
+	m, n := vecs.Shape()[0], vecs.Shape()[1]
+	for j := range n {
+		var largest float32
+		for i := range m {
+			largest = max(largest, abs(vecs.At(i, j)))
+		}
+		if largest == 0 {
+			continue
+		}
+		for i := range m {
+			x := vecs.At(i, j)
+			if abs(x) < (1-phaseTol)*largest {
+				continue
+			}
+			phase := conj(x) / complex(abs(x), 0)
+			vecs.Slice([][2]int{{0, m}, {j, j + 1}}).Mul(phase)
+			// Remove the rounding error of the imaginary part of the chosen component.
+			vecs.SetAt([]int{i, j}, complex(abs(x), 0))
+			break
+		}
+	}
+	return vecs
+}
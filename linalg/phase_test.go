@@ -0,0 +1,88 @@
+package linalg
+
+import (
+	"fmt"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestFixPhase(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		vecs *tensor.Dense
+		want *tensor.Dense
+	}{
+		{
+			vecs: tensor.T2([][]complex64{{0.6i, 0}, {-0.8, 0}}),
+			want: tensor.T2([][]complex64{{-0.6i, 0}, {0.8, 0}}),
+		},
+		{
+			// Among components of equal magnitudes, the first is chosen.
+			vecs: tensor.T2([][]complex64{{-0.5}, {0.5i}, {0.1}}),
+			want: tensor.T2([][]complex64{{0.5}, {-0.5i}, {-0.1}}),
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if err := FixPhase(test.vecs).Equal(test.want, 1e-6); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestFixPhaseSolvers(t *testing.T) {
+	t.Parallel()
+	a := hermitian(rand.New(rand.NewPCG(3, 3)), 32)
+	m, k := a.Shape()[0], 3
+	solvers := []func(eigvals, eigvecs *tensor.Dense, bufs [7]*tensor.Dense, opt ArnoldiOptions) error{
+		func(eigvals, eigvecs *tensor.Dense, bufs [7]*tensor.Dense, opt ArnoldiOptions) error {
+			return Arnoldi(eigvals, eigvecs, a, k, bufs, opt)
+		},
+		func(eigvals, eigvecs *tensor.Dense, bufs [7]*tensor.Dense, opt ArnoldiOptions) error {
+			return LanczosOperator(eigvals, eigvecs, MatrixOperator(a), k, bufs, opt)
+		},
+		func(eigvals, eigvecs *tensor.Dense, bufs [7]*tensor.Dense, opt ArnoldiOptions) error {
+			return DavidsonOperator(eigvals, eigvecs, MatrixOperator(a), k, bufs, opt)
+		},
+	}
+
+	// The eigenvectors of solvers with different starting vectors agree once their phases are fixed.
+	var want *tensor.Dense
+	for i, solve := range solvers {
+		var bufs [7]*tensor.Dense
+		for j := range len(bufs) {
+			bufs[j] = tensor.Zeros(1)
+		}
+		eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+		if err := solve(eigvals, eigvecs, bufs, NewArnoldiOptions().FixPhase(true)); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+		for j := range k {
+			var largest complex64
+			for l := range m {
+				if x := eigvecs.At(l, j); abs(x) > abs(largest) {
+					largest = x
+				}
+			}
+			if imag(largest) != 0 || real(largest) <= 0 {
+				t.Fatalf("%d %d %v", i, j, largest)
+			}
+		}
+		if want == nil {
+			want = eigvecs
+			continue
+		}
+		for j := range k {
+			for l := range m {
+				if d := cmplx.Abs(complex128(eigvecs.At(l, j) - want.At(l, j))); d > 1e-3 {
+					t.Fatalf("%d %d %d %f", i, j, l, d)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package linalg
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fumin/tensor"
+)
+
+// ReduceOptions are options for Reduce.
+type ReduceOptions struct {
+	workers     int
+	chunkSize   int
+	compensated bool
+}
+
+// NewReduceOptions returns the default Reduce options.
+func NewReduceOptions() ReduceOptions {
+	opt := ReduceOptions{}
+	opt.workers = runtime.GOMAXPROCS(0)
+	opt.chunkSize = 4096
+	return opt
+}
+
+// Workers sets the number of goroutines among which the chunks of the sum are divided, which does not change the result.
+// If n < 1, the number is runtime.GOMAXPROCS(0).
+func (opt ReduceOptions) Workers(n int) ReduceOptions {
+	opt.workers = n
+	return opt
+}
+
+// ChunkSize sets the number of consecutive terms of each partial sum.
+// Unlike the number of workers, the chunk size changes the order of the additions, hence the rounding errors of the result.
+func (opt ReduceOptions) ChunkSize(n int) ReduceOptions {
+	opt.chunkSize = n
+	return opt
+}
+
+// Compensated sets whether the partial sums of the chunks are Kahan compensated,
+// whose rounding errors do not grow with the chunk size, at the cost of four additions per term instead of one.
+func (opt ReduceOptions) Compensated(c bool) ReduceOptions {
+	opt.compensated = c
+	return opt
+}
+
+// Reduce returns the sum of term(i) for i in [0, n), which is bit-for-bit reproducible regardless of the number of workers and their scheduling.
+// The terms are divided into chunks of a fixed size, each of which is summed in order by a single goroutine,
+// and the partial sums of the chunks are added pairwise in a fixed tree order.
+// Parallel runs thus give the same result as serial ones, which the golden tests and debugging of parallel code rely on,
+// whereas the sums of partial results in the order the goroutines finish differ between runs by rounding errors.
+// term is called concurrently, and must not modify shared state.
+func Reduce(n int, term func(i int) complex128, options ...ReduceOptions) complex128 {
+	opt := NewReduceOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.chunkSize < 1 {
+		panic(fmt.Sprintf("%d", opt.chunkSize))
+	}
+	workers := opt.workers
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	numChunks := (n + opt.chunkSize - 1) / opt.chunkSize
+	partials := make([]complex128, numChunks)
+	sumChunk := func(c int) {
+		start, end := c*opt.chunkSize, min((c+1)*opt.chunkSize, n)
+		if opt.compensated {
+			partials[c] = kahanSum(term, start, end)
+			return
+		}
+		var s complex128
+		for i := start; i < end; i++ {
+			s += term(i)
+		}
+		partials[c] = s
+	}
+
+	if workers == 1 || numChunks <= 1 {
+		for c := range numChunks {
+			sumChunk(c)
+		}
+		return pairwiseSum(partials)
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range min(workers, numChunks) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				c := int(next.Add(1)) - 1
+				if c >= numChunks {
+					return
+				}
+				sumChunk(c)
+			}
+		}()
+	}
+	wg.Wait()
+	return pairwiseSum(partials)
+}
+
+// Dot returns the inner product x.H @ y of the column vectors x and y, accumulated in double precision by Reduce.
+func Dot(x, y *tensor.Dense, options ...ReduceOptions) complex128 {
+	if xs, ys := x.Shape(), y.Shape(); len(xs) != 2 || len(ys) != 2 || xs[1] != 1 || ys[1] != 1 || xs[0] != ys[0] {
+		panic(fmt.Sprintf("%#v %#v", xs, ys))
+	}
+	return Reduce(x.Shape()[0], func(i int) complex128 {
+		return complex128(conj(x.At(i, 0))) * complex128(y.At(i, 0))
+	}, options...)
+}
+
+// kahanSum returns the compensated sum of term(i) for i in [start, end).
+// See Section 4.3 Compensated Summation, N. J. Higham, Accuracy and Stability of Numerical Algorithms 2nd Ed.
+func kahanSum(term func(i int) complex128, start, end int) complex128 {
+	var s, c complex128
+	for i := start; i < end; i++ {
+		y := term(i) - c
+		t := s + y
+		c = (t - s) - y
+		s = t
+	}
+	return s
+}
+
+// pairwiseSum returns the sum of xs by recursively adding the sums of its two halves, whose order depends only on len(xs).
+func pairwiseSum(xs []complex128) complex128 {
+	switch len(xs) {
+	case 0:
+		return 0
+	case 1:
+		return xs[0]
+	}
+	h := len(xs) / 2
+	return pairwiseSum(xs[:h]) + pairwiseSum(xs[h:])
+}
@@ -0,0 +1,87 @@
+package linalg
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestReduce(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n           int
+		chunkSize   int
+		compensated bool
+	}{
+		{n: 0, chunkSize: 4},
+		{n: 1, chunkSize: 4},
+		{n: 1000, chunkSize: 7},
+		{n: 100000, chunkSize: 4096},
+		{n: 100000, chunkSize: 333, compensated: true},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			// Terms of widely different magnitudes, whose sums depend on the order of the additions.
+			r := rand.New(rand.NewPCG(uint64(i), uint64(i)))
+			terms := make([]complex128, test.n)
+			var want complex128
+			for j := range terms {
+				terms[j] = complex(math.Pow(10, r.Float64()*16-8)*(r.Float64()-0.5), r.NormFloat64())
+				want += terms[j]
+			}
+			term := func(j int) complex128 { return terms[j] }
+
+			opt := NewReduceOptions().ChunkSize(test.chunkSize).Compensated(test.compensated)
+			serial := Reduce(test.n, term, opt.Workers(1))
+			if d := cmplx.Abs(serial - want); d > 1e-9*max(1, cmplx.Abs(want)) {
+				t.Fatalf("%v %v", serial, want)
+			}
+			for _, workers := range []int{2, 3, 16, 0} {
+				for range 5 {
+					if got := Reduce(test.n, term, opt.Workers(workers)); got != serial {
+						t.Fatalf("%d %v %v", workers, got, serial)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReduceCompensated(t *testing.T) {
+	t.Parallel()
+	// Adding the small terms one by one to a large one loses them without compensation.
+	n := 1 << 16
+	term := func(i int) complex128 {
+		if i == 0 {
+			return 1
+		}
+		return 1e-17
+	}
+	want := 1 + float64(n-1)*1e-17
+	opt := NewReduceOptions().ChunkSize(n)
+	if got := real(Reduce(n, term, opt)); got != 1 {
+		t.Fatalf("%g", got)
+	}
+	if got := real(Reduce(n, term, opt.Compensated(true))); math.Abs(got-want) > 1e-16 {
+		t.Fatalf("%g %g", got, want)
+	}
+}
+
+func TestDot(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(0, 0))
+	x, y := randTensor(r, 10000, 1), randTensor(r, 10000, 1)
+	want := tensor.MatMul(tensor.Zeros(1), x.H(), y).At(0, 0)
+	got := Dot(x, y, NewReduceOptions().ChunkSize(100))
+	if d := cmplx.Abs(got - complex128(want)); d > 1e-3 {
+		t.Fatalf("%v %v", got, want)
+	}
+	if serial := Dot(x, y, NewReduceOptions().ChunkSize(100).Workers(1)); serial != got {
+		t.Fatalf("%v %v", serial, got)
+	}
+}
@@ -0,0 +1,179 @@
+package linalg
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// ResolventOptions are options for Resolvent.
+type ResolventOptions struct {
+	maxIterations int
+	tol           float32
+}
+
+// NewResolventOptions returns the default options.
+func NewResolventOptions() ResolventOptions {
+	opt := ResolventOptions{}
+	opt.maxIterations = 1000
+	opt.tol = 1e-5
+	return opt
+}
+
+// MaxIterations sets the maximum number of Lanczos iterations, each of which applies the operator once for all shifts.
+func (opt ResolventOptions) MaxIterations(n int) ResolventOptions {
+	opt.maxIterations = n
+	return opt
+}
+
+// Tol sets the tolerance of the relative residual |v - (z - H) x| < tol*|v| of each shift.
+func (opt ResolventOptions) Tol(tol float32) ResolventOptions {
+	opt.tol = tol
+	return opt
+}
+
+// minresShift is the state of the MINRES iteration of a shift z.
+type minresShift struct {
+	z complex64
+	x *tensor.Dense
+	// d1 and d2 are the last two search directions.
+	d1, d2 *tensor.Dense
+	// c1, s1 and c2, s2 are the last two Givens rotations.
+	c1, c2 float32
+	s1, s2 complex64
+	// phi is the residual of the least squares problem, whose magnitude is that of the residual of x.
+	phi       complex64
+	converged bool
+}
+
+// Resolvent computes xs[j] = (zs[j] - H)^{-1} v for each frequency zs[j] and the Hermitian operator op,
+// which is the primitive of Green's functions, see GreensFunction.
+// Each xs[j] is the MINRES solution in the Krylov space of v, which is the same for all frequencies, since the space is invariant to shifts.
+// Hence, a single Lanczos iteration serves all frequencies, and only the Givens QR factorization of the shifted tridiagonal matrix is done per frequency.
+// Since z - H is not Hermitian for complex z, the rotations are complex, but the residual remains minimal, and the iteration converges for any z off the spectrum.
+// The Lanczos vectors are not reorthogonalized, which merely delays convergence.
+// See C. C. Paige and M. A. Saunders, Solution of Sparse Indefinite Systems of Linear Equations, SIAM J. Numer. Anal. 12, 617 (1975),
+// and A. Frommer and U. Glassner, Many Masses on One Stroke: Economic Computation of Quark Propagators, Int. J. Mod. Phys. C 6, 627 (1995).
+func Resolvent(xs []*tensor.Dense, op LinearOperator, zs []complex64, v *tensor.Dense, bufs [4]*tensor.Dense, options ...ResolventOptions) error {
+	opt := NewResolventOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	m := op.Dim()
+	if len(xs) != len(zs) {
+		panic(fmt.Sprintf("%d %d", len(xs), len(zs)))
+	}
+	if len(v.Shape()) != 2 || v.Shape()[0] != m || v.Shape()[1] != 1 {
+		panic(fmt.Sprintf("%d %#v", m, v.Shape()))
+	}
+
+	shifts := make([]*minresShift, 0, len(zs))
+	for j, z := range zs {
+		s := &minresShift{z: z, x: xs[j].Reset(m, 1), d1: tensor.Zeros(m, 1), d2: tensor.Zeros(m, 1), c1: 1, c2: 1}
+		shifts = append(shifts, s)
+	}
+	beta1 := v.FrobeniusNorm()
+	if beta1 == 0 {
+		return nil
+	}
+	for _, s := range shifts {
+		s.phi = complex(beta1, 0)
+	}
+
+	// The Lanczos recurrence H vCur = beta vPrev + alpha vCur + betaNext vNext.
+	vPrev, vCur, w := bufs[0].Reset(m, 1), bufs[1].Reset(m, 1).Set([]int{0, 0}, v).Mul(complex(1/beta1, 0)), bufs[2]
+	var beta float32
+	for range opt.maxIterations {
+		w = op.Apply(w, vCur)
+		w.Add(complex(-beta, 0), vPrev)
+		alpha := real(tensor.MatMul(bufs[3], vCur.H(), w).At(0, 0))
+		w.Add(complex(-alpha, 0), vCur)
+		betaNext := w.FrobeniusNorm()
+
+		done := true
+		for _, s := range shifts {
+			if s.converged {
+				continue
+			}
+			if err := s.step(vCur, beta, alpha, betaNext); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%v", s.z))
+			}
+			s.converged = abs(s.phi) < opt.tol*beta1
+			done = done && s.converged
+		}
+		if done {
+			return nil
+		}
+		// An invariant subspace is found, in which the solutions are exact up to rounding errors.
+		if betaNext < epsilon*beta1 {
+			break
+		}
+
+		vPrev, vCur, w = vCur, w.Mul(complex(1/betaNext, 0)), vPrev
+		beta = betaNext
+	}
+
+	residuals := make([]float32, 0, len(shifts))
+	for _, s := range shifts {
+		residuals = append(residuals, abs(s.phi)/beta1)
+	}
+	return errors.Errorf("not converged %v", residuals)
+}
+
+// step updates the solution with the Lanczos vector vk, whose column of the tridiagonal matrix of H is beta, alpha, betaNext,
+// and that of z - H is thus -beta, z - alpha, -betaNext.
+func (s *minresShift) step(vk *tensor.Dense, beta, alpha, betaNext float32) error {
+	// Apply the previous two rotations to the column.
+	e, a, b := complex(-beta, 0), s.z-complex(alpha, 0), -betaNext
+	eps := s.s2 * e
+	dp := complex(s.c2, 0) * e
+	delta := complex(s.c1, 0)*dp + s.s1*a
+	gbar := -conj(s.s1)*dp + complex(s.c1, 0)*a
+
+	// The new rotation annihilates betaNext.
+	var c float32
+	var sn, gamma complex64
+	gAbs := abs(gbar)
+	r := float32(math.Hypot(float64(gAbs), float64(b)))
+	switch {
+	case r == 0:
+		return errors.Errorf("singular")
+	case gAbs == 0:
+		c, sn, gamma = 0, 1, complex(b, 0)
+	default:
+		phase := gbar / complex(gAbs, 0)
+		c, sn, gamma = gAbs/r, phase*complex(b/r, 0), phase*complex(r, 0)
+	}
+	tau := complex(c, 0) * s.phi
+	s.phi = -conj(sn) * s.phi
+
+	// d = (vk - delta d1 - eps d2) / gamma, which replaces d2.
+	d := s.d2.Mul(-eps).Add(-delta, s.d1).Add(1, vk).Mul(1 / gamma)
+	s.d1, s.d2 = d, s.d1
+	s.x.Add(tau, d)
+
+	s.c1, s.s1, s.c2, s.s2 = c, sn, s.c1, s.s1
+	return nil
+}
+
+// GreensFunction returns <u|(z - H)^{-1}|v> for each frequency of zs and the Hermitian operator op, see Resolvent.
+// The retarded Green's function of an operator B acting on the ground state |0> of energy E0 is
+// G(omega) = <0|B^H (omega + i eta + E0 - H)^{-1} B|0>, whose spectral function is A(omega) = -Im G(omega) / pi,
+// with the broadening eta > 0 replacing the delta functions of the finite spectrum by Lorentzians of width eta.
+// See E. Dagotto, Correlated electrons in high-temperature superconductors, Rev. Mod. Phys. 66, 763 (1994).
+func GreensFunction(op LinearOperator, zs []complex64, u, v *tensor.Dense, bufs [5]*tensor.Dense, options ...ResolventOptions) ([]complex128, error) {
+	xs := make([]*tensor.Dense, 0, len(zs))
+	for range zs {
+		xs = append(xs, tensor.Zeros(1))
+	}
+	if err := Resolvent(xs, op, zs, v, [4]*tensor.Dense(bufs[:4]), options...); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	gs := make([]complex128, 0, len(zs))
+	for _, x := range xs {
+		gs = append(gs, complex128(tensor.MatMul(bufs[4], u.H(), x).At(0, 0)))
+	}
+	return gs, nil
+}
@@ -0,0 +1,78 @@
+package linalg
+
+import (
+	"fmt"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestResolvent(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		m  int
+		zs []complex64
+	}{
+		{m: 8, zs: []complex64{complex(0.3, 0.1)}},
+		{m: 48, zs: []complex64{complex(-1, 0.5), complex(0.2, 0.05), complex(0, 10), complex(20, 0)}},
+		{m: 96, zs: []complex64{complex(-30, 0.01), complex(1, -0.2)}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			h := hermitian(rand.New(rand.NewPCG(uint64(i), uint64(i))), test.m)
+			v := randVec(tensor.Zeros(test.m, 1))
+			var bufs [5]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+
+			xs := make([]*tensor.Dense, 0, len(test.zs))
+			for range test.zs {
+				xs = append(xs, tensor.Zeros(1))
+			}
+			if err := Resolvent(xs, MatrixOperator(h), test.zs, v, [4]*tensor.Dense(bufs[:4])); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for j, z := range test.zs {
+				// The residual v - (z - H) x.
+				r := tensor.MatMul(tensor.Zeros(1), h, xs[j]).Add(-z, xs[j]).Add(1, v)
+				if rNorm, vNorm := r.FrobeniusNorm(), v.FrobeniusNorm(); rNorm > 1e-4*vNorm {
+					t.Fatalf("%d %v %f %f", j, z, rNorm, vNorm)
+				}
+			}
+
+			// Compare the Green's function with that of the LU factorization of H - z.
+			u := randVec(tensor.Zeros(test.m, 1))
+			gs, err := GreensFunction(MatrixOperator(h), test.zs, u, v, bufs)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for j, z := range test.zs {
+				lu, err := factorizeLU(tensor.Zeros(test.m, test.m).Set([]int{0, 0}, h), z)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				x := lu.Apply(tensor.Zeros(1), v)
+				want := -complex128(tensor.MatMul(tensor.Zeros(1), u.H(), x).At(0, 0))
+				if d := cmplx.Abs(gs[j] - want); d > 1e-3*cmplx.Abs(want) {
+					t.Fatalf("%d %v %v %v", j, z, gs[j], want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolventSingular(t *testing.T) {
+	t.Parallel()
+	// z is an eigenvalue, whose eigenvector is in the Krylov space of v.
+	h := tensor.T2([][]complex64{{1, 0}, {0, 2}})
+	v := tensor.T2([][]complex64{{1}, {0}})
+	xs := []*tensor.Dense{tensor.Zeros(1)}
+	bufs := [4]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+	if err := Resolvent(xs, MatrixOperator(h), []complex64{1}, v, bufs); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,93 @@
+package linalg
+
+import (
+	"math/rand/v2"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// RandomizedSVDOptions are options for RandomizedSVD.
+type RandomizedSVDOptions struct {
+	rand *rand.Rand
+}
+
+// NewRandomizedSVDOptions returns the default options.
+func NewRandomizedSVDOptions() RandomizedSVDOptions {
+	return RandomizedSVDOptions{}
+}
+
+// Rand sets the source of the random sketch, which is otherwise drawn from the global source.
+func (opt RandomizedSVDOptions) Rand(r *rand.Rand) RandomizedSVDOptions {
+	opt.rand = r
+	return opt
+}
+
+// RandomizedSVD returns the k largest singular triplets of a = u @ s @ v.H, as the first k columns of SVD, but without the bidiagonalization of the whole matrix.
+// The range of a is sketched by a multiplied by a random matrix of k+oversampling columns, which is sharpened by iterations of the power iteration a @ a.H,
+// and the singular values are those of the projection of a onto the sketch, whose size is only k+oversampling.
+// For an m×n matrix, the cost is O(mnl) for l = k+oversampling, instead of the O(mn min(m, n)) of SVD,
+// which is the case of the truncations of two-site DMRG and TEBD that keep a small bond dimension of a large matrix.
+// The errors of the singular values decay as (s[k+oversampling]/s[k])^(2*iterations+1), hence a few iterations suffice unless the spectrum is flat.
+// It returns the k×k diagonal matrix s, which is a view into a or bufs, and u and v are set to the m×k and n×k singular vectors.
+// If the sketch is no smaller than a, it falls back to SVD, whose results are exact.
+// Matrix a is modified upon return.
+// See Algorithm 4.4 and 5.1, N. Halko, P. G. Martinsson, and J. A. Tropp, Finding structure with randomness: Probabilistic algorithms for constructing approximate matrix decompositions, SIAM Rev. 53, 217 (2011).
+func RandomizedSVD(u, v, a *tensor.Dense, k, oversampling, iterations int, bufs [6]*tensor.Dense, options ...RandomizedSVDOptions) (*tensor.Dense, error) {
+	opt := NewRandomizedSVDOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	m, n := a.Shape()[0], a.Shape()[1]
+	if k < 1 || k > min(m, n) || oversampling < 0 || iterations < 0 {
+		return nil, errors.Errorf("%d %d %d %d %d", m, n, k, oversampling, iterations)
+	}
+	qrBufs := [2]*tensor.Dense{bufs[4], bufs[5]}
+	l := k + oversampling
+	if l >= min(m, n) {
+		s, err := SVD(u, v, a, [3]*tensor.Dense(bufs[:3]))
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		keepColumns(u, k, bufs[3])
+		keepColumns(v, k, bufs[3])
+		return s.Slice([][2]int{{0, k}, {0, k}}), nil
+	}
+
+	// q is the orthonormal basis of the sketch a @ omega of the range of a.
+	normal := rand.NormFloat64
+	if opt.rand != nil {
+		normal = opt.rand.NormFloat64
+	}
+	omega := bufs[0].Reset(n, l)
+	for i := range n {
+		for j := range l {
+			omega.SetAt([]int{i, j}, complex(float32(normal()), float32(normal())))
+		}
+	}
+	q := bufs[2]
+	QR(q, MatMul(bufs[1], a, omega), qrBufs)
+	// The power iteration, whose intermediate bases are orthonormalized to keep the small singular values from being lost to rounding errors.
+	for range iterations {
+		QR(bufs[3], MatMul(bufs[0], a.H(), q), qrBufs)
+		QR(q, MatMul(bufs[1], a, bufs[3]), qrBufs)
+	}
+
+	// The SVD of the projection q.H @ a = ub @ s @ v.H gives a ≈ (q @ ub) @ s @ v.H.
+	b := MatMul(bufs[3], q.H(), a)
+	ub := bufs[1]
+	s, err := SVD(ub, v, b, [3]*tensor.Dense{bufs[0], bufs[4], bufs[5]})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	MatMul(u, q, ub.Slice([][2]int{{0, l}, {0, k}}))
+	keepColumns(v, k, bufs[0])
+	return s.Slice([][2]int{{0, k}, {0, k}}), nil
+}
+
+// keepColumns sets x to its first k columns, using buf as the intermediate copy.
+func keepColumns(x *tensor.Dense, k int, buf *tensor.Dense) {
+	m := x.Shape()[0]
+	buf.Reset(m, k).Set([]int{0, 0}, x.Slice([][2]int{{0, m}, {0, k}}))
+	x.Reset(m, k).Set([]int{0, 0}, buf)
+}
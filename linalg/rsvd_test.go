@@ -0,0 +1,132 @@
+package linalg
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestRandomizedSVD(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		m, n         int
+		k            int
+		oversampling int
+		iterations   int
+		// decay is the ratio between consecutive singular values.
+		decay float64
+	}{
+		{m: 60, n: 40, k: 5, oversampling: 5, iterations: 2, decay: 0.7},
+		{m: 30, n: 80, k: 8, oversampling: 4, iterations: 1, decay: 0.6},
+		{m: 50, n: 50, k: 3, oversampling: 10, iterations: 0, decay: 0.3},
+		{m: 50, n: 20, k: 4, oversampling: 2, iterations: 3, decay: 0.9},
+		// The sketch is as large as a, which falls back to SVD.
+		{m: 12, n: 9, k: 6, oversampling: 3, iterations: 1, decay: 1},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			r := rand.New(rand.NewPCG(uint64(i), uint64(i)))
+			a, sigma := decayingMatrix(r, test.m, test.n, test.decay)
+			want := resetCopy(a)
+			var bufs [6]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+			u, v := tensor.Zeros(1), tensor.Zeros(1)
+			s, err := RandomizedSVD(u, v, a, test.k, test.oversampling, test.iterations, bufs)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			s = resetCopy(s)
+
+			if u.Shape()[0] != test.m || u.Shape()[1] != test.k || v.Shape()[0] != test.n || v.Shape()[1] != test.k || s.Shape()[0] != test.k {
+				t.Fatalf("%#v %#v %#v", u.Shape(), v.Shape(), s.Shape())
+			}
+			eye := tensor.Zeros(1).Eye(test.k, 0)
+			if err := tensor.MatMul(tensor.Zeros(1), u.H(), u).Equal(eye, 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.MatMul(tensor.Zeros(1), v.H(), v).Equal(eye, 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			// The singular values are close to the exact ones, whose errors are bounded by the discarded ones.
+			for j := range test.k {
+				got := float64(real(s.At(j, j)))
+				if tol := 1e-4 + 0.05*sigma[min(test.k+test.oversampling, len(sigma)-1)]; math.Abs(got-sigma[j]) > tol {
+					t.Fatalf("%d %f %f", j, got, sigma[j])
+				}
+			}
+			// The error of the rank k approximation is close to that of the truncated SVD.
+			usv := tensor.MatMul(tensor.Zeros(1), tensor.MatMul(tensor.Zeros(1), u, s), v.H())
+			diff := float64(usv.Add(-1, want).FrobeniusNorm())
+			var optimal float64
+			for _, sv := range sigma[test.k:] {
+				optimal += sv * sv
+			}
+			optimal = math.Sqrt(optimal)
+			if diff > 1.1*optimal+1e-4 {
+				t.Fatalf("%f %f", diff, optimal)
+			}
+		})
+	}
+}
+
+func TestRandomizedSVDRand(t *testing.T) {
+	t.Parallel()
+	a, _ := decayingMatrix(rand.New(rand.NewPCG(0, 0)), 40, 30, 0.7)
+	// The singular triplets of runs with the same seed are identical.
+	var results [2][3]*tensor.Dense
+	for i := range results {
+		var bufs [6]*tensor.Dense
+		for j := range bufs {
+			bufs[j] = tensor.Zeros(1)
+		}
+		u, v := tensor.Zeros(1), tensor.Zeros(1)
+		opt := NewRandomizedSVDOptions().Rand(rand.New(rand.NewPCG(1, 2)))
+		s, err := RandomizedSVD(u, v, resetCopy(a), 4, 2, 1, bufs, opt)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		results[i] = [3]*tensor.Dense{u, resetCopy(s), v}
+	}
+	for j := range results[0] {
+		if err := results[1][j].Equal(results[0][j], 0); err != nil {
+			t.Fatalf("%d %+v", j, err)
+		}
+	}
+}
+
+func TestRandomizedSVDError(t *testing.T) {
+	t.Parallel()
+	a := randTensor(rand.New(rand.NewPCG(0, 0)), 4, 3)
+	var bufs [6]*tensor.Dense
+	for j := range bufs {
+		bufs[j] = tensor.Zeros(1)
+	}
+	for _, k := range []int{0, 4} {
+		if _, err := RandomizedSVD(tensor.Zeros(1), tensor.Zeros(1), a, k, 1, 1, bufs); err == nil {
+			t.Fatalf("expected error %d", k)
+		}
+	}
+}
+
+// decayingMatrix returns a random m×n matrix whose singular values, which are also returned, decay geometrically by decay.
+func decayingMatrix(r *rand.Rand, m, n int, decay float64) (*tensor.Dense, []float64) {
+	k := min(m, n)
+	bufs := [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+	u, v := tensor.Zeros(1), tensor.Zeros(1)
+	QR(u, randTensor(r, m, k), bufs)
+	QR(v, randTensor(r, n, k), bufs)
+	sigma := make([]float64, k)
+	s := tensor.Zeros(k, k)
+	for i := range k {
+		sigma[i] = math.Pow(decay, float64(i))
+		s.SetAt([]int{i, i}, complex(float32(sigma[i]), 0))
+	}
+	a := tensor.MatMul(tensor.Zeros(1), tensor.MatMul(tensor.Zeros(1), u, s), v.H())
+	return a, sigma
+}
@@ -0,0 +1,305 @@
+package linalg
+
+import (
+	"math"
+	"time"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// sqrtEpsilon is the level of the loss of orthogonality of the Lanczos vectors, at which they are reorthogonalized against a Ritz vector.
+var sqrtEpsilon = float32(math.Sqrt(epsilon))
+
+// SelectiveLanczosOperator is like LanczosOperator, but builds the Krylov basis with the three-term recurrence of the Lanczos iteration alone,
+// reorthogonalizing it selectively against the Ritz vectors that have converged to the square root of machine precision,
+// which are the only directions along which orthogonality is lost.
+// The projection of op stays real symmetric tridiagonal, whose eigenpairs are found by SymTridiagEig,
+// and are checked for convergence after every step, so that the iteration stops as soon as the k smallest ones converge.
+// When the Krylov space is full, the iteration is thick restarted with the lowest Ritz vectors, as in LanczosOperator,
+// which are rotated such that the projection remains tridiagonal, see tridiagonalizeRestart.
+// Compared with LanczosOperator, each step costs one application of op and O(n^2) for the Ritz pairs, instead of a full reorthogonalization and a general eigensolver.
+// See B. N. Parlett and D. S. Scott, The Lanczos Algorithm with Selective Orthogonalization, Math. Comp. 33, 217 (1979).
+func SelectiveLanczosOperator(eigvals, eigvecs *tensor.Dense, op LinearOperator, k int, bufs [7]*tensor.Dense, options ...ArnoldiOptions) error {
+	opt := NewArnoldiOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.shiftInvert {
+		return errors.Errorf("shift-invert needs a matrix")
+	}
+	m := op.Dim()
+	n := opt.krylovSpaceDim
+	if n == 0 {
+		n = max(2*k+1, 20)
+	}
+	n = min(n, m)
+	if k < 1 || k > m || (n <= k && n < m) {
+		return errors.Errorf("%d %d %d", k, n, m)
+	}
+	prof := opt.profile
+
+	// v[:, :n] is the Krylov basis, in which the projection of op is tridiagonal with the diagonal alpha and off diagonal beta,
+	// and beta[n-1] couples v[:, n-1] to the residual v[:, n].
+	v := bufs[0].Reset(m, n+1)
+	rng := opt.rand
+	if err := opt.startVector(rng, v.Slice([][2]int{{0, m}, {0, 1}})); err != nil {
+		return errors.Wrap(err, "")
+	}
+	alpha, beta := make([]float64, n), make([]float64, n)
+	// d and z are the eigenpairs of the projection.
+	d, e := make([]float64, n), make([]float64, n)
+	zs := make([][]float64, n)
+	for i := range zs {
+		zs[i] = make([]float64, n)
+	}
+	z := make([][]float64, n)
+
+	// p is the number of Ritz vectors kept by the last restart.
+	var p, size int
+	var converged bool
+	for range opt.maxIterations {
+		if err := opt.interrupted(); err != nil {
+			return errors.Wrap(err, "")
+		}
+		for j := p; j < n; j++ {
+			t0 := prof.clock()
+			vj := v.Slice([][2]int{{0, m}, {j, j + 1}})
+			w := op.Apply(bufs[1], vj)
+			t1 := prof.clock()
+			if j > 0 {
+				w.Add(complex(-float32(beta[j-1]), 0), v.Slice([][2]int{{0, m}, {j - 1, j}}))
+			}
+			a := real(tensor.MatMul(bufs[2], vj.H(), w).At(0, 0))
+			w.Add(complex(-a, 0), vj)
+			alpha[j] = float64(a)
+			b := w.FrobeniusNorm()
+
+			// The Ritz pairs of the tridiagonal projection.
+			size = j + 1
+			copy(d, alpha[:size])
+			copy(e, beta[:size])
+			for i := range size {
+				z[i] = zs[i][:size]
+			}
+			if err := SymTridiagEig(d[:size], e[:size], z[:size]); err != nil {
+				return errors.Wrap(err, "")
+			}
+			anorm := float32(max(math.Abs(d[0]), math.Abs(d[size-1])))
+
+			// Orthogonalize against the Ritz vectors whose error bounds b*|z[j][i]| have reached the loss of orthogonality.
+			if b > 0 {
+				basis := v.Slice([][2]int{{0, m}, {0, size}})
+				var reorthogonalized bool
+				for i := range size {
+					if b*float32(math.Abs(z[j][i])) > sqrtEpsilon*anorm {
+						continue
+					}
+					y := tensor.MatMul(bufs[4], basis, ritzCoords(bufs[5], z, size, i, i+1))
+					c := tensor.MatMul(bufs[2], y.H(), w).At(0, 0)
+					yNorm := y.FrobeniusNorm()
+					w.Add(-c/complex(yNorm*yNorm, 0), y)
+					reorthogonalized = true
+				}
+				if reorthogonalized {
+					b = w.FrobeniusNorm()
+				}
+			}
+			beta[j] = float64(b)
+			if prof != nil {
+				prof.Apply += t1.Sub(t0)
+				prof.Applications++
+				prof.Orthogonalize += time.Since(t1)
+			}
+
+			vNext := v.Slice([][2]int{{0, m}, {size, size + 1}})
+			if b >= epsilon*max(anorm, 1) {
+				vNext.Set([]int{0, 0}, w).Mul(complex(1/b, 0))
+			} else {
+				// An invariant subspace is found, continue with a random orthogonal vector.
+				beta[j] = 0
+				if size < m {
+					if err := randOrthogonal(rng, vNext, v.Slice([][2]int{{0, m}, {0, size}}), [2]*tensor.Dense(bufs[2:4])); err != nil {
+						return errors.Wrap(err, "")
+					}
+				}
+			}
+			if size >= k && leadingConverged(d, z, float32(beta[j]), size, k, opt.tol) == k {
+				converged = true
+				break
+			}
+		}
+		if converged {
+			break
+		}
+
+		// Keep at least half of the Krylov space, and more if many Ritz pairs converged, as in arnoldi.
+		t0 := prof.clock()
+		numConverged := leadingConverged(d, z, float32(beta[n-1]), n, n, opt.tol)
+		p = min(max(k+numConverged, (n+k)/2), n-1)
+		tridiagonalizeRestart(v, alpha, beta, d, z, n, p, [3]*tensor.Dense(bufs[3:6]))
+		if prof != nil {
+			prof.Restart += time.Since(t0)
+		}
+	}
+	if !converged {
+		return errors.Errorf("not converged %d", k)
+	}
+
+	t0 := prof.clock()
+	eigvals.Reset(k)
+	for i := range k {
+		eigvals.SetAt([]int{i}, complex(float32(d[i]), 0))
+	}
+	tensor.MatMul(eigvecs, v.Slice([][2]int{{0, m}, {0, size}}), ritzCoords(bufs[5], z, size, 0, k))
+	if opt.fixPhase {
+		FixPhase(eigvecs)
+	}
+	if prof != nil {
+		prof.Restart += time.Since(t0)
+	}
+	return nil
+}
+
+// leadingConverged returns the number of leading Ritz pairs among the first want ones of the tridiagonal projection of size, whose eigenpairs are d and z,
+// that satisfy the convergence criterion of ArnoldiOptions.Tol, where b is the norm of the residual vector.
+func leadingConverged(d []float64, z [][]float64, b float32, size, want int, tol float32) int {
+	for i := range want {
+		if b*float32(math.Abs(z[size-1][i])) >= tol*max(1, float32(math.Abs(d[i]))) {
+			return i
+		}
+	}
+	return want
+}
+
+// ritzCoords stores the columns [i0, i1) of the eigenvectors z of the tridiagonal projection of size in c, and returns c.
+func ritzCoords(c *tensor.Dense, z [][]float64, size, i0, i1 int) *tensor.Dense {
+	c.Reset(size, i1-i0)
+	for r := range size {
+		for i := i0; i < i1; i++ {
+			c.SetAt([]int{r, i - i0}, complex(float32(z[r][i]), 0))
+		}
+	}
+	return c
+}
+
+// tridiagonalizeRestart thick restarts the Lanczos decomposition op@v[:, :n] = v[:, :n]@T + beta[n-1]*v[:, n]@e_{n-1}^T,
+// whose tridiagonal projection T has the eigenpairs d and z, with the p lowest Ritz vectors y = v[:, :n]@z[:, :p].
+// Since op@y = y@diag(d) + v[:, n]@s^T with the couplings s = beta[n-1]*z[n-1, :p], the projection is an arrowhead.
+// It is restored to a tridiagonal one by the basis y@q, where the columns of q are the Lanczos vectors of diag(d) starting from s,
+// in reverse order, such that only the last one couples to v[:, n], which becomes v[:, p].
+// See K. Wu and H. Simon, Thick-Restart Lanczos Method for Large Symmetric Eigenvalue Problems, SIAM J. Matrix Anal. Appl. 22, 602 (2000).
+func tridiagonalizeRestart(v *tensor.Dense, alpha, beta, d []float64, z [][]float64, n, p int, bufs [3]*tensor.Dense) {
+	m := v.Shape()[0]
+	s := make([]float64, p)
+	var sNorm float64
+	for i := range p {
+		s[i] = beta[n-1] * z[n-1][i]
+		sNorm += s[i] * s[i]
+	}
+	sNorm = math.Sqrt(sNorm)
+
+	// The Lanczos iteration of diag(d[:p]) from s, with full reorthogonalization since p is small.
+	q := make([][]float64, p)
+	a, b := make([]float64, p), make([]float64, p)
+	for i := range p {
+		q[i] = make([]float64, p)
+	}
+	if sNorm > 0 {
+		for l := range p {
+			q[0][l] = s[l] / sNorm
+		}
+	} else {
+		q[0][0] = 1
+	}
+	x := make([]float64, p)
+	for i := range p {
+		for l := range p {
+			x[l] = d[l] * q[i][l]
+		}
+		a[i] = dot(q[i], x)
+		for range 2 {
+			for j := range i + 1 {
+				c := dot(q[j], x)
+				for l := range p {
+					x[l] -= c * q[j][l]
+				}
+			}
+		}
+		if i+1 == p {
+			break
+		}
+		b[i] = math.Sqrt(dot(x, x))
+		if b[i] > 0x1p-40*max(math.Abs(d[0]), math.Abs(d[p-1]), 1) {
+			for l := range p {
+				q[i+1][l] = x[l] / b[i]
+			}
+			continue
+		}
+		// The couplings vanish in the remaining directions, which continue with the unit vector least in the span of q.
+		b[i] = 0
+		best, bestNorm := 0, -1.0
+		for u := range p {
+			for l := range p {
+				x[l] = 0
+			}
+			x[u] = 1
+			for j := range i + 1 {
+				c := q[j][u]
+				for l := range p {
+					x[l] -= c * q[j][l]
+				}
+			}
+			if xn := math.Sqrt(dot(x, x)); xn > bestNorm {
+				best, bestNorm = u, xn
+			}
+		}
+		for l := range p {
+			x[l] = 0
+		}
+		x[best] = 1
+		for range 2 {
+			for j := range i + 1 {
+				c := dot(q[j], x)
+				for l := range p {
+					x[l] -= c * q[j][l]
+				}
+			}
+		}
+		xn := math.Sqrt(dot(x, x))
+		for l := range p {
+			q[i+1][l] = x[l] / xn
+		}
+	}
+
+	// The new basis is v[:, :n]@z[:, :p]@q in the reverse order.
+	zq := bufs[0].Reset(n, p)
+	for r := range n {
+		for t := range p {
+			var c float64
+			for l := range p {
+				c += z[r][l] * q[p-1-t][l]
+			}
+			zq.SetAt([]int{r, t}, complex(float32(c), 0))
+		}
+	}
+	y := tensor.MatMul(bufs[1], v.Slice([][2]int{{0, m}, {0, n}}), zq)
+	residual := bufs[2].Reset(m, 1).Set([]int{0, 0}, v.Slice([][2]int{{0, m}, {n, n + 1}}))
+	v.Slice([][2]int{{0, m}, {0, p}}).Set([]int{0, 0}, y)
+	v.Slice([][2]int{{0, m}, {p, p + 1}}).Set([]int{0, 0}, residual)
+	for t := range p {
+		alpha[t] = a[p-1-t]
+		if t+1 < p {
+			beta[t] = b[p-2-t]
+		}
+	}
+	beta[p-1] = sNorm
+}
+
+func dot(x, y []float64) float64 {
+	var s float64
+	for i := range x {
+		s += x[i] * y[i]
+	}
+	return s
+}
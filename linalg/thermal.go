@@ -0,0 +1,243 @@
+package linalg
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// ThermalOptions are options for ThermalExpectation.
+type ThermalOptions struct {
+	numSamples     int
+	krylovSpaceDim int
+	rand           *rand.Rand
+}
+
+// NewThermalOptions returns the default options.
+func NewThermalOptions() ThermalOptions {
+	opt := ThermalOptions{}
+	opt.numSamples = 8
+	opt.krylovSpaceDim = 64
+	return opt
+}
+
+// NumSamples sets the number of random vectors, over which the traces are averaged.
+func (opt ThermalOptions) NumSamples(n int) ThermalOptions {
+	opt.numSamples = n
+	return opt
+}
+
+// KrylovSpaceDim sets the dimension of the Krylov space of each random vector, in which exp(-beta H / 2) is approximated.
+// It needs to grow with beta times the width of the spectrum of H.
+func (opt ThermalOptions) KrylovSpaceDim(n int) ThermalOptions {
+	opt.krylovSpaceDim = n
+	return opt
+}
+
+// Rand sets the source of the random vectors, which are otherwise drawn from the global source.
+func (opt ThermalOptions) Rand(r *rand.Rand) ThermalOptions {
+	opt.rand = r
+	return opt
+}
+
+// ThermalAverage is the canonical average of an observable at an inverse temperature.
+type ThermalAverage struct {
+	Beta float64
+	// Value is Tr(O exp(-beta H)) / Tr(exp(-beta H)).
+	Value complex128
+	// Err is the jackknife estimate of the standard error of Value over the random vectors, which is NaN for a single vector.
+	Err float64
+}
+
+// ThermalExpectation computes the canonical averages of the observable obs at each inverse temperature of betas, for the Hermitian hamiltonian op.
+// The traces are estimated by quantum typicality Tr(A) ~ <r|A|r> averaged over random vectors r,
+// where each r is propagated in imaginary time to |r(beta)> = exp(-beta H / 2)|r>, and <O> = sum_r <r(beta)|O|r(beta)> / sum_r <r(beta)|r(beta)>.
+// The propagator is the matrix exponential of the Lanczos tridiagonal matrix of r, and a single Krylov space serves all of betas.
+// The statistical error is suppressed by the number of states with significant Boltzmann weights,
+// and thus grows as the temperature decreases, until only the ground states contribute.
+// See J. Schnack, J. Richter and R. Steinigeweg, Accuracy of the finite-temperature Lanczos method compared to simple typicality-based estimates, Phys. Rev. Research 2, 013186 (2020),
+// and M. Hochbruck and C. Lubich, On Krylov Subspace Approximations to the Matrix Exponential Operator, SIAM J. Numer. Anal. 34, 1911 (1997).
+func ThermalExpectation(op, obs LinearOperator, betas []float64, bufs [7]*tensor.Dense, options ...ThermalOptions) ([]ThermalAverage, error) {
+	opt := NewThermalOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	m := op.Dim()
+	if obs.Dim() != m {
+		return nil, errors.Errorf("%d %d", m, obs.Dim())
+	}
+
+	// num[r][b] and den[r][b] are <r(beta)|O|r(beta)> and <r(beta)|r(beta)> of sample r at betas[b].
+	num, den := newSampleTable[complex128](opt.numSamples, len(betas)), newSampleTable[float64](opt.numSamples, len(betas))
+	shift, _, err := typicality(op, betas, bufs, opt, func(r, b int, v, h, c *tensor.Dense) {
+		psi := tensor.MatMul(bufs[4], v.Slice([][2]int{{0, m}, {0, c.Shape()[0]}}), c)
+		opsi := obs.Apply(bufs[5], psi)
+		num[r][b] = complex128(tensor.MatMul(bufs[6], psi.H(), opsi).At(0, 0))
+		cNorm := float64(c.FrobeniusNorm())
+		den[r][b] = cNorm * cNorm
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	avgs := make([]ThermalAverage, 0, len(betas))
+	for b, beta := range betas {
+		var numSum complex128
+		var denSum float64
+		for r := range opt.numSamples {
+			scale := boltzmannScale(beta, shift, r)
+			num[r][b] *= complex(scale, 0)
+			den[r][b] *= scale
+			numSum += num[r][b]
+			denSum += den[r][b]
+		}
+		avg := ThermalAverage{Beta: beta, Value: numSum / complex(denSum, 0), Err: math.NaN()}
+
+		// The jackknife error of the ratio estimator, see B. Efron, The Jackknife, the Bootstrap and Other Resampling Plans (1982).
+		if k := opt.numSamples; k > 1 {
+			var variance float64
+			for r := range k {
+				jack := (numSum - num[r][b]) / complex(denSum-den[r][b], 0)
+				d := jack - avg.Value
+				variance += real(d)*real(d) + imag(d)*imag(d)
+			}
+			avg.Err = math.Sqrt(variance * float64(k-1) / float64(k))
+		}
+		avgs = append(avgs, avg)
+	}
+	return avgs, nil
+}
+
+// Thermodynamics are the thermodynamic quantities of a hamiltonian H at an inverse temperature.
+type Thermodynamics struct {
+	Beta float64
+	// LogZ is the logarithm of the partition function Z = Tr exp(-beta H).
+	LogZ float64
+	// Energy is <H> = Tr(H exp(-beta H)) / Z, and SpecificHeat is beta^2 (<H^2> - <H>^2).
+	Energy, SpecificHeat float64
+	// Entropy is ln Z + beta <H>.
+	Entropy float64
+}
+
+// ThermalTraces computes the thermodynamics of the Hermitian hamiltonian op at each inverse temperature of betas,
+// from the stochastic estimates of the traces Tr exp(-beta H), Tr(H exp(-beta H)) and Tr(H^2 exp(-beta H)).
+// Like ThermalExpectation, the traces are averaged over random vectors propagated in imaginary time in their Krylov spaces,
+// in which H r(beta) is also known, hence the memory is that of the Krylov basis, and no full spectrum is needed.
+// The random vectors are normalized by their average norm square, which makes Z exact at infinite temperature.
+// See M. F. Hutchinson, A Stochastic Estimator of the Trace of the Influence Matrix for Laplacian Smoothing Splines, Commun. Stat. Simul. Comput. 19, 433 (1990),
+// and J. Jaklic and P. Prelovsek, Lanczos method for the calculation of finite-temperature quantities in correlated systems, Phys. Rev. B 49, 5065 (1994).
+func ThermalTraces(op LinearOperator, betas []float64, bufs [7]*tensor.Dense, options ...ThermalOptions) ([]Thermodynamics, error) {
+	opt := NewThermalOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+
+	// z[r][b], e[r][b] and e2[r][b] are <r(beta)|H^k|r(beta)> of sample r at betas[b] for k = 0, 1, 2.
+	z, e, e2 := newSampleTable[float64](opt.numSamples, len(betas)), newSampleTable[float64](opt.numSamples, len(betas)), newSampleTable[float64](opt.numSamples, len(betas))
+	shift, norm2, err := typicality(op, betas, bufs, opt, func(r, b int, v, h, c *tensor.Dense) {
+		// h @ c are the coordinates of H r(beta) in the Lanczos basis including the residual.
+		n := c.Shape()[0]
+		hc := tensor.MatMul(bufs[4], h, c)
+		cNorm, hcNorm := float64(c.FrobeniusNorm()), float64(hc.FrobeniusNorm())
+		z[r][b] = cNorm * cNorm
+		e[r][b] = float64(real(tensor.MatMul(bufs[5], c.H(), hc.Slice([][2]int{{0, n}, {0, 1}})).At(0, 0)))
+		e2[r][b] = hcNorm * hcNorm
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	var normSum float64
+	for _, n2 := range norm2 {
+		normSum += n2
+	}
+	dim := float64(op.Dim())
+	ths := make([]Thermodynamics, 0, len(betas))
+	for b, beta := range betas {
+		var zSum, eSum, e2Sum float64
+		for r := range opt.numSamples {
+			scale := boltzmannScale(beta, shift, r)
+			zSum += z[r][b] * scale
+			eSum += e[r][b] * scale
+			e2Sum += e2[r][b] * scale
+		}
+		th := Thermodynamics{Beta: beta, Energy: eSum / zSum}
+		th.SpecificHeat = beta * beta * (e2Sum/zSum - th.Energy*th.Energy)
+		// The traces are scaled by exp(beta * minShift), see boltzmannScale.
+		th.LogZ = math.Log(zSum*dim/normSum) - beta*slices.Min(shift)
+		th.Entropy = th.LogZ + beta*th.Energy
+		ths = append(ths, th)
+	}
+	return ths, nil
+}
+
+// typicality propagates random vectors r in imaginary time, see ThermalExpectation.
+// For each sample r and each of betas, fn is called with the Lanczos decomposition op @ v[:, :n] = v @ h of r,
+// and the coordinates c in v[:, :n] of exp(-beta (H - shift[r]) / 2) r, where shift[r] is the lowest Ritz value of r.
+// fn may use bufs[4:7], and must not modify v, h and c.
+// It returns the shifts and the norm squares of the random vectors.
+func typicality(op LinearOperator, betas []float64, bufs [7]*tensor.Dense, opt ThermalOptions, fn func(r, b int, v, h, c *tensor.Dense)) ([]float64, []float64, error) {
+	if opt.numSamples < 1 || opt.krylovSpaceDim < 1 {
+		return nil, nil, errors.Errorf("%d %d", opt.numSamples, opt.krylovSpaceDim)
+	}
+	for _, beta := range betas {
+		if !(beta >= 0) || math.IsInf(beta, 0) {
+			return nil, nil, errors.Errorf("%f", beta)
+		}
+	}
+	m := op.Dim()
+	n := min(opt.krylovSpaceDim, m)
+	rng := opt.rand
+
+	shift := make([]float64, opt.numSamples)
+	norm2 := make([]float64, opt.numSamples)
+	for r := range opt.numSamples {
+		// v[:, :n] is the orthonormal Lanczos basis of the random vector v[:, 0] * rNorm, and t = h[:n, :n] is tridiagonal.
+		v := bufs[0].Reset(m, n+1)
+		h := bufs[1].Reset(n+1, n)
+		v0 := v.Slice([][2]int{{0, m}, {0, 1}})
+		randUniform(rng, v0)
+		rNorm := v0.FrobeniusNorm()
+		v0.Mul(complex(1/rNorm, 0))
+		norm2[r] = float64(rNorm) * float64(rNorm)
+		if err := expandLanczos(op, v, h, 0, n, nil, rng, [3]*tensor.Dense(bufs[2:5])); err != nil {
+			return nil, nil, errors.Wrap(err, "")
+		}
+		t := h.Slice([][2]int{{0, n}, {0, n}})
+
+		// Shift the spectrum by the lowest Ritz value, so that the propagator is bounded by 1.
+		ritz := bufs[3]
+		if err := tensor.Eig(ritz, nil, bufs[2].Reset(n, n).Set([]int{0, 0}, t), [3]*tensor.Dense(bufs[4:7])); err != nil {
+			return nil, nil, errors.Wrap(err, "")
+		}
+		shift[r] = float64(real(ritz.At(0)))
+
+		for b, beta := range betas {
+			a := bufs[2].Reset(n, n).Set([]int{0, 0}, t)
+			for i := range n {
+				a.SetAt([]int{i, i}, a.At(i, i)-complex(float32(shift[r]), 0))
+			}
+			a.Mul(complex(float32(-beta/2), 0))
+			e := Expm(bufs[3], a, [3]*tensor.Dense(bufs[4:7]))
+			c := e.Slice([][2]int{{0, n}, {0, 1}}).Mul(complex(rNorm, 0))
+			fn(r, b, v, h, c)
+		}
+	}
+	return shift, norm2, nil
+}
+
+// boltzmannScale returns exp(-beta (shift[r] - min(shift))), which rescales the quantities of sample r propagated with shift[r] to the common shift.
+func boltzmannScale(beta float64, shift []float64, r int) float64 {
+	return math.Exp(-beta * (shift[r] - slices.Min(shift)))
+}
+
+func newSampleTable[T any](numSamples, numBetas int) [][]T {
+	table := make([][]T, numSamples)
+	for r := range table {
+		table[r] = make([]T, numBetas)
+	}
+	return table
+}
@@ -0,0 +1,166 @@
+package linalg
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestThermalExpectation(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		h, o  *tensor.Dense
+		betas []float64
+		opt   ThermalOptions
+	}
+	tests := []testcase{
+		{
+			h:     tensor.T2([][]complex64{{-1, 0}, {0, 1}}),
+			o:     tensor.T2([][]complex64{{-1, 0}, {0, 1}}),
+			betas: []float64{0, 0.5, 3},
+			opt:   NewThermalOptions().NumSamples(64).Rand(rand.New(rand.NewPCG(1, 1))),
+		},
+		{
+			h:     hermitian(rand.New(rand.NewPCG(2, 2)), 48),
+			o:     hermitian(rand.New(rand.NewPCG(2, 2)), 48),
+			betas: []float64{0.01, 0.1, 0.5},
+			opt:   NewThermalOptions().NumSamples(64).KrylovSpaceDim(32).Rand(rand.New(rand.NewPCG(4, 4))),
+		},
+		{
+			h:     hermitian(rand.New(rand.NewPCG(5, 5)), 64),
+			o:     tensor.Zeros(64, 64).Eye(64, 0),
+			betas: []float64{1, 4},
+			opt:   NewThermalOptions().NumSamples(16).Rand(rand.New(rand.NewPCG(6, 6))),
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [7]*tensor.Dense
+			for i := range bufs {
+				bufs[i] = tensor.Zeros(1)
+			}
+			avgs, err := ThermalExpectation(MatrixOperator(test.h), MatrixOperator(test.o), test.betas, bufs, test.opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Compare with the averages over the eigenstates of h.
+			m := test.h.Shape()[0]
+			lambda, v := tensor.Zeros(1), tensor.Zeros(1)
+			if err := tensor.Eig(lambda, v, tensor.Zeros(m, m).Set([]int{0, 0}, test.h), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			ov := tensor.MatMul(tensor.Zeros(1), test.o, v)
+			for b, beta := range test.betas {
+				var num complex128
+				var den float64
+				for j := range m {
+					w := math.Exp(-beta * float64(real(lambda.At(j))-real(lambda.At(0))))
+					x := v.Slice([][2]int{{0, m}, {j, j + 1}})
+					oj := complex128(tensor.MatMul(tensor.Zeros(1), x.H(), ov.Slice([][2]int{{0, m}, {j, j + 1}})).At(0, 0))
+					num += complex(w, 0) * oj
+					den += w
+				}
+				want := num / complex(den, 0)
+				if avgs[b].Beta != beta || cmplx.Abs(avgs[b].Value-want) > 4*avgs[b].Err+1e-3 {
+					t.Fatalf("%f %v %v %f", beta, avgs[b].Value, want, avgs[b].Err)
+				}
+			}
+		})
+	}
+}
+
+func TestThermalExpectationError(t *testing.T) {
+	t.Parallel()
+	var bufs [7]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	h := MatrixOperator(hermitian(rand.New(rand.NewPCG(0, 0)), 4))
+	if _, err := ThermalExpectation(h, MatrixOperator(hermitian(rand.New(rand.NewPCG(1, 1)), 3)), []float64{1}, bufs); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := ThermalExpectation(h, h, []float64{-1}, bufs); err == nil {
+		t.Fatalf("expected error")
+	}
+	if _, err := ThermalExpectation(h, h, []float64{1}, bufs, NewThermalOptions().NumSamples(0)); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestThermalTraces(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		h     *tensor.Dense
+		betas []float64
+		opt   ThermalOptions
+		// tol is the tolerance of ln Z, the specific heat relative to max(1, c), and the energy relative to the spectral radius.
+		tol float64
+	}
+	tests := []testcase{
+		{
+			h:     tensor.T2([][]complex64{{-1, 0}, {0, 1}}),
+			betas: []float64{0, 0.5, 3},
+			opt:   NewThermalOptions().NumSamples(64).Rand(rand.New(rand.NewPCG(1, 1))),
+			tol:   0.1,
+		},
+		{
+			h:     hermitian(rand.New(rand.NewPCG(2, 2)), 48),
+			betas: []float64{0, 0.05, 0.2},
+			opt:   NewThermalOptions().NumSamples(32).KrylovSpaceDim(32).Rand(rand.New(rand.NewPCG(3, 3))),
+			tol:   0.1,
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [7]*tensor.Dense
+			for i := range bufs {
+				bufs[i] = tensor.Zeros(1)
+			}
+			ths, err := ThermalTraces(MatrixOperator(test.h), test.betas, bufs, test.opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Compare with the sums over the eigenvalues of h.
+			m := test.h.Shape()[0]
+			lambda := tensor.Zeros(1)
+			if err := tensor.Eig(lambda, nil, tensor.Zeros(m, m).Set([]int{0, 0}, test.h), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			var radius float64
+			for j := range m {
+				radius = max(radius, float64(abs(lambda.At(j))))
+			}
+			for b, beta := range test.betas {
+				var z, e, e2 float64
+				for j := range m {
+					ej := float64(real(lambda.At(j)))
+					w := math.Exp(-beta * ej)
+					z += w
+					e += w * ej
+					e2 += w * ej * ej
+				}
+				e, e2 = e/z, e2/z
+				c := beta * beta * (e2 - e*e)
+				th := ths[b]
+				if math.Abs(th.LogZ-math.Log(z)) > test.tol || math.Abs(th.Energy-e) > test.tol*radius || math.Abs(th.SpecificHeat-c) > test.tol*max(1, c) {
+					t.Fatalf("%f %+v %f %f %f", beta, th, math.Log(z), e, c)
+				}
+				if math.Abs(th.Entropy-(th.LogZ+beta*th.Energy)) > 1e-9 {
+					t.Fatalf("%+v", th)
+				}
+			}
+			// Z is exact at infinite temperature.
+			if test.betas[0] == 0 && math.Abs(ths[0].LogZ-math.Log(float64(m))) > 1e-6 {
+				t.Fatalf("%+v %d", ths[0], m)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package linalg
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+// SymTridiagEig computes the eigenpairs of the real symmetric tridiagonal matrix, whose diagonal is d and off diagonal e,
+// where e[i] couples the rows i and i+1, and e[len(d)-1] is ignored.
+// On return, d holds the eigenvalues in ascending order, the columns of z the corresponding eigenvectors, and e is destroyed.
+// z is n×n, and is overwritten.
+// The eigenvalues are found by the implicit QL iteration with Wilkinson shifts, whose rotations accumulate into z,
+// which for a tridiagonal matrix of size n costs O(n^2), instead of the O(n^3) of a general eigensolver of the same matrix.
+// See Section 8.3 The Symmetric QR Algorithm, Matrix Computations 4th Ed., G. H. Golub, C. F. Van Loan, and the routine tql2 of EISPACK.
+func SymTridiagEig(d, e []float64, z [][]float64) error {
+	n := len(d)
+	for i := range n {
+		for j := range n {
+			z[i][j] = 0
+		}
+		z[i][i] = 1
+	}
+	if n == 0 {
+		return nil
+	}
+	e[n-1] = 0
+
+	const maxSweeps = 30
+	for l := range n {
+		for iter := 0; ; iter++ {
+			// Find the small off diagonal element e[m], at which the matrix splits.
+			m := l
+			for ; m < n-1; m++ {
+				dd := math.Abs(d[m]) + math.Abs(d[m+1])
+				if math.Abs(e[m]) <= 0x1p-52*dd {
+					break
+				}
+			}
+			if m == l {
+				break
+			}
+			if iter >= maxSweeps {
+				return errors.Errorf("not converged %d", l)
+			}
+
+			// The Wilkinson shift is the eigenvalue of the leading 2×2 block closer to d[l].
+			g := (d[l+1] - d[l]) / (2 * e[l])
+			r := math.Hypot(g, 1)
+			g = d[m] - d[l] + e[l]/(g+math.Copysign(r, g))
+			s, c, p := 1.0, 1.0, 0.0
+			i := m - 1
+			for ; i >= l; i-- {
+				f, b := s*e[i], c*e[i]
+				r = math.Hypot(f, g)
+				e[i+1] = r
+				if r == 0 {
+					// Recover from underflow.
+					d[i+1] -= p
+					e[m] = 0
+					break
+				}
+				s, c = f/r, g/r
+				g = d[i+1] - p
+				r = (d[i]-g)*s + 2*c*b
+				p = s * r
+				d[i+1] = g + p
+				g = c*r - b
+				for k := range n {
+					f = z[k][i+1]
+					z[k][i+1] = s*z[k][i] + c*f
+					z[k][i] = c*z[k][i] - s*f
+				}
+			}
+			if r == 0 && i >= l {
+				continue
+			}
+			d[l] -= p
+			e[l] = g
+			e[m] = 0
+		}
+	}
+
+	// Sort the eigenpairs in ascending order.
+	for i := range n {
+		j := i
+		for l := i + 1; l < n; l++ {
+			if d[l] < d[j] {
+				j = l
+			}
+		}
+		if j == i {
+			continue
+		}
+		d[i], d[j] = d[j], d[i]
+		for k := range n {
+			z[k][i], z[k][j] = z[k][j], z[k][i]
+		}
+	}
+	return nil
+}
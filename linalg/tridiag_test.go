@@ -0,0 +1,86 @@
+package linalg
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestSymTridiagEig(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(0, 0))
+	tests := []struct {
+		d, e []float64
+	}{
+		{d: []float64{3}, e: []float64{0}},
+		{d: []float64{1, 1}, e: []float64{1, 0}},
+		// A split in the middle.
+		{d: []float64{2, -1, 4, 0}, e: []float64{0.5, 0, 1e-3, 0}},
+		// The laplacian, whose eigenvalues are 2 - 2cos(pi j / (n+1)).
+		{d: []float64{2, 2, 2, 2, 2, 2}, e: []float64{-1, -1, -1, -1, -1, 0}},
+		{d: randFloats(r, 40), e: randFloats(r, 40)},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			n := len(test.d)
+			d, e := append([]float64{}, test.d...), append([]float64{}, test.e...)
+			z := make([][]float64, n)
+			for j := range z {
+				z[j] = make([]float64, n)
+			}
+			if err := SymTridiagEig(d, e, z); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			for j := range n {
+				if j > 0 && d[j] < d[j-1] {
+					t.Fatalf("%v", d)
+				}
+				// The residual of T z_j = d_j z_j, and the orthonormality of z.
+				for row := range n {
+					tz := test.d[row] * z[row][j]
+					if row > 0 {
+						tz += test.e[row-1] * z[row-1][j]
+					}
+					if row < n-1 {
+						tz += test.e[row] * z[row+1][j]
+					}
+					if diff := math.Abs(tz - d[j]*z[row][j]); diff > 1e-12 {
+						t.Fatalf("%d %d %g", j, row, diff)
+					}
+				}
+				for l := range n {
+					var dot float64
+					for row := range n {
+						dot += z[row][j] * z[row][l]
+					}
+					want := 0.0
+					if l == j {
+						want = 1
+					}
+					if math.Abs(dot-want) > 1e-12 {
+						t.Fatalf("%d %d %f", j, l, dot)
+					}
+				}
+			}
+			if i == 3 {
+				for j := range n {
+					want := 2 - 2*math.Cos(math.Pi*float64(j+1)/float64(n+1))
+					if math.Abs(d[j]-want) > 1e-12 {
+						t.Fatalf("%d %f %f", j, d[j], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func randFloats(r *rand.Rand, n int) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = r.Float64()*2 - 1
+	}
+	return x
+}
@@ -0,0 +1,57 @@
+package mps
+
+import (
+	"fmt"
+
+	"github.com/fumin/tensor"
+)
+
+// ApplyMPO sets out to the MPS of the state ws|ms>, where ws is an MPO such as Ising, and returns out.
+// Each site of out is the contraction of the sites of ws and ms over the physical axis,
+// whose bond dimension is the product of those of ws and ms, and can be truncated by Compress.
+// Together with Add, it builds the states of polynomials of H, such as the moments <psi|H^k|psi> = <H^i psi|H^j psi> for i+j = k,
+// and the Chebyshev vectors T_k(H)|psi> = 2H T_{k-1}(H)|psi> - T_{k-2}(H)|psi>.
+// out must have the same length as ms, and may contain nil tensors, or be ms itself, in which case ms is evolved in place.
+// See Section 5.1 Applying an MPO to an MPS, Ulrich Schollwock.
+func ApplyMPO(out, ws, ms []*tensor.Dense, buf *tensor.Dense) []*tensor.Dense {
+	if len(ws) != len(ms) || len(out) != len(ms) {
+		panic(fmt.Sprintf("%d %d %d", len(ws), len(ms), len(out)))
+	}
+	for i, w := range ws {
+		m := ms[i]
+		if w.Shape()[mpoDownAxis] != m.Shape()[mpsUpAxis] {
+			panic(fmt.Sprintf("%d %#v %#v", i, w.Shape(), m.Shape()))
+		}
+		// The dimensions are read before out[i] is reset, which may be m.
+		dLeft := w.Shape()[mpoLeftAxis] * m.Shape()[mpsLeftAxis]
+		dUp := w.Shape()[mpoUpAxis]
+		dRight := w.Shape()[mpoRightAxis] * m.Shape()[mpsRightAxis]
+
+		// wm is of shape {mpoLeft, mpoRight, mpoUp, mpsLeft, mpsRight}.
+		wm := tensor.Product(buf, w, m, [][2]int{{mpoDownAxis, mpsUpAxis}})
+		if out[i] == nil {
+			out[i] = tensor.Zeros(1)
+		}
+		out[i] = resetCopy(out[i], wm.Transpose(0, 3, 2, 1, 4)).Reshape(dLeft, dUp, dRight)
+	}
+	return out
+}
+
+// Add sets out to the MPS of the sum |a> + |b>, and returns out.
+// The result is exact, with the bond dimensions being the sums of those of a and b, and can be truncated by Compress.
+// Superpose adds more than two states with coefficients.
+// out must have the same length as a and b, and may contain nil tensors, or be a or b itself.
+// See Section 4.3 Adding two matrix product states, Ulrich Schollwock.
+func Add(out, a, b []*tensor.Dense) []*tensor.Dense {
+	if len(a) != len(b) || len(out) != len(a) {
+		panic(fmt.Sprintf("%d %d %d", len(a), len(b), len(out)))
+	}
+	sum := addMPS(a, b, 1, 1)
+	for i, m := range sum {
+		if out[i] == nil {
+			out[i] = tensor.Zeros(1)
+		}
+		resetCopy(out[i], m)
+	}
+	return out
+}
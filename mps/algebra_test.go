@@ -0,0 +1,73 @@
+package mps
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestApplyMPO(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ws []*tensor.Dense
+		d  int
+	}{
+		{ws: Ising([2]int{5, 1}, 1), d: 3},
+		{ws: Ising([2]int{2, 2}, 0.7), d: 2},
+		{ws: XXZ([2]int{4, 1}, 1, 0.5, 0.1), d: 4},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ms := RandMPSWithRand(rand.New(rand.NewPCG(uint64(i), 0)), test.ws, test.d)
+			buf := tensor.Zeros(1)
+			bufs2 := [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+			psi := product(tensor.Zeros(1), ms, buf).Reshape(-1, 1)
+
+			hms := ApplyMPO(make([]*tensor.Dense, len(ms)), test.ws, ms, buf)
+			want := tensor.MatMul(tensor.Zeros(1), denseMPO(test.ws), psi)
+			if err := product(tensor.Zeros(1), hms, buf).Reshape(-1, 1).Equal(want, 1e-4); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// <psi|H|psi> and <H psi|H psi> agree with the expressions that contract the MPO directly.
+			fs := make([]*tensor.Dense, 0, len(test.ws))
+			for range test.ws {
+				fs = append(fs, tensor.Zeros(1))
+			}
+			if got, e := InnerProduct(ms, hms, bufs2), LExpressions(fs, test.ws, ms, bufs2); abs(got-e) > 1e-4*max(1, abs(e)) {
+				t.Fatalf("%v %v", got, e)
+			}
+			if got, h2 := InnerProduct(hms, hms, bufs2), H2(test.ws, ms, bufs2); abs(got-h2) > 1e-4*max(1, abs(h2)) {
+				t.Fatalf("%v %v", got, h2)
+			}
+
+			// Applying in place.
+			ApplyMPO(ms, test.ws, ms, buf)
+			if err := product(tensor.Zeros(1), ms, buf).Reshape(-1, 1).Equal(want, 1e-4); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(2, 0))
+	ws := Ising([2]int{5, 1}, 1)
+	a, b := RandMPSWithRand(r, ws, 3), RandMPSWithRand(r, ws, 2)
+	buf := tensor.Zeros(1)
+	want := product(tensor.Zeros(1), a, buf).Add(1, product(tensor.Zeros(1), b, buf))
+
+	sum := Add(make([]*tensor.Dense, len(a)), a, b)
+	if err := product(tensor.Zeros(1), sum, buf).Equal(want, 1e-5); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// Adding in place.
+	Add(a, a, b)
+	if err := product(tensor.Zeros(1), a, buf).Equal(want, 1e-5); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
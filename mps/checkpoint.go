@@ -0,0 +1,88 @@
+package mps
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+const (
+	checkpointFname = "checkpoint.bin"
+)
+
+// saveCheckpoint saves the iteration number, whether the search converged at it, F expressions and MPS tensors to dir.
+// The checkpoint is written to a temporary file and renamed, so that an interruption never leaves a partially written checkpoint.
+func saveCheckpoint(dir string, iteration int, converged bool, fs, ms []*tensor.Dense) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "")
+	}
+	f, err := os.CreateTemp(dir, checkpointFname+".*")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer os.Remove(f.Name())
+
+	var convergedInt int
+	if converged {
+		convergedInt = 1
+	}
+	w := bufio.NewWriter(f)
+	if err1 := writeInts(w, iteration, convergedInt, len(fs), len(ms)); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	for i, t := range append(append([]*tensor.Dense{}, fs...), ms...) {
+		if err != nil {
+			break
+		}
+		if err1 := writeTensor(w, t); err1 != nil {
+			err = errors.Wrap(err1, fmt.Sprintf("%d", i))
+		}
+	}
+	if err1 := w.Flush(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(f.Name(), filepath.Join(dir, checkpointFname)); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// loadCheckpoint loads the checkpoint in dir into fs and ms, and returns the iteration number of the checkpoint and whether the search converged at it.
+// If there is no checkpoint, it returns -1.
+func loadCheckpoint(dir string, fs, ms []*tensor.Dense) (int, bool, error) {
+	f, err := os.Open(filepath.Join(dir, checkpointFname))
+	if os.IsNotExist(err) {
+		return -1, false, nil
+	}
+	if err != nil {
+		return -1, false, errors.Wrap(err, "")
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	header, err := readInts(r, 4)
+	if err != nil {
+		return -1, false, errors.Wrap(err, "")
+	}
+	iteration, converged, numFs, numMs := header[0], header[1] != 0, header[2], header[3]
+	if numFs != len(fs) || numMs != len(ms) {
+		return -1, false, errors.Errorf("%d %d %d %d", numFs, len(fs), numMs, len(ms))
+	}
+	for i, t := range append(append([]*tensor.Dense{}, fs...), ms...) {
+		if err := readTensor(r, t); err != nil {
+			return -1, false, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+	}
+	return iteration, converged, nil
+}
@@ -0,0 +1,231 @@
+package mps
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Parallel()
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ms := RandMPS(Ising([2]int{6, 1}, 1), 4)
+	fs := make([]*tensor.Dense, 0, len(ms))
+	for _, m := range ms {
+		fs = append(fs, randTensor(m.Shape()[mpsRightAxis], 3, m.Shape()[mpsRightAxis]))
+	}
+	if err := saveCheckpoint(dir, 7, true, fs, ms); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	loadedFs := make([]*tensor.Dense, 0, len(fs))
+	for range fs {
+		loadedFs = append(loadedFs, tensor.Zeros(1))
+	}
+	loadedMs := make([]*tensor.Dense, 0, len(ms))
+	for range ms {
+		loadedMs = append(loadedMs, tensor.Zeros(1))
+	}
+	iteration, converged, err := loadCheckpoint(dir, loadedFs, loadedMs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if iteration != 7 || !converged {
+		t.Fatalf("%d %t", iteration, converged)
+	}
+	for i := range fs {
+		if err := loadedFs[i].Equal(fs[i], 0); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+	}
+	for i := range ms {
+		if err := loadedMs[i].Equal(ms[i], 0); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+	}
+
+	// Loading from an empty directory reports no checkpoint.
+	emptyDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(emptyDir)
+	if iteration, _, err := loadCheckpoint(emptyDir, loadedFs, loadedMs); err != nil || iteration != -1 {
+		t.Fatalf("%d %+v", iteration, err)
+	}
+}
+
+func TestSearchGroundStateResume(t *testing.T) {
+	t.Parallel()
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	var bufs [10]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	h := Ising([2]int{4, 1}, 0.031623)
+	const e0 = -3.001501
+
+	// Interrupt the search after one iteration.
+	ms := RandMPS(h, 4)
+	fs := make([]*tensor.Dense, 0, len(ms))
+	for range ms {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	opt := NewSearchGroundStateOptions().Checkpoint(dir, 1)
+	if err := SearchGroundState(fs, h, ms, bufs, opt.MaxIterations(1).Tol(0)); err == nil {
+		t.Fatalf("expected not converged")
+	}
+
+	// Resuming with an exhausted iteration budget only loads the checkpoint.
+	resumed := RandMPS(h, 4)
+	resumedFs := make([]*tensor.Dense, 0, len(ms))
+	for range ms {
+		resumedFs = append(resumedFs, tensor.Zeros(1))
+	}
+	if err := SearchGroundState(resumedFs, h, resumed, bufs, opt.MaxIterations(1)); err == nil {
+		t.Fatalf("expected not converged")
+	}
+	for i := range ms {
+		if err := resumed[i].Equal(ms[i], 0); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+	}
+
+	// Resume and converge.
+	if err := SearchGroundState(resumedFs, h, resumed, bufs, opt); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	psiIP := InnerProduct(resumed, resumed, [2]*tensor.Dense(bufs[:2]))
+	e := LExpressions(resumedFs, h, resumed, [2]*tensor.Dense(bufs[:2])) / psiIP
+	if diff := abs(e - e0); diff > 2e-6 {
+		t.Fatalf("%f %f %f", diff, e, e0)
+	}
+}
+
+func TestSearchGroundStateResumeConverged(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		search func(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense, options ...SearchGroundStateOptions) error
+	}{
+		{search: SearchGroundState},
+		{search: SearchGroundState2Site},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			dir, err := os.MkdirTemp("", "")
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			defer os.RemoveAll(dir)
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			h := Ising([2]int{4, 1}, 0.031623)
+			const e0 = -3.001501
+			ms := RandMPS(h, 4)
+			fs := make([]*tensor.Dense, 0, len(ms))
+			for range ms {
+				fs = append(fs, tensor.Zeros(1))
+			}
+
+			// A checkpoint is due at every iteration, including the converged last one.
+			opt := NewSearchGroundStateOptions().MaxBondDim(4).Checkpoint(dir, 1)
+			if err := test.search(fs, h, ms, bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			resumed := RandMPS(h, 4)
+			resumedFs := make([]*tensor.Dense, 0, len(ms))
+			for range ms {
+				resumedFs = append(resumedFs, tensor.Zeros(1))
+			}
+			iteration, converged, err := loadCheckpoint(dir, resumedFs, resumed)
+			if err != nil || !converged {
+				t.Fatalf("%d %t %+v", iteration, converged, err)
+			}
+
+			// Resuming a converged search succeeds, even if no iterations remain.
+			resumed = RandMPS(h, 4)
+			if err := test.search(resumedFs, h, resumed, bufs, opt.MaxIterations(iteration+1)); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			psiIP := InnerProduct(resumed, resumed, [2]*tensor.Dense(bufs[:2]))
+			e := LExpressions(resumedFs, h, resumed, [2]*tensor.Dense(bufs[:2])) / psiIP
+			if diff := abs(e - e0); diff > 2e-6 {
+				t.Fatalf("%f %f %f", diff, e, e0)
+			}
+		})
+	}
+}
+
+func TestSearchGroundStateInterrupt(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		search func(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense, options ...SearchGroundStateOptions) error
+	}{
+		{search: SearchGroundState},
+		{search: SearchGroundState2Site},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			dir, err := os.MkdirTemp("", "")
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			defer os.RemoveAll(dir)
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			h := Ising([2]int{4, 1}, 0.031623)
+			const e0 = -3.001501
+			ms := RandMPS(h, 4)
+			fs := make([]*tensor.Dense, 0, len(ms))
+			for range ms {
+				fs = append(fs, tensor.Zeros(1))
+			}
+
+			// An interrupted search saves a checkpoint even if none is due.
+			interrupt := make(chan struct{})
+			close(interrupt)
+			opt := NewSearchGroundStateOptions().MaxBondDim(4).Checkpoint(dir, 0)
+			err = test.search(fs, h, ms, bufs, opt.Interrupt(interrupt).Tol(0))
+			if !errors.Is(err, ErrInterrupted) {
+				t.Fatalf("%+v", err)
+			}
+			resumed := RandMPS(h, 4)
+			resumedFs := make([]*tensor.Dense, 0, len(ms))
+			for range ms {
+				resumedFs = append(resumedFs, tensor.Zeros(1))
+			}
+			iteration, converged, err := loadCheckpoint(dir, resumedFs, resumed)
+			if err != nil || iteration != 0 || converged {
+				t.Fatalf("%d %t %+v", iteration, converged, err)
+			}
+
+			// Resume and converge.
+			if err := test.search(resumedFs, h, resumed, bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			psiIP := InnerProduct(resumed, resumed, [2]*tensor.Dense(bufs[:2]))
+			e := LExpressions(resumedFs, h, resumed, [2]*tensor.Dense(bufs[:2])) / psiIP
+			if diff := abs(e - e0); diff > 2e-6 {
+				t.Fatalf("%f %f %f", diff, e, e0)
+			}
+		})
+	}
+}
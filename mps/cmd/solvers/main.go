@@ -0,0 +1,105 @@
+// Command solvers compares the local eigenvalue solvers of the MPS ground state search on the same problem,
+// reporting the number of applications of the effective hamiltonians and the wall time of each solver and Krylov space dimension.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fumin/qising/mps"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+var (
+	length     = flag.Int("l", 16, "number of spins")
+	model      = flag.String("model", "ising", "name of the registered model")
+	params     = flag.String("params", "h=1", "comma separated parameters of the model, such as h=1,g=0.5")
+	bondDim    = flag.Int("b", 8, "bond dimension")
+	tol        = flag.Float64("tol", 1e-6, "tolerance of the stopping criterion")
+	seed       = flag.Uint64("seed", 1, "seed of the random initial state")
+	solvers    = flag.String("solvers", "arnoldi,lanczos,davidson,selective", "comma separated local solvers")
+	krylovDims = flag.String("krylov", "0,8,16,32", "comma separated Krylov space dimensions, where 0 means the default")
+)
+
+func parseSolvers(s string) ([]mps.LocalSolver, error) {
+	all := []mps.LocalSolver{mps.ArnoldiSolver, mps.LanczosSolver, mps.DavidsonSolver, mps.SelectiveLanczosSolver}
+	parsed := make([]mps.LocalSolver, 0)
+	for _, name := range strings.Split(s, ",") {
+		i := slices.IndexFunc(all, func(s mps.LocalSolver) bool { return s.String() == name })
+		if i < 0 {
+			return nil, errors.Errorf("unknown solver %q", name)
+		}
+		parsed = append(parsed, all[i])
+	}
+	return parsed, nil
+}
+
+func parseInts(s string) ([]int, error) {
+	parsed := make([]int, 0)
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		parsed = append(parsed, n)
+	}
+	return parsed, nil
+}
+
+func writeComparisons(w io.Writer, comparisons []mps.SolverComparison) {
+	fmt.Fprintf(w, "solver,krylov,e0,sweeps,applications,eigensolve_ms,total_ms\n")
+	for _, c := range comparisons {
+		fmt.Fprintf(w, "%v,%d,%f,%d,%d,%f,%f\n", c.Solver, c.KrylovSpaceDim, real(c.Energy), c.Sweeps, c.Applications,
+			float64(c.Eigensolve.Microseconds())/1e3, float64(c.Total.Microseconds())/1e3)
+	}
+}
+
+func mainWithErr() error {
+	ss, err := parseSolvers(*solvers)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	ns, err := parseInts(*krylovDims)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	ps, err := mps.ParseModelParams(*params)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	h, err := mps.BuildModel(*model, [2]int{*length, 1}, ps)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	var bufs [10]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	opt := mps.NewSearchGroundStateOptions().Tol(float32(*tol))
+	comparisons, err := mps.CompareLocalSolvers(h, *bondDim, *seed, ss, ns, bufs, opt)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	for _, c := range comparisons {
+		log.Printf("%v", c)
+	}
+	writeComparisons(os.Stdout, comparisons)
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	log.SetFlags(log.Lmicroseconds | log.Llongfile | log.LstdFlags)
+
+	if err := mainWithErr(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
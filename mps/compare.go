@@ -0,0 +1,68 @@
+package mps
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// SolverComparison is the cost of a ground state search with a local solver and a Krylov space dimension.
+type SolverComparison struct {
+	Solver LocalSolver
+	// KrylovSpaceDim is the dimension of the Krylov space of the local solver, where zero means the default.
+	KrylovSpaceDim int
+
+	// Energy is the energy of the found ground state, and Sweeps the number of iterations of the search.
+	Energy complex64
+	Sweeps int
+	// Applications is the number of applications of the effective hamiltonians, which dominate the cost of the local solvers.
+	Applications int
+	// Eigensolve is the time spent in the local solvers, and Total the wall time of the search.
+	Eigensolve time.Duration
+	Total      time.Duration
+}
+
+func (c SolverComparison) String() string {
+	return fmt.Sprintf("%v krylov %d energy %f sweeps %d applications %d eigensolve %v total %v",
+		c.Solver, c.KrylovSpaceDim, real(c.Energy), c.Sweeps, c.Applications, c.Eigensolve, c.Total)
+}
+
+// CompareLocalSolvers searches for the ground state of the hamiltonian ws with every combination of solvers and krylovDims,
+// starting each search from the same random MPS of bond dimension maxD seeded by seed.
+// The other options of the searches are given by options, whose local solver, Krylov space dimension and profile are overridden.
+func CompareLocalSolvers(ws []*tensor.Dense, maxD int, seed uint64, solvers []LocalSolver, krylovDims []int, bufs [10]*tensor.Dense, options ...SearchGroundStateOptions) ([]SolverComparison, error) {
+	opt := NewSearchGroundStateOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+
+	comparisons := make([]SolverComparison, 0, len(solvers)*len(krylovDims))
+	fs := make([]*tensor.Dense, len(ws))
+	for i := range fs {
+		fs[i] = tensor.Zeros(1)
+	}
+	for _, solver := range solvers {
+		for _, n := range krylovDims {
+			ms := RandMPSWithRand(rand.New(rand.NewPCG(seed, seed)), ws, maxD)
+			var prof Profile
+			o := opt.LocalSolver(solver).KrylovSpaceDim(n).Profile(&prof)
+			start := time.Now()
+			if err := SearchGroundState(fs, ws, ms, bufs, o); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%v %d", solver, n))
+			}
+			c := SolverComparison{Solver: solver, KrylovSpaceDim: n, Total: time.Since(start)}
+
+			bufs2 := [2]*tensor.Dense(bufs[:2])
+			c.Energy = LExpressions(fs, ws, ms, bufs2) / InnerProduct(ms, ms, bufs2)
+			c.Sweeps = len(prof.Sweeps)
+			total := prof.Total()
+			c.Applications = total.Arnoldi.Applications
+			c.Eigensolve = total.Eigensolve
+			comparisons = append(comparisons, c)
+		}
+	}
+	return comparisons, nil
+}
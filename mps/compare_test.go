@@ -0,0 +1,53 @@
+package mps
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestCompareLocalSolvers(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		h []*tensor.Dense
+	}
+	tests := []testcase{
+		{h: Ising([2]int{8, 1}, 0.5)},
+		{h: Ising([2]int{8, 1}, 2)},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			solvers := []LocalSolver{ArnoldiSolver, LanczosSolver, DavidsonSolver, SelectiveLanczosSolver}
+			krylovDims := []int{0, 8}
+			comparisons, err := CompareLocalSolvers(test.h, 4, 1, solvers, krylovDims, bufs)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(comparisons) != len(solvers)*len(krylovDims) {
+				t.Fatalf("%d", len(comparisons))
+			}
+
+			// Compare with the dense eigenvalue solver.
+			lambda := tensor.Zeros(1)
+			if err := tensor.Eig(lambda, nil, denseMPO(test.h), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			e0 := lambda.At(0)
+			for _, c := range comparisons {
+				if diff := abs(c.Energy - e0); diff > 1e-4*abs(e0) {
+					t.Fatalf("%v %f", c, e0)
+				}
+				if c.Sweeps == 0 || c.Applications == 0 || c.Total < c.Eigensolve {
+					t.Fatalf("%v", c)
+				}
+			}
+		})
+	}
+}
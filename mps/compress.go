@@ -0,0 +1,193 @@
+package mps
+
+import (
+	"fmt"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// CompressOptions are options for Compress.
+type CompressOptions struct {
+	sweeps           int
+	truncationReport *TruncationReport
+}
+
+// NewCompressOptions returns the default compression options.
+func NewCompressOptions() CompressOptions {
+	opt := CompressOptions{}
+	opt.sweeps = 2
+	return opt
+}
+
+// Sweeps sets the number of variational sweeps after the SVD compression, each of which is a right sweep followed by a left sweep.
+// If it is zero, the result is that of the SVD compression alone.
+func (opt CompressOptions) Sweeps(n int) CompressOptions {
+	opt.sweeps = n
+	return opt
+}
+
+// TruncationReport sets where the discarded weights of the SVD compression are accumulated.
+func (opt CompressOptions) TruncationReport(r *TruncationReport) CompressOptions {
+	opt.truncationReport = r
+	return opt
+}
+
+// Compress sets dst to a normalized MPS of bond dimensions at most maxD that approximates src, such as the results of Superpose or the application of an MPO,
+// whose bond dimensions are the sums or products of those of their inputs.
+// dst is first the SVD compression of src, which keeps at most maxD singular values of each bond such that the discarded weight is within tol.
+// Since each SVD truncates a bond given the truncations of the bonds to its right, the result is not optimal,
+// which the variational sweeps correct by minimizing ||dst - src|| with respect to one site at a time, keeping the bond dimensions of dst.
+// It returns the fidelity |<dst|src>|^2 / <src|src>, which is one minus the squared distance between the normalized states.
+// dst must have the same length as src, and may contain nil tensors, but no tensors of src, which is not modified.
+// Upon return, dst[1:] is right normalized.
+// See Section 4.5.1 Compressing a matrix product state by SVD, and Section 4.5.2 Compressing a matrix product state iteratively, Ulrich Schollwock.
+func Compress(dst, src []*tensor.Dense, maxD int, tol float32, bufs [10]*tensor.Dense, options ...CompressOptions) (float32, error) {
+	opt := NewCompressOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if len(dst) != len(src) || len(src) == 0 || maxD < 1 || opt.sweeps < 0 {
+		return 0, errors.Errorf("%d %d %d %d", len(dst), len(src), maxD, opt.sweeps)
+	}
+	for i := range dst {
+		for _, m := range src {
+			if dst[i] == m {
+				return 0, errors.Errorf("shared tensor %d", i)
+			}
+		}
+		if dst[i] == nil {
+			dst[i] = tensor.Zeros(1)
+		}
+		resetCopy(dst[i], src[i])
+	}
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	srcNorm2 := real(InnerProduct(src, src, bufs2))
+	if !(srcNorm2 > 0) {
+		return 0, errors.Errorf("zero state %f", srcNorm2)
+	}
+
+	if err := compressMPS(dst, maxD, tol, opt.truncationReport, bufs); err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	if opt.sweeps > 0 {
+		if err := compressVariational(dst, src, opt.sweeps, bufs); err != nil {
+			return 0, errors.Wrap(err, "")
+		}
+	}
+
+	overlap := InnerProduct(dst, src, bufs2)
+	fidelity := (real(overlap)*real(overlap) + imag(overlap)*imag(overlap)) / srcNorm2
+	return min(fidelity, 1), nil
+}
+
+// compressProjection returns the compression of src by Compress, scaled to the projection of src onto it,
+// which keeps the norm and the phase of src up to the truncation, unlike the normalized result of Compress.
+// src must not be zero.
+func compressProjection(src []*tensor.Dense, maxD int, tol float32, bufs [10]*tensor.Dense) ([]*tensor.Dense, error) {
+	dst := make([]*tensor.Dense, len(src))
+	if _, err := Compress(dst, src, maxD, tol, bufs); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	dst[0].Mul(InnerProduct(dst, src, [2]*tensor.Dense(bufs[:2])))
+	return dst, nil
+}
+
+// compressVariational sweeps the single-site updates that minimize ||dst - src||, starting from dst whose orthogonality center is at the first site.
+// With the orthogonality center of dst at site l, the optimal dst[l] is the contraction of src[l] with the overlaps of dst and src on either side of l,
+// after which the center is moved to the next site by a QR or LQ decomposition as in the single-site ground state search.
+// Upon return, dst is normalized, and dst[1:] is right normalized.
+// See Section 4.5.2 Compressing a matrix product state iteratively, Ulrich Schollwock.
+func compressVariational(dst, src []*tensor.Dense, sweeps int, bufs [10]*tensor.Dense) error {
+	n := len(dst)
+	// lefts[l] is the overlap of dst[:l+1] and src[:l+1], of shape {dst right, src right},
+	// and rights[l] that of dst[l:] and src[l:], of shape {dst left, src left}.
+	lefts, rights := make([]*tensor.Dense, n), make([]*tensor.Dense, n)
+	for l := range n {
+		lefts[l], rights[l] = tensor.Zeros(1), tensor.Zeros(1)
+	}
+	left := func(l int) *tensor.Dense {
+		if l < 0 {
+			return ones(bufs[3], 1, 1)
+		}
+		return lefts[l]
+	}
+	right := func(l int) *tensor.Dense {
+		if l >= n {
+			return ones(bufs[4], 1, 1)
+		}
+		return rights[l]
+	}
+	updateLeft := func(l int) {
+		fy := tensor.Product(bufs[0], left(l-1), src[l], [][2]int{{1, mpsLeftAxis}})
+		tensor.Product(lefts[l], dst[l].Conj(), fy, [][2]int{{mpsLeftAxis, 0}, {mpsUpAxis, 1}})
+	}
+	updateRight := func(l int) {
+		fy := tensor.Product(bufs[0], src[l], right(l+1), [][2]int{{mpsRightAxis, 1}})
+		tensor.Product(rights[l], dst[l].Conj(), fy, [][2]int{{mpsUpAxis, 1}, {mpsRightAxis, 2}})
+	}
+	// optimal returns the site l of shape {mpsLeft, mpsUp, mpsRight} that minimizes the distance, given the rest of dst.
+	optimal := func(l int) *tensor.Dense {
+		t := tensor.Product(bufs[0], left(l-1), src[l], [][2]int{{1, mpsLeftAxis}})
+		return tensor.Product(bufs[1], t, right(l+1), [][2]int{{2, 1}})
+	}
+
+	for l := n - 1; l >= 1; l-- {
+		updateRight(l)
+	}
+	for range sweeps {
+		for l := range n - 1 {
+			m := optimal(l)
+			dLeft, dUp := m.Shape()[mpsLeftAxis], m.Shape()[mpsUpAxis]
+			q := bufs[2]
+			linalg.QR(q, m.Reshape(dLeft*dUp, -1), [2]*tensor.Dense{bufs[5], bufs[6]})
+			dst[l] = resetCopy(dst[l], q).Reshape(dLeft, dUp, -1)
+			updateLeft(l)
+		}
+		for l := n - 1; l >= 1; l-- {
+			m := optimal(l)
+			dUp, dRight := m.Shape()[mpsUpAxis], m.Shape()[mpsRightAxis]
+			q := bufs[2]
+			lq(q, m.Reshape(m.Shape()[mpsLeftAxis], dUp*dRight), [2]*tensor.Dense{bufs[5], bufs[6]})
+			dst[l] = resetCopy(dst[l], q.H()).Reshape(-1, dUp, dRight)
+			updateRight(l)
+		}
+		resetCopy(dst[0], optimal(0))
+	}
+
+	norm := dst[0].FrobeniusNorm()
+	if norm == 0 {
+		return errors.Errorf("orthogonal states")
+	}
+	dst[0].Mul(complex(1/norm, 0))
+	return nil
+}
+
+// compressMPS truncates the bond dimensions of ms to at most maxD with SVDs sweeping from the last site,
+// after bringing ms to the left canonical form, and records the discarded weights in report if it is not nil.
+// Upon return, ms is normalized, and ms[1:] is right normalized.
+// See Section 4.5.1 Compressing a matrix product state by SVD, Ulrich Schollwock.
+func compressMPS(ms []*tensor.Dense, maxD int, truncationErr float32, report *TruncationReport, bufs [10]*tensor.Dense) error {
+	leftNormalizeAll(ms, bufs[:3])
+	for i := len(ms) - 1; i >= 1; i-- {
+		s := ms[i].Shape()
+		dUp, dRight := s[mpsUpAxis], s[mpsRightAxis]
+		a := resetCopy(bufs[2], ms[i]).Reshape(s[mpsLeftAxis], dUp*dRight)
+		u, vh, sv, discarded, err := truncatedSVD(bufs[3], bufs[4], a, maxD, truncationErr, [4]*tensor.Dense(bufs[5:9]))
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		report.add(i-1, discarded)
+
+		ms[i] = resetCopy(ms[i], vh).Reshape(-1, dUp, dRight)
+		us := tensor.MatMul(bufs[0], u, sv)
+		resetCopy(ms[i-1], tensor.Product(bufs[1], ms[i-1], us, [][2]int{{mpsRightAxis, 0}}))
+	}
+	norm := ms[0].FrobeniusNorm()
+	if norm == 0 {
+		return errors.Errorf("zero state")
+	}
+	ms[0].Mul(complex(1/norm, 0))
+	return nil
+}
@@ -0,0 +1,124 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n    int
+		maxD int
+		// exact is whether maxD is no smaller than the bond dimensions of the superposition, which is then reproduced.
+		exact bool
+	}{
+		{n: 6, maxD: 8, exact: true},
+		{n: 8, maxD: 3},
+		{n: 10, maxD: 5},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+			bufs2 := [2]*tensor.Dense(bufs[:2])
+			r := rand.New(rand.NewPCG(uint64(i), 0))
+			ws := Ising([2]int{test.n, 1}, 1)
+			src := Superpose([][]*tensor.Dense{RandMPSWithRand(r, ws, 4), RandMPSWithRand(r, ws, 4)}, []complex64{1, 0.5i})
+			srcNorm2 := real(InnerProduct(src, src, bufs2))
+			want := product(tensor.Zeros(1), src, tensor.Zeros(1))
+
+			svd := make([]*tensor.Dense, test.n)
+			svdFidelity, err := Compress(svd, src, test.maxD, 0, bufs, NewCompressOptions().Sweeps(0))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			dst := make([]*tensor.Dense, test.n)
+			report := NewTruncationReport(test.n)
+			fidelity, err := Compress(dst, src, test.maxD, 0, bufs, NewCompressOptions().Sweeps(4).TruncationReport(report))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// src is not modified.
+			if err := product(tensor.Zeros(1), src, tensor.Zeros(1)).Equal(want, 0); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for l, m := range dst {
+				if m.Shape()[mpsRightAxis] > test.maxD {
+					t.Fatalf("%d %#v", l, m.Shape())
+				}
+			}
+			if n2 := real(InnerProduct(dst, dst, bufs2)); math.Abs(float64(n2-1)) > 1e-5 {
+				t.Fatalf("%f", n2)
+			}
+			overlap := abs(InnerProduct(dst, src, bufs2))
+			if wantFidelity := overlap * overlap / srcNorm2; math.Abs(float64(fidelity-wantFidelity)) > 1e-5 {
+				t.Fatalf("%f %f", fidelity, wantFidelity)
+			}
+			// The variational sweeps improve on the SVD compression, whose infidelity is bounded by the discarded weight.
+			if fidelity < svdFidelity-1e-5 {
+				t.Fatalf("%f %f", fidelity, svdFidelity)
+			}
+			if infidelity := float64(1 - svdFidelity); infidelity > 2*report.Total()+1e-5 {
+				t.Fatalf("%g %g", infidelity, report.Total())
+			}
+			if test.exact && math.Abs(float64(fidelity-1)) > 1e-5 {
+				t.Fatalf("%f", fidelity)
+			}
+			if !test.exact && !(fidelity < 1-1e-5) {
+				t.Fatalf("%f", fidelity)
+			}
+		})
+	}
+}
+
+func TestCompressSharedTensors(t *testing.T) {
+	t.Parallel()
+	var bufs [10]*tensor.Dense
+	for j := range bufs {
+		bufs[j] = tensor.Zeros(1)
+	}
+	src := RandMPSWithRand(rand.New(rand.NewPCG(0, 0)), Ising([2]int{4, 1}, 1), 2)
+	dst := []*tensor.Dense{nil, src[0], nil, nil}
+	if _, err := Compress(dst, src, 2, 0, bufs); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestCompressMPS(t *testing.T) {
+	t.Parallel()
+	ws := Ising([2]int{6, 1}, 1)
+	r := rand.New(rand.NewPCG(1, 1))
+	x := RandMPSWithRand(r, ws, 8)
+	var bufs [10]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	norm2 := real(InnerProduct(x, x, bufs2))
+
+	// Adding x to itself doubles the bond dimensions, which the compression restores.
+	z := addMPS(x, x, 1, 1)
+	if err := compressMPS(z, 8, 1e-12, nil, bufs); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for i, zi := range z {
+		if zi.Shape()[mpsRightAxis] > x[i].Shape()[mpsRightAxis] {
+			t.Fatalf("%d %v %v", i, zi.Shape(), x[i].Shape())
+		}
+	}
+	if nz := real(InnerProduct(z, z, bufs2)); abs(complex(nz-1, 0)) > 1e-5 {
+		t.Fatalf("%f", nz)
+	}
+	if fidelity := abs(InnerProduct(x, z, bufs2)) / float32(math.Sqrt(float64(norm2))); abs(complex(fidelity-1, 0)) > 1e-5 {
+		t.Fatalf("%f", fidelity)
+	}
+}
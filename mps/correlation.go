@@ -0,0 +1,204 @@
+package mps
+
+import (
+	"fmt"
+
+	"github.com/fumin/tensor"
+)
+
+// Correlation returns <psi|A_i B_j|psi>, where opA and opB are single site operators acting on sites i and j.
+// Similar to InnerProduct, the result is not divided by <psi|psi>.
+func Correlation(ms []*tensor.Dense, opA, opB *tensor.Dense, i, j int, bufs [3]*tensor.Dense) complex64 {
+	if i < 0 || i >= len(ms) || j < 0 || j >= len(ms) {
+		panic(fmt.Sprintf("%d %d %d", i, j, len(ms)))
+	}
+	var opAB *tensor.Dense
+	if i == j {
+		opAB = tensor.MatMul(tensor.Zeros(1), opA, opB)
+	}
+
+	f := ones(bufs[0], 1, 1)
+	for k, m := range ms {
+		var op *tensor.Dense
+		switch {
+		case k == i && k == j:
+			op = opAB
+		case k == i:
+			op = opA
+		case k == j:
+			op = opB
+		}
+		f = transferLeft(f, m, op, [2]*tensor.Dense(bufs[1:]))
+	}
+	return f.At(0, 0)
+}
+
+// LocalExpectation returns <psi|op_site|psi> / <psi|psi>, where op is a single site operator acting on site.
+func LocalExpectation(ms []*tensor.Dense, op *tensor.Dense, site int, bufs [3]*tensor.Dense) complex64 {
+	if site < 0 || site >= len(ms) {
+		panic(fmt.Sprintf("%d %d", site, len(ms)))
+	}
+	f := ones(bufs[0], 1, 1)
+	for k, m := range ms {
+		var o *tensor.Dense
+		if k == site {
+			o = op
+		}
+		f = transferLeft(f, m, o, [2]*tensor.Dense(bufs[1:]))
+	}
+	// Read the expectation before InnerProduct reuses the buffer of f.
+	v := f.At(0, 0)
+	return v / InnerProduct(ms, ms, [2]*tensor.Dense(bufs[:2]))
+}
+
+// SiteProfile returns the profile <psi|op_i|psi> / <psi|psi> of the single site operator op for every site i,
+// such as the local magnetization <Z_i>, whose deviations near the ends of an open chain are averaged out by the total magnetization.
+// It costs about three times a single LocalExpectation, by caching the environments on the right of each site.
+func SiteProfile(ms []*tensor.Dense, op *tensor.Dense, bufs [3]*tensor.Dense) []complex64 {
+	// rights[k] is the contraction of sites k, k+1, ... len(ms)-1.
+	rights := make([]*tensor.Dense, len(ms)+1)
+	rights[len(ms)] = ones(tensor.Zeros(1), 1, 1)
+	for k := len(ms) - 1; k >= 0; k-- {
+		rights[k] = resetCopy(tensor.Zeros(1), transferRight(bufs[0], rights[k+1], ms[k], nil, [2]*tensor.Dense(bufs[1:])))
+	}
+	psiIP := rights[0].At(0, 0)
+
+	profile := make([]complex64, len(ms))
+	left := ones(tensor.Zeros(1), 1, 1)
+	f := tensor.Zeros(1)
+	for i, m := range ms {
+		profile[i] = closeEnvironment(transferLeft(resetCopy(f, left), m, op, [2]*tensor.Dense(bufs[1:])), rights[i+1]) / psiIP
+		transferLeft(left, m, nil, [2]*tensor.Dense(bufs[1:]))
+	}
+	return profile
+}
+
+// BondExpectation returns <psi|A_bond B_{bond+1}|psi> / <psi|psi>, where opA and opB are single site operators acting on the two sites of the bond.
+func BondExpectation(ms []*tensor.Dense, opA, opB *tensor.Dense, bond int, bufs [3]*tensor.Dense) complex64 {
+	if bond < 0 || bond+1 >= len(ms) {
+		panic(fmt.Sprintf("%d %d", bond, len(ms)))
+	}
+	v := Correlation(ms, opA, opB, bond, bond+1, bufs)
+	return v / InnerProduct(ms, ms, [2]*tensor.Dense(bufs[:2]))
+}
+
+// EnergyProfile returns the expectations <psi|h_l|psi> / <psi|psi> of the terms of the hamiltonian H = sum_l h_l,
+// where h_l acts on sites l and l+1 and is of shape {up0, up1, down0, down1}, such as the terms of IsingBonds.
+// The profile sums to the energy, and shows the boundary effects of open chains, as well as the bonds where an MPO differs from its terms.
+func EnergyProfile(ms, bonds []*tensor.Dense, bufs [5]*tensor.Dense) []complex64 {
+	if len(bonds) != len(ms)-1 {
+		panic(fmt.Sprintf("%d %d", len(bonds), len(ms)))
+	}
+	// rights[k] is the contraction of sites k, k+1, ... len(ms)-1.
+	rights := make([]*tensor.Dense, len(ms)+1)
+	rights[len(ms)] = ones(tensor.Zeros(1), 1, 1)
+	for k := len(ms) - 1; k >= 0; k-- {
+		rights[k] = resetCopy(tensor.Zeros(1), transferRight(bufs[0], rights[k+1], ms[k], nil, [2]*tensor.Dense(bufs[1:3])))
+	}
+	psiIP := rights[0].At(0, 0)
+
+	profile := make([]complex64, len(bonds))
+	left := ones(tensor.Zeros(1), 1, 1)
+	for l, bond := range bonds {
+		// theta is of shape {mpsLeft, mpsUp0, mpsUp1, mpsRight}.
+		theta := tensor.Product(bufs[3], ms[l], ms[l+1], [][2]int{{mpsRightAxis, mpsLeftAxis}})
+		// ht is of shape {up0, up1, mpsLeft, mpsRight}.
+		ht := tensor.Product(bufs[4], bond, theta, [][2]int{{2, 1}, {3, 2}})
+		// lht is of shape {mpsLeft.conj, up0, up1, mpsRight.conj}.
+		lht := tensor.Product(bufs[1], left, ht, [][2]int{{1, 2}})
+		lht = tensor.Product(bufs[2], lht, rights[l+2], [][2]int{{3, 1}})
+
+		var v complex64
+		s := theta.Shape()
+		for a := range s[0] {
+			for i := range s[1] {
+				for j := range s[2] {
+					for b := range s[3] {
+						v += conj(theta.At(a, i, j, b)) * lht.At(a, i, j, b)
+					}
+				}
+			}
+		}
+		profile[l] = v / psiIP
+		transferLeft(left, ms[l], nil, [2]*tensor.Dense(bufs[1:3]))
+	}
+	return profile
+}
+
+// ZZCorrelations returns the correlation function C(r) = <psi|Z_i Z_{i+r}|psi> / <psi|psi> for r = 0, 1, ..., len(ms)-1.
+// Since a finite chain is not translation invariant, C(r) is averaged over all sites i.
+// The connected correlation function can be obtained by subtracting the squared magnetization.
+func ZZCorrelations(ms []*tensor.Dense, bufs [3]*tensor.Dense) []complex64 {
+	z := tensor.T2(pauliZ)
+	zz := tensor.MatMul(tensor.Zeros(1), z, z)
+
+	// rights[k] is the contraction of sites k, k+1, ... len(ms)-1.
+	rights := make([]*tensor.Dense, len(ms)+1)
+	rights[len(ms)] = ones(tensor.Zeros(1), 1, 1)
+	for k := len(ms) - 1; k >= 0; k-- {
+		rights[k] = resetCopy(tensor.Zeros(1), transferRight(bufs[0], rights[k+1], ms[k], nil, [2]*tensor.Dense(bufs[1:])))
+	}
+	psiIP := rights[0].At(0, 0)
+
+	corr := make([]complex64, len(ms))
+	left := ones(tensor.Zeros(1), 1, 1)
+	f := tensor.Zeros(1)
+	for i, mi := range ms {
+		corr[0] += closeEnvironment(transferLeft(resetCopy(f, left), mi, zz, [2]*tensor.Dense(bufs[1:])), rights[i+1])
+
+		// f is the contraction of sites up to j-1, with Z inserted at site i.
+		transferLeft(resetCopy(f, left), mi, z, [2]*tensor.Dense(bufs[1:]))
+		for j := i + 1; j < len(ms); j++ {
+			fz := transferLeft(resetCopy(bufs[0], f), ms[j], z, [2]*tensor.Dense(bufs[1:]))
+			corr[j-i] += closeEnvironment(fz, rights[j+1])
+			transferLeft(f, ms[j], nil, [2]*tensor.Dense(bufs[1:]))
+		}
+
+		transferLeft(left, mi, nil, [2]*tensor.Dense(bufs[1:]))
+	}
+
+	for r := range corr {
+		corr[r] /= complex(float32(len(ms)-r), 0) * psiIP
+	}
+	return corr
+}
+
+// transferLeft contracts f of shape {fTop, fBottom} with site m, with an optional operator op acting on m.
+// The result is stored in f, and is of shape {mpsRight.conj, mpsRight}.
+func transferLeft(f, m, op *tensor.Dense, bufs [2]*tensor.Dense) *tensor.Dense {
+	// fm is of shape {fTop, mpsUp, mpsRight}.
+	fm := tensor.Product(bufs[0], f, m, [][2]int{{1, mpsLeftAxis}})
+	topAxis, upAxis := 0, 1
+	if op != nil {
+		// fm is of shape {opUp, fTop, mpsRight}.
+		fm = tensor.Product(bufs[1], op, fm, [][2]int{{1, 1}})
+		topAxis, upAxis = 1, 0
+	}
+	return tensor.Product(f, m.Conj(), fm, [][2]int{{mpsLeftAxis, topAxis}, {mpsUpAxis, upAxis}})
+}
+
+// transferRight contracts f of shape {fTop, fBottom} with site m from the right, with an optional operator op acting on m.
+// The result is stored in dst, and is of shape {mpsLeft.conj, mpsLeft}.
+func transferRight(dst, f, m, op *tensor.Dense, bufs [2]*tensor.Dense) *tensor.Dense {
+	// fm is of shape {fTop, mpsLeft, mpsUp}.
+	fm := tensor.Product(bufs[0], f, m, [][2]int{{1, mpsRightAxis}})
+	topAxis, upAxis := 0, 2
+	if op != nil {
+		// fm is of shape {opUp, fTop, mpsLeft}.
+		fm = tensor.Product(bufs[1], op, fm, [][2]int{{1, 2}})
+		topAxis, upAxis = 1, 0
+	}
+	return tensor.Product(dst, m.Conj(), fm, [][2]int{{mpsRightAxis, topAxis}, {mpsUpAxis, upAxis}})
+}
+
+// closeEnvironment returns the full contraction of a left environment with a right environment.
+func closeEnvironment(left, right *tensor.Dense) complex64 {
+	var v complex64
+	s := left.Shape()
+	for i := range s[0] {
+		for j := range s[1] {
+			v += left.At(i, j) * right.At(i, j)
+		}
+	}
+	return v
+}
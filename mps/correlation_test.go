@@ -0,0 +1,255 @@
+package mps
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestCorrelation(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		i, j int
+		opA  [][]complex64
+		opB  [][]complex64
+	}
+	tests := []testcase{
+		{i: 0, j: 5, opA: pauliZ, opB: pauliZ},
+		{i: 1, j: 3, opA: pauliX, opB: pauliY},
+		{i: 4, j: 2, opA: pauliY, opB: pauliZ},
+		{i: 2, j: 2, opA: pauliX, opB: pauliZ},
+		{i: 5, j: 5, opA: pauliZ, opB: identity},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [3]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			ms := RandMPS(Ising([2]int{6, 1}, 1), 4)
+			opA, opB := tensor.T2(test.opA), tensor.T2(test.opB)
+
+			c := Correlation(ms, opA, opB, test.i, test.j, bufs)
+
+			ops := make([]*tensor.Dense, len(ms))
+			ops[test.i] = opA
+			if test.i == test.j {
+				ops[test.i] = tensor.MatMul(tensor.Zeros(1), opA, opB)
+			} else {
+				ops[test.j] = opB
+			}
+			want := exactExpectation(ms, ops)
+			if abs(c-want) > 1e-4*max(abs(want), 1) {
+				t.Fatalf("%v %v", c, want)
+			}
+		})
+	}
+}
+
+func TestLocalExpectation(t *testing.T) {
+	t.Parallel()
+	var bufs [3]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	ms := RandMPS(Ising([2]int{6, 1}, 1), 4)
+	psiIP := InnerProduct(ms, ms, [2]*tensor.Dense(bufs[:2]))
+	for _, op := range [][][]complex64{pauliX, pauliY, pauliZ} {
+		o := tensor.T2(op)
+		profile := SiteProfile(ms, o, bufs)
+		if len(profile) != len(ms) {
+			t.Fatalf("%d %d", len(profile), len(ms))
+		}
+		for site := range ms {
+			ops := make([]*tensor.Dense, len(ms))
+			ops[site] = o
+			want := exactExpectation(ms, ops) / psiIP
+			if got := LocalExpectation(ms, o, site, bufs); abs(got-want) > 1e-4*max(abs(want), 1) {
+				t.Fatalf("%d %v %v", site, got, want)
+			}
+			if abs(profile[site]-want) > 1e-4*max(abs(want), 1) {
+				t.Fatalf("%d %v %v", site, profile[site], want)
+			}
+		}
+	}
+}
+
+func TestSiteProfileEdges(t *testing.T) {
+	t.Parallel()
+	var bufs [10]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	ws := Ising([2]int{12, 1}, 2)
+	fs := make([]*tensor.Dense, 0, len(ws))
+	for range ws {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	ms := RandMPSWithRand(rand.New(rand.NewPCG(0, 0)), ws, 8)
+	if err := SearchGroundState(fs, ws, ms, bufs, NewSearchGroundStateOptions().Tol(1e-6).MaxBondDim(8)); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// The end sites of an open chain have a single bond, hence fluctuate less and align more with the transverse field than the bulk.
+	profile := SiteProfile(ms, tensor.T2(pauliX), [3]*tensor.Dense(bufs[:3]))
+	n := len(profile)
+	for i := range n / 2 {
+		if abs(profile[i]-profile[n-1-i]) > 1e-3 {
+			t.Fatalf("%d %v", i, profile)
+		}
+	}
+	if !(real(profile[0]) > real(profile[n/2])+0.01) {
+		t.Fatalf("%v", profile)
+	}
+}
+
+func TestBondExpectation(t *testing.T) {
+	t.Parallel()
+	var bufs [3]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	ms := RandMPS(Ising([2]int{5, 1}, 1), 4)
+	psiIP := InnerProduct(ms, ms, [2]*tensor.Dense(bufs[:2]))
+	opA, opB := tensor.T2(pauliZ), tensor.T2(pauliX)
+	for bond := range len(ms) - 1 {
+		ops := make([]*tensor.Dense, len(ms))
+		ops[bond], ops[bond+1] = opA, opB
+		want := exactExpectation(ms, ops) / psiIP
+		if got := BondExpectation(ms, opA, opB, bond, bufs); abs(got-want) > 1e-4*max(abs(want), 1) {
+			t.Fatalf("%d %v %v", bond, got, want)
+		}
+	}
+}
+
+func TestEnergyProfile(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n   int
+		h   complex64
+		opt IsingOptions
+	}{
+		{n: 6, h: 1, opt: NewIsingOptions()},
+		{n: 7, h: 0.5, opt: NewIsingOptions().LongitudinalField(0.3)},
+		{n: 2, h: 2, opt: NewIsingOptions().SpinHalf(true)},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [5]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			ws := Ising([2]int{test.n, 1}, test.h, test.opt)
+			ms := RandMPSWithRand(rand.New(rand.NewPCG(uint64(i), 0)), ws, 4)
+			bonds := IsingBonds(test.n, test.h, test.opt)
+
+			profile := EnergyProfile(ms, bonds, bufs)
+			if len(profile) != test.n-1 {
+				t.Fatalf("%d %d", len(profile), test.n-1)
+			}
+			psiIP := InnerProduct(ms, ms, [2]*tensor.Dense(bufs[:2]))
+			var sum complex64
+			for l, e := range profile {
+				// Each term is the coupling of its bond and the shares of the fields of its sites.
+				want := exactBondExpectation(ms, bonds[l], l) / psiIP
+				if abs(e-want) > 1e-4*max(abs(want), 1) {
+					t.Fatalf("%d %v %v", l, e, want)
+				}
+				sum += e
+			}
+			fs := make([]*tensor.Dense, 0, len(ws))
+			for range ws {
+				fs = append(fs, tensor.Zeros(1))
+			}
+			if e0 := LExpressions(fs, ws, ms, [2]*tensor.Dense(bufs[:2])) / psiIP; abs(sum-e0) > 1e-4*max(abs(e0), 1) {
+				t.Fatalf("%v %v", sum, e0)
+			}
+		})
+	}
+}
+
+// exactBondExpectation returns <psi|h_l|psi> of the two site term h of shape {up0, up1, down0, down1} acting on sites l and l+1, from the dense state.
+func exactBondExpectation(ms []*tensor.Dense, h *tensor.Dense, l int) complex64 {
+	state := product(tensor.Zeros(1), ms, tensor.Zeros(1))
+	s := state.Shape()
+	psi := resetCopy(tensor.Zeros(1), state).Reshape(s[1 : len(s)-1]...)
+	var v complex64
+	for idx := range psi.All() {
+		for _, down := range [][2]int{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+			c := h.At(idx[l], idx[l+1], down[0], down[1])
+			if c == 0 {
+				continue
+			}
+			src := append([]int{}, idx...)
+			src[l], src[l+1] = down[0], down[1]
+			v += conj(psi.At(idx...)) * c * psi.At(src...)
+		}
+	}
+	return v
+}
+
+func TestZZCorrelations(t *testing.T) {
+	t.Parallel()
+	var bufs [3]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	ms := RandMPS(Ising([2]int{7, 1}, 1), 4)
+	z := tensor.T2(pauliZ)
+	psiIP := InnerProduct(ms, ms, [2]*tensor.Dense(bufs[:2]))
+
+	corr := ZZCorrelations(ms, bufs)
+
+	if len(corr) != len(ms) {
+		t.Fatalf("%d %d", len(corr), len(ms))
+	}
+	for r := range corr {
+		var want complex64
+		for i := 0; i+r < len(ms); i++ {
+			want += Correlation(ms, z, z, i, i+r, bufs)
+		}
+		want /= complex(float32(len(ms)-r), 0) * psiIP
+		if abs(corr[r]-want) > 1e-5 {
+			t.Fatalf("%d %v %v", r, corr[r], want)
+		}
+	}
+	if abs(corr[0]-1) > 1e-5 {
+		t.Fatalf("%v", corr[0])
+	}
+}
+
+// exactExpectation returns <psi|O|psi>, where O is the tensor product of ops, and nil ops are the identity.
+func exactExpectation(ms []*tensor.Dense, ops []*tensor.Dense) complex64 {
+	state := product(tensor.Zeros(1), ms, tensor.Zeros(1))
+	s := state.Shape()
+	state = state.Reshape(s[1 : len(s)-1]...)
+
+	opState := resetCopy(tensor.Zeros(1), state)
+	buf := tensor.Zeros(1)
+	for k, op := range ops {
+		if op == nil {
+			continue
+		}
+		// Apply op on axis k, and move the resulting axis back to position k.
+		opPsi := tensor.Product(buf, op, opState, [][2]int{{1, k}})
+		axes := make([]int, 0, len(s))
+		for a := 1; a <= k; a++ {
+			axes = append(axes, a)
+		}
+		axes = append(axes, 0)
+		for a := k + 1; a < len(ops); a++ {
+			axes = append(axes, a)
+		}
+		resetCopy(opState, opPsi.Transpose(axes...))
+	}
+
+	var v complex64
+	for ijk := range state.All() {
+		v += conj(state.At(ijk...)) * opState.At(ijk...)
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+package mps
+
+import (
+	"fmt"
+
+	"github.com/fumin/qising/debug"
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// The checks in this file are run during sweeps only if debug.Enabled.
+
+// checkLeftCanonical checks that ms[l] is left normalized, and that fs[l] is the L expression of ms[:l+1], recomputed from scratch.
+func checkLeftCanonical(fs, ws, ms []*tensor.Dense, l int) error {
+	s := ms[l].Shape()
+	m := resetCopy(tensor.Zeros(1), ms[l]).Reshape(s[mpsLeftAxis]*s[mpsUpAxis], s[mpsRightAxis])
+	if err := debug.Orthonormal(m); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%d", l))
+	}
+
+	f := ones(tensor.Zeros(1), 1, 1, 1)
+	bufs := []*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+	for i := range l + 1 {
+		f = lExpression(tensor.Zeros(1), f, ws[i], ms[i], bufs)
+	}
+	if err := debug.Close(fs[l], f); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%d", l))
+	}
+	return nil
+}
+
+// checkRightCanonical checks that ms[l] is right normalized, and that fs[l] is the R expression of ms[l:], recomputed from scratch.
+func checkRightCanonical(fs, ws, ms []*tensor.Dense, l int) error {
+	s := ms[l].Shape()
+	m := resetCopy(tensor.Zeros(1), ms[l]).Reshape(s[mpsLeftAxis], s[mpsUpAxis]*s[mpsRightAxis])
+	if err := debug.Orthonormal(m.H()); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%d", l))
+	}
+
+	f := ones(tensor.Zeros(1), 1, 1, 1)
+	bufs := []*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+	for i := len(ms) - 1; i >= l; i-- {
+		f = rExpression(tensor.Zeros(1), f, ws[i], ms[i], bufs)
+	}
+	if err := debug.Close(fs[l], f); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%d", l))
+	}
+	return nil
+}
+
+// checkHermitian checks that the effective hamiltonian h is Hermitian, by checking <x|h|y> = conj(<y|h|x>) for random x and y.
+// Since the effective hamiltonian of a non-Hermitian MPO need not be Hermitian, the check is skipped unless every operator in the MPO sites ws is Hermitian.
+func checkHermitian(h linalg.LinearOperator, ws ...*tensor.Dense) error {
+	for _, w := range ws {
+		s := w.Shape()
+		for a := range s[mpoLeftAxis] {
+			for b := range s[mpoRightAxis] {
+				op := w.Slice([][2]int{{a, a + 1}, {b, b + 1}, {0, s[mpoUpAxis]}, {0, s[mpoDownAxis]}})
+				if debug.Hermitian(resetCopy(tensor.Zeros(1), op).Reshape(s[mpoUpAxis], s[mpoDownAxis])) != nil {
+					return nil
+				}
+			}
+		}
+	}
+
+	x, y := randTensor(h.Dim(), 1), randTensor(h.Dim(), 1)
+	xhy := tensor.MatMul(tensor.Zeros(1), x.H(), h.Apply(tensor.Zeros(1), y)).At(0, 0)
+	yhx := tensor.MatMul(tensor.Zeros(1), y.H(), h.Apply(tensor.Zeros(1), x)).At(0, 0)
+	if err := debug.Close(tensor.T1([]complex64{xhy}), tensor.T1([]complex64{conj(yhx)})); err != nil {
+		return errors.Wrap(err, fmt.Sprintf("%v %v", xhy, yhx))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package mps
+
+import (
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestCheckCanonical(t *testing.T) {
+	t.Parallel()
+	ws := Ising([2]int{6, 1}, 0.5)
+	ms := RandMPS(ws, 4)
+	bufs := [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+	leftNormalizeAll(ms, []*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)})
+	fs := make([]*tensor.Dense, len(ws))
+	for i := range fs {
+		fs[i] = tensor.Zeros(1)
+	}
+	LExpressions(fs, ws, ms, bufs)
+
+	l := 2
+	if err := checkLeftCanonical(fs, ws, ms, l); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// Since the last site holds the norm, it is not left normalized.
+	if err := checkLeftCanonical(fs, ws, ms, len(ms)-1); err == nil {
+		t.Fatalf("not normalized")
+	}
+	// A stale L expression is detected.
+	fs[l].Mul(2)
+	if err := checkLeftCanonical(fs, ws, ms, l); err == nil {
+		t.Fatalf("stale L expression")
+	}
+
+	rightNormalizeAll(ms, []*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)})
+	RExpressions(fs, ws, ms, bufs)
+	if err := checkRightCanonical(fs, ws, ms, l); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func TestCheckHermitian(t *testing.T) {
+	t.Parallel()
+	left, right := randTensor(3, 3, 3), randTensor(4, 3, 4)
+	w := Ising([2]int{3, 1}, 0.5)[1]
+	h := newEffectiveH(nil)
+	h.set(left, right, w)
+	// Random L and R expressions do not make a Hermitian effective hamiltonian.
+	if err := checkHermitian(h, w); err == nil {
+		t.Fatalf("not Hermitian")
+	}
+	// The check is skipped for non-Hermitian MPOs.
+	if err := checkHermitian(h, Ising([2]int{3, 1}, 0.5i)[1]); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package mps
+
+import (
+	"fmt"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// WeightedMPS is a member of a statistical ensemble, such as a minimally entangled typical thermal state of METTS,
+// a target state of state-averaged DMRG, or a state of a degenerate manifold.
+type WeightedMPS struct {
+	// Weight is the non-negative probability of the state, up to normalization.
+	Weight float64
+	// MPS is the state, which need not be normalized.
+	MPS []*tensor.Dense
+}
+
+// EnsembleExpectation returns Tr(rho W) of the MPO ws in the mixed state rho = sum_k w_k |psi_k><psi_k| / <psi_k|psi_k> / sum_k w_k.
+// Unlike for a superposition, which is built by Superpose, the states do not interfere.
+func EnsembleExpectation(ws []*tensor.Dense, ensemble []WeightedMPS, bufs [2]*tensor.Dense) (complex64, error) {
+	fs := make([]*tensor.Dense, len(ws))
+	for i := range fs {
+		fs[i] = tensor.Zeros(1)
+	}
+	v, err := ensembleAverage(ensemble, func(ms []*tensor.Dense) []complex64 {
+		return []complex64{LExpressions(fs, ws, ms, bufs) / InnerProduct(ms, ms, bufs)}
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	return v[0], nil
+}
+
+// EnsembleCorrelation returns Tr(rho A_i B_j) in the mixed state rho of ensemble, see EnsembleExpectation.
+// Unlike Correlation, the correlation of each state is divided by its norm.
+func EnsembleCorrelation(ensemble []WeightedMPS, opA, opB *tensor.Dense, i, j int, bufs [3]*tensor.Dense) (complex64, error) {
+	v, err := ensembleAverage(ensemble, func(ms []*tensor.Dense) []complex64 {
+		return []complex64{Correlation(ms, opA, opB, i, j, bufs) / InnerProduct(ms, ms, [2]*tensor.Dense(bufs[:2]))}
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	return v[0], nil
+}
+
+// EnsembleZZCorrelations returns the ensemble average of the ZZCorrelations of each state.
+func EnsembleZZCorrelations(ensemble []WeightedMPS, bufs [3]*tensor.Dense) ([]complex64, error) {
+	v, err := ensembleAverage(ensemble, func(ms []*tensor.Dense) []complex64 { return ZZCorrelations(ms, bufs) })
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	return v, nil
+}
+
+// EnsembleStatistics returns the magnetization statistics of the mixed state of ensemble, see EnsembleExpectation.
+// The moments of the magnetization are averaged over the ensemble, from which the susceptibility and the Binder cumulant are derived,
+// hence they are not the averages of those of the individual states.
+func EnsembleStatistics(ensemble []WeightedMPS, bufs [2]*tensor.Dense) (MagnetizationStatistics, error) {
+	v, err := ensembleAverage(ensemble, func(ms []*tensor.Dense) []complex64 {
+		m, m2, m4 := magnetizationMoments(ms, bufs)
+		return []complex64{complex(float32(m), 0), complex(float32(m2), 0), complex(float32(m4), 0)}
+	})
+	if err != nil {
+		return MagnetizationStatistics{}, errors.Wrap(err, "")
+	}
+	return newMagnetizationStatistics(len(ensemble[0].MPS), float64(real(v[0])), float64(real(v[1])), float64(real(v[2]))), nil
+}
+
+// ensembleAverage returns the weighted average of the values of the states in ensemble, which are computed by f.
+func ensembleAverage(ensemble []WeightedMPS, f func(ms []*tensor.Dense) []complex64) ([]complex64, error) {
+	if len(ensemble) == 0 {
+		return nil, errors.Errorf("empty ensemble")
+	}
+	var total float64
+	for i, e := range ensemble {
+		if e.Weight < 0 {
+			return nil, errors.Errorf("%d %f", i, e.Weight)
+		}
+		if len(e.MPS) != len(ensemble[0].MPS) {
+			return nil, errors.Errorf("%d %d %d", i, len(e.MPS), len(ensemble[0].MPS))
+		}
+		total += e.Weight
+	}
+	if !(total > 0) {
+		return nil, errors.Errorf("%f", total)
+	}
+
+	var avg []complex64
+	for _, e := range ensemble {
+		// Skip the states that do not contribute.
+		if e.Weight == 0 {
+			continue
+		}
+		vs := f(e.MPS)
+		if avg == nil {
+			avg = make([]complex64, len(vs))
+		}
+		w := complex(float32(e.Weight/total), 0)
+		for i, v := range vs {
+			avg[i] += w * v
+		}
+	}
+	return avg, nil
+}
+
+// Superpose returns the MPS of the superposition sum_k c_k |psi_k> of states with the coefficients coefs.
+// The result is exact, with the bond dimensions being the sums of those of the states, and can be truncated by compression.
+// Its site tensors are block diagonal, except for the first and last sites which are the rows and columns of the blocks.
+// See Section 4.3 Adding two matrix product states, Ulrich Schollwock.
+func Superpose(states [][]*tensor.Dense, coefs []complex64) []*tensor.Dense {
+	if len(states) == 0 || len(states) != len(coefs) {
+		panic(fmt.Sprintf("%d %d", len(states), len(coefs)))
+	}
+	n := len(states[0])
+	for k, s := range states {
+		if len(s) != n {
+			panic(fmt.Sprintf("%d %d %d", k, len(s), n))
+		}
+	}
+
+	sum := make([]*tensor.Dense, 0, n)
+	for i := range n {
+		var left, right int
+		physD := states[0][i].Shape()[mpsUpAxis]
+		for _, s := range states {
+			shape := s[i].Shape()
+			if shape[mpsUpAxis] != physD {
+				panic(fmt.Sprintf("%d %v %d", i, shape, physD))
+			}
+			left += shape[mpsLeftAxis]
+			right += shape[mpsRightAxis]
+		}
+		// The dangling bonds at the ends of the chain are shared.
+		if i == 0 {
+			left = 1
+		}
+		if i == n-1 {
+			right = 1
+		}
+
+		m := tensor.Zeros(left, physD, right)
+		var l, r int
+		for k, s := range states {
+			block := resetCopy(tensor.Zeros(1), s[i])
+			// The coefficients are absorbed in the first site.
+			if i == 0 {
+				block.Mul(coefs[k])
+			}
+			// A single site holds the sum itself.
+			if n == 1 {
+				m.Add(1, block)
+				continue
+			}
+			m.Set([]int{l, 0, r}, block)
+
+			shape := s[i].Shape()
+			if i != 0 {
+				l += shape[mpsLeftAxis]
+			}
+			if i != n-1 {
+				r += shape[mpsRightAxis]
+			}
+		}
+		sum = append(sum, m)
+	}
+	return sum
+}
@@ -0,0 +1,143 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestSuperpose(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(1, 1))
+	site := func(v0, v1 complex64) []*tensor.Dense {
+		return []*tensor.Dense{tensor.T3([][][]complex64{{{v0}, {v1}}})}
+	}
+	tests := []struct {
+		states [][]*tensor.Dense
+		coefs  []complex64
+	}{
+		{
+			states: [][]*tensor.Dense{RandMPSWithRand(r, Ising([2]int{5, 1}, 1), 2), RandMPSWithRand(r, Ising([2]int{5, 1}, 1), 3)},
+			coefs:  []complex64{0.5, 2i},
+		},
+		{
+			states: [][]*tensor.Dense{
+				RandMPSWithRand(r, Ising([2]int{4, 1}, 1), 2),
+				RandMPSWithRand(r, Ising([2]int{4, 1}, 1), 1),
+				RandMPSWithRand(r, Ising([2]int{4, 1}, 1), 4),
+			},
+			coefs: []complex64{1, -1, 0.25},
+		},
+		{states: [][]*tensor.Dense{site(1, 0), site(0, 1)}, coefs: []complex64{3, 4}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			sum := Superpose(test.states, test.coefs)
+			got := product(tensor.Zeros(1), sum, tensor.Zeros(1))
+
+			want := tensor.Zeros(got.Shape()...)
+			for k, s := range test.states {
+				want.Add(test.coefs[k], product(tensor.Zeros(1), s, tensor.Zeros(1)))
+			}
+			for digits, v := range want.All() {
+				if g := got.At(digits...); cmplx.Abs(complex128(g-v)) > 1e-5 {
+					t.Fatalf("%v %v %v", digits, g, v)
+				}
+			}
+		})
+	}
+}
+
+func TestEnsemble(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(2, 2))
+	n := 5
+	ensemble := []WeightedMPS{
+		{Weight: 1, MPS: RandMPSWithRand(r, Ising([2]int{n, 1}, 1), 2)},
+		{Weight: 3, MPS: RandMPSWithRand(r, Ising([2]int{n, 1}, 1), 4)},
+		{Weight: 0, MPS: RandMPSWithRand(r, Ising([2]int{n, 1}, 1), 2)},
+	}
+	bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+
+	// The ensemble averages are the weighted averages of the normalized observables of the states.
+	var m, m2, m4 float64
+	var corr complex64
+	zzs := make([]complex64, n)
+	z := tensor.T2(pauliZ)
+	for _, e := range ensemble {
+		s := Statistics(e.MPS, bufs2)
+		w := e.Weight / 4
+		m += w * s.M
+		m2 += w * s.M2
+		m4 += w * s.M4
+		corr += complex(float32(w), 0) * Correlation(e.MPS, z, z, 1, 3, bufs) / InnerProduct(e.MPS, e.MPS, bufs2)
+		for r, c := range ZZCorrelations(e.MPS, bufs) {
+			zzs[r] += complex(float32(w), 0) * c
+		}
+	}
+
+	stats, err := EnsembleStatistics(ensemble, bufs2)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if math.Abs(stats.M-m) > 1e-5 || math.Abs(stats.M2-m2) > 1e-5 || math.Abs(stats.M4-m4) > 1e-5 {
+		t.Fatalf("%#v %f %f %f", stats, m, m2, m4)
+	}
+	if want := 1 - m4/(3*m2*m2); math.Abs(stats.BinderCumulant-want) > 1e-4 {
+		t.Fatalf("%f %f", stats.BinderCumulant, want)
+	}
+
+	mz, err := EnsembleExpectation(MagnetizationZ([2]int{n, 1}), ensemble, bufs2)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if math.Abs(float64(real(mz))/float64(n)-m) > 1e-5 {
+		t.Fatalf("%v %f", mz, m)
+	}
+
+	c, err := EnsembleCorrelation(ensemble, z, z, 1, 3, bufs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if cmplx.Abs(complex128(c-corr)) > 1e-5 {
+		t.Fatalf("%v %v", c, corr)
+	}
+
+	cs, err := EnsembleZZCorrelations(ensemble, bufs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for r := range cs {
+		if cmplx.Abs(complex128(cs[r]-zzs[r])) > 1e-5 {
+			t.Fatalf("%d %v %v", r, cs, zzs)
+		}
+	}
+}
+
+func TestEnsembleError(t *testing.T) {
+	t.Parallel()
+	ms := RandMPS(Ising([2]int{4, 1}, 1), 2)
+	tests := []struct {
+		ensemble []WeightedMPS
+	}{
+		{ensemble: nil},
+		{ensemble: []WeightedMPS{{Weight: -1, MPS: ms}}},
+		{ensemble: []WeightedMPS{{Weight: 0, MPS: ms}}},
+		{ensemble: []WeightedMPS{{Weight: 1, MPS: ms}, {Weight: 1, MPS: ms[:3]}}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			bufs := [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+			if _, err := EnsembleStatistics(test.ensemble, bufs); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// EntanglementEntropyOptions are options for computing the entanglement entropy.
+type EntanglementEntropyOptions struct {
+	renyi float32
+}
+
+// NewEntanglementEntropyOptions returns the default entanglement entropy options, which computes the von Neumann entropy.
+func NewEntanglementEntropyOptions() EntanglementEntropyOptions {
+	opt := EntanglementEntropyOptions{}
+	opt.renyi = 1
+	return opt
+}
+
+// Renyi sets the order n of the Renyi entropy log(sum_i p_i^n) / (1-n).
+// An order of 1 corresponds to the von Neumann entropy, and the order must be positive and finite.
+func (opt EntanglementEntropyOptions) Renyi(n float32) EntanglementEntropyOptions {
+	opt.renyi = n
+	return opt
+}
+
+// EntanglementEntropy returns the entanglement entropy between sites ms[:bond] and ms[bond:].
+// The entropy is computed from the singular values of the mixed canonical form in Equation 79, Section 4.4.3 Generation of a mixed-canonical MPS, Ulrich Schollwock.
+// ms is not modified.
+func EntanglementEntropy(ms []*tensor.Dense, bond int, bufs [5]*tensor.Dense, options ...EntanglementEntropyOptions) (float32, error) {
+	opt := NewEntanglementEntropyOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if bond < 1 || bond > len(ms)-1 {
+		return -1, errors.Errorf("%d %d", bond, len(ms))
+	}
+	if !(opt.renyi > 0) || math.IsInf(float64(opt.renyi), 1) {
+		return -1, errors.Errorf("%f", opt.renyi)
+	}
+
+	s, err := schmidtValues(ms, bond, bufs)
+	if err != nil {
+		return -1, errors.Wrap(err, "")
+	}
+	return entropy(s, opt.renyi), nil
+}
+
+// schmidtValues returns the Schmidt coefficients between sites ms[:bond] and ms[bond:], in descending order.
+// The returned coefficients are normalized such that their squares sum to one.
+// ms is not modified.
+func schmidtValues(ms []*tensor.Dense, bond int, bufs [5]*tensor.Dense) ([]float32, error) {
+	cp := make([]*tensor.Dense, 0, len(ms))
+	for _, m := range ms {
+		cp = append(cp, resetCopy(tensor.Zeros(1), m))
+	}
+
+	// Bring the state into mixed canonical form, with the center at cp[bond-1].
+	for i := range bond - 1 {
+		leftNormalize(cp, i, bufs[:3])
+	}
+	for i := len(cp) - 1; i >= bond; i-- {
+		rightNormalize(cp, i, bufs[:3])
+	}
+
+	c := cp[bond-1]
+	cs := c.Shape()
+	a := c.Reshape(cs[mpsLeftAxis]*cs[mpsUpAxis], cs[mpsRightAxis])
+	s, err := linalg.SVD(bufs[0], bufs[1], a, [3]*tensor.Dense(bufs[2:]))
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("%#v", cs))
+	}
+
+	values := make([]float32, s.Shape()[0])
+	var norm2 float32
+	for i := range values {
+		values[i] = real(s.At(i, i))
+		norm2 += values[i] * values[i]
+	}
+	norm := float32(math.Sqrt(float64(norm2)))
+	for i := range values {
+		values[i] /= norm
+	}
+	return values, nil
+}
+
+func entropy(schmidt []float32, renyi float32) float32 {
+	if renyi == 1 {
+		var s float64
+		for _, v := range schmidt {
+			p := float64(v * v)
+			if p > 0 {
+				s -= p * math.Log(p)
+			}
+		}
+		return float32(s)
+	}
+
+	var sum float64
+	for _, v := range schmidt {
+		p := float64(v * v)
+		sum += math.Pow(p, float64(renyi))
+	}
+	return float32(math.Log(sum) / float64(1-renyi))
+}
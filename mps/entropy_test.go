@@ -0,0 +1,125 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestEntanglementEntropy(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		ms    []*tensor.Dense
+		bond  int
+		renyi float32
+		want  float32
+	}
+	tests := []testcase{}
+
+	// Product state.
+	product0 := tensor.Zeros(2, 2, 2, 2)
+	product0.SetAt([]int{0, 0, 0, 0}, 1)
+	var bufs2 [2]*tensor.Dense
+	for i := range len(bufs2) {
+		bufs2[i] = tensor.Zeros(1)
+	}
+	tests = append(tests, testcase{ms: NewMPS(product0, bufs2), bond: 2, renyi: 1, want: 0})
+
+	// GHZ state.
+	ghz := tensor.Zeros(2, 2, 2, 2)
+	ghz.SetAt([]int{0, 0, 0, 0}, complex(float32(1/math.Sqrt2), 0))
+	ghz.SetAt([]int{1, 1, 1, 1}, complex(float32(1/math.Sqrt2), 0))
+	for _, bond := range []int{1, 2, 3} {
+		tests = append(tests, testcase{ms: NewMPS(resetCopy(tensor.Zeros(1), ghz), bufs2), bond: bond, renyi: 1, want: math.Ln2})
+		tests = append(tests, testcase{ms: NewMPS(resetCopy(tensor.Zeros(1), ghz), bufs2), bond: bond, renyi: 2, want: math.Ln2})
+	}
+
+	// Random states that are not normalized nor in canonical form.
+	for _, bond := range []int{1, 3, 4, 7} {
+		for _, renyi := range []float32{1, 2, 0.5} {
+			ms := RandMPS(Ising([2]int{8, 1}, 1), 4)
+			tests = append(tests, testcase{ms: ms, bond: bond, renyi: renyi, want: exactEntropy(ms, bond, renyi)})
+		}
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [5]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			msCopy := make([]*tensor.Dense, 0, len(test.ms))
+			for _, m := range test.ms {
+				msCopy = append(msCopy, resetCopy(tensor.Zeros(1), m))
+			}
+
+			opt := NewEntanglementEntropyOptions().Renyi(test.renyi)
+			s, err := EntanglementEntropy(test.ms, test.bond, bufs, opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if math.Abs(float64(s-test.want)) > 1e-5 {
+				t.Fatalf("%f %f", s, test.want)
+			}
+
+			// Check ms is not modified.
+			for j, m := range test.ms {
+				if err := m.Equal(msCopy[j], 0); err != nil {
+					t.Fatalf("%d %+v", j, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEntanglementEntropyRenyi(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		renyi float32
+	}{
+		{renyi: 0},
+		{renyi: -1},
+		{renyi: float32(math.NaN())},
+		{renyi: float32(math.Inf(1))},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [5]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			ms := RandMPS(Ising([2]int{4, 1}, 1), 2)
+			if s, err := EntanglementEntropy(ms, 2, bufs, NewEntanglementEntropyOptions().Renyi(test.renyi)); err == nil {
+				t.Fatalf("expected error %f", s)
+			}
+		})
+	}
+}
+
+func exactEntropy(ms []*tensor.Dense, bond int, renyi float32) float32 {
+	state := product(tensor.Zeros(1), ms, tensor.Zeros(1))
+	var dLeft int = 1
+	for _, m := range ms[:bond] {
+		dLeft *= m.Shape()[mpsUpAxis]
+	}
+	a := state.Reshape(dLeft, -1)
+	s, err := tensor.SVD(tensor.Zeros(1), tensor.Zeros(1), a, [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)})
+	if err != nil {
+		panic(err)
+	}
+
+	values := make([]float32, s.Shape()[0])
+	var norm2 float32
+	for i := range values {
+		values[i] = real(s.At(i, i))
+		norm2 += values[i] * values[i]
+	}
+	for i := range values {
+		values[i] /= float32(math.Sqrt(float64(norm2)))
+	}
+	return entropy(values, renyi)
+}
@@ -0,0 +1,174 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// SearchExcitedStates finds the k lowest eigenstates of the hamiltonian ws, and returns them together with their eigenvalues in ascending order.
+// Each state is searched for starting from a random MPS of maximum bond dimension maxD,
+// with the previously found states pushed up in energy by adding penalty * |psi_j><psi_j| to the hamiltonian.
+// The difference between the first two eigenvalues is the spectral gap.
+// See Studying Two Dimensional Systems With the Density Matrix Renormalization Group, E.M. Stoudenmire and Steven R. White.
+func SearchExcitedStates(k int, ws []*tensor.Dense, maxD int, bufs [10]*tensor.Dense, options ...SearchGroundStateOptions) ([][]*tensor.Dense, []complex64, error) {
+	opt := NewSearchGroundStateOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	states := make([][]*tensor.Dense, 0, k)
+	energies := make([]complex64, 0, k)
+	for i := range k {
+		penalty := opt.penalty
+		if penalty <= 0 {
+			penalty = 1
+			for _, e := range energies {
+				penalty = max(penalty, 2*abs(e)+1)
+			}
+		}
+		stateOpt := opt
+		if opt.checkpointDir != "" {
+			stateOpt.checkpointDir = filepath.Join(opt.checkpointDir, strconv.Itoa(i))
+		}
+
+		fs := make([]*tensor.Dense, len(ws))
+		for j := range fs {
+			fs[j] = opt.pool.Get(1)
+		}
+		ms := RandMPSWithRand(opt.rand, ws, maxD)
+		if err := searchGroundState(fs, ws, ms, newProjector(states, penalty), bufs, stateOpt); err != nil {
+			return nil, nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+
+		// Since the search ends with a left sweep, ms[1:] is right normalized, and the norm is held in ms[0].
+		norm2 := InnerProduct(ms, ms, bufs2)
+		energies = append(energies, RExpressions(fs, ws, ms, bufs2)/norm2)
+		ms[0].Mul(complex(float32(1/math.Sqrt(float64(real(norm2)))), 0))
+		states = append(states, ms)
+		opt.pool.Release(fs...)
+	}
+	return states, energies, nil
+}
+
+// projector is a local effective hamiltonian with the penalty weight * sum_j |psi_j><psi_j| added, where psi_j are the given states.
+// The projection of psi_j onto a site is obtained by contracting psi_j with the overlaps between psi_j and the current MPS on both sides of the site,
+// which are updated during sweeps in the same way as the L and R expressions.
+type projector struct {
+	linalg.LinearOperator
+	weight float32
+	states [][]*tensor.Dense
+
+	// envs[j][l] is the overlap between the current MPS and states[j] of the sites up to l after a right sweep,
+	// and of the sites from l onwards after a left sweep.
+	envs [][]*tensor.Dense
+	// phis[j] is the projection of states[j] onto the current site, flattened to a column vector.
+	phis []*tensor.Dense
+	one  *tensor.Dense
+	bufs [2]*tensor.Dense
+}
+
+func newProjector(states [][]*tensor.Dense, weight float32) *projector {
+	p := &projector{weight: weight, states: states}
+	p.envs = make([][]*tensor.Dense, len(states))
+	p.phis = make([]*tensor.Dense, len(states))
+	for j, state := range states {
+		p.envs[j] = make([]*tensor.Dense, len(state))
+		for l := range state {
+			p.envs[j][l] = tensor.Zeros(1)
+		}
+		p.phis[j] = tensor.Zeros(1)
+	}
+	p.one = ones(tensor.Zeros(1), 1, 1)
+	for i := range len(p.bufs) {
+		p.bufs[i] = tensor.Zeros(1)
+	}
+	return p
+}
+
+// init computes the overlaps from the right for a right normalized ms.
+func (p *projector) init(ms []*tensor.Dense) {
+	for l := len(ms) - 1; l >= 1; l-- {
+		p.rightOverlap(ms, l)
+	}
+}
+
+// set sets the local effective hamiltonian h of site l, and projects the states onto site l.
+func (p *projector) set(h linalg.LinearOperator, l int) {
+	p.LinearOperator = h
+	for j, state := range p.states {
+		left, right := p.one, p.one
+		if l-1 >= 0 {
+			left = p.envs[j][l-1]
+		}
+		if l+1 <= len(state)-1 {
+			right = p.envs[j][l+1]
+		}
+
+		// left is of shape {leftTop, leftBot}.
+		// lm is of shape {leftTop, mpsUp, mpsRight}.
+		lm := tensor.Product(p.bufs[0], left, state[l], [][2]int{{1, mpsLeftAxis}})
+		// right is of shape {rightTop, rightBot}.
+		// phi is of shape {leftTop, mpsUp, rightTop}.
+		phi := tensor.Product(p.phis[j], lm, right, [][2]int{{2, 1}})
+		p.phis[j] = phi.Reshape(-1, 1)
+	}
+}
+
+// Apply applies the penalized hamiltonian to x.
+func (p *projector) Apply(dst, x *tensor.Dense) *tensor.Dense {
+	dst = p.LinearOperator.Apply(dst, x)
+	for _, phi := range p.phis {
+		c := tensor.MatMul(p.bufs[1], phi.H(), x).At(0, 0)
+		dst.Add(complex(p.weight, 0)*c, phi)
+	}
+	return dst
+}
+
+// Diagonal returns the diagonal of the penalized hamiltonian, in which the penalty adds weight * sum_j |phi_j|^2 elementwise.
+func (p *projector) Diagonal(dst *tensor.Dense) *tensor.Dense {
+	dst = p.LinearOperator.(linalg.DiagonalOperator).Diagonal(dst)
+	for _, phi := range p.phis {
+		for i := range phi.Shape()[0] {
+			c := phi.At(i, 0)
+			dst.SetAt([]int{i, 0}, dst.At(i, 0)+complex(p.weight*(real(c)*real(c)+imag(c)*imag(c)), 0))
+		}
+	}
+	return dst
+}
+
+// leftOverlap updates the overlaps up to site l after ms[l] has been left normalized.
+func (p *projector) leftOverlap(ms []*tensor.Dense, l int) {
+	for j, state := range p.states {
+		left := p.one
+		if l-1 >= 0 {
+			left = p.envs[j][l-1]
+		}
+
+		// lp is of shape {leftTop, mpsUp, mpsRight}.
+		lp := tensor.Product(p.bufs[0], left, state[l], [][2]int{{1, mpsLeftAxis}})
+		// envs[j][l] is of shape {mpsRight.conj, mpsRight}.
+		tensor.Product(p.envs[j][l], ms[l].Conj(), lp, [][2]int{{mpsLeftAxis, 0}, {mpsUpAxis, 1}})
+	}
+}
+
+// rightOverlap updates the overlaps from site l onwards after ms[l] has been right normalized.
+func (p *projector) rightOverlap(ms []*tensor.Dense, l int) {
+	for j, state := range p.states {
+		right := p.one
+		if l+1 <= len(ms)-1 {
+			right = p.envs[j][l+1]
+		}
+
+		// rp is of shape {rightTop, mpsLeft, mpsUp}.
+		rp := tensor.Product(p.bufs[0], right, state[l], [][2]int{{1, mpsRightAxis}})
+		// envs[j][l] is of shape {mpsLeft.conj, mpsLeft}.
+		tensor.Product(p.envs[j][l], ms[l].Conj(), rp, [][2]int{{mpsRightAxis, 0}, {mpsUpAxis, 2}})
+	}
+}
@@ -0,0 +1,90 @@
+package mps
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestSearchExcitedStates(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		h   []*tensor.Dense
+		k   int
+		opt SearchGroundStateOptions
+		tol float32
+	}
+	tests := []testcase{
+		{
+			h:   Ising([2]int{6, 1}, 2),
+			k:   3,
+			opt: NewSearchGroundStateOptions(),
+			tol: 1e-3,
+		},
+		{
+			h:   Ising([2]int{6, 1}, 0.5),
+			k:   3,
+			opt: NewSearchGroundStateOptions(),
+			tol: 1e-3,
+		},
+		{
+			h:   Ising([2]int{6, 1}, 0.5),
+			k:   3,
+			opt: NewSearchGroundStateOptions().LocalSolver(DavidsonSolver),
+			tol: 1e-3,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			states, energies, err := SearchExcitedStates(test.k, test.h, 8, bufs, test.opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Compare with the dense eigenvalue solver.
+			lambda := tensor.Zeros(1)
+			if err := tensor.Eig(lambda, nil, denseMPO(test.h), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.T1(energies).Equal(lambda.Slice([][2]int{{0, test.k}}), test.tol); err != nil {
+				t.Fatalf("%+v %v %v", err, energies, lambda.ToSlice1())
+			}
+
+			// Eigenstates are orthonormal.
+			bufs2 := [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+			for a := range states {
+				for b := range states {
+					var want complex64
+					if a == b {
+						want = 1
+					}
+					if ip := InnerProduct(states[a], states[b], bufs2); abs(ip-want) > test.tol {
+						t.Fatalf("%d %d %v", a, b, ip)
+					}
+				}
+			}
+		})
+	}
+}
+
+// denseMPO contracts the MPO ws into a matrix.
+func denseMPO(ws []*tensor.Dense) *tensor.Dense {
+	// m is of shape {mpoRight, up, down}, where up and down are the combined physical axes of the contracted sites.
+	s := ws[0].Shape()
+	m := resetCopy(tensor.Zeros(1), ws[0]).Reshape(s[mpoRightAxis], s[mpoUpAxis], s[mpoDownAxis])
+	for _, w := range ws[1:] {
+		ms, s := m.Shape(), w.Shape()
+		// mw is of shape {up, down, mpoRight, mpoUp, mpoDown}.
+		mw := tensor.Product(tensor.Zeros(1), m, w, [][2]int{{0, mpoLeftAxis}})
+		m = resetCopy(tensor.Zeros(1), mw.Transpose(2, 0, 3, 1, 4)).Reshape(s[mpoRightAxis], ms[1]*s[mpoUpAxis], ms[2]*s[mpoDownAxis])
+	}
+	s = m.Shape()
+	return m.Reshape(s[1], s[2])
+}
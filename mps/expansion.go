@@ -0,0 +1,69 @@
+package mps
+
+import (
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// expandRight enlarges the right bond of ms[l] by the expansion term alpha * L W ms[l], where L is the L expression fLeft and W the MPO site w,
+// and the left bond of ms[l+1] by zeros, which leaves the state unchanged.
+// ms[l] is then left normalized by a truncated SVD, whose remainder is multiplied into ms[l+1].
+// See Section III.B, C. Hubig, I. P. McCulloch, U. Schollwock and F. A. Wolf, Strictly single-site DMRG algorithm with subspace expansion, Phys. Rev. B 91, 155115 (2015).
+func expandRight(fLeft, w *tensor.Dense, ms []*tensor.Dense, l int, sp sweepParams, bufs [10]*tensor.Dense) error {
+	s := ms[l].Shape()
+	dLeft, dUp, dRight := s[mpsLeftAxis], s[mpsUpAxis], s[mpsRightAxis]
+	wRight := w.Shape()[mpoRightAxis]
+
+	// lm is of shape {leftTop, leftMid, mpsUp, mpsRight}.
+	lm := tensor.Product(bufs[0], fLeft, ms[l], [][2]int{{2, mpsLeftAxis}})
+	// wlm is of shape {mpoRight, mpoUp, leftTop, mpsRight}, and the expansion term is of shape {leftTop, mpoUp, mpsRight, mpoRight}.
+	wlm := tensor.Product(bufs[1], w, lm, [][2]int{{mpoLeftAxis, 1}, {mpoDownAxis, 2}})
+	p := resetCopy(bufs[3], wlm.Transpose(2, 1, 3, 0)).Reshape(dLeft, dUp, dRight*wRight).Mul(complex(sp.expansion, 0))
+	expanded := bufs[2].Reset(dLeft, dUp, dRight+dRight*wRight).Set([]int{0, 0, 0}, ms[l]).Set([]int{0, 0, dRight}, p)
+
+	nextUp, nextRight := ms[l+1].Shape()[mpsUpAxis], ms[l+1].Shape()[mpsRightAxis]
+	padded := bufs[4].Reset(dRight+dRight*wRight, nextUp, nextRight).Set([]int{0, 0, 0}, ms[l+1])
+
+	u, vh, sv, discarded, err := truncatedSVD(bufs[5], bufs[6], expanded.Reshape(dLeft*dUp, -1), sp.maxBondDim, sp.truncationErr, [4]*tensor.Dense{bufs[0], bufs[1], bufs[3], bufs[7]})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	sp.report.add(l, discarded)
+
+	// ms[l] = u is left-normalized, and ms[l+1] = s @ vh @ padded.
+	ms[l] = resetCopy(ms[l], u).Reshape(dLeft, dUp, -1)
+	svh := tensor.MatMul(bufs[8], sv, vh)
+	ms[l+1] = resetCopy(ms[l+1], tensor.MatMul(bufs[9], svh, padded.Reshape(dRight+dRight*wRight, -1))).Reshape(-1, nextUp, nextRight)
+	return nil
+}
+
+// expandLeft is the mirror image of expandRight, which enlarges the left bond of ms[l] by the expansion term alpha * ms[l] W R,
+// where R is the R expression fRight, and the right bond of ms[l-1] by zeros.
+// ms[l] is then right normalized by a truncated SVD, whose remainder is multiplied into ms[l-1].
+func expandLeft(fRight, w *tensor.Dense, ms []*tensor.Dense, l int, sp sweepParams, bufs [10]*tensor.Dense) error {
+	s := ms[l].Shape()
+	dLeft, dUp, dRight := s[mpsLeftAxis], s[mpsUpAxis], s[mpsRightAxis]
+	wLeft := w.Shape()[mpoLeftAxis]
+
+	// mr is of shape {mpsLeft, mpsUp, rightTop, rightMid}.
+	mr := tensor.Product(bufs[0], ms[l], fRight, [][2]int{{mpsRightAxis, 2}})
+	// wmr is of shape {mpoLeft, mpoUp, mpsLeft, rightTop}, and the expansion term is of shape {mpsLeft, mpoLeft, mpoUp, rightTop}.
+	wmr := tensor.Product(bufs[1], w, mr, [][2]int{{mpoRightAxis, 3}, {mpoDownAxis, 1}})
+	p := resetCopy(bufs[3], wmr.Transpose(2, 0, 1, 3)).Reshape(dLeft*wLeft, dUp, dRight).Mul(complex(sp.expansion, 0))
+	expanded := bufs[2].Reset(dLeft+dLeft*wLeft, dUp, dRight).Set([]int{0, 0, 0}, ms[l]).Set([]int{dLeft, 0, 0}, p)
+
+	prevLeft, prevUp := ms[l-1].Shape()[mpsLeftAxis], ms[l-1].Shape()[mpsUpAxis]
+	padded := bufs[4].Reset(prevLeft, prevUp, dLeft+dLeft*wLeft).Set([]int{0, 0, 0}, ms[l-1])
+
+	u, vh, sv, discarded, err := truncatedSVD(bufs[5], bufs[6], expanded.Reshape(dLeft+dLeft*wLeft, -1), sp.maxBondDim, sp.truncationErr, [4]*tensor.Dense{bufs[0], bufs[1], bufs[3], bufs[7]})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	sp.report.add(l-1, discarded)
+
+	// ms[l] = vh is right-normalized, and ms[l-1] = padded @ u @ s.
+	ms[l] = resetCopy(ms[l], vh).Reshape(-1, dUp, dRight)
+	us := tensor.MatMul(bufs[8], u, sv)
+	ms[l-1] = resetCopy(ms[l-1], tensor.MatMul(bufs[9], padded.Reshape(-1, dLeft+dLeft*wLeft), us)).Reshape(prevLeft, prevUp, -1)
+	return nil
+}
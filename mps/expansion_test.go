@@ -0,0 +1,134 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/fumin/qising/analytic"
+	"github.com/fumin/tensor"
+)
+
+func TestExpand(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		right bool
+		l     int
+		// grow is whether the bond grows, which it cannot at the ends, where the rank of the site is at most the physical dimension.
+		grow bool
+	}{
+		{right: true, l: 1, grow: true},
+		{right: true, l: 0},
+		{right: false, l: 2, grow: true},
+		{right: false, l: 4},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ws := Ising([2]int{5, 1}, 0.7)
+			ms := RandMPSWithRand(rand.New(rand.NewPCG(1, 2)), ws, 2)
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+			want := product(tensor.Zeros(1), ms, tensor.Zeros(1))
+
+			sp := sweepParams{expansion: 0.1, maxBondDim: 64, truncationErr: 0}
+			s := slices.Clone(ms[test.l].Shape())
+			var err error
+			var bond int
+			if test.right {
+				w := ws[test.l].Shape()[mpoLeftAxis]
+				err = expandRight(rangeT(s[mpsLeftAxis], w, s[mpsLeftAxis]), ws[test.l], ms, test.l, sp, bufs)
+				bond = ms[test.l].Shape()[mpsRightAxis]
+			} else {
+				w := ws[test.l].Shape()[mpoRightAxis]
+				err = expandLeft(rangeT(s[mpsRightAxis], w, s[mpsRightAxis]), ws[test.l], ms, test.l, sp, bufs)
+				bond = ms[test.l].Shape()[mpsLeftAxis]
+			}
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// The expansion leaves the state unchanged, but grows the bond.
+			if err := product(tensor.Zeros(1), ms, tensor.Zeros(1)).Equal(want, 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if old := s[mpsRightAxis]; test.grow && test.right && bond <= old {
+				t.Fatalf("%d %d", bond, old)
+			}
+			if old := s[mpsLeftAxis]; test.grow && !test.right && bond <= old {
+				t.Fatalf("%d %d", bond, old)
+			}
+
+			// The site is normalized in the direction of the sweep.
+			ml := ms[test.l]
+			ms2 := ml.Shape()
+			var gram *tensor.Dense
+			if test.right {
+				a := ml.Reshape(ms2[mpsLeftAxis]*ms2[mpsUpAxis], ms2[mpsRightAxis])
+				gram = tensor.MatMul(tensor.Zeros(1), a.H(), a)
+			} else {
+				a := ml.Reshape(ms2[mpsLeftAxis], ms2[mpsUpAxis]*ms2[mpsRightAxis])
+				gram = tensor.MatMul(tensor.Zeros(1), a, a.H())
+			}
+			if err := gram.Equal(tensor.Zeros(1).Eye(bond, 0), 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestSubspaceExpansion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n     int
+		h     float64
+		alpha float32
+	}{
+		{n: 12, h: 1, alpha: 1e-3},
+		{n: 12, h: 0.5, alpha: 1e-2},
+		{n: 10, h: 2, alpha: 1e-4},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			h := Ising([2]int{test.n, 1}, complex(float32(test.h), 0))
+			fs := make([]*tensor.Dense, len(h))
+			for j := range fs {
+				fs[j] = tensor.Zeros(1)
+			}
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+
+			// Start from a product state, whose bond dimension single-site updates alone never grow.
+			ms := RandMPS(h, 1)
+			report := NewTruncationReport(len(h))
+			opt := NewSearchGroundStateOptions().SubspaceExpansion(test.alpha).MaxBondDim(16).MaxIterations(64).Tol(1e-5).TruncationReport(report)
+			if err := SearchGroundState(fs, h, ms, bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if d := ms[len(ms)/2].Shape()[mpsLeftAxis]; d < 2 {
+				t.Fatalf("bond dimension did not grow %d", d)
+			}
+			for j, m := range ms {
+				if m.Shape()[mpsRightAxis] > 16 {
+					t.Fatalf("%d %#v", j, m.Shape())
+				}
+			}
+			bufs2 := [2]*tensor.Dense(bufs[:2])
+			e0 := LExpressions(fs, h, ms, bufs2) / InnerProduct(ms, ms, bufs2)
+			exact, err := analytic.ExactIsing(test.n, test.h)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if want := exact.Energy * float64(test.n); math.Abs(float64(real(e0))-want) > 1e-3 {
+				t.Fatalf("%f %f", e0, want)
+			}
+		})
+	}
+}
@@ -1,14 +1,13 @@
 package mps
 
 import (
+	"math"
+	"math/cmplx"
+
 	"github.com/fumin/tensor"
 )
 
 var (
-	zero = [][]complex64{
-		{0, 0},
-		{0, 0},
-	}
 	identity = [][]complex64{
 		{1, 0},
 		{0, 1},
@@ -30,37 +29,428 @@ var (
 // MagnetizationZ returns the MPO hamiltonian of the Z axis magnetization.
 // The shape of the lattice is specified by n.
 func MagnetizationZ(n [2]int) []*tensor.Dense {
-	w := tensor.T4([][][][]complex64{
-		{identity, zero},
-		{pauliZ, identity},
-	})
+	return SingleSiteSumMPO(pauliZ, n)
+}
+
+// MagnetizationX returns the MPO of the X axis magnetization, which is the transverse magnetization of the Ising model.
+func MagnetizationX(n [2]int) []*tensor.Dense {
+	return SingleSiteSumMPO(pauliX, n)
+}
+
+// MagnetizationY returns the MPO of the Y axis magnetization.
+func MagnetizationY(n [2]int) []*tensor.Dense {
+	return SingleSiteSumMPO(pauliY, n)
+}
+
+// SingleSiteSumMPO returns the MPO of sum_i op_i, where op_i is the single site operator op acting on site i of the lattice of shape n.
+// The local dimension is that of op, which may differ from 2, such as for the Potts model.
+func SingleSiteSumMPO(op [][]complex64, n [2]int) []*tensor.Dense {
+	d := len(op)
+	id := make([][]complex64, d)
+	for i := range id {
+		id[i] = make([]complex64, d)
+		id[i][i] = 1
+	}
+	w := tensor.Zeros(2, 2, d, d)
+	addMPOBlock(w, 0, 0, 1, id)
+	addMPOBlock(w, 1, 1, 1, id)
+	addMPOBlock(w, 1, 0, 1, op)
 	return newMPO(w, n)
 }
 
-// Ising returns the MPO hamiltonian of the [Transverse Field Ising Model].
-// n is the shape of the lattic, and h is the field strength.
+// IsingOptions are options for building the transverse field Ising hamiltonian.
+type IsingOptions struct {
+	periodic     [2]bool
+	longitudinal complex64
+	coupling     complex64
+	spinHalf     bool
+	fieldAlongZ  bool
+}
+
+// NewIsingOptions returns the default options, which has open boundary conditions and no longitudinal field.
+// The default conventions are the hamiltonian -J sum_<a, b> Z_a Z_b - h sum_a X_a in terms of Pauli matrices, with the ferromagnetic J = 1.
+func NewIsingOptions() IsingOptions {
+	opt := IsingOptions{}
+	opt.coupling = 1
+	return opt
+}
+
+// Periodic sets whether the boundary conditions are periodic in each direction of the lattice, as in exactdiag.IsingOptions.Periodic.
+// Wrap-around bonds are added only in directions of length greater than 2, since otherwise they coincide with existing bonds.
+// Wrap-around bonds longer than the other bonds on the chain are carried by a dedicated channel of the MPO each,
+// which adds one to the bond dimension of the MPO of a chain, and n[1] to that of a lattice periodic along n[0].
+// The MPS remains open, whose bond dimension needs to be about the square of that of the open chain for the same accuracy.
+// See F. Verstraete, D. Porras and J. I. Cirac, Density Matrix Renormalization Group and Periodic Boundary Conditions: A Quantum Information Perspective,
+// Phys. Rev. Lett. 93, 227205 (2004).
+func (opt IsingOptions) Periodic(p [2]bool) IsingOptions {
+	opt.periodic = p
+	return opt
+}
+
+// LongitudinalField sets the field g of the additional term -g * sum_i Z_i, which breaks the Z2 symmetry and the integrability of the chain.
+func (opt IsingOptions) LongitudinalField(g complex64) IsingOptions {
+	opt.longitudinal = g
+	return opt
+}
+
+// Coupling sets the overall coupling J, which multiplies the coupling of every bond.
+// A negative J is antiferromagnetic.
+func (opt IsingOptions) Coupling(j complex64) IsingOptions {
+	opt.coupling = j
+	return opt
+}
+
+// SpinHalf sets whether the hamiltonian is in terms of the spin operators S = sigma/2 instead of the Pauli matrices sigma,
+// which divides the couplings by 4 and the fields by 2.
+// Observables such as the magnetization remain in terms of the Pauli matrices.
+func (opt IsingOptions) SpinHalf(s bool) IsingOptions {
+	opt.spinHalf = s
+	return opt
+}
+
+// FieldAlongZ sets whether the transverse field is along Z and the couplings along X, -J sum_<a, b> X_a X_b - h sum_a Z_a - g sum_a X_a,
+// which is the convention of the Jordan-Wigner solution of the chain.
+// The spectrum is unchanged, but observables such as the magnetization remain along Z, which becomes the transverse axis.
+func (opt IsingOptions) FieldAlongZ(z bool) IsingOptions {
+	opt.fieldAlongZ = z
+	return opt
+}
+
+// conventions returns the scales of the couplings and the fields, and the operators of the couplings and the transverse field.
+func (opt IsingOptions) conventions() (complex64, complex64, [][]complex64, [][]complex64) {
+	j, h := opt.coupling, complex64(1)
+	if opt.spinHalf {
+		j, h = j/4, h/2
+	}
+	couplingOp, fieldOp := pauliZ, pauliX
+	if opt.fieldAlongZ {
+		couplingOp, fieldOp = pauliX, pauliZ
+	}
+	return j, h, couplingOp, fieldOp
+}
+
+// Ising returns the MPO hamiltonian of the [Transverse Field Ising Model] with open boundaries, unless periodic by the options.
+// n is the shape of the lattice, and h is the field strength.
+// A two dimensional lattice is mapped to a chain with the snake mapping, in which row y of length n[1] is traversed from left to right if y is even,
+// and from right to left otherwise.
+// Vertical couplings become long ranged on the chain, spanning up to 2*n[1]-1 sites, and the bond dimension of the MPO is 2*n[1]+1,
+// hence n[1] should be the shorter side of the lattice.
+// See Section 3.1, E. M. Stoudenmire and S. R. White, Studying Two-Dimensional Systems with the Density Matrix Renormalization Group,
+// Annu. Rev. Condens. Matter Phys. 3, 111 (2012).
 //
 // [Transverse Field Ising Model]: https://en.wikipedia.org/wiki/Transverse-field_Ising_model
-func Ising(n [2]int, h complex64) []*tensor.Dense {
-	mul := func(c complex64, x [][]complex64) [][]complex64 {
-		return tensor.T2(x).Mul(c).ToSlice2()
-	}
-	w := tensor.T4([][][][]complex64{
-		{identity, zero, zero},
-		{pauliZ, zero, zero},
-		{mul(-h, pauliX), mul(-1, pauliZ), identity},
-	})
-	return newMPO(w, n)
+func Ising(n [2]int, h complex64, options ...IsingOptions) []*tensor.Dense {
+	coupling := func(a, b [2]int) complex64 { return 1 }
+	field := func(a [2]int) complex64 { return h }
+	return IsingDisordered(n, coupling, field, options...)
+}
+
+// IsingDisordered returns the MPO of the random transverse field Ising hamiltonian -sum_<a, b> J_ab Z_a Z_b - sum_a h_a X_a with open boundaries, unless periodic by the options,
+// where the coupling J_ab of each bond is given by coupling(a, b), and the field h_a of each site by field(a).
+// coupling is called once per bond, and field once per site, hence both may draw random numbers, or look up the couplings in a slice.
+// The lattice is mapped to a chain as in Ising.
+// See D. S. Fisher, Critical behavior of random transverse-field Ising spin chains, Phys. Rev. B 51, 6411 (1995).
+func IsingDisordered(n [2]int, coupling func(a, b [2]int) complex64, field func(a [2]int) complex64, options ...IsingOptions) []*tensor.Dense {
+	opt := NewIsingOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	j, h, couplingOp, fieldOp := opt.conventions()
+	bonds := make([]isingBond, 0, 2*n[0]*n[1])
+	hs := make([]complex64, n[0]*n[1])
+	for y := range n[0] {
+		for x := range n[1] {
+			a := [2]int{y, x}
+			if x+1 < n[1] {
+				b := [2]int{y, x + 1}
+				bonds = append(bonds, isingBond{i: snakeIndex(n, y, x), j: snakeIndex(n, y, x+1), coupling: j * coupling(a, b)})
+			}
+			if y+1 < n[0] {
+				b := [2]int{y + 1, x}
+				bonds = append(bonds, isingBond{i: snakeIndex(n, y, x), j: snakeIndex(n, y+1, x), coupling: j * coupling(a, b)})
+			}
+			if x == n[1]-1 && opt.periodic[1] && n[1] > 2 {
+				b := [2]int{y, 0}
+				bonds = append(bonds, isingBond{i: snakeIndex(n, y, x), j: snakeIndex(n, y, 0), coupling: j * coupling(a, b), wrap: true})
+			}
+			if y == n[0]-1 && opt.periodic[0] && n[0] > 2 {
+				b := [2]int{0, x}
+				bonds = append(bonds, isingBond{i: snakeIndex(n, y, x), j: snakeIndex(n, 0, x), coupling: j * coupling(a, b), wrap: true})
+			}
+			hs[snakeIndex(n, y, x)] = h * field(a)
+		}
+	}
+	return isingMPO(bonds, hs, h*opt.longitudinal, couplingOp, fieldOp)
+}
+
+// isingBond is the coupling between sites i and j of a chain.
+type isingBond struct {
+	i        int
+	j        int
+	coupling complex64
+	// wrap is whether the bond wraps around a periodic boundary.
+	wrap bool
+}
+
+// snakeIndex returns the position of site {y, x} of a lattice of shape n on the chain of the snake mapping.
+func snakeIndex(n [2]int, y, x int) int {
+	if y%2 == 1 {
+		x = n[1] - 1 - x
+	}
+	return y*n[1] + x
+}
+
+// isingMPO returns the MPO of -sum_{b in bonds} J_b Z_{b.i} Z_{b.j} - sum_i hs[i] X_i - g sum_i Z_i on a chain of len(hs) sites,
+// where Z is couplingOp and X is fieldOp.
+// The MPO is a finite state machine, in which the first bond index is the final state of completed terms, the last is the initial state,
+// and index D-1-r in between carries a Z placed r sites to the left, which completes a coupling when it meets a Z r sites later.
+// Wrap-around bonds longer than the others would make D grow with the length of the chain,
+// hence each of them has a dedicated index from 1 on instead, which carries its Z from one end of the bond to the other.
+// See Section 6.1 Construction of a Hamiltonian MPO, Ulrich Schollwock.
+func isingMPO(bonds []isingBond, hs []complex64, g complex64, couplingOp, fieldOp [][]complex64) []*tensor.Dense {
+	rMax := 1
+	for _, b := range bonds {
+		if !b.wrap {
+			rMax = max(rMax, max(b.i, b.j)-min(b.i, b.j))
+		}
+	}
+	var long []isingBond
+	for _, b := range bonds {
+		if max(b.i, b.j)-min(b.i, b.j) > rMax {
+			long = append(long, b)
+		}
+	}
+	d := rMax + 2 + len(long)
+	channel := func(r int) int { return d - 1 - r }
+
+	ws := make([]*tensor.Dense, len(hs))
+	for j := range ws {
+		w := tensor.Zeros(d, d, 2, 2)
+		addMPOBlock(w, 0, 0, 1, identity)
+		addMPOBlock(w, d-1, d-1, 1, identity)
+		addMPOBlock(w, d-1, 0, -hs[j], fieldOp)
+		addMPOBlock(w, d-1, 0, -g, couplingOp)
+		addMPOBlock(w, d-1, channel(1), 1, couplingOp)
+		for r := 1; r < rMax; r++ {
+			addMPOBlock(w, channel(r), channel(r+1), 1, identity)
+		}
+		ws[j] = w
+	}
+	for _, b := range bonds {
+		i, j := min(b.i, b.j), max(b.i, b.j)
+		if j-i <= rMax {
+			addMPOBlock(ws[j], channel(j-i), 0, -b.coupling, couplingOp)
+		}
+	}
+	for c, b := range long {
+		i, j := min(b.i, b.j), max(b.i, b.j)
+		addMPOBlock(ws[i], d-1, 1+c, 1, couplingOp)
+		for k := i + 1; k < j; k++ {
+			addMPOBlock(ws[k], 1+c, 1+c, 1, identity)
+		}
+		addMPOBlock(ws[j], 1+c, 0, -b.coupling, couplingOp)
+	}
+
+	// The first MPO is the last row, and the last MPO is the first column.
+	numSites := len(hs)
+	ws[0] = ws[0].Slice([][2]int{{d - 1, d}, {0, d}, {0, 2}, {0, 2}})
+	ws[numSites-1] = ws[numSites-1].Slice([][2]int{{0, d}, {0, 1}, {0, 2}, {0, 2}})
+	return ws
+}
+
+// XXZ returns the MPO hamiltonian of the [XXZ model] J sum_<a, b> (X_a X_b + Y_a Y_b + delta Z_a Z_b) - hz sum_a Z_a with open boundaries,
+// where n is the shape of the lattice, which is mapped to a chain as in Ising.
+// J > 0 is antiferromagnetic, and delta = 1 is the isotropic Heisenberg model.
+//
+// [XXZ model]: https://en.wikipedia.org/wiki/Quantum_Heisenberg_model
+func XXZ(n [2]int, j, delta, hz complex64) []*tensor.Dense {
+	terms := []pairTerm{
+		{c: j, a: pauliX, b: pauliX},
+		{c: j, a: pauliY, b: pauliY},
+		{c: j * delta, a: pauliZ, b: pauliZ},
+	}
+	onsite := make([][][]complex64, n[0]*n[1])
+	for i := range onsite {
+		onsite[i] = scaleOp(-hz, pauliZ)
+	}
+	return pairMPO(latticeBonds(n), terms, onsite)
+}
+
+// Potts returns the MPO hamiltonian of the q-state [quantum Potts model] -J sum_<a, b> sum_{k=1}^{q-1} Omega_a^k Omega_b^{q-k} - f sum_a sum_{k=1}^{q-1} Gamma_a^k
+// with open boundaries, where Omega = diag(1, w, ..., w^{q-1}) with w = exp(2*pi*i/q), and Gamma is the cyclic shift of the q states.
+// n is the shape of the lattice, which is mapped to a chain as in Ising, and q = 2 is the transverse field Ising model up to constants.
+// See J. Solyom, Duality of the block transformation and decimation for quantum spins, Phys. Rev. B 24, 230 (1981).
+//
+// [quantum Potts model]: https://en.wikipedia.org/wiki/Potts_model
+func Potts(n [2]int, q int, j, f complex64) []*tensor.Dense {
+	omega, gamma := clockOps(q)
+	terms := make([]pairTerm, 0, q-1)
+	field := make([][]complex64, q)
+	for s := range field {
+		field[s] = make([]complex64, q)
+	}
+	for k := 1; k < q; k++ {
+		terms = append(terms, pairTerm{c: -j, a: opPow(omega, k), b: opPow(omega, q-k)})
+		addOp(field, -f, opPow(gamma, k))
+	}
+	onsite := make([][][]complex64, n[0]*n[1])
+	for i := range onsite {
+		onsite[i] = field
+	}
+	return pairMPO(latticeBonds(n), terms, onsite)
+}
+
+// Clock returns the MPO hamiltonian of the q-state quantum clock model -J/2 sum_<a, b> (Omega_a Omega_b^dagger + h.c.) - f/2 sum_a (Gamma_a + Gamma_a^dagger)
+// with open boundaries, where Omega and Gamma are those of Potts.
+// The factors of 1/2 make q = 2 the transverse field Ising model with h = f, while q = 3 is the Potts model with J/2 and f/2, and large q approaches the XY model.
+// See G. Ortiz, E. Cobanera and Z. Nussinov, Dualities and the phase diagram of the p-clock model, Nucl. Phys. B 854, 780 (2012).
+func Clock(n [2]int, q int, j, f complex64) []*tensor.Dense {
+	omega, gamma := clockOps(q)
+	omegaH, gammaH := opPow(omega, q-1), opPow(gamma, q-1)
+	terms := []pairTerm{
+		{c: -j / 2, a: omega, b: omegaH},
+		{c: -j / 2, a: omegaH, b: omega},
+	}
+	field := make([][]complex64, q)
+	for s := range field {
+		field[s] = make([]complex64, q)
+	}
+	addOp(field, -f/2, gamma)
+	addOp(field, -f/2, gammaH)
+	onsite := make([][][]complex64, n[0]*n[1])
+	for i := range onsite {
+		onsite[i] = field
+	}
+	return pairMPO(latticeBonds(n), terms, onsite)
+}
+
+// clockOps returns the clock operator Omega = diag(1, w, ..., w^{q-1}) with w = exp(2*pi*i/q), and the cyclic shift Gamma of q states.
+// Since both are unitary with Omega^q = Gamma^q = 1, their adjoints are their q-1-th powers.
+func clockOps(q int) ([][]complex64, [][]complex64) {
+	omega := make([][]complex64, q)
+	gamma := make([][]complex64, q)
+	for s := range q {
+		omega[s] = make([]complex64, q)
+		omega[s][s] = complex64(cmplx.Exp(complex(0, 2*math.Pi*float64(s)/float64(q))))
+		gamma[s] = make([]complex64, q)
+	}
+	for s := range q {
+		gamma[(s+1)%q][s] = 1
+	}
+	return omega, gamma
+}
+
+// latticeBonds returns the nearest neighbor bonds of a lattice of shape n on the chain of the snake mapping.
+func latticeBonds(n [2]int) [][2]int {
+	bonds := make([][2]int, 0, 2*n[0]*n[1])
+	for y := range n[0] {
+		for x := range n[1] {
+			if x+1 < n[1] {
+				bonds = append(bonds, [2]int{snakeIndex(n, y, x), snakeIndex(n, y, x+1)})
+			}
+			if y+1 < n[0] {
+				bonds = append(bonds, [2]int{snakeIndex(n, y, x), snakeIndex(n, y+1, x)})
+			}
+		}
+	}
+	return bonds
+}
+
+// pairTerm is the two site term c * a_i b_j of a bond (i, j), where i < j.
+type pairTerm struct {
+	c complex64
+	a [][]complex64
+	b [][]complex64
+}
+
+// pairMPO returns the MPO of sum_{(i, j) in bonds} sum_t terms[t] + sum_i onsite[i] on a chain of len(onsite) sites.
+// Like isingMPO, the MPO is a finite state machine, in which index 1+t*rMax+r-1 carries the operator a of terms[t] placed r sites to the left.
+func pairMPO(bonds [][2]int, terms []pairTerm, onsite [][][]complex64) []*tensor.Dense {
+	rMax := 1
+	for _, b := range bonds {
+		rMax = max(rMax, max(b[0], b[1])-min(b[0], b[1]))
+	}
+	d := len(terms)*rMax + 2
+	channel := func(t, r int) int { return 1 + t*rMax + r - 1 }
+	q := len(onsite[0])
+	id := tensor.Zeros(1).Eye(q, 0).ToSlice2()
+
+	ws := make([]*tensor.Dense, len(onsite))
+	for j := range ws {
+		w := tensor.Zeros(d, d, q, q)
+		addMPOBlock(w, 0, 0, 1, id)
+		addMPOBlock(w, d-1, d-1, 1, id)
+		addMPOBlock(w, d-1, 0, 1, onsite[j])
+		for t, term := range terms {
+			addMPOBlock(w, d-1, channel(t, 1), term.c, term.a)
+			for r := 1; r < rMax; r++ {
+				addMPOBlock(w, channel(t, r), channel(t, r+1), 1, id)
+			}
+		}
+		ws[j] = w
+	}
+	for _, b := range bonds {
+		i, j := min(b[0], b[1]), max(b[0], b[1])
+		for t, term := range terms {
+			addMPOBlock(ws[j], channel(t, j-i), 0, 1, term.b)
+		}
+	}
+
+	numSites := len(onsite)
+	ws[0] = ws[0].Slice([][2]int{{d - 1, d}, {0, d}, {0, q}, {0, q}})
+	ws[numSites-1] = ws[numSites-1].Slice([][2]int{{0, d}, {0, 1}, {0, q}, {0, q}})
+	return ws
+}
+
+// scaleOp returns c*op.
+func scaleOp(c complex64, op [][]complex64) [][]complex64 {
+	scaled := make([][]complex64, len(op))
+	for i, row := range op {
+		scaled[i] = make([]complex64, len(row))
+		for j, v := range row {
+			scaled[i][j] = c * v
+		}
+	}
+	return scaled
+}
+
+// addOp adds c*op to dst.
+func addOp(dst [][]complex64, c complex64, op [][]complex64) {
+	for i, row := range op {
+		for j, v := range row {
+			dst[i][j] += c * v
+		}
+	}
+}
+
+// opPow returns op^k.
+func opPow(op [][]complex64, k int) [][]complex64 {
+	p := tensor.Zeros(1).Eye(len(op), 0)
+	a := tensor.T2(op)
+	for range k {
+		p = tensor.MatMul(tensor.Zeros(1), p, a)
+	}
+	return p.ToSlice2()
+}
+
+// addMPOBlock adds c*op to the operator of the MPO site w at bond indices a and b.
+func addMPOBlock(w *tensor.Dense, a, b int, c complex64, op [][]complex64) {
+	for i, row := range op {
+		for j, v := range row {
+			w.SetAt([]int{a, b, i, j}, w.At(a, b, i, j)+c*v)
+		}
+	}
 }
 
 func newMPO(w *tensor.Dense, n [2]int) []*tensor.Dense {
 	d0, d1, d2, d3 := w.Shape()[0], w.Shape()[1], w.Shape()[2], w.Shape()[3]
-	mpo := make([]*tensor.Dense, 0, n[0])
+	numSites := n[0] * n[1]
+	mpo := make([]*tensor.Dense, 0, numSites)
 
 	// First MPO is w[-1].
 	mpo = append(mpo, w.Slice([][2]int{{d0 - 1, d0}, {0, d1}, {0, d2}, {0, d3}}))
 
-	for _ = range n[0] - 2 {
+	for _ = range numSites - 2 {
 		mpo = append(mpo, w)
 	}
 
@@ -69,3 +459,42 @@ func newMPO(w *tensor.Dense, n [2]int) []*tensor.Dense {
 
 	return mpo
 }
+
+// IsingBonds returns the [Transverse Field Ising Model] on a chain of n sites as a sum of nearest-neighbor terms, for use with TEBD.
+// The l-th term acts on sites l and l+1, and the fields on each site are split evenly among the terms that act on it.
+//
+// [Transverse Field Ising Model]: https://en.wikipedia.org/wiki/Transverse-field_Ising_model
+func IsingBonds(n int, h complex64, options ...IsingOptions) []*tensor.Dense {
+	opt := NewIsingOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	j, hScale, couplingOp, fieldOp := opt.conventions()
+	h, g := hScale*h, hScale*opt.longitudinal
+	x, z, id := tensor.T2(fieldOp), tensor.T2(couplingOp), tensor.T2(identity)
+	kron := func(a, b *tensor.Dense) *tensor.Dense {
+		// The result is of shape {up0, up1, down0, down1}.
+		return tensor.Product(tensor.Zeros(1), a, b, nil).Transpose(0, 2, 1, 3)
+	}
+
+	bonds := make([]*tensor.Dense, 0, n-1)
+	for l := range n - 1 {
+		c0, c1 := complex64(0.5), complex64(0.5)
+		if l == 0 {
+			c0 = 1
+		}
+		if l == n-2 {
+			c1 = 1
+		}
+		bond := tensor.Zeros(2, 2, 2, 2)
+		bond.Add(-j, kron(z, z))
+		bond.Add(-c0*h, kron(x, id))
+		bond.Add(-c1*h, kron(id, x))
+		if g != 0 {
+			bond.Add(-c0*g, kron(z, id))
+			bond.Add(-c1*g, kron(id, z))
+		}
+		bonds = append(bonds, bond)
+	}
+	return bonds
+}
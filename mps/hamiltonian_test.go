@@ -0,0 +1,499 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/qising/exactdiag"
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/tensor"
+)
+
+func TestIsing(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		n [2]int
+		h complex64
+		g complex64
+	}
+	tests := []testcase{
+		{n: [2]int{5, 1}, h: 0.5},
+		{n: [2]int{2, 2}, h: 1},
+		{n: [2]int{3, 2}, h: 0.7},
+		{n: [2]int{2, 3}, h: 2},
+		{n: [2]int{5, 1}, h: 0.5, g: 0.3},
+		{n: [2]int{3, 2}, h: 1, g: -0.2},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ws := Ising(test.n, test.h, NewIsingOptions().LongitudinalField(test.g))
+			if len(ws) != test.n[0]*test.n[1] {
+				t.Fatalf("%d", len(ws))
+			}
+			if d := ws[0].Shape()[mpoRightAxis]; d != 2*test.n[1]+1 {
+				t.Fatalf("%d", d)
+			}
+
+			// Compare the spectrum with that of the hamiltonian built in the row major order of the lattice.
+			bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+			got, want := tensor.Zeros(1), tensor.Zeros(1)
+			if err := tensor.Eig(got, nil, denseMPO(ws), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.Eig(want, nil, latticeIsing(test.n, test.h, test.g), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := got.Equal(want, 1e-4); err != nil {
+				t.Fatalf("%+v %v %v", err, got.ToSlice1(), want.ToSlice1())
+			}
+		})
+	}
+}
+
+func TestSearchGroundStateIsing2D(t *testing.T) {
+	t.Parallel()
+	n := [2]int{4, 2}
+	h := Ising(n, 1)
+	fs := make([]*tensor.Dense, 0, len(h))
+	for range h {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	var bufs [10]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	ms := RandMPS(h, 8)
+	if err := SearchGroundState(fs, h, ms, bufs); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	e0 := LExpressions(fs, h, ms, bufs2) / InnerProduct(ms, ms, bufs2)
+
+	lambda := tensor.Zeros(1)
+	if err := tensor.Eig(lambda, nil, latticeIsing(n, 1, 0), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if diff := abs(e0 - lambda.At(0)); diff > 1e-4*abs(lambda.At(0)) {
+		t.Fatalf("%f %f", e0, lambda.At(0))
+	}
+}
+
+// latticeIsing returns the dense transverse field Ising hamiltonian with open boundaries and longitudinal field g of a lattice of shape n,
+// in which site {y, x} is the spin y*n[1]+x counted from the most significant bit of the basis states.
+func latticeIsing(n [2]int, h, g complex64) *tensor.Dense {
+	coupling := func(a, b [2]int) complex64 { return 1 }
+	field := func(a [2]int) complex64 { return h }
+	return latticeIsingDisordered(n, coupling, field, g)
+}
+
+// latticeIsingDisordered is like latticeIsing, but with the couplings and fields of IsingDisordered.
+func latticeIsingDisordered(n [2]int, coupling func(a, b [2]int) complex64, field func(a [2]int) complex64, g complex64) *tensor.Dense {
+	numSpins := n[0] * n[1]
+	dim := 1 << numSpins
+	spin := func(state, y, x int) complex64 {
+		return complex(float32(1-2*((state>>(numSpins-1-(y*n[1]+x)))&1)), 0)
+	}
+	a := tensor.Zeros(dim, dim)
+	for state := range dim {
+		var diag complex64
+		for y := range n[0] {
+			for x := range n[1] {
+				if x+1 < n[1] {
+					diag -= coupling([2]int{y, x}, [2]int{y, x + 1}) * spin(state, y, x) * spin(state, y, x+1)
+				}
+				if y+1 < n[0] {
+					diag -= coupling([2]int{y, x}, [2]int{y + 1, x}) * spin(state, y, x) * spin(state, y+1, x)
+				}
+				diag -= g * spin(state, y, x)
+				flipped := state ^ (1 << (numSpins - 1 - (y*n[1] + x)))
+				a.SetAt([]int{flipped, state}, -field([2]int{y, x}))
+			}
+		}
+		a.SetAt([]int{state, state}, diag)
+	}
+	return a
+}
+
+func TestIsingDisordered(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n [2]int
+		g complex64
+	}{
+		{n: [2]int{6, 1}},
+		{n: [2]int{3, 2}},
+		{n: [2]int{2, 3}, g: 0.3},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			// The couplings and fields are deterministic functions of the sites, so that the dense hamiltonian sees the same disorder.
+			coupling := func(a, b [2]int) complex64 {
+				return complex(0.5+float32((3*a[0]+5*a[1]+7*b[0]+11*b[1])%7)/7, 0)
+			}
+			field := func(a [2]int) complex64 { return complex(0.2+float32((5*a[0]+3*a[1])%5)/5, 0) }
+			ws := IsingDisordered(test.n, coupling, field, NewIsingOptions().LongitudinalField(test.g))
+
+			bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+			got, want := tensor.Zeros(1), tensor.Zeros(1)
+			if err := tensor.Eig(got, nil, denseMPO(ws), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.Eig(want, nil, latticeIsingDisordered(test.n, coupling, field, test.g), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := got.Equal(want, 1e-4); err != nil {
+				t.Fatalf("%+v %v %v", err, got.ToSlice1(), want.ToSlice1())
+			}
+		})
+	}
+}
+
+func TestIsingPeriodic(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n        [2]int
+		periodic [2]bool
+		g        complex64
+		// bondDim is the bond dimension of the MPO.
+		bondDim int
+	}{
+		{n: [2]int{6, 1}, periodic: [2]bool{true, false}, bondDim: 4},
+		{n: [2]int{6, 1}, periodic: [2]bool{true, false}, g: 0.3, bondDim: 4},
+		// The wrap-around bonds of the rows fit in the channels of the vertical bonds.
+		{n: [2]int{2, 3}, periodic: [2]bool{false, true}, bondDim: 7},
+		{n: [2]int{3, 2}, periodic: [2]bool{true, true}, bondDim: 7},
+		{n: [2]int{4, 2}, periodic: [2]bool{true, false}, bondDim: 7},
+		// Wrap-around bonds are not added in directions of length 2.
+		{n: [2]int{2, 2}, periodic: [2]bool{true, true}, bondDim: 5},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			coupling := func(a, b [2]int) complex64 {
+				return complex(0.5+float32((3*a[0]+5*a[1]+7*b[0]+11*b[1])%7)/7, 0)
+			}
+			field := func(a [2]int) complex64 { return complex(0.2+float32((5*a[0]+3*a[1])%5)/5, 0) }
+			ws := IsingDisordered(test.n, coupling, field, NewIsingOptions().Periodic(test.periodic).LongitudinalField(test.g))
+			for l, w := range ws[:len(ws)-1] {
+				if d := w.Shape()[mpoRightAxis]; d != test.bondDim {
+					t.Fatalf("%d %d %d", l, d, test.bondDim)
+				}
+			}
+
+			// Compare the spectrum with that of the exact diagonalization under the same boundary conditions.
+			h, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+			exactdiag.TransverseFieldIsingDisordered(h, buf, test.n, coupling, field, exactdiag.NewIsingOptions().Periodic(test.periodic).LongitudinalField(test.g))
+			dense := tensor.Zeros(h.Rows(), h.Cols())
+			col, e := make([]complex64, h.Rows()), make([]complex64, h.Cols())
+			for c := range h.Cols() {
+				e[c] = 1
+				h.MulVec(col, e)
+				e[c] = 0
+				for r, v := range col {
+					dense.SetAt([]int{r, c}, v)
+				}
+			}
+			bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+			got, want := tensor.Zeros(1), tensor.Zeros(1)
+			if err := tensor.Eig(got, nil, denseMPO(ws), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.Eig(want, nil, dense, bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := got.Equal(want, 1e-4); err != nil {
+				t.Fatalf("%+v %v %v", err, got.ToSlice1(), want.ToSlice1())
+			}
+		})
+	}
+}
+
+func TestIsingConventions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n   [2]int
+		opt IsingOptions
+		// j and h scale the couplings and fields of the equivalent hamiltonian in the default conventions, and g is its longitudinal field.
+		j, h, g complex64
+	}{
+		{n: [2]int{6, 1}, opt: NewIsingOptions().Coupling(2), j: 2, h: 1},
+		{n: [2]int{3, 2}, opt: NewIsingOptions().SpinHalf(true).LongitudinalField(0.5), j: 0.25, h: 0.5, g: 0.25},
+		{n: [2]int{6, 1}, opt: NewIsingOptions().SpinHalf(true).Coupling(-1), j: -0.25, h: 0.5},
+		{n: [2]int{2, 3}, opt: NewIsingOptions().FieldAlongZ(true).LongitudinalField(0.3), j: 1, h: 1, g: 0.3},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			coupling := func(a, b [2]int) complex64 {
+				return complex(0.5+float32((3*a[0]+5*a[1]+7*b[0]+11*b[1])%7)/7, 0)
+			}
+			field := func(a [2]int) complex64 { return complex(0.2+float32((5*a[0]+3*a[1])%5)/5, 0) }
+			ws := IsingDisordered(test.n, coupling, field, test.opt)
+
+			scaledCoupling := func(a, b [2]int) complex64 { return test.j * coupling(a, b) }
+			scaledField := func(a [2]int) complex64 { return test.h * field(a) }
+			bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+			got, want := tensor.Zeros(1), tensor.Zeros(1)
+			if err := tensor.Eig(got, nil, denseMPO(ws), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.Eig(want, nil, latticeIsingDisordered(test.n, scaledCoupling, scaledField, test.g), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := got.Equal(want, 1e-4); err != nil {
+				t.Fatalf("%+v %v %v", err, got.ToSlice1(), want.ToSlice1())
+			}
+		})
+	}
+}
+
+// latticeDense returns the dense hamiltonian sum_{<a, b>} sum_t terms[t] + sum_a onsite of a lattice of shape n with local dimension q,
+// in which site {y, x} is the factor y*n[1]+x of the tensor product.
+func latticeDense(n [2]int, terms []pairTerm, onsite [][]complex64) *tensor.Dense {
+	q := len(onsite)
+	numSites := n[0] * n[1]
+	kron := func(ops map[int][][]complex64) *tensor.Dense {
+		m := tensor.T2([][]complex64{{1}})
+		for i := range numSites {
+			op := tensor.Zeros(1).Eye(q, 0)
+			if o, ok := ops[i]; ok {
+				op = tensor.T2(o)
+			}
+			ms := m.Shape()
+			mo := tensor.Product(tensor.Zeros(1), m, op, nil).Transpose(0, 2, 1, 3)
+			m = resetCopy(tensor.Zeros(1), mo).Reshape(ms[0]*q, ms[1]*q)
+		}
+		return m
+	}
+	dim := 1
+	for range numSites {
+		dim *= q
+	}
+	h := tensor.Zeros(dim, dim)
+	for y := range n[0] {
+		for x := range n[1] {
+			i := y*n[1] + x
+			neighbors := make([]int, 0, 2)
+			if x+1 < n[1] {
+				neighbors = append(neighbors, i+1)
+			}
+			if y+1 < n[0] {
+				neighbors = append(neighbors, i+n[1])
+			}
+			for _, j := range neighbors {
+				for _, term := range terms {
+					h.Add(term.c, kron(map[int][][]complex64{i: term.a, j: term.b}))
+				}
+			}
+			h.Add(1, kron(map[int][][]complex64{i: onsite}))
+		}
+	}
+	return h
+}
+
+func TestPairMPO(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n      [2]int
+		ws     []*tensor.Dense
+		terms  []pairTerm
+		onsite [][]complex64
+	}{
+		{
+			n:      [2]int{5, 1},
+			ws:     XXZ([2]int{5, 1}, 1, 0.5, 0.25),
+			terms:  []pairTerm{{c: 1, a: pauliX, b: pauliX}, {c: 1, a: pauliY, b: pauliY}, {c: 0.5, a: pauliZ, b: pauliZ}},
+			onsite: scaleOp(-0.25, pauliZ),
+		},
+		{
+			n:      [2]int{3, 2},
+			ws:     XXZ([2]int{3, 2}, -1, 2, 0),
+			terms:  []pairTerm{{c: -1, a: pauliX, b: pauliX}, {c: -1, a: pauliY, b: pauliY}, {c: -2, a: pauliZ, b: pauliZ}},
+			onsite: scaleOp(0, pauliZ),
+		},
+		{
+			n:  [2]int{2, 2},
+			ws: Potts([2]int{2, 2}, 3, 1, 0.7),
+			terms: []pairTerm{
+				{c: -1, a: opPow(potts3Omega(), 1), b: opPow(potts3Omega(), 2)},
+				{c: -1, a: opPow(potts3Omega(), 2), b: opPow(potts3Omega(), 1)},
+			},
+			// Gamma + Gamma^2 is 1 off the diagonal.
+			onsite: [][]complex64{{0, -0.7, -0.7}, {-0.7, 0, -0.7}, {-0.7, -0.7, 0}},
+		},
+		{
+			// The 2 state Potts model is the transverse field Ising model.
+			n:      [2]int{4, 1},
+			ws:     Potts([2]int{4, 1}, 2, 1, 0.6),
+			terms:  []pairTerm{{c: -1, a: pauliZ, b: pauliZ}},
+			onsite: scaleOp(-0.6, pauliX),
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+			got, want := tensor.Zeros(1), tensor.Zeros(1)
+			if err := tensor.Eig(got, nil, denseMPO(test.ws), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.Eig(want, nil, latticeDense(test.n, test.terms, test.onsite), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := got.Equal(want, 1e-4); err != nil {
+				t.Fatalf("%+v %v %v", err, got.ToSlice1(), want.ToSlice1())
+			}
+		})
+	}
+}
+
+// potts3Omega returns the clock operator of the 3 state Potts model.
+func potts3Omega() [][]complex64 {
+	w := complex64(cmplx.Exp(2i * math.Pi / 3))
+	return [][]complex64{{1, 0, 0}, {0, w, 0}, {0, 0, w * w}}
+}
+
+func TestIsingBonds(t *testing.T) {
+	t.Parallel()
+	n, h := 5, complex64(0.7)
+	tests := []IsingOptions{
+		NewIsingOptions().LongitudinalField(0.4),
+		NewIsingOptions().LongitudinalField(0.4).SpinHalf(true).Coupling(-1.5),
+		NewIsingOptions().LongitudinalField(0.4).FieldAlongZ(true),
+	}
+	for i, opt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			// The sum of the bond terms embedded in the chain is the hamiltonian.
+			kron := func(a, b *tensor.Dense) *tensor.Dense {
+				as, bs := a.Shape(), b.Shape()
+				ab := tensor.Product(tensor.Zeros(1), a, b, nil).Transpose(0, 2, 1, 3)
+				return resetCopy(tensor.Zeros(1), ab).Reshape(as[0]*bs[0], as[1]*bs[1])
+			}
+			dim := 1 << n
+			got := tensor.Zeros(dim, dim)
+			for l, bond := range IsingBonds(n, h, opt) {
+				term := tensor.Zeros(1).Eye(1, 0)
+				for range l {
+					term = kron(term, tensor.T2(identity))
+				}
+				term = kron(term, resetCopy(tensor.Zeros(1), bond).Reshape(4, 4))
+				for range n - l - 2 {
+					term = kron(term, tensor.T2(identity))
+				}
+				got.Add(1, term)
+			}
+			if err := got.Equal(denseMPO(Ising([2]int{n, 1}, h, opt)), 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestSingleSiteSumMPO(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ws []*tensor.Dense
+		n  [2]int
+		op [][]complex64
+	}{
+		{ws: MagnetizationX([2]int{4, 1}), n: [2]int{4, 1}, op: pauliX},
+		{ws: MagnetizationY([2]int{4, 1}), n: [2]int{4, 1}, op: pauliY},
+		{ws: MagnetizationZ([2]int{2, 2}), n: [2]int{2, 2}, op: pauliZ},
+		{ws: SingleSiteSumMPO(potts3Omega(), [2]int{3, 1}), n: [2]int{3, 1}, op: potts3Omega()},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			want := latticeDense(test.n, nil, test.op)
+			if err := denseMPO(test.ws).Equal(want, 1e-6); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestTransverseMagnetization(t *testing.T) {
+	t.Parallel()
+	// Deep in the paramagnetic phase, the spins align with the field along X, and <X> approaches the number of sites.
+	n := [2]int{8, 1}
+	ws := Ising(n, 10)
+	var bufs [10]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	fs := make([]*tensor.Dense, 0, len(ws))
+	for range ws {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	ms := RandMPSWithRand(rand.New(rand.NewPCG(0, 0)), ws, 4)
+	if err := SearchGroundState(fs, ws, ms, bufs, NewSearchGroundStateOptions().Tol(1e-6).MaxBondDim(4)); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	bufs2 := [2]*tensor.Dense{bufs[0], bufs[1]}
+	norm := InnerProduct(ms, ms, bufs2)
+	mx := LExpressions(fs, MagnetizationX(n), ms, bufs2) / norm
+	my := LExpressions(fs, MagnetizationY(n), ms, bufs2) / norm
+	// In second order perturbation theory in 1/h, each bond flips its two spins with probability 1/(16h^2), which lowers <X> by 1/(4h^2).
+	if want := float32(n[0]) - float32(n[0]-1)/(4*100); cmplx.Abs(complex128(mx)-complex(float64(want), 0)) > 1e-3 {
+		t.Fatalf("%v %f", mx, want)
+	}
+	if cmplx.Abs(complex128(my)) > 1e-5 {
+		t.Fatalf("%v", my)
+	}
+}
+
+func TestClock(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n     [2]int
+		ws    []*tensor.Dense
+		exact func(dir string) error
+	}{
+		{n: [2]int{4, 1}, ws: Potts([2]int{4, 1}, 3, 1, 0.7), exact: func(dir string) error { return exactdiag.PottsExplicit(dir, [2]int{4, 1}, 3, 1, 0.7) }},
+		{n: [2]int{4, 1}, ws: Potts([2]int{4, 1}, 4, 0.5, 1.2), exact: func(dir string) error { return exactdiag.PottsExplicit(dir, [2]int{4, 1}, 4, 0.5, 1.2) }},
+		{n: [2]int{4, 1}, ws: Clock([2]int{4, 1}, 4, 1, 0.7), exact: func(dir string) error { return exactdiag.ClockExplicit(dir, [2]int{4, 1}, 4, 1, 0.7) }},
+		{n: [2]int{3, 1}, ws: Clock([2]int{3, 1}, 5, 1.5, 0.3), exact: func(dir string) error { return exactdiag.ClockExplicit(dir, [2]int{3, 1}, 5, 1.5, 0.3) }},
+		// The snake mapping of a lattice orders the sites differently from exactdiag, hence only the spectra are compared.
+		{n: [2]int{2, 2}, ws: Clock([2]int{2, 2}, 3, 1, 0.9), exact: func(dir string) error { return exactdiag.ClockExplicit(dir, [2]int{2, 2}, 3, 1, 0.9) }},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			if err := test.exact(dir); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			h, err := mat.ReadCOO(dir)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			want := tensor.T2(h.Dense())
+			got := denseMPO(test.ws)
+			if test.n[1] == 1 {
+				if err := got.Equal(want, 1e-5); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				return
+			}
+
+			bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+			gotVals, wantVals := tensor.Zeros(1), tensor.Zeros(1)
+			if err := tensor.Eig(gotVals, nil, got, bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.Eig(wantVals, nil, want, bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := gotVals.Equal(wantVals, 1e-4); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
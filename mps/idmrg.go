@@ -0,0 +1,213 @@
+package mps
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// IDMRGOptions are options for the infinite DMRG.
+type IDMRGOptions struct {
+	maxIterations int
+	tol           float32
+	maxBondDim    int
+	truncationErr float32
+}
+
+// NewIDMRGOptions returns the default infinite DMRG options.
+func NewIDMRGOptions() IDMRGOptions {
+	opt := IDMRGOptions{}
+	opt.maxIterations = 256
+	opt.tol = 1e-6
+	opt.maxBondDim = 32
+	opt.truncationErr = 1e-12
+	return opt
+}
+
+// MaxIterations sets the maximum iterations, each of which grows the chain by two sites.
+func (opt IDMRGOptions) MaxIterations(i int) IDMRGOptions {
+	opt.maxIterations = i
+	return opt
+}
+
+// Tol sets the tolerance of the change in energy density between iterations.
+func (opt IDMRGOptions) Tol(tol float32) IDMRGOptions {
+	opt.tol = tol
+	return opt
+}
+
+// MaxBondDim sets the maximum bond dimension kept after the SVD truncation.
+func (opt IDMRGOptions) MaxBondDim(d int) IDMRGOptions {
+	opt.maxBondDim = d
+	return opt
+}
+
+// TruncationError sets the maximum discarded weight, relative to the norm square, in the SVD truncation.
+func (opt IDMRGOptions) TruncationError(e float32) IDMRGOptions {
+	opt.truncationErr = e
+	return opt
+}
+
+// IDMRGResult is the result of the infinite DMRG.
+// The chain is Left[n-1] ... Left[0] S Right[0] ... Right[n-1], where n is the number of iterations.
+type IDMRGResult struct {
+	// EnergyDensity is the ground state energy per site in the thermodynamic limit.
+	EnergyDensity complex64
+	// Left are the left normalized sites on the left of the center bond, ordered from the center outwards.
+	// Right are the right normalized sites on the right of the center bond, ordered likewise.
+	Left, Right []*tensor.Dense
+	// S is the diagonal matrix of singular values at the center bond.
+	S *tensor.Dense
+}
+
+// Correlation returns <A_i B_j>, where i is the r-th site on the left of the center bond, and j the r-th site on the right, with r starting from 1.
+// A nil operator is the identity, so for example Correlation(op, nil, 1) is the expectation value of op on the site left of the center.
+// In a phase of broken symmetry, the resulting state may be any superposition of the symmetry broken states,
+// in which case the order parameter is better obtained from the correlation at large r, such as sqrt(<Z_i Z_j>) for the Ising model.
+func (res IDMRGResult) Correlation(opA, opB *tensor.Dense, r int) complex64 {
+	if r < 1 || r > len(res.Left) {
+		panic(fmt.Sprintf("%d %d", r, len(res.Left)))
+	}
+	window := make([]*tensor.Dense, 0, 2*r)
+	for k := r - 1; k >= 0; k-- {
+		window = append(window, res.Left[k])
+	}
+	window = append(window, tensor.Product(tensor.Zeros(1), res.S, res.Right[0], [][2]int{{1, mpsLeftAxis}}))
+	window = append(window, res.Right[1:r]...)
+
+	// Since sites outside of the window are normalized, the environments at the ends of the window are identities.
+	f := tensor.Zeros(1).Eye(window[0].Shape()[mpsLeftAxis], 0)
+	bufs := [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+	for k, m := range window {
+		var op *tensor.Dense
+		switch k {
+		case 0:
+			op = opA
+		case len(window) - 1:
+			op = opB
+		}
+		f = transferLeft(f, m, op, bufs)
+	}
+	var v complex64
+	for i := range f.Shape()[0] {
+		v += f.At(i, i)
+	}
+	return v
+}
+
+// IDMRG finds the ground state of an infinite translation-invariant chain, by growing the chain two sites at a time from the center.
+// ws is the MPO of a finite chain of at least three sites such as one returned by Ising,
+// from which ws[0] and ws[len(ws)-1] are taken as the boundaries, and ws[1] as the bulk.
+// After each iteration, half of the energy is subtracted from each of the L and R expressions,
+// so that they stay bounded as the chain grows, and the eigenvalue of the next iteration is the energy of the two added sites.
+// See Section 10.1 Infinite DMRG, Ulrich Schollwock, and I. P. McCulloch, Infinite size density matrix renormalization group, revisited, arXiv:0804.2509.
+func IDMRG(ws []*tensor.Dense, bufs [10]*tensor.Dense, options ...IDMRGOptions) (IDMRGResult, error) {
+	opt := NewIDMRGOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if len(ws) < 3 {
+		return IDMRGResult{}, errors.Errorf("%d", len(ws))
+	}
+	w := ws[1]
+	rowID, colID, err := identityChannels(ws[0], w, ws[len(ws)-1])
+	if err != nil {
+		return IDMRGResult{}, errors.Wrap(err, "")
+	}
+
+	left, right := ones(tensor.Zeros(1), 1, 1, 1), ones(tensor.Zeros(1), 1, 1, 1)
+	leftBuf, rightBuf := tensor.Zeros(1), tensor.Zeros(1)
+	a, b := tensor.Zeros(1), tensor.Zeros(1)
+	var s *tensor.Dense
+	var lefts, rights []*tensor.Dense
+	h := newEffectiveH2Site(nil)
+	d := w.Shape()[mpoDownAxis]
+	var density complex64
+	var converged bool
+	for i := range opt.maxIterations {
+		w0, w1 := w, w
+		if i == 0 {
+			w0, w1 = ws[0], ws[len(ws)-1]
+		}
+		h.set(left, right, w0, w1)
+
+		eigvals, eigvecs := bufs[1], bufs[2]
+		if err := linalg.ArnoldiOperator(eigvals, eigvecs, h, 1, [7]*tensor.Dense(bufs[3:])); err != nil {
+			return IDMRGResult{}, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		energy := eigvals.At(0)
+
+		dLeft, dRight := left.Shape()[2], right.Shape()[2]
+		theta := resetCopy(bufs[3], eigvecs.Reshape(dLeft*d, d*dRight))
+		var err error
+		_, _, s, _, err = truncatedSVD(a, b, theta, opt.maxBondDim, opt.truncationErr, [4]*tensor.Dense(bufs[6:]))
+		if err != nil {
+			return IDMRGResult{}, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		s.Mul(complex(1/s.FrobeniusNorm(), 0))
+		aSite, bSite := a.Reshape(dLeft, d, -1), b.Reshape(-1, d, dRight)
+		lefts = append(lefts, resetCopy(tensor.Zeros(1), aSite))
+		rights = append(rights, resetCopy(tensor.Zeros(1), bSite))
+
+		// Grow the L and R expressions by the new sites, and subtract half of the energy from each.
+		left, leftBuf = lExpression(leftBuf, left, w0, aSite, bufs[:2]), left
+		right, rightBuf = rExpression(rightBuf, right, w1, bSite, bufs[:2]), right
+		shiftChannel(left, colID, rowID, -energy/2)
+		shiftChannel(right, rowID, colID, -energy/2)
+
+		// Except for the first iteration, the energy is that of the two sites added to the chain.
+		if i == 0 {
+			continue
+		}
+		prev := density
+		density = energy / 2
+		if i >= 2 && abs(density-prev) < opt.tol {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return IDMRGResult{}, errors.Errorf("not converged %v", density)
+	}
+
+	slices.Reverse(lefts)
+	slices.Reverse(rights)
+	res := IDMRGResult{EnergyDensity: density, Left: lefts, Right: rights, S: resetCopy(tensor.Zeros(1), s)}
+	return res, nil
+}
+
+// identityChannels returns the MPO channels of w in which nothing has been placed yet and in which all terms are completed,
+// which are the row selected by the left boundary w0 and the column selected by the right boundary wn respectively.
+func identityChannels(w0, w, wn *tensor.Dense) (int, int, error) {
+	s := w.Shape()
+	row := -1
+	for r := range s[mpoLeftAxis] {
+		wr := w.Slice([][2]int{{r, r + 1}, {0, s[1]}, {0, s[2]}, {0, s[3]}})
+		if wr.Equal(w0, 0) == nil {
+			row = r
+			break
+		}
+	}
+	col := -1
+	for c := range s[mpoRightAxis] {
+		wc := w.Slice([][2]int{{0, s[0]}, {c, c + 1}, {0, s[2]}, {0, s[3]}})
+		if wc.Equal(wn, 0) == nil {
+			col = c
+			break
+		}
+	}
+	if row < 0 || col < 0 || row == col {
+		return -1, -1, errors.Errorf("%d %d", row, col)
+	}
+	return row, col, nil
+}
+
+// shiftChannel adds c times the identity channel id to channel ch of the L or R expression f of shape {fTop, fMid, fBot}.
+func shiftChannel(f *tensor.Dense, ch, id int, c complex64) {
+	s := f.Shape()
+	fID := f.Slice([][2]int{{0, s[0]}, {id, id + 1}, {0, s[2]}})
+	f.Slice([][2]int{{0, s[0]}, {ch, ch + 1}, {0, s[2]}}).Add(c, fID)
+}
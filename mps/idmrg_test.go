@@ -0,0 +1,69 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestIDMRG(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		h   float64
+		mz  float64
+		tol float32
+	}
+	tests := []testcase{
+		{h: 0.5, mz: math.Pow(1-0.5*0.5, 1.0/8), tol: 1e-4},
+		{h: 2, mz: 0, tol: 1e-4},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			res, err := IDMRG(Ising([2]int{3, 1}, complex(float32(test.h), 0)), bufs, NewIDMRGOptions().MaxBondDim(16))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Compare with the exact solution of the Transverse Field Ising Model.
+			e, mx := exactIsingDensity(test.h)
+			if diff := abs(res.EnergyDensity - complex(float32(e), 0)); diff > test.tol {
+				t.Fatalf("%f %v %f", diff, res.EnergyDensity, e)
+			}
+			for r := range 2 {
+				x := res.Correlation(tensor.T2(pauliX), nil, r+1)
+				if diff := abs(x - complex(float32(mx), 0)); diff > 10*test.tol {
+					t.Fatalf("%d %f %v %f", r, diff, x, mx)
+				}
+			}
+
+			// Since the ground state may be any superposition of the two ferromagnetic states, compare the long range correlation with the square of the magnetization.
+			r := len(res.Left) / 2
+			zz := res.Correlation(tensor.T2(pauliZ), tensor.T2(pauliZ), r)
+			if diff := abs(zz - complex(float32(test.mz*test.mz), 0)); diff > 0.05 {
+				t.Fatalf("%d %f %v %f", r, diff, zz, test.mz)
+			}
+		})
+	}
+}
+
+// exactIsingDensity returns the ground state energy density and transverse magnetization of the infinite Transverse Field Ising chain.
+// See Pfeuty, The one-dimensional Ising model with a transverse field, Annals of Physics 57, 79 (1970).
+func exactIsingDensity(h float64) (float64, float64) {
+	const n = 1 << 14
+	var e, mx float64
+	for i := range n {
+		k := (float64(i) + 0.5) * math.Pi / n
+		eps := math.Sqrt(1 + h*h - 2*h*math.Cos(k))
+		e -= eps / n
+		mx += (h - math.Cos(k)) / eps / n
+	}
+	return e, mx
+}
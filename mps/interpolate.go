@@ -0,0 +1,152 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// EnergyPathOptions are options for EnergyPath.
+type EnergyPathOptions struct {
+	steps         int
+	maxBondDim    int
+	truncationErr float32
+}
+
+// NewEnergyPathOptions returns the default options.
+func NewEnergyPathOptions() EnergyPathOptions {
+	opt := EnergyPathOptions{}
+	opt.steps = 10
+	opt.maxBondDim = 64
+	opt.truncationErr = 1e-12
+	return opt
+}
+
+// Steps sets the number of intervals of the path, which is evaluated at t = 0, 1/steps, ..., 1.
+func (opt EnergyPathOptions) Steps(n int) EnergyPathOptions {
+	opt.steps = n
+	return opt
+}
+
+// MaxBondDim sets the maximum bond dimension of the interpolated states after compression.
+func (opt EnergyPathOptions) MaxBondDim(d int) EnergyPathOptions {
+	opt.maxBondDim = d
+	return opt
+}
+
+// TruncationError sets the maximum discarded weight, relative to the norm square, in the SVD compression of each bond.
+func (opt EnergyPathOptions) TruncationError(e float32) EnergyPathOptions {
+	opt.truncationErr = e
+	return opt
+}
+
+// EnergyPathPoint is the energy of an interpolated state.
+type EnergyPathPoint struct {
+	T      float32
+	Energy complex64
+}
+
+// EnergyPath evaluates the energy <psi(t)|H|psi(t)>/<psi(t)|psi(t)> of the MPO ws along the path psi(t) = (1-t) x + t y for t in [0, 1],
+// where x and y are normalized, and the phase of y is chosen such that <x|y> is real and non-negative.
+// Each psi(t) is compressed to the bond dimension of options.
+//
+// It is an inexpensive diagnostic of whether DMRG runs, such as those at adjacent fields, converged to different branches.
+// If x and y are in the same branch, their overlap is close to 1, and the energy varies little and monotonically along the path.
+// If they are in different symmetry broken branches, their overlap vanishes, and the energy is close to
+// ((1-t)^2 E(0) + t^2 E(1)) / ((1-t)^2 + t^2), whose deviations measure the tunneling between the branches.
+// x and y are not modified.
+// See Section 4.3 Adding two matrix product states, and Section 4.5.1 Compressing a matrix product state by SVD, Ulrich Schollwock.
+func EnergyPath(ws, x, y []*tensor.Dense, bufs [10]*tensor.Dense, options ...EnergyPathOptions) ([]EnergyPathPoint, error) {
+	opt := NewEnergyPathOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if len(x) != len(ws) || len(y) != len(ws) {
+		return nil, errors.Errorf("%d %d %d", len(ws), len(x), len(y))
+	}
+	for i := range ws {
+		d := ws[i].Shape()[mpoDownAxis]
+		if x[i].Shape()[mpsUpAxis] != d || y[i].Shape()[mpsUpAxis] != d {
+			return nil, errors.Errorf("%d %v %v %v", i, ws[i].Shape(), x[i].Shape(), y[i].Shape())
+		}
+	}
+	if opt.steps < 1 {
+		return nil, errors.Errorf("%d", opt.steps)
+	}
+
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	xNorm := float32(math.Sqrt(float64(abs(InnerProduct(x, x, bufs2)))))
+	yNorm := float32(math.Sqrt(float64(abs(InnerProduct(y, y, bufs2)))))
+	if xNorm == 0 || yNorm == 0 {
+		return nil, errors.Errorf("%f %f", xNorm, yNorm)
+	}
+	// Align the phase of y to x, so that the path does not cancel the common components of the two states.
+	yScale := complex(1/yNorm, 0)
+	if xy := InnerProduct(x, y, bufs2); abs(xy) > 0 {
+		yScale *= conj(xy) / complex(abs(xy), 0)
+	}
+
+	fs := make([]*tensor.Dense, 0, len(ws))
+	for range ws {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	points := make([]EnergyPathPoint, 0, opt.steps+1)
+	for k := range opt.steps + 1 {
+		t := float32(k) / float32(opt.steps)
+		ms := addMPS(x, y, complex((1-t)/xNorm, 0), complex(t, 0)*yScale)
+		if err := compressMPS(ms, opt.maxBondDim, opt.truncationErr, nil, bufs); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", k))
+		}
+		e := LExpressions(fs, ws, ms, bufs2) / InnerProduct(ms, ms, bufs2)
+		points = append(points, EnergyPathPoint{T: t, Energy: e})
+	}
+	return points, nil
+}
+
+// addMPS returns the MPS of a*x + b*y, whose bond dimensions are the sums of those of x and y.
+// The sites are the block diagonal direct sums of the sites of x and y, except for the first and last sites,
+// which are concatenated along their right and left axes.
+// See Section 4.3 Adding two matrix product states, Ulrich Schollwock.
+func addMPS(x, y []*tensor.Dense, a, b complex64) []*tensor.Dense {
+	if len(x) != len(y) {
+		panic(fmt.Sprintf("%d %d", len(x), len(y)))
+	}
+	ms := make([]*tensor.Dense, 0, len(x))
+	for i := range x {
+		xs, ys := x[i].Shape(), y[i].Shape()
+		xi, yi := x[i], y[i]
+		if i == 0 {
+			xi = resetCopy(tensor.Zeros(1), x[i]).Mul(a)
+			yi = resetCopy(tensor.Zeros(1), y[i]).Mul(b)
+		}
+
+		l, r := xs[mpsLeftAxis]+ys[mpsLeftAxis], xs[mpsRightAxis]+ys[mpsRightAxis]
+		yOffset := []int{xs[mpsLeftAxis], 0, xs[mpsRightAxis]}
+		switch {
+		case len(x) == 1:
+			l, r = 1, 1
+			yOffset = []int{0, 0, 0}
+		case i == 0:
+			l = 1
+			yOffset[mpsLeftAxis] = 0
+		case i == len(x)-1:
+			r = 1
+			yOffset[mpsRightAxis] = 0
+		}
+
+		m := tensor.Zeros(l, xs[mpsUpAxis], r)
+		m.Set([]int{0, 0, 0}, xi)
+		if len(x) == 1 {
+			// A single site is the sum of the two sites, rather than a concatenation.
+			for ijk := range m.All() {
+				m.SetAt(ijk, m.At(ijk...)+yi.At(ijk...))
+			}
+		} else {
+			m.Set(yOffset, yi)
+		}
+		ms = append(ms, m)
+	}
+	return ms
+}
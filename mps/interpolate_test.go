@@ -0,0 +1,102 @@
+package mps
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestAddMPS(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n    int
+		a, b complex64
+	}{
+		{n: 1, a: 1, b: 2i},
+		{n: 2, a: 0.5, b: -1},
+		{n: 5, a: 1 + 1i, b: 3},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			x := []*tensor.Dense{tensor.T3([][][]complex64{{{1}, {2i}}})}
+			y := []*tensor.Dense{tensor.T3([][][]complex64{{{-1}, {3}}})}
+			if test.n > 1 {
+				r := rand.New(rand.NewPCG(uint64(i), 0))
+				ws := Ising([2]int{test.n, 1}, 1)
+				x, y = RandMPSWithRand(r, ws, 3), RandMPSWithRand(r, ws, 2)
+			}
+			buf := tensor.Zeros(1)
+			px, py := product(tensor.Zeros(1), x, buf), product(tensor.Zeros(1), y, buf)
+
+			z := addMPS(x, y, test.a, test.b)
+			pz := product(tensor.Zeros(1), z, buf)
+			want := resetCopy(tensor.Zeros(1), px).Mul(test.a).Add(test.b, py)
+			if err := pz.Equal(want, 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			// x and y are not modified.
+			if err := product(tensor.Zeros(1), x, buf).Equal(px, 0); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestEnergyPath(t *testing.T) {
+	t.Parallel()
+	ws := Ising([2]int{6, 1}, 0.5)
+	var bufs [10]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	fs := make([]*tensor.Dense, 0, len(ws))
+	for range ws {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	energy := func(ms []*tensor.Dense) complex64 {
+		return LExpressions(fs, ws, ms, bufs2) / InnerProduct(ms, ms, bufs2)
+	}
+
+	r := rand.New(rand.NewPCG(2, 2))
+	x, y := RandMPSWithRand(r, ws, 4), RandMPSWithRand(r, ws, 4)
+	ex, ey := energy(x), energy(y)
+
+	// The endpoints are the energies of x and y.
+	points, err := EnergyPath(ws, x, y, bufs, NewEnergyPathOptions().Steps(4))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(points) != 5 || points[0].T != 0 || points[4].T != 1 {
+		t.Fatalf("%#v", points)
+	}
+	if abs(points[0].Energy-ex) > 1e-4 || abs(points[4].Energy-ey) > 1e-4 {
+		t.Fatalf("%v %v %v", points, ex, ey)
+	}
+
+	// The path from a state to itself, even with a different phase, is flat.
+	yx := make([]*tensor.Dense, 0, len(x))
+	for i, xi := range x {
+		yi := resetCopy(tensor.Zeros(1), xi)
+		if i == 0 {
+			yi.Mul(-2i)
+		}
+		yx = append(yx, yi)
+	}
+	points, err = EnergyPath(ws, x, yx, bufs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for _, p := range points {
+		if abs(p.Energy-ex) > 1e-4 {
+			t.Fatalf("%v %v", p, ex)
+		}
+	}
+
+	if _, err := EnergyPath(ws, x, y[:3], bufs); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,167 @@
+package mps
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+var (
+	// saveMagic identifies the format written by Save.
+	saveMagic = [4]byte{'M', 'P', 'S', '1'}
+)
+
+const (
+	// maxTensorLen is the largest number of elements of a tensor read by readTensor, which guards against corrupt shapes.
+	maxTensorLen = 1 << 31
+	// readChunkLen is the number of elements readTensor reads at a time.
+	readChunkLen = 1 << 12
+	// maxSites is the largest number of sites read by Load, which guards against corrupt site counts.
+	// It is the same bound readInts applies to its count.
+	maxSites = 1 << 16
+)
+
+// Save writes ms to w in a compact binary format.
+// The format consists of a magic header, the number of sites, and for each site its shape followed by its complex64 elements in row major order.
+// All numbers are little endian.
+func Save(w io.Writer, ms []*tensor.Dense) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(saveMagic[:]); err != nil {
+		return errors.Wrap(err, "")
+	}
+	if err := writeInts(bw, len(ms)); err != nil {
+		return errors.Wrap(err, "")
+	}
+	for i, m := range ms {
+		if err := writeTensor(bw, m); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// Load reads a matrix product state written by Save.
+func Load(r io.Reader) ([]*tensor.Dense, error) {
+	br := bufio.NewReader(r)
+	var magic [4]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if magic != saveMagic {
+		return nil, errors.Errorf("%q", magic)
+	}
+	n, err := readInts(br, 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	if n[0] < 0 || n[0] > maxSites {
+		return nil, errors.Errorf("%d", n[0])
+	}
+
+	ms := make([]*tensor.Dense, 0, n[0])
+	for i := range n[0] {
+		m := tensor.Zeros(1)
+		if err := readTensor(br, m); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		if len(m.Shape()) != 3 {
+			return nil, errors.Errorf("%d %#v", i, m.Shape())
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// writeTensor writes the shape followed by the elements of t in row major order.
+func writeTensor(w io.Writer, t *tensor.Dense) error {
+	shape := t.Shape()
+	if err := writeInts(w, append([]int{len(shape)}, shape...)...); err != nil {
+		return errors.Wrap(err, "")
+	}
+	var b [8]byte
+	for _, v := range t.All() {
+		binary.LittleEndian.PutUint32(b[0:], math.Float32bits(real(v)))
+		binary.LittleEndian.PutUint32(b[4:], math.Float32bits(imag(v)))
+		if _, err := w.Write(b[:]); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return nil
+}
+
+// readTensor reads a tensor written by writeTensor into t.
+// The elements are read before t is allocated, so that the shape of a corrupt or truncated input fails at the end of the input,
+// instead of allocating a tensor larger than the input.
+func readTensor(r io.Reader, t *tensor.Dense) error {
+	ndim, err := readInts(r, 1)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	shape, err := readInts(r, ndim[0])
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	n := 1
+	for _, d := range shape {
+		if d <= 0 || d > maxTensorLen/n {
+			return errors.Errorf("%#v", shape)
+		}
+		n *= d
+	}
+
+	elements := make([]complex64, 0, min(n, readChunkLen))
+	b := make([]byte, 8*readChunkLen)
+	for len(elements) < n {
+		chunk := b[:8*min(n-len(elements), readChunkLen)]
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d %#v", len(elements), shape))
+		}
+		for i := 0; i < len(chunk); i += 8 {
+			re := math.Float32frombits(binary.LittleEndian.Uint32(chunk[i:]))
+			im := math.Float32frombits(binary.LittleEndian.Uint32(chunk[i+4:]))
+			elements = append(elements, complex(re, im))
+		}
+	}
+
+	t.Reset(shape...)
+	var i int
+	for ijk := range t.All() {
+		t.SetAt(ijk, elements[i])
+		i++
+	}
+	return nil
+}
+
+func writeInts(w io.Writer, xs ...int) error {
+	var b [8]byte
+	for _, x := range xs {
+		binary.LittleEndian.PutUint64(b[:], uint64(x))
+		if _, err := w.Write(b[:]); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return nil
+}
+
+func readInts(r io.Reader, n int) ([]int, error) {
+	if n < 0 || n > 1<<16 {
+		return nil, errors.Errorf("%d", n)
+	}
+	xs := make([]int, n)
+	var b [8]byte
+	for i := range xs {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		xs[i] = int(binary.LittleEndian.Uint64(b[:]))
+	}
+	return xs, nil
+}
@@ -0,0 +1,86 @@
+package mps
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestSaveLoad(t *testing.T) {
+	t.Parallel()
+	h := Ising([2]int{7, 1}, 1)
+	ms := RandMPS(h, 5)
+	var bufs [2]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+
+	var b bytes.Buffer
+	if err := Save(&b, ms); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	saved := b.Bytes()
+
+	loaded, err := Load(bytes.NewReader(saved))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(loaded) != len(ms) {
+		t.Fatalf("%d %d", len(loaded), len(ms))
+	}
+	for i := range ms {
+		if err := loaded[i].Equal(ms[i], 0); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+	}
+	if ip, lip := InnerProduct(ms, ms, bufs), InnerProduct(loaded, loaded, bufs); ip != lip {
+		t.Fatalf("%v %v", ip, lip)
+	}
+
+	// Corrupted inputs.
+	if _, err := Load(bytes.NewReader(saved[:len(saved)-1])); err == nil {
+		t.Fatalf("expected error for truncated input")
+	}
+	corrupted := bytes.Clone(saved)
+	corrupted[0] = 'X'
+	if _, err := Load(bytes.NewReader(corrupted)); err == nil {
+		t.Fatalf("expected error for wrong magic")
+	}
+	for _, n := range []int{-1, maxSites + 1, 1 << 62} {
+		corrupted := bytes.Clone(saved)
+		binary.LittleEndian.PutUint64(corrupted[len(saveMagic):], uint64(n))
+		if _, err := Load(bytes.NewReader(corrupted)); err == nil {
+			t.Fatalf("expected error for site count %d", n)
+		}
+	}
+}
+
+func TestReadTensorShape(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		shape []int
+	}{
+		{shape: []int{2, 0, 2}},
+		{shape: []int{2, -1, 2}},
+		{shape: []int{1 << 20, 1 << 20, 1}},
+		{shape: []int{1 << 40, 1 << 40}},
+		// The shape is within the limit, but larger than the input.
+		{shape: []int{1 << 10, 1 << 10, 1 << 10}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var b bytes.Buffer
+			if err := writeInts(&b, append([]int{len(test.shape)}, test.shape...)...); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			b.Write(make([]byte, 8*16))
+			if err := readTensor(&b, tensor.Zeros(1)); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
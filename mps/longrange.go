@@ -0,0 +1,120 @@
+package mps
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// LongRangeIsing returns the MPO hamiltonian of the long range transverse field Ising model -sum_{i<j} Z_i Z_j / (j-i)^alpha - h sum_i X_i on an open chain of n sites,
+// such as the models simulated by trapped ions, in which alpha is between 0 and 3.
+// The power law is fitted by a sum of nExp exponentials sum_k c_k lambda_k^r over the distances r = 1, ..., n-1,
+// each of which is carried by a channel of the MPO, whose bond dimension is therefore 2+nExp.
+// The error of the fit decreases rapidly with nExp, and a handful of exponentials suffice for hundreds of sites.
+// nExp is at most about n/2, since the fit needs at least twice as many distances as exponentials.
+// See B. Pirvu, V. Murg, J. I. Cirac and F. Verstraete, Matrix product operator representations, New J. Phys. 12, 025012 (2010),
+// and G. M. Crosswhite, A. C. Doherty and G. Vidal, Applying matrix product operators to model systems with long-range interactions, Phys. Rev. B 78, 035116 (2008).
+func LongRangeIsing(n int, h complex64, alpha float64, nExp int) ([]*tensor.Dense, error) {
+	if n < 2 {
+		return nil, errors.Errorf("%d", n)
+	}
+	f := make([]complex64, n-1)
+	for r := range f {
+		f[r] = complex(float32(math.Pow(float64(r+1), -alpha)), 0)
+	}
+	c, lambda, err := fitExponentials(f, nExp)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	// Index 0 is the final state of completed terms, the last is the initial state,
+	// and index 1+k carries a Z whose weight decays by lambda_k on every site it passes.
+	d := 2 + nExp
+	ws := make([]*tensor.Dense, n)
+	for i := range ws {
+		w := tensor.Zeros(d, d, 2, 2)
+		addMPOBlock(w, 0, 0, 1, identity)
+		addMPOBlock(w, d-1, d-1, 1, identity)
+		addMPOBlock(w, d-1, 0, -h, pauliX)
+		for k := range nExp {
+			addMPOBlock(w, d-1, 1+k, lambda[k], pauliZ)
+			addMPOBlock(w, 1+k, 1+k, lambda[k], identity)
+			addMPOBlock(w, 1+k, 0, -c[k], pauliZ)
+		}
+		ws[i] = w
+	}
+
+	// The first MPO is the last row, and the last MPO is the first column.
+	ws[0] = ws[0].Slice([][2]int{{d - 1, d}, {0, d}, {0, 2}, {0, 2}})
+	ws[n-1] = ws[n-1].Slice([][2]int{{0, d}, {0, 1}, {0, 2}, {0, 2}})
+	return ws, nil
+}
+
+// fitExponentials returns the coefficients c and the bases lambda of the k exponentials, whose sum sum_i c_i lambda_i^r best fits f[r-1] for r = 1, ..., len(f).
+// The bases are the eigenvalues of the shift operator of the rank k approximation of the Hankel matrix of f, as in the matrix pencil method,
+// and the coefficients are then the least squares solution.
+// For a decreasing function such as a power law, the bases are real and between 0 and 1.
+// See Appendix of B. Pirvu, V. Murg, J. I. Cirac and F. Verstraete, Matrix product operator representations, New J. Phys. 12, 025012 (2010).
+func fitExponentials(f []complex64, k int) ([]complex64, []complex64, error) {
+	cols := (len(f) + 1) / 2
+	rows := len(f) + 1 - cols
+	if k < 1 || k > cols || k > rows-1 {
+		return nil, nil, errors.Errorf("%d %d", k, len(f))
+	}
+	hankel := tensor.Zeros(rows, cols)
+	for i := range rows {
+		for j := range cols {
+			hankel.SetAt([]int{i, j}, f[i+j])
+		}
+	}
+	bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+	u := tensor.Zeros(1)
+	if cols == 1 {
+		// The left singular vector of a single column is the normalized column.
+		u = hankel.Mul(complex(1/hankel.FrobeniusNorm(), 0))
+	} else if _, err := linalg.SVD(u, tensor.Zeros(1), hankel, bufs); err != nil {
+		return nil, nil, errors.Wrap(err, "")
+	}
+
+	// The dominant left singular vectors span the columns of the Hankel matrix, which are shifted by one row by the multiplication with diag(lambda).
+	// Hence the bases are the eigenvalues of x in top @ x = bottom.
+	top := resetCopy(tensor.Zeros(1), u.Slice([][2]int{{0, rows - 1}, {0, k}}))
+	bottom := u.Slice([][2]int{{1, rows}, {0, k}})
+	x := leastSquares(top, bottom, bufs)
+	lambda := tensor.Zeros(1)
+	if err := tensor.Eig(lambda, nil, x, bufs); err != nil {
+		return nil, nil, errors.Wrap(err, "")
+	}
+
+	vandermonde := tensor.Zeros(len(f), k)
+	for r := range f {
+		for i := range k {
+			vandermonde.SetAt([]int{r, i}, complex64(cmplx.Pow(complex128(lambda.At(i)), complex(float64(r+1), 0))))
+		}
+	}
+	c := leastSquares(vandermonde, tensor.T1(f).Reshape(len(f), 1), bufs)
+	return c.Reshape(k).ToSlice1(), lambda.ToSlice1(), nil
+}
+
+// leastSquares returns x of shape {n, p} that minimizes the Frobenius norm of a @ x - b, where a of shape {m, n} has full column rank and m >= n.
+// Matrix a is modified upon return.
+func leastSquares(a, b *tensor.Dense, bufs [3]*tensor.Dense) *tensor.Dense {
+	q := tensor.Zeros(1)
+	r := linalg.QR(q, a, [2]*tensor.Dense(bufs[:2]))
+	x := resetCopy(tensor.Zeros(1), tensor.MatMul(bufs[2], q.H(), b))
+	// Back substitution of the upper triangular r.
+	n, p := x.Shape()[0], x.Shape()[1]
+	for i := n - 1; i >= 0; i-- {
+		for j := range p {
+			v := x.At(i, j)
+			for l := i + 1; l < n; l++ {
+				v -= r.At(i, l) * x.At(l, j)
+			}
+			x.SetAt([]int{i, j}, v/r.At(i, i))
+		}
+	}
+	return x
+}
@@ -0,0 +1,106 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestFitExponentials(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		f   func(r int) float64
+		n   int
+		k   int
+		tol float64
+	}{
+		// A sum of k exponentials is fitted exactly.
+		{f: func(r int) float64 { return 0.7*math.Pow(0.5, float64(r)) + 0.2*math.Pow(0.9, float64(r)) }, n: 20, k: 2, tol: 1e-5},
+		{f: func(r int) float64 { return math.Pow(float64(r), -1.5) }, n: 50, k: 4, tol: 1e-3},
+		{f: func(r int) float64 { return math.Pow(float64(r), -3) }, n: 100, k: 5, tol: 1e-4},
+		{f: func(r int) float64 { return math.Pow(float64(r), -1) }, n: 7, k: 3, tol: 1e-3},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			f := make([]complex64, test.n)
+			for r := range f {
+				f[r] = complex(float32(test.f(r+1)), 0)
+			}
+			c, lambda, err := fitExponentials(f, test.k)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for r := 1; r <= test.n; r++ {
+				var fit complex128
+				for j := range c {
+					fit += complex128(c[j]) * cmplx.Pow(complex128(lambda[j]), complex(float64(r), 0))
+				}
+				if diff := cmplx.Abs(fit - complex(test.f(r), 0)); diff > test.tol {
+					t.Fatalf("%d %f %f %v %v", r, fit, test.f(r), c, lambda)
+				}
+			}
+		})
+	}
+}
+
+func TestFitExponentialsError(t *testing.T) {
+	t.Parallel()
+	f := []complex64{1, 0.5, 0.25, 0.125}
+	for i, k := range []int{0, 3, 5} {
+		if _, _, err := fitExponentials(f, k); err == nil {
+			t.Fatalf("%d %d", i, k)
+		}
+	}
+}
+
+func TestLongRangeIsing(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n     int
+		h     complex64
+		alpha float64
+		nExp  int
+		tol   float32
+	}{
+		{n: 6, h: 0.5, alpha: 1.5, nExp: 2, tol: 1e-2},
+		{n: 8, h: 1, alpha: 3, nExp: 3, tol: 1e-3},
+		{n: 3, h: 0.3, alpha: 1, nExp: 1, tol: 1e-5},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ws, err := LongRangeIsing(test.n, test.h, test.alpha, test.nExp)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if d := ws[0].Shape()[1]; d != 2+test.nExp {
+				t.Fatalf("%d", d)
+			}
+			if err := denseMPO(ws).Equal(denseLongRangeIsing(test.n, test.h, test.alpha), test.tol); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+// denseLongRangeIsing returns the dense hamiltonian of LongRangeIsing, in the basis of latticeIsing.
+func denseLongRangeIsing(n int, h complex64, alpha float64) *tensor.Dense {
+	dim := 1 << n
+	spin := func(state, i int) float64 { return float64(1 - 2*((state>>(n-1-i))&1)) }
+	a := tensor.Zeros(dim, dim)
+	for state := range dim {
+		var diag float64
+		for i := range n {
+			for j := i + 1; j < n; j++ {
+				diag -= spin(state, i) * spin(state, j) * math.Pow(float64(j-i), -alpha)
+			}
+			a.SetAt([]int{state ^ (1 << (n - 1 - i)), state}, -h)
+		}
+		a.SetAt([]int{state, state}, complex(float32(diag), 0))
+	}
+	return a
+}
@@ -0,0 +1,190 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// METTSOptions are options for sampling minimally entangled typical thermal states.
+type METTSOptions struct {
+	numSamples int
+	warmup     int
+	timeStep   float32
+	tebd       TEBDOptions
+	rand       *rand.Rand
+}
+
+// NewMETTSOptions returns the default METTS options.
+func NewMETTSOptions() METTSOptions {
+	opt := METTSOptions{}
+	opt.numSamples = 32
+	opt.warmup = 4
+	opt.timeStep = 0.05
+	opt.tebd = NewTEBDOptions()
+	return opt
+}
+
+// NumSamples sets the number of returned states.
+func (opt METTSOptions) NumSamples(n int) METTSOptions {
+	opt.numSamples = n
+	return opt
+}
+
+// Warmup sets the number of states discarded at the beginning of the Markov chain, which depend on the initial random product state.
+func (opt METTSOptions) Warmup(n int) METTSOptions {
+	opt.warmup = n
+	return opt
+}
+
+// TimeStep sets the maximum imaginary time step of the TEBD evolution by exp(-beta H / 2).
+func (opt METTSOptions) TimeStep(dt float32) METTSOptions {
+	opt.timeStep = dt
+	return opt
+}
+
+// TEBDOptions sets the truncation of the imaginary time evolution, see TEBD.
+func (opt METTSOptions) TEBDOptions(o TEBDOptions) METTSOptions {
+	opt.tebd = o
+	return opt
+}
+
+// Rand sets the random source of the collapses.
+// If r is nil, the global random source is used.
+func (opt METTSOptions) Rand(r *rand.Rand) METTSOptions {
+	opt.rand = r
+	return opt
+}
+
+// METTS samples minimally entangled typical thermal states of the chain hamiltonian H = sum_l hs[l] at the inverse temperature beta,
+// where hs are the bond terms of TEBD, such as those of IsingBonds.
+// Each state is the imaginary time evolution exp(-beta H / 2) |i> / sqrt(P_i) of a product state |i>,
+// and the next product state is drawn by collapsing the state site by site with the probabilities P(j|i) = |<j|phi_i>|^2.
+// The Markov chain samples the product states with the Boltzmann weights P_i = <i|exp(-beta H)|i> / Z,
+// hence the returned states are of equal weight, and Tr(rho A) is the EnsembleExpectation of the ensemble.
+// For spins 1/2, the collapses alternate between the Z and X bases, which shortens the autocorrelation of the chain in the ordered phases of the Ising model.
+// Since the states are only slightly entangled at high temperatures, chains far longer than those of exact diagonalization are within reach.
+// See E. M. Stoudenmire and S. R. White, Minimally entangled typical thermal state algorithms, New J. Phys. 12, 055026 (2010).
+func METTS(hs []*tensor.Dense, beta float32, bufs [10]*tensor.Dense, options ...METTSOptions) ([]WeightedMPS, error) {
+	opt := NewMETTSOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if len(hs) == 0 || beta < 0 || opt.timeStep <= 0 || opt.numSamples < 1 || opt.warmup < 0 {
+		return nil, errors.Errorf("%d %f %f %d %d", len(hs), beta, opt.timeStep, opt.numSamples, opt.warmup)
+	}
+	randFloat := rand.Float64
+	if opt.rand != nil {
+		randFloat = opt.rand.Float64
+	}
+	n, d := len(hs)+1, hs[0].Shape()[0]
+	steps := int(math.Ceil(float64(beta / 2 / opt.timeStep)))
+	var dt float32
+	if steps > 0 {
+		dt = beta / 2 / float32(steps)
+	}
+
+	// The initial product state is random in the Z basis.
+	zBasis := tensor.Zeros(1).Eye(d, 0)
+	bases := []*tensor.Dense{zBasis}
+	if d == 2 {
+		s := complex(float32(1/math.Sqrt2), 0)
+		bases = append(bases, tensor.T2([][]complex64{{s, s}, {s, -s}}))
+	}
+	basis := zBasis
+	state := make([]int, n)
+	for i := range state {
+		state[i] = int(randFloat() * float64(d))
+	}
+
+	ensemble := make([]WeightedMPS, 0, opt.numSamples)
+	for k := range opt.warmup + opt.numSamples {
+		ms := productMPS(basis, state)
+		if err := TEBD(ms, hs, dt, steps, bufs, opt.tebd.Imaginary(true)); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d", k))
+		}
+		if k >= opt.warmup {
+			ensemble = append(ensemble, WeightedMPS{Weight: 1, MPS: ms})
+		}
+
+		basis = bases[(k+1)%len(bases)]
+		collapse(state, ms, basis, randFloat)
+	}
+	return ensemble, nil
+}
+
+// productMPS returns the MPS of the product state, in which site i is in the state basis[state[i]], a row of basis.
+func productMPS(basis *tensor.Dense, state []int) []*tensor.Dense {
+	d := basis.Shape()[1]
+	ms := make([]*tensor.Dense, 0, len(state))
+	for _, s := range state {
+		m := tensor.Zeros(1, d, 1)
+		for j := range d {
+			m.SetAt([]int{0, j, 0}, basis.At(s, j))
+		}
+		ms = append(ms, m)
+	}
+	return ms
+}
+
+// collapse draws the product state |state> = prod_i |basis[state[i]]> with the probability |<state|ms>|^2,
+// where ms is normalized with its orthogonality center at the first site, and ms[1:] is right normalized as upon the return of TEBD.
+// The sites are drawn one by one, since the probability of the spin of a site conditioned on the spins of the previous sites is the norm square of
+// the left vector projected onto the spin, thanks to the right normalization of the remaining sites.
+func collapse(state []int, ms []*tensor.Dense, basis *tensor.Dense, randFloat func() float64) {
+	left := []complex64{1}
+	probs := make([]float64, 0, basis.Shape()[0])
+	var vs [][]complex64
+	for i, m := range ms {
+		s := m.Shape()
+		dLeft, d, dRight := s[mpsLeftAxis], s[mpsUpAxis], s[mpsRightAxis]
+
+		// vs[b] is the left vector of the next site if the spin of site i is the state b of basis.
+		vs = vs[:0]
+		probs = probs[:0]
+		var total float64
+		for b := range basis.Shape()[0] {
+			v := make([]complex64, dRight)
+			for r := range dRight {
+				for j := range d {
+					c := conj(basis.At(b, j))
+					for l := range dLeft {
+						v[r] += c * left[l] * m.At(l, j, r)
+					}
+				}
+			}
+			var p float64
+			for _, x := range v {
+				p += float64(real(x)*real(x) + imag(x)*imag(x))
+			}
+			vs = append(vs, v)
+			probs = append(probs, p)
+			total += p
+		}
+
+		// Rounding may leave u beyond the cumulative probabilities, in which case the last possible spin is drawn.
+		u := randFloat() * total
+		var b int
+		for j, p := range probs {
+			if p > 0 {
+				b = j
+			}
+		}
+		for j, p := range probs {
+			if u < p {
+				b = j
+				break
+			}
+			u -= p
+		}
+		state[i] = b
+		left = vs[b]
+		norm := complex(float32(1/math.Sqrt(probs[b])), 0)
+		for r := range left {
+			left[r] *= norm
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+)
+
+func TestMETTS(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n    int
+		h    complex64
+		beta float32
+	}{
+		{n: 6, h: 1, beta: 0},
+		{n: 6, h: 1, beta: 1},
+		{n: 6, h: 0.5, beta: 2},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [10]*tensor.Dense
+			for i := range bufs {
+				bufs[i] = tensor.Zeros(1)
+			}
+			r := rand.New(rand.NewPCG(uint64(i), 1))
+			opt := NewMETTSOptions().NumSamples(256).Rand(r)
+			ensemble, err := METTS(IsingBonds(test.n, test.h), test.beta, bufs, opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			ws := Ising([2]int{test.n, 1}, test.h)
+			energy, err := EnsembleExpectation(ws, ensemble, [2]*tensor.Dense(bufs[:2]))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			stats, err := EnsembleStatistics(ensemble, [2]*tensor.Dense(bufs[:2]))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			// The standard errors of the averages are those of the independent samples, since the alternating collapses decorrelate the chain.
+			var eErr, m2Err float64
+			for _, e := range ensemble {
+				ei, err := EnsembleExpectation(ws, []WeightedMPS{e}, [2]*tensor.Dense(bufs[:2]))
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				si, err := EnsembleStatistics([]WeightedMPS{e}, [2]*tensor.Dense(bufs[:2]))
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				eErr += math.Pow(float64(real(ei-energy)), 2)
+				m2Err += math.Pow(si.M2-stats.M2, 2)
+			}
+			k := float64(len(ensemble))
+			eErr, m2Err = math.Sqrt(eErr/(k-1)/k), math.Sqrt(m2Err/(k-1)/k)
+
+			// Compare with the exact thermal averages Tr(A exp(-beta H)) / Tr exp(-beta H).
+			hDense := denseMPO(ws)
+			rho := linalg.Expm(tensor.Zeros(1), resetCopy(tensor.Zeros(1), hDense).Mul(complex(-test.beta, 0)), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)})
+			mz := MagnetizationZ([2]int{test.n, 1})
+			thermal := func(a *tensor.Dense) float64 {
+				var tr, z complex64
+				ar := tensor.MatMul(tensor.Zeros(1), a, rho)
+				for j := range rho.Shape()[0] {
+					tr += ar.At(j, j)
+					z += rho.At(j, j)
+				}
+				return float64(real(tr / z))
+			}
+			wantE := thermal(hDense)
+			wantM2 := thermal(denseMPO(mpoProduct(mz, mz))) / float64(test.n*test.n)
+			if d := math.Abs(float64(real(energy)) - wantE); d > 4*eErr+0.01*math.Abs(wantE) {
+				t.Fatalf("%f %f %f", energy, wantE, eErr)
+			}
+			if d := math.Abs(stats.M2 - wantM2); d > 4*m2Err+0.01 {
+				t.Fatalf("%f %f %f", stats.M2, wantM2, m2Err)
+			}
+		})
+	}
+}
+
+func TestMETTSCollapse(t *testing.T) {
+	t.Parallel()
+	// The collapses of the GHZ state (|000> + |111>)/sqrt(2) in the Z basis are either all up or all down with equal probabilities.
+	state := tensor.Zeros(2, 2, 2)
+	state.SetAt([]int{0, 0, 0}, complex(float32(1/math.Sqrt2), 0))
+	state.SetAt([]int{1, 1, 1}, complex(float32(1/math.Sqrt2), 0))
+	ms := NewMPS(resetCopy(tensor.Zeros(1), state), [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)})
+	rightNormalizeAll(ms, []*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)})
+
+	r := rand.New(rand.NewPCG(1, 1))
+	spins := make([]int, 3)
+	var ups int
+	numSamples := 1000
+	for range numSamples {
+		collapse(spins, ms, tensor.Zeros(1).Eye(2, 0), r.Float64)
+		if spins[0] != spins[1] || spins[1] != spins[2] {
+			t.Fatalf("%v", spins)
+		}
+		if spins[0] == 0 {
+			ups++
+		}
+	}
+	if math.Abs(float64(ups)/float64(numSamples)-0.5) > 0.05 {
+		t.Fatalf("%d %d", ups, numSamples)
+	}
+}
@@ -0,0 +1,72 @@
+package mps
+
+import (
+	"fmt"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// MomentOptions are options for Moment.
+type MomentOptions struct {
+	maxBondDim    int
+	truncationErr float32
+}
+
+// NewMomentOptions returns the default Moment options.
+func NewMomentOptions() MomentOptions {
+	opt := MomentOptions{}
+	opt.maxBondDim = 64
+	opt.truncationErr = 1e-12
+	return opt
+}
+
+// MaxBondDim sets the maximum bond dimension of the states H^k|psi>, which are compressed after each application of the MPO.
+func (opt MomentOptions) MaxBondDim(d int) MomentOptions {
+	opt.maxBondDim = d
+	return opt
+}
+
+// TruncationError sets the maximum discarded weight, relative to the norm square, in the compression of each bond.
+func (opt MomentOptions) TruncationError(e float32) MomentOptions {
+	opt.truncationErr = e
+	return opt
+}
+
+// Moment returns <psi|H^order|psi> of the Hermitian MPO ws, such as the moments <M^4> of MagnetizationZ for the Binder cumulant,
+// or those of Ising for the cumulant expansions of the energy.
+// Like H2, which it equals for order 2 up to the compressions, the state is not normalized.
+// It is the inner product <H^i psi|H^j psi> for i = order/2 and j = order-i, where H^k|psi> is found by repeated ApplyMPO,
+// each followed by Compress, since the bond dimension would otherwise grow by a factor of the MPO bond dimension.
+// The compressions are the projections onto the compressed states, whose errors are second order in their infidelities.
+// ms is not modified.
+// See Section 5.1 Applying an MPO to an MPS, Ulrich Schollwock.
+func Moment(ws, ms []*tensor.Dense, order int, bufs [10]*tensor.Dense, options ...MomentOptions) (complex64, error) {
+	opt := NewMomentOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if order < 0 || len(ws) != len(ms) {
+		return 0, errors.Errorf("%d %d %d", order, len(ws), len(ms))
+	}
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+
+	half := order / 2
+	// left is H^half|psi>, and right is H^(order-half)|psi>.
+	left, right := ms, ms
+	for k := range order - half {
+		hm := ApplyMPO(make([]*tensor.Dense, len(ms)), ws, right, bufs[0])
+		if InnerProduct(hm, hm, bufs2) == 0 {
+			return 0, nil
+		}
+		compressed, err := compressProjection(hm, opt.maxBondDim, opt.truncationErr, bufs)
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("%d", k))
+		}
+		right = compressed
+		if k+1 == half {
+			left = right
+		}
+	}
+	return InnerProduct(left, right, bufs2), nil
+}
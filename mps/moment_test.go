@@ -0,0 +1,56 @@
+package mps
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestMoment(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ws    []*tensor.Dense
+		order int
+	}{
+		{ws: Ising([2]int{5, 1}, 1), order: 0},
+		{ws: Ising([2]int{5, 1}, 1), order: 1},
+		{ws: Ising([2]int{5, 1}, 1), order: 2},
+		{ws: Ising([2]int{6, 1}, 0.5), order: 3},
+		{ws: Ising([2]int{6, 1}, 0.5), order: 5},
+		// The fourth moment of the magnetization of the Binder cumulant.
+		{ws: MagnetizationZ([2]int{6, 1}), order: 4},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ms := RandMPSWithRand(rand.New(rand.NewPCG(uint64(i), 0)), test.ws, 4)
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+			got, err := Moment(test.ws, ms, test.order, bufs)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Compare with the dense matrix power.
+			psi := product(tensor.Zeros(1), ms, tensor.Zeros(1)).Reshape(-1, 1)
+			h := denseMPO(test.ws)
+			hpsi := resetCopy(tensor.Zeros(1), psi)
+			for range test.order {
+				hpsi = tensor.MatMul(tensor.Zeros(1), h, hpsi)
+			}
+			want := tensor.MatMul(tensor.Zeros(1), psi.H(), hpsi).At(0, 0)
+			if abs(got-want) > 1e-4*max(1, abs(want)) {
+				t.Fatalf("%v %v", got, want)
+			}
+			if test.order == 2 {
+				if h2 := H2(test.ws, ms, [2]*tensor.Dense(bufs[:2])); abs(got-h2) > 1e-4*max(1, abs(h2)) {
+					t.Fatalf("%v %v", got, h2)
+				}
+			}
+		})
+	}
+}
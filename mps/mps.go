@@ -6,12 +6,17 @@ package mps
 
 import (
 	"fmt"
+	"math"
 	"math/cmplx"
 	"math/rand/v2"
+	"runtime"
 	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/fumin/qising/debug"
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/qising/pool"
 	"github.com/fumin/tensor"
 	"github.com/pkg/errors"
 )
@@ -31,6 +36,10 @@ const (
 	epsilon = 0x1p-23
 )
 
+// ErrInterrupted is the cause of the error returned by a ground state search that is stopped by the Interrupt option.
+// It is linalg.ErrInterrupted, so that callers check for a single error across the solvers they interrupt.
+var ErrInterrupted = linalg.ErrInterrupted
+
 // NewMPS create a matrix product representation from a general state.
 func NewMPS(state *tensor.Dense, bufs [2]*tensor.Dense) []*tensor.Dense {
 	shape := state.Shape()
@@ -39,7 +48,7 @@ func NewMPS(state *tensor.Dense, bufs [2]*tensor.Dense) []*tensor.Dense {
 	var leftD int = 1
 	for _, physD := range shape[:len(shape)-1] {
 		q := tensor.Zeros(1)
-		r := tensor.QR(q, state.Reshape(leftD*physD, -1), bufs)
+		r := linalg.QR(q, state.Reshape(leftD*physD, -1), bufs)
 
 		leftD = r.Shape()[0]
 		state = r
@@ -56,12 +65,18 @@ func NewMPS(state *tensor.Dense, bufs [2]*tensor.Dense) []*tensor.Dense {
 // RandMPS creates a random matrix product state.
 // maxD is the maximum bond dimension, which is D in the discussion below equation 71 in section 4.1.4, Ulrich Schollwock.
 func RandMPS(mpo []*tensor.Dense, maxD int) []*tensor.Dense {
+	return RandMPSWithRand(nil, mpo, maxD)
+}
+
+// RandMPSWithRand is like RandMPS, but draws random numbers from r.
+// If r is nil, the global random source is used.
+func RandMPSWithRand(r *rand.Rand, mpo []*tensor.Dense, maxD int) []*tensor.Dense {
 	sites := make([]*tensor.Dense, 0, len(mpo))
 
 	// First site.
 	physD := mpo[0].Shape()[mpoDownAxis]
 	leftD := physD
-	sites = append(sites, randTensor(1, physD, min(physD, maxD)))
+	sites = append(sites, randTensorWithRand(r, 1, physD, min(physD, maxD)))
 
 	for i := 1; i <= len(mpo)-2; i++ {
 		physD := mpo[i].Shape()[mpoDownAxis]
@@ -79,13 +94,13 @@ func RandMPS(mpo []*tensor.Dense, maxD int) []*tensor.Dense {
 		leftD = rightD
 
 		si1 := sites[i-1].Shape()
-		sites = append(sites, randTensor(si1[mpsRightAxis], physD, min(rightD, maxD)))
+		sites = append(sites, randTensorWithRand(r, si1[mpsRightAxis], physD, min(rightD, maxD)))
 	}
 
 	// Last site.
 	physD = mpo[len(mpo)-1].Shape()[mpoDownAxis]
 	si1 := sites[len(mpo)-2].Shape()
-	sites = append(sites, randTensor(si1[mpsRightAxis], physD, 1))
+	sites = append(sites, randTensorWithRand(r, si1[mpsRightAxis], physD, 1))
 
 	return sites
 }
@@ -215,10 +230,77 @@ func H2(ws, ms []*tensor.Dense, bufs [2]*tensor.Dense) complex64 {
 	return fi1.At(0, 0, 0, 0)
 }
 
+// StoppingCriterion is the criterion with which the ground state search decides convergence.
+type StoppingCriterion int
+
+const (
+	// VarianceCriterion stops when the relative variance |<H^2> - <H>^2| / max(|<H^2>|, 1) is below the tolerance.
+	// It is the most reliable criterion, but requires a pass over the MPS whose cost grows with the square of the MPO bond dimension.
+	VarianceCriterion StoppingCriterion = iota
+	// EnergyCriterion stops when the relative change of the energy in an iteration, |E - E_prev| / max(|E|, 1), is below the tolerance.
+	EnergyCriterion
+	// GradientCriterion stops when the largest norm of the local gradients H x - <H> x over the sites x of an iteration,
+	// relative to max(|E|, 1), is below the tolerance.
+	GradientCriterion
+)
+
+// LocalSolver is the eigenvalue solver of the local effective hamiltonians in sweeps.
+type LocalSolver int
+
+const (
+	// ArnoldiSolver is the Krylov-Schur restarted Arnoldi iteration of linalg.ArnoldiOperator, which does not assume a Hermitian hamiltonian.
+	ArnoldiSolver LocalSolver = iota
+	// LanczosSolver is the thick restarted Lanczos iteration of linalg.LanczosOperator.
+	LanczosSolver
+	// DavidsonSolver is the Davidson method of linalg.DavidsonOperator, preconditioned by the diagonal of the effective hamiltonian.
+	DavidsonSolver
+	// SelectiveLanczosSolver is the Lanczos iteration with selective reorthogonalization of linalg.SelectiveLanczosOperator,
+	// whose steps are the cheapest, and which stops as soon as the ground state converges.
+	SelectiveLanczosSolver
+)
+
+func (s LocalSolver) String() string {
+	switch s {
+	case ArnoldiSolver:
+		return "arnoldi"
+	case LanczosSolver:
+		return "lanczos"
+	case DavidsonSolver:
+		return "davidson"
+	case SelectiveLanczosSolver:
+		return "selective"
+	default:
+		return fmt.Sprintf("LocalSolver(%d)", int(s))
+	}
+}
+
 // SearchGroundStateOptions are options for the MPS ground state search algorithm.
 type SearchGroundStateOptions struct {
 	maxIterations int
 	tol           float32
+	criterion     StoppingCriterion
+	eigenTol      float32
+	solver        LocalSolver
+	krylovDim     int
+
+	maxBondDim       int
+	bondDimSchedule  []int
+	expansion        float32
+	noise            float32
+	noiseDecay       float32
+	truncationErr    float32
+	truncationReport *TruncationReport
+
+	checkpointDir   string
+	checkpointEvery int
+	interrupt       <-chan struct{}
+
+	penalty  float32
+	rand     *rand.Rand
+	profile  *Profile
+	pool     *pool.Pool
+	workers  int
+	fixGauge bool
 }
 
 // NewSearchGroundStateOptions returns the default MPS ground state search options.
@@ -226,6 +308,10 @@ func NewSearchGroundStateOptions() SearchGroundStateOptions {
 	opt := SearchGroundStateOptions{}
 	opt.maxIterations = 32
 	opt.tol = 1e-6
+	opt.eigenTol = 2 * epsilon
+	opt.maxBondDim = 64
+	opt.truncationErr = 1e-12
+	opt.workers = 1
 	return opt
 }
 
@@ -235,12 +321,230 @@ func (opt SearchGroundStateOptions) MaxIterations(i int) SearchGroundStateOption
 	return opt
 }
 
-// Tol sets the tolerance of the convergence criterion <H^2> - (<H>)^2.
+// Tol sets the tolerance of the stopping criterion.
 func (opt SearchGroundStateOptions) Tol(tol float32) SearchGroundStateOptions {
 	opt.tol = tol
 	return opt
 }
 
+// StoppingCriterion sets the stopping criterion, which defaults to VarianceCriterion.
+func (opt SearchGroundStateOptions) StoppingCriterion(c StoppingCriterion) SearchGroundStateOptions {
+	opt.criterion = c
+	return opt
+}
+
+// EigenTol sets the tolerance of the local eigenvalue problems in the first sweep, which defaults to twice the machine precision.
+// The tolerance is tightened in later sweeps to follow the relative variance of the state, down to the machine precision.
+// Loose tolerances speed up early sweeps, in which the state is far from the ground state anyway,
+// but may cost additional sweeps when the local problems are small.
+func (opt SearchGroundStateOptions) EigenTol(tol float32) SearchGroundStateOptions {
+	opt.eigenTol = tol
+	return opt
+}
+
+// LocalSolver sets the eigenvalue solver of the local effective hamiltonians, which defaults to ArnoldiSolver.
+// The Lanczos, Davidson and selective Lanczos solvers assume that the hamiltonian is Hermitian, as are the effective hamiltonians of a Hermitian MPO.
+func (opt SearchGroundStateOptions) LocalSolver(s LocalSolver) SearchGroundStateOptions {
+	opt.solver = s
+	return opt
+}
+
+// KrylovSpaceDim sets the dimension of the Krylov space of the local eigenvalue problems, or the search space of the Davidson solver.
+// The default is that of linalg.ArnoldiOptions.
+func (opt SearchGroundStateOptions) KrylovSpaceDim(n int) SearchGroundStateOptions {
+	opt.krylovDim = n
+	return opt
+}
+
+// MaxBondDim sets the maximum bond dimension kept after the SVD truncation in two-site updates, and in single-site updates with SubspaceExpansion.
+func (opt SearchGroundStateOptions) MaxBondDim(d int) SearchGroundStateOptions {
+	opt.maxBondDim = d
+	return opt
+}
+
+// BondDimSchedule sets the increasing maximum bond dimensions of the stages of two-site updates, which replace MaxBondDim.
+// The search starts at the first bond dimension, and moves on to the next one when the stopping criterion either converges,
+// or plateaus by decreasing less than twofold between tests, and converges only at the last one.
+// Early sweeps are thus cheap, and the final bond dimension is reached from a state that is already close to the ground state,
+// which replaces separate searches of increasing bond dimensions.
+// The stages need more iterations in total than a single bond dimension, see MaxIterations.
+// Single-site updates keep the bond dimensions of the initial state, and ignore the schedule.
+// See the discussion on the growth of the bond dimension in Section 6.3 Iterative ground state search, Ulrich Schollwock.
+func (opt SearchGroundStateOptions) BondDimSchedule(dims []int) SearchGroundStateOptions {
+	opt.bondDimSchedule = dims
+	return opt
+}
+
+// SubspaceExpansion sets the mixing factor alpha of the subspace expansion of single-site updates, which is disabled by the default 0.
+// After the update of a site, its bond in the direction of the sweep is enlarged by alpha times the action of the hamiltonian on the site
+// from the environment behind it, and the next site by zeros, which leaves the state unchanged.
+// The truncated SVD that normalizes the site then keeps the directions of the enlarged bond that the next update needs,
+// so that SearchGroundState grows the bond dimensions up to MaxBondDim and escapes the local minima of a fixed bond space,
+// at a cost per update lower than that of SearchGroundState2Site, of which it is a cheaper approximation.
+// A mixing factor between 1e-4 and 1e-2 is typical, and larger ones grow the bond dimensions faster.
+// See C. Hubig, I. P. McCulloch, U. Schollwock and F. A. Wolf, Strictly single-site DMRG algorithm with subspace expansion, Phys. Rev. B 91, 155115 (2015).
+func (opt SearchGroundStateOptions) SubspaceExpansion(alpha float32) SearchGroundStateOptions {
+	opt.expansion = alpha
+	return opt
+}
+
+// Noise sets the mixing factor alpha of the density matrix perturbation of local updates in the first sweep, which decays by the factor decay in every later sweep,
+// and is disabled by the default 0.
+// In two-site updates, the reduced density matrix of the sites behind the sweep is perturbed by alpha times that of the action of the hamiltonian on them from the environment,
+// so that the truncation keeps directions absent from the current state, such as those of the other symmetry sector in the ordered phase,
+// without which a state trapped in a symmetric superposition, or in a single sector, stays there.
+// Single-site updates apply the noise as the SubspaceExpansion of mixing factor sqrt(alpha), if larger, and hence grow the bond dimensions up to MaxBondDim.
+// The noise is turned off once it decays below the machine precision, and the search converges only afterwards, which takes more iterations, see MaxIterations.
+// An alpha between 1e-4 and 1e-2, and a decay between 0.1 and 0.5 are typical.
+// See S. R. White, Density matrix renormalization group algorithms with a single center site, Phys. Rev. B 72, 180403(R) (2005).
+func (opt SearchGroundStateOptions) Noise(alpha, decay float32) SearchGroundStateOptions {
+	opt.noise = alpha
+	opt.noiseDecay = decay
+	return opt
+}
+
+// checkNoise checks that the noise is not negative and decays.
+func (opt SearchGroundStateOptions) checkNoise() error {
+	if opt.noise < 0 || opt.noiseDecay < 0 || opt.noiseDecay >= 1 {
+		return errors.Errorf("%f %f", opt.noise, opt.noiseDecay)
+	}
+	return nil
+}
+
+// noiseAt returns the mixing factor of the noise in iteration i, which is zero once it decays below the machine precision.
+func (opt SearchGroundStateOptions) noiseAt(i int) float32 {
+	alpha := opt.noise * float32(math.Pow(float64(opt.noiseDecay), float64(i)))
+	if alpha < epsilon {
+		return 0
+	}
+	return alpha
+}
+
+// TruncationError sets the maximum discarded weight, relative to the norm square, in the SVD truncation of two-site updates.
+func (opt SearchGroundStateOptions) TruncationError(e float32) SearchGroundStateOptions {
+	opt.truncationErr = e
+	return opt
+}
+
+// TruncationReport sets where the discarded weights of the SVD truncations of two-site updates are accumulated.
+// Single-site updates do not truncate, and leave it unchanged, unless with SubspaceExpansion.
+func (opt SearchGroundStateOptions) TruncationReport(r *TruncationReport) SearchGroundStateOptions {
+	opt.truncationReport = r
+	return opt
+}
+
+// Checkpoint sets the directory to which the MPS tensors and F expressions are saved every given number of iterations.
+// If a checkpoint already exists in dir, the search resumes from it.
+func (opt SearchGroundStateOptions) Checkpoint(dir string, every int) SearchGroundStateOptions {
+	opt.checkpointDir = dir
+	opt.checkpointEvery = every
+	return opt
+}
+
+// Interrupt sets a channel, such as the Done channel of a context.Context, whose closing stops the search at the end of the current iteration, unless it converges.
+// The search then saves a checkpoint if a checkpoint directory is set, regardless of how often checkpoints are due,
+// and returns an error whose cause is ErrInterrupted.
+func (opt SearchGroundStateOptions) Interrupt(c <-chan struct{}) SearchGroundStateOptions {
+	opt.interrupt = c
+	return opt
+}
+
+// Penalty sets the energy penalty of previously found eigenstates in SearchExcitedStates.
+// The penalty must be larger than the gap between those eigenstates and the one being searched for.
+// If it is not positive, which is the default, twice the largest magnitude of the found eigenvalues plus one is used.
+func (opt SearchGroundStateOptions) Penalty(w float32) SearchGroundStateOptions {
+	opt.penalty = w
+	return opt
+}
+
+// Rand sets the random source of the perturbations of the starting vectors of the local eigenvalue problems,
+// and of the random vectors of the local solvers, so that searches from the same initial state are reproducible, see RandMPSWithRand.
+// SearchExcitedStates also draws its initial states from r.
+// Since r is not safe for concurrent use, concurrent searches need their own sources.
+// If r is nil, the global random source is used.
+func (opt SearchGroundStateOptions) Rand(r *rand.Rand) SearchGroundStateOptions {
+	opt.rand = r
+	return opt
+}
+
+// Profile sets where the time spent in the phases of the search is recorded.
+func (opt SearchGroundStateOptions) Profile(p *Profile) SearchGroundStateOptions {
+	opt.profile = p
+	return opt
+}
+
+// Pool sets the pool from which the temporary tensors of sweeps are allocated.
+func (opt SearchGroundStateOptions) Pool(p *pool.Pool) SearchGroundStateOptions {
+	opt.pool = p
+	return opt
+}
+
+// Workers sets the number of goroutines among which the applications of the effective hamiltonian are divided, see linalg.ContractionPlan.Workers.
+// If n < 1, the number is runtime.GOMAXPROCS(0).
+func (opt SearchGroundStateOptions) Workers(n int) SearchGroundStateOptions {
+	opt.workers = n
+	return opt
+}
+
+// FixGauge sets whether to fix the gauge and the phase of the found state, see FixGauge, so that states of different runs are comparable.
+func (opt SearchGroundStateOptions) FixGauge(fix bool) SearchGroundStateOptions {
+	opt.fixGauge = fix
+	return opt
+}
+
+// fixStateGauge fixes the gauge of ms if requested, and recomputes the R expressions fs, which depend on the gauge.
+func (opt SearchGroundStateOptions) fixStateGauge(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense) error {
+	if !opt.fixGauge {
+		return nil
+	}
+	if err := FixGauge(ms, bufs); err != nil {
+		return errors.Wrap(err, "")
+	}
+	RExpressions(fs, ws, ms, [2]*tensor.Dense(bufs[:2]))
+	return nil
+}
+
+// resume loads the checkpoint if one exists, and returns the iteration to start from, and whether the search already converged at the checkpoint.
+func (opt SearchGroundStateOptions) resume(fs, ms []*tensor.Dense) (start int, resumed, converged bool, err error) {
+	if opt.checkpointDir == "" {
+		return 0, false, false, nil
+	}
+	i, converged, err := loadCheckpoint(opt.checkpointDir, fs, ms)
+	if err != nil {
+		return -1, false, false, errors.Wrap(err, "")
+	}
+	if i < 0 {
+		return 0, false, false, nil
+	}
+	return i + 1, true, converged, nil
+}
+
+// checkpoint saves a checkpoint if iteration i is due, recording whether the search converged at it.
+func (opt SearchGroundStateOptions) checkpoint(i int, converged bool, fs, ms []*tensor.Dense) error {
+	if opt.checkpointDir == "" || opt.checkpointEvery <= 0 || (i+1)%opt.checkpointEvery != 0 {
+		return nil
+	}
+	if err := saveCheckpoint(opt.checkpointDir, i, converged, fs, ms); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// interrupted saves a checkpoint of iteration i and returns ErrInterrupted if the Interrupt channel is closed.
+func (opt SearchGroundStateOptions) interrupted(i int, fs, ms []*tensor.Dense) error {
+	select {
+	case <-opt.interrupt:
+	default:
+		return nil
+	}
+	if opt.checkpointDir != "" {
+		if err := saveCheckpoint(opt.checkpointDir, i, false, fs, ms); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return errors.WithStack(ErrInterrupted)
+}
+
 // SearchGroundState performs the MPS ground state search.
 // See Section 6.3 Iterative ground state search, Ulrich Schollwock.
 func SearchGroundState(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense, options ...SearchGroundStateOptions) error {
@@ -248,116 +552,446 @@ func SearchGroundState(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense, optio
 	if len(options) > 0 {
 		opt = options[0]
 	}
+	return searchGroundState(fs, ws, ms, newProjector(nil, 0), bufs, opt)
+}
 
-	rightNormalizeAll(ms, bufs[:3])
-	RExpressions(fs, ws, ms, [2]*tensor.Dense(bufs[:2]))
-	convergence := struct {
-		ok bool
-		h2 complex64
-	}{}
-	for i := range opt.maxIterations {
-		if err := rightSweep(fs, ws, ms, bufs); err != nil {
+// searchGroundState searches for the ground state of the hamiltonian ws, penalized by proj.
+func searchGroundState(fs, ws, ms []*tensor.Dense, proj *projector, bufs [10]*tensor.Dense, opt SearchGroundStateOptions) error {
+	if err := opt.checkNoise(); err != nil {
+		return errors.Wrap(err, "")
+	}
+	start, resumed, converged, err := opt.resume(fs, ms)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if !resumed {
+		rightNormalizeAll(ms, bufs[:3])
+		RExpressions(fs, ws, ms, [2]*tensor.Dense(bufs[:2]))
+	}
+	proj.init(ms)
+	convergence := newConvergence()
+	// A search that converged at its checkpoint only fixes the gauge, regardless of the remaining iterations.
+	convergence.ok = converged
+	eigTol := opt.eigenTol
+	for i := start; i < opt.maxIterations && !convergence.ok; i++ {
+		sp := opt.sweepParams(i, eigTol, convergence.resetGradient(opt))
+		if err := rightSweep(fs, ws, ms, proj, sp, bufs); err != nil {
 			return errors.Wrap(err, fmt.Sprintf("%d", i))
 		}
-		if err := leftSweep(fs, ws, ms, bufs); err != nil {
+		if err := leftSweep(fs, ws, ms, proj, sp, bufs); err != nil {
 			return errors.Wrap(err, fmt.Sprintf("%d", i))
 		}
 
 		// Test for convergence.
-		bufs2 := [2]*tensor.Dense(bufs[:2])
-		psiIP := InnerProduct(ms, ms, bufs2)
-		if abs(psiIP) < epsilon {
-			return errors.Errorf("%f", psiIP)
+		t := sp.prof.clock()
+		if err := convergence.test(fs, ws, ms, opt, eigTol, bufs); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
 		}
-		// Since leftSweep built R expression to fs[1], we need only further build fs[0].
-		rExpression(fs[0], fs[1], ws[0], ms[0], bufs[:])
-		h := fs[0].At(0, 0, 0) / psiIP
-		// Compute h2 and use the criterion h2 - h*h.
-		h2 := H2(ws, ms, bufs2) / psiIP
-		convergence.h2 = h2 - h*h
-		if abs(convergence.h2) < opt.tol*max(abs(h2), 1) {
-			convergence.ok = true
+		sp.prof.lap(convergencePhase, t)
+		eigTol = tightenEigenTol(eigTol, convergence.measure)
+		// The state of a noisy sweep is not a converged one.
+		convergence.ok = convergence.ok && sp.noise == 0
+		if err := opt.checkpoint(i, convergence.ok, fs, ms); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		if convergence.ok {
 			break
 		}
+		if err := opt.interrupted(i, fs, ms); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
 	}
 	if !convergence.ok {
-		return errors.Errorf("%#v", convergence)
+		return errors.Errorf("%#v", *convergence)
+	}
+	if err := opt.fixStateGauge(fs, ws, ms, bufs); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// convergence is the state of the stopping criterion of a ground state search.
+type convergence struct {
+	ok bool
+	// measure is the value of the stopping criterion, which is compared against the tolerance.
+	measure float32
+	energy  complex64
+	// gradient is the largest local gradient norm of the current iteration, which is only tracked for GradientCriterion.
+	gradient float32
+}
+
+func newConvergence() *convergence {
+	return &convergence{measure: float32(math.Inf(1)), energy: complex64(cmplx.NaN())}
+}
+
+// resetGradient resets the largest local gradient norm at the start of an iteration,
+// and returns where sweeps should record it, or nil if the gradient is not needed.
+func (c *convergence) resetGradient(opt SearchGroundStateOptions) *float32 {
+	if opt.criterion != GradientCriterion {
+		return nil
+	}
+	c.gradient = 0
+	return &c.gradient
+}
+
+// test tests for convergence with the stopping criterion in opt.
+// It assumes that a left sweep has just been completed, so that fs[1] holds the R expression.
+func (c *convergence) test(fs, ws, ms []*tensor.Dense, opt SearchGroundStateOptions, eigTol float32, bufs [10]*tensor.Dense) error {
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	psiIP := InnerProduct(ms, ms, bufs2)
+	if abs(psiIP) < epsilon {
+		return errors.Errorf("%f", psiIP)
+	}
+	// Since leftSweep built R expression to fs[1], we need only further build fs[0].
+	rExpression(fs[0], fs[1], ws[0], ms[0], bufs[:])
+	h := fs[0].At(0, 0, 0) / psiIP
+
+	switch opt.criterion {
+	case EnergyCriterion:
+		// Comparisons with the NaN energy of the first iteration are false.
+		c.measure = float32(math.Inf(1))
+		if d := abs(h-c.energy) / max(abs(h), 1); d < c.measure {
+			c.measure = d
+		}
+	case GradientCriterion:
+		c.measure = c.gradient / max(abs(h), 1)
+	default:
+		// Compute h2 and use the criterion h2 - h*h.
+		h2 := H2(ws, ms, bufs2) / psiIP
+		c.measure = abs(h2-h*h) / max(abs(h2), 1)
 	}
+	c.energy = h
+
+	// A state counts as converged only if its local eigenvalue problems were solved at least as accurately as the criterion.
+	c.ok = c.measure < opt.tol && eigTol <= max(opt.tol, 2*epsilon)
 	return nil
 }
 
-func leftSweep(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense) error {
+// localGradient returns the norm of the gradient H x - <H> x of the energy at the site x, relative to the norm of x.
+// x is a MPS site flattened to a column vector, and h is its effective hamiltonian.
+func localGradient(h linalg.LinearOperator, x *tensor.Dense, bufs [2]*tensor.Dense) float32 {
+	hx := h.Apply(bufs[0], x)
+	xx := tensor.MatMul(bufs[1], x.H(), x).At(0, 0)
+	e := tensor.MatMul(bufs[1], x.H(), hx).At(0, 0) / xx
+	hx.Add(-e, x)
+	return hx.FrobeniusNorm() / float32(math.Sqrt(float64(real(xx))))
+}
+
+// sweepParams are the parameters of the sweeps in an iteration.
+type sweepParams struct {
+	// eigTol is the tolerance of the local eigenvalue problems.
+	eigTol float32
+	// solver and krylovDim are the solver of the local eigenvalue problems and the dimension of its search space.
+	solver    LocalSolver
+	krylovDim int
+	// grad, if not nil, records the largest local gradient norm.
+	grad *float32
+	// prof, if not nil, records the time spent in the sweeps.
+	prof *SweepProfile
+	// pool, if not nil, is where temporary tensors are allocated from.
+	pool *pool.Pool
+	// workers is the number of goroutines applying the effective hamiltonian.
+	workers int
+	// rand, if not nil, is the random source of the local solves.
+	rand *rand.Rand
+	// expansion is the mixing factor of the subspace expansion of single-site updates, which is disabled if zero,
+	// in which case the sites are normalized without truncation.
+	expansion float32
+	// noise is the mixing factor of the density matrix perturbation of two-site updates, which is disabled if zero.
+	noise         float32
+	maxBondDim    int
+	truncationErr float32
+	report        *TruncationReport
+}
+
+// gradient records the local gradient norm of site x of the effective hamiltonian h, if requested.
+func (sp sweepParams) gradient(h linalg.LinearOperator, x *tensor.Dense, bufs [2]*tensor.Dense) {
+	if sp.grad == nil {
+		return
+	}
+	t := sp.prof.clock()
+	*sp.grad = max(*sp.grad, localGradient(h, x, bufs))
+	sp.prof.lap(convergencePhase, t)
+}
+
+// sweepParams returns the parameters of the sweeps of iteration i.
+func (opt SearchGroundStateOptions) sweepParams(i int, eigTol float32, grad *float32) sweepParams {
+	workers := opt.workers
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	sp := sweepParams{eigTol: eigTol, solver: opt.solver, krylovDim: opt.krylovDim, grad: grad, prof: opt.profile.next(), pool: opt.pool, workers: workers, rand: opt.rand}
+	sp.expansion, sp.maxBondDim, sp.truncationErr, sp.report = opt.expansion, opt.maxBondDim, opt.truncationErr, opt.truncationReport
+	sp.noise = opt.noiseAt(i)
+	sp.expansion = max(sp.expansion, float32(math.Sqrt(float64(sp.noise))))
+	return sp
+}
+
+// warmStartNoise is the relative norm of the random perturbation of the initial vectors of the local solves, see eigensolve.
+const warmStartNoise = 1e-3
+
+// eigensolve finds the ground state of the local effective hamiltonian h with the local solver, starting from x0,
+// which is the current state of the sites, and is close to the ground state once the sweeps begin to converge.
+// The initial vector is perturbed by a small random vector, since a Krylov space started from a state of a symmetry sector,
+// such as one of the nearly degenerate ground states of the ordered phase, never finds the lower state of another sector.
+func (sp sweepParams) eigensolve(eigvals, eigvecs *tensor.Dense, h linalg.LinearOperator, x0 *tensor.Dense, bufs [7]*tensor.Dense) error {
+	// eigvecs holds the initial vector, which the solvers read only at their start.
+	randFloat := rand.Float32
+	if sp.rand != nil {
+		randFloat = sp.rand.Float32
+	}
+	start := resetCopy(eigvecs, x0)
+	scale := warmStartNoise * start.FrobeniusNorm() / float32(math.Sqrt(float64(h.Dim())))
+	for ij, v := range start.All() {
+		start.SetAt(ij, v+complex(scale*(randFloat()*2-1), scale*(randFloat()*2-1)))
+	}
+	opt := linalg.NewArnoldiOptions().Tol(sp.eigTol).Profile(sp.prof.arnoldi()).InitialVector(start).Rand(sp.rand)
+	if sp.krylovDim > 0 {
+		opt = opt.KrylovSpaceDim(sp.krylovDim)
+	}
+	solve := linalg.ArnoldiOperator
+	switch sp.solver {
+	case LanczosSolver:
+		solve = linalg.LanczosOperator
+	case DavidsonSolver:
+		solve = linalg.DavidsonOperator
+	case SelectiveLanczosSolver:
+		solve = linalg.SelectiveLanczosOperator
+	}
+	if err := solve(eigvals, eigvecs, h, 1, bufs, opt); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// tightenEigenTol returns the tolerance of the local eigenvalue problems for the next sweep.
+// The tolerance follows a hundredth of the stopping criterion, which is a measure of how far the state is from an eigenstate,
+// since solving the local problems much more accurately than that is wasted effort.
+func tightenEigenTol(eigTol, measure float32) float32 {
+	return max(2*epsilon, min(eigTol, measure/100))
+}
+
+func leftSweep(fs, ws, ms []*tensor.Dense, proj *projector, sp sweepParams, bufs [10]*tensor.Dense) error {
+	h := newEffectiveH(sp.pool)
+	h.workers = sp.workers
+	defer h.release(sp.pool)
 	for l := len(ms) - 1; l >= 1; l-- {
 		fRight := ones(fs[l], 1, 1, 1)
 		if l+1 <= len(ms)-1 {
 			fRight = fs[l+1]
 		}
-		h := getH(bufs[0], fs[l-1], fRight, ws[l], l, bufs[1:])
+		h.set(fs[l-1], fRight, ws[l])
+		if debug.Enabled {
+			debug.Assert(checkHermitian(h, ws[l]))
+		}
+		proj.set(h, l)
+		sp.gradient(proj, ms[l].Reshape(-1, 1), [2]*tensor.Dense(bufs[1:3]))
 
+		t := sp.prof.clock()
 		eigvals, eigvecs := bufs[1], bufs[2]
 		abufs := [7]*tensor.Dense(bufs[3:])
-		if err := tensor.Arnoldi(eigvals, eigvecs, h, 1, abufs); err != nil {
+		if err := sp.eigensolve(eigvals, eigvecs, proj, ms[l].Reshape(-1, 1), abufs); err != nil {
 			return errors.Wrap(err, "")
 		}
 		resetCopy(ms[l], eigvecs.Reshape(ms[l].Shape()...))
+		t = sp.prof.lap(eigensolvePhase, t)
 
 		// Right normalize ms[l], and multiply into ms[l-1].
 		// Since ms[l-1] is modified, reset fs[l-1].
-		rightNormalize(ms, l, bufs[:3])
+		if sp.expansion != 0 {
+			if err := expandLeft(fRight, ws[l], ms, l, sp, bufs); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%d", l))
+			}
+		} else {
+			rightNormalize(ms, l, bufs[:3])
+		}
 		fs[l-1].Reset(1)
+		t = sp.prof.lap(decompositionPhase, t)
 
 		rExpression(fs[l], fRight, ws[l], ms[l], bufs[:2])
+		if debug.Enabled {
+			debug.Assert(checkRightCanonical(fs, ws, ms, l))
+		}
+		proj.rightOverlap(ms, l)
+		sp.prof.lap(environmentPhase, t)
 	}
 	return nil
 }
 
-func rightSweep(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense) error {
+func rightSweep(fs, ws, ms []*tensor.Dense, proj *projector, sp sweepParams, bufs [10]*tensor.Dense) error {
+	h := newEffectiveH(sp.pool)
+	h.workers = sp.workers
+	defer h.release(sp.pool)
 	for l := range len(ms) - 1 {
 		fLeft := ones(fs[l], 1, 1, 1)
 		if l-1 >= 0 {
 			fLeft = fs[l-1]
 		}
-		h := getH(bufs[0], fLeft, fs[l+1], ws[l], l, bufs[1:])
+		h.set(fLeft, fs[l+1], ws[l])
+		if debug.Enabled {
+			debug.Assert(checkHermitian(h, ws[l]))
+		}
+		proj.set(h, l)
+		sp.gradient(proj, ms[l].Reshape(-1, 1), [2]*tensor.Dense(bufs[1:3]))
 
+		t := sp.prof.clock()
 		eigvals, eigvecs := bufs[1], bufs[2]
 		abufs := [7]*tensor.Dense(bufs[3:])
-		if err := tensor.Arnoldi(eigvals, eigvecs, h, 1, abufs); err != nil {
+		if err := sp.eigensolve(eigvals, eigvecs, proj, ms[l].Reshape(-1, 1), abufs); err != nil {
 			return errors.Wrap(err, "")
 		}
 		resetCopy(ms[l], eigvecs.Reshape(ms[l].Shape()...))
+		t = sp.prof.lap(eigensolvePhase, t)
 
 		// Left normalize ms[l], and multiply into ms[l+1].
 		// Since ms[l+1] is modified, reset fs[l+1].
 		// The reason why ms[:l-1] has to be left-normalized, and ms[l:] right-normalized at all times is because in this case,
 		// the generalized eigenvalue problem simplifies to the ordinary eigenvalue problem we are doing here.
 		// See Equation 211, Section 6.3 Iterative ground state search, Ulrich Schollwock.
-		leftNormalize(ms, l, bufs[:3])
+		if sp.expansion != 0 {
+			if err := expandRight(fLeft, ws[l], ms, l, sp, bufs); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%d", l))
+			}
+		} else {
+			leftNormalize(ms, l, bufs[:3])
+		}
 		fs[l+1].Reset(1)
+		t = sp.prof.lap(decompositionPhase, t)
 
 		lExpression(fs[l], fLeft, ws[l], ms[l], bufs[:2])
+		if debug.Enabled {
+			debug.Assert(checkLeftCanonical(fs, ws, ms, l))
+		}
+		proj.leftOverlap(ms, l)
+		sp.prof.lap(environmentPhase, t)
 	}
 	return nil
 }
 
-// getH returns the H matrix defined in Equation 210, Section 6.3 Iterative ground state search, Ulrich Schollwock.
-func getH(h, left, right, w *tensor.Dense, l int, bufs []*tensor.Dense) *tensor.Dense {
-	// right is of shape {rightTop, rightMid, rightBot}.
-	// wRight is of shape {mpoLeft, mpoUp, mpoDown, rightTop, rightBot}.
-	wRight := tensor.Product(bufs[0], w, right, [][2]int{{mpoRightAxis, 1}})
+// effectiveH is the H matrix defined in Equation 210, Section 6.3 Iterative ground state search, Ulrich Schollwock.
+// Instead of materializing the matrix, which is of size O((D^2 d)^2), it is applied by contracting with the L and R expressions and the MPO.
+type effectiveH struct {
+	left, right, w *tensor.Dense
+	// plans are the contractions of Apply, which are planned again only when the shapes of left, right or w change,
+	// so that the many applications in an eigensolve perform no allocation.
+	plans  [3]*linalg.ContractionPlan
+	shapes [3][]int
+	digits [3]int
+	bufs   [3]*tensor.Dense
+	// workers, if greater than one, is the number of goroutines of each contraction.
+	workers int
+}
 
-	// left is of shape {leftTop, leftMid, leftBot}.
-	// lwr is of shape {leftTop, leftBot, mpoUp, mpoDown, rightTop, rightBot}.
-	lwr := tensor.Product(bufs[1], left, wRight, [][2]int{{1, 0}})
+func newEffectiveH(p *pool.Pool) *effectiveH {
+	h := &effectiveH{}
+	for i := range len(h.bufs) {
+		h.bufs[i] = p.Get(1)
+	}
+	return h
+}
 
-	// h is of shape {leftTop, mpoUp, rightTop, leftBot, mpoDown, rightBot}.
-	resetCopy(h, lwr.Transpose(0, 2, 4, 1, 3, 5))
+func (h *effectiveH) release(p *pool.Pool) {
+	p.Release(h.bufs[:]...)
+}
 
-	// Reshape h to square matrix.
+func (h *effectiveH) set(left, right, w *tensor.Dense) {
 	ls, ws, rs := left.Shape(), w.Shape(), right.Shape()
 	if ls[0] != ls[2] || ws[mpoUpAxis] != ws[mpoDownAxis] || rs[0] != rs[2] {
 		panic(fmt.Sprintf("%#v %#v %#v", ls, ws, rs))
 	}
-	return h.Reshape(ls[0]*ws[mpoUpAxis]*rs[0], ls[2]*ws[mpoDownAxis]*rs[2])
+	h.left, h.right, h.w = left, right, w
+	if h.plans[0] != nil && slices.Equal(ls, h.shapes[0]) && slices.Equal(ws, h.shapes[1]) && slices.Equal(rs, h.shapes[2]) {
+		return
+	}
+	h.shapes = [3][]int{slices.Clone(ls), slices.Clone(ws), slices.Clone(rs)}
+
+	mShape := []int{ls[2], ws[mpoDownAxis], rs[2]}
+	h.plans[0] = linalg.NewContractionPlan(ls, mShape, [][2]int{{2, mpsLeftAxis}})
+	h.plans[1] = linalg.NewContractionPlan(ws, h.plans[0].Shape(), [][2]int{{mpoLeftAxis, 1}, {mpoDownAxis, 2}})
+	h.plans[2] = linalg.NewContractionPlan(h.plans[1].Shape(), rs, [][2]int{{0, 1}, {3, 2}}, 1, 0, 2).Reshape(h.Dim(), 1)
+	if h.workers > 1 {
+		for _, p := range h.plans {
+			p.Workers(h.workers)
+		}
+	}
+	h.bufs[0].Reset(mShape...)
+	h.bufs[1].Reset(h.plans[0].Shape()...)
+	h.bufs[2].Reset(h.plans[1].Shape()...)
+}
+
+// Dim returns the size of a MPS site.
+func (h *effectiveH) Dim() int {
+	return h.left.Shape()[2] * h.w.Shape()[mpoDownAxis] * h.right.Shape()[2]
+}
+
+// Apply applies H to the MPS site x, which is flattened to a column vector.
+// See Figure 39, Section 6.3 Iterative ground state search, Ulrich Schollwock for a graphical explanation.
+func (h *effectiveH) Apply(dst, x *tensor.Dense) *tensor.Dense {
+	// m is of shape {mpsLeft, mpsUp, mpsRight}.
+	m := unflatten(h.bufs[0], x, h.digits[:])
+
+	// left is of shape {leftTop, leftMid, leftBot}.
+	// lm is of shape {leftTop, leftMid, mpsUp, mpsRight}.
+	lm := h.plans[0].Product(h.bufs[1], h.left, m)
+
+	// wlm is of shape {mpoRight, mpoUp, leftTop, mpsRight}.
+	wlm := h.plans[1].Product(h.bufs[2], h.w, lm)
+
+	// right is of shape {rightTop, rightMid, rightBot}.
+	// The result is of shape {mpoUp, leftTop, rightTop}, transposed to {leftTop, mpoUp, rightTop} and flattened.
+	return h.plans[2].Product(dst, wlm, h.right)
+}
+
+// Diagonal returns the diagonal of H, whose element (a, sigma, b) is sum_{beta, gamma} L[a, beta, a] W[beta, gamma, sigma, sigma] R[b, gamma, b].
+func (h *effectiveH) Diagonal(dst *tensor.Dense) *tensor.Dense {
+	// lw is of shape {leftTop, mpoRight, mpoUp}.
+	lw := tensor.Product(tensor.Zeros(1), envDiagonal(h.left), mpoDiagonal(h.w), [][2]int{{1, mpoLeftAxis}})
+	// The result is of shape {leftTop, mpoUp, rightTop}.
+	d := tensor.Product(tensor.Zeros(1), lw, envDiagonal(h.right), [][2]int{{1, 1}})
+	return resetCopy(dst, d).Reshape(-1, 1)
+}
+
+// envDiagonal returns f[a, beta, a] of the L or R expression f, which is of shape {top, mid}.
+func envDiagonal(f *tensor.Dense) *tensor.Dense {
+	s := f.Shape()
+	d := tensor.Zeros(s[0], s[1])
+	for a := range s[0] {
+		for beta := range s[1] {
+			d.SetAt([]int{a, beta}, f.At(a, beta, a))
+		}
+	}
+	return d
+}
+
+// mpoDiagonal returns w[beta, gamma, sigma, sigma] of the MPO site w, which is of shape {mpoLeft, mpoRight, mpoUp}.
+func mpoDiagonal(w *tensor.Dense) *tensor.Dense {
+	s := w.Shape()
+	d := tensor.Zeros(s[mpoLeftAxis], s[mpoRightAxis], s[mpoUpAxis])
+	for beta := range s[mpoLeftAxis] {
+		for gamma := range s[mpoRightAxis] {
+			for sigma := range s[mpoUpAxis] {
+				d.SetAt([]int{beta, gamma, sigma}, w.At(beta, gamma, sigma, sigma))
+			}
+		}
+	}
+	return d
+}
+
+// unflatten copies the column vector x into m in row major order, where digits is the scratch for the indices of m.
+func unflatten(m, x *tensor.Dense, digits []int) *tensor.Dense {
+	s := m.Shape()
+	clear(digits)
+	for i := range x.Shape()[0] {
+		m.SetAt(digits, x.At(i, 0))
+		for j := len(digits) - 1; j >= 0; j-- {
+			digits[j]++
+			if digits[j] < s[j] {
+				break
+			}
+			digits[j] = 0
+		}
+	}
+	return m
 }
 
 func rightNormalizeAll(ms []*tensor.Dense, bufs []*tensor.Dense) {
@@ -385,6 +1019,41 @@ func rightNormalize(ms []*tensor.Dense, i int, bufs []*tensor.Dense) {
 	ms[i] = resetCopy(ms[i], q.H()).Reshape(-1, dUp, dRight)
 }
 
+// gaugeTruncationErr is the relative weight of the Schmidt vectors dropped by FixGauge, which are indeterminate since their singular values are rounding errors.
+const gaugeTruncationErr = 1e-12
+
+// FixGauge brings ms to the right canonical form, in which ms[1:] are right normalized, and fixes the remaining freedom of the gauge,
+// so that states equal up to a phase have equal tensors.
+// The bond bases are the Schmidt vectors in decreasing order of their singular values, found by SVDs sweeping leftwards from the left canonical form.
+// Each Schmidt vector is determined up to a phase, which is fixed by linalg.FixPhase, and so is the phase of the state, which is left in ms[0].
+// Schmidt vectors of negligible singular values are dropped, which may reduce the bond dimensions to the ranks of the bonds,
+// and those of degenerate singular values remain determined only up to rounding errors.
+// See Section 4.4.3 Generation of a mixed-canonical MPS, Ulrich Schollwock.
+func FixGauge(ms []*tensor.Dense, bufs [10]*tensor.Dense) error {
+	leftNormalizeAll(ms, bufs[:3])
+	for i := len(ms) - 1; i >= 1; i-- {
+		s := ms[i].Shape()
+		dLeft, dUp, dRight := s[mpsLeftAxis], s[mpsUpAxis], s[mpsRightAxis]
+
+		// Since ms[:i] are left normalized and ms[i+1:] right normalized, the SVD ms[i] = u @ s @ vh is the Schmidt decomposition of the bond i.
+		// The SVD overwrites its input, hence the copy a.
+		a := resetCopy(bufs[0], ms[i]).Reshape(dLeft, dUp*dRight)
+		_, vh, _, _, err := truncatedSVD(bufs[1], bufs[2], a, dLeft, gaugeTruncationErr, [4]*tensor.Dense(bufs[3:7]))
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		linalg.FixPhase(vh.H())
+
+		// ms[i-1] = ms[i-1] @ u @ s, where u @ s = ms[i] @ vh.H after fixing the phases of the rows of vh.
+		us := tensor.MatMul(bufs[3], ms[i].Reshape(dLeft, dUp*dRight), vh.H())
+		resetCopy(ms[i-1], tensor.Product(bufs[4], ms[i-1], us, [][2]int{{mpsRightAxis, 0}}))
+		ms[i] = resetCopy(ms[i], vh).Reshape(-1, dUp, dRight)
+	}
+	s := slices.Clone(ms[0].Shape())
+	linalg.FixPhase(ms[0].Reshape(-1, 1)).Reshape(s...)
+	return nil
+}
+
 func leftNormalizeAll(ms []*tensor.Dense, bufs []*tensor.Dense) {
 	for i := range len(ms) - 1 {
 		leftNormalize(ms, i, bufs)
@@ -398,7 +1067,7 @@ func leftNormalize(ms []*tensor.Dense, i int, bufs []*tensor.Dense) {
 	// Decompose ms[i] = q @ r.
 	mi := ms[i].Reshape(dLeft*dUp, s[mpsRightAxis])
 	q, qrbufs := bufs[0], [2]*tensor.Dense(bufs[1:])
-	r := tensor.QR(q, mi, qrbufs)
+	r := linalg.QR(q, mi, qrbufs)
 
 	// ms[i+1] = r @ ms[i+1].
 	axes := [][2]int{{1, mpsLeftAxis}}
@@ -409,7 +1078,7 @@ func leftNormalize(ms []*tensor.Dense, i int, bufs []*tensor.Dense) {
 }
 
 func lq(q, a *tensor.Dense, bufs [2]*tensor.Dense) *tensor.Dense {
-	r := tensor.QR(q, a.H(), bufs)
+	r := linalg.QR(q, a.H(), bufs)
 	return r.H()
 }
 
@@ -475,10 +1144,22 @@ func abs(x complex64) float32 {
 	return float32(cmplx.Abs(complex128(x)))
 }
 
+func conj(x complex64) complex64 {
+	return complex(real(x), -imag(x))
+}
+
 func randTensor(shape ...int) *tensor.Dense {
+	return randTensorWithRand(nil, shape...)
+}
+
+func randTensorWithRand(r *rand.Rand, shape ...int) *tensor.Dense {
+	randFloat := rand.Float32
+	if r != nil {
+		randFloat = r.Float32
+	}
 	t := tensor.Zeros(shape...)
 	for ijk := range t.All() {
-		v := complex(rand.Float32()*2-1, rand.Float32()*2-1)
+		v := complex(randFloat()*2-1, randFloat()*2-1)
 		t.SetAt(ijk, v)
 	}
 	return t
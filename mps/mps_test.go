@@ -6,9 +6,11 @@ import (
 	"log"
 	"math"
 	"math/cmplx"
+	"math/rand/v2"
 	"slices"
 	"testing"
 
+	"github.com/fumin/qising/pool"
 	"github.com/fumin/tensor"
 )
 
@@ -411,6 +413,342 @@ func TestSearchGroundState(t *testing.T) {
 	}
 }
 
+func TestSearchGroundStateEigenTol(t *testing.T) {
+	t.Parallel()
+	h := Ising([2]int{16, 1}, 0.501187)
+	fs := make([]*tensor.Dense, 0, len(h))
+	for _ = range h {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	var bufs [10]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+
+	// Start with a loose tolerance, which is tightened as the sweeps converge.
+	mps := RandMPS(h, 8)
+	opt := NewSearchGroundStateOptions().EigenTol(1e-2)
+	if err := SearchGroundState(fs, h, mps, bufs, opt); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	e0 := LExpressions(fs, h, mps, bufs2) / InnerProduct(mps, mps, bufs2)
+	var want complex64 = -16.151592
+	if diff := abs(e0 - want); diff > 2e-4*abs(want) {
+		t.Fatalf("%f %f %f", diff, e0, want)
+	}
+}
+
+func TestSearchGroundStateCriterion(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		opt    SearchGroundStateOptions
+		search func([]*tensor.Dense, []*tensor.Dense, []*tensor.Dense, [10]*tensor.Dense, ...SearchGroundStateOptions) error
+		initD  int
+	}
+	tests := []testcase{
+		{
+			opt:    NewSearchGroundStateOptions().StoppingCriterion(EnergyCriterion).Tol(1e-6),
+			search: SearchGroundState,
+			initD:  8,
+		},
+		{
+			opt:    NewSearchGroundStateOptions().StoppingCriterion(GradientCriterion).Tol(1e-4),
+			search: SearchGroundState,
+			initD:  8,
+		},
+		{
+			opt:    NewSearchGroundStateOptions().StoppingCriterion(GradientCriterion).Tol(1e-4).MaxBondDim(8),
+			search: SearchGroundState2Site,
+			initD:  1,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			h := Ising([2]int{16, 1}, 0.501187)
+			fs := make([]*tensor.Dense, 0, len(h))
+			for _ = range h {
+				fs = append(fs, tensor.Zeros(1))
+			}
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+
+			mps := RandMPS(h, test.initD)
+			if err := test.search(fs, h, mps, bufs, test.opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			bufs2 := [2]*tensor.Dense(bufs[:2])
+			e0 := LExpressions(fs, h, mps, bufs2) / InnerProduct(mps, mps, bufs2)
+			var want complex64 = -16.151592
+			if diff := abs(e0 - want); diff > 2e-4*abs(want) {
+				t.Fatalf("%f %f %f", diff, e0, want)
+			}
+		})
+	}
+}
+
+func TestSearchGroundStatePool(t *testing.T) {
+	t.Parallel()
+	h := Ising([2]int{8, 1}, 0.5)
+	fs := make([]*tensor.Dense, 0, len(h))
+	for _ = range h {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	var bufs [10]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+
+	p := pool.New()
+	opt := NewSearchGroundStateOptions().Tol(1e-4).Pool(p)
+	if err := SearchGroundState2Site(fs, h, RandMPS(h, 1), bufs, opt.MaxBondDim(4)); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := SearchGroundState(fs, h, RandMPS(h, 4), bufs, opt); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	// Every temporary is released, and reused by later sweeps.
+	stats := p.Stats()
+	if stats.Releases != stats.Gets || stats.Hits == 0 || stats.Idle != stats.Releases-stats.Hits {
+		t.Fatalf("%#v", stats)
+	}
+}
+
+func TestSearchGroundStateWorkers(t *testing.T) {
+	t.Parallel()
+	h := Ising([2]int{8, 1}, 0.5)
+	var bufs [10]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+
+	// The bond dimension is large enough for the contractions to be divided among goroutines.
+	var energies []complex64
+	for _, workers := range []int{1, 4} {
+		fs := make([]*tensor.Dense, 0, len(h))
+		for _ = range h {
+			fs = append(fs, tensor.Zeros(1))
+		}
+		ms := RandMPS(h, 16)
+		opt := NewSearchGroundStateOptions().Tol(1e-4).Workers(workers)
+		if err := SearchGroundState(fs, h, ms, bufs, opt); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		e, err := EnsembleExpectation(h, []WeightedMPS{{Weight: 1, MPS: ms}}, [2]*tensor.Dense(bufs[:2]))
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		energies = append(energies, e)
+	}
+	if abs(energies[1]-energies[0]) > 1e-4*abs(energies[0]) {
+		t.Fatalf("%v", energies)
+	}
+}
+
+func TestSearchGroundStateRand(t *testing.T) {
+	t.Parallel()
+	h := Ising([2]int{8, 1}, 0.5)
+	tests := []struct {
+		search func(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense, options ...SearchGroundStateOptions) error
+		d      int
+	}{
+		{search: SearchGroundState, d: 4},
+		{search: SearchGroundState2Site, d: 1},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			// Searches with the same seeds find the same state, including its gauge.
+			var states [2][]*tensor.Dense
+			for j := range states {
+				fs := make([]*tensor.Dense, 0, len(h))
+				for _ = range h {
+					fs = append(fs, tensor.Zeros(1))
+				}
+				var bufs [10]*tensor.Dense
+				for k := range len(bufs) {
+					bufs[k] = tensor.Zeros(1)
+				}
+				ms := RandMPSWithRand(rand.New(rand.NewPCG(1, 2)), h, test.d)
+				opt := NewSearchGroundStateOptions().Tol(1e-4).MaxBondDim(4).Rand(rand.New(rand.NewPCG(3, 4)))
+				if err := test.search(fs, h, ms, bufs, opt); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				states[j] = ms
+			}
+			for l := range states[0] {
+				if err := states[0][l].Equal(states[1][l], 0); err != nil {
+					t.Fatalf("%d %+v", l, err)
+				}
+			}
+		})
+	}
+}
+
+func TestEffectiveH(t *testing.T) {
+	t.Parallel()
+	left, right := randTensor(3, 5, 3), randTensor(6, 4, 6)
+	w := randTensor(5, 4, 2, 2)
+	hm := getH(tensor.Zeros(1), left, right, w, []*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)})
+
+	h := newEffectiveH(nil)
+	h.set(left, right, w)
+	if h.Dim() != hm.Shape()[0] {
+		t.Fatalf("%d %#v", h.Dim(), hm.Shape())
+	}
+	x := randTensor(h.Dim(), 1)
+	want := tensor.MatMul(tensor.Zeros(1), hm, x)
+	got := h.Apply(tensor.Zeros(1), x)
+	if err := got.Equal(want, 1e-4); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := h.Diagonal(tensor.Zeros(1)).Equal(matrixDiagonal(hm), 1e-4); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+// matrixDiagonal returns the diagonal of the square matrix a as a column vector.
+func matrixDiagonal(a *tensor.Dense) *tensor.Dense {
+	n := a.Shape()[0]
+	d := tensor.Zeros(n, 1)
+	for i := range n {
+		d.SetAt([]int{i, 0}, a.At(i, i))
+	}
+	return d
+}
+
+func TestEffectiveHAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	// AllocsPerRun cannot be called in parallel tests.
+	left, right := randTensor(3, 5, 3), randTensor(6, 4, 6)
+	w := randTensor(5, 4, 2, 2)
+	h := newEffectiveH(nil)
+	h.set(randTensor(2, 5, 2), right, w)
+	h.set(left, right, w)
+	x, dst := randTensor(h.Dim(), 1), tensor.Zeros(1)
+
+	// After the contractions are planned, setting tensors of the same shapes and applying them do not allocate.
+	allocs := testing.AllocsPerRun(8, func() {
+		h.set(left, right, w)
+		h.Apply(dst, x)
+	})
+	if allocs != 0 {
+		t.Fatalf("%f", allocs)
+	}
+	hm := getH(tensor.Zeros(1), left, right, w, []*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)})
+	if err := dst.Equal(tensor.MatMul(tensor.Zeros(1), hm, x), 1e-4); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+// getH materializes the H matrix defined in Equation 210, Section 6.3 Iterative ground state search, Ulrich Schollwock.
+// It is the reference for effectiveH.
+func getH(h, left, right, w *tensor.Dense, bufs []*tensor.Dense) *tensor.Dense {
+	// right is of shape {rightTop, rightMid, rightBot}.
+	// wRight is of shape {mpoLeft, mpoUp, mpoDown, rightTop, rightBot}.
+	wRight := tensor.Product(bufs[0], w, right, [][2]int{{mpoRightAxis, 1}})
+
+	// left is of shape {leftTop, leftMid, leftBot}.
+	// lwr is of shape {leftTop, leftBot, mpoUp, mpoDown, rightTop, rightBot}.
+	lwr := tensor.Product(bufs[1], left, wRight, [][2]int{{1, 0}})
+
+	// h is of shape {leftTop, mpoUp, rightTop, leftBot, mpoDown, rightBot}.
+	resetCopy(h, lwr.Transpose(0, 2, 4, 1, 3, 5))
+
+	// Reshape h to square matrix.
+	ls, ws, rs := left.Shape(), w.Shape(), right.Shape()
+	if ls[0] != ls[2] || ws[mpoUpAxis] != ws[mpoDownAxis] || rs[0] != rs[2] {
+		panic(fmt.Sprintf("%#v %#v %#v", ls, ws, rs))
+	}
+	return h.Reshape(ls[0]*ws[mpoUpAxis]*rs[0], ls[2]*ws[mpoDownAxis]*rs[2])
+}
+
+func TestFixGauge(t *testing.T) {
+	t.Parallel()
+	r := rand.New(rand.NewPCG(5, 5))
+	ms := RandMPSWithRand(r, Ising([2]int{5, 1}, 1), 4)
+
+	// other is the same state in another gauge, times a phase.
+	other := make([]*tensor.Dense, 0, len(ms))
+	for _, m := range ms {
+		other = append(other, resetCopy(tensor.Zeros(1), m))
+	}
+	other[0].Mul(complex(0, 1))
+	for i := range len(other) - 1 {
+		// Insert u @ u.H between sites i and i+1, where u is the unitary Q of a random matrix.
+		d := other[i].Shape()[mpsRightAxis]
+		u := tensor.Zeros(1)
+		tensor.QR(u, randTensorWithRand(r, d, d), [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)})
+		other[i] = tensor.Product(tensor.Zeros(1), other[i], u, [][2]int{{mpsRightAxis, 0}})
+		other[i+1] = tensor.Product(tensor.Zeros(1), u.H(), other[i+1], [][2]int{{1, mpsLeftAxis}})
+	}
+
+	var bufs [10]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	want := product(tensor.Zeros(1), ms, tensor.Zeros(1))
+	if err := FixGauge(ms, bufs); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := FixGauge(other, bufs); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for i := range ms {
+		if err := other[i].Equal(ms[i], 1e-4); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+	}
+	// The state is unchanged up to a phase, and ms[1:] are right normalized.
+	got := product(tensor.Zeros(1), ms, tensor.Zeros(1))
+	overlap := tensor.MatMul(tensor.Zeros(1), got.Reshape(1, -1).Conj(), want.Reshape(-1, 1)).At(0, 0)
+	if d := abs(overlap) - want.FrobeniusNorm()*want.FrobeniusNorm(); absf(d) > 1e-4*abs(overlap) {
+		t.Fatalf("%v %f", overlap, want.FrobeniusNorm())
+	}
+	for i := 1; i < len(ms); i++ {
+		s := ms[i].Shape()
+		m := ms[i].Reshape(s[mpsLeftAxis], -1)
+		if err := tensor.MatMul(tensor.Zeros(1), m, m.H()).Equal(tensor.Zeros(1).Eye(s[mpsLeftAxis], 0), 1e-5); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+	}
+}
+
+func TestSearchGroundStateFixGauge(t *testing.T) {
+	t.Parallel()
+	h := Ising([2]int{6, 1}, 1.5)
+	var bufs [10]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+
+	// The ground states found from different random states have equal tensors once their gauges are fixed.
+	var states [][]*tensor.Dense
+	for seed := range 2 {
+		fs := make([]*tensor.Dense, 0, len(h))
+		for _ = range h {
+			fs = append(fs, tensor.Zeros(1))
+		}
+		ms := RandMPSWithRand(rand.New(rand.NewPCG(uint64(seed), 0)), h, 4)
+		opt := NewSearchGroundStateOptions().Tol(1e-6).FixGauge(true)
+		if err := SearchGroundState(fs, h, ms, bufs, opt); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		states = append(states, ms)
+	}
+	for i := range states[0] {
+		if err := states[1][i].Equal(states[0][i], 1e-2); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+	}
+}
+
 func TestNormlize(t *testing.T) {
 	t.Parallel()
 	type testcase struct {
@@ -0,0 +1,51 @@
+package mps
+
+import (
+	"math"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// perturbedSVD decomposes the two-site tensor theta of shape {dims[0]*dims[1], dims[2]*dims[3]} into u @ s @ vh,
+// after perturbing the reduced density matrix of the sites behind the sweep by the noise of sp.
+// In a right sweep, u holds the dominant eigenvectors of theta @ theta^H + alpha * sum_b P_b @ P_b^H, where P = L W0 theta is the action of the left half of the hamiltonian h,
+// and b runs over the bond between the MPO sites, while s is the identity and vh = u^H @ theta.
+// A left sweep is the mirror image with P = theta W1 R, in which vh holds the dominant eigenvectors and u = theta @ vh^H.
+// The perturbation is computed as the SVD of theta enlarged by sqrt(alpha) * P, whose Gram matrix is the perturbed density matrix,
+// and the returned discarded weight is that of the perturbed density matrix.
+// The returned tensors are views of bufs, and are only valid until bufs is modified, and theta may be bufs[3] but no other of bufs.
+// See S. R. White, Density matrix renormalization group algorithms with a single center site, Phys. Rev. B 72, 180403(R) (2005).
+func perturbedSVD(h *effectiveH2Site, theta *tensor.Dense, dims [4]int, right bool, opt SearchGroundStateOptions, sp sweepParams, bufs [10]*tensor.Dense) (*tensor.Dense, *tensor.Dense, *tensor.Dense, float32, error) {
+	dLeft, dUp0, dUp1, dRight := dims[0], dims[1], dims[2], dims[3]
+	scale := complex(float32(math.Sqrt(float64(sp.noise))), 0)
+	theta4 := theta.Reshape(dLeft, dUp0, dUp1, dRight)
+	var enlarged *tensor.Dense
+	if right {
+		wRight := h.w0.Shape()[mpoRightAxis]
+		// lt is of shape {leftTop, leftMid, mpsUp0, mpsUp1, mpsRight}.
+		lt := tensor.Product(bufs[0], h.left, theta4, [][2]int{{2, 0}})
+		// wlt is of shape {mpoRight, mpoUp, leftTop, mpsUp1, mpsRight}, and P is of shape {leftTop, mpoUp, mpoRight, mpsUp1, mpsRight}.
+		wlt := tensor.Product(bufs[1], h.w0, lt, [][2]int{{mpoLeftAxis, 1}, {mpoDownAxis, 2}})
+		p := resetCopy(bufs[2], wlt.Transpose(2, 1, 0, 3, 4)).Reshape(dLeft*dUp0, -1).Mul(scale)
+		enlarged = bufs[6].Reset(dLeft*dUp0, dUp1*dRight*(1+wRight)).Set([]int{0, 0}, theta).Set([]int{0, dUp1 * dRight}, p)
+	} else {
+		wLeft := h.w1.Shape()[mpoLeftAxis]
+		// tr is of shape {mpsLeft, mpsUp0, mpsUp1, rightTop, rightMid}.
+		tr := tensor.Product(bufs[0], theta4, h.right, [][2]int{{3, 2}})
+		// wtr is of shape {mpoLeft, mpoUp, mpsLeft, mpsUp0, rightTop}, and P is of shape {mpsLeft, mpsUp0, mpoLeft, mpoUp, rightTop}.
+		wtr := tensor.Product(bufs[1], h.w1, tr, [][2]int{{mpoRightAxis, 4}, {mpoDownAxis, 2}})
+		p := resetCopy(bufs[2], wtr.Transpose(2, 3, 0, 1, 4)).Reshape(-1, dUp1*dRight).Mul(scale)
+		enlarged = bufs[6].Reset(dLeft*dUp0*(1+wLeft), dUp1*dRight).Set([]int{0, 0}, theta).Set([]int{dLeft * dUp0, 0}, p)
+	}
+
+	u, vh, s, discarded, err := truncatedSVD(bufs[4], bufs[5], enlarged, opt.maxBondDim, opt.truncationErr, [4]*tensor.Dense{bufs[0], bufs[1], bufs[2], bufs[7]})
+	if err != nil {
+		return nil, nil, nil, 0, errors.Wrap(err, "")
+	}
+	eye := bufs[9].Eye(s.Shape()[0], 0)
+	if right {
+		return u, eye, tensor.MatMul(bufs[8], u.H(), theta), discarded, nil
+	}
+	return tensor.MatMul(bufs[8], theta, vh.H()), eye, vh, discarded, nil
+}
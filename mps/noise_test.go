@@ -0,0 +1,139 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/qising/analytic"
+	"github.com/fumin/tensor"
+)
+
+func TestPerturbedSVD(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		right bool
+		noise float32
+	}{
+		{right: true, noise: 1e-2},
+		{right: false, noise: 1e-2},
+		{right: true, noise: 1e-6},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ws := Ising([2]int{4, 1}, 0.7)
+			const dLeft, dRight = 2, 2
+			h := newEffectiveH2Site(nil)
+			h.set(rangeT(dLeft, 3, dLeft), rangeT(dRight, 3, dRight), ws[1], ws[2])
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+
+			// theta is a product state of the two halves, whose density matrices have rank one.
+			r := rand.New(rand.NewPCG(1, 2))
+			a, b := tensor.Zeros(dLeft*2, 1), tensor.Zeros(1, 2*dRight)
+			for ij := range a.All() {
+				a.SetAt(ij, complex(r.Float32(), r.Float32()))
+			}
+			for ij := range b.All() {
+				b.SetAt(ij, complex(r.Float32(), r.Float32()))
+			}
+			want := tensor.MatMul(tensor.Zeros(1), a, b)
+			theta := resetCopy(bufs[3], want)
+
+			opt := NewSearchGroundStateOptions().TruncationError(0)
+			sp := sweepParams{noise: test.noise}
+			u, s, vh, _, err := perturbedSVD(h, theta, [4]int{dLeft, 2, 2, dRight}, test.right, opt, sp, bufs)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// The noise keeps directions beyond the rank of theta, onto which the projection leaves theta unchanged.
+			if d := s.Shape()[0]; d < 2 {
+				t.Fatalf("%d", d)
+			}
+			usvh := tensor.MatMul(tensor.Zeros(1), tensor.MatMul(tensor.Zeros(1), u, s), vh)
+			if err := usvh.Equal(want, 1e-4); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			var gram *tensor.Dense
+			if test.right {
+				gram = tensor.MatMul(tensor.Zeros(1), u.H(), u)
+			} else {
+				gram = tensor.MatMul(tensor.Zeros(1), vh, vh.H())
+			}
+			if err := gram.Equal(tensor.Zeros(1).Eye(s.Shape()[0], 0), 1e-5); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestNoise(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n       int
+		h       float64
+		twoSite bool
+	}{
+		{n: 12, h: 0.2, twoSite: true},
+		{n: 12, h: 0.2},
+		{n: 10, h: 1, twoSite: true},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			h := Ising([2]int{test.n, 1}, complex(float32(test.h), 0))
+			fs := make([]*tensor.Dense, len(h))
+			for j := range fs {
+				fs[j] = tensor.Zeros(1)
+			}
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+
+			ms := RandMPS(h, 1)
+			opt := NewSearchGroundStateOptions().Noise(1e-2, 0.3).MaxBondDim(16).MaxIterations(64).Tol(1e-5)
+			search := SearchGroundState
+			if test.twoSite {
+				search = SearchGroundState2Site
+			}
+			if err := search(fs, h, ms, bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			bufs2 := [2]*tensor.Dense(bufs[:2])
+			e0 := LExpressions(fs, h, ms, bufs2) / InnerProduct(ms, ms, bufs2)
+			exact, err := analytic.ExactIsing(test.n, test.h)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if want := exact.Energy * float64(test.n); math.Abs(float64(real(e0))-want) > 1e-3 {
+				t.Fatalf("%f %f", e0, want)
+			}
+		})
+	}
+}
+
+func TestNoiseError(t *testing.T) {
+	t.Parallel()
+	h := Ising([2]int{4, 1}, 1)
+	for i, noise := range [][2]float32{{-1e-2, 0.5}, {1e-2, 1}, {1e-2, -0.5}} {
+		for _, search := range []func(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense, options ...SearchGroundStateOptions) error{SearchGroundState, SearchGroundState2Site} {
+			fs := make([]*tensor.Dense, len(h))
+			for j := range fs {
+				fs[j] = tensor.Zeros(1)
+			}
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+			if err := search(fs, h, RandMPS(h, 1), bufs, NewSearchGroundStateOptions().Noise(noise[0], noise[1])); err == nil {
+				t.Fatalf("%d %v", i, noise)
+			}
+		}
+	}
+}
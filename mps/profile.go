@@ -0,0 +1,101 @@
+package mps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fumin/qising/linalg"
+)
+
+// Profile is a breakdown of the time spent in a ground state search.
+// Like linalg.ArnoldiProfile, it is not safe for concurrent use, hence concurrent searches record their own profiles.
+type Profile struct {
+	// Sweeps are the profiles of each iteration, which consists of a right and a left sweep.
+	Sweeps []SweepProfile
+}
+
+// Total returns the sum of the profiles of all iterations.
+func (p *Profile) Total() SweepProfile {
+	var total SweepProfile
+	for _, s := range p.Sweeps {
+		total.Environment += s.Environment
+		total.Eigensolve += s.Eigensolve
+		total.Arnoldi.Add(s.Arnoldi)
+		total.Decomposition += s.Decomposition
+		total.Convergence += s.Convergence
+	}
+	return total
+}
+
+// next starts the profile of a new iteration, and returns nil if p is nil in which case profiling is disabled.
+func (p *Profile) next() *SweepProfile {
+	if p == nil {
+		return nil
+	}
+	p.Sweeps = append(p.Sweeps, SweepProfile{})
+	return &p.Sweeps[len(p.Sweeps)-1]
+}
+
+// SweepProfile is a breakdown of the time spent in an iteration of the ground state search.
+type SweepProfile struct {
+	// Environment is the time spent contracting the L and R expressions, as well as the overlaps with penalized states.
+	Environment time.Duration
+	// Eigensolve is the time spent in the local eigenvalue problems.
+	Eigensolve time.Duration
+	// Arnoldi breaks down Eigensolve, in which Apply is the time spent contracting the effective hamiltonian with sites.
+	Arnoldi linalg.ArnoldiProfile
+	// Decomposition is the time spent in the QR, LQ and SVD decompositions that normalize and truncate sites.
+	Decomposition time.Duration
+	// Convergence is the time spent evaluating the stopping criterion.
+	Convergence time.Duration
+}
+
+func (s SweepProfile) String() string {
+	return fmt.Sprintf("environment %v eigensolve %v (apply %v x%d, orthogonalize %v, restart %v) decomposition %v convergence %v",
+		s.Environment, s.Eigensolve, s.Arnoldi.Apply, s.Arnoldi.Applications, s.Arnoldi.Orthogonalize, s.Arnoldi.Restart, s.Decomposition, s.Convergence)
+}
+
+type phase int
+
+const (
+	environmentPhase phase = iota
+	eigensolvePhase
+	decompositionPhase
+	convergencePhase
+)
+
+// clock returns the current time, or the zero time if s is nil.
+func (s *SweepProfile) clock() time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// lap adds the time elapsed since t to phase ph, and returns the current time.
+func (s *SweepProfile) lap(ph phase, t time.Time) time.Time {
+	if s == nil {
+		return t
+	}
+	now := time.Now()
+	d := now.Sub(t)
+	switch ph {
+	case environmentPhase:
+		s.Environment += d
+	case eigensolvePhase:
+		s.Eigensolve += d
+	case decompositionPhase:
+		s.Decomposition += d
+	default:
+		s.Convergence += d
+	}
+	return now
+}
+
+// arnoldi returns where the Arnoldi iteration should record its profile.
+func (s *SweepProfile) arnoldi() *linalg.ArnoldiProfile {
+	if s == nil {
+		return nil
+	}
+	return &s.Arnoldi
+}
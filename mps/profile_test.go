@@ -0,0 +1,51 @@
+package mps
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestProfile(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		search func([]*tensor.Dense, []*tensor.Dense, []*tensor.Dense, [10]*tensor.Dense, ...SearchGroundStateOptions) error
+		initD  int
+	}
+	tests := []testcase{
+		{search: SearchGroundState, initD: 4},
+		{search: SearchGroundState2Site, initD: 1},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			h := Ising([2]int{8, 1}, 0.5)
+			fs := make([]*tensor.Dense, 0, len(h))
+			for _ = range h {
+				fs = append(fs, tensor.Zeros(1))
+			}
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+
+			var prof Profile
+			opt := NewSearchGroundStateOptions().MaxBondDim(4).Tol(1e-4).Profile(&prof)
+			if err := test.search(fs, h, RandMPS(h, test.initD), bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(prof.Sweeps) == 0 {
+				t.Fatalf("no sweeps")
+			}
+			total := prof.Total()
+			if total.Environment <= 0 || total.Eigensolve <= 0 || total.Decomposition <= 0 || total.Convergence <= 0 {
+				t.Fatalf("%v", total)
+			}
+			if total.Arnoldi.Applications <= 0 || total.Arnoldi.Apply > total.Eigensolve {
+				t.Fatalf("%v", total)
+			}
+		})
+	}
+}
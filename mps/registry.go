@@ -0,0 +1,219 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// ModelParam is a parameter of a registered model.
+type ModelParam struct {
+	Name string
+	// Default is the value of the parameter when it is not given.
+	Default complex64
+	// Doc describes the parameter.
+	Doc string
+}
+
+// Model is a named builder of MPO hamiltonians, which lets tools and config files select hamiltonians by name.
+type Model struct {
+	Name string
+	// Doc describes the model.
+	Doc    string
+	Params []ModelParam
+	// Build returns the MPO of a lattice of shape n, where params holds a value for every parameter in Params.
+	Build func(n [2]int, params map[string]complex64) ([]*tensor.Dense, error)
+}
+
+var (
+	modelsMu sync.RWMutex
+	models   = make(map[string]Model)
+)
+
+// RegisterModel makes the model available by its name.
+// Like database/sql.Register, it is meant to be called from init functions, and panics if the name is taken or Build is nil.
+func RegisterModel(m Model) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+	if m.Build == nil {
+		panic(fmt.Sprintf("%s", m.Name))
+	}
+	if _, ok := models[m.Name]; ok {
+		panic(fmt.Sprintf("%s", m.Name))
+	}
+	models[m.Name] = m
+}
+
+// LookupModel returns the model registered under name.
+func LookupModel(name string) (Model, bool) {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+	m, ok := models[name]
+	return m, ok
+}
+
+// Models returns the names of the registered models in sorted order.
+func Models() []string {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// BuildModel returns the MPO of the model registered under name on a lattice of shape n.
+// Parameters missing from params take their default values, and unknown parameters are an error.
+func BuildModel(name string, n [2]int, params map[string]complex64) ([]*tensor.Dense, error) {
+	m, ok := LookupModel(name)
+	if !ok {
+		return nil, errors.Errorf("unknown model %q, registered %v", name, Models())
+	}
+	full := make(map[string]complex64, len(m.Params))
+	for _, p := range m.Params {
+		full[p.Name] = p.Default
+	}
+	for k, v := range params {
+		if _, ok := full[k]; !ok {
+			return nil, errors.Errorf("unknown parameter %q of model %q", k, name)
+		}
+		full[k] = v
+	}
+	ws, err := m.Build(n, full)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("%s %v %v", name, n, full))
+	}
+	return ws, nil
+}
+
+// ParseModelParams parses parameters of the form "h=1,g=0.5i", whose values are complex numbers as in strconv.ParseComplex.
+func ParseModelParams(s string) (map[string]complex64, error) {
+	params := make(map[string]complex64)
+	if strings.TrimSpace(s) == "" {
+		return params, nil
+	}
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, errors.Errorf("%q", kv)
+		}
+		c, err := strconv.ParseComplex(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		params[strings.TrimSpace(k)] = complex64(c)
+	}
+	return params, nil
+}
+
+func init() {
+	RegisterModel(Model{
+		Name: "ising",
+		Doc:  "transverse field Ising model, see Ising",
+		Params: []ModelParam{
+			{Name: "h", Default: 1, Doc: "transverse field"},
+			{Name: "g", Default: 0, Doc: "longitudinal field"},
+		},
+		Build: func(n [2]int, p map[string]complex64) ([]*tensor.Dense, error) {
+			return Ising(n, p["h"], NewIsingOptions().LongitudinalField(p["g"])), nil
+		},
+	})
+	RegisterModel(Model{
+		Name: "xxz",
+		Doc:  "XXZ model, see XXZ",
+		Params: []ModelParam{
+			{Name: "j", Default: 1, Doc: "exchange coupling"},
+			{Name: "delta", Default: 1, Doc: "anisotropy of the Z coupling"},
+			{Name: "hz", Default: 0, Doc: "Z field"},
+		},
+		Build: func(n [2]int, p map[string]complex64) ([]*tensor.Dense, error) {
+			return XXZ(n, p["j"], p["delta"], p["hz"]), nil
+		},
+	})
+	RegisterModel(Model{
+		Name: "potts",
+		Doc:  "quantum Potts model, see Potts",
+		Params: []ModelParam{
+			{Name: "q", Default: 3, Doc: "number of states, an integer of at least 2"},
+			{Name: "j", Default: 1, Doc: "coupling"},
+			{Name: "f", Default: 1, Doc: "transverse field"},
+		},
+		Build: func(n [2]int, p map[string]complex64) ([]*tensor.Dense, error) {
+			q := real(p["q"])
+			if q < 2 || q != float32(math.Floor(float64(q))) || imag(p["q"]) != 0 {
+				return nil, errors.Errorf("%v", p["q"])
+			}
+			return Potts(n, int(q), p["j"], p["f"]), nil
+		},
+	})
+	RegisterModel(Model{
+		Name: "clock",
+		Doc:  "quantum clock model, see Clock",
+		Params: []ModelParam{
+			{Name: "q", Default: 4, Doc: "number of states, an integer of at least 2"},
+			{Name: "j", Default: 1, Doc: "coupling"},
+			{Name: "f", Default: 1, Doc: "transverse field"},
+		},
+		Build: func(n [2]int, p map[string]complex64) ([]*tensor.Dense, error) {
+			q := real(p["q"])
+			if q < 2 || q != float32(math.Floor(float64(q))) || imag(p["q"]) != 0 {
+				return nil, errors.Errorf("%v", p["q"])
+			}
+			return Clock(n, int(q), p["j"], p["f"]), nil
+		},
+	})
+	RegisterModel(Model{
+		Name: "spin1",
+		Doc:  "spin 1 Heisenberg model with single-ion anisotropy, see Spin1Heisenberg",
+		Params: []ModelParam{
+			{Name: "j", Default: 1, Doc: "exchange coupling"},
+			{Name: "d", Default: 0, Doc: "single-ion anisotropy"},
+			{Name: "hz", Default: 0, Doc: "Z field"},
+		},
+		Build: func(n [2]int, p map[string]complex64) ([]*tensor.Dense, error) {
+			return Spin1Heisenberg(n, p["j"], p["d"], p["hz"]), nil
+		},
+	})
+	RegisterModel(Model{
+		Name: "spin1-ising",
+		Doc:  "spin 1 transverse field Ising model, see Spin1Ising",
+		Params: []ModelParam{
+			{Name: "j", Default: 1, Doc: "coupling"},
+			{Name: "h", Default: 1, Doc: "transverse field"},
+		},
+		Build: func(n [2]int, p map[string]complex64) ([]*tensor.Dense, error) {
+			return Spin1Ising(n, p["j"], p["h"]), nil
+		},
+	})
+	RegisterModel(Model{
+		Name: "long-range-ising",
+		Doc:  "transverse field Ising chain with power law couplings, see LongRangeIsing",
+		Params: []ModelParam{
+			{Name: "h", Default: 1, Doc: "transverse field"},
+			{Name: "alpha", Default: 1.5, Doc: "exponent of the power law"},
+			{Name: "nexp", Default: 6, Doc: "number of exponentials of the fit, an integer of at most about half the chain"},
+		},
+		Build: func(n [2]int, p map[string]complex64) ([]*tensor.Dense, error) {
+			nExp := real(p["nexp"])
+			if nExp < 1 || nExp != float32(math.Floor(float64(nExp))) || imag(p["nexp"]) != 0 {
+				return nil, errors.Errorf("%v", p["nexp"])
+			}
+			if n[0] > 1 && n[1] > 1 {
+				return nil, errors.Errorf("%v", n)
+			}
+			ws, err := LongRangeIsing(n[0]*n[1], p["h"], float64(real(p["alpha"])), int(nExp))
+			if err != nil {
+				return nil, errors.Wrap(err, "")
+			}
+			return ws, nil
+		},
+	})
+}
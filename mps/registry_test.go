@@ -0,0 +1,174 @@
+package mps
+
+import (
+	"fmt"
+	"maps"
+	"math/cmplx"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestBuildModel(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		n      [2]int
+		params string
+		want   []*tensor.Dense
+	}{
+		{name: "ising", n: [2]int{4, 1}, want: Ising([2]int{4, 1}, 1)},
+		{name: "ising", n: [2]int{3, 2}, params: "h=0.5, g=0.2", want: Ising([2]int{3, 2}, 0.5, NewIsingOptions().LongitudinalField(0.2))},
+		{name: "xxz", n: [2]int{5, 1}, params: "delta=0.5", want: XXZ([2]int{5, 1}, 1, 0.5, 0)},
+		{name: "potts", n: [2]int{4, 1}, params: "f=2", want: Potts([2]int{4, 1}, 3, 1, 2)},
+		{name: "clock", n: [2]int{4, 1}, params: "f=0.5", want: Clock([2]int{4, 1}, 4, 1, 0.5)},
+		{name: "spin1", n: [2]int{4, 1}, params: "d=0.2", want: Spin1Heisenberg([2]int{4, 1}, 1, 0.2, 0)},
+		{name: "spin1-ising", n: [2]int{4, 1}, params: "h=0.5", want: Spin1Ising([2]int{4, 1}, 1, 0.5)},
+		{name: "long-range-ising", n: [2]int{1, 8}, params: "alpha=3, nexp=2", want: mustLongRangeIsing(8, 1, 3, 2)},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			params, err := ParseModelParams(test.params)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			ws, err := BuildModel(test.name, test.n, params)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(ws) != len(test.want) {
+				t.Fatalf("%d %d", len(ws), len(test.want))
+			}
+			for j := range ws {
+				if err := ws[j].Equal(test.want[j], 0); err != nil {
+					t.Fatalf("%d %+v", j, err)
+				}
+			}
+
+			// The ground state search works on the local dimension of the model.
+			fs := make([]*tensor.Dense, len(ws))
+			for j := range fs {
+				fs[j] = tensor.Zeros(1)
+			}
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+			ms := RandMPSWithRand(rand.New(rand.NewPCG(1, 1)), ws, 16)
+			if err := SearchGroundState(fs, ws, ms, bufs, NewSearchGroundStateOptions().Tol(1e-6)); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			bufs2 := [2]*tensor.Dense(bufs[:2])
+			e0 := LExpressions(fs, ws, ms, bufs2) / InnerProduct(ms, ms, bufs2)
+			vals := tensor.Zeros(1)
+			if err := tensor.Eig(vals, nil, denseMPO(ws), [3]*tensor.Dense(bufs[:3])); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if cmplx.Abs(complex128(e0-vals.At(0))) > 1e-3 {
+				t.Fatalf("%v %v", e0, vals.At(0))
+			}
+		})
+	}
+}
+
+func TestBuildModelError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		params map[string]complex64
+	}{
+		{name: "nonexistent"},
+		{name: "ising", params: map[string]complex64{"delta": 1}},
+		{name: "potts", params: map[string]complex64{"q": 2.5}},
+		{name: "potts", params: map[string]complex64{"q": 1}},
+		{name: "clock", params: map[string]complex64{"q": 3.5}},
+		{name: "long-range-ising", params: map[string]complex64{"nexp": 1.5}},
+		{name: "long-range-ising", params: map[string]complex64{"nexp": 2}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if _, err := BuildModel(test.name, [2]int{3, 1}, test.params); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestRegisterModel(t *testing.T) {
+	t.Parallel()
+	m := Model{
+		Name:   "test-ising",
+		Params: []ModelParam{{Name: "c", Default: 2}},
+		Build: func(n [2]int, p map[string]complex64) ([]*tensor.Dense, error) {
+			return Ising(n, p["c"]), nil
+		},
+	}
+	RegisterModel(m)
+	if !slices.Contains(Models(), m.Name) {
+		t.Fatalf("%v", Models())
+	}
+	ws, err := BuildModel(m.Name, [2]int{3, 1}, nil)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for i, w := range Ising([2]int{3, 1}, 2) {
+		if err := ws[i].Equal(w, 0); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+	}
+
+	// Registering a name twice panics.
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected panic")
+			}
+		}()
+		RegisterModel(m)
+	}()
+}
+
+func TestParseModelParams(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		s    string
+		want map[string]complex64
+		err  bool
+	}{
+		{s: "", want: map[string]complex64{}},
+		{s: "h=1", want: map[string]complex64{"h": 1}},
+		{s: " h = 0.5 ,g=0.3i", want: map[string]complex64{"h": 0.5, "g": 0.3i}},
+		{s: "h", err: true},
+		{s: "h=x", err: true},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseModelParams(test.s)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if !maps.Equal(got, test.want) {
+				t.Fatalf("%v %v", got, test.want)
+			}
+		})
+	}
+}
+
+func mustLongRangeIsing(n int, h complex64, alpha float64, nExp int) []*tensor.Dense {
+	ws, err := LongRangeIsing(n, h, alpha, nExp)
+	if err != nil {
+		panic(fmt.Sprintf("%+v", err))
+	}
+	return ws
+}
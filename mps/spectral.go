@@ -0,0 +1,289 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// SpectralOptions are options for ChebyshevSpectral and DynamicStructureFactor.
+type SpectralOptions struct {
+	numMoments    int
+	maxBondDim    int
+	truncationErr float32
+	bandwidth     float64
+}
+
+// NewSpectralOptions returns the default spectral function options.
+func NewSpectralOptions() SpectralOptions {
+	opt := SpectralOptions{}
+	opt.numMoments = 128
+	opt.maxBondDim = 64
+	opt.truncationErr = 1e-10
+	return opt
+}
+
+// NumMoments sets the number of Chebyshev moments, which determines the energy resolution of about pi times the half width of the spectrum divided by the number of moments.
+func (opt SpectralOptions) NumMoments(n int) SpectralOptions {
+	opt.numMoments = n
+	return opt
+}
+
+// MaxBondDim sets the maximum bond dimension of the Chebyshev vectors, which are compressed after each application of the MPO.
+func (opt SpectralOptions) MaxBondDim(d int) SpectralOptions {
+	opt.maxBondDim = d
+	return opt
+}
+
+// TruncationError sets the maximum discarded weight, relative to the norm square, in the compression of each bond.
+func (opt SpectralOptions) TruncationError(e float32) SpectralOptions {
+	opt.truncationErr = e
+	return opt
+}
+
+// Bandwidth sets the width of the energy window above the ground state energy that is mapped onto the domain [-1, 1] of the Chebyshev polynomials.
+// The state must have no weight on eigenstates above the window, otherwise the expansion diverges.
+// If it is zero, the window extends to a rigorous bound on the largest eigenvalue of H, which may be loose for large lattices.
+func (opt SpectralOptions) Bandwidth(w float64) SpectralOptions {
+	opt.bandwidth = w
+	return opt
+}
+
+// ChebyshevExpansion is the expansion of the spectral function S(omega) = sum_n |<n|psi>|^2 delta(omega - E_n + E0) of a state |psi> in Chebyshev polynomials,
+// where |n> are the eigenstates of H with energies E_n.
+type ChebyshevExpansion struct {
+	// Moments are mu_n = <psi|T_n(H')|psi>, where H' = (H - Center) / HalfWidth.
+	Moments []float64
+	// E0 is the ground state energy.
+	E0 float64
+	// Center and HalfWidth map the spectrum of H to within [-1, 1].
+	Center, HalfWidth float64
+}
+
+// Spectral returns the spectral function at the excitation energy omega, broadened by the Jackson kernel,
+// whose width is about pi*HalfWidth/len(Moments).
+// The kernel keeps the spectral function positive, and its integral equal to <psi|psi>.
+// See Section II.C Kernel polynomials and Gibbs oscillations, Weisse, Wellein, Alvermann, Fehske, The kernel polynomial method, Rev. Mod. Phys. 78, 275 (2006).
+func (c ChebyshevExpansion) Spectral(omega float64) float64 {
+	x := (omega + c.E0 - c.Center) / c.HalfWidth
+	if !(x > -1 && x < 1) {
+		return 0
+	}
+	theta := math.Acos(x)
+	n := len(c.Moments)
+	var s float64
+	for k, mu := range c.Moments {
+		g := jackson(k, n)
+		if k > 0 {
+			g *= 2
+		}
+		s += g * mu * math.Cos(float64(k)*theta)
+	}
+	return s / (math.Pi * c.HalfWidth * math.Sqrt(1-x*x))
+}
+
+// jackson returns the k-th coefficient of the Jackson kernel of n moments.
+func jackson(k, n int) float64 {
+	q := math.Pi / float64(n+1)
+	return (float64(n-k+1)*math.Cos(q*float64(k)) + math.Sin(q*float64(k))/math.Tan(q)) / float64(n+1)
+}
+
+// ChebyshevSpectral returns the Chebyshev expansion of the spectral function of the state ms under the MPO hamiltonian ws,
+// whose ground state energy is e0.
+// The Chebyshev vectors |t_n> = T_n(H')|psi> follow the recursion |t_n> = 2H'|t_{n-1}> - |t_{n-2}>,
+// each of which is compressed to the maximum bond dimension, and the moments are found two at a time by
+// mu_{2n} = 2<t_n|t_n> - mu_0 and mu_{2n+1} = 2<t_{n+1}|t_n> - mu_1, which halves the number of vectors.
+// ms is not modified.
+// See Holzner, Weichselbaum, McCulloch, Schollwock, von Delft, Chebyshev matrix product state approach for spectral functions, Phys. Rev. B 83, 195115 (2011).
+func ChebyshevSpectral(ws, ms []*tensor.Dense, e0 float64, bufs [10]*tensor.Dense, options ...SpectralOptions) (ChebyshevExpansion, error) {
+	opt := NewSpectralOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if len(ws) != len(ms) || opt.numMoments < 2 || opt.bandwidth < 0 {
+		return ChebyshevExpansion{}, errors.Errorf("%d %d %d %f", len(ws), len(ms), opt.numMoments, opt.bandwidth)
+	}
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+
+	// Map [e0, eMax] to [-1+eps/2, 1-eps/2], leaving a margin for the errors of e0 and the compressions.
+	const eps = 0.025
+	eMax := e0 + opt.bandwidth
+	if opt.bandwidth == 0 {
+		bound, err := mpoNormBound(ws, bufs)
+		if err != nil {
+			return ChebyshevExpansion{}, errors.Wrap(err, "")
+		}
+		eMax = bound
+	}
+	if !(eMax > e0) {
+		return ChebyshevExpansion{}, errors.Errorf("%f %f", e0, eMax)
+	}
+	c := ChebyshevExpansion{E0: e0, Center: (eMax + e0) / 2, HalfWidth: (eMax - e0) / (2 - eps)}
+	c.Moments = make([]float64, opt.numMoments)
+
+	mu0 := float64(real(InnerProduct(ms, ms, bufs2)))
+	if mu0 == 0 {
+		return c, nil
+	}
+	c.Moments[0] = mu0
+
+	a, b := complex(float32(1/c.HalfWidth), 0), complex(float32(-c.Center/c.HalfWidth), 0)
+	prev, cur := ms, ms
+	for k := 1; k <= opt.numMoments/2; k++ {
+		// next is 2H'|t_{k-1}> - |t_{k-2}>, or H'|t_0> for k = 1.
+		hm := ApplyMPO(make([]*tensor.Dense, len(ms)), ws, cur, bufs[0])
+		states, coefs := [][]*tensor.Dense{hm, cur}, []complex64{a, b}
+		if k > 1 {
+			states, coefs = append(states, prev), []complex64{2 * a, 2 * b, -1}
+		}
+		next, err := compressProjection(Superpose(states, coefs), opt.maxBondDim, opt.truncationErr, bufs)
+		if err != nil {
+			return ChebyshevExpansion{}, errors.Wrap(err, fmt.Sprintf("%d", k))
+		}
+		prev, cur = cur, next
+
+		if k == 1 {
+			c.Moments[1] = float64(real(InnerProduct(ms, cur, bufs2)))
+		} else {
+			c.Moments[2*k-1] = 2*float64(real(InnerProduct(cur, prev, bufs2))) - c.Moments[1]
+		}
+		if 2*k < opt.numMoments {
+			c.Moments[2*k] = 2*float64(real(InnerProduct(cur, cur, bufs2))) - mu0
+		}
+	}
+	return c, nil
+}
+
+// mpoNormBound returns an upper bound of the operator norm of the MPO ws, which bounds its largest eigenvalue.
+// By the triangle inequality, the bound is the MPO whose entries are replaced by the operator norms of the local operators,
+// contracted over the bonds.
+func mpoNormBound(ws []*tensor.Dense, bufs [10]*tensor.Dense) (float64, error) {
+	// v is the row vector of the bounds of the contraction of the sites to the left of each bond.
+	v := []float64{1}
+	for i, w := range ws {
+		s := w.Shape()
+		next := make([]float64, s[mpoRightAxis])
+		for a := range s[mpoLeftAxis] {
+			for b := range s[mpoRightAxis] {
+				op := w.Slice([][2]int{{a, a + 1}, {b, b + 1}, {0, s[mpoUpAxis]}, {0, s[mpoDownAxis]}})
+				m := resetCopy(bufs[0], op).Reshape(s[mpoUpAxis], s[mpoDownAxis])
+				if m.FrobeniusNorm() == 0 {
+					continue
+				}
+				sv, err := linalg.SVD(bufs[1], bufs[2], m, [3]*tensor.Dense(bufs[3:6]))
+				if err != nil {
+					return 0, errors.Wrap(err, fmt.Sprintf("%d %d %d", i, a, b))
+				}
+				next[b] += v[a] * float64(real(sv.At(0, 0)))
+			}
+		}
+		v = next
+	}
+	return v[0], nil
+}
+
+// StructureFactor is the dynamic structure factor S(k, omega) of a chain, tabulated for plotting as a heatmap or as the dispersion relation.
+type StructureFactor struct {
+	// Ks are the momenta pi*k/(L+1) of the standing waves of the open chain, for k = 1, ..., L.
+	Ks []float64
+	// Omegas are the excitation energies.
+	Omegas []float64
+	// S[i][j] is the structure factor at Ks[i] and Omegas[j].
+	S [][]float64
+}
+
+// Dispersion returns the excitation energy of the maximum of S(k, omega) for each momentum, which traces the dispersion relation of the dominant excitations.
+func (sf StructureFactor) Dispersion() []float64 {
+	peaks := make([]float64, len(sf.Ks))
+	for i, row := range sf.S {
+		var best float64
+		for j, s := range row {
+			if s > best {
+				best, peaks[i] = s, sf.Omegas[j]
+			}
+		}
+	}
+	return peaks
+}
+
+// DynamicStructureFactor returns the dynamic structure factor
+// S(k, omega) = sum_n |<n|A_k - <A_k>|0>|^2 delta(omega - E_n + E0) of the chain hamiltonian ws with the ground state ground of energy e0,
+// where A_k = sqrt(2/(L+1)) sum_j sin(pi*k*(j+1)/(L+1)) op_j are the standing waves of the local operator op on the open chain of L sites.
+// The expectation <A_k> is subtracted so that S has no elastic peak at omega = 0.
+// For the transverse field Ising chain Ising, op being the Pauli Z matrix of the coupling excites single quasiparticles in the paramagnetic phase h > J,
+// whose dispersion is 2*sqrt(J^2 + h^2 - 2*J*h*cos(k)).
+// ground is not modified.
+// See Holzner, Weichselbaum, McCulloch, Schollwock, von Delft, Chebyshev matrix product state approach for spectral functions, Phys. Rev. B 83, 195115 (2011).
+func DynamicStructureFactor(ws, ground []*tensor.Dense, e0 float64, op [][]complex64, omegas []float64, bufs [10]*tensor.Dense, options ...SpectralOptions) (StructureFactor, error) {
+	opt := NewSpectralOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	numSites := len(ground)
+	if len(ws) != numSites || numSites < 2 {
+		return StructureFactor{}, errors.Errorf("%d %d", len(ws), numSites)
+	}
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	norm2 := InnerProduct(ground, ground, bufs2)
+	if real(norm2) == 0 {
+		return StructureFactor{}, errors.Errorf("zero state")
+	}
+
+	sf := StructureFactor{Omegas: omegas}
+	for k := 1; k <= numSites; k++ {
+		sf.Ks = append(sf.Ks, math.Pi*float64(k)/float64(numSites+1))
+		coefs := make([]complex64, numSites)
+		for j := range coefs {
+			coefs[j] = complex(float32(math.Sqrt(2/float64(numSites+1))*math.Sin(sf.Ks[k-1]*float64(j+1))), 0)
+		}
+		am := ApplyMPO(make([]*tensor.Dense, numSites), siteSumMPO(coefs, op), ground, bufs[0])
+		// Subtract the projection onto the ground state.
+		expect := InnerProduct(ground, am, bufs2) / norm2
+		psi := Superpose([][]*tensor.Dense{am, ground}, []complex64{1, -expect})
+
+		row := make([]float64, len(omegas))
+		if InnerProduct(psi, psi, bufs2) != 0 {
+			psi, err := compressProjection(psi, opt.maxBondDim, opt.truncationErr, bufs)
+			if err != nil {
+				return StructureFactor{}, errors.Wrap(err, fmt.Sprintf("%d", k))
+			}
+			c, err := ChebyshevSpectral(ws, psi, e0, bufs, opt)
+			if err != nil {
+				return StructureFactor{}, errors.Wrap(err, fmt.Sprintf("%d", k))
+			}
+			for j, omega := range omegas {
+				row[j] = c.Spectral(omega)
+			}
+		}
+		sf.S = append(sf.S, row)
+	}
+	return sf, nil
+}
+
+// siteSumMPO returns the MPO of sum_j coefs[j] op_j on a chain, which is built like SingleSiteSumMPO but with a coefficient on each site.
+func siteSumMPO(coefs []complex64, op [][]complex64) []*tensor.Dense {
+	n, d := len(coefs), len(op)
+	id := make([][]complex64, d)
+	for i := range id {
+		id[i] = make([]complex64, d)
+		id[i][i] = 1
+	}
+	ws := make([]*tensor.Dense, 0, n)
+	for j, c := range coefs {
+		w := tensor.Zeros(2, 2, d, d)
+		addMPOBlock(w, 0, 0, 1, id)
+		addMPOBlock(w, 1, 1, 1, id)
+		addMPOBlock(w, 1, 0, c, op)
+		switch j {
+		case 0:
+			w = w.Slice([][2]int{{1, 2}, {0, 2}, {0, d}, {0, d}})
+		case n - 1:
+			w = w.Slice([][2]int{{0, 2}, {0, 1}, {0, d}, {0, d}})
+		}
+		ws = append(ws, w)
+	}
+	return ws
+}
@@ -0,0 +1,160 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestChebyshevSpectral(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ws  []*tensor.Dense
+		opt SpectralOptions
+	}{
+		{ws: Ising([2]int{6, 1}, 1), opt: NewSpectralOptions().NumMoments(32)},
+		{ws: Ising([2]int{5, 1}, 0.5), opt: NewSpectralOptions().NumMoments(33)},
+		{ws: XXZ([2]int{5, 1}, 1, 0.5, 0.2), opt: NewSpectralOptions().NumMoments(24)},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ms := RandMPSWithRand(rand.New(rand.NewPCG(uint64(i), 0)), test.ws, 4)
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+			h := denseMPO(test.ws)
+			lambda := tensor.Zeros(1)
+			if err := tensor.Eig(lambda, nil, resetCopy(tensor.Zeros(1), h), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			e0, eMax := float64(real(lambda.At(0))), float64(real(lambda.At(lambda.Shape()[0]-1)))
+			c, err := ChebyshevSpectral(test.ws, ms, e0, bufs, test.opt)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if c.Center+c.HalfWidth < eMax {
+				t.Fatalf("%f %f %f", c.Center, c.HalfWidth, eMax)
+			}
+
+			// Compare with the moments of the dense Chebyshev recursion.
+			n := h.Shape()[0]
+			hp := resetCopy(tensor.Zeros(1), h)
+			for j := range n {
+				hp.SetAt([]int{j, j}, hp.At(j, j)-complex(float32(c.Center), 0))
+			}
+			hp.Mul(complex(float32(1/c.HalfWidth), 0))
+			t0 := product(tensor.Zeros(1), ms, tensor.Zeros(1)).Reshape(-1, 1)
+			prev, cur := t0, tensor.MatMul(tensor.Zeros(1), hp, t0)
+			mu0 := c.Moments[0]
+			for k := range c.Moments {
+				var want float64
+				switch k {
+				case 0:
+					want = float64(real(tensor.MatMul(tensor.Zeros(1), t0.H(), t0).At(0, 0)))
+				case 1:
+					want = float64(real(tensor.MatMul(tensor.Zeros(1), t0.H(), cur).At(0, 0)))
+				default:
+					next := tensor.MatMul(tensor.Zeros(1), hp, cur).Mul(2)
+					for j := range n {
+						next.SetAt([]int{j, 0}, next.At(j, 0)-prev.At(j, 0))
+					}
+					prev, cur = cur, next
+					want = float64(real(tensor.MatMul(tensor.Zeros(1), t0.H(), cur).At(0, 0)))
+				}
+				if math.Abs(c.Moments[k]-want) > 1e-3*mu0 {
+					t.Fatalf("%d %f %f", k, c.Moments[k], want)
+				}
+			}
+
+			// The Jackson kernel preserves the sum rule, whose integral over omega = Center + HalfWidth*cos(theta) - E0 removes the singularities at the edges.
+			var integral float64
+			steps := 4000
+			dtheta := math.Pi / float64(steps)
+			for j := range steps {
+				theta := (float64(j) + 0.5) * dtheta
+				s := c.Spectral(c.Center + c.HalfWidth*math.Cos(theta) - c.E0)
+				if s < -1e-3*mu0 {
+					t.Fatalf("%d %f", j, s)
+				}
+				integral += s * c.HalfWidth * math.Sin(theta) * dtheta
+			}
+			if math.Abs(integral-mu0) > 1e-2*mu0 {
+				t.Fatalf("%f %f", integral, mu0)
+			}
+		})
+	}
+}
+
+func TestMPONormBound(t *testing.T) {
+	t.Parallel()
+	var bufs [10]*tensor.Dense
+	for j := range bufs {
+		bufs[j] = tensor.Zeros(1)
+	}
+	// The bound of the Ising chain is the sum of the norms of its terms.
+	for _, n := range []int{2, 5} {
+		h := 1.5
+		bound, err := mpoNormBound(Ising([2]int{n, 1}, complex(float32(h), 0)), bufs)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if want := float64(n-1) + float64(n)*h; math.Abs(bound-want) > 1e-5 {
+			t.Fatalf("%d %f %f", n, bound, want)
+		}
+	}
+}
+
+func TestDynamicStructureFactor(t *testing.T) {
+	t.Parallel()
+	numSites := 6
+	field := 2.0
+	ws := Ising([2]int{numSites, 1}, complex(float32(field), 0))
+	var bufs [10]*tensor.Dense
+	for j := range bufs {
+		bufs[j] = tensor.Zeros(1)
+	}
+
+	// Find the exact ground state.
+	h := denseMPO(ws)
+	m := h.Shape()[0]
+	lambda, v := tensor.Zeros(1), tensor.Zeros(1)
+	if err := tensor.Eig(lambda, v, resetCopy(tensor.Zeros(1), h), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	shape := make([]int, numSites)
+	for j := range shape {
+		shape[j] = 2
+	}
+	state := resetCopy(tensor.Zeros(1), v.Slice([][2]int{{0, m}, {0, 1}})).Reshape(shape...)
+	ground := NewMPS(state, [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)})
+	e0 := float64(real(lambda.At(0)))
+
+	omegas := make([]float64, 0)
+	for w := 0.0; w < 10; w += 0.02 {
+		omegas = append(omegas, w)
+	}
+	sf, err := DynamicStructureFactor(ws, ground, e0, pauliZ, omegas, bufs, NewSpectralOptions().NumMoments(96))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(sf.Ks) != numSites || len(sf.S) != numSites {
+		t.Fatalf("%d %d", len(sf.Ks), len(sf.S))
+	}
+
+	// In the paramagnetic phase, the peaks follow the single quasiparticle dispersion within the resolution of the kernel.
+	peaks := sf.Dispersion()
+	for i, k := range sf.Ks {
+		want := 2 * math.Sqrt(1+field*field-2*field*math.Cos(k))
+		if math.Abs(peaks[i]-want) > 0.5 {
+			t.Fatalf("%d %f %f %f", i, k, peaks[i], want)
+		}
+		if i > 0 && !(peaks[i] >= peaks[i-1]) {
+			t.Fatalf("%v", peaks)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package mps
+
+import (
+	"math"
+
+	"github.com/fumin/tensor"
+)
+
+// s1 is 1/sqrt(2), the matrix element of the spin 1 ladder operators divided by 2.
+const s1 = complex64(1 / math.Sqrt2)
+
+// The spin 1 operators in the basis |+1>, |0>, |-1> of eigenstates of Sz, for use as the local operators of the MPS with physical dimension 3,
+// such as the arguments of SingleSiteSumMPO and LocalExpectation.
+var (
+	Spin1X = [][]complex64{
+		{0, s1, 0},
+		{s1, 0, s1},
+		{0, s1, 0},
+	}
+	Spin1Y = [][]complex64{
+		{0, -1i * s1, 0},
+		{1i * s1, 0, -1i * s1},
+		{0, 1i * s1, 0},
+	}
+	Spin1Z = [][]complex64{
+		{1, 0, 0},
+		{0, 0, 0},
+		{0, 0, -1},
+	}
+)
+
+// Spin1Heisenberg returns the MPO hamiltonian of the spin 1 Heisenberg model J sum_<a, b> S_a . S_b + D sum_a (Sz_a)^2 - hz sum_a Sz_a with open boundaries,
+// where n is the shape of the lattice, which is mapped to a chain as in Ising.
+// The antiferromagnetic chain J > 0 with small single-ion anisotropy D is in the Haldane phase, whose gap is 0.41048 J at D = 0.
+// Since the open chain has free spin 1/2 edge states, whose singlet and triplet are nearly degenerate, the Haldane gap of the open chain
+// is the difference between the lowest energies of total Sz = 2 and Sz = 1, in which both edge spins are polarized, such as by a small hz.
+// See F. D. M. Haldane, Nonlinear field theory of large-spin Heisenberg antiferromagnets, Phys. Lett. A 93, 464 (1983),
+// and S. R. White and D. A. Huse, Numerical renormalization-group study of low-lying eigenstates of the antiferromagnetic S=1 Heisenberg chain, Phys. Rev. B 48, 3844 (1993).
+func Spin1Heisenberg(n [2]int, j, d, hz complex64) []*tensor.Dense {
+	terms := []pairTerm{
+		{c: j, a: Spin1X, b: Spin1X},
+		{c: j, a: Spin1Y, b: Spin1Y},
+		{c: j, a: Spin1Z, b: Spin1Z},
+	}
+	field := scaleOp(d, opPow(Spin1Z, 2))
+	addOp(field, -hz, Spin1Z)
+	onsite := make([][][]complex64, n[0]*n[1])
+	for i := range onsite {
+		onsite[i] = field
+	}
+	return pairMPO(latticeBonds(n), terms, onsite)
+}
+
+// Spin1Ising returns the MPO hamiltonian of the spin 1 transverse field Ising model -J sum_<a, b> Sz_a Sz_b - h sum_a Sx_a with open boundaries,
+// where n is the shape of the lattice, which is mapped to a chain as in Ising.
+// Unlike the spin 1/2 model, it is not integrable, and its critical field differs from J.
+func Spin1Ising(n [2]int, j, h complex64) []*tensor.Dense {
+	terms := []pairTerm{{c: -j, a: Spin1Z, b: Spin1Z}}
+	onsite := make([][][]complex64, n[0]*n[1])
+	for i := range onsite {
+		onsite[i] = scaleOp(-h, Spin1X)
+	}
+	return pairMPO(latticeBonds(n), terms, onsite)
+}
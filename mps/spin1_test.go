@@ -0,0 +1,95 @@
+package mps
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestSpin1Operators(t *testing.T) {
+	t.Parallel()
+	x, y, z := tensor.T2(Spin1X), tensor.T2(Spin1Y), tensor.T2(Spin1Z)
+	commutator := func(a, b *tensor.Dense) *tensor.Dense {
+		ab := tensor.MatMul(tensor.Zeros(1), a, b)
+		return ab.Add(-1, tensor.MatMul(tensor.Zeros(1), b, a))
+	}
+	// [Sx, Sy] = i Sz, and cyclic permutations.
+	for i, c := range [][3]*tensor.Dense{{x, y, z}, {y, z, x}, {z, x, y}} {
+		want := resetCopy(tensor.Zeros(1), c[2]).Mul(1i)
+		if err := commutator(c[0], c[1]).Equal(want, 1e-6); err != nil {
+			t.Fatalf("%d %+v", i, err)
+		}
+	}
+	// S^2 = S(S+1) = 2.
+	s2 := tensor.MatMul(tensor.Zeros(1), x, x)
+	s2.Add(1, tensor.MatMul(tensor.Zeros(1), y, y))
+	s2.Add(1, tensor.MatMul(tensor.Zeros(1), z, z))
+	if err := s2.Equal(tensor.Zeros(1).Eye(3, 0).Mul(2), 1e-6); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func TestSpin1Hamiltonians(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		ws     []*tensor.Dense
+		n      [2]int
+		terms  []pairTerm
+		onsite [][]complex64
+	}{
+		{
+			ws: Spin1Heisenberg([2]int{3, 1}, 1, 0.3, 0.2), n: [2]int{3, 1},
+			terms:  []pairTerm{{c: 1, a: Spin1X, b: Spin1X}, {c: 1, a: Spin1Y, b: Spin1Y}, {c: 1, a: Spin1Z, b: Spin1Z}},
+			onsite: [][]complex64{{0.3 - 0.2, 0, 0}, {0, 0, 0}, {0, 0, 0.3 + 0.2}},
+		},
+		{
+			ws: Spin1Heisenberg([2]int{2, 2}, 0.5, 0, 0), n: [2]int{2, 2},
+			terms:  []pairTerm{{c: 0.5, a: Spin1X, b: Spin1X}, {c: 0.5, a: Spin1Y, b: Spin1Y}, {c: 0.5, a: Spin1Z, b: Spin1Z}},
+			onsite: [][]complex64{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}},
+		},
+		{
+			ws: Spin1Ising([2]int{4, 1}, 1, 0.7), n: [2]int{4, 1},
+			terms:  []pairTerm{{c: -1, a: Spin1Z, b: Spin1Z}},
+			onsite: scaleOp(-0.7, Spin1X),
+		},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			got := denseMPO(test.ws)
+			if test.n[1] == 1 {
+				if err := got.Equal(latticeDense(test.n, test.terms, test.onsite), 1e-6); err != nil {
+					t.Fatalf("%+v", err)
+				}
+				return
+			}
+			// The snake mapping orders the sites of a lattice differently from latticeDense.
+			bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+			gotVals, wantVals := tensor.Zeros(1), tensor.Zeros(1)
+			if err := tensor.Eig(gotVals, nil, got, bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := tensor.Eig(wantVals, nil, latticeDense(test.n, test.terms, test.onsite), bufs); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if err := gotVals.Equal(wantVals, 1e-4); err != nil {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestSpin1Dimer(t *testing.T) {
+	t.Parallel()
+	// The energies S(S+1)/2 - 2 of the total spins S = 0, 1, 2 of two spins 1 have multiplicities 2S+1.
+	vals := tensor.Zeros(1)
+	bufs := [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}
+	if err := tensor.Eig(vals, nil, denseMPO(Spin1Heisenberg([2]int{2, 1}, 1, 0, 0)), bufs); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	want := tensor.T1([]complex64{-2, -1, -1, -1, 1, 1, 1, 1, 1})
+	if err := vals.Equal(want, 1e-5); err != nil {
+		t.Fatalf("%+v %v", err, vals.ToSlice1())
+	}
+}
@@ -0,0 +1,69 @@
+package mps
+
+import (
+	"github.com/fumin/tensor"
+)
+
+// MagnetizationStatistics are the moments of the Z axis magnetization M = sum_i Z_i of a state, normalized per spin.
+type MagnetizationStatistics struct {
+	// M is <M>/N, M2 is <M^2>/N^2, and M4 is <M^4>/N^4, where N is the number of spins.
+	M, M2, M4 float64
+	// Susceptibility is (<M^2> - <M>^2) / N, the fluctuation of the magnetization,
+	// which is the magnetic susceptibility at zero temperature up to the factor of inverse temperature.
+	Susceptibility float64
+	// BinderCumulant is 1 - <M^4> / (3 <M^2>^2).
+	BinderCumulant float64
+}
+
+// Statistics returns the magnetization statistics of the state ms, which need not be normalized.
+// The moments are the expectation values of the MPOs of M, M^2 and M^4, where the MPO of M^2 is the product of the MPO of M with itself.
+// Unlike exactdiag.GetStatistics which averages |M|, M is averaged as is,
+// hence it vanishes in a symmetric ground state of the ferromagnetic phase, in which M2 is the square of the order parameter instead.
+// See K. Binder, Finite size scaling analysis of Ising model block distribution functions, Z. Phys. B 43, 119 (1981).
+func Statistics(ms []*tensor.Dense, bufs [2]*tensor.Dense) MagnetizationStatistics {
+	m, m2, m4 := magnetizationMoments(ms, bufs)
+	return newMagnetizationStatistics(len(ms), m, m2, m4)
+}
+
+// magnetizationMoments returns <M>, <M^2> and <M^4> of the state ms, which need not be normalized.
+func magnetizationMoments(ms []*tensor.Dense, bufs [2]*tensor.Dense) (float64, float64, float64) {
+	n := len(ms)
+	mz := MagnetizationZ([2]int{n, 1})
+	mz2 := mpoProduct(mz, mz)
+
+	fs := make([]*tensor.Dense, n)
+	for i := range fs {
+		fs[i] = tensor.Zeros(1)
+	}
+	norm := float64(real(InnerProduct(ms, ms, bufs)))
+	m := float64(real(LExpressions(fs, mz, ms, bufs))) / norm
+	m2 := float64(real(H2(mz, ms, bufs))) / norm
+	m4 := float64(real(H2(mz2, ms, bufs))) / norm
+	return m, m2, m4
+}
+
+// newMagnetizationStatistics returns the statistics of the moments m, m2 and m4 of the magnetization of n spins.
+func newMagnetizationStatistics(n int, m, m2, m4 float64) MagnetizationStatistics {
+	var stats MagnetizationStatistics
+	nf := float64(n)
+	stats.M = m / nf
+	stats.M2 = m2 / (nf * nf)
+	stats.M4 = m4 / (nf * nf * nf * nf)
+	stats.Susceptibility = (m2 - m*m) / nf
+	stats.BinderCumulant = 1 - m4/(3*m2*m2)
+	return stats
+}
+
+// mpoProduct returns the MPO of the operator product AB, where a and b are the MPOs of A and B.
+// The bond dimensions of the result are the products of those of a and b.
+func mpoProduct(a, b []*tensor.Dense) []*tensor.Dense {
+	ab := make([]*tensor.Dense, len(a))
+	for i := range a {
+		as, bs := a[i].Shape(), b[i].Shape()
+		// p is of shape {aLeft, aRight, aUp, bLeft, bRight, bDown}.
+		p := tensor.Product(tensor.Zeros(1), a[i], b[i], [][2]int{{mpoDownAxis, mpoUpAxis}})
+		p = p.Transpose(0, 3, 1, 4, 2, 5)
+		ab[i] = resetCopy(tensor.Zeros(1), p).Reshape(as[mpoLeftAxis]*bs[mpoLeftAxis], as[mpoRightAxis]*bs[mpoRightAxis], as[mpoUpAxis], bs[mpoDownAxis])
+	}
+	return ab
+}
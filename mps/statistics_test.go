@@ -0,0 +1,56 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestStatistics(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		ms []*tensor.Dense
+	}
+	tests := []testcase{
+		{ms: RandMPS(Ising([2]int{5, 1}, 1), 4)},
+		{ms: RandMPS(Ising([2]int{6, 1}, 1), 2)},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			bufs := [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)}
+			stats := Statistics(test.ms, bufs)
+
+			// Compute the moments from the probabilities of the basis states.
+			n := len(test.ms)
+			state := product(tensor.Zeros(1), test.ms, tensor.Zeros(1))
+			var norm, m, m2, m4 float64
+			for digits, amplitude := range state.All() {
+				var basisM float64
+				for _, spin := range digits[1 : n+1] {
+					basisM += float64(1 - 2*spin)
+				}
+				p := float64(real(amplitude)*real(amplitude) + imag(amplitude)*imag(amplitude))
+				norm += p
+				m += p * basisM
+				m2 += p * basisM * basisM
+				m4 += p * math.Pow(basisM, 4)
+			}
+			m, m2, m4 = m/norm, m2/norm, m4/norm
+
+			nf := float64(n)
+			want := MagnetizationStatistics{M: m / nf, M2: m2 / (nf * nf), M4: m4 / math.Pow(nf, 4)}
+			want.Susceptibility = (m2 - m*m) / nf
+			want.BinderCumulant = 1 - m4/(3*m2*m2)
+			got := []float64{stats.M, stats.M2, stats.M4, stats.Susceptibility, stats.BinderCumulant}
+			for j, w := range []float64{want.M, want.M2, want.M4, want.Susceptibility, want.BinderCumulant} {
+				if math.Abs(got[j]-w) > 1e-4 {
+					t.Fatalf("%d %#v %#v", j, stats, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package mps
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// TEBDOptions are options for the time evolving block decimation.
+type TEBDOptions struct {
+	imaginary        bool
+	maxBondDim       int
+	truncationErr    float32
+	truncationReport *TruncationReport
+	fixGauge         bool
+
+	// randomized indicates that the gates are truncated by the randomized SVD, whose sketches are drawn from rand.
+	randomized   bool
+	oversampling int
+	iterations   int
+	rand         *rand.Rand
+}
+
+// NewTEBDOptions returns the default TEBD options.
+func NewTEBDOptions() TEBDOptions {
+	opt := TEBDOptions{}
+	opt.maxBondDim = 64
+	opt.truncationErr = 1e-12
+	return opt
+}
+
+// Imaginary sets whether to evolve in imaginary time by exp(-H t), which projects the state onto the ground state as t grows.
+func (opt TEBDOptions) Imaginary(imaginary bool) TEBDOptions {
+	opt.imaginary = imaginary
+	return opt
+}
+
+// MaxBondDim sets the maximum bond dimension kept after the SVD truncation of each gate.
+func (opt TEBDOptions) MaxBondDim(d int) TEBDOptions {
+	opt.maxBondDim = d
+	return opt
+}
+
+// TruncationError sets the maximum discarded weight, relative to the norm square, in the SVD truncation of each gate.
+func (opt TEBDOptions) TruncationError(e float32) TEBDOptions {
+	opt.truncationErr = e
+	return opt
+}
+
+// TruncationReport sets where the discarded weights of the SVD truncation of each gate are accumulated,
+// whose sum over the evolution bounds the error of the state due to the maximum bond dimension.
+func (opt TEBDOptions) TruncationReport(r *TruncationReport) TEBDOptions {
+	opt.truncationReport = r
+	return opt
+}
+
+// FixGauge sets whether to fix the gauge and the phase of the evolved state, see FixGauge.
+func (opt TEBDOptions) FixGauge(fix bool) TEBDOptions {
+	opt.fixGauge = fix
+	return opt
+}
+
+// RandomizedSVD sets the truncation of each gate to the randomized SVD linalg.RandomizedSVD with oversampling and iterations of the power iteration,
+// which computes only the MaxBondDim largest singular values, and is thus faster than the full SVD when the maximum bond dimension is much smaller than that of the evolved state.
+func (opt TEBDOptions) RandomizedSVD(oversampling, iterations int) TEBDOptions {
+	opt.randomized = true
+	opt.oversampling = oversampling
+	opt.iterations = iterations
+	return opt
+}
+
+// Rand sets the source of the sketches of RandomizedSVD, which are otherwise drawn from the global source.
+func (opt TEBDOptions) Rand(r *rand.Rand) TEBDOptions {
+	opt.rand = r
+	return opt
+}
+
+// TEBD evolves the state ms by exp(-i H dt) for the given number of steps, or by exp(-H dt) in imaginary time.
+// H = sum_l hs[l] is a nearest-neighbor hamiltonian, in which hs[l] is of shape {up_l, up_{l+1}, down_l, down_{l+1}} and acts on sites l and l+1.
+// Each step is the second order Trotter decomposition prod_{l=0}^{L-2} exp(-i hs[l] dt/2) prod_{l=L-2}^{0} exp(-i hs[l] dt/2),
+// which is applied as a right sweep followed by a left sweep of two-site gates.
+// The orthogonality center is thus always at the gate being applied, which makes the SVD truncations optimal.
+// The state is kept normalized, and upon return ms[1:] is right normalized.
+// See Section 7.1 Conventional time evolution: pure states, Ulrich Schollwock.
+func TEBD(ms, hs []*tensor.Dense, dt float32, steps int, bufs [10]*tensor.Dense, options ...TEBDOptions) error {
+	opt := NewTEBDOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if len(hs) != len(ms)-1 {
+		return errors.Errorf("%d %d", len(hs), len(ms))
+	}
+
+	// gates[l] is exp(-i hs[l] dt/2), or exp(-hs[l] dt/2) in imaginary time.
+	c := complex(0, -dt/2)
+	if opt.imaginary {
+		c = complex(-dt/2, 0)
+	}
+	gates := make([]*tensor.Dense, 0, len(hs))
+	for _, h := range hs {
+		s := h.Shape()
+		hm := resetCopy(bufs[0], h).Reshape(s[0]*s[1], s[2]*s[3]).Mul(c)
+		gate := linalg.Expm(tensor.Zeros(1), hm, [3]*tensor.Dense(bufs[1:4]))
+		gates = append(gates, gate.Reshape(s...))
+	}
+
+	// Bring the state to the canonical form with the orthogonality center at the first site.
+	rightNormalizeAll(ms, bufs[:3])
+	ms[0].Mul(complex(1/ms[0].FrobeniusNorm(), 0))
+
+	for i := range steps {
+		for l := range len(ms) - 1 {
+			if err := applyGate(ms, l, gates[l], true, opt, bufs); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%d %d", i, l))
+			}
+		}
+		for l := len(ms) - 2; l >= 0; l-- {
+			if err := applyGate(ms, l, gates[l], false, opt, bufs); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%d %d", i, l))
+			}
+		}
+	}
+	if opt.fixGauge {
+		if err := FixGauge(ms, bufs); err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+	return nil
+}
+
+// applyGate applies a two-site gate to sites l and l+1, where the orthogonality center is.
+// If right is true, the orthogonality center is moved to site l+1, otherwise it stays at site l.
+func applyGate(ms []*tensor.Dense, l int, gate *tensor.Dense, right bool, opt TEBDOptions, bufs [10]*tensor.Dense) error {
+	dLeft, dUp0 := ms[l].Shape()[mpsLeftAxis], ms[l].Shape()[mpsUpAxis]
+	dUp1, dRight := ms[l+1].Shape()[mpsUpAxis], ms[l+1].Shape()[mpsRightAxis]
+
+	// theta is of shape {mpsLeft, mpsUp0, mpsUp1, mpsRight}.
+	theta := tensor.Product(bufs[0], ms[l], ms[l+1], [][2]int{{mpsRightAxis, mpsLeftAxis}})
+	// gt is of shape {up0, up1, mpsLeft, mpsRight}.
+	gt := tensor.Product(bufs[1], gate, theta, [][2]int{{2, 1}, {3, 2}})
+	a := resetCopy(bufs[2], gt.Transpose(2, 0, 1, 3)).Reshape(dLeft*dUp0, dUp1*dRight)
+
+	var u, vh, s *tensor.Dense
+	var discarded float32
+	var err error
+	switch {
+	case opt.randomized:
+		// bufs[0] and bufs[1] are free after a is copied.
+		rsvdBufs := [7]*tensor.Dense{bufs[0], bufs[1], bufs[5], bufs[6], bufs[7], bufs[8], bufs[9]}
+		u, vh, s, discarded, err = truncatedRandomizedSVD(bufs[3], bufs[4], a, opt.maxBondDim, opt.truncationErr, opt.oversampling, opt.iterations, opt.rand, rsvdBufs)
+	default:
+		u, vh, s, discarded, err = truncatedSVD(bufs[3], bufs[4], a, opt.maxBondDim, opt.truncationErr, [4]*tensor.Dense(bufs[5:9]))
+	}
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	opt.truncationReport.add(l, discarded)
+	// Renormalize, since both the truncation and imaginary time evolution change the norm.
+	s.Mul(complex(1/s.FrobeniusNorm(), 0))
+
+	if right {
+		ms[l] = resetCopy(ms[l], u).Reshape(dLeft, dUp0, -1)
+		ms[l+1] = resetCopy(ms[l+1], tensor.MatMul(bufs[0], s, vh)).Reshape(-1, dUp1, dRight)
+	} else {
+		ms[l+1] = resetCopy(ms[l+1], vh).Reshape(-1, dUp1, dRight)
+		ms[l] = resetCopy(ms[l], tensor.MatMul(bufs[0], u, s)).Reshape(dLeft, dUp0, -1)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package mps
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/tensor"
+)
+
+func TestTEBDImaginary(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		n   int
+		h   complex64
+		tol float32
+		// randomized is whether the gates are truncated by the randomized SVD.
+		randomized bool
+	}
+	tests := []testcase{
+		{n: 8, h: 0.5, tol: 1e-3},
+		{n: 8, h: 1.5, tol: 1e-3},
+		{n: 8, h: 0.5, tol: 1e-3, randomized: true},
+		{n: 8, h: 1.5, tol: 1e-3, randomized: true},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			ms := RandMPS(Ising([2]int{test.n, 1}, test.h), 2)
+			opt := NewTEBDOptions().Imaginary(true).MaxBondDim(16)
+			if test.randomized {
+				opt = opt.MaxBondDim(8).RandomizedSVD(4, 2).Rand(rand.New(rand.NewPCG(uint64(i), 0)))
+			}
+			if err := TEBD(ms, IsingBonds(test.n, test.h), 0.02, 500, bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// Compare with the dense eigenvalue solver.
+			ws := Ising([2]int{test.n, 1}, test.h)
+			fs := make([]*tensor.Dense, 0, len(ws))
+			for _ = range ws {
+				fs = append(fs, tensor.Zeros(1))
+			}
+			bufs2 := [2]*tensor.Dense(bufs[:2])
+			e0 := LExpressions(fs, ws, ms, bufs2) / InnerProduct(ms, ms, bufs2)
+			lambda := tensor.Zeros(1)
+			if err := tensor.Eig(lambda, nil, denseMPO(ws), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)}); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if diff := abs(e0 - lambda.At(0)); diff > test.tol*abs(lambda.At(0)) {
+				t.Fatalf("%f %f %f", diff, e0, lambda.At(0))
+			}
+		})
+	}
+}
+
+func TestTEBDReal(t *testing.T) {
+	t.Parallel()
+	n, h := 4, complex64(1)
+	var bufs [10]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+
+	// Start from the state with all spins up.
+	state := tensor.Zeros(2, 2, 2, 2)
+	state.SetAt([]int{0, 0, 0, 0}, 1)
+	ms := NewMPS(resetCopy(tensor.Zeros(1), state), [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)})
+	dt, steps := float32(0.01), 100
+	if err := TEBD(ms, IsingBonds(n, h), dt, steps, bufs); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// Compare with the exact evolution exp(-i H t).
+	u := linalg.Expm(tensor.Zeros(1), denseMPO(Ising([2]int{n, 1}, h)).Mul(complex(0, -dt*float32(steps))), [3]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)})
+	want := tensor.MatMul(tensor.Zeros(1), u, state.Reshape(-1, 1))
+	got := product(tensor.Zeros(1), ms, tensor.Zeros(1)).Reshape(-1, 1)
+	if overlap := abs(tensor.MatMul(tensor.Zeros(1), want.H(), got).At(0, 0)); overlap < 1-1e-4 {
+		t.Fatalf("%f %v %v", overlap, got.ToSlice2(), want.ToSlice2())
+	}
+}
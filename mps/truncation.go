@@ -0,0 +1,63 @@
+package mps
+
+import (
+	"fmt"
+)
+
+// TruncationReport accumulates the weights discarded by the SVD truncations of the bonds of an MPS, relative to the norm square of the state.
+// They tell whether a maximum bond dimension is adequate: the error of a state compressed by truncations is bounded by the sum of their discarded weights,
+// which near a critical point grows with the entanglement, while deep in a phase it stays negligible even for small bond dimensions.
+// The normalizations by QR decompositions, such as those bringing a state to a canonical form, are exact and discard nothing.
+// See Section 4.5.1 Compressing a matrix product state by SVD, and Section 7.3.1 Sources of error, Ulrich Schollwock.
+type TruncationReport struct {
+	// Discarded[l] is the sum of the discarded weights of the truncations of the bond between sites l and l+1.
+	Discarded []float64
+	// MaxDiscarded[l] is the largest discarded weight of a single truncation of the bond l.
+	MaxDiscarded []float64
+	// Truncations[l] is the number of truncations of the bond l.
+	Truncations []int
+}
+
+// NewTruncationReport returns an empty report of the bonds of a chain of numSites sites.
+func NewTruncationReport(numSites int) *TruncationReport {
+	if numSites < 1 {
+		panic(fmt.Sprintf("%d", numSites))
+	}
+	r := &TruncationReport{}
+	r.Discarded = make([]float64, numSites-1)
+	r.MaxDiscarded = make([]float64, numSites-1)
+	r.Truncations = make([]int, numSites-1)
+	return r
+}
+
+// Total returns the sum of the discarded weights of all bonds.
+func (r *TruncationReport) Total() float64 {
+	var total float64
+	for _, w := range r.Discarded {
+		total += w
+	}
+	return total
+}
+
+// Max returns the largest discarded weight of a single truncation of any bond.
+func (r *TruncationReport) Max() float64 {
+	var m float64
+	for _, w := range r.MaxDiscarded {
+		m = max(m, w)
+	}
+	return m
+}
+
+// add records a truncation of the bond between sites l and l+1 that discarded the relative weight w.
+// It is a no-op on a nil report, which is the default of options.
+func (r *TruncationReport) add(l int, w float32) {
+	if r == nil {
+		return
+	}
+	if l < 0 || l >= len(r.Discarded) {
+		panic(fmt.Sprintf("%d %d", l, len(r.Discarded)))
+	}
+	r.Discarded[l] += float64(w)
+	r.MaxDiscarded[l] = max(r.MaxDiscarded[l], float64(w))
+	r.Truncations[l]++
+}
@@ -0,0 +1,86 @@
+package mps
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestTruncationReport(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		bondDim int
+		// lo and hi bound the total discarded weight.
+		lo, hi float64
+	}
+	tests := []testcase{
+		{bondDim: 2, lo: 1e-4, hi: 1},
+		// The maximum bond dimension of 8 sites is 16, which discards only rounding errors.
+		{bondDim: 16, lo: 0, hi: 1e-6},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			n, steps := 8, 20
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+			// Quench the state with all spins up, whose entanglement grows with time.
+			state := tensor.Zeros(1 << n)
+			state.SetAt([]int{0}, 1)
+			ms := NewMPS(state.Reshape(2, 2, 2, 2, 2, 2, 2, 2), [2]*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1)})
+			report := NewTruncationReport(n)
+			opt := NewTEBDOptions().MaxBondDim(test.bondDim).TruncationReport(report)
+			if err := TEBD(ms, IsingBonds(n, 1), 0.05, steps, bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			for l, c := range report.Truncations {
+				if c != 2*steps {
+					t.Fatalf("%d %d", l, c)
+				}
+				if report.MaxDiscarded[l] > report.Discarded[l] {
+					t.Fatalf("%d %g %g", l, report.MaxDiscarded[l], report.Discarded[l])
+				}
+			}
+			if total := report.Total(); total < test.lo || total > test.hi {
+				t.Fatalf("%g %#v", total, report)
+			}
+			if report.Max() > report.Total() {
+				t.Fatalf("%g %g", report.Max(), report.Total())
+			}
+		})
+	}
+}
+
+func TestTruncationReport2Site(t *testing.T) {
+	t.Parallel()
+	n := 8
+	ws := Ising([2]int{n, 1}, 1)
+	fs := make([]*tensor.Dense, 0, len(ws))
+	for range ws {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	var bufs [10]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	ms := RandMPS(ws, 2)
+	report := NewTruncationReport(n)
+	opt := NewSearchGroundStateOptions().MaxBondDim(4).TruncationReport(report)
+	if err := SearchGroundState2Site(fs, ws, ms, bufs, opt); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// Each sweep truncates every bond twice, and the critical ground state is not a product state.
+	for l, c := range report.Truncations {
+		if c == 0 || c%2 != 0 {
+			t.Fatalf("%d %d", l, c)
+		}
+	}
+	if total := report.Total(); !(total > 0) {
+		t.Fatalf("%g", total)
+	}
+}
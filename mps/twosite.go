@@ -0,0 +1,420 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/fumin/qising/debug"
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/qising/pool"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// SearchGroundState2Site performs the MPS ground state search with two-site updates.
+// Unlike SearchGroundState, the bond dimensions of ms are allowed to grow up to the MaxBondDim option.
+// See the discussion on two-site DMRG at the end of Section 6.3 Iterative ground state search, Ulrich Schollwock.
+func SearchGroundState2Site(fs, ws, ms []*tensor.Dense, bufs [10]*tensor.Dense, options ...SearchGroundStateOptions) error {
+	opt := NewSearchGroundStateOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if len(ms) < 2 {
+		return errors.Errorf("%d", len(ms))
+	}
+	if err := opt.checkNoise(); err != nil {
+		return errors.Wrap(err, "")
+	}
+	schedule := opt.bondDimSchedule
+	for i, d := range schedule {
+		if d < 1 || (i > 0 && d <= schedule[i-1]) {
+			return errors.Errorf("%v", schedule)
+		}
+	}
+
+	start, resumed, converged, err := opt.resume(fs, ms)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if !resumed {
+		rightNormalizeAll(ms, bufs[:3])
+		RExpressions(fs, ws, ms, [2]*tensor.Dense(bufs[:2]))
+	}
+	// maxDims[l] is the largest dimension of the bond between ms[l] and ms[l+1] so far.
+	maxDims := make([]int, len(ms)-1)
+	for l := range maxDims {
+		maxDims[l] = ms[l].Shape()[mpsRightAxis]
+	}
+	// stage is the current stage of the schedule, which for a resumed search is the first whose bond dimension holds the state,
+	// and prevMeasure is the stopping criterion of the previous test in the stage.
+	var stage int
+	if len(schedule) > 0 {
+		for stage < len(schedule)-1 && schedule[stage] < slices.Max(maxDims) {
+			stage++
+		}
+		opt.maxBondDim = schedule[stage]
+	}
+	prevMeasure := float32(math.Inf(1))
+	convergence := newConvergence()
+	// A search that converged at its checkpoint only fixes the gauge, regardless of the remaining iterations.
+	convergence.ok = converged
+	eigTol := opt.eigenTol
+	for i := start; i < opt.maxIterations && !convergence.ok; i++ {
+		sp := opt.sweepParams(i, eigTol, convergence.resetGradient(opt))
+		rightGrew, err := rightSweep2Site(fs, ws, ms, maxDims, opt, sp, bufs)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		leftGrew, err := leftSweep2Site(fs, ws, ms, maxDims, opt, sp, bufs)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+
+		// The state may be far from converged if the bond dimensions are still growing.
+		// This happens especially when starting from a state with small bond dimensions,
+		// since each sweep grows bond dimensions by at most a factor of the physical dimension.
+		// Growth is relative to the largest dimensions so far, since a singular value at the truncation threshold
+		// is kept and discarded in turn by successive sweeps, which starting from the current state barely change it.
+		if !rightGrew && !leftGrew {
+			t := sp.prof.clock()
+			if err := convergence.test(fs, ws, ms, opt, eigTol, bufs); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("%d", i))
+			}
+			sp.prof.lap(convergencePhase, t)
+			eigTol = tightenEigenTol(eigTol, convergence.measure)
+			convergence.ok = convergence.ok && sp.noise == 0
+			if stage < len(schedule)-1 {
+				if convergence.measure < opt.tol || convergence.measure > prevMeasure/bondDimPlateau {
+					stage++
+					opt.maxBondDim = schedule[stage]
+					prevMeasure = float32(math.Inf(1))
+				} else {
+					prevMeasure = convergence.measure
+				}
+				convergence.ok = false
+			}
+		}
+		// The checkpoint follows the convergence test, so that a search resumed from a converged checkpoint stops right away.
+		if err := opt.checkpoint(i, convergence.ok, fs, ms); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+		if convergence.ok {
+			break
+		}
+		if err := opt.interrupted(i, fs, ms); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("%d", i))
+		}
+	}
+	if !convergence.ok {
+		return errors.Errorf("%#v", *convergence)
+	}
+	if err := opt.fixStateGauge(fs, ws, ms, bufs); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// bondDimPlateau is the factor by which the stopping criterion must decrease in a sweep at a stage of BondDimSchedule,
+// below which the stage has plateaued and the bond dimension grows.
+const bondDimPlateau = 2
+
+// rightSweep2Site performs a right sweep of two-site updates, and reports whether any bond dimension grew beyond maxDims, which it updates.
+func rightSweep2Site(fs, ws, ms []*tensor.Dense, maxDims []int, opt SearchGroundStateOptions, sp sweepParams, bufs [10]*tensor.Dense) (bool, error) {
+	h := newEffectiveH2Site(sp.pool)
+	h.workers = sp.workers
+	defer h.release(sp.pool)
+	var grew bool
+	for l := range len(ms) - 1 {
+		fLeft := ones(fs[l], 1, 1, 1)
+		if l-1 >= 0 {
+			fLeft = fs[l-1]
+		}
+		fRight := ones(fs[l+1], 1, 1, 1)
+		if l+2 <= len(ms)-1 {
+			fRight = fs[l+2]
+		}
+
+		h.set(fLeft, fRight, ws[l], ws[l+1])
+		if debug.Enabled {
+			debug.Assert(checkHermitian(h, ws[l], ws[l+1]))
+		}
+		if sp.grad != nil {
+			// theta is the two-site tensor of shape {mpsLeft, mpsUp0, mpsUp1, mpsRight}.
+			theta := tensor.Product(bufs[3], ms[l], ms[l+1], [][2]int{{mpsRightAxis, mpsLeftAxis}})
+			sp.gradient(h, theta.Reshape(-1, 1), [2]*tensor.Dense(bufs[1:3]))
+		}
+		u, s, vh, discarded, err := solve2Site(h, ms[l], ms[l+1], true, opt, sp, bufs)
+		if err != nil {
+			return false, errors.Wrap(err, fmt.Sprintf("%d", l))
+		}
+		opt.truncationReport.add(l, discarded)
+
+		if s.Shape()[0] > maxDims[l] {
+			maxDims[l] = s.Shape()[0]
+			grew = true
+		}
+
+		// ms[l] = u is left-normalized, and ms[l+1] = s @ vh.
+		t := sp.prof.clock()
+		dLeft, dUp := ms[l].Shape()[mpsLeftAxis], ms[l].Shape()[mpsUpAxis]
+		dUp1, dRight := ms[l+1].Shape()[mpsUpAxis], ms[l+1].Shape()[mpsRightAxis]
+		ms[l] = resetCopy(ms[l], u).Reshape(dLeft, dUp, -1)
+		ms[l+1] = resetCopy(ms[l+1], tensor.MatMul(bufs[0], s, vh)).Reshape(-1, dUp1, dRight)
+		fs[l+1].Reset(1)
+		t = sp.prof.lap(decompositionPhase, t)
+
+		lExpression(fs[l], fLeft, ws[l], ms[l], bufs[:2])
+		if debug.Enabled {
+			debug.Assert(checkLeftCanonical(fs, ws, ms, l))
+		}
+		sp.prof.lap(environmentPhase, t)
+	}
+	return grew, nil
+}
+
+// leftSweep2Site performs a left sweep of two-site updates, and reports whether any bond dimension grew beyond maxDims, which it updates.
+func leftSweep2Site(fs, ws, ms []*tensor.Dense, maxDims []int, opt SearchGroundStateOptions, sp sweepParams, bufs [10]*tensor.Dense) (bool, error) {
+	h := newEffectiveH2Site(sp.pool)
+	h.workers = sp.workers
+	defer h.release(sp.pool)
+	var grew bool
+	for l := len(ms) - 2; l >= 0; l-- {
+		fLeft := ones(fs[l], 1, 1, 1)
+		if l-1 >= 0 {
+			fLeft = fs[l-1]
+		}
+		fRight := ones(fs[l+1], 1, 1, 1)
+		if l+2 <= len(ms)-1 {
+			fRight = fs[l+2]
+		}
+
+		h.set(fLeft, fRight, ws[l], ws[l+1])
+		if debug.Enabled {
+			debug.Assert(checkHermitian(h, ws[l], ws[l+1]))
+		}
+		if sp.grad != nil {
+			// theta is the two-site tensor of shape {mpsLeft, mpsUp0, mpsUp1, mpsRight}.
+			theta := tensor.Product(bufs[3], ms[l], ms[l+1], [][2]int{{mpsRightAxis, mpsLeftAxis}})
+			sp.gradient(h, theta.Reshape(-1, 1), [2]*tensor.Dense(bufs[1:3]))
+		}
+		u, s, vh, discarded, err := solve2Site(h, ms[l], ms[l+1], false, opt, sp, bufs)
+		if err != nil {
+			return false, errors.Wrap(err, fmt.Sprintf("%d", l))
+		}
+		opt.truncationReport.add(l, discarded)
+
+		if s.Shape()[0] > maxDims[l] {
+			maxDims[l] = s.Shape()[0]
+			grew = true
+		}
+
+		// ms[l] = u @ s, and ms[l+1] = vh is right-normalized.
+		t := sp.prof.clock()
+		dLeft, dUp := ms[l].Shape()[mpsLeftAxis], ms[l].Shape()[mpsUpAxis]
+		dUp1, dRight := ms[l+1].Shape()[mpsUpAxis], ms[l+1].Shape()[mpsRightAxis]
+		ms[l+1] = resetCopy(ms[l+1], vh).Reshape(-1, dUp1, dRight)
+		ms[l] = resetCopy(ms[l], tensor.MatMul(bufs[0], u, s)).Reshape(dLeft, dUp, -1)
+		fs[l].Reset(1)
+		t = sp.prof.lap(decompositionPhase, t)
+
+		rExpression(fs[l+1], fRight, ws[l+1], ms[l+1], bufs[:2])
+		if debug.Enabled {
+			debug.Assert(checkRightCanonical(fs, ws, ms, l+1))
+		}
+		sp.prof.lap(environmentPhase, t)
+	}
+	return grew, nil
+}
+
+// solve2Site finds the ground state of the two-site effective hamiltonian h of sites m0 and m1, and decomposes it into u @ s @ vh,
+// in which u is left-normalized in a right sweep, and vh is right-normalized in a left sweep.
+// The returned tensors are views of bufs, and are only valid until bufs is modified.
+func solve2Site(h *effectiveH2Site, m0, m1 *tensor.Dense, right bool, opt SearchGroundStateOptions, sp sweepParams, bufs [10]*tensor.Dense) (*tensor.Dense, *tensor.Dense, *tensor.Dense, float32, error) {
+	t := sp.prof.clock()
+	eigvals, eigvecs := bufs[1], bufs[2]
+	abufs := [7]*tensor.Dense(bufs[3:])
+	// The current two-site tensor of shape {mpsLeft, mpsUp0, mpsUp1, mpsRight} is the initial vector.
+	x0 := tensor.Product(bufs[0], m0, m1, [][2]int{{mpsRightAxis, mpsLeftAxis}}).Reshape(-1, 1)
+	if err := sp.eigensolve(eigvals, eigvecs, h, x0, abufs); err != nil {
+		return nil, nil, nil, 0, errors.Wrap(err, "")
+	}
+	t = sp.prof.lap(eigensolvePhase, t)
+
+	dLeft, dUp0 := m0.Shape()[mpsLeftAxis], m0.Shape()[mpsUpAxis]
+	dUp1, dRight := m1.Shape()[mpsUpAxis], m1.Shape()[mpsRightAxis]
+	theta := resetCopy(bufs[3], eigvecs.Reshape(dLeft*dUp0, dUp1*dRight))
+	if sp.noise != 0 {
+		u, s, vh, discarded, err := perturbedSVD(h, theta, [4]int{dLeft, dUp0, dUp1, dRight}, right, opt, sp, bufs)
+		if err != nil {
+			return nil, nil, nil, 0, errors.Wrap(err, "")
+		}
+		sp.prof.lap(decompositionPhase, t)
+		return u, s, vh, discarded, nil
+	}
+	u, vh, s, discarded, err := truncatedSVD(bufs[4], bufs[5], theta, opt.maxBondDim, opt.truncationErr, [4]*tensor.Dense(bufs[6:]))
+	if err != nil {
+		return nil, nil, nil, 0, errors.Wrap(err, "")
+	}
+	sp.prof.lap(decompositionPhase, t)
+	return u, s, vh, discarded, nil
+}
+
+// truncatedSVD decomposes a = u @ s @ vh, keeping at most maxD singular values such that the discarded weight is within truncationErr.
+// u and vh are set to contiguous tensors, while the returned s is a view into bufs.
+// The returned float is the discarded weight relative to the norm square of a.
+// Matrix a is modified upon return.
+func truncatedSVD(u, vh, a *tensor.Dense, maxD int, truncationErr float32, bufs [4]*tensor.Dense) (*tensor.Dense, *tensor.Dense, *tensor.Dense, float32, error) {
+	v := bufs[0]
+	s, err := linalg.SVD(u, v, a, [3]*tensor.Dense(bufs[1:]))
+	if err != nil {
+		return nil, nil, nil, 0, errors.Wrap(err, "")
+	}
+	var norm2 float32
+	for i := range s.Shape()[0] {
+		si := real(s.At(i, i))
+		norm2 += si * si
+	}
+	u, vh, sd, discarded := truncate(u, vh, v, s, maxD, truncationErr, norm2, 0, bufs[1])
+	return u, vh, sd, discarded, nil
+}
+
+// truncatedRandomizedSVD is like truncatedSVD, but computes only the largest maxD singular values with linalg.RandomizedSVD,
+// whose discarded weight is the norm square of a less the weights of the computed singular values.
+func truncatedRandomizedSVD(u, vh, a *tensor.Dense, maxD int, truncationErr float32, oversampling, iterations int, r *rand.Rand, bufs [7]*tensor.Dense) (*tensor.Dense, *tensor.Dense, *tensor.Dense, float32, error) {
+	v := bufs[0]
+	norm := a.FrobeniusNorm()
+	norm2 := norm * norm
+	k := min(max(maxD, 1), a.Shape()[0], a.Shape()[1])
+	s, err := linalg.RandomizedSVD(u, v, a, k, oversampling, iterations, [6]*tensor.Dense(bufs[1:]), linalg.NewRandomizedSVDOptions().Rand(r))
+	if err != nil {
+		return nil, nil, nil, 0, errors.Wrap(err, "")
+	}
+	tail := norm2
+	for i := range k {
+		si := real(s.At(i, i))
+		tail -= si * si
+	}
+	u, vh, sd, discarded := truncate(u, vh, v, s, maxD, truncationErr, norm2, max(tail, 0), bufs[1])
+	return u, vh, sd, discarded, nil
+}
+
+// truncate keeps at most maxD of the singular values s of u @ s @ v.H such that the discarded weight is within truncationErr,
+// where norm2 is the norm square of the decomposed matrix, and tail the weight of its singular values that are not in s.
+// It returns u and vh = v.H truncated, the truncated s which is a view into buf, and the discarded weight relative to norm2.
+func truncate(u, vh, v, s *tensor.Dense, maxD int, truncationErr, norm2, tail float32, buf *tensor.Dense) (*tensor.Dense, *tensor.Dense, *tensor.Dense, float32) {
+	// Find the number of singular values to keep.
+	k := s.Shape()[0]
+	d := min(k, max(maxD, 1))
+	discarded := tail
+	for i := k - 1; i >= d; i-- {
+		si := real(s.At(i, i))
+		discarded += si * si
+	}
+	for d > 1 {
+		si := real(s.At(d-1, d-1))
+		if discarded+si*si > truncationErr*norm2 {
+			break
+		}
+		discarded += si * si
+		d--
+	}
+
+	m, n := u.Shape()[0], v.Shape()[0]
+	resetCopy(buf, u.Slice([][2]int{{0, m}, {0, d}}))
+	resetCopy(u, buf)
+	resetCopy(vh, v.Slice([][2]int{{0, n}, {0, d}}).H())
+	sd := resetCopy(buf, s.Slice([][2]int{{0, d}, {0, d}}))
+	if norm2 > 0 {
+		discarded /= norm2
+	}
+	return u, vh, sd, discarded
+}
+
+// effectiveH2Site is the two-site generalization of the H matrix defined in Equation 210, Section 6.3 Iterative ground state search, Ulrich Schollwock.
+// Like effectiveH, it is applied by contractions without materializing the matrix.
+type effectiveH2Site struct {
+	left, right, w0, w1 *tensor.Dense
+	plans               [4]*linalg.ContractionPlan
+	shapes              [4][]int
+	digits              [4]int
+	bufs                [4]*tensor.Dense
+	// workers, if greater than one, is the number of goroutines of each contraction.
+	workers int
+}
+
+func newEffectiveH2Site(p *pool.Pool) *effectiveH2Site {
+	h := &effectiveH2Site{}
+	for i := range len(h.bufs) {
+		h.bufs[i] = p.Get(1)
+	}
+	return h
+}
+
+func (h *effectiveH2Site) release(p *pool.Pool) {
+	p.Release(h.bufs[:]...)
+}
+
+func (h *effectiveH2Site) set(left, right, w0, w1 *tensor.Dense) {
+	ls, w0s, w1s, rs := left.Shape(), w0.Shape(), w1.Shape(), right.Shape()
+	if ls[0] != ls[2] || w0s[mpoUpAxis] != w0s[mpoDownAxis] || w1s[mpoUpAxis] != w1s[mpoDownAxis] || rs[0] != rs[2] {
+		panic(fmt.Sprintf("%#v %#v %#v %#v", ls, w0s, w1s, rs))
+	}
+	h.left, h.right, h.w0, h.w1 = left, right, w0, w1
+	if h.plans[0] != nil && slices.Equal(ls, h.shapes[0]) && slices.Equal(w0s, h.shapes[1]) && slices.Equal(w1s, h.shapes[2]) && slices.Equal(rs, h.shapes[3]) {
+		return
+	}
+	h.shapes = [4][]int{slices.Clone(ls), slices.Clone(w0s), slices.Clone(w1s), slices.Clone(rs)}
+
+	thetaShape := []int{ls[2], w0s[mpoDownAxis], w1s[mpoDownAxis], rs[2]}
+	h.plans[0] = linalg.NewContractionPlan(ls, thetaShape, [][2]int{{2, 0}})
+	h.plans[1] = linalg.NewContractionPlan(w0s, h.plans[0].Shape(), [][2]int{{mpoLeftAxis, 1}, {mpoDownAxis, 2}})
+	h.plans[2] = linalg.NewContractionPlan(w1s, h.plans[1].Shape(), [][2]int{{mpoLeftAxis, 0}, {mpoDownAxis, 3}})
+	h.plans[3] = linalg.NewContractionPlan(h.plans[2].Shape(), rs, [][2]int{{0, 1}, {4, 2}}, 2, 1, 0, 3).Reshape(h.Dim(), 1)
+	if h.workers > 1 {
+		for _, p := range h.plans {
+			p.Workers(h.workers)
+		}
+	}
+	h.bufs[0].Reset(thetaShape...)
+	for i := range 3 {
+		h.bufs[i+1].Reset(h.plans[i].Shape()...)
+	}
+}
+
+// Dim returns the size of two contracted MPS sites.
+func (h *effectiveH2Site) Dim() int {
+	return h.left.Shape()[2] * h.w0.Shape()[mpoDownAxis] * h.w1.Shape()[mpoDownAxis] * h.right.Shape()[2]
+}
+
+// Apply applies H to the two-site tensor x, which is flattened to a column vector.
+func (h *effectiveH2Site) Apply(dst, x *tensor.Dense) *tensor.Dense {
+	// theta is of shape {mpsLeft, mpsUp0, mpsUp1, mpsRight}.
+	theta := unflatten(h.bufs[0], x, h.digits[:])
+
+	// left is of shape {leftTop, leftMid, leftBot}.
+	// lt is of shape {leftTop, leftMid, mpsUp0, mpsUp1, mpsRight}.
+	lt := h.plans[0].Product(h.bufs[1], h.left, theta)
+
+	// wlt is of shape {mpoRight0, mpoUp0, leftTop, mpsUp1, mpsRight}.
+	wlt := h.plans[1].Product(h.bufs[2], h.w0, lt)
+
+	// wwlt is of shape {mpoRight1, mpoUp1, mpoUp0, leftTop, mpsRight}.
+	wwlt := h.plans[2].Product(h.bufs[3], h.w1, wlt)
+
+	// right is of shape {rightTop, rightMid, rightBot}.
+	// The result is of shape {mpoUp1, mpoUp0, leftTop, rightTop}, transposed to {leftTop, mpoUp0, mpoUp1, rightTop} and flattened.
+	return h.plans[3].Product(dst, wwlt, h.right)
+}
+
+// Diagonal returns the diagonal of H, whose element (a, sigma, tau, b) is sum_{beta, gamma, delta} L[a, beta, a] W0[beta, gamma, sigma, sigma] W1[gamma, delta, tau, tau] R[b, delta, b].
+func (h *effectiveH2Site) Diagonal(dst *tensor.Dense) *tensor.Dense {
+	// lw is of shape {leftTop, mpoRight0, mpoUp0}.
+	lw := tensor.Product(tensor.Zeros(1), envDiagonal(h.left), mpoDiagonal(h.w0), [][2]int{{1, mpoLeftAxis}})
+	// lww is of shape {leftTop, mpoUp0, mpoRight1, mpoUp1}.
+	lww := tensor.Product(tensor.Zeros(1), lw, mpoDiagonal(h.w1), [][2]int{{1, mpoLeftAxis}})
+	// The result is of shape {leftTop, mpoUp0, mpoUp1, rightTop}.
+	d := tensor.Product(tensor.Zeros(1), lww, envDiagonal(h.right), [][2]int{{2, 1}})
+	return resetCopy(dst, d).Reshape(-1, 1)
+}
@@ -0,0 +1,243 @@
+package mps
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/fumin/qising/analytic"
+	"github.com/fumin/tensor"
+)
+
+func TestSearchGroundState2Site(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		h          []*tensor.Dense
+		e0         complex64
+		mz         []*tensor.Dense
+		m          complex64
+		maxBondDim int
+		tol        float32
+	}
+	tests := []testcase{
+		{
+			h:          Ising([2]int{4, 1}, 0.031623),
+			e0:         -3.001501,
+			mz:         MagnetizationZ([2]int{4, 1}),
+			m:          0.999765,
+			maxBondDim: 4,
+			tol:        2e-6,
+		},
+		{
+			h:          Ising([2]int{16, 1}, 0.316228),
+			e0:         -15.453211,
+			mz:         MagnetizationZ([2]int{16, 1}),
+			m:          0.982974,
+			maxBondDim: 8,
+			tol:        2e-4,
+		},
+		{
+			h:          Ising([2]int{16, 1}, 1.584893),
+			e0:         -27.780334,
+			mz:         MagnetizationZ([2]int{16, 1}),
+			m:          0.369304,
+			maxBondDim: 8,
+			tol:        2e-4,
+		},
+		{
+			h:          Ising([2]int{16, 1}, 10),
+			e0:         -160.375198,
+			mz:         MagnetizationZ([2]int{16, 1}),
+			m:          0.262319,
+			maxBondDim: 8,
+			tol:        2e-4,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			fs := make([]*tensor.Dense, 0, len(test.h))
+			for _ = range test.h {
+				fs = append(fs, tensor.Zeros(1))
+			}
+			var bufs [10]*tensor.Dense
+			for i := range len(bufs) {
+				bufs[i] = tensor.Zeros(1)
+			}
+
+			// Start from a product state, and let the two-site updates grow the bond dimension.
+			mps := RandMPS(test.h, 1)
+			opt := NewSearchGroundStateOptions().MaxBondDim(test.maxBondDim)
+			if err := SearchGroundState2Site(fs, test.h, mps, bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for j, m := range mps {
+				if m.Shape()[mpsRightAxis] > test.maxBondDim {
+					t.Fatalf("%d %#v", j, m.Shape())
+				}
+			}
+			if d := mps[len(mps)/2].Shape()[mpsLeftAxis]; d < 2 {
+				t.Fatalf("bond dimension did not grow %d", d)
+			}
+
+			bufs2 := [2]*tensor.Dense(bufs[:2])
+			psiIP := InnerProduct(mps, mps, bufs2)
+
+			e0 := LExpressions(fs, test.h, mps, bufs2) / psiIP
+			if diff := abs(e0 - test.e0); diff > test.tol*max(abs(test.e0), 1) {
+				t.Fatalf("%f %f %f", diff, e0, test.e0)
+			}
+
+			m2 := H2(test.mz, mps, bufs2) / psiIP
+			m := sqrt(m2) / complex(float32(len(mps)), 0) // per spin
+			if diff := abs(m - test.m); diff > test.tol*max(abs(test.m), 1) {
+				t.Fatalf("%f %f %f", diff, m, test.m)
+			}
+		})
+	}
+}
+
+func TestBondDimSchedule(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		n        int
+		h        float64
+		schedule []int
+	}{
+		{n: 16, h: 1, schedule: []int{2, 4, 8, 16}},
+		{n: 12, h: 0.5, schedule: []int{1, 3, 6}},
+		{n: 10, h: 2, schedule: []int{8}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			h := Ising([2]int{test.n, 1}, complex(float32(test.h), 0))
+			fs := make([]*tensor.Dense, len(h))
+			for j := range fs {
+				fs[j] = tensor.Zeros(1)
+			}
+			var bufs [10]*tensor.Dense
+			for j := range bufs {
+				bufs[j] = tensor.Zeros(1)
+			}
+
+			ms := RandMPS(h, 1)
+			opt := NewSearchGroundStateOptions().BondDimSchedule(test.schedule).MaxIterations(64).Tol(1e-5)
+			if err := SearchGroundState2Site(fs, h, ms, bufs, opt); err != nil {
+				t.Fatalf("%+v", err)
+			}
+			var maxD int
+			for _, m := range ms {
+				maxD = max(maxD, m.Shape()[mpsRightAxis])
+			}
+			// The last stage is reached, though the truncation error may keep the bond dimension below its limit.
+			if last := test.schedule[len(test.schedule)-1]; maxD > last || (len(test.schedule) > 1 && maxD <= test.schedule[len(test.schedule)-2]) {
+				t.Fatalf("%d %v", maxD, test.schedule)
+			}
+			bufs2 := [2]*tensor.Dense(bufs[:2])
+			e0 := LExpressions(fs, h, ms, bufs2) / InnerProduct(ms, ms, bufs2)
+			exact, err := analytic.ExactIsing(test.n, test.h)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if want := exact.Energy * float64(test.n); math.Abs(float64(real(e0))-want) > 1e-3 {
+				t.Fatalf("%f %f", e0, want)
+			}
+		})
+	}
+}
+
+func TestBondDimScheduleError(t *testing.T) {
+	t.Parallel()
+	h := Ising([2]int{4, 1}, 1)
+	for i, schedule := range [][]int{{0, 2}, {4, 2}, {2, 2}} {
+		fs := make([]*tensor.Dense, len(h))
+		for j := range fs {
+			fs[j] = tensor.Zeros(1)
+		}
+		var bufs [10]*tensor.Dense
+		for j := range bufs {
+			bufs[j] = tensor.Zeros(1)
+		}
+		if err := SearchGroundState2Site(fs, h, RandMPS(h, 1), bufs, NewSearchGroundStateOptions().BondDimSchedule(schedule)); err == nil {
+			t.Fatalf("%d %v", i, schedule)
+		}
+	}
+}
+
+func TestTruncatedSVD(t *testing.T) {
+	t.Parallel()
+	a := tensor.T2([][]complex64{
+		{3, 0, 0, 0},
+		{0, 2, 0, 0},
+		{0, 0, 1e-4, 0},
+	})
+	var bufs [4]*tensor.Dense
+	for i := range len(bufs) {
+		bufs[i] = tensor.Zeros(1)
+	}
+	u, vh := tensor.Zeros(1), tensor.Zeros(1)
+	u, vh, s, discarded, err := truncatedSVD(u, vh, resetCopy(tensor.Zeros(1), a), 8, 1e-6, bufs)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if s.Shape()[0] != 2 {
+		t.Fatalf("%#v", s.Shape())
+	}
+	if want := float32(1e-8 / (9 + 4 + 1e-8)); math.Abs(float64(discarded-want)) > 1e-3*float64(want) {
+		t.Fatalf("%g %g", discarded, want)
+	}
+	usvh := tensor.MatMul(tensor.Zeros(1), tensor.MatMul(tensor.Zeros(1), u, s), vh)
+	if err := usvh.Equal(a, 1e-3); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+func TestEffectiveH2Site(t *testing.T) {
+	t.Parallel()
+	left, right := randTensor(3, 5, 3), randTensor(6, 4, 6)
+	w0, w1 := randTensor(5, 7, 2, 2), randTensor(7, 4, 2, 2)
+	hm := getH2Site(tensor.Zeros(1), left, right, w0, w1, []*tensor.Dense{tensor.Zeros(1), tensor.Zeros(1), tensor.Zeros(1)})
+
+	h := newEffectiveH2Site(nil)
+	h.set(left, right, w0, w1)
+	if h.Dim() != hm.Shape()[0] {
+		t.Fatalf("%d %#v", h.Dim(), hm.Shape())
+	}
+	x := randTensor(h.Dim(), 1)
+	want := tensor.MatMul(tensor.Zeros(1), hm, x)
+	got := h.Apply(tensor.Zeros(1), x)
+	if err := got.Equal(want, 1e-4); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := h.Diagonal(tensor.Zeros(1)).Equal(matrixDiagonal(hm), 1e-4); err != nil {
+		t.Fatalf("%+v", err)
+	}
+}
+
+// getH2Site materializes the two-site generalization of the H matrix defined in Equation 210, Section 6.3 Iterative ground state search, Ulrich Schollwock.
+// It is the reference for effectiveH2Site.
+func getH2Site(h, left, right, w0, w1 *tensor.Dense, bufs []*tensor.Dense) *tensor.Dense {
+	// ww is of shape {mpoLeft, mpoUp0, mpoDown0, mpoRight, mpoUp1, mpoDown1}.
+	ww := tensor.Product(bufs[0], w0, w1, [][2]int{{mpoRightAxis, mpoLeftAxis}})
+
+	// right is of shape {rightTop, rightMid, rightBot}.
+	// wwRight is of shape {mpoLeft, mpoUp0, mpoDown0, mpoUp1, mpoDown1, rightTop, rightBot}.
+	wwRight := tensor.Product(bufs[1], ww, right, [][2]int{{3, 1}})
+
+	// left is of shape {leftTop, leftMid, leftBot}.
+	// lwwr is of shape {leftTop, leftBot, mpoUp0, mpoDown0, mpoUp1, mpoDown1, rightTop, rightBot}.
+	lwwr := tensor.Product(bufs[2], left, wwRight, [][2]int{{1, 0}})
+
+	// h is of shape {leftTop, mpoUp0, mpoUp1, rightTop, leftBot, mpoDown0, mpoDown1, rightBot}.
+	resetCopy(h, lwwr.Transpose(0, 2, 4, 6, 1, 3, 5, 7))
+
+	// Reshape h to square matrix.
+	ls, w0s, w1s, rs := left.Shape(), w0.Shape(), w1.Shape(), right.Shape()
+	if ls[0] != ls[2] || w0s[mpoUpAxis] != w0s[mpoDownAxis] || w1s[mpoUpAxis] != w1s[mpoDownAxis] || rs[0] != rs[2] {
+		panic(fmt.Sprintf("%#v %#v %#v %#v", ls, w0s, w1s, rs))
+	}
+	dim := ls[0] * w0s[mpoUpAxis] * w1s[mpoUpAxis] * rs[0]
+	return h.Reshape(dim, dim)
+}
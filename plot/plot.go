@@ -0,0 +1,235 @@
+// Package plot renders line plots as SVG, which is viewed in any browser and embedded in documents without conversion.
+// Only what is needed to plot the results of sweeps is implemented: lines with markers on linear or logarithmic axes, and a legend.
+//
+// References:
+//   - Scalable Vector Graphics (SVG) 1.1 (Second Edition), https://www.w3.org/TR/SVG11/
+//   - Nice Numbers for Graph Labels, Paul S. Heckbert, Graphics Gems, 1990
+package plot
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	width  = 640
+	height = 480
+	// The plotting area is inset by the margins, and the legend is in the right margin.
+	marginLeft   = 70
+	marginRight  = 160
+	marginTop    = 40
+	marginBottom = 50
+
+	markerRadius = 3
+	tickLength   = 5
+	// numTicks is the approximate number of ticks of a linear axis.
+	numTicks = 5
+)
+
+// palette is the Tableau 10 palette, which is distinguishable by most color blind readers.
+var palette = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+// A Series is a curve of a plot.
+type Series struct {
+	Name string
+	X, Y []float64
+	// NoLine is whether only the markers of the points are drawn.
+	NoLine bool
+}
+
+// A Plot is a set of curves sharing the axes.
+// Points that are not finite, or not positive on a logarithmic axis, are skipped.
+type Plot struct {
+	Title, XLabel, YLabel string
+	LogX, LogY            bool
+	Series                []Series
+}
+
+// WriteSVG writes p as an SVG document to w.
+func (p *Plot) WriteSVG(w io.Writer) error {
+	for _, s := range p.Series {
+		if len(s.X) != len(s.Y) {
+			return errors.Errorf("%s %d %d", s.Name, len(s.X), len(s.Y))
+		}
+	}
+	xs, ys := newAxis(p.LogX), newAxis(p.LogY)
+	for _, s := range p.Series {
+		for i := range s.X {
+			if xs.valid(s.X[i]) && ys.valid(s.Y[i]) {
+				xs.extend(s.X[i])
+				ys.extend(s.Y[i])
+			}
+		}
+	}
+	xs.finish()
+	ys.finish()
+	left, right, top, bottom := float64(marginLeft), float64(width-marginRight), float64(marginTop), float64(height-marginBottom)
+	px := func(x float64) float64 { return left + xs.fraction(x)*(right-left) }
+	py := func(y float64) float64 { return bottom - ys.fraction(y)*(bottom-top) }
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	fmt.Fprintf(bw, `<text x="%g" y="%d" text-anchor="middle" font-size="14">%s</text>`+"\n", (left+right)/2, marginTop/2, html.EscapeString(p.Title))
+
+	// Axes, ticks and labels.
+	fmt.Fprintf(bw, `<rect x="%g" y="%g" width="%g" height="%g" fill="none" stroke="black"/>`+"\n", left, top, right-left, bottom-top)
+	for _, t := range xs.ticks() {
+		x := px(t)
+		fmt.Fprintf(bw, `<line x1="%.2f" y1="%g" x2="%.2f" y2="%g" stroke="black"/>`+"\n", x, bottom, x, bottom+tickLength)
+		fmt.Fprintf(bw, `<text x="%.2f" y="%g" text-anchor="middle">%s</text>`+"\n", x, bottom+tickLength+14, formatTick(t))
+	}
+	for _, t := range ys.ticks() {
+		y := py(t)
+		fmt.Fprintf(bw, `<line x1="%g" y1="%.2f" x2="%g" y2="%.2f" stroke="black"/>`+"\n", left-tickLength, y, left, y)
+		fmt.Fprintf(bw, `<text x="%g" y="%.2f" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", left-tickLength-3, y, formatTick(t))
+	}
+	fmt.Fprintf(bw, `<text x="%g" y="%d" text-anchor="middle">%s</text>`+"\n", (left+right)/2, height-10, html.EscapeString(p.XLabel))
+	fmt.Fprintf(bw, `<text x="15" y="%g" text-anchor="middle" transform="rotate(-90 15 %g)">%s</text>`+"\n", (top+bottom)/2, (top+bottom)/2, html.EscapeString(p.YLabel))
+
+	// Curves and the legend.
+	for k, s := range p.Series {
+		color := palette[k%len(palette)]
+		fmt.Fprintf(bw, `<g stroke="%s" fill="%s">`+"\n", color, color)
+		if !s.NoLine {
+			fmt.Fprintf(bw, `<polyline fill="none" points="`)
+			for i := range s.X {
+				if xs.valid(s.X[i]) && ys.valid(s.Y[i]) {
+					fmt.Fprintf(bw, "%.2f,%.2f ", px(s.X[i]), py(s.Y[i]))
+				}
+			}
+			fmt.Fprintf(bw, `"/>`+"\n")
+		}
+		for i := range s.X {
+			if xs.valid(s.X[i]) && ys.valid(s.Y[i]) {
+				fmt.Fprintf(bw, `<circle cx="%.2f" cy="%.2f" r="%d"/>`+"\n", px(s.X[i]), py(s.Y[i]), markerRadius)
+			}
+		}
+		ly := top + 10 + 18*float64(k)
+		fmt.Fprintf(bw, `<circle cx="%g" cy="%g" r="%d"/>`+"\n", right+20, ly, markerRadius)
+		fmt.Fprintf(bw, `<text x="%g" y="%g" stroke="none" fill="black" dominant-baseline="middle">%s</text>`+"\n", right+30, ly, html.EscapeString(s.Name))
+		fmt.Fprintf(bw, "</g>\n")
+	}
+	fmt.Fprintf(bw, "</svg>\n")
+
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// WriteFile writes p as an SVG document to the file fpath.
+func WriteFile(fpath string, p *Plot) error {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	err = p.WriteSVG(f)
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
+
+// axis maps data to the unit interval, linearly or logarithmically.
+type axis struct {
+	log      bool
+	min, max float64
+}
+
+func newAxis(log bool) *axis {
+	return &axis{log: log, min: math.Inf(1), max: math.Inf(-1)}
+}
+
+func (a *axis) valid(v float64) bool {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return false
+	}
+	return !a.log || v > 0
+}
+
+func (a *axis) extend(v float64) {
+	a.min, a.max = min(a.min, v), max(a.max, v)
+}
+
+// finish sets the range of an axis without data, and widens a degenerate range.
+func (a *axis) finish() {
+	switch {
+	case a.min > a.max && a.log:
+		a.min, a.max = 1, 10
+	case a.min > a.max:
+		a.min, a.max = 0, 1
+	case a.min == a.max && a.log:
+		a.min, a.max = a.min/2, a.max*2
+	case a.min == a.max:
+		a.min, a.max = a.min-0.5, a.max+0.5
+	}
+}
+
+func (a *axis) fraction(v float64) float64 {
+	if a.log {
+		return (math.Log10(v) - math.Log10(a.min)) / (math.Log10(a.max) - math.Log10(a.min))
+	}
+	return (v - a.min) / (a.max - a.min)
+}
+
+// ticks returns the positions of the ticks within the range.
+// A logarithmic axis has ticks at the powers of 10, and also at 2 and 5 times them when it spans less than two decades.
+func (a *axis) ticks() []float64 {
+	ts := make([]float64, 0)
+	if a.log {
+		lo, hi := math.Floor(math.Log10(a.min)), math.Ceil(math.Log10(a.max))
+		mantissas := []float64{1}
+		if hi-lo <= 2 {
+			mantissas = []float64{1, 2, 5}
+		}
+		for e := lo; e <= hi; e++ {
+			for _, m := range mantissas {
+				// Ticks at the ends of the range are kept despite roundoff.
+				if t := m * math.Pow(10, e); t >= a.min*(1-1e-9) && t <= a.max*(1+1e-9) {
+					ts = append(ts, t)
+				}
+			}
+		}
+		return ts
+	}
+
+	step := niceNum((a.max - a.min) / numTicks)
+	for t := math.Ceil(a.min/step) * step; t <= a.max+step*1e-9; t += step {
+		// Avoid labels such as 1e-17 for zero.
+		if math.Abs(t) < step*1e-9 {
+			t = 0
+		}
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// niceNum returns the number of the form 1, 2 or 5 times a power of 10 that is closest to x.
+func niceNum(x float64) float64 {
+	e := math.Floor(math.Log10(x))
+	f := x / math.Pow(10, e)
+	var nice float64
+	switch {
+	case f < 1.5:
+		nice = 1
+	case f < 3:
+		nice = 2
+	case f < 7:
+		nice = 5
+	default:
+		nice = 10
+	}
+	return nice * math.Pow(10, e)
+}
+
+func formatTick(t float64) string {
+	return strconv.FormatFloat(t, 'g', 4, 64)
+}
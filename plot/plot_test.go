@@ -0,0 +1,106 @@
+package plot
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteSVG(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		plot Plot
+		// polylines and circles are the numbers of the respective elements, where each series has a circle in the legend.
+		polylines int
+		circles   int
+	}{
+		{
+			plot: Plot{
+				Title: "m <h>", XLabel: "h", YLabel: "m", LogX: true,
+				Series: []Series{
+					{Name: "4x1", X: []float64{0.1, 1, 10}, Y: []float64{0.9, 0.5, 0.1}},
+					{Name: "9x1 & more", X: []float64{0, 1, 10}, Y: []float64{0.95, math.NaN(), 0.05}},
+				},
+			},
+			polylines: 2,
+			circles:   3 + 1 + 2,
+		},
+		{
+			plot: Plot{
+				Series: []Series{
+					{Name: "crossings", X: []float64{1}, Y: []float64{0.6}, NoLine: true},
+				},
+			},
+			polylines: 0,
+			circles:   1 + 1,
+		},
+		{plot: Plot{LogY: true}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			var b bytes.Buffer
+			if err := test.plot.WriteSVG(&b); err != nil {
+				t.Fatalf("%+v", err)
+			}
+
+			// The document is well formed XML.
+			counts := make(map[string]int)
+			d := xml.NewDecoder(&b)
+			for {
+				tok, err := d.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if se, ok := tok.(xml.StartElement); ok {
+					counts[se.Name.Local]++
+				}
+			}
+			if counts["svg"] != 1 || counts["polyline"] != test.polylines || counts["circle"] != test.circles {
+				t.Fatalf("%v", counts)
+			}
+		})
+	}
+}
+
+func TestWriteSVGError(t *testing.T) {
+	t.Parallel()
+	p := Plot{Series: []Series{{X: []float64{1, 2}, Y: []float64{1}}}}
+	if err := p.WriteSVG(io.Discard); err == nil {
+		t.Fatalf("expected error")
+	}
+	if err := WriteFile(filepath.Join(t.TempDir(), "nonexistent", "p.svg"), &Plot{}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestTicks(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		axis  axis
+		ticks []float64
+	}{
+		{axis: axis{min: 0, max: 1}, ticks: []float64{0, 0.2, 0.4, 0.6, 0.8, 1}},
+		{axis: axis{min: -0.3, max: 0.7}, ticks: []float64{-0.2, 0, 0.2, 0.4, 0.6}},
+		{axis: axis{log: true, min: 0.01, max: 100}, ticks: []float64{0.01, 0.1, 1, 10, 100}},
+		{axis: axis{log: true, min: 0.3, max: 4}, ticks: []float64{0.5, 1, 2}},
+		{axis: axis{log: true, min: math.Pow(10, -1+math.Log10(1)), max: 10}, ticks: []float64{0.1, 0.2, 0.5, 1, 2, 5, 10}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ticks := test.axis.ticks()
+			if !slices.EqualFunc(ticks, test.ticks, func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }) {
+				t.Fatalf("%v %v", ticks, test.ticks)
+			}
+		})
+	}
+}
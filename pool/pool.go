@@ -0,0 +1,132 @@
+// Package pool implements a pool of tensors, so that the backing slices of temporaries are reused instead of garbage collected.
+package pool
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"sync"
+
+	"github.com/fumin/tensor"
+)
+
+// Stats are the statistics of a pool, for tuning its usage.
+type Stats struct {
+	// Gets is the number of calls to Get, of which Hits were served by released tensors.
+	Gets, Hits int
+	// Releases is the number of released tensors.
+	Releases int
+	// Idle is the number of released tensors held by the pool, and IdleElements is their total number of elements.
+	Idle, IdleElements int
+}
+
+// Pool is a pool of tensors.
+// Since tensor.Dense.Reset keeps the backing slice, a tensor obtained from Get can be resized without allocation up to the size it was released with.
+// A nil *Pool is valid, in which case Get allocates a new tensor and Release is a no-op.
+// A Pool is safe for concurrent use.
+type Pool struct {
+	mu sync.Mutex
+	// classes[c] holds the released tensors whose number of elements is in [2^c, 2^(c+1)).
+	classes [bits.UintSize][]*tensor.Dense
+	idle    map[*tensor.Dense]struct{}
+	stats   Stats
+}
+
+// New returns an empty pool.
+func New() *Pool {
+	return &Pool{idle: make(map[*tensor.Dense]struct{})}
+}
+
+// Get returns a zero tensor of the given shape.
+// The tensor is taken from the released tensors that are large enough if there is one, and is newly allocated otherwise.
+func (p *Pool) Get(shape ...int) *tensor.Dense {
+	if p == nil {
+		return tensor.Zeros(shape...)
+	}
+	volume := 1
+	for _, s := range shape {
+		volume *= s
+	}
+
+	p.mu.Lock()
+	p.stats.Gets++
+	t := p.take(volume)
+	p.mu.Unlock()
+
+	if t == nil {
+		return tensor.Zeros(shape...)
+	}
+	return t.Reset(shape...)
+}
+
+// take removes and returns a released tensor with at least volume elements, or nil if there is none.
+func (p *Pool) take(volume int) *tensor.Dense {
+	// Tensors in the class of volume may or may not be large enough, whereas those in higher classes always are.
+	c := bits.Len(uint(max(volume, 1))) - 1
+	for i := len(p.classes[c]) - 1; i >= 0; i-- {
+		if numElements(p.classes[c][i]) >= volume {
+			return p.remove(c, i)
+		}
+	}
+	for c++; c < len(p.classes); c++ {
+		if n := len(p.classes[c]); n > 0 {
+			return p.remove(c, n-1)
+		}
+	}
+	return nil
+}
+
+// remove removes the i-th tensor of class c from the pool.
+func (p *Pool) remove(c, i int) *tensor.Dense {
+	t := p.classes[c][i]
+	p.classes[c] = slices.Delete(p.classes[c], i, i+1)
+	delete(p.idle, t)
+
+	p.stats.Hits++
+	p.stats.Idle--
+	p.stats.IdleElements -= numElements(t)
+	return t
+}
+
+// Release returns tensors to the pool, after which they must no longer be used.
+// Views of a tensor share its backing slice, and must not be released together with the tensor or while the tensor is in use.
+// Releasing a tensor that is already in the pool panics.
+func (p *Pool) Release(ts ...*tensor.Dense) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range ts {
+		if _, ok := p.idle[t]; ok {
+			panic(fmt.Sprintf("double release %p", t))
+		}
+		n := numElements(t)
+		c := bits.Len(uint(max(n, 1))) - 1
+		p.classes[c] = append(p.classes[c], t)
+		p.idle[t] = struct{}{}
+
+		p.stats.Releases++
+		p.stats.Idle++
+		p.stats.IdleElements += n
+	}
+}
+
+// Stats returns the statistics of the pool.
+func (p *Pool) Stats() Stats {
+	if p == nil {
+		return Stats{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// numElements returns the number of elements of t, which is a lower bound of the capacity of its backing slice.
+func numElements(t *tensor.Dense) int {
+	n := 1
+	for _, s := range t.Shape() {
+		n *= s
+	}
+	return n
+}
@@ -0,0 +1,115 @@
+package pool
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/fumin/tensor"
+)
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+	type testcase struct {
+		release [][]int
+		get     []int
+		hit     bool
+	}
+	tests := []testcase{
+		{release: nil, get: []int{2, 3}, hit: false},
+		{release: [][]int{{2, 3}}, get: []int{3, 2}, hit: true},
+		{release: [][]int{{2, 3}}, get: []int{1}, hit: true},
+		{release: [][]int{{2, 3}}, get: []int{7}, hit: false},
+		{release: [][]int{{8}}, get: []int{2, 2, 2}, hit: true},
+		{release: [][]int{{5}}, get: []int{5}, hit: true},
+		{release: [][]int{{5}}, get: []int{6}, hit: false},
+		{release: [][]int{{1}, {16, 16}}, get: []int{100}, hit: true},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			p := New()
+			released := make([]*tensor.Dense, 0, len(test.release))
+			for _, shape := range test.release {
+				a := tensor.Zeros(shape...)
+				for ijk := range a.All() {
+					a.SetAt(ijk, 1)
+				}
+				released = append(released, a)
+			}
+			p.Release(released...)
+
+			a := p.Get(test.get...)
+			if !slices.Equal(a.Shape(), test.get) {
+				t.Fatalf("%#v", a.Shape())
+			}
+			for _, v := range a.All() {
+				if v != 0 {
+					t.Fatalf("not zero %v", a.ToSlice1())
+				}
+			}
+			if hit := slices.Contains(released, a); hit != test.hit {
+				t.Fatalf("%t", hit)
+			}
+
+			stats := p.Stats()
+			want := Stats{Gets: 1, Releases: len(released), Idle: len(released)}
+			if test.hit {
+				want.Hits = 1
+				want.Idle--
+			}
+			stats.IdleElements = 0
+			if stats != want {
+				t.Fatalf("%#v %#v", stats, want)
+			}
+		})
+	}
+}
+
+func TestPoolDoubleRelease(t *testing.T) {
+	t.Parallel()
+	p := New()
+	a := p.Get(2)
+	p.Release(a)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic")
+		}
+	}()
+	p.Release(a)
+}
+
+func TestPoolNil(t *testing.T) {
+	t.Parallel()
+	var p *Pool
+	a := p.Get(2, 2)
+	if !slices.Equal(a.Shape(), []int{2, 2}) {
+		t.Fatalf("%#v", a.Shape())
+	}
+	p.Release(a)
+	if s := p.Stats(); s != (Stats{}) {
+		t.Fatalf("%#v", s)
+	}
+}
+
+func TestPoolConcurrent(t *testing.T) {
+	t.Parallel()
+	p := New()
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				a := p.Get(j%10 + 1)
+				p.Release(a)
+			}
+		}()
+	}
+	wg.Wait()
+	if s := p.Stats(); s.Gets != 800 || s.Releases != 800 || s.Idle != s.Releases-s.Hits {
+		t.Fatalf("%#v", s)
+	}
+}
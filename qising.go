@@ -0,0 +1,346 @@
+// Package qising solves the transverse field Ising model with either exact diagonalization or matrix product states,
+// and is the entry point for using this module as a library.
+// The subpackages exactdiag and mps expose the full control over each method.
+package qising
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/fumin/qising/exactdiag"
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/qising/linalg"
+	"github.com/fumin/qising/mps"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+// ModelSpec specifies the hamiltonian -sum_<a, b> J_ab Z_a Z_b - sum_a h_a X_a - g sum_a Z_a on a lattice.
+type ModelSpec struct {
+	// N is the shape of the lattice.
+	N [2]int
+	// H is the transverse field h_a of every site, unless Field is set.
+	H complex64
+	// G is the longitudinal field g.
+	G complex64
+	// Coupling, when non-nil, returns the coupling J_ab of the bond between sites a and b, which is otherwise 1.
+	Coupling func(a, b [2]int) complex64
+	// Field, when non-nil, returns the transverse field h_a of site a.
+	Field func(a [2]int) complex64
+	// Periodic is whether the boundary conditions are periodic in each direction, which MPS supports at the cost of a larger bond dimension, see mps.IsingOptions.Periodic.
+	Periodic [2]bool
+}
+
+func (m ModelSpec) coupling() func(a, b [2]int) complex64 {
+	if m.Coupling != nil {
+		return m.Coupling
+	}
+	return func(a, b [2]int) complex64 { return 1 }
+}
+
+func (m ModelSpec) field() func(a [2]int) complex64 {
+	if m.Field != nil {
+		return m.Field
+	}
+	return func(a [2]int) complex64 { return m.H }
+}
+
+// Method is a method of solving for the ground state.
+type Method int
+
+const (
+	// ExactDiag diagonalizes the sparse hamiltonian, whose dimension grows exponentially in the number of spins.
+	ExactDiag Method = iota
+	// MPS searches for the ground state among matrix product states of a bounded bond dimension with DMRG.
+	MPS
+	// Dense diagonalizes the full dense hamiltonian, which needs memory quadratic in its dimension.
+	Dense
+	// MatrixFree is ExactDiag without storing the hamiltonian, whose products with vectors are computed on the fly.
+	MatrixFree
+	// Auto chooses a method that fits in the memory budget with SelectMethod.
+	Auto
+)
+
+func (m Method) String() string {
+	switch m {
+	case ExactDiag:
+		return "exactdiag"
+	case MPS:
+		return "mps"
+	case Dense:
+		return "dense"
+	case MatrixFree:
+		return "matrixfree"
+	case Auto:
+		return "auto"
+	default:
+		return fmt.Sprintf("Method(%d)", int(m))
+	}
+}
+
+// Set parses the name of a method as returned by String, so that a method can be overridden by a command line flag with flag.Var.
+func (m *Method) Set(s string) error {
+	for _, method := range []Method{ExactDiag, MPS, Dense, MatrixFree, Auto} {
+		if s == method.String() {
+			*m = method
+			return nil
+		}
+	}
+	return errors.Errorf("unknown method %q", s)
+}
+
+// SolveOptions are options for Solve.
+type SolveOptions struct {
+	numStates  int
+	maxBondDim int
+	tol        float32
+	seed       uint64
+	memory     int64
+	interrupt  <-chan struct{}
+}
+
+// NewSolveOptions returns the default options.
+func NewSolveOptions() SolveOptions {
+	opt := SolveOptions{}
+	opt.numStates = 3
+	opt.maxBondDim = 16
+	opt.tol = 1e-6
+	return opt
+}
+
+// NumStates sets the number of the lowest eigenvalues found by the exact diagonalization methods, whereas MPS finds only the ground state.
+func (opt SolveOptions) NumStates(k int) SolveOptions {
+	opt.numStates = k
+	return opt
+}
+
+// MaxBondDim sets the maximum bond dimension of MPS.
+func (opt SolveOptions) MaxBondDim(d int) SolveOptions {
+	opt.maxBondDim = d
+	return opt
+}
+
+// Tol sets the convergence tolerance of the energy in MPS.
+func (opt SolveOptions) Tol(tol float32) SolveOptions {
+	opt.tol = tol
+	return opt
+}
+
+// Seed sets the seed of the random numbers of the solvers, which are the initial state and the local solves of MPS,
+// and the starting vector of the Arnoldi iteration of exact diagonalization, and are otherwise drawn from the global source.
+func (opt SolveOptions) Seed(seed uint64) SolveOptions {
+	opt.seed = seed
+	return opt
+}
+
+// rand returns the source of the seed of opt, or nil for the global source if the seed is zero.
+func (opt SolveOptions) rand() *rand.Rand {
+	if opt.seed == 0 {
+		return nil
+	}
+	return rand.New(rand.NewPCG(opt.seed, opt.seed))
+}
+
+// Interrupt sets a channel, such as the Done channel of a context.Context, whose closing stops the solvers of Solve,
+// which then returns an error whose cause is linalg.ErrInterrupted.
+// The dense diagonalization of Dense cannot be interrupted once started, hence the channel is only checked before it.
+func (opt SolveOptions) Interrupt(c <-chan struct{}) SolveOptions {
+	opt.interrupt = c
+	return opt
+}
+
+// interrupted returns linalg.ErrInterrupted if the Interrupt channel is closed.
+func (opt SolveOptions) interrupted() error {
+	select {
+	case <-opt.interrupt:
+		return errors.WithStack(linalg.ErrInterrupted)
+	default:
+		return nil
+	}
+}
+
+// Memory sets the memory budget in bytes of Auto, which is otherwise AvailableMemory.
+func (opt SolveOptions) Memory(bytes int64) SolveOptions {
+	opt.memory = bytes
+	return opt
+}
+
+// Observables are the properties of the ground state, which are defined the same way by all methods.
+type Observables struct {
+	// Energies are the lowest eigenvalues in ascending order of their real parts, the first of which is the ground energy.
+	Energies []complex128
+	// Magnetization is sqrt(<M^2>)/N, where M is the sum of the Z spins and N the number of spins.
+	// Unlike <|M|>, it does not depend on how the symmetric ground state is broken.
+	Magnetization float64
+	// BinderCumulant is 1 - <M^4> / (3 <M^2>^2).
+	BinderCumulant float64
+}
+
+// Solve solves for the ground state of model with method, and returns its observables.
+// Any method other than Auto overrides the automatic selection.
+func Solve(model ModelSpec, method Method, options ...SolveOptions) (Observables, error) {
+	opt := NewSolveOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if model.N[0] < 1 || model.N[1] < 1 {
+		return Observables{}, errors.Errorf("%v", model.N)
+	}
+	if method == Auto {
+		var err error
+		if method, err = SelectMethod(model, opt); err != nil {
+			return Observables{}, errors.Wrap(err, "")
+		}
+	}
+	switch method {
+	case ExactDiag, MatrixFree:
+		obs, err := solveExactDiag(model, method, opt)
+		if err != nil {
+			return Observables{}, errors.Wrap(err, "")
+		}
+		return obs, nil
+	case MPS:
+		obs, err := solveMPS(model, opt)
+		if err != nil {
+			return Observables{}, errors.Wrap(err, "")
+		}
+		return obs, nil
+	case Dense:
+		obs, err := solveDense(model, opt)
+		if err != nil {
+			return Observables{}, errors.Wrap(err, "")
+		}
+		return obs, nil
+	default:
+		return Observables{}, errors.Errorf("%v", method)
+	}
+}
+
+func solveExactDiag(model ModelSpec, method Method, opt SolveOptions) (Observables, error) {
+	isingOpt := exactdiag.NewIsingOptions().Periodic(model.Periodic).LongitudinalField(model.G)
+	var op linalg.LinearOperator
+	switch method {
+	case MatrixFree:
+		var err error
+		if op, err = exactdiag.NewIsingOperator(model.N, model.coupling(), model.field(), isingOpt); err != nil {
+			return Observables{}, errors.Wrap(err, "")
+		}
+	default:
+		h, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+		exactdiag.TransverseFieldIsingDisordered(h, buf, model.N, model.coupling(), model.field(), isingOpt)
+		op = newCSROperator(h.CSR())
+	}
+
+	k := min(opt.numStates, op.Dim())
+	eigvals, eigvecs := tensor.Zeros(1), tensor.Zeros(1)
+	var bufs [7]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+	if err := linalg.ArnoldiOperator(eigvals, eigvecs, op, k, bufs, linalg.NewArnoldiOptions().Rand(opt.rand()).Interrupt(opt.interrupt)); err != nil {
+		return Observables{}, errors.Wrap(err, "")
+	}
+
+	vvs := make([]mat.ValVec, k)
+	for j := range vvs {
+		vvs[j].Val = complex128(eigvals.At(j))
+	}
+	ground := make([]complex128, op.Dim())
+	for i := range ground {
+		ground[i] = complex128(eigvecs.At(i, 0))
+	}
+	vvs[0].Vec = ground
+	obs, err := exactObservables(model, vvs)
+	if err != nil {
+		return Observables{}, errors.Wrap(err, "")
+	}
+	return obs, nil
+}
+
+func solveDense(model ModelSpec, opt SolveOptions) (Observables, error) {
+	h, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+	isingOpt := exactdiag.NewIsingOptions().Periodic(model.Periodic).LongitudinalField(model.G)
+	exactdiag.TransverseFieldIsingDisordered(h, buf, model.N, model.coupling(), model.field(), isingOpt)
+	if err := opt.interrupted(); err != nil {
+		return Observables{}, errors.Wrap(err, "")
+	}
+	vvs := h.Eigen()
+	vvs = vvs[:min(opt.numStates, len(vvs))]
+
+	obs, err := exactObservables(model, vvs)
+	if err != nil {
+		return Observables{}, errors.Wrap(err, "")
+	}
+	return obs, nil
+}
+
+// exactObservables returns the observables of the eigenpairs vvs, of which only the first needs its eigenvector.
+func exactObservables(model ModelSpec, vvs []mat.ValVec) (Observables, error) {
+	stats, err := exactdiag.GetStatistics(model.N, vvs)
+	if err != nil {
+		return Observables{}, errors.Wrap(err, "")
+	}
+	obs := Observables{Magnetization: math.Sqrt(stats.M2), BinderCumulant: stats.BinderCumulant}
+	for _, vv := range vvs {
+		obs.Energies = append(obs.Energies, vv.Val)
+	}
+	return obs, nil
+}
+
+func solveMPS(model ModelSpec, opt SolveOptions) (Observables, error) {
+	isingOpt := mps.NewIsingOptions().Periodic(model.Periodic).LongitudinalField(model.G)
+	ws := mps.IsingDisordered(model.N, model.coupling(), model.field(), isingOpt)
+
+	fs := make([]*tensor.Dense, 0, len(ws))
+	for range ws {
+		fs = append(fs, tensor.Zeros(1))
+	}
+	var bufs [10]*tensor.Dense
+	for i := range bufs {
+		bufs[i] = tensor.Zeros(1)
+	}
+
+	r := opt.rand()
+	state := mps.RandMPSWithRand(r, ws, opt.maxBondDim)
+	searchOpt := mps.NewSearchGroundStateOptions().Tol(opt.tol).MaxBondDim(opt.maxBondDim).Rand(r).Interrupt(opt.interrupt)
+	if err := mps.SearchGroundState(fs, ws, state, bufs, searchOpt); err != nil {
+		return Observables{}, errors.Wrap(err, "")
+	}
+
+	bufs2 := [2]*tensor.Dense(bufs[:2])
+	e0 := mps.LExpressions(fs, ws, state, bufs2) / mps.InnerProduct(state, state, bufs2)
+	stats := mps.Statistics(state, bufs2)
+	obs := Observables{
+		Energies:       []complex128{complex128(e0)},
+		Magnetization:  math.Sqrt(stats.M2),
+		BinderCumulant: stats.BinderCumulant,
+	}
+	return obs, nil
+}
+
+// csrOperator is the linear operator of a sparse matrix.
+type csrOperator struct {
+	m *mat.CSR
+	// x and y are reusable buffers for the input and output of CSR.MulVec.
+	x []complex64
+	y []complex64
+}
+
+func newCSROperator(m *mat.CSR) *csrOperator {
+	return &csrOperator{m: m, x: make([]complex64, m.Cols()), y: make([]complex64, m.Rows())}
+}
+
+func (op *csrOperator) Dim() int { return op.m.Rows() }
+
+func (op *csrOperator) Apply(dst, src *tensor.Dense) *tensor.Dense {
+	for i := range op.x {
+		op.x[i] = src.At(i, 0)
+	}
+	op.m.MulVec(op.y, op.x)
+	dst.Reset(len(op.y), 1)
+	for i, v := range op.y {
+		dst.SetAt([]int{i, 0}, v)
+	}
+	return dst
+}
@@ -0,0 +1,135 @@
+package qising
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/fumin/qising/linalg"
+	"github.com/pkg/errors"
+)
+
+func TestSolve(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		model ModelSpec
+	}{
+		{model: ModelSpec{N: [2]int{6, 1}, H: 0.5}},
+		{model: ModelSpec{N: [2]int{6, 1}, H: 2}},
+		{model: ModelSpec{N: [2]int{3, 2}, H: 1, G: 0.1}},
+		{model: ModelSpec{N: [2]int{6, 1}, H: 0.8, Periodic: [2]bool{true, false}}},
+		{model: ModelSpec{N: [2]int{3, 3}, H: 3, Periodic: [2]bool{true, true}}},
+		{model: ModelSpec{
+			N:        [2]int{5, 1},
+			Coupling: func(a, b [2]int) complex64 { return complex(1+0.25*float32(a[0]), 0) },
+			Field:    func(a [2]int) complex64 { return complex(0.5+0.125*float32(a[0]), 0) },
+		}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ed, err := Solve(test.model, ExactDiag)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(ed.Energies) != 3 || real(ed.Energies[0]) > real(ed.Energies[1]) || real(ed.Energies[1]) > real(ed.Energies[2]) {
+				t.Fatalf("%v", ed.Energies)
+			}
+
+			m, err := Solve(test.model, MPS, NewSolveOptions().Seed(1))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if len(m.Energies) != 1 {
+				t.Fatalf("%v", m.Energies)
+			}
+			if cmplx.Abs(m.Energies[0]-ed.Energies[0]) > 1e-4 {
+				t.Fatalf("%v %v", m.Energies, ed.Energies)
+			}
+			if math.Abs(m.Magnetization-ed.Magnetization) > 1e-3 {
+				t.Fatalf("%f %f", m.Magnetization, ed.Magnetization)
+			}
+			if math.Abs(m.BinderCumulant-ed.BinderCumulant) > 1e-3 {
+				t.Fatalf("%f %f", m.BinderCumulant, ed.BinderCumulant)
+			}
+		})
+	}
+}
+
+func TestSolveExact(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		model ModelSpec
+	}{
+		{model: ModelSpec{N: [2]int{6, 1}, H: 0.5}},
+		{model: ModelSpec{N: [2]int{3, 3}, H: 3, Periodic: [2]bool{true, true}}},
+		{model: ModelSpec{
+			N:        [2]int{4, 1},
+			G:        0.1,
+			Coupling: func(a, b [2]int) complex64 { return complex(1+0.25*float32(a[0]), 0) },
+			Field:    func(a [2]int) complex64 { return complex(0.5+0.125*float32(a[0]), 0) },
+		}},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			ed, err := Solve(test.model, ExactDiag)
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			for _, method := range []Method{Dense, MatrixFree} {
+				obs, err := Solve(test.model, method)
+				if err != nil {
+					t.Fatalf("%+v", err)
+				}
+				if len(obs.Energies) != len(ed.Energies) {
+					t.Fatalf("%v %v %v", method, obs.Energies, ed.Energies)
+				}
+				for j := range obs.Energies {
+					if cmplx.Abs(obs.Energies[j]-ed.Energies[j]) > 1e-4 {
+						t.Fatalf("%v %v %v", method, obs.Energies, ed.Energies)
+					}
+				}
+				if math.Abs(obs.Magnetization-ed.Magnetization) > 1e-3 {
+					t.Fatalf("%v %f %f", method, obs.Magnetization, ed.Magnetization)
+				}
+			}
+		})
+	}
+}
+
+func TestSolveInterrupt(t *testing.T) {
+	t.Parallel()
+	interrupt := make(chan struct{})
+	close(interrupt)
+	// The tolerance of MPS is too tight for the search to converge before the interrupt is checked.
+	opt := NewSolveOptions().Tol(1e-12).Interrupt(interrupt)
+	for i, method := range []Method{ExactDiag, MatrixFree, MPS, Dense} {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if _, err := Solve(ModelSpec{N: [2]int{8, 1}, H: 1}, method, opt); !errors.Is(err, linalg.ErrInterrupted) {
+				t.Fatalf("%+v", err)
+			}
+		})
+	}
+}
+
+func TestSolveError(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		model  ModelSpec
+		method Method
+	}{
+		{model: ModelSpec{N: [2]int{0, 1}, H: 1}, method: ExactDiag},
+		{model: ModelSpec{N: [2]int{4, 1}, H: 1}, method: Method(-1)},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			if _, err := Solve(test.model, test.method); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
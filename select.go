@@ -0,0 +1,125 @@
+package qising
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// denseMaxSpins is the largest number of spins solved by Dense, beyond which its cubic time dominates its memory.
+	denseMaxSpins = 10
+	// exactMaxSpins is the largest number of spins whose basis states are indexed by an int.
+	exactMaxSpins = 40
+	// fallbackMemory is the memory budget when AvailableMemory cannot read it from the system.
+	fallbackMemory = 4 << 30
+	// cooEntryBytes is the memory per non-zero entry for building the sparse hamiltonian, which is held by the COO hamiltonian,
+	// the buffer of its terms, and the CSR copy.
+	cooEntryBytes = 64
+)
+
+// AvailableMemory returns the memory available to Solve in bytes, which is the Go memory limit GOMEMLIMIT if it is set,
+// and otherwise MemAvailable in /proc/meminfo.
+// When neither is available, it returns 4GiB.
+func AvailableMemory() int64 {
+	if limit := debug.SetMemoryLimit(-1); limit < math.MaxInt64 {
+		return limit
+	}
+	if m, err := memAvailable("/proc/meminfo"); err == nil {
+		return m
+	}
+	return fallbackMemory
+}
+
+// memAvailable returns the MemAvailable field in bytes of the meminfo file at fpath.
+func memAvailable(fpath string) (int64, error) {
+	b, err := os.ReadFile(fpath)
+	if err != nil {
+		return -1, errors.Wrap(err, "")
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		// The line is of the form "MemAvailable:   12345678 kB".
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) != 3 || string(fields[0]) != "MemAvailable:" || string(fields[2]) != "kB" {
+			continue
+		}
+		kb, err := strconv.ParseInt(string(fields[1]), 10, 64)
+		if err != nil {
+			return -1, errors.Wrap(err, fmt.Sprintf("%s", scanner.Bytes()))
+		}
+		return kb << 10, nil
+	}
+	return -1, errors.Errorf("no MemAvailable in %s", fpath)
+}
+
+// EstimateMemory returns the rough peak memory in bytes of solving model with method, which is math.MaxInt64 if it overflows.
+// With the dimension D = 2^N of N spins and the Krylov dimension m of ExactDiag and MatrixFree, the estimates are:
+//   - Dense: the real and complex copies of the dense matrix and its eigenvectors, 40 D^2.
+//   - ExactDiag: the sparse hamiltonian of D (N+1) non-zeros, and the complex64 Krylov vectors 8 D (m+k+7).
+//   - MatrixFree: the diagonal of the hamiltonian and the Krylov vectors 8 D (m+k+8).
+//   - MPS: the site tensors, environments and buffers, 8 chi^2 (8N + 64) for the maximum bond dimension chi.
+func EstimateMemory(model ModelSpec, method Method, options ...SolveOptions) int64 {
+	opt := NewSolveOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	numSpins := model.N[0] * model.N[1]
+	if method != MPS && numSpins > exactMaxSpins {
+		return math.MaxInt64
+	}
+	dim := math.Ldexp(1, numSpins)
+	k := float64(min(opt.numStates, 1<<min(numSpins, exactMaxSpins)))
+	krylov := float64(max(2*int(k)+1, 20))
+
+	var mem float64
+	switch method {
+	case Dense:
+		mem = 40*dim*dim + cooEntryBytes*dim*float64(numSpins+1)
+	case ExactDiag:
+		mem = cooEntryBytes*dim*float64(numSpins+1) + 8*dim*(krylov+k+7)
+	case MatrixFree:
+		mem = 8 * dim * (krylov + k + 8)
+	case MPS:
+		chi := float64(opt.maxBondDim)
+		mem = 8 * chi * chi * float64(8*numSpins+64)
+	default:
+		panic(fmt.Sprintf("%v", method))
+	}
+	if mem >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(mem)
+}
+
+// SelectMethod returns the method that Auto uses to solve model within the memory budget of options.
+// In the order of preference, they are Dense for up to 10 spins, ExactDiag, MatrixFree, and finally the approximate MPS.
+func SelectMethod(model ModelSpec, options ...SolveOptions) (Method, error) {
+	opt := NewSolveOptions()
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	budget := opt.memory
+	if budget <= 0 {
+		budget = AvailableMemory()
+	}
+
+	numSpins := model.N[0] * model.N[1]
+	methods := []Method{ExactDiag, MatrixFree}
+	if numSpins <= denseMaxSpins {
+		methods = append([]Method{Dense}, methods...)
+	}
+	methods = append(methods, MPS)
+	for _, m := range methods {
+		if EstimateMemory(model, m, opt) <= budget {
+			return m, nil
+		}
+	}
+	return -1, errors.Errorf("no method fits %v in %d bytes", model.N, budget)
+}
@@ -0,0 +1,100 @@
+package qising
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectMethod(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		model  ModelSpec
+		memory int64
+		method Method
+	}{
+		{model: ModelSpec{N: [2]int{8, 1}}, memory: 1 << 30, method: Dense},
+		{model: ModelSpec{N: [2]int{4, 4}}, memory: 1 << 30, method: ExactDiag},
+		{model: ModelSpec{N: [2]int{24, 1}}, memory: 8 << 30, method: MatrixFree},
+		{model: ModelSpec{N: [2]int{100, 1}}, memory: 1 << 30, method: MPS},
+		{model: ModelSpec{N: [2]int{100, 1}, Periodic: [2]bool{true, false}}, memory: 1 << 30, method: MPS},
+		{model: ModelSpec{N: [2]int{8, 1}}, memory: 1 << 17, method: MatrixFree},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			method, err := SelectMethod(test.model, NewSolveOptions().Memory(test.memory))
+			if err != nil {
+				t.Fatalf("%+v", err)
+			}
+			if method != test.method {
+				t.Fatalf("%v %v", method, test.method)
+			}
+		})
+	}
+}
+
+func TestSelectMethodError(t *testing.T) {
+	t.Parallel()
+	model := ModelSpec{N: [2]int{100, 1}}
+	if _, err := SelectMethod(model, NewSolveOptions().Memory(1<<10)); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestSolveAuto(t *testing.T) {
+	t.Parallel()
+	model := ModelSpec{N: [2]int{6, 1}, H: 0.5}
+	ed, err := Solve(model, ExactDiag)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	auto, err := Solve(model, Auto)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(auto.Energies) != len(ed.Energies) || real(auto.Energies[0]-ed.Energies[0]) > 1e-4 {
+		t.Fatalf("%v %v", auto.Energies, ed.Energies)
+	}
+}
+
+func TestMethodSet(t *testing.T) {
+	t.Parallel()
+	for _, method := range []Method{ExactDiag, MPS, Dense, MatrixFree, Auto} {
+		m := Auto
+		if err := m.Set(method.String()); err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if m != method {
+			t.Fatalf("%v %v", m, method)
+		}
+	}
+	m := Auto
+	if err := m.Set("lanczos"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestMemAvailable(t *testing.T) {
+	t.Parallel()
+	fpath := filepath.Join(t.TempDir(), "meminfo")
+	meminfo := "MemTotal:       16318412 kB\nMemFree:         1234567 kB\nMemAvailable:    8000000 kB\n"
+	if err := os.WriteFile(fpath, []byte(meminfo), 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	m, err := memAvailable(fpath)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if m != 8000000<<10 {
+		t.Fatalf("%d", m)
+	}
+
+	if err := os.WriteFile(fpath, []byte("MemTotal:       16318412 kB\n"), 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if _, err := memAvailable(fpath); err == nil {
+		t.Fatalf("expected error")
+	}
+}
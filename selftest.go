@@ -0,0 +1,275 @@
+package qising
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/fumin/qising/exactdiag"
+	"github.com/fumin/qising/exactdiag/mat"
+	"github.com/fumin/qising/mps"
+	"github.com/fumin/tensor"
+	"github.com/pkg/errors"
+)
+
+const (
+	fnameSpectra = "spectra.csv"
+	fnamePfeuty  = "pfeuty.csv"
+
+	// selfTestDenseMaxSpins is the largest lattice compared with the dense solver, whose time is cubic in the dimension.
+	selfTestDenseMaxSpins = 9
+)
+
+var (
+	// referenceSpectra are the lowest eigenvalues of open lattices, computed in double precision by WriteReference.
+	//go:embed reference/spectra.csv
+	referenceSpectra []byte
+	// referencePfeuty are the ground energies of periodic chains, and the energy density of the infinite chain in the row of length 0,
+	// from the exact solution of Pfeuty.
+	//go:embed reference/pfeuty.csv
+	referencePfeuty []byte
+)
+
+// referenceHs are the transverse fields of the reference data, which are in the ordered phase, at the critical point, and in the disordered phase.
+var referenceHs = []float64{0.5, 1, 2}
+
+// SelfTestResult is the comparison of a solver with a reference value.
+type SelfTestResult struct {
+	// Name identifies the solver and the reference value.
+	Name string
+	Got  float64
+	Want float64
+	// OK is whether Got agrees with Want within the single precision of the solvers.
+	OK bool
+}
+
+func (r SelfTestResult) String() string {
+	status := "ok"
+	if !r.OK {
+		status = "FAIL"
+	}
+	return fmt.Sprintf("%s %s got %.8f want %.8f", status, r.Name, r.Got, r.Want)
+}
+
+// SelfTest validates the solvers of this build against the reference data embedded in the package,
+// which is useful for verifying the numerical correctness on a new architecture or toolchain.
+// The exact diagonalization and MPS ground state search are compared with the spectra of open lattices of up to 12 spins,
+// the exact diagonalization of periodic chains and the infinite DMRG with the exact solution of Pfeuty.
+// An error is returned only if a solver fails to run, whereas disagreements are reported in the results.
+// See P. Pfeuty, The one-dimensional Ising model with a transverse field, Ann. Phys. 57, 79 (1970).
+func SelfTest() ([]SelfTestResult, error) {
+	results := make([]SelfTestResult, 0)
+	compare := func(name string, got, want float64) {
+		ok := math.Abs(got-want) <= selfTestTol*max(1, math.Abs(want))
+		results = append(results, SelfTestResult{Name: name, Got: got, Want: want, OK: ok})
+	}
+
+	spectra, err := readReference(referenceSpectra)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	for _, s := range spectra {
+		model := ModelSpec{N: s.n, H: complex(float32(s.h), 0)}
+		ed, err := Solve(model, ExactDiag, NewSolveOptions().NumStates(len(s.energies)))
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%v %f", s.n, s.h))
+		}
+		if s.n[0]*s.n[1] <= selfTestDenseMaxSpins {
+			dense, err := Solve(model, Dense, NewSolveOptions().NumStates(len(s.energies)))
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%v %f", s.n, s.h))
+			}
+			for i, e := range s.energies {
+				compare(fmt.Sprintf("dense %dx%d h=%g e%d", s.n[0], s.n[1], s.h, i), real(dense.Energies[i]), e)
+			}
+		}
+		// A Krylov space of a single starting vector resolves only one state of a degenerate eigenvalue,
+		// so that the levels from the first repeated one on are compared only with the dense solver.
+		for i, e := range s.energies {
+			if i > 0 && math.Abs(e-s.energies[i-1]) <= selfTestTol*max(1, math.Abs(e)) {
+				break
+			}
+			compare(fmt.Sprintf("exactdiag %dx%d h=%g e%d", s.n[0], s.n[1], s.h, i), real(ed.Energies[i]), e)
+		}
+
+		m, err := Solve(model, MPS, NewSolveOptions().Seed(1))
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%v %f", s.n, s.h))
+		}
+		compare(fmt.Sprintf("mps %dx%d h=%g e0", s.n[0], s.n[1], s.h), real(m.Energies[0]), s.energies[0])
+	}
+
+	pfeuty, err := readReference(referencePfeuty)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	for _, p := range pfeuty {
+		switch {
+		case p.n[0] == 0:
+			var bufs [10]*tensor.Dense
+			for i := range bufs {
+				bufs[i] = tensor.Zeros(1)
+			}
+			res, err := mps.IDMRG(mps.Ising([2]int{3, 1}, complex(float32(p.h), 0)), bufs, mps.NewIDMRGOptions().MaxBondDim(16))
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%f", p.h))
+			}
+			compare(fmt.Sprintf("idmrg h=%g energy density", p.h), float64(real(res.EnergyDensity)), p.energies[0])
+		default:
+			model := ModelSpec{N: p.n, H: complex(float32(p.h), 0), Periodic: [2]bool{true, false}}
+			ed, err := Solve(model, ExactDiag, NewSolveOptions().NumStates(1))
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%v %f", p.n, p.h))
+			}
+			compare(fmt.Sprintf("exactdiag periodic %dx%d h=%g e0", p.n[0], p.n[1], p.h), real(ed.Energies[0]), p.energies[0])
+		}
+	}
+	return results, nil
+}
+
+// selfTestTol is the relative tolerance of SelfTest, which is limited by the single precision of the solvers.
+const selfTestTol = 1e-4
+
+// WriteReference computes the reference data of SelfTest in double precision, and writes it to dir.
+// The spectra are computed by diagonalizing both parity sectors of the hamiltonian with gonum, which takes minutes for the largest lattices.
+func WriteReference(dir string) error {
+	spectra := make([]reference, 0)
+	for _, n := range [][2]int{{4, 1}, {6, 1}, {8, 1}, {10, 1}, {12, 1}, {3, 3}} {
+		for _, h := range referenceHs {
+			energies := make([]float64, 0)
+			for _, parity := range []int{1, -1} {
+				m, buf := mat.COOZeros(1, 1), mat.COOZeros(1, 1)
+				exactdiag.TransverseFieldIsing(m, buf, n, complex(float32(h), 0), exactdiag.NewIsingOptions().ParitySector(parity))
+				for _, vv := range m.Eigen() {
+					energies = append(energies, real(vv.Val))
+				}
+			}
+			slices.Sort(energies)
+			spectra = append(spectra, reference{n: n, h: h, energies: energies[:4]})
+		}
+	}
+	if err := writeReference(filepath.Join(dir, fnameSpectra), spectra); err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	pfeuty := make([]reference, 0)
+	for _, l := range []int{0, 4, 8, 12} {
+		for _, h := range referenceHs {
+			e := pfeutyEnergyDensity(h)
+			if l > 0 {
+				e = pfeutyEnergy(l, h)
+			}
+			pfeuty = append(pfeuty, reference{n: [2]int{l, 1}, h: h, energies: []float64{e}})
+		}
+	}
+	if err := writeReference(filepath.Join(dir, fnamePfeuty), pfeuty); err != nil {
+		return errors.Wrap(err, "")
+	}
+	return nil
+}
+
+// pfeutyEnergy returns the ground energy of the periodic chain of l spins,
+// which lies in the even parity sector, where the fermions are antiperiodic with momenta k = (2m+1)pi/l.
+func pfeutyEnergy(l int, h float64) float64 {
+	var e float64
+	for m := range l {
+		k := math.Pi * float64(2*m+1) / float64(l)
+		e -= math.Sqrt(1 + h*h - 2*h*math.Cos(k))
+	}
+	return e
+}
+
+// pfeutyEnergyDensity returns the ground energy per site of the infinite chain, -1/pi integral_0^pi sqrt(1 + h^2 - 2h cos k) dk,
+// which is integrated with Simpson's rule.
+func pfeutyEnergyDensity(h float64) float64 {
+	const steps = 1 << 16
+	f := func(k float64) float64 { return math.Sqrt(1 + h*h - 2*h*math.Cos(k)) }
+	dk := math.Pi / steps
+	sum := f(0) + f(math.Pi)
+	for i := 1; i < steps; i++ {
+		w := 2.0
+		if i%2 == 1 {
+			w = 4
+		}
+		sum += w * f(float64(i)*dk)
+	}
+	return -sum * dk / 3 / math.Pi
+}
+
+// reference is a row of reference data, which are the energies of a lattice of shape n in the transverse field h.
+type reference struct {
+	n        [2]int
+	h        float64
+	energies []float64
+}
+
+func readReference(b []byte) ([]reference, error) {
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	refs := make([]reference, 0, len(records))
+	// Skip the header.
+	for i, rec := range records[1:] {
+		if len(rec) < 4 {
+			return nil, errors.Errorf("%d %#v", i, rec)
+		}
+		var ref reference
+		for j := range 2 {
+			if ref.n[j], err = strconv.Atoi(rec[j]); err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%d %#v", i, rec))
+			}
+		}
+		if ref.h, err = strconv.ParseFloat(rec[2], 64); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("%d %#v", i, rec))
+		}
+		for _, s := range rec[3:] {
+			e, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, errors.Wrap(err, fmt.Sprintf("%d %#v", i, rec))
+			}
+			ref.energies = append(ref.energies, e)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+func writeReference(fpath string, refs []reference) error {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	w := csv.NewWriter(f)
+	header := []string{"n0", "n1", "h"}
+	for i := range len(refs[0].energies) {
+		header = append(header, fmt.Sprintf("e%d", i))
+	}
+	if err1 := w.Write(header); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	for _, ref := range refs {
+		rec := []string{strconv.Itoa(ref.n[0]), strconv.Itoa(ref.n[1]), strconv.FormatFloat(ref.h, 'g', -1, 64)}
+		for _, e := range ref.energies {
+			rec = append(rec, strconv.FormatFloat(e, 'g', -1, 64))
+		}
+		if err1 := w.Write(rec); err1 != nil && err == nil {
+			err = errors.Wrap(err1, "")
+			break
+		}
+	}
+	w.Flush()
+	if err1 := w.Error(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	if err1 := f.Close(); err1 != nil && err == nil {
+		err = errors.Wrap(err1, "")
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package qising
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	t.Parallel()
+	results, err := SelfTest()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(results) == 0 {
+		t.Fatalf("no results")
+	}
+	for _, r := range results {
+		if !r.OK {
+			t.Errorf("%v", r)
+		}
+	}
+}
+
+func TestPfeuty(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		l    int
+		h    float64
+		want float64
+	}{
+		// Two spins on a ring are coupled twice, so that H = -2 Z0 Z1 - h (X0 + X1), whose ground energy is -2 sqrt(1 + h^2).
+		{l: 2, h: 1, want: -2 * math.Sqrt(2)},
+		{l: 2, h: 0.5, want: -2 * math.Sqrt(1.25)},
+		// Without a field, every bond contributes -1.
+		{l: 6, h: 0, want: -6},
+		// At the critical point, the energy density of the infinite chain is -4/pi.
+		{l: 0, h: 1, want: -4 / math.Pi},
+		// Without a coupling, every spin contributes -h.
+		{l: 0, h: 1e6, want: -1e6},
+	}
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			t.Parallel()
+			got := pfeutyEnergyDensity(test.h)
+			if test.l > 0 {
+				got = pfeutyEnergy(test.l, test.h)
+			}
+			if math.Abs(got-test.want) > 1e-9*max(1, math.Abs(test.want)) {
+				t.Fatalf("%f %f", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadReference(t *testing.T) {
+	t.Parallel()
+	refs, err := readReference(referencePfeuty)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	for _, ref := range refs {
+		want := pfeutyEnergyDensity(ref.h)
+		if ref.n[0] > 0 {
+			want = pfeutyEnergy(ref.n[0], ref.h)
+		}
+		if len(ref.energies) != 1 || ref.energies[0] != want {
+			t.Fatalf("%v %f", ref, want)
+		}
+	}
+
+	if _, err := readReference([]byte("n0,n1,h,e0\n1,1,x,2\n")); err == nil {
+		t.Fatalf("expected error")
+	}
+}